@@ -0,0 +1,147 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/audit"
+	"helm.sh/helm/v3/pkg/cli/output"
+)
+
+const auditHelp = `
+This command consists of subcommands to read the audit log of Helm operations.
+
+Operations changing releases (install, upgrade, rollback and uninstall) are
+recorded when an audit log is configured with --audit-log or $HELM_AUDIT_LOG:
+
+    file:<path>   append JSON lines to a file
+    configmap     create a ConfigMap per operation in the namespace
+    secret        create a Secret per operation in the namespace
+    sql           write to the database of the SQL storage driver, given by
+                  $HELM_DRIVER_SQL_CONNECTION_STRING
+
+Each entry records who ran the operation and when, the release, chart and the
+digest of the values, and whether it succeeded. The values themselves are not
+recorded as they may hold secrets.
+`
+
+const auditLogHelp = `
+This command prints the entries of the audit log, oldest first.
+
+    $ helm audit log --release angry-bird
+    TIME                    	USER 	ACTION 	RELEASE   	NAMESPACE	REVISION	CHART       	RESULT
+    2024-05-01T12:00:00Z    	alice	install	angry-bird	default  	1       	alpine-0.1.0	success
+`
+
+func newAuditCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "read the audit log of Helm operations",
+		Long:  auditHelp,
+		Args:  require.NoArgs,
+	}
+	cmd.AddCommand(newAuditLogCmd(cfg, out))
+	return cmd
+}
+
+func newAuditLogCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewAudit(cfg)
+	var outfmt output.Format
+
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "print the audit log",
+		Long:  auditLogHelp,
+		Args:  require.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			entries, err := client.Run()
+			if err != nil {
+				return err
+			}
+			return outfmt.Write(out, auditEntries(entries))
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&client.Release, "release", "", "only print the entries of the named release")
+	f.IntVar(&client.Max, "max", 0, "maximum number of most recent entries to print")
+	bindOutputFlag(cmd, &outfmt)
+
+	return cmd
+}
+
+type auditEntries []audit.Entry
+
+func (a auditEntries) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, a)
+}
+
+func (a auditEntries) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, a)
+}
+
+func (a auditEntries) WriteTable(out io.Writer) error {
+	tbl := uitable.New()
+	tbl.AddRow("TIME", "USER", "ACTION", "RELEASE", "NAMESPACE", "REVISION", "CHART", "RESULT")
+	for _, e := range a {
+		chart := e.Chart
+		if e.ChartVersion != "" {
+			chart += "-" + e.ChartVersion
+		}
+		revision := ""
+		if e.Revision > 0 {
+			revision = strconv.Itoa(e.Revision)
+		}
+		result := e.Result
+		if e.Error != "" {
+			result += ": " + e.Error
+		}
+		if e.Note != "" {
+			result += " (" + e.Note + ")"
+		}
+		tbl.AddRow(e.Time.UTC().Format(time.RFC3339), e.User, e.Action, e.Release, e.Namespace, revision, chart, result)
+	}
+	return output.EncodeTable(out, tbl)
+}
+
+// auditUser returns the Kubernetes user recorded in the audit log: the
+// impersonated user, or the user of the kubeconfig context.
+func auditUser() string {
+	if settings.KubeAsUser != "" {
+		return settings.KubeAsUser
+	}
+	raw, err := settings.RESTClientGetter().ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	name := settings.KubeContext
+	if name == "" {
+		name = raw.CurrentContext
+	}
+	if c, ok := raw.Contexts[name]; ok {
+		return c.AuthInfo
+	}
+	return ""
+}
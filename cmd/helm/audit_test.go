@@ -0,0 +1,50 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/audit"
+)
+
+func TestAuditLogCmdWithoutAuditLog(t *testing.T) {
+	_, _, err := executeActionCommandC(storageFixture(), "audit log")
+	if err == nil || !strings.Contains(err.Error(), "no audit log configured") {
+		t.Errorf("expected error about the missing audit log, got %v", err)
+	}
+}
+
+func TestAuditEntriesTable(t *testing.T) {
+	entries := auditEntries{
+		{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), User: "alice", Action: "install", Release: "angry-bird", Namespace: "default", Revision: 1, Chart: "alpine", ChartVersion: "0.1.0", Result: audit.ResultSuccess},
+		{Time: time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC), User: "bob", Action: "upgrade", Release: "angry-bird", Namespace: "default", Result: audit.ResultFailure, Error: "timed out"},
+	}
+	var out bytes.Buffer
+	if err := entries.WriteTable(&out); err != nil {
+		t.Fatal(err)
+	}
+	expect := "TIME                	USER 	ACTION 	RELEASE   	NAMESPACE	REVISION	CHART       	RESULT            \n" +
+		"2024-05-01T12:00:00Z	alice	install	angry-bird	default  	1       	alpine-0.1.0	success           \n" +
+		"2024-05-01T12:05:00Z	bob  	upgrade	angry-bird	default  	        	            	failure: timed out\n"
+	if out.String() != expect {
+		t.Errorf("expected\n%q\ngot\n%q", expect, out.String())
+	}
+}
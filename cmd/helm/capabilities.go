@@ -0,0 +1,82 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const capabilitiesHelp = `
+This command consists of subcommands to work with the capabilities of clusters.
+`
+
+const capabilitiesExportHelp = `
+This command prints a snapshot of the capabilities of the current cluster as JSON.
+
+The snapshot holds the Kubernetes version and API versions of the cluster, and
+its custom resource definitions with their schemas. It can be passed to
+'helm template --capabilities-file' to render charts as they would be rendered
+for the cluster, and to validate the rendered custom resources against their
+schemas, without access to the cluster.
+
+    $ helm capabilities export > caps.json
+    $ helm template ./mychart --capabilities-file caps.json
+`
+
+func newCapabilitiesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "capabilities",
+		Short: "work with the capabilities of clusters",
+		Long:  capabilitiesHelp,
+		Args:  require.NoArgs,
+	}
+	cmd.AddCommand(newCapabilitiesExportCmd(cfg, out))
+	return cmd
+}
+
+func newCapabilitiesExportCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewCapabilitiesExport(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "print a snapshot of the capabilities of the current cluster",
+		Long:  capabilitiesExportHelp,
+		Args:  require.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			snapshot, err := client.Run()
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(snapshot, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(out, string(data))
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&client.SkipCRDs, "skip-crds", false, "leave the custom resource definitions out of the snapshot")
+	return cmd
+}
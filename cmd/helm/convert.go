@@ -0,0 +1,88 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const convertHelp = `
+This command consists of subcommands to convert other formats of Kubernetes
+manifests into charts.
+`
+
+const convertKustomizeHelp = `
+This command builds a kustomization, typically an overlay, and creates a chart
+of the resulting objects.
+
+Every object becomes a template, in which its name, labels, replicas and
+container images are parameterized by values. The fields set by the patches of
+the kustomization are lifted into values too, under the key of the object they
+patch, so that each overlay can become a values file of the chart:
+
+    $ helm convert kustomize ./overlays/production --name shop
+    $ helm template ./shop
+
+The values of the built objects are the defaults of the chart.
+`
+
+func newConvertCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "convert Kubernetes manifests into charts",
+		Long:  convertHelp,
+		Args:  require.NoArgs,
+	}
+	cmd.AddCommand(newConvertKustomizeCmd(out))
+	return cmd
+}
+
+func newConvertKustomizeCmd(out io.Writer) *cobra.Command {
+	client := action.NewConvertKustomize()
+
+	cmd := &cobra.Command{
+		Use:   "kustomize DIR",
+		Short: "create a chart from a kustomization",
+		Long:  convertKustomizeHelp,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return nil, cobra.ShellCompDirectiveFilterDirs
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			path, err := client.Run(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Created chart %s\n", path)
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&client.Name, "name", "", "the name of the chart (default: the name of the kustomization directory)")
+	f.StringVarP(&client.Destination, "destination", "d", ".", "location to create the chart in")
+	return cmd
+}
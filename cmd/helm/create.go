@@ -21,6 +21,7 @@ import (
 	"io"
 	"path/filepath"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
@@ -48,12 +49,18 @@ something like this:
 do not exist, Helm will attempt to create them as it goes. If the given
 destination exists and there are files in that directory, conflicting files
 will be overwritten, but other files will be left alone.
+
+With '--from-manifests', the chart is created from existing Kubernetes
+manifests instead: every object of the given files or directories becomes a
+template, in which the name, labels, replicas and container images are
+parameterized by values.yaml, with the values of the manifests as defaults.
 `
 
 type createOptions struct {
-	starter    string // --starter
-	name       string
-	starterDir string
+	starter       string   // --starter
+	fromManifests []string // --from-manifests
+	name          string
+	starterDir    string
 }
 
 func newCreateCmd(out io.Writer) *cobra.Command {
@@ -81,10 +88,15 @@ func newCreateCmd(out io.Writer) *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&o.starter, "starter", "p", "", "the name or absolute path to Helm starter scaffold")
+	cmd.Flags().StringArrayVar(&o.fromManifests, "from-manifests", []string{}, "create the chart from the Kubernetes manifests of a file or directory (can specify multiple)")
 	return cmd
 }
 
 func (o *createOptions) run(out io.Writer) error {
+	if o.starter != "" && len(o.fromManifests) > 0 {
+		return errors.New("cannot use --starter and --from-manifests together")
+	}
+
 	fmt.Fprintf(out, "Creating %s\n", o.name)
 
 	chartname := filepath.Base(o.name)
@@ -108,6 +120,10 @@ func (o *createOptions) run(out io.Writer) error {
 	}
 
 	chartutil.Stderr = out
+	if len(o.fromManifests) > 0 {
+		_, err := chartutil.CreateFromManifests(chartname, filepath.Dir(o.name), o.fromManifests...)
+		return err
+	}
 	_, err := chartutil.Create(chartname, filepath.Dir(o.name))
 	return err
 }
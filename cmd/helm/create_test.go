@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"helm.sh/helm/v3/internal/test"
 	"helm.sh/helm/v3/internal/test/ensure"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
@@ -192,6 +193,42 @@ func TestCreateStarterAbsoluteCmd(t *testing.T) {
 	}
 }
 
+func TestCreateFromManifestsCmd(t *testing.T) {
+	ensure.HelmHome(t)
+	manifests, err := filepath.Abs("testdata/manifests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restore := testChdir(t, t.TempDir())
+
+	if _, _, err := executeActionCommand("create shop --from-manifests " + manifests); err != nil {
+		t.Fatalf("Failed to run create: %s", err)
+	}
+
+	c, err := loader.LoadDir("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name() != "shop" {
+		t.Errorf("Expected %q name, got %q", "shop", c.Name())
+	}
+	if l := len(c.Templates); l != 4 {
+		t.Errorf("Expected 4 templates, got %d", l)
+	}
+
+	_, out, err := executeActionCommand("template shop --set web.replicaCount=5 --set web.containers.web.image.tag=2.1.0")
+	if err != nil {
+		t.Fatalf("Failed to render the chart: %s", err)
+	}
+
+	if _, _, err := executeActionCommand("create other --starter foo --from-manifests " + manifests); err == nil {
+		t.Error("Expected an error when using --starter and --from-manifests together")
+	}
+
+	restore()
+	test.AssertGoldenString(t, out, "output/create-from-manifests.txt")
+}
+
 func TestCreateFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "create", true)
 	checkFileCompletion(t, "create myname", false)
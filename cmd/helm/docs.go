@@ -29,6 +29,7 @@ import (
 	"golang.org/x/text/language"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
 )
 
 const docsDesc = `
@@ -40,6 +41,31 @@ This command can generate documentation for Helm in the following formats:
 - Man pages
 
 It can also generate bash autocompletions.
+
+To generate the README of a chart, use 'helm docs generate'.
+`
+
+const docsGenerateDesc = `
+Generate the README of a chart, documenting its values.
+
+The values are documented in a table from the comments above them in
+values.yaml and from values.schema.json. The comment directly above a key
+describes it. If the comment has a line starting with '-- ', the description
+starts there. A line starting with '@default -- ' replaces the default shown.
+
+	# -- number of replicas of the deployment
+	replicaCount: 1
+
+When the chart has a README.md.gotmpl, or a template is given with --template,
+the README is rendered from it. The template is given the metadata of the chart
+as .Chart, the documented values as .Values and their table as .ValuesTable.
+The functions of Sprig are available.
+
+Otherwise, the values table of an existing README is updated in place, or
+appended to it if it has none, and a missing README is created.
+
+Use --check in continuous integration to fail when the README is not up to
+date.
 `
 
 type docsOptions struct {
@@ -74,6 +100,43 @@ func newDocsCmd(out io.Writer) *cobra.Command {
 		return []string{"bash", "man", "markdown"}, cobra.ShellCompDirectiveNoFileComp
 	})
 
+	cmd.AddCommand(newDocsGenerateCmd(out))
+
+	return cmd
+}
+
+func newDocsGenerateCmd(out io.Writer) *cobra.Command {
+	client := action.NewDocs()
+
+	cmd := &cobra.Command{
+		Use:   "generate CHART",
+		Short: "generate the README of a chart, documenting its values",
+		Long:  docsGenerateDesc,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return nil, cobra.ShellCompDirectiveFilterDirs
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			path, err := client.Run(args[0])
+			if err != nil {
+				return err
+			}
+			if client.Check {
+				fmt.Fprintf(out, "%s is up to date\n", path)
+				return nil
+			}
+			fmt.Fprintf(out, "Generated %s\n", path)
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&client.Template, "template", "", "path to the template the README is rendered from (default: README.md.gotmpl in the chart, if present)")
+	f.StringVar(&client.Output, "output-file", client.Output, "name of the README in the chart")
+	f.BoolVar(&client.Check, "check", false, "fail if the README is not up to date rather than writing it")
 	return cmd
 }
 
@@ -24,13 +24,21 @@ import (
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/releaseutil"
 )
 
 var envHelp = `
 Env prints out all the environment information in use by Helm.
+
+With '--sort-order', it prints the order in which resources are installed and
+uninstalled by kind instead, including the kinds placed by the file given with
+'--kind-order' or $HELM_KIND_ORDER. Resources of kinds not in the order are
+installed and uninstalled last, sorted by kind.
 `
 
 func newEnvCmd(out io.Writer) *cobra.Command {
+	var sortOrder bool
 	cmd := &cobra.Command{
 		Use:   "env",
 		Short: "helm client environment information",
@@ -44,7 +52,10 @@ func newEnvCmd(out io.Writer) *cobra.Command {
 
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		},
-		Run: func(_ *cobra.Command, args []string) {
+		RunE: func(_ *cobra.Command, args []string) error {
+			if sortOrder {
+				return printKindOrder(out)
+			}
 			envVars := settings.EnvVars()
 
 			if len(args) == 0 {
@@ -58,11 +69,41 @@ func newEnvCmd(out io.Writer) *cobra.Command {
 			} else {
 				fmt.Fprintf(out, "%s\n", envVars[args[0]])
 			}
+			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&sortOrder, "sort-order", false, "print the order in which resources are installed and uninstalled by kind")
 	return cmd
 }
 
+// printKindOrder prints the install and uninstall orders of the kind order
+// of the settings.
+func printKindOrder(out io.Writer) error {
+	kindOrder, err := action.LoadKindOrder(settings.KindOrder)
+	if err != nil {
+		return err
+	}
+	installOrder, err := kindOrder.InstallOrder()
+	if err != nil {
+		return err
+	}
+	uninstallOrder, err := kindOrder.UninstallOrder()
+	if err != nil {
+		return err
+	}
+	printOrder(out, "INSTALL ORDER", installOrder)
+	fmt.Fprintln(out)
+	printOrder(out, "UNINSTALL ORDER", uninstallOrder)
+	return nil
+}
+
+func printOrder(out io.Writer, title string, order releaseutil.KindSortOrder) {
+	fmt.Fprintln(out, title)
+	for i, kind := range order {
+		fmt.Fprintf(out, "%3d  %s\n", i+1, kind)
+	}
+}
+
 func getSortedEnvVarKeys() []string {
 	envVars := settings.EnvVars()
 
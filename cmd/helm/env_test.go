@@ -25,6 +25,15 @@ func TestEnv(t *testing.T) {
 		name:   "completion for env",
 		cmd:    "__complete env ''",
 		golden: "output/env-comp.txt",
+	}, {
+		name:   "sort order",
+		cmd:    "env --sort-order --kind-order testdata/kind-order.yaml",
+		golden: "output/env-sort-order.txt",
+	}, {
+		name:      "invalid sort order",
+		cmd:       "env --sort-order --kind-order testdata/kind-order-invalid.yaml",
+		golden:    "output/env-sort-order-invalid.txt",
+		wantError: true,
 	}}
 	runTestCmd(t, tests)
 }
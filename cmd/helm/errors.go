@@ -0,0 +1,51 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/errcode"
+)
+
+// errorOutput is an error printed in the json error format.
+type errorOutput struct {
+	Code    errcode.Code `json:"code,omitempty"`
+	Message string       `json:"message"`
+}
+
+// printError prints an error in the error format of the settings, with its
+// message translated by the messages of the settings, if any.
+func printError(out io.Writer, err error) {
+	msg := err.Error()
+	if settings.Messages != "" {
+		translations, terr := errcode.LoadTranslations(settings.Messages)
+		if terr != nil {
+			debug("unable to load messages: %s", terr)
+		} else {
+			msg = translations.Message(err)
+		}
+	}
+	if settings.ErrorFormat == "json" {
+		if jerr := output.EncodeJSON(out, errorOutput{Code: errcode.Of(err), Message: msg}); jerr == nil {
+			return
+		}
+	}
+	fmt.Fprintf(out, "Error: %s\n", msg)
+}
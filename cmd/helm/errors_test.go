@@ -0,0 +1,61 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/errcode"
+)
+
+func TestPrintError(t *testing.T) {
+	defer resetEnv()()
+
+	messages := filepath.Join(t.TempDir(), "messages.yaml")
+	if err := os.WriteFile(messages, []byte(`HELM-1001: "release introuvable"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := errors.Wrap(errcode.New(errcode.ReleaseNotFound), "angry-bird")
+
+	tests := []struct {
+		name     string
+		format   string
+		messages string
+		want     string
+	}{
+		{"text", "text", "", "Error: angry-bird: release: not found\n"},
+		{"json", "json", "", `{"code":"HELM-1001","message":"angry-bird: release: not found"}` + "\n"},
+		{"translated", "text", messages, "Error: angry-bird: release introuvable\n"},
+		{"translated json", "json", messages, `{"code":"HELM-1001","message":"angry-bird: release introuvable"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings.ErrorFormat = tt.format
+			settings.Messages = tt.messages
+			var out bytes.Buffer
+			printError(&out, err)
+			if out.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, out.String())
+			}
+		})
+	}
+}
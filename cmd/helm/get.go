@@ -34,6 +34,7 @@ get extended information about the release, including:
 - The notes provided by the chart of the release
 - The hooks associated with the release
 - The metadata of the release
+- A bundle to reproduce the release
 `
 
 func newGetCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -50,6 +51,7 @@ func newGetCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	cmd.AddCommand(newGetHooksCmd(cfg, out))
 	cmd.AddCommand(newGetNotesCmd(cfg, out))
 	cmd.AddCommand(newGetMetadataCmd(cfg, out))
+	cmd.AddCommand(newGetReleaseCmd(cfg, out))
 
 	return cmd
 }
@@ -25,16 +25,22 @@ import (
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/redact"
 )
 
 var getAllHelp = `
 This command prints a human readable collection of information about the
 notes, hooks, supplied values, and generated manifest file of the given release.
+
+The values the schema of the chart marks as sensitive are masked unless
+--reveal is set.
 `
 
 func newGetAllCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	var template string
+	var reveal bool
 	client := action.NewGet(cfg)
+	revision := newRevisionValue(&client.Version)
 
 	cmd := &cobra.Command{
 		Use:   "all RELEASE_NAME",
@@ -48,22 +54,29 @@ func newGetAllCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
 			res, err := client.Run(args[0])
 			if err != nil {
 				return err
 			}
+			warnNewerSchema(res)
+			if !reveal {
+				res = redact.Sensitive(res)
+			}
 			if template != "" {
 				data := map[string]interface{}{
 					"Release": res,
 				}
 				return tpl(template, data, out)
 			}
-			return output.Table.Write(out, &statusPrinter{res, true, false, false, true, false})
+			return output.Table.Write(out, &statusPrinter{res, true, false, false, true, false, false})
 		},
 	}
 
 	f := cmd.Flags()
-	f.IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	f.Var(revision, "revision", "get the named release with revision, by number or tag")
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
 			return compListRevisions(toComplete, cfg, args[0])
@@ -76,6 +89,7 @@ func newGetAllCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	}
 
 	f.StringVar(&template, "template", "", "go template for formatting the output, eg: {{.Release.Name}}")
+	f.BoolVar(&reveal, "reveal", false, "show the values the chart marks as sensitive instead of masking them")
 
 	return cmd
 }
@@ -35,6 +35,7 @@ Hooks are formatted in YAML and separated by the YAML '---\n' separator.
 
 func newGetHooksCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewGet(cfg)
+	revision := newRevisionValue(&client.Version)
 
 	cmd := &cobra.Command{
 		Use:   "hooks RELEASE_NAME",
@@ -48,6 +49,9 @@ func newGetHooksCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
 			res, err := client.Run(args[0])
 			if err != nil {
 				return err
@@ -59,7 +63,7 @@ func newGetHooksCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	cmd.Flags().Var(revision, "revision", "get the named release with revision, by number or tag")
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
 			return compListRevisions(toComplete, cfg, args[0])
@@ -37,6 +37,7 @@ charts, those resources will also be included in the manifest.
 
 func newGetManifestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewGet(cfg)
+	revision := newRevisionValue(&client.Version)
 
 	cmd := &cobra.Command{
 		Use:   "manifest RELEASE_NAME",
@@ -50,6 +51,9 @@ func newGetManifestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
 			res, err := client.Run(args[0])
 			if err != nil {
 				return err
@@ -59,7 +63,7 @@ func newGetManifestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 		},
 	}
 
-	cmd.Flags().IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	cmd.Flags().Var(revision, "revision", "get the named release with revision, by number or tag")
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
 			return compListRevisions(toComplete, cfg, args[0])
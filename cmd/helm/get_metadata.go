@@ -35,6 +35,7 @@ type metadataWriter struct {
 func newGetMetadataCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	var outfmt output.Format
 	client := action.NewGetMetadata(cfg)
+	revision := newRevisionValue(&client.Version)
 
 	cmd := &cobra.Command{
 		Use:   "metadata RELEASE_NAME",
@@ -47,6 +48,9 @@ func newGetMetadataCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
 			releaseMetadata, err := client.Run(args[0])
 			if err != nil {
 				return err
@@ -56,7 +60,7 @@ func newGetMetadataCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 	}
 
 	f := cmd.Flags()
-	f.IntVar(&client.Version, "revision", 0, "specify release revision")
+	f.Var(revision, "revision", "specify release revision, by number or tag")
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
 			return compListRevisions(toComplete, cfg, args[0])
@@ -29,10 +29,17 @@ import (
 
 var getNotesHelp = `
 This command shows notes provided by the chart of a named release.
+
+By default the notes rendered when the revision was deployed are shown. With
+'--re-render' they are rendered again from the chart and values stored with
+the revision, which is useful when the notes contain time-sensitive
+instructions.
 `
 
 func newGetNotesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewGet(cfg)
+	revision := newRevisionValue(&client.Version)
+	var reRender bool
 
 	cmd := &cobra.Command{
 		Use:   "notes RELEASE_NAME",
@@ -46,19 +53,30 @@ func newGetNotesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
 			res, err := client.Run(args[0])
 			if err != nil {
 				return err
 			}
-			if len(res.Info.Notes) > 0 {
-				fmt.Fprintf(out, "NOTES:\n%s\n", res.Info.Notes)
+			notes := res.Info.Notes
+			if reRender {
+				if notes, err = client.RenderNotes(res); err != nil {
+					return err
+				}
+			}
+			if len(notes) > 0 {
+				fmt.Fprintf(out, "NOTES:\n%s\n", notes)
 			}
 			return nil
 		},
 	}
 
 	f := cmd.Flags()
-	f.IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	f.Var(revision, "revision", "get the named release with revision, by number or tag")
+	f.BoolVar(&reRender, "re-render", false, "render the notes again from the chart and values stored with the revision instead of showing the stored notes")
+	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set with --re-render, render subchart notes along with the parent")
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
 			return compListRevisions(toComplete, cfg, args[0])
@@ -19,10 +19,17 @@ package main
 import (
 	"testing"
 
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/release"
 )
 
 func TestGetNotesCmd(t *testing.T) {
+	rel := release.Mock(&release.MockReleaseOptions{Name: "the-limerick", Version: 2})
+	rel.Chart.Templates = append(rel.Chart.Templates, &chart.File{
+		Name: "templates/NOTES.txt",
+		Data: []byte("Revision {{ .Release.Revision }} of {{ .Release.Name }} upgraded: {{ .Release.IsUpgrade }}"),
+	})
+
 	tests := []cmdTestCase{{
 		name:   "get notes of a deployed release",
 		cmd:    "get notes the-limerick",
@@ -33,6 +40,11 @@ func TestGetNotesCmd(t *testing.T) {
 		cmd:       "get notes",
 		golden:    "output/get-notes-no-args.txt",
 		wantError: true,
+	}, {
+		name:   "get notes rendered again",
+		cmd:    "get notes the-limerick --re-render",
+		golden: "output/get-notes-re-render.txt",
+		rels:   []*release.Release{rel},
 	}}
 	runTestCmd(t, tests)
 }
@@ -0,0 +1,106 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+var getReleaseHelp = `
+This command exports a named release for audits and incident forensics.
+
+With the 'bundle' format, it writes a gzipped tar archive holding everything
+needed to reproduce the release revision:
+
+- metadata.json: the name, namespace, revision, status and dates of the release
+- chart/: the chart archive reconstructed from the chart stored with the release
+- values.yaml: the values supplied by the user
+- computed-values.yaml: the values computed from the chart and the user values
+- manifest.yaml: the generated manifest
+- hooks.json: the hooks including the results of their last run
+- notes.txt: the notes of the release, if any
+
+Subcharts are not stored with a release, so their output is only captured by
+the manifest and the computed values.
+`
+
+func newGetReleaseCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewGetRelease(cfg)
+	revision := newRevisionValue(&client.Version)
+	var destination string
+
+	cmd := &cobra.Command{
+		Use:   "release RELEASE_NAME",
+		Short: "export a named release",
+		Long:  getReleaseHelp,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			rel, err := client.Run(args[0], &buf)
+			if err != nil {
+				return err
+			}
+			filename := filepath.Join(destination, fmt.Sprintf("%s-%d-bundle.tgz", rel.Name, rel.Version))
+			if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Release bundle saved to: %s\n", filename)
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.Var(revision, "revision", "get the named release with revision, by number or tag")
+	f.StringVar(&client.Format, "format", action.ReleaseFormatBundle, "format of the export. Allowed values: bundle")
+	f.StringVarP(&destination, "destination", "d", ".", "location to write the export to")
+	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return compListRevisions(toComplete, cfg, args[0])
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = cmd.RegisterFlagCompletionFunc("format", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{action.ReleaseFormatBundle}, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return cmd
+}
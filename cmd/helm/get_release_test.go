@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestGetReleaseCmd(t *testing.T) {
+	dir := t.TempDir()
+	store := storageFixture()
+	rel := release.Mock(&release.MockReleaseOptions{Name: "thomas-guide"})
+	rel.Chart.Metadata.APIVersion = chart.APIVersionV2
+	if err := store.Create(rel); err != nil {
+		t.Fatal(err)
+	}
+
+	_, out, err := executeActionCommandC(store, fmt.Sprintf("get release thomas-guide --destination %s", dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "thomas-guide-1-bundle.tgz")
+	if out != fmt.Sprintf("Release bundle saved to: %s\n", filename) {
+		t.Errorf("unexpected output %q", out)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Error(err)
+	}
+
+	if _, _, err := executeActionCommandC(store, fmt.Sprintf("get release thomas-guide --format zip --destination %s", dir)); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestGetReleaseCompletion(t *testing.T) {
+	checkReleaseCompletion(t, "get release", false)
+}
+
+func TestGetReleaseRevisionCompletion(t *testing.T) {
+	revisionFlagCompletionTest(t, "get release")
+}
+
+func TestGetReleaseFileCompletion(t *testing.T) {
+	checkFileCompletion(t, "get release", false)
+	checkFileCompletion(t, "get release myrelease", false)
+}
@@ -30,6 +30,11 @@ import (
 
 var getValuesHelp = `
 This command downloads a values file for a given release.
+
+The values the schema of the chart marks as sensitive, with "x-helm-sensitive",
+are masked unless --reveal is set. With the configmap driver, they are stored in
+Secrets together with the manifests of their releases. The helmrelease and sql
+drivers keep them in the records of the releases.
 `
 
 type valuesWriter struct {
@@ -40,6 +45,7 @@ type valuesWriter struct {
 func newGetValuesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	var outfmt output.Format
 	client := action.NewGetValues(cfg)
+	revision := newRevisionValue(&client.Version)
 
 	cmd := &cobra.Command{
 		Use:   "values RELEASE_NAME",
@@ -53,6 +59,9 @@ func newGetValuesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
 			vals, err := client.Run(args[0])
 			if err != nil {
 				return err
@@ -62,7 +71,7 @@ func newGetValuesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	}
 
 	f := cmd.Flags()
-	f.IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	f.Var(revision, "revision", "get the named release with revision, by number or tag")
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
 			return compListRevisions(toComplete, cfg, args[0])
@@ -75,6 +84,7 @@ func newGetValuesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	}
 
 	f.BoolVarP(&client.AllValues, "all", "a", false, "dump all (computed) values")
+	f.BoolVar(&client.Reveal, "reveal", false, "show the values the chart marks as sensitive instead of masking them")
 	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
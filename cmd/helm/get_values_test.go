@@ -53,6 +53,24 @@ func TestGetValuesCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestGetValuesSensitiveCmd(t *testing.T) {
+	rel := release.Mock(&release.MockReleaseOptions{Name: "thomas-guide"})
+	rel.Chart.Schema = []byte(`{"properties": {"name": {"type": "string", "x-helm-sensitive": true}}}`)
+
+	tests := []cmdTestCase{{
+		name:   "get values masks sensitive values",
+		cmd:    "get values thomas-guide",
+		golden: "output/get-values-sensitive.txt",
+		rels:   []*release.Release{rel},
+	}, {
+		name:   "get values reveals sensitive values",
+		cmd:    "get values thomas-guide --reveal",
+		golden: "output/get-values.txt",
+		rels:   []*release.Release{rel},
+	}}
+	runTestCmd(t, tests)
+}
+
 func TestGetValuesCompletion(t *testing.T) {
 	checkReleaseCompletion(t, "get values", false)
 }
@@ -30,9 +30,11 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/audit"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/redact"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
 )
@@ -43,10 +45,14 @@ func init() {
 	log.SetFlags(log.Lshortfile)
 }
 
+// redactor masks secrets in the output and logs of Helm. It is nil, masking
+// nothing, when secrets are shown.
+var redactor *redact.Redactor
+
 func debug(format string, v ...interface{}) {
 	if settings.Debug {
 		format = fmt.Sprintf("[debug] %s\n", format)
-		log.Output(2, fmt.Sprintf(format, v...))
+		log.Output(2, redactor.Text(fmt.Sprintf(format, v...)))
 	}
 }
 
@@ -71,17 +77,87 @@ func main() {
 
 	// run when each command's execute method is called
 	cobra.OnInitialize(func() {
+		if !settings.ShowSecrets {
+			r, err := redact.New(settings.RedactPatterns...)
+			if err != nil {
+				log.Fatal(err)
+			}
+			redactor = r
+		}
+		actionConfig.Redactor = redactor
+		actionConfig.SensitiveSecrets = settings.SensitiveSecrets
 		helmDriver := os.Getenv("HELM_DRIVER")
 		if err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), helmDriver, debug); err != nil {
 			log.Fatal(err)
 		}
+		actionConfig.Releases.MaxNotesSize = settings.MaxNotesSize
+		actionConfig.Releases.MaxManifestSize = settings.MaxManifestSize
+		if kc, ok := actionConfig.KubeClient.(*kube.Client); ok {
+			kc.StatusMappingsSource = settings.WaitStatusMappings
+		}
+		// The policy files are loaded by the actions needing them, so that a
+		// malformed file does not fail every command.
+		actionConfig.PolicyFiles = action.PolicyFiles{
+			Webhooks:        settings.WebhooksConfig,
+			MetadataPolicy:  settings.MetadataPolicy,
+			NamePolicy:      settings.NamePolicy,
+			KindOrder:       settings.KindOrder,
+			HookImagePolicy: settings.HookImagePolicy,
+		}
+		actionConfig.FreezePolicy = settings.FreezePolicy
+		if len(settings.ExternalHookCommands) > 0 || len(settings.ExternalHookHosts) > 0 {
+			actionConfig.ExternalHooks = &action.ExternalHookPolicy{
+				Commands: settings.ExternalHookCommands,
+				Hosts:    settings.ExternalHookHosts,
+			}
+		}
+		actionConfig.HookServiceAccount = settings.HookServiceAccount
+		actionConfig.HookParallelism = settings.HookParallelism
+		if settings.HookLogs {
+			actionConfig.HookOutputFunc = hookLogPrinter(os.Stderr)
+		}
+		actionConfig.MaxIncludeDepth = settings.MaxIncludeDepth
+		actionConfig.TemplateTimeout = settings.TemplateTimeout
+		switch settings.ErrorFormat {
+		case "text", "json":
+		default:
+			log.Fatalf("invalid error format %q: must be text or json", settings.ErrorFormat)
+		}
+		// Errors are printed by printError to format or translate them.
+		if settings.ErrorFormat == "json" || settings.Messages != "" {
+			cmd.SilenceErrors = true
+		}
+		if settings.Profile != "" {
+			profiler, err := action.NewProfiler(settings.Profile, settings.ProfileDir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			actionConfig.Profiler = profiler
+		}
+		if settings.AuditLog != "" {
+			sink, err := audit.Open(settings.AuditLog, settings.Namespace(), actionConfig.KubernetesClientSet)
+			if err != nil {
+				log.Fatal(err)
+			}
+			actionConfig.AuditLog = sink
+			actionConfig.AuditUser = auditUser()
+		}
 		if helmDriver == "memory" {
 			loadReleasesInMemory(actionConfig)
 		}
 	})
 
-	if err := cmd.Execute(); err != nil {
+	err = cmd.Execute()
+	if actionConfig.Profiler != nil {
+		for _, f := range actionConfig.Profiler.Files() {
+			fmt.Fprintf(os.Stderr, "wrote profile %s\n", f)
+		}
+	}
+	if err != nil {
 		debug("%+v", err)
+		if cmd.SilenceErrors {
+			printError(os.Stderr, err)
+		}
 		switch e := err.(type) {
 		case pluginError:
 			os.Exit(e.code)
@@ -75,7 +75,11 @@ func runTestCmd(t *testing.T, tests []cmdTestCase) {
 }
 
 func storageFixture() *storage.Storage {
-	return storage.Init(driver.NewMemory())
+	store := storage.Init(driver.NewMemory())
+	// Pin the version releases are stamped with, so that the golden files
+	// do not change with the version of Helm.
+	store.HelmVersion = "v3.0.0-test"
+	return store
 }
 
 func executeActionCommandC(store *storage.Storage, cmd string) (*cobra.Command, string, error) {
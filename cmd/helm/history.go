@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gosuri/uitable"
@@ -43,11 +44,18 @@ configures the maximum length of the revision list returned.
 The historical release set is printed as a formatted table, e.g:
 
     $ helm history angry-bird
-    REVISION    UPDATED                     STATUS          CHART             APP VERSION     DESCRIPTION
-    1           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Initial install
-    2           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Upgraded successfully
-    3           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Rolled back to 2
-    4           Mon Oct 3 10:15:13 2016     deployed        alpine-0.1.0      1.0             Upgraded successfully
+    REVISION    UPDATED                     STATUS          CHART             APP VERSION     DEPLOYED BY     DESCRIPTION
+    1           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             alice           Initial install
+    2           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             alice           Upgraded successfully
+    3           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             bob             Rolled back to 2
+    4           Mon Oct 3 10:15:13 2016     deployed        alpine-0.1.0      1.0             bob             Upgraded successfully
+
+The DEPLOYED BY column shows the value of '--deployed-by' followed by the user
+the cluster authenticated Helm as, when known. Use '-o json' or '-o yaml' to
+also see the CI metadata recorded with each revision.
+
+Revisions given a name with 'helm release tag' are listed with their tags in a
+TAGS column. Tags can be used wherever a revision number is accepted.
 `
 
 func newHistoryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -90,6 +98,15 @@ type releaseInfo struct {
 	Chart       string        `json:"chart"`
 	AppVersion  string        `json:"app_version"`
 	Description string        `json:"description"`
+	DeployedBy  string        `json:"deployed_by,omitempty"`
+	// Identity is the full description of who performed the revision.
+	Identity *release.Identity `json:"identity,omitempty"`
+	// Tags are the names given to the revision with 'helm release tag'.
+	Tags []string `json:"tags,omitempty"`
+	// ChartDigest and ValuesDigest are the digests of the chart and values
+	// of the revision.
+	ChartDigest  string `json:"chart_digest,omitempty"`
+	ValuesDigest string `json:"values_digest,omitempty"`
 }
 
 type releaseHistory []releaseInfo
@@ -103,10 +120,24 @@ func (r releaseHistory) WriteYAML(out io.Writer) error {
 }
 
 func (r releaseHistory) WriteTable(out io.Writer) error {
+	// The TAGS column is only shown for releases with tagged revisions.
+	tagged := false
+	for _, item := range r {
+		tagged = tagged || len(item.Tags) > 0
+	}
+
 	tbl := uitable.New()
-	tbl.AddRow("REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION", "DESCRIPTION")
+	if tagged {
+		tbl.AddRow("REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION", "DEPLOYED BY", "TAGS", "DESCRIPTION")
+	} else {
+		tbl.AddRow("REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION", "DEPLOYED BY", "DESCRIPTION")
+	}
 	for _, item := range r {
-		tbl.AddRow(item.Revision, item.Updated.Format(time.ANSIC), item.Status, item.Chart, item.AppVersion, item.Description)
+		if tagged {
+			tbl.AddRow(item.Revision, item.Updated.Format(time.ANSIC), item.Status, item.Chart, item.AppVersion, item.DeployedBy, strings.Join(item.Tags, ","), item.Description)
+		} else {
+			tbl.AddRow(item.Revision, item.Updated.Format(time.ANSIC), item.Status, item.Chart, item.AppVersion, item.DeployedBy, item.Description)
+		}
 	}
 	return output.EncodeTable(out, tbl)
 }
@@ -143,11 +174,16 @@ func getReleaseHistory(rls []*release.Release) (history releaseHistory) {
 		a := formatAppVersion(r.Chart)
 
 		rInfo := releaseInfo{
-			Revision:    v,
-			Status:      s,
-			Chart:       c,
-			AppVersion:  a,
-			Description: d,
+			Revision:     v,
+			Status:       s,
+			Chart:        c,
+			AppVersion:   a,
+			Description:  d,
+			DeployedBy:   r.Info.DeployedBy.String(),
+			Identity:     r.Info.DeployedBy,
+			Tags:         action.ReleaseTags(r),
+			ChartDigest:  r.ChartDigest,
+			ValuesDigest: r.ValuesDigest,
 		}
 		if !r.Info.LastDeployed.IsZero() {
 			rInfo.Updated = r.Info.LastDeployed
@@ -193,6 +229,9 @@ func compListRevisions(_ string, cfg *action.Configuration, releaseName string)
 			appVersion := fmt.Sprintf("App: %s", release.Chart.Metadata.AppVersion)
 			chartDesc := fmt.Sprintf("Chart: %s-%s", release.Chart.Metadata.Name, release.Chart.Metadata.Version)
 			revisions = append(revisions, fmt.Sprintf("%s\t%s, %s", strconv.Itoa(release.Version), appVersion, chartDesc))
+			for _, tag := range action.ReleaseTags(release) {
+				revisions = append(revisions, fmt.Sprintf("%s\tRevision: %d, %s", tag, release.Version, chartDesc))
+			}
 		}
 		return revisions, cobra.ShellCompDirectiveNoFileComp
 	}
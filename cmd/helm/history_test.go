@@ -32,6 +32,9 @@ func TestHistoryCmd(t *testing.T) {
 		})
 	}
 
+	tagged := mk("angry-bird", 3, release.StatusSuperseded)
+	tagged.Labels = map[string]string{"tag.helm.sh/stable": "true", "tag.helm.sh/before-migration": "true"}
+
 	tests := []cmdTestCase{{
 		name: "get history for release",
 		cmd:  "history angry-bird",
@@ -50,6 +53,14 @@ func TestHistoryCmd(t *testing.T) {
 			mk("angry-bird", 3, release.StatusSuperseded),
 		},
 		golden: "output/history-limit.txt",
+	}, {
+		name: "get history with tagged revisions",
+		cmd:  "history angry-bird",
+		rels: []*release.Release{
+			mk("angry-bird", 4, release.StatusDeployed),
+			tagged,
+		},
+		golden: "output/history-tags.txt",
 	}, {
 		name: "get history with yaml output format",
 		cmd:  "history angry-bird --output yaml",
@@ -66,6 +77,29 @@ func TestHistoryCmd(t *testing.T) {
 			mk("angry-bird", 3, release.StatusSuperseded),
 		},
 		golden: "output/history.json",
+	}, {
+		name: "get history with the identity of the deployer",
+		cmd:  "history angry-bird --output json",
+		rels: func() []*release.Release {
+			rel := mk("angry-bird", 1, release.StatusDeployed)
+			rel.Info.DeployedBy = &release.Identity{
+				User:       "alice@example.com",
+				DeployedBy: "release-pipeline",
+				CI:         map[string]string{"GITHUB_RUN_ID": "42"},
+			}
+			return []*release.Release{rel}
+		}(),
+		golden: "output/history-deployed-by.json",
+	}, {
+		name: "get history with the digests of the chart and values",
+		cmd:  "history angry-bird --output json",
+		rels: func() []*release.Release {
+			rel := mk("angry-bird", 1, release.StatusDeployed)
+			rel.ChartDigest = release.ChartDigest(rel.Chart)
+			rel.ValuesDigest = release.ValuesDigest(rel.Config)
+			return []*release.Release{rel}
+		}(),
+		golden: "output/history-digests.json",
 	}}
 	runTestCmd(t, tests)
 }
@@ -0,0 +1,38 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestHookLogPrinter(t *testing.T) {
+	var out bytes.Buffer
+	output := hookLogPrinter(&out)
+	mainWriter, sidecarWriter := output("default", "migrate", "main"), output("default", "migrate", "sidecar")
+
+	io.WriteString(mainWriter, "one\ntw")
+	io.WriteString(sidecarWriter, "started\n")
+	io.WriteString(mainWriter, "o\n")
+
+	expected := "[migrate/main] one\n[migrate/sidecar] started\n[migrate/main] two\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
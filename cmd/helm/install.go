@@ -34,6 +34,7 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/downloader"
@@ -97,12 +98,19 @@ To check the generated manifests of a release without installing the chart,
 the --debug and --dry-run flags can be combined.
 
 The --dry-run flag will output all generated chart manifests, including Secrets
-which can contain sensitive values. To hide Kubernetes Secrets use the
---hide-secret flag. Please carefully consider how and when these flags are used.
+which can contain sensitive values. The data of Secrets and the values of keys
+matching the --redact-pattern expressions are masked unless --show-secrets is
+set. To hide Kubernetes Secrets entirely use the --hide-secret flag. Please
+carefully consider how and when these flags are used.
 
 If --verify is set, the chart MUST have a provenance file, and the provenance
 file MUST pass all verification steps.
 
+Charts may deploy resources into other namespaces than the namespace of the
+release by setting their metadata.namespace. The namespaces are recorded with
+the release and shown by 'helm status', and the permissions needed in each of
+them are checked before any resource is installed, upgraded or uninstalled.
+
 There are six different ways you can express the chart you want to install:
 
 1. By chart reference: helm install mymaria example/mariadb
@@ -112,6 +120,14 @@ There are six different ways you can express the chart you want to install:
 5. By chart reference and repo url: helm install --repo https://example.com/charts/ mynginx nginx
 6. By OCI registries: helm install mynginx --version 1.2.3 oci://example.com/charts/nginx
 
+A packaged chart can also be streamed, without writing it to disk first. Use
+'-' as the chart to read the archive from stdin, or give a URL together with
+'--digest' to stream it from the URL. The archive must match the digest when
+one is given:
+
+    $ curl -s https://example.com/build/nginx-1.2.3.tgz | helm install mynginx -
+    $ helm install mynginx https://example.com/charts/nginx-1.2.3.tgz --digest sha256:<hex>
+
 CHART REFERENCES
 
 A chart reference is a convenient way of referencing a chart in a chart repository.
@@ -130,6 +146,7 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewInstall(cfg)
 	valueOpts := &values.Options{}
 	var outfmt output.Format
+	var showComputedValues bool
 
 	cmd := &cobra.Command{
 		Use:   "install [NAME] [CHART]",
@@ -153,12 +170,15 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			if client.DryRunOption == "" {
 				client.DryRunOption = "none"
 			}
+			if showComputedValues && !isDryRunOption(client.DryRunOption) {
+				return errors.New("--show-computed-values requires --dry-run")
+			}
 			rel, err := runInstall(args, client, valueOpts, out)
 			if err != nil {
 				return errors.Wrap(err, "INSTALLATION FAILED")
 			}
 
-			return outfmt.Write(out, &statusPrinter{rel, settings.Debug, false, false, false, client.HideNotes})
+			return outfmt.Write(out, &statusPrinter{redactor.Release(rel), settings.Debug, false, false, false, client.HideNotes, showComputedValues})
 		},
 	}
 
@@ -167,6 +187,10 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	// it is added separately
 	f := cmd.Flags()
 	f.BoolVar(&client.HideSecret, "hide-secret", false, "hide Kubernetes Secrets when also using the --dry-run flag")
+	f.BoolVar(&client.StrictDeprecations, "strict-deprecations", false, "fail if the chart is deprecated or past its end of life")
+	f.BoolVar(&client.OverrideFreeze, "override-freeze", false, "proceed during an active freeze window of the freeze policy, recording the override in the audit log")
+	f.StringVar(&client.ManifestOutput, "output-dir", "", "after a successful install, write the applied manifests to this directory, or to this file if it has a .yaml or .yml extension")
+	f.BoolVar(&showComputedValues, "show-computed-values", false, "print the values the templates are rendered with, coalesced from the chart's values and the supplied values, when also using the --dry-run flag")
 	bindOutputFlag(cmd, &outfmt)
 	bindPostRenderFlag(cmd, &client.PostRenderer)
 
@@ -199,7 +223,10 @@ func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Instal
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
 	f.StringToStringVarP(&client.Labels, "labels", "l", nil, "Labels that would be added to release metadata. Should be divided by comma.")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
+	f.BoolVar(&client.LabelResources, "label-resources", false, "stamp all resources with labels for the release name, revision, chart and manager, so that they can be selected with 'kubectl get -l app.kubernetes.io/instance=RELEASE'")
+	f.StringVar(&client.DeployedBy, "deployed-by", "", "record who performs the operation, e.g. a person or a pipeline, in the release history")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in install output. Does not affect presence in chart metadata")
+	f.StringVar(&client.Digest, "digest", "", "digest, e.g. sha256:<hex>, the chart archive must match. Required when streaming a chart from a URL, optional when reading it from stdin")
 	addValueOptionsFlags(f, valueOpts)
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 
@@ -220,43 +247,66 @@ func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Instal
 }
 
 func runInstall(args []string, client *action.Install, valueOpts *values.Options, out io.Writer) (*release.Release, error) {
+	return runComposedInstall(args, client, valueOpts, nil, out)
+}
+
+// runComposedInstall is runInstall for a release of a composition, whose
+// namespace and values the composition may set. The values given on the
+// command line take precedence over those of the composition.
+func runComposedInstall(args []string, client *action.Install, valueOpts *values.Options, composed *composedRelease, out io.Writer) (*release.Release, error) {
 	debug("Original chart version: %q", client.Version)
 	if client.Version == "" && client.Devel {
 		debug("setting version to >0.0.0-0")
 		client.Version = ">0.0.0-0"
 	}
 
-	name, chart, err := client.NameAndChart(args)
+	name, chartRef, err := client.NameAndChart(args)
 	if err != nil {
 		return nil, err
 	}
 	client.ReleaseName = name
 
-	cp, err := client.ChartPathOptions.LocateChart(chart, settings)
-	if err != nil {
-		return nil, err
-	}
+	var cp string
+	var chartRequested *chart.Chart
+	if client.ChartPathOptions.IsStreamedChart(chartRef) {
+		if chartRequested, err = client.ChartPathOptions.LoadStreamedChart(chartRef, os.Stdin, settings); err != nil {
+			return nil, err
+		}
+		debug("CHART STREAMED FROM: %s\n", chartRef)
+	} else {
+		if client.ChartPathOptions.Digest != "" {
+			return nil, errors.New("--digest is only supported for charts read from stdin or a URL")
+		}
+		if cp, err = client.ChartPathOptions.LocateChart(chartRef, settings); err != nil {
+			return nil, err
+		}
+		debug("CHART PATH: %s\n", cp)
 
-	debug("CHART PATH: %s\n", cp)
+		// Check chart dependencies to make sure all are present in /charts
+		if chartRequested, err = loader.Load(cp); err != nil {
+			return nil, err
+		}
+	}
 
 	p := getter.All(settings)
 	vals, err := valueOpts.MergeValues(p)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check chart dependencies to make sure all are present in /charts
-	chartRequested, err := loader.Load(cp)
-	if err != nil {
-		return nil, err
+	if composed != nil {
+		base, err := composed.mergeValues(p)
+		if err != nil {
+			return nil, err
+		}
+		vals = chartutil.MergeTables(vals, base)
 	}
 
 	if err := checkIfInstallable(chartRequested); err != nil {
 		return nil, err
 	}
 
-	if chartRequested.Metadata.Deprecated {
-		warning("This chart is deprecated")
+	if d := chartRequested.Metadata.Deprecation(); d != nil {
+		warning("%s", d.Message(time.Now()))
 	}
 
 	if req := chartRequested.Metadata.Dependencies; req != nil {
@@ -265,7 +315,7 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 		// https://github.com/helm/helm/issues/2209
 		if err := action.CheckDependencies(chartRequested, req); err != nil {
 			err = errors.Wrap(err, "An error occurred while checking for chart dependencies. You may need to run `helm dependency build` to fetch missing dependencies")
-			if client.DependencyUpdate {
+			if client.DependencyUpdate && cp != "" {
 				man := &downloader.Manager{
 					Out:              out,
 					ChartPath:        cp,
@@ -291,6 +341,9 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 	}
 
 	client.Namespace = settings.Namespace()
+	if composed != nil && composed.Namespace != "" {
+		client.Namespace = composed.Namespace
+	}
 
 	// Validate DryRunOption member is one of the allowed values
 	if err := validateDryRunOptionFlag(client.DryRunOption); err != nil {
@@ -338,6 +391,11 @@ func compInstall(args []string, toComplete string, client *action.Install) ([]st
 	return nil, cobra.ShellCompDirectiveNoFileComp
 }
 
+// isDryRunOption reports whether a --dry-run option simulates the operation.
+func isDryRunOption(option string) bool {
+	return option != "none" && option != "false"
+}
+
 func validateDryRunOptionFlag(dryRunOptionFlagValue string) error {
 	// Validate dry-run flag value with a set of allowed value
 	allowedDryRunValues := []string{"false", "true", "none", "client", "server"}
@@ -17,12 +17,16 @@ limitations under the License.
 package main
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"helm.sh/helm/v3/pkg/redact"
 	"helm.sh/helm/v3/pkg/repo/repotest"
 )
 
@@ -231,6 +235,12 @@ func TestInstall(t *testing.T) {
 			cmd:    "install aeneas testdata/testcharts/deprecated --namespace default",
 			golden: "output/deprecated-chart.txt",
 		},
+		{
+			name:      "install deprecated chart with --strict-deprecations",
+			cmd:       "install aeneas testdata/testcharts/deprecated --namespace default --strict-deprecations",
+			wantError: true,
+			golden:    "output/deprecated-chart-strict.txt",
+		},
 		// Install chart with only crds
 		{
 			name: "install chart with only crds",
@@ -268,11 +278,134 @@ func TestInstall(t *testing.T) {
 			wantError: true,
 			golden:    "output/install-hide-secret.txt",
 		},
+		{
+			name:   "dry-run showing computed values",
+			cmd:    "install computed testdata/testcharts/alpine --dry-run --show-computed-values --set Name=computed --hide-notes",
+			golden: "output/install-dry-run-computed-values.txt",
+		},
+		{
+			name:      "show-computed-values error without dry-run",
+			cmd:       "install computed testdata/testcharts/alpine --show-computed-values",
+			wantError: true,
+			golden:    "output/install-show-computed-values.txt",
+		},
 	}
 
 	runTestCmd(t, tests)
 }
 
+func TestInstallDryRunRedactsSecrets(t *testing.T) {
+	r, err := redact.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { redactor = nil }()
+	redactor = r
+
+	runTestCmd(t, []cmdTestCase{{
+		name:   "dry-run masking secret",
+		cmd:    "install secrets testdata/testcharts/chart-with-secret --dry-run",
+		golden: "output/install-dry-run-with-secret-redacted.txt",
+	}})
+}
+
+func TestInstallStreamedChart(t *testing.T) {
+	const archive = "testdata/testcharts/signtest-0.1.0.tgz"
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	wrongDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(nil))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(data)
+	}))
+	defer srv.Close()
+	chartURL := srv.URL + "/signtest-0.1.0.tgz"
+
+	tests := []struct {
+		name    string
+		cmd     string
+		stdin   bool
+		wantOut string
+		wantErr string
+	}{
+		{
+			name:    "install from stdin",
+			cmd:     "install streamed - --namespace default",
+			stdin:   true,
+			wantOut: "NAME: streamed",
+		},
+		{
+			name:    "install from stdin with digest",
+			cmd:     "install streamed - --namespace default --digest " + digest,
+			stdin:   true,
+			wantOut: "NAME: streamed",
+		},
+		{
+			name:    "install from stdin with generated name",
+			cmd:     "install - --generate-name --namespace default",
+			stdin:   true,
+			wantOut: "NAME: chart-",
+		},
+		{
+			name:    "install from stdin with mismatching digest",
+			cmd:     "install streamed - --namespace default --digest " + wrongDigest,
+			stdin:   true,
+			wantErr: "chart archive digest mismatch",
+		},
+		{
+			name:    "install from stdin with verify",
+			cmd:     "install streamed - --namespace default --verify",
+			stdin:   true,
+			wantErr: "--verify is not supported",
+		},
+		{
+			name:    "install from URL with digest",
+			cmd:     "install streamed " + chartURL + " --namespace default --digest " + digest,
+			wantOut: "NAME: streamed",
+		},
+		{
+			name:    "install from URL with mismatching digest",
+			cmd:     "install streamed " + chartURL + " --namespace default --digest " + wrongDigest,
+			wantErr: "chart archive digest mismatch",
+		},
+		{
+			name:    "install from path with digest",
+			cmd:     "install streamed " + archive + " --namespace default --digest " + digest,
+			wantErr: "--digest is only supported for charts read from stdin or a URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in *os.File
+			if tt.stdin {
+				f, err := os.Open(archive)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				in = f
+			}
+			_, out, err := executeActionCommandStdinC(storageFixture(), in, tt.cmd)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("expected output containing %q, got:\n%s", tt.wantOut, out)
+			}
+		})
+	}
+}
+
 func TestInstallOutputCompletion(t *testing.T) {
 	outputFlagCompletionTest(t, "install")
 }
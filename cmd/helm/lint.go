@@ -40,12 +40,17 @@ the chart is well-formed.
 If the linter encounters things that will cause the chart to fail installation,
 it will emit [ERROR] messages. If it encounters issues that break with convention
 or recommendation, it will emit [WARNING] messages.
+
+The '--kube-version' flag accepts a comma-separated list of Kubernetes versions,
+such as '--kube-version 1.27,1.29,1.31', to lint against a support matrix in one
+run. Messages found with some of the versions only are followed by those versions.
 `
 
 func newLintCmd(out io.Writer) *cobra.Command {
 	client := action.NewLint()
 	valueOpts := &values.Options{}
 	var kubeVersion string
+	var admissionPolicies []string
 
 	cmd := &cobra.Command{
 		Use:   "lint PATH",
@@ -58,11 +63,20 @@ func newLintCmd(out io.Writer) *cobra.Command {
 			}
 
 			if kubeVersion != "" {
-				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
-				if err != nil {
-					return fmt.Errorf("invalid kube version '%s': %s", kubeVersion, err)
+				var kubeVersions []*chartutil.KubeVersion
+				for _, v := range strings.Split(kubeVersion, ",") {
+					v = strings.TrimSpace(v)
+					parsedKubeVersion, err := chartutil.ParseKubeVersion(v)
+					if err != nil {
+						return fmt.Errorf("invalid kube version '%s': %s", v, err)
+					}
+					kubeVersions = append(kubeVersions, parsedKubeVersion)
+				}
+				if len(kubeVersions) == 1 {
+					client.KubeVersion = kubeVersions[0]
+				} else {
+					client.KubeVersions = kubeVersions
 				}
-				client.KubeVersion = parsedKubeVersion
 			}
 
 			if client.WithSubcharts {
@@ -81,6 +95,17 @@ func newLintCmd(out io.Writer) *cobra.Command {
 			}
 
 			client.Namespace = settings.Namespace()
+			if len(admissionPolicies) > 0 {
+				cfg := &action.Configuration{SensitiveSecrets: settings.SensitiveSecrets}
+				if err := cfg.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv("HELM_DRIVER"), debug); err != nil {
+					return err
+				}
+				policies, err := loadAdmissionPolicies(cfg, admissionPolicies)
+				if err != nil {
+					return err
+				}
+				client.AdmissionPolicies = policies
+			}
 			vals, err := valueOpts.MergeValues(getter.All(settings))
 			if err != nil {
 				return err
@@ -148,7 +173,8 @@ func newLintCmd(out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Strict, "strict", false, "fail on lint warnings")
 	f.BoolVar(&client.WithSubcharts, "with-subcharts", false, "lint dependent charts")
 	f.BoolVar(&client.Quiet, "quiet", false, "print only warnings and errors")
-	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for capabilities and deprecation checks")
+	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for capabilities, API availability and deprecation checks. A comma-separated list lints against each version in turn")
+	f.StringArrayVar(&admissionPolicies, "admission-policy", []string{}, "validate the rendered templates against the ValidatingAdmissionPolicies of a file or directory, or of the current cluster with 'cluster' (can specify multiple)")
 	addValueOptionsFlags(f, valueOpts)
 
 	return cmd
@@ -87,6 +87,20 @@ func TestLintCmdWithKubeVersionFlag(t *testing.T) {
 		cmd:       fmt.Sprintf("lint --kube-version 1.21.0 --strict %s", testChart),
 		golden:    "output/lint-chart-with-deprecated-api-old-k8s.txt",
 		wantError: false,
+	}, {
+		name:      "lint chart with removed api version using kube version flag",
+		cmd:       fmt.Sprintf("lint --kube-version 1.25.0 %s", testChart),
+		golden:    "output/lint-chart-with-removed-api.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart against a list of kube versions",
+		cmd:       fmt.Sprintf("lint --kube-version 1.21.0,1.22.0,1.24.0,1.25.0 %s", testChart),
+		golden:    "output/lint-chart-with-deprecated-api-kube-versions.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart against an invalid list of kube versions",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0,latest %s", testChart),
+		wantError: true,
 	}}
 	runTestCmd(t, tests)
 }
@@ -21,6 +21,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
@@ -28,6 +29,7 @@ import (
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/release"
 )
 
@@ -56,6 +58,12 @@ By default, up to 256 items may be returned. To limit this, use the '--max' flag
 Setting '--max' to 0 will not return all results. Rather, it will return the
 server's default, which may be much higher than 256. Pairing the '--max'
 flag with the '--offset' flag allows you to page through results.
+
+The '--health' flag adds a HEALTH column summarizing the readiness of the
+resources of each release as a traffic light: 'green' when all resources are
+ready, 'yellow' when some are not ready yet, 'red' when some failed or are
+missing, and 'unknown' when the cluster could not be queried. The health is
+cached for '--health-cache-ttl' to keep repeated listings of large fleets fast.
 `
 
 func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -105,7 +113,7 @@ func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				}
 			}
 
-			return outfmt.Write(out, newReleaseListWriter(results, client.TimeFormat, client.NoHeaders))
+			return outfmt.Write(out, newReleaseListWriter(results, client.TimeFormat, client.NoHeaders, client.Health))
 		},
 	}
 
@@ -127,6 +135,9 @@ func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.IntVar(&client.Offset, "offset", 0, "next release index in the list, used to offset from start value")
 	f.StringVarP(&client.Filter, "filter", "f", "", "a regular expression (Perl compatible). Any releases that match the expression will be included in the results")
 	f.StringVarP(&client.Selector, "selector", "l", "", "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2). Works only for secret(default) and configmap storage backends.")
+	f.BoolVar(&client.Health, "health", false, "evaluate the readiness of the resources of each release and show it as a traffic light")
+	f.DurationVar(&client.HealthCacheTTL, "health-cache-ttl", time.Minute, "how long the health of a release revision is cached. Use 0 to always query the cluster")
+	client.HealthCache = helmpath.CachePath("health")
 	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
@@ -140,24 +151,39 @@ type releaseElement struct {
 	Status     string `json:"status"`
 	Chart      string `json:"chart"`
 	AppVersion string `json:"app_version"`
+	Health     string `json:"health,omitempty"`
+	// ChartDigest and ValuesDigest are the digests of the chart and values
+	// of the revision.
+	ChartDigest  string `json:"chart_digest,omitempty"`
+	ValuesDigest string `json:"values_digest,omitempty"`
+
+	// healthSummary is the health shown in the table.
+	healthSummary string
 }
 
 type releaseListWriter struct {
-	releases  []releaseElement
-	noHeaders bool
+	releases   []releaseElement
+	noHeaders  bool
+	showHealth bool
 }
 
-func newReleaseListWriter(releases []*release.Release, timeFormat string, noHeaders bool) *releaseListWriter {
+func newReleaseListWriter(releases []*release.Release, timeFormat string, noHeaders, showHealth bool) *releaseListWriter {
 	// Initialize the array so no results returns an empty array instead of null
 	elements := make([]releaseElement, 0, len(releases))
 	for _, r := range releases {
 		element := releaseElement{
-			Name:       r.Name,
-			Namespace:  r.Namespace,
-			Revision:   strconv.Itoa(r.Version),
-			Status:     r.Info.Status.String(),
-			Chart:      formatChartname(r.Chart),
-			AppVersion: formatAppVersion(r.Chart),
+			Name:         r.Name,
+			Namespace:    r.Namespace,
+			Revision:     strconv.Itoa(r.Version),
+			Status:       r.Info.Status.String(),
+			Chart:        formatChartname(r.Chart),
+			AppVersion:   formatAppVersion(r.Chart),
+			ChartDigest:  r.ChartDigest,
+			ValuesDigest: r.ValuesDigest,
+		}
+		if showHealth {
+			element.Health = r.Info.Health
+			element.healthSummary = formatHealth(r.Info)
 		}
 
 		t := "-"
@@ -172,16 +198,42 @@ func newReleaseListWriter(releases []*release.Release, timeFormat string, noHead
 
 		elements = append(elements, element)
 	}
-	return &releaseListWriter{elements, noHeaders}
+	return &releaseListWriter{elements, noHeaders, showHealth}
+}
+
+// formatHealth formats the traffic light health of a release along with how
+// many of its resources are ready.
+func formatHealth(info *release.Info) string {
+	if info.Health == "" {
+		return "-"
+	}
+	if len(info.ResourceStatuses) == 0 {
+		return info.Health
+	}
+	ready := 0
+	for _, s := range info.ResourceStatuses {
+		if s.Health == "Ready" {
+			ready++
+		}
+	}
+	return fmt.Sprintf("%s (%d/%d ready)", info.Health, ready, len(info.ResourceStatuses))
 }
 
 func (r *releaseListWriter) WriteTable(out io.Writer) error {
 	table := uitable.New()
 	if !r.noHeaders {
-		table.AddRow("NAME", "NAMESPACE", "REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION")
+		if r.showHealth {
+			table.AddRow("NAME", "NAMESPACE", "REVISION", "UPDATED", "STATUS", "HEALTH", "CHART", "APP VERSION")
+		} else {
+			table.AddRow("NAME", "NAMESPACE", "REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION")
+		}
 	}
-	for _, r := range r.releases {
-		table.AddRow(r.Name, r.Namespace, r.Revision, r.Updated, r.Status, r.Chart, r.AppVersion)
+	for _, rel := range r.releases {
+		if r.showHealth {
+			table.AddRow(rel.Name, rel.Namespace, rel.Revision, rel.Updated, rel.Status, rel.healthSummary, rel.Chart, rel.AppVersion)
+		} else {
+			table.AddRow(rel.Name, rel.Namespace, rel.Revision, rel.Updated, rel.Status, rel.Chart, rel.AppVersion)
+		}
 	}
 	return output.EncodeTable(out, table)
 }
@@ -148,6 +148,11 @@ func TestListCmd(t *testing.T) {
 		cmd:    "list",
 		golden: "output/list.txt",
 		rels:   releaseFixture,
+	}, {
+		name:   "list releases with health",
+		cmd:    "list --all --health --health-cache-ttl 0",
+		golden: "output/list-health.txt",
+		rels:   releaseFixture,
 	}, {
 		name:   "list without headers",
 		cmd:    "list --no-headers",
@@ -80,6 +80,12 @@ func loadPlugins(baseCmd *cobra.Command, out io.Writer) {
 					return err
 				}
 
+				// Plugins that declare their flags get Helm's help output,
+				// which lists those flags.
+				if len(md.Flags) > 0 && helpRequested(u) {
+					return cmd.Help()
+				}
+
 				// Call setupEnv before PrepareCommand because
 				// PrepareCommand uses os.ExpandEnv and expects the
 				// setupEnv vars.
@@ -90,12 +96,14 @@ func loadPlugins(baseCmd *cobra.Command, out io.Writer) {
 					return errors.Errorf("plugin %q exited with error", md.Name)
 				}
 
-				return callPluginExecutable(md.Name, main, argv, out)
+				return callPluginExecutable(plug, main, argv, out)
 			},
 			// This passes all the flags to the subcommand.
 			DisableFlagParsing: true,
 		}
 
+		addDeclaredFlags(c, md.Flags)
+
 		// TODO: Make sure a command with this name does not already exist.
 		baseCmd.AddCommand(c)
 
@@ -109,6 +117,9 @@ func loadPlugins(baseCmd *cobra.Command, out io.Writer) {
 			/* for the tests */ subCmd == baseCmd.Root() {
 			loadCompletionForPlugin(c, plug)
 		}
+		if len(md.Flags) > 0 {
+			c.ValidArgsFunction = declaredFlagComp(md.Flags, c.ValidArgsFunction)
+		}
 	}
 }
 
@@ -121,8 +132,10 @@ func processParent(cmd *cobra.Command, args []string) ([]string, error) {
 }
 
 // This function is used to setup the environment for the plugin and then
-// call the executable specified by the parameter 'main'
-func callPluginExecutable(pluginName string, main string, argv []string, out io.Writer) error {
+// call the executable specified by the parameter 'main', in the sandbox of
+// the plugin if it runs in one
+func callPluginExecutable(plug *plugin.Plugin, main string, argv []string, out io.Writer) error {
+	pluginName := plug.Metadata.Name
 	env := os.Environ()
 	for k, v := range settings.EnvVars() {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
@@ -134,6 +147,11 @@ func callPluginExecutable(pluginName string, main string, argv []string, out io.
 	prog.Stdin = os.Stdin
 	prog.Stdout = out
 	prog.Stderr = os.Stderr
+	cleanup, err := plug.SandboxCommand(prog, settings.PluginSandbox)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	if err := prog.Run(); err != nil {
 		if eerr, ok := err.(*exec.ExitError); ok {
 			os.Stderr.Write(eerr.Stderr)
@@ -148,6 +166,85 @@ func callPluginExecutable(pluginName string, main string, argv []string, out io.
 	return nil
 }
 
+// addDeclaredFlags registers the flags declared in the plugin.yaml of a plugin
+// on its command, so they are shown in help and offered by completion.
+// Flag parsing stays disabled for plugin commands, so the flags only
+// describe the arguments that are passed through to the plugin.
+func addDeclaredFlags(cmd *cobra.Command, flags []plugin.Flag) {
+	f := cmd.Flags()
+	for _, flag := range flags {
+		switch flag.Type {
+		case plugin.FlagTypeBool:
+			def, _ := strconv.ParseBool(flag.Default)
+			f.BoolP(flag.Name, flag.Shorthand, def, flag.Usage)
+		case plugin.FlagTypeInt:
+			def, _ := strconv.Atoi(flag.Default)
+			f.IntP(flag.Name, flag.Shorthand, def, flag.Usage)
+		case plugin.FlagTypeStringArray:
+			var def []string
+			if flag.Default != "" {
+				def = strings.Split(flag.Default, ",")
+			}
+			f.StringArrayP(flag.Name, flag.Shorthand, def, flag.Usage)
+		default:
+			f.StringP(flag.Name, flag.Shorthand, flag.Default, flag.Usage)
+		}
+	}
+}
+
+// declaredFlagComp completes the values of the flags declared by a plugin and
+// defers to next for everything else. Cobra does not complete flag values
+// itself for commands that disable flag parsing, as plugin commands do.
+func declaredFlagComp(flags []plugin.Flag, next func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var flag *plugin.Flag
+		if i := strings.Index(toComplete, "="); i > 0 && strings.HasPrefix(toComplete, "-") {
+			flag = lookupDeclaredFlag(flags, toComplete[:i])
+		} else if len(args) > 0 {
+			flag = lookupDeclaredFlag(flags, args[len(args)-1])
+			if flag != nil && flag.Type == plugin.FlagTypeBool {
+				// Boolean flags take no separate value.
+				flag = nil
+			}
+		}
+		if flag != nil {
+			if len(flag.Completions) == 0 {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return flag.Completions, cobra.ShellCompDirectiveNoFileComp
+		}
+		if next != nil {
+			return next(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
+
+// lookupDeclaredFlag returns the declared flag named by arg, which is either
+// --name or -shorthand.
+func lookupDeclaredFlag(flags []plugin.Flag, arg string) *plugin.Flag {
+	for i := range flags {
+		f := &flags[i]
+		if arg == "--"+f.Name || (f.Shorthand != "" && arg == "-"+f.Shorthand) {
+			return f
+		}
+	}
+	return nil
+}
+
+// helpRequested reports whether the arguments passed to a plugin ask for help.
+func helpRequested(args []string) bool {
+	for _, a := range args {
+		switch a {
+		case "--":
+			return false
+		case "-h", "--help":
+			return true
+		}
+	}
+	return false
+}
+
 // manuallyProcessArgs processes an arg array, removing special args.
 //
 // Returns two sets of args: known and unknown (in that order)
@@ -272,22 +369,32 @@ func addPluginCommands(plugin *plugin.Plugin, baseCmd *cobra.Command, cmds *plug
 		}
 
 		f := baseCmd.Flags()
+		// Flags declared in the plugin.yaml already exist and take precedence.
+		fakeFlag := func(long, short string) {
+			if f.Lookup(long) != nil {
+				return
+			}
+			if f.ShorthandLookup(short) != nil {
+				short = ""
+			}
+			f.BoolP(long, short, false, "")
+		}
 		if len(longs) >= len(shorts) {
 			for i := range longs {
 				if i < len(shorts) {
-					f.BoolP(longs[i], shorts[i], false, "")
+					fakeFlag(longs[i], shorts[i])
 				} else {
-					f.Bool(longs[i], false, "")
+					fakeFlag(longs[i], "")
 				}
 			}
 		} else {
 			for i := range shorts {
 				if i < len(longs) {
-					f.BoolP(longs[i], shorts[i], false, "")
+					fakeFlag(longs[i], shorts[i])
 				} else {
 					// Create a long flag with the same name as the short flag.
 					// Not a perfect solution, but its better than ignoring the extra short flags.
-					f.BoolP(shorts[i], shorts[i], false, "")
+					fakeFlag(shorts[i], shorts[i])
 				}
 			}
 		}
@@ -346,7 +453,7 @@ func pluginDynamicComp(plug *plugin.Plugin, cmd *cobra.Command, args []string, t
 
 	cobra.CompDebugln(fmt.Sprintf("calling %s with args %v", main, argv), settings.Debug)
 	buf := new(bytes.Buffer)
-	if err := callPluginExecutable(md.Name, main, argv, buf); err != nil {
+	if err := callPluginExecutable(plug, main, argv, buf); err != nil {
 		// The dynamic completion file is optional for a plugin, so this error is ok.
 		cobra.CompDebugln(fmt.Sprintf("Unable to call %s: %v", main, err.Error()), settings.Debug)
 		return nil, cobra.ShellCompDirectiveDefault
@@ -28,6 +28,34 @@ import (
 
 const pluginHelp = `
 Manage client-side Helm plugins.
+
+A plugin can ask to run in a sandbox with a 'sandbox' section in its
+plugin.yaml, and '--plugin-sandbox' runs every plugin in one:
+
+    sandbox:
+      env: [AWS_REGION, "MYPLUGIN_*"]
+      restricted: true
+
+A sandboxed plugin gets a scratch directory as HOME and TMPDIR, removed once
+it exited, and only the variables of the environment listed below, plus
+those of 'env'. A name ending in '*' passes all variables with that prefix.
+Credentials such as KUBECONFIG or HELM_KUBETOKEN are only passed if listed.
+
+    all sandboxed plugins:  PATH, SYSTEMROOT, HELM_BIN, HELM_DEBUG,
+                            HELM_NAMESPACE, HELM_PLUGIN_NAME, HELM_PLUGIN_DIR
+    unless restricted:      HELM_KUBECONTEXT, HELM_PLUGINS, HELM_CACHE_HOME,
+                            HELM_CONFIG_HOME, HELM_DATA_HOME,
+                            HELM_REGISTRY_CONFIG, HELM_REPOSITORY_CACHE,
+                            HELM_REPOSITORY_CONFIG, HELM_PLUGIN_USERNAME,
+                            HELM_PLUGIN_PASSWORD,
+                            HELM_PLUGIN_PASS_CREDENTIALS_ALL
+
+A plugin that is not restricted can still read and write any file the user
+can. With 'restricted: true', which is only supported on Linux, the plugin
+has no network and only sees the system directories such as /usr and /etc,
+its own directory and the Helm binary, all read-only, and its scratch
+directory, which is its working directory. The hooks of a plugin are never
+restricted.
 `
 
 func newPluginCmd(out io.Writer) *cobra.Command {
@@ -61,6 +89,11 @@ func runHook(p *plugin.Plugin, event string) error {
 
 	plugin.SetupPluginEnv(settings, p.Metadata.Name, p.Dir)
 	prog.Stdout, prog.Stderr = os.Stdout, os.Stderr
+	cleanup, err := p.SandboxHook(prog, settings.PluginSandbox)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	if err := prog.Run(); err != nil {
 		if eerr, ok := err.(*exec.ExitError); ok {
 			os.Stderr.Write(eerr.Stderr)
@@ -18,6 +18,7 @@ package main
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
@@ -161,6 +162,90 @@ func TestLoadPlugins(t *testing.T) {
 	}
 }
 
+func TestPluginDeclaredFlags(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins assume a Linux subsystem")
+	}
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "greet"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `name: greet
+usage: "greet someone"
+command: "echo"
+flags:
+  - name: output
+    shorthand: o
+    usage: "output format"
+    completions: [table, json, yaml]
+  - name: verbose
+    type: bool
+    usage: "print more"
+  - name: retries
+    type: int
+    default: "3"
+    usage: "number of attempts"
+`
+	if err := os.WriteFile(filepath.Join(dir, "greet", "plugin.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(old string) { settings.PluginsDirectory = old }(settings.PluginsDirectory)
+	settings.PluginsDirectory = dir
+
+	// Arguments are still passed through to the plugin unparsed.
+	_, out, err := executeActionCommandC(storageFixture(), "greet -o json --verbose world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "-o json --verbose world\n" {
+		t.Errorf("unexpected plugin output %q", out)
+	}
+
+	_, out, err = executeActionCommandC(storageFixture(), "greet --help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"-o, --output string",
+		"output format",
+		"--verbose",
+		"--retries int",
+		"(default 3)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected help to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	_, out, err = executeActionCommandC(storageFixture(), "__complete greet --output ''")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "table\njson\nyaml\n:4\n") {
+		t.Errorf("unexpected flag value completions %q", out)
+	}
+
+	_, out, err = executeActionCommandC(storageFixture(), "__complete greet --output=''")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "table\njson\nyaml\n:4\n") {
+		t.Errorf("unexpected flag value completions %q", out)
+	}
+
+	_, out, err = executeActionCommandC(storageFixture(), "__complete greet --")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--output", "--retries", "--verbose"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected flag completion %q, got:\n%s", want, out)
+		}
+	}
+}
+
 func TestLoadPluginsWithSpace(t *testing.T) {
 	settings.PluginsDirectory = "testdata/helm home with space/helm/plugins"
 	settings.RepositoryConfig = "testdata/helm home with space/helm/repositories.yaml"
@@ -19,7 +19,9 @@ package main
 import (
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
@@ -32,6 +34,24 @@ Upload a chart to a registry.
 
 If the chart has an associated provenance file,
 it will also be uploaded.
+
+To sign the chart before uploading it, use the '--sign' flag along with
+'--key' and '--keyring', as for 'helm package'. The provenance file is written
+next to the chart and uploaded with it.
+
+When pushing to a registry, other artifacts can be attached to the chart: an
+SBOM with '--sbom', and any file with '--attach <path>:<artifact type>', e.g.
+test results. They are pushed as OCI referrers of the chart. The chart manifest
+can also be annotated with '--annotation', e.g. with its source revision or the
+URL of the build that produced it:
+
+  $ helm push mychart-0.1.0.tgz oci://registry.example.com/charts --sign \
+      --key mykey --keyring ~/.gnupg/secring.gpg --sbom sbom.spdx.json \
+      --attach results.xml:application/vnd.junit+xml \
+      --annotation org.opencontainers.image.revision=$GIT_COMMIT
+
+The chart is only pushed once everything attached to it is, and the attached
+artifacts are deleted if the push fails, if the registry allows it.
 `
 
 type registryPushOptions struct {
@@ -40,6 +60,13 @@ type registryPushOptions struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	sign                  bool
+	key                   string
+	keyring               string
+	passphraseFile        string
+	sbom                  string
+	attachments           []string
+	annotations           []string
 }
 
 func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -81,6 +108,30 @@ func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				action.WithPlainHTTP(o.plainHTTP),
 				action.WithPushOptWriter(out))
 			client.Settings = settings
+			if o.sign {
+				if o.key == "" {
+					return errors.New("--key is required for signing a chart")
+				}
+				if o.keyring == "" {
+					return errors.New("--keyring is required for signing a chart")
+				}
+			}
+			client.Sign = o.sign
+			client.Key = o.key
+			client.Keyring = o.keyring
+			client.PassphraseFile = o.passphraseFile
+			client.SBOM = o.sbom
+			client.Attachments = o.attachments
+			if len(o.annotations) > 0 {
+				client.Annotations = map[string]string{}
+				for _, a := range o.annotations {
+					k, v, ok := strings.Cut(a, "=")
+					if !ok || k == "" {
+						return errors.Errorf("annotation %q must be given as <key>=<value>", a)
+					}
+					client.Annotations[k] = v
+				}
+			}
 			output, err := client.Run(chartRef, remote)
 			if err != nil {
 				return err
@@ -96,6 +147,13 @@ func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
 	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the chart upload")
 	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the chart upload")
+	f.BoolVar(&o.sign, "sign", false, "use a PGP private key to sign the chart before uploading it")
+	f.StringVar(&o.key, "key", "", "name of the key to use when signing. Used if --sign is true")
+	f.StringVar(&o.keyring, "keyring", defaultKeyring(), "location of a public keyring")
+	f.StringVar(&o.passphraseFile, "passphrase-file", "", `location of a file which contains the passphrase for the signing key. Use "-" in order to read from stdin.`)
+	f.StringVar(&o.sbom, "sbom", "", "attach the given SPDX or CycloneDX SBOM to the chart")
+	f.StringArrayVar(&o.attachments, "attach", nil, "attach a file to the chart, given as <path>:<artifact type> (can specify multiple)")
+	f.StringArrayVar(&o.annotations, "annotation", nil, "set an annotation on the chart manifest, given as <key>=<value> (can specify multiple)")
 
 	return cmd
 }
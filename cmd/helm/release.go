@@ -0,0 +1,193 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const releaseHelp = `
+This command consists of multiple subcommands to manage releases.
+`
+
+const releaseMigrateApplyMethodHelp = `
+This command switches a release from client-side to server-side apply.
+
+Releases installed with client-side apply conflict with server-side apply,
+because Kubernetes records the fields Helm set as owned by client-side updates.
+This command transfers the ownership of those fields to server-side apply by
+rewriting the managed fields of the resources of the release. The resources
+are neither changed nor recreated.
+
+Later upgrades and rollbacks of the release apply its resources server-side.
+A migration that failed part way can be run again.
+
+    $ helm release migrate-apply-method angry-bird
+`
+
+const releaseTagHelp = `
+This command gives a revision of a release a name.
+
+The tag can be used instead of the revision number wherever one is accepted,
+e.g. by 'helm rollback', 'helm get' and 'helm status'. A tag names a single
+revision of a release: tagging another revision moves the tag. Without
+'--revision', the latest revision is tagged.
+
+Tags are stored as labels of the release, and are listed by 'helm history'.
+
+    $ helm release tag angry-bird --revision 12 stable-before-migration
+    $ helm rollback angry-bird stable-before-migration
+`
+
+const releaseRepairHelp = `
+This command settles a release left pending by an interrupted install, upgrade
+or rollback, e.g. when Helm was killed.
+
+Helm journals how far an operation got in the revision it creates. Based on
+the journal, the pending revision is marked:
+
+- deployed if the operation completed: its resources were applied, waited
+  for if requested, and its post hooks ran. The previously deployed revision
+  is superseded.
+- failed if the operation was interrupted before it completed. Its resources
+  may be partially applied, or not ready, and its post hooks may not have run.
+
+Only run this command when no operation on the release is in progress.
+
+    $ helm release repair angry-bird
+`
+
+func newReleaseCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "release",
+		Short: "manage releases",
+		Long:  releaseHelp,
+		Args:  require.NoArgs,
+	}
+	cmd.AddCommand(newReleaseMigrateApplyMethodCmd(cfg, out))
+	cmd.AddCommand(newReleaseRepairCmd(cfg, out))
+	cmd.AddCommand(newReleaseTagCmd(cfg, out))
+	return cmd
+}
+
+func newReleaseMigrateApplyMethodCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewMigrateApplyMethod(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "migrate-apply-method RELEASE_NAME",
+		Short: "switch a release from client-side to server-side apply",
+		Long:  releaseMigrateApplyMethodHelp,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if _, err := client.Run(args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Release %q now uses server-side apply\n", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newReleaseRepairCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewReleaseRepair(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "repair RELEASE_NAME",
+		Short: "settle a release left pending by an interrupted operation",
+		Long:  releaseRepairHelp,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			rel, outcome, err := client.Run(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Revision %d of release %q was %s, it is now %s\n", rel.Version, args[0], outcome, rel.Info.Status)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newReleaseTagCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewReleaseTag(cfg)
+	revision := newRevisionValue(&client.Version)
+
+	cmd := &cobra.Command{
+		Use:   "tag RELEASE_NAME TAG",
+		Short: "give a revision of a release a name",
+		Long:  releaseTagHelp,
+		Args:  require.ExactArgs(2),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
+			rel, err := client.Run(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			if client.Delete {
+				fmt.Fprintf(out, "Removed tag %q from revision %d of release %q\n", args[1], rel.Version, args[0])
+			} else {
+				fmt.Fprintf(out, "Tagged revision %d of release %q as %q\n", rel.Version, args[0], args[1])
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.Var(revision, "revision", "the revision to tag, by number or tag")
+	f.BoolVar(&client.Delete, "delete", false, "remove the tag from the release")
+	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return compListRevisions(toComplete, cfg, args[0])
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return cmd
+}
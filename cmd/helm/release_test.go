@@ -0,0 +1,152 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestReleaseMigrateApplyMethodCmd(t *testing.T) {
+	rels := []*release.Release{{
+		Name:    "funny-honey",
+		Info:    &release.Info{Status: release.StatusDeployed},
+		Chart:   &chart.Chart{},
+		Version: 1,
+	}}
+	migrated := []*release.Release{{
+		Name:        "funny-honey",
+		Info:        &release.Info{Status: release.StatusDeployed},
+		Chart:       &chart.Chart{},
+		Version:     1,
+		ApplyMethod: release.ApplyMethodServerSide,
+	}}
+
+	tests := []cmdTestCase{{
+		name:   "migrate a release to server-side apply",
+		cmd:    "release migrate-apply-method funny-honey",
+		golden: "output/release-migrate-apply-method.txt",
+		rels:   rels,
+	}, {
+		name:      "migrate a release that uses server-side apply",
+		cmd:       "release migrate-apply-method funny-honey",
+		golden:    "output/release-migrate-apply-method-migrated.txt",
+		rels:      migrated,
+		wantError: true,
+	}, {
+		name:      "migrate a release without a name",
+		cmd:       "release migrate-apply-method",
+		golden:    "output/release-migrate-apply-method-no-args.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestReleaseMigrateApplyMethodFileCompletion(t *testing.T) {
+	checkFileCompletion(t, "release migrate-apply-method", false)
+	checkFileCompletion(t, "release migrate-apply-method myrelease", false)
+}
+
+func TestReleaseRepairCmd(t *testing.T) {
+	pending := func(phases ...release.JournalPhase) []*release.Release {
+		rel := &release.Release{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusPendingUpgrade},
+			Chart:   &chart.Chart{},
+			Version: 2,
+		}
+		for _, phase := range phases {
+			rel.Journal = append(rel.Journal, release.JournalEntry{Phase: phase})
+		}
+		return []*release.Release{{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusDeployed},
+			Chart:   &chart.Chart{},
+			Version: 1,
+		}, rel}
+	}
+
+	tests := []cmdTestCase{{
+		name:   "repair a completed upgrade",
+		cmd:    "release repair funny-honey",
+		golden: "output/release-repair-completed.txt",
+		rels:   pending(release.JournalCreated, release.JournalApplying, release.JournalApplied, release.JournalCompleted),
+	}, {
+		name:   "repair a partially applied upgrade",
+		cmd:    "release repair funny-honey",
+		golden: "output/release-repair-partially-applied.txt",
+		rels:   pending(release.JournalCreated, release.JournalApplying),
+	}, {
+		name:      "repair an upgrade without a journal",
+		cmd:       "release repair funny-honey",
+		golden:    "output/release-repair-no-journal.txt",
+		rels:      pending(),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestReleaseRepairFileCompletion(t *testing.T) {
+	checkFileCompletion(t, "release repair", false)
+	checkFileCompletion(t, "release repair myrelease", false)
+}
+
+func TestReleaseTagCmd(t *testing.T) {
+	rels := []*release.Release{{
+		Name:    "funny-honey",
+		Info:    &release.Info{Status: release.StatusSuperseded},
+		Chart:   &chart.Chart{},
+		Version: 1,
+		Labels:  map[string]string{"tag.helm.sh/stable": "true"},
+	}, {
+		Name:    "funny-honey",
+		Info:    &release.Info{Status: release.StatusDeployed},
+		Chart:   &chart.Chart{},
+		Version: 2,
+	}}
+
+	tests := []cmdTestCase{{
+		name:   "tag the latest revision",
+		cmd:    "release tag funny-honey latest-good",
+		golden: "output/release-tag.txt",
+		rels:   rels,
+	}, {
+		name:   "tag a revision",
+		cmd:    "release tag funny-honey --revision 1 before-migration",
+		golden: "output/release-tag-revision.txt",
+		rels:   rels,
+	}, {
+		name:   "get the status of a tagged revision",
+		cmd:    "status funny-honey --revision stable",
+		golden: "output/release-tag-status.txt",
+		rels:   rels,
+	}, {
+		name:   "remove a tag",
+		cmd:    "release tag funny-honey stable --delete",
+		golden: "output/release-tag-delete.txt",
+		rels:   rels,
+	}, {
+		name:      "tag with an invalid name",
+		cmd:       "release tag funny-honey 12",
+		golden:    "output/release-tag-invalid.txt",
+		rels:      rels,
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
@@ -37,6 +37,12 @@ set an absolute URL to the charts, use '--url' flag.
 To merge the generated index with an existing index file, use the '--merge'
 flag. In this case, the charts found in the current directory will be merged
 into the existing index, with local charts taking priority over existing charts.
+
+Charts are read in parallel, by as many workers as CPUs unless '--workers' is
+set. To index large repositories incrementally, use '--digest-cache' along with
+'--merge': the digests of the charts are kept in the given file, and the charts
+that did not change since the previous run are not read again, their entries in
+the merged index are kept instead.
 `
 
 type repoIndexOptions struct {
@@ -44,6 +50,9 @@ type repoIndexOptions struct {
 	url   string
 	merge string
 	json  bool
+
+	digestCache string
+	workers     int
 }
 
 func newRepoIndexCmd(out io.Writer) *cobra.Command {
@@ -72,6 +81,8 @@ func newRepoIndexCmd(out io.Writer) *cobra.Command {
 	f.StringVar(&o.url, "url", "", "url of chart repository")
 	f.StringVar(&o.merge, "merge", "", "merge the generated index into the given index")
 	f.BoolVar(&o.json, "json", false, "output in JSON format")
+	f.StringVar(&o.digestCache, "digest-cache", "", "keep the digests of the charts in the given file to skip unchanged charts on the next run")
+	f.IntVar(&o.workers, "workers", 0, "number of charts read in parallel (default: number of CPUs)")
 
 	return cmd
 }
@@ -82,19 +93,27 @@ func (i *repoIndexOptions) run(_ io.Writer) error {
 		return err
 	}
 
-	return index(path, i.url, i.merge, i.json)
+	opts := repo.IndexOptions{BaseURL: i.url, Workers: i.workers}
+	if i.digestCache != "" {
+		if opts.Cache, err = repo.LoadDigestCache(i.digestCache); err != nil {
+			return errors.Wrap(err, "failed to load the digest cache")
+		}
+	}
+	if err := index(path, i.merge, i.json, opts); err != nil {
+		return err
+	}
+	if opts.Cache != nil {
+		return opts.Cache.WriteFile(i.digestCache, 0644)
+	}
+	return nil
 }
 
-func index(dir, url, mergeTo string, json bool) error {
+func index(dir, mergeTo string, json bool, opts repo.IndexOptions) error {
 	out := filepath.Join(dir, "index.yaml")
 
-	i, err := repo.IndexDirectory(dir, url)
-	if err != nil {
-		return err
-	}
+	var i2 *repo.IndexFile
 	if mergeTo != "" {
 		// if index.yaml is missing then create an empty one to merge into
-		var i2 *repo.IndexFile
 		if _, err := os.Stat(mergeTo); os.IsNotExist(err) {
 			i2 = repo.NewIndexFile()
 			writeIndexFile(i2, mergeTo, json)
@@ -104,6 +123,14 @@ func index(dir, url, mergeTo string, json bool) error {
 				return errors.Wrap(err, "merge failed")
 			}
 		}
+		opts.Previous = i2
+	}
+
+	i, err := repo.IndexDirectoryWithOptions(dir, opts)
+	if err != nil {
+		return err
+	}
+	if i2 != nil {
 		i.Merge(i2)
 	}
 	i.SortEntries()
@@ -162,6 +162,45 @@ func TestRepoIndexCmd(t *testing.T) {
 	}
 }
 
+func TestRepoIndexCmdDigestCache(t *testing.T) {
+	dir := t.TempDir()
+	if err := linkOrCopy("testdata/testcharts/compressedchart-0.1.0.tgz", filepath.Join(dir, "compressedchart-0.1.0.tgz")); err != nil {
+		t.Fatal(err)
+	}
+	destIndex := filepath.Join(dir, "index.yaml")
+	cache := filepath.Join(t.TempDir(), "digests.json")
+
+	c := newRepoIndexCmd(io.Discard)
+	c.ParseFlags([]string{"--merge", destIndex, "--digest-cache", cache, "--workers", "2"})
+	if err := c.RunE(c, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cache); err != nil {
+		t.Fatalf("expected the digest cache to be written: %s", err)
+	}
+
+	if err := linkOrCopy("testdata/testcharts/compressedchart-0.2.0.tgz", filepath.Join(dir, "compressedchart-0.2.0.tgz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RunE(c, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := repo.LoadIndexFile(destIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs := index.Entries["compressedchart"]
+	if len(vs) != 2 {
+		t.Fatalf("expected 2 versions, got %d: %#v", len(vs), vs)
+	}
+	for _, v := range vs {
+		if v.Digest == "" {
+			t.Errorf("expected version %s to have a digest", v.Version)
+		}
+	}
+}
+
 func linkOrCopy(old, new string) error {
 	if err := os.Link(old, new); err != nil {
 		return copyFile(old, new)
@@ -0,0 +1,243 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/internal/tlsutil"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const repoPublishDesc = `
+Publish packaged charts to a chart repository.
+
+The repository is given with '--repo', either as the name of a repository
+added with 'helm repo add' or as a URL. How the charts are published depends
+on the repository:
+
+- With '--git DIR', the charts are committed to DIR, a clone of the git
+  repository the chart repository is served from (e.g. the gh-pages branch of a
+  GitHub Pages site), along with their entries in index.yaml, and the commit is
+  pushed unless '--no-push' is set. The chart URLs in the index are relative to
+  the URL of the repository.
+
+- An HTTP(S) repository is expected to have a ChartMuseum compatible upload API,
+  which maintains index.yaml on the server.
+
+- For other URL schemes, e.g. s3://, the charts and index.yaml are uploaded with
+  the 'upload' command of the downloader plugin for the scheme. The repository
+  must already have an index.yaml.
+
+When Helm maintains index.yaml, the published charts are merged into the current
+index, and the merge is retried if another publisher modified it concurrently.
+A provenance file next to a chart, such as the one 'helm package --sign'
+creates, is published with it.
+
+    $ helm repo publish mychart-0.1.0.tgz --repo https://charts.example.com
+    $ helm repo publish mychart-0.1.0.tgz --repo https://example.github.io/charts --git ./gh-pages
+`
+
+type repoPublishOptions struct {
+	charts []string
+	repo   string
+	gitDir string
+	noPush bool
+	force  bool
+
+	username              string
+	password              string
+	certFile              string
+	keyFile               string
+	caFile                string
+	insecureSkipTLSverify bool
+
+	repoFile string
+}
+
+func newRepoPublishCmd(out io.Writer) *cobra.Command {
+	o := &repoPublishOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "publish [CHART...]",
+		Short: "publish packaged charts to a chart repository",
+		Long:  repoPublishDesc,
+		Args:  require.MinimumNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+			// Allow file completion when completing the charts
+			return nil, cobra.ShellCompDirectiveDefault
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			o.charts = args
+			o.repoFile = settings.RepositoryConfig
+			return o.run(out)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&o.repo, "repo", "", "name or URL of the chart repository")
+	f.StringVar(&o.gitDir, "git", "", "publish by committing to this clone of the git repository the chart repository is served from")
+	f.BoolVar(&o.noPush, "no-push", false, "with --git, commit the charts without pulling or pushing")
+	f.BoolVar(&o.force, "force", false, "replace chart versions that were already published")
+	f.StringVar(&o.username, "username", "", "chart repository username")
+	f.StringVar(&o.password, "password", "", "chart repository password")
+	f.StringVar(&o.certFile, "cert-file", "", "identify HTTPS client using this SSL certificate file")
+	f.StringVar(&o.keyFile, "key-file", "", "identify HTTPS client using this SSL key file")
+	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the repository")
+	cmd.MarkFlagRequired("repo")
+
+	return cmd
+}
+
+func (o *repoPublishOptions) run(out io.Writer) error {
+	if err := o.resolveRepo(); err != nil {
+		return err
+	}
+	publisher, err := o.publisher()
+	if err != nil {
+		return err
+	}
+	for _, c := range o.charts {
+		if err := publisher.Publish(c); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Published %s to %s\n", filepath.Base(c), o.repo)
+	}
+	return nil
+}
+
+// resolveRepo replaces the name of a repository by its URL and fills the
+// credentials that were not given from its entry in the repositories file.
+func (o *repoPublishOptions) resolveRepo() error {
+	f, err := repo.LoadFile(o.repoFile)
+	if err != nil && !isNotExist(err) {
+		return err
+	}
+	if f == nil {
+		return nil
+	}
+	entry := f.Get(o.repo)
+	if entry == nil {
+		for _, e := range f.Repositories {
+			if strings.TrimSuffix(e.URL, "/") == strings.TrimSuffix(o.repo, "/") {
+				entry = e
+			}
+		}
+	}
+	if entry == nil {
+		return nil
+	}
+	o.repo = entry.URL
+	if o.username == "" && o.password == "" {
+		o.username, o.password = entry.Username, entry.Password
+	}
+	if o.certFile == "" && o.keyFile == "" && o.caFile == "" {
+		o.certFile, o.keyFile, o.caFile = entry.CertFile, entry.KeyFile, entry.CAFile
+	}
+	o.insecureSkipTLSverify = o.insecureSkipTLSverify || entry.InsecureSkipTLSverify
+	return nil
+}
+
+func (o *repoPublishOptions) publisher() (repo.Publisher, error) {
+	if o.gitDir != "" {
+		return &repo.GitPublisher{Dir: o.gitDir, URL: o.repo, Force: o.force, Push: !o.noPush}, nil
+	}
+
+	u, err := url.Parse(o.repo)
+	if err != nil || u.Scheme == "" {
+		return nil, errors.Errorf("%q is neither a repository name nor a URL", o.repo)
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		tlsConf, err := tlsutil.NewClientTLS(o.certFile, o.keyFile, o.caFile, o.insecureSkipTLSverify)
+		if err != nil {
+			return nil, err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConf
+		return &repo.ChartMuseumPublisher{
+			URL:      o.repo,
+			Username: o.username,
+			Password: o.password,
+			Force:    o.force,
+			Client:   &http.Client{Transport: transport},
+		}, nil
+	}
+
+	upload, env, err := findUploader(u.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	g, err := getter.All(settings).ByScheme(u.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	backend := &repo.CommandBackend{
+		URL:    o.repo,
+		Getter: g,
+		Options: []getter.Option{
+			getter.WithBasicAuth(o.username, o.password),
+			getter.WithTLSClientConfig(o.certFile, o.keyFile, o.caFile),
+			getter.WithInsecureSkipVerifyTLS(o.insecureSkipTLSverify),
+		},
+		Upload: upload,
+		Env:    env,
+	}
+	return &repo.IndexPublisher{
+		Backend: backend,
+		URL:     o.repo,
+		Force:   o.force,
+	}, nil
+}
+
+// findUploader returns the upload command, and its environment, of the
+// downloader plugin for the URL scheme.
+func findUploader(scheme string) ([]string, []string, error) {
+	plugins, err := plugin.FindPlugins(settings.PluginsDirectory)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range plugins {
+		for _, d := range p.Metadata.Downloaders {
+			for _, protocol := range d.Protocols {
+				if protocol != scheme {
+					continue
+				}
+				if d.Upload == "" {
+					return nil, nil, errors.Errorf("the %s plugin for %s:// repositories cannot upload charts", p.Metadata.Name, scheme)
+				}
+				plugin.SetupPluginEnv(settings, p.Metadata.Name, p.Dir)
+				command := strings.Fields(d.Upload)
+				command[0] = filepath.Join(p.Dir, command[0])
+				return command, p.Environ(os.Environ(), settings.PluginSandbox), nil
+			}
+		}
+	}
+	return nil, nil, errors.Errorf("no plugin can publish to %s:// repositories", scheme)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestRepoPublishCmd(t *testing.T) {
+	var uploaded []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); r.URL.Path != "/api/charts" || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, header, err := r.FormFile("chart")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		uploaded = append(uploaded, header.Filename)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	// The URL and credentials of a repository are found by its name.
+	repoFile := filepath.Join(t.TempDir(), "repositories.yaml")
+	f := repo.NewFile()
+	f.Update(&repo.Entry{Name: "museum", URL: srv.URL, Username: "user", Password: "pass"})
+	if err := f.WriteFile(repoFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(repoFile string) { settings.RepositoryConfig = repoFile }(settings.RepositoryConfig)
+	settings.RepositoryConfig = repoFile
+
+	buf := bytes.NewBuffer(nil)
+	c := newRepoPublishCmd(buf)
+	if err := c.Flags().Set("repo", "museum"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RunE(c, []string{"testdata/testcharts/compressedchart-0.1.0.tgz", "testdata/testcharts/compressedchart-0.2.0.tgz"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(uploaded, ",") != "compressedchart-0.1.0.tgz,compressedchart-0.2.0.tgz" {
+		t.Errorf("unexpected uploaded charts %v", uploaded)
+	}
+	if !strings.Contains(buf.String(), "Published compressedchart-0.2.0.tgz to "+srv.URL) {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestRepoPublishCmdUnknownScheme(t *testing.T) {
+	defer func(repoFile, pluginsDir string) {
+		settings.RepositoryConfig, settings.PluginsDirectory = repoFile, pluginsDir
+	}(settings.RepositoryConfig, settings.PluginsDirectory)
+	settings.RepositoryConfig = filepath.Join(t.TempDir(), "repositories.yaml")
+	settings.PluginsDirectory = t.TempDir()
+
+	c := newRepoPublishCmd(bytes.NewBuffer(nil))
+	if err := c.Flags().Set("repo", "s3://bucket/charts"); err != nil {
+		t.Fatal(err)
+	}
+	err := c.RunE(c, []string{"testdata/testcharts/compressedchart-0.1.0.tgz"})
+	if err == nil || !strings.Contains(err.Error(), "no plugin can publish to s3:// repositories") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
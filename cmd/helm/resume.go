@@ -0,0 +1,68 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const resumeDesc = `
+This command scales the workloads of a release suspended by 'helm suspend'
+back to the replicas they ran before.
+
+Replicas are kept within the minimum and maximum replicas of the
+HorizontalPodAutoscalers of the release, which resume scaling the workloads.
+
+    $ helm resume angry-bird --wait
+`
+
+func newResumeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewResume(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "resume RELEASE_NAME",
+		Short: "scale the workloads of a suspended release back up",
+		Long:  resumeDesc,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if _, err := client.Run(args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Release %q has been resumed\n", args[0])
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&client.Wait, "wait", false, "if set, will wait until the workloads of the release are ready. It will wait for as long as --timeout")
+	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for the workloads to become ready")
+
+	return cmd
+}
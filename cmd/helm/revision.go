@@ -0,0 +1,73 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strconv"
+
+	"helm.sh/helm/v3/pkg/action"
+)
+
+// revisionValue is the value of a flag giving a revision of a release by
+// number or by tag. Numbers are set right away, tags once the release is
+// known, by resolve.
+type revisionValue struct {
+	version *int
+	tag     string
+}
+
+func newRevisionValue(version *int) *revisionValue {
+	return &revisionValue{version: version}
+}
+
+func (v *revisionValue) String() string {
+	if v.tag != "" {
+		return v.tag
+	}
+	if *v.version == 0 {
+		return ""
+	}
+	return strconv.Itoa(*v.version)
+}
+
+func (v *revisionValue) Set(s string) error {
+	if version, err := strconv.Atoi(s); err == nil {
+		*v.version = version
+		v.tag = ""
+		return nil
+	}
+	v.tag = s
+	return nil
+}
+
+func (v *revisionValue) Type() string {
+	return "revision"
+}
+
+// resolve sets the version to the revision of the release with the tag of
+// the flag, if the revision was given by tag.
+func (v *revisionValue) resolve(cfg *action.Configuration, name string) error {
+	if v.tag == "" {
+		return nil
+	}
+	version, err := action.ResolveRevision(cfg, name, v.tag)
+	if err != nil {
+		return err
+	}
+	*v.version = version
+	return nil
+}
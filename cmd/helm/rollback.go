@@ -19,7 +19,6 @@ package main
 import (
 	"fmt"
 	"io"
-	"strconv"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -32,10 +31,11 @@ const rollbackDesc = `
 This command rolls back a release to a previous revision.
 
 The first argument of the rollback command is the name of a release, and the
-second is a revision (version) number. If this argument is omitted or set to
-0, it will roll back to the previous release.
+second is a revision (version) number, or a tag given to a revision with
+'helm release tag'. If this argument is omitted or set to 0, it will roll back
+to the previous release.
 
-To see revision numbers, run 'helm history RELEASE'.
+To see revision numbers and tags, run 'helm history RELEASE'.
 `
 
 func newRollbackCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -59,9 +59,9 @@ func newRollbackCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
 			if len(args) > 1 {
-				ver, err := strconv.Atoi(args[1])
+				ver, err := action.ResolveRevision(cfg, args[0], args[1])
 				if err != nil {
-					return fmt.Errorf("could not convert revision to a number: %v", err)
+					return err
 				}
 				client.Version = ver
 			}
@@ -84,6 +84,9 @@ func newRollbackCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Wait, "wait", false, "if set, will wait until all Pods, PVCs, Services, and minimum number of Pods of a Deployment, StatefulSet, or ReplicaSet are in a ready state before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.CleanupOnFail, "cleanup-on-fail", false, "allow deletion of new resources created in this rollback when rollback fails")
+	f.BoolVar(&client.OverrideFreeze, "override-freeze", false, "proceed during an active freeze window of the freeze policy, recording the override in the audit log")
+	f.BoolVar(&client.LabelResources, "label-resources", false, "stamp all resources with labels for the release name, revision, chart and manager, so that they can be selected with 'kubectl get -l app.kubernetes.io/instance=RELEASE'")
+	f.StringVar(&client.DeployedBy, "deployed-by", "", "record who performs the operation, e.g. a person or a pipeline, in the release history")
 	f.IntVar(&client.MaxHistory, "history-max", settings.MaxHistory, "limit the maximum number of revisions saved per release. Use 0 for no limit")
 
 	return cmd
@@ -41,11 +41,33 @@ func TestRollbackCmd(t *testing.T) {
 		},
 	}
 
+	tagged := []*release.Release{
+		{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusSuperseded},
+			Chart:   &chart.Chart{},
+			Version: 1,
+			Labels:  map[string]string{"tag.helm.sh/stable": "true"},
+		},
+		rels[1],
+	}
+
 	tests := []cmdTestCase{{
 		name:   "rollback a release",
 		cmd:    "rollback funny-honey 1",
 		golden: "output/rollback.txt",
 		rels:   rels,
+	}, {
+		name:   "rollback a release to a tagged revision",
+		cmd:    "rollback funny-honey stable",
+		golden: "output/rollback.txt",
+		rels:   tagged,
+	}, {
+		name:      "rollback a release to an unknown tag",
+		cmd:       "rollback funny-honey stable",
+		golden:    "output/rollback-unknown-tag.txt",
+		rels:      rels,
+		wantError: true,
 	}, {
 		name:   "rollback a release with timeout",
 		cmd:    "rollback funny-honey 1 --timeout 120s",
@@ -47,19 +47,46 @@ Environment variables:
 
 | Name                               | Description                                                                                                |
 |------------------------------------|------------------------------------------------------------------------------------------------------------|
+| $HELM_AUDIT_LOG                    | record operations changing releases in an audit log: file:<path>, configmap, secret or sql.                |
 | $HELM_CACHE_HOME                   | set an alternative location for storing cached files.                                                      |
 | $HELM_CONFIG_HOME                  | set an alternative location for storing Helm configuration.                                                |
 | $HELM_DATA_HOME                    | set an alternative location for storing Helm data.                                                         |
 | $HELM_DEBUG                        | indicate whether or not Helm is running in Debug mode                                                      |
-| $HELM_DRIVER                       | set the backend storage driver. Values are: configmap, secret, memory, sql.                                |
+| $HELM_DRIVER                       | set the backend storage driver. Values are: configmap, secret, memory, sql, helmrelease.                   |
 | $HELM_DRIVER_SQL_CONNECTION_STRING | set the connection string the SQL storage driver should use.                                               |
+| $HELM_DRIVER_SQL_AUTH              | set how the SQL storage driver gets its password: aws-rds-iam, exec:<command> or file:<path>.              |
+| $HELM_DRIVER_SQL_AUTH_REFRESH      | set how long the SQL storage driver reuses a generated password (default 10m).                             |
+| $HELM_DRIVER_SENSITIVE_SECRETS     | keep sensitive values in Secrets with the configmap, sql and helmrelease drivers (default false).          |
+| $HELM_ERROR_FORMAT                 | set the format errors are printed in: text, or json with the code of the error (default text).             |
+| $HELM_EXTERNAL_HOOK_COMMANDS       | set the comma-separated patterns of the commands hooks may run on this machine, e.g. /opt/hooks/*.         |
+| $HELM_EXTERNAL_HOOK_HOSTS          | set the comma-separated patterns of the hosts hooks may send requests to from this machine.                |
+| $HELM_FREEZE_POLICY                | set the file, or ConfigMap as configmap:<namespace>/<name>, defining freeze windows for releases.          |
+| $HELM_HOOK_IMAGE_POLICY            | set the path to the file defining who must have signed the container images of hooks.                      |
+| $HELM_HOOK_LOGS                    | print the logs of the containers of hooks running pods to stderr while they run (default false).           |
+| $HELM_HOOK_PARALLELISM             | set how many hooks of the same weight run at the same time (default 1).                                    |
+| $HELM_HOOK_SERVICE_ACCOUNT         | run the pods of hooks as a temporary ServiceAccount granted the hook permissions of the chart.             |
+| $HELM_KIND_ORDER                   | set the path to the file placing kinds in the order in which resources are installed.                      |
 | $HELM_MAX_HISTORY                  | set the maximum number of helm release history.                                                            |
+| $HELM_MAX_INCLUDE_DEPTH            | set how deeply include and tpl calls may nest when rendering templates (default 1000).                     |
+| $HELM_MAX_MANIFEST_SIZE            | set the size in bytes above which manifests are stored apart from release records (default 0: no limit).   |
+| $HELM_MAX_NOTES_SIZE               | set the size in bytes above which notes are stored apart from release records (default 0: no limit).        |
+| $HELM_MESSAGES                     | set the path to a file translating the messages of errors by code.                                         |
+| $HELM_METADATA_POLICY              | set the path to the file defining how labels and annotations of resources are normalized.                  |
+| $HELM_NAME_POLICY                  | set the path to the file defining the prefix, maximum length and pattern of the names of new releases.     |
 | $HELM_NAMESPACE                    | set the namespace used for the helm operations.                                                            |
 | $HELM_NO_PLUGINS                   | disable plugins. Set HELM_NO_PLUGINS=1 to disable plugins.                                                 |
+| $HELM_PLUGIN_SANDBOX               | run all plugins with a minimal environment and a scratch home directory (default false).                   |
 | $HELM_PLUGINS                      | set the path to the plugins directory                                                                      |
+| $HELM_PROFILE                      | capture profiles of the render, apply and wait phases of actions: cpu, mem or trace.                       |
+| $HELM_PROFILE_DIR                  | set the directory profiles are written to (default the current directory).                                 |
+| $HELM_REDACT_PATTERNS              | set the comma-separated regular expressions matching the keys of values that are secrets.                  |
 | $HELM_REGISTRY_CONFIG              | set the path to the registry config file.                                                                  |
 | $HELM_REPOSITORY_CACHE             | set the path to the repository cache directory                                                             |
 | $HELM_REPOSITORY_CONFIG            | set the path to the repositories file.                                                                     |
+| $HELM_SHOW_SECRETS                 | show Secret data and secret values in dry-run output, debug logs and audit log errors.                     |
+| $HELM_TEMPLATE_TIMEOUT             | set the time to wait for a single template to render, e.g. 30s (default 0, no limit).                      |
+| $HELM_TRUST_POLICY                 | set the path to the file defining how charts must be verified per repository or registry.                  |
+| $HELM_WEBHOOKS_CONFIG              | set the path to the file configuring the webhooks notified of release events.                              |
 | $KUBECONFIG                        | set an alternative Kubernetes configuration file (default "~/.kube/config")                                |
 | $HELM_KUBEAPISERVER                | set the Kubernetes API Server Endpoint for authentication                                                  |
 | $HELM_KUBECAFILE                   | set the Kubernetes certificate authority file.                                                             |
@@ -71,6 +98,7 @@ Environment variables:
 | $HELM_KUBETLS_SERVER_NAME          | set the server name used to validate the Kubernetes API server certificate                                 |
 | $HELM_BURST_LIMIT                  | set the default burst limit in the case the server contains many CRDs (default 100, -1 to disable)         |
 | $HELM_QPS                          | set the Queries Per Second in cases where a high number of calls exceed the option for higher burst values |
+| $HELM_WAIT_STATUS_MAPPINGS         | set the file or ConfigMap (configmap:<namespace>/<name>) defining readiness of custom resources on wait    |
 
 Helm stores cache, configuration, and data based on the following configuration order:
 
@@ -163,6 +191,7 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 	cmd.AddCommand(
 		// chart commands
 		newCreateCmd(out),
+		newConvertCmd(out),
 		newDependencyCmd(actionConfig, out),
 		newPullCmd(actionConfig, out),
 		newShowCmd(actionConfig, out),
@@ -173,13 +202,18 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 		newVerifyCmd(out),
 
 		// release commands
+		newAuditCmd(actionConfig, out),
+		newCapabilitiesCmd(actionConfig, out),
 		newGetCmd(actionConfig, out),
 		newHistoryCmd(actionConfig, out),
 		newInstallCmd(actionConfig, out),
 		newListCmd(actionConfig, out),
+		newReleaseCmd(actionConfig, out),
 		newReleaseTestCmd(actionConfig, out),
+		newResumeCmd(actionConfig, out),
 		newRollbackCmd(actionConfig, out),
 		newStatusCmd(actionConfig, out),
+		newSuspendCmd(actionConfig, out),
 		newTemplateCmd(actionConfig, out),
 		newUninstallCmd(actionConfig, out),
 		newUpgradeCmd(actionConfig, out),
@@ -25,8 +25,9 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
-	"helm.sh/helm/v3/internal/monocular"
+	"helm.sh/helm/v3/internal/hub"
 	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/helmpath"
 )
 
 const searchHubDesc = `
@@ -47,14 +48,41 @@ Monocular search API. Similarly, when setting the 'endpoint' flag, the specified
 endpoint must also be implement a Monocular compatible search API endpoint.
 Note that when specifying a Monocular instance as the 'endpoint', rich queries
 are not supported. For API details, see https://github.com/helm/monocular
+
+Other hubs are searched by setting the '--provider' flag: 'artifacthub' uses the
+native API of the Artifact Hub, which can also filter the charts by license,
+signature and verified publisher, and 'harbor' searches the charts of a Harbor
+registry. Hubs, including their credentials, can be named in the hubs file and
+selected with the '--hub' flag:
+
+    hubs:
+    - name: internal
+      provider: harbor
+      endpoint: https://harbor.example.com
+      username: me
+      password: secret
+    - name: artifacthub
+      provider: artifacthub
+      headers:
+        X-API-KEY-ID: <id>
+        X-API-KEY-SECRET: <secret>
+
+Results are processed as the hub returns them, so '--max-results' ends the search
+as soon as enough charts have been found.
 `
 
 type searchHubOptions struct {
 	searchEndpoint string
+	provider       string
+	hubName        string
+	hubsFile       string
+	token          string
 	maxColWidth    uint
+	maxResults     int
 	outputFormat   output.Format
 	listRepoURL    bool
 	failOnNoResult bool
+	query          hub.Query
 }
 
 func newSearchHubCmd(out io.Writer) *cobra.Command {
@@ -64,13 +92,21 @@ func newSearchHubCmd(out io.Writer) *cobra.Command {
 		Use:   "hub [KEYWORD]",
 		Short: "search for charts in the Artifact Hub or your own hub instance",
 		Long:  searchHubDesc,
-		RunE: func(_ *cobra.Command, args []string) error {
-			return o.run(out, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(cmd, out, args)
 		},
 	}
 
 	f := cmd.Flags()
-	f.StringVar(&o.searchEndpoint, "endpoint", "https://hub.helm.sh", "Hub instance to query for charts")
+	f.StringVar(&o.searchEndpoint, "endpoint", hub.DefaultEndpoints[hub.ProviderMonocular], "Hub instance to query for charts")
+	f.StringVar(&o.provider, "provider", hub.ProviderMonocular, fmt.Sprintf("API of the hub, one of: %s", strings.Join(hub.Providers, ", ")))
+	f.StringVar(&o.hubName, "hub", "", "name of the hub in the hubs file to query for charts")
+	f.StringVar(&o.hubsFile, "hubs-file", helmpath.ConfigPath("hubs.yaml"), "path to the file containing the named hubs")
+	f.StringVar(&o.token, "token", "", "bearer token to authenticate to the hub")
+	f.StringVar(&o.query.License, "license", "", "only show charts with the given SPDX license identifier")
+	f.BoolVar(&o.query.SignedOnly, "signed-only", false, "only show signed charts")
+	f.BoolVar(&o.query.VerifiedPublisherOnly, "verified-publisher", false, "only show charts of verified publishers")
+	f.IntVar(&o.maxResults, "max-results", 0, "stop searching after the given number of charts, 0 for no limit")
 	f.UintVar(&o.maxColWidth, "max-col-width", 50, "maximum column width for output table")
 	f.BoolVar(&o.listRepoURL, "list-repo-url", false, "print charts repository URL")
 	f.BoolVar(&o.failOnNoResult, "fail-on-no-result", false, "search fails if no results are found")
@@ -80,33 +116,70 @@ func newSearchHubCmd(out io.Writer) *cobra.Command {
 	return cmd
 }
 
-func (o *searchHubOptions) run(out io.Writer, args []string) error {
-	c, err := monocular.New(o.searchEndpoint)
+func (o *searchHubOptions) run(cmd *cobra.Command, out io.Writer, args []string) error {
+	cfg, err := o.hubConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	p, err := hub.New(cfg)
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("unable to create connection to %q", o.searchEndpoint))
+		return errors.Wrap(err, fmt.Sprintf("unable to create connection to %q", cfg.Endpoint))
 	}
 
-	q := strings.Join(args, " ")
-	results, err := c.Search(q)
+	o.query.Term = strings.Join(args, " ")
+	var results []hub.Chart
+	err = p.Search(o.query, func(c hub.Chart) error {
+		results = append(results, c)
+		if o.maxResults > 0 && len(results) >= o.maxResults {
+			return hub.ErrStop
+		}
+		return nil
+	})
 	if err != nil {
 		debug("%s", err)
-		return fmt.Errorf("unable to perform search against %q", o.searchEndpoint)
+		return fmt.Errorf("unable to perform search against %q: %s", cfg.Endpoint, err)
 	}
 
-	return o.outputFormat.Write(out, newHubSearchWriter(results, o.searchEndpoint, o.maxColWidth, o.listRepoURL, o.failOnNoResult))
+	return o.outputFormat.Write(out, newHubSearchWriter(results, o.maxColWidth, o.listRepoURL, o.failOnNoResult))
 }
 
-type hubChartRepo struct {
-	URL  string `json:"url"`
-	Name string `json:"name"`
+// hubConfig returns the hub to search, which is the named hub of the hubs
+// file if one is selected. Flags set on the command line take precedence.
+func (o *searchHubOptions) hubConfig(cmd *cobra.Command) (hub.Config, error) {
+	cfg := hub.Config{Provider: o.provider}
+	if o.hubName != "" {
+		f, err := hub.LoadFile(o.hubsFile)
+		if err != nil {
+			return cfg, err
+		}
+		if cfg, err = f.Get(o.hubName); err != nil {
+			return cfg, err
+		}
+		if cmd.Flags().Changed("provider") {
+			cfg.Provider = o.provider
+		}
+	}
+	// The default endpoint only applies to the monocular provider; the
+	// others use their own default.
+	if cmd.Flags().Changed("endpoint") || (o.hubName == "" && (cfg.Provider == "" || cfg.Provider == hub.ProviderMonocular)) {
+		cfg.Endpoint = o.searchEndpoint
+	}
+	if o.token != "" {
+		cfg.Token = o.token
+	}
+	return cfg, nil
 }
 
 type hubChartElement struct {
-	URL         string       `json:"url"`
-	Version     string       `json:"version"`
-	AppVersion  string       `json:"app_version"`
-	Description string       `json:"description"`
-	Repository  hubChartRepo `json:"repository"`
+	URL               string         `json:"url"`
+	Version           string         `json:"version"`
+	AppVersion        string         `json:"app_version"`
+	Description       string         `json:"description"`
+	License           string         `json:"license,omitempty"`
+	Signed            bool           `json:"signed,omitempty"`
+	VerifiedPublisher bool           `json:"verified_publisher,omitempty"`
+	Repository        hub.Repository `json:"repository"`
 }
 
 type hubSearchWriter struct {
@@ -116,18 +189,10 @@ type hubSearchWriter struct {
 	failOnNoResult bool
 }
 
-func newHubSearchWriter(results []monocular.SearchResult, endpoint string, columnWidth uint, listRepoURL, failOnNoResult bool) *hubSearchWriter {
+func newHubSearchWriter(results []hub.Chart, columnWidth uint, listRepoURL, failOnNoResult bool) *hubSearchWriter {
 	var elements []hubChartElement
 	for _, r := range results {
-		// Backwards compatibility for Monocular
-		url := endpoint + "/charts/" + r.ID
-
-		// Check for artifactHub compatibility
-		if r.ArtifactHub.PackageURL != "" {
-			url = r.ArtifactHub.PackageURL
-		}
-
-		elements = append(elements, hubChartElement{url, r.Relationships.LatestChartVersion.Data.Version, r.Relationships.LatestChartVersion.Data.AppVersion, r.Attributes.Description, hubChartRepo{URL: r.Attributes.Repo.URL, Name: r.Attributes.Repo.Name}})
+		elements = append(elements, hubChartElement{r.URL, r.Version, r.AppVersion, r.Description, r.License, r.Signed, r.VerifiedPublisher, r.Repository})
 	}
 	return &hubSearchWriter{elements, columnWidth, listRepoURL, failOnNoResult}
 }
@@ -182,7 +247,7 @@ func (h *hubSearchWriter) encodeByFormat(out io.Writer, format output.Format) er
 	chartList := make([]hubChartElement, 0, len(h.elements))
 
 	for _, r := range h.elements {
-		chartList = append(chartList, hubChartElement{r.URL, r.Version, r.AppVersion, r.Description, r.Repository})
+		chartList = append(chartList, r)
 	}
 
 	switch format {
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -185,3 +187,44 @@ func TestSearchHubCmd_FailOnNoResponseTests(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchHubProviderCmd(t *testing.T) {
+	// Setup a mock Artifact Hub returning the signed and unsigned versions
+	// of a chart
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("license") != "MIT" {
+			t.Errorf("expected the license to be filtered by the hub, got %q", r.URL.RawQuery)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("expected the token of the hub, got %q", got)
+		}
+		fmt.Fprintln(w, `{"packages":[{"name":"mariadb","normalized_name":"mariadb","version":"1.0.0","app_version":"10.5","description":"MariaDB","license":"MIT","signed":true,"repository":{"name":"example","url":"https://charts.example.com"}},{"name":"mariadb-unsigned","normalized_name":"mariadb-unsigned","version":"2.0.0","license":"MIT","repository":{"name":"example","url":"https://charts.example.com"}}]}`)
+	}))
+	defer ts.Close()
+
+	hubsFile := filepath.Join(t.TempDir(), "hubs.yaml")
+	hubs := fmt.Sprintf("hubs:\n- name: example\n  provider: artifacthub\n  endpoint: %s\n  token: secret\n", ts.URL)
+	if err := os.WriteFile(hubsFile, []byte(hubs), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprintf(`[{"url":"%s/packages/helm/example/mariadb","version":"1.0.0","app_version":"10.5","description":"MariaDB","license":"MIT","signed":true,"repository":{"url":"https://charts.example.com","name":"example"}}]
+`, ts.URL)
+
+	for _, cmd := range []string{
+		"search hub --provider artifacthub --endpoint " + ts.URL + " --token secret --license MIT --signed-only -o json maria",
+		"search hub --hub example --hubs-file " + hubsFile + " --license MIT --max-results 1 -o json maria",
+	} {
+		_, out, err := executeActionCommandC(storageFixture(), cmd)
+		if err != nil {
+			t.Fatalf("unexpected error, %s", err)
+		}
+		if out != expected {
+			t.Errorf("expected and actual output did not match\nexpected: %q\nactual  : %q", expected, out)
+		}
+	}
+
+	if _, _, err := executeActionCommandC(storageFixture(), "search hub --endpoint "+ts.URL+" --signed-only maria"); err == nil {
+		t.Error("expected an error filtering the monocular provider by signature")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/gosuri/uitable"
@@ -31,6 +32,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/search"
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/repo"
@@ -204,6 +206,9 @@ type repoChartElement struct {
 	Version     string `json:"version"`
 	AppVersion  string `json:"app_version"`
 	Description string `json:"description"`
+	// Deprecation is set for deprecated charts and charts with an end-of-life
+	// date.
+	Deprecation *chart.Deprecation `json:"deprecation,omitempty"`
 }
 
 type repoSearchWriter struct {
@@ -228,8 +233,15 @@ func (r *repoSearchWriter) WriteTable(out io.Writer) error {
 	table := uitable.New()
 	table.MaxColWidth = r.columnWidth
 	table.AddRow("NAME", "CHART VERSION", "APP VERSION", "DESCRIPTION")
+	now := time.Now()
 	for _, r := range r.results {
-		table.AddRow(r.Name, r.Chart.Version, r.Chart.AppVersion, r.Chart.Description)
+		version := r.Chart.Version
+		if d := r.Chart.Metadata.Deprecation(); d.EndOfLifeReached(now) {
+			version += " (end of life)"
+		} else if d != nil && d.Deprecated {
+			version += " (deprecated)"
+		}
+		table.AddRow(r.Name, version, r.Chart.AppVersion, r.Chart.Description)
 	}
 	return output.EncodeTable(out, table)
 }
@@ -252,7 +264,7 @@ func (r *repoSearchWriter) encodeByFormat(out io.Writer, format output.Format) e
 	chartList := make([]repoChartElement, 0, len(r.results))
 
 	for _, r := range r.results {
-		chartList = append(chartList, repoChartElement{r.Name, r.Chart.Version, r.Chart.AppVersion, r.Chart.Description})
+		chartList = append(chartList, repoChartElement{r.Name, r.Chart.Version, r.Chart.AppVersion, r.Chart.Description, r.Chart.Metadata.Deprecation()})
 	}
 
 	switch format {
@@ -20,10 +20,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/internal/markdown"
 	"helm.sh/helm/v3/pkg/action"
 )
 
@@ -39,6 +42,11 @@ This command inspects a chart (directory, file, or URL) and displays all its con
 const showValuesDesc = `
 This command inspects a chart (directory, file, or URL) and displays the contents
 of the values.yaml file
+
+With '--generate-skeleton', a values file is generated from the values.schema.json
+of the chart instead. Each value is commented with its description, type and
+whether it is required, and set to its default. Optional values without a default
+are commented out, giving a starting point for overrides.
 `
 
 const showChartDesc = `
@@ -49,6 +57,14 @@ of the Chart.yaml file
 const readmeChartDesc = `
 This command inspects a chart (directory, file, or URL) and displays the contents
 of the README file
+
+When writing to a terminal, the Markdown of the README is rendered: headings,
+emphasis and code are highlighted, tables are aligned and paragraphs are wrapped
+to the width of the terminal. Use '--plain' to show the Markdown as it is.
+
+The '--section' flag only shows the section under the given heading, for example
+'--section Configuration', and '--values-table' only shows the tables describing
+the values of the chart.
 `
 
 const showCRDsDesc = `
@@ -139,6 +155,7 @@ func newShowCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		},
 	}
 
+	var plain bool
 	readmeSubCmd := &cobra.Command{
 		Use:               "readme [CHART]",
 		Short:             "show the chart's README",
@@ -155,10 +172,16 @@ func newShowCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if width, ok := terminalWidth(out); ok && !plain {
+				output = markdown.Render([]byte(output), width)
+			}
 			fmt.Fprint(out, output)
 			return nil
 		},
 	}
+	readmeSubCmd.Flags().BoolVar(&plain, "plain", false, "show the Markdown of the README without rendering it")
+	readmeSubCmd.Flags().StringVar(&client.ReadmeSection, "section", "", "only show the section of the README under the heading with the given title")
+	readmeSubCmd.Flags().BoolVar(&client.ReadmeValuesTable, "values-table", false, "only show the tables of the README describing the values of the chart")
 
 	crdsSubCmd := &cobra.Command{
 		Use:               "crds [CHART]",
@@ -196,6 +219,7 @@ func addShowFlags(subCmd *cobra.Command, client *action.Show) {
 	f.BoolVar(&client.Devel, "devel", false, "use development versions, too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
 	if subCmd.Name() == "values" {
 		f.StringVar(&client.JSONPathTemplate, "jsonpath", "", "supply a JSONPath expression to filter the output")
+		f.BoolVar(&client.GenerateSkeleton, "generate-skeleton", false, "generate a commented values file from the values schema of the chart instead of showing its values.yaml")
 	}
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 
@@ -234,3 +258,16 @@ func addRegistryClient(client *action.Show) error {
 	client.SetRegistryClient(registryClient)
 	return nil
 }
+
+// terminalWidth returns the width of the terminal if out is one.
+func terminalWidth(out io.Writer) (int, bool) {
+	f, ok := out.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0, false
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return markdown.DefaultWidth, true
+	}
+	return width, true
+}
@@ -153,3 +153,17 @@ func TestShowValuesFileCompletion(t *testing.T) {
 func TestShowCRDsFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "show crds", true)
 }
+
+func TestShowValuesGenerateSkeleton(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "generate values skeleton from schema",
+		cmd:    "show values --generate-skeleton testdata/testcharts/chart-with-schema",
+		golden: "output/show-values-skeleton.txt",
+	}, {
+		name:      "generate values skeleton without schema",
+		cmd:       "show values --generate-skeleton testdata/testcharts/empty",
+		golden:    "output/show-values-skeleton-no-schema.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
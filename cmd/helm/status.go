@@ -24,14 +24,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 
+	"k8s.io/apimachinery/pkg/util/duration"
 	"k8s.io/kubectl/pkg/cmd/get"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
-	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/errcode"
 	"helm.sh/helm/v3/pkg/release"
 )
 
@@ -44,13 +46,17 @@ The status consists of:
 - state of the release (can be: unknown, deployed, uninstalled, superseded, failed, uninstalling, pending-install, pending-upgrade or pending-rollback)
 - revision of the release
 - description of the release (can be completion message or error message, need to enable --show-desc)
-- list of resources that this release consists of (need to enable --show-resources)
+- list of resources that this release consists of with their health and age
+  (need to enable --show-resources)
 - details on last test suite run, if applicable
+- last execution of each hook with its duration and the exit codes of its
+  containers (need to enable --last-hooks)
 - additional notes provided by the chart
 `
 
 func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewStatus(cfg)
+	revision := newRevisionValue(&client.Version)
 	var outfmt output.Format
 
 	cmd := &cobra.Command{
@@ -65,6 +71,9 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if err := revision.resolve(cfg, args[0]); err != nil {
+				return err
+			}
 
 			// When the output format is a table the resources should be fetched
 			// and displayed as a table. When YAML or JSON the resources will be
@@ -76,17 +85,18 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			warnNewerSchema(rel)
 
 			// strip chart metadata from the output
 			rel.Chart = nil
 
-			return outfmt.Write(out, &statusPrinter{rel, false, client.ShowDescription, client.ShowResources, false, false})
+			return outfmt.Write(out, &statusPrinter{rel, false, client.ShowDescription, client.ShowResources, false, false, false})
 		},
 	}
 
 	f := cmd.Flags()
 
-	f.IntVar(&client.Version, "revision", 0, "if set, display the status of the named release with revision")
+	f.Var(revision, "revision", "if set, display the status of the named release with revision, by number or tag")
 
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
@@ -102,7 +112,8 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	bindOutputFlag(cmd, &outfmt)
 	f.BoolVar(&client.ShowDescription, "show-desc", false, "if set, display the description message of the named release")
 
-	f.BoolVar(&client.ShowResources, "show-resources", false, "if set, display the resources of the named release")
+	f.BoolVar(&client.ShowResources, "show-resources", false, "if set, display the resources of the named release with their health and age")
+	f.BoolVar(&client.ShowHooks, "last-hooks", false, "if set, display the last execution of each hook of the named release with the exit codes of its containers")
 
 	return cmd
 }
@@ -114,6 +125,8 @@ type statusPrinter struct {
 	showResources   bool
 	showMetadata    bool
 	hideNotes       bool
+	// showComputedValues prints the computed values even without debug.
+	showComputedValues bool
 }
 
 func (s statusPrinter) WriteJSON(out io.Writer) error {
@@ -133,8 +146,14 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		_, _ = fmt.Fprintf(out, "LAST DEPLOYED: %s\n", s.release.Info.LastDeployed.Format(time.ANSIC))
 	}
 	_, _ = fmt.Fprintf(out, "NAMESPACE: %s\n", s.release.Namespace)
+	if len(s.release.Namespaces) > 0 {
+		_, _ = fmt.Fprintf(out, "RESOURCE NAMESPACES: %s\n", strings.Join(s.release.Namespaces, ", "))
+	}
 	_, _ = fmt.Fprintf(out, "STATUS: %s\n", s.release.Info.Status.String())
 	_, _ = fmt.Fprintf(out, "REVISION: %d\n", s.release.Version)
+	if sus := s.release.Suspension; sus != nil {
+		_, _ = fmt.Fprintf(out, "SUSPENDED: %s (%d workloads scaled to zero)\n", sus.Suspended.Format(time.ANSIC), len(sus.Workloads))
+	}
 	if s.showMetadata {
 		_, _ = fmt.Fprintf(out, "CHART: %s\n", s.release.Chart.Metadata.Name)
 		_, _ = fmt.Fprintf(out, "VERSION: %s\n", s.release.Chart.Metadata.Version)
@@ -143,8 +162,19 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 	if s.showDescription {
 		_, _ = fmt.Fprintf(out, "DESCRIPTION: %s\n", s.release.Info.Description)
 	}
+	if d := s.release.Info.Deprecation; d != nil {
+		_, _ = fmt.Fprintf(out, "DEPRECATION: %s\n", d.Message(time.Now()))
+	}
+	if len(s.release.Info.Warnings) > 0 {
+		_, _ = fmt.Fprintln(out, "WARNINGS:")
+		for _, w := range s.release.Info.Warnings {
+			_, _ = fmt.Fprintf(out, "  %s\n", w)
+		}
+	}
 
-	if s.showResources && s.release.Info.Resources != nil && len(s.release.Info.Resources) > 0 {
+	if s.showResources && len(s.release.Info.ResourceStatuses) > 0 {
+		_, _ = fmt.Fprintf(out, "RESOURCES:\n%s\n", formatResourceStatuses(s.release.Info.ResourceStatuses))
+	} else if s.showResources && s.release.Info.Resources != nil && len(s.release.Info.Resources) > 0 {
 		buf := new(bytes.Buffer)
 		printFlags := get.NewHumanPrintFlags()
 		typePrinter, _ := printFlags.ToPrinter("")
@@ -171,6 +201,10 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		_, _ = fmt.Fprintf(out, "RESOURCES:\n%s\n", buf.String())
 	}
 
+	if len(s.release.Info.HookStatuses) > 0 {
+		_, _ = fmt.Fprintf(out, "HOOK RESULTS:\n%s\n", formatHookStatuses(s.release.Info.HookStatuses))
+	}
+
 	executions := executionsByHookEvent(s.release)
 	if tests, ok := executions[release.HookTest]; !ok || len(tests) == 0 {
 		_, _ = fmt.Fprintln(out, "TEST SUITE: None")
@@ -197,8 +231,10 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		}
 		// Print an extra newline
 		_, _ = fmt.Fprintln(out)
+	}
 
-		cfg, err := chartutil.CoalesceValues(s.release.Chart, s.release.Config)
+	if s.debug || s.showComputedValues {
+		cfg, err := action.ComputedValues(s.release)
 		if err != nil {
 			return err
 		}
@@ -227,6 +263,85 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 	return nil
 }
 
+// formatResourceStatuses formats the live status of resources as a table per
+// kind. The statuses are expected to be grouped by kind. The namespace of the
+// resources is shown when they are in several namespaces.
+func formatResourceStatuses(statuses []release.ResourceStatus) string {
+	multiNamespace := false
+	for _, st := range statuses {
+		if st.Namespace != statuses[0].Namespace {
+			multiNamespace = true
+			break
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	var tbl *uitable.Table
+	flush := func() {
+		if tbl != nil {
+			_, _ = fmt.Fprintf(buf, "%s\n\n", tbl.String())
+		}
+	}
+
+	var group string
+	for _, st := range statuses {
+		if g := st.APIVersion + "/" + st.Kind; g != group {
+			flush()
+			group = g
+			_, _ = fmt.Fprintf(buf, "==> %s\n", group)
+			tbl = uitable.New()
+			if multiNamespace {
+				tbl.AddRow("NAMESPACE", "NAME", "HEALTH", "AGE", "MESSAGE")
+			} else {
+				tbl.AddRow("NAME", "HEALTH", "AGE", "MESSAGE")
+			}
+		}
+		age := "-"
+		if !st.Created.IsZero() {
+			age = duration.HumanDuration(time.Since(st.Created.Time))
+		}
+		if multiNamespace {
+			tbl.AddRow(st.Namespace, st.Name, st.Health, age, st.Message)
+		} else {
+			tbl.AddRow(st.Name, st.Health, age, st.Message)
+		}
+	}
+	flush()
+	return buf.String()
+}
+
+// formatHookStatuses formats the last execution of hooks as a table.
+func formatHookStatuses(statuses []release.HookStatus) string {
+	tbl := uitable.New()
+	tbl.AddRow("NAME", "KIND", "EVENTS", "PHASE", "DURATION", "EXIT CODES")
+	for _, st := range statuses {
+		events := make([]string, 0, len(st.Events))
+		for _, e := range st.Events {
+			events = append(events, e.String())
+		}
+		phase, took := "not run", "-"
+		if st.Phase != "" {
+			phase = st.Phase.String()
+		}
+		if !st.StartedAt.IsZero() && !st.CompletedAt.IsZero() {
+			took = st.CompletedAt.Sub(st.StartedAt).Round(time.Second).String()
+		}
+		codes := make([]string, 0, len(st.ExitCodes))
+		for _, c := range st.ExitCodes {
+			code := fmt.Sprintf("%s/%s=%d", c.Pod, c.Container, c.ExitCode)
+			if c.Reason != "" {
+				code += fmt.Sprintf(" (%s)", c.Reason)
+			}
+			codes = append(codes, code)
+		}
+		if len(codes) == 0 {
+			codes = append(codes, "-")
+		}
+		tbl.AddRow(st.Name, st.Kind, strings.Join(events, ","), phase, took, strings.Join(codes, ", "))
+	}
+	return tbl.String() + "\n"
+}
+
 func executionsByHookEvent(rel *release.Release) map[release.HookEvent][]*release.Hook {
 	result := make(map[release.HookEvent][]*release.Hook)
 	for _, h := range rel.Hooks {
@@ -240,3 +355,11 @@ func executionsByHookEvent(rel *release.Release) map[release.HookEvent][]*releas
 	}
 	return result
 }
+
+// warnNewerSchema warns that the release was written by a newer version of
+// Helm, whose information this version may not show.
+func warnNewerSchema(rel *release.Release) {
+	if rel.NewerSchema() {
+		warning("%s", errcode.Format(errcode.NewerReleaseSchemaShown, rel.Name, rel.Version, rel.HelmVersion))
+	}
+}
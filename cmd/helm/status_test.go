@@ -52,6 +52,14 @@ func TestStatusCmd(t *testing.T) {
 			Status:      release.StatusDeployed,
 			Description: "Mock description",
 		}),
+	}, {
+		name:   "get status of a deployed release with warnings",
+		cmd:    "status flummoxed-chickadee",
+		golden: "output/status-with-warnings.txt",
+		rels: releasesMockWithStatus(&release.Info{
+			Status:   release.StatusDeployed,
+			Warnings: []string{`HorizontalPodAutoscaler "web": autoscaling/v2beta2 HorizontalPodAutoscaler is deprecated in v1.23+, unavailable in v1.26+; use autoscaling/v2 HorizontalPodAutoscaler`},
+		}),
 	}, {
 		name:   "get status of a deployed release with notes",
 		cmd:    "status flummoxed-chickadee",
@@ -60,6 +68,17 @@ func TestStatusCmd(t *testing.T) {
 			Status: release.StatusDeployed,
 			Notes:  "release notes",
 		}),
+	}, {
+		name:   "get status of a release deployed into several namespaces",
+		cmd:    "status flummoxed-chickadee",
+		golden: "output/status-multi-namespace.txt",
+		rels: func() []*release.Release {
+			rels := releasesMockWithStatus(&release.Info{
+				Status: release.StatusDeployed,
+			})
+			rels[0].Namespaces = []string{"default", "monitoring"}
+			return rels
+		}(),
 	}, {
 		name:   "get status of a deployed release with notes in json",
 		cmd:    "status flummoxed-chickadee -o json",
@@ -126,6 +145,30 @@ func TestStatusCmd(t *testing.T) {
 				},
 			},
 		),
+	}, {
+		name:   "get status of a deployed release with last hooks",
+		cmd:    "status --last-hooks flummoxed-chickadee",
+		golden: "output/status-with-last-hooks.txt",
+		rels: releasesMockWithStatus(
+			&release.Info{
+				Status: release.StatusFailed,
+			},
+			&release.Hook{
+				Name:   "migrate",
+				Kind:   "Job",
+				Events: []release.HookEvent{release.HookPreUpgrade},
+				LastRun: release.HookExecution{
+					StartedAt:   mustParseTime("2006-01-02T15:04:05Z"),
+					CompletedAt: mustParseTime("2006-01-02T15:05:07Z"),
+					Phase:       release.HookPhaseFailed,
+				},
+			},
+			&release.Hook{
+				Name:   "smoke",
+				Kind:   "Pod",
+				Events: []release.HookEvent{release.HookPostInstall, release.HookPostUpgrade},
+			},
+		),
 	}}
 	runTestCmd(t, tests)
 }
@@ -218,3 +261,67 @@ func TestStatusFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "status", false)
 	checkFileCompletion(t, "status myrelease", false)
 }
+
+func TestFormatResourceStatuses(t *testing.T) {
+	got := formatResourceStatuses([]release.ResourceStatus{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Health: "Ready", Created: helmtime.Now().Add(-2 * time.Hour)},
+		{APIVersion: "batch/v1", Kind: "Job", Name: "migrate", Health: "Failed", Message: "BackoffLimitExceeded"},
+		{APIVersion: "batch/v1", Kind: "Job", Name: "seed", Health: "Missing"},
+	})
+	expected := `==> v1/ConfigMap
+NAME  	HEALTH	AGE 	MESSAGE
+config	Ready 	120m	       
+
+==> batch/v1/Job
+NAME   	HEALTH 	AGE	MESSAGE             
+migrate	Failed 	-  	BackoffLimitExceeded
+seed   	Missing	-  	                    
+
+`
+	if got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+}
+
+func TestFormatResourceStatusesMultiNamespace(t *testing.T) {
+	got := formatResourceStatuses([]release.ResourceStatus{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default", Health: "Ready"},
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "dashboards", Namespace: "monitoring", Health: "Ready"},
+	})
+	expected := `==> v1/ConfigMap
+NAMESPACE 	NAME      	HEALTH	AGE	MESSAGE
+default   	config    	Ready 	-  	       
+monitoring	dashboards	Ready 	-  	       
+
+`
+	if got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+}
+
+func TestFormatHookStatuses(t *testing.T) {
+	started := helmtime.Unix(1452902400, 0)
+	got := formatHookStatuses([]release.HookStatus{{
+		Name:        "migrate",
+		Kind:        "Job",
+		Events:      []release.HookEvent{release.HookPreInstall, release.HookPreUpgrade},
+		Phase:       release.HookPhaseFailed,
+		StartedAt:   started,
+		CompletedAt: started.Add(90 * time.Second),
+		ExitCodes: []release.ContainerExitCode{
+			{Pod: "migrate-abc", Container: "main", ExitCode: 1, Reason: "Error"},
+			{Pod: "migrate-def", Container: "main", ExitCode: 0},
+		},
+	}, {
+		Name:   "smoke",
+		Kind:   "Pod",
+		Events: []release.HookEvent{release.HookPostInstall},
+	}})
+	expected := `NAME   	KIND	EVENTS                 	PHASE  	DURATION	EXIT CODES                                    
+migrate	Job 	pre-install,pre-upgrade	Failed 	1m30s   	migrate-abc/main=1 (Error), migrate-def/main=0
+smoke  	Pod 	post-install           	not run	-       	-                                             
+`
+	if got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+}
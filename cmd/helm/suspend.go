@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const suspendDesc = `
+This command scales the workloads of a release to zero, e.g. to save the cost
+of a development environment while it is not used.
+
+The Deployments and StatefulSets of the release are scaled to zero, and the
+replicas they ran are recorded in the release, so that 'helm resume' can scale
+them back. Workloads annotated with 'helm.sh/suspend-policy: skip' keep
+running. Autoscalers stop scaling workloads scaled to zero.
+
+Upgrading or rolling back a suspended release applies the replicas set by the
+chart instead: workloads whose replicas the chart leaves unset stay scaled to
+zero until the release is resumed.
+
+    $ helm suspend angry-bird
+`
+
+func newSuspendCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewSuspend(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "suspend RELEASE_NAME",
+		Short: "scale the workloads of a release to zero",
+		Long:  suspendDesc,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			rel, err := client.Run(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Release %q has been suspended, %d workloads were scaled to zero\n", args[0], len(rel.Suspension.Workloads))
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,92 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+func TestSuspendCmd(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "suspend a release without workloads",
+		cmd:    "suspend funny-honey",
+		golden: "output/suspend-no-workloads.txt",
+		rels: []*release.Release{{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusDeployed},
+			Chart:   &chart.Chart{},
+			Version: 1,
+		}},
+		wantError: true,
+	}, {
+		name:      "suspend a release without a name",
+		cmd:       "suspend",
+		golden:    "output/suspend-no-args.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestResumeCmd(t *testing.T) {
+	suspended := func() []*release.Release {
+		return []*release.Release{{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusDeployed},
+			Chart:   &chart.Chart{},
+			Version: 1,
+			Suspension: &release.Suspension{
+				Suspended: helmtime.Unix(1452902400, 0).UTC(),
+				Workloads: []release.SuspendedWorkload{{Kind: "Deployment", Name: "web", Replicas: 2}},
+			},
+		}}
+	}
+
+	tests := []cmdTestCase{{
+		name:   "resume a suspended release",
+		cmd:    "resume funny-honey",
+		golden: "output/resume.txt",
+		rels:   suspended(),
+	}, {
+		name:   "status of a suspended release",
+		cmd:    "status funny-honey",
+		golden: "output/status-suspended.txt",
+		rels:   suspended(),
+	}, {
+		name:   "resume a release that is not suspended",
+		cmd:    "resume funny-honey",
+		golden: "output/resume-not-suspended.txt",
+		rels: []*release.Release{{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusDeployed},
+			Chart:   &chart.Chart{},
+			Version: 1,
+		}},
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestSuspendFileCompletion(t *testing.T) {
+	checkFileCompletion(t, "suspend", false)
+	checkFileCompletion(t, "suspend myrelease", false)
+	checkFileCompletion(t, "resume", false)
+	checkFileCompletion(t, "resume myrelease", false)
+}
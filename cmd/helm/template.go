@@ -18,23 +18,30 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"syscall"
 
 	"helm.sh/helm/v3/pkg/release"
 
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/admission"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/releaseutil"
 )
 
@@ -44,6 +51,57 @@ Render chart templates locally and display the output.
 Any values that would normally be looked up or retrieved in-cluster will be
 faked locally. Additionally, none of the server-side testing of chart validity
 (e.g. whether an API is supported) is done.
+
+With '--admission-policy', the rendered objects are evaluated against the
+ValidatingAdmissionPolicies and bindings of the given files or directories,
+or of the current cluster with the value 'cluster'. Objects a policy would
+deny make the command fail; other failed validations are printed as warnings.
+
+With '--capabilities-file', the chart is rendered with the Kubernetes version
+and API versions of a snapshot written by 'helm capabilities export', and the
+rendered custom resources are validated against the schemas of the custom
+resource definitions of the snapshot. This renders the chart as it would be
+rendered for the cluster, without access to it.
+
+With '--source-comments', each rendered document is annotated with a comment
+identifying the template, the range of lines and the chart it comes from. With
+'--source-map', the same information is also written to a JSON file along with
+the kind and name of each document.
+
+With '--watch', the templates are rendered again whenever the files of the
+chart or the values files change, to stdout or to '--output-dir'. Render
+errors are reported as they happen and the command keeps watching until it is
+interrupted.
+
+With '--composition', the releases listed in a composition file are rendered
+together into one stream, instead of the chart given as argument:
+
+    releases:
+      - name: frontend
+        chart: ./charts/web
+        namespace: web
+        valuesFiles: [web-values.yaml]
+        values:
+          replicas: 2
+      - name: cache
+        chart: bitnami/redis
+        version: 18.x
+
+Paths are relative to the composition file. Values given on the command line
+apply to every release and take precedence over those of the composition.
+Without a chart argument, a composition file may also be passed with '-f', as
+in 'helm template -f composition.yaml': '-f' stays the values flag, and a file
+holding nothing but a list of releases is read as the composition, while the
+other values files apply to every release.
+Each rendered document is marked with a '# Release:' comment, and rendering
+fails if two releases render the same resource. With '--output-dir', each
+release is written to a directory named after it.
+
+With '--debug-interactive', the chart is loaded with its values and template
+expressions read from the input are evaluated in its render context, e.g.
+'{{ .Values.image.tag | b64enc }}' or '.Capabilities.KubeVersion'. The command
+':render templates/service.yaml' renders a single template file. The chart is
+reloaded when its files or values files change.
 `
 
 func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -55,16 +113,27 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	var kubeVersion string
 	var extraAPIs []string
 	var showFiles []string
+	var admissionPolicies []string
+	var capabilitiesFile string
+	var debugInteractive bool
+	var watch bool
+	var sourceMap string
+	var compositionFile string
 
 	cmd := &cobra.Command{
 		Use:   "template [NAME] [CHART]",
 		Short: "locally render templates",
 		Long:  templateDesc,
-		Args:  require.MinimumNArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if compositionFile != "" || (len(args) == 0 && composedValuesFile(valueOpts) >= 0) {
+				return require.NoArgs(cmd, args)
+			}
+			return require.MinimumNArgs(1)(cmd, args)
+		},
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return compInstall(args, toComplete, client)
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(c *cobra.Command, args []string) error {
 			if kubeVersion != "" {
 				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
 				if err != nil {
@@ -92,100 +161,167 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			client.ClientOnly = !validate
 			client.APIVersions = chartutil.VersionSet(extraAPIs)
 			client.IncludeCRDs = includeCrds
-			rel, err := runInstall(args, client, valueOpts, out)
 
-			if err != nil && !settings.Debug {
-				if rel != nil {
-					return fmt.Errorf("%w\n\nUse --debug flag to render out invalid YAML", err)
+			var snapshot *chartutil.CapabilitiesSnapshot
+			if capabilitiesFile != "" {
+				if validate {
+					return fmt.Errorf("--capabilities-file cannot be used with --validate")
+				}
+				if snapshot, err = chartutil.LoadCapabilitiesSnapshot(capabilitiesFile); err != nil {
+					return err
+				}
+				if client.Capabilities, err = snapshot.Capabilities(); err != nil {
+					return err
 				}
-				return err
 			}
 
-			// We ignore a potential error here because, when the --debug flag was specified,
-			// we always want to print the YAML, even if it is not valid. The error is still returned afterwards.
-			if rel != nil {
-				var manifests bytes.Buffer
-				fmt.Fprintln(&manifests, strings.TrimSpace(rel.Manifest))
-				if !client.DisableHooks {
-					fileWritten := make(map[string]bool)
-					for _, m := range rel.Hooks {
-						if skipTests && isTestHook(m) {
-							continue
-						}
-						if client.OutputDir == "" {
-							fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", m.Path, m.Manifest)
-						} else {
-							newDir := client.OutputDir
-							if client.UseReleaseName {
-								newDir = filepath.Join(client.OutputDir, client.ReleaseName)
-							}
-							_, err := os.Stat(filepath.Join(newDir, m.Path))
-							if err == nil {
-								fileWritten[m.Path] = true
-							}
+			if debugInteractive {
+				if validate {
+					return fmt.Errorf("--debug-interactive cannot be used with --validate")
+				}
+				return runTemplateDebugger(args, cfg, client, valueOpts, c.InOrStdin(), out)
+			}
 
-							err = writeToFile(newDir, m.Path, m.Manifest, fileWritten[m.Path])
-							if err != nil {
-								return err
-							}
+			if i := composedValuesFile(valueOpts); compositionFile == "" && len(args) == 0 && i >= 0 {
+				compositionFile = valueOpts.ValueFiles[i]
+				valueOpts.ValueFiles = append(valueOpts.ValueFiles[:i:i], valueOpts.ValueFiles[i+1:]...)
+			}
+			if compositionFile != "" {
+				switch {
+				case debugInteractive:
+					return fmt.Errorf("--composition cannot be used with --debug-interactive")
+				case watch:
+					return fmt.Errorf("--composition cannot be used with --watch")
+				case sourceMap != "":
+					return fmt.Errorf("--composition cannot be used with --source-map")
+				case len(showFiles) > 0:
+					return fmt.Errorf("--composition cannot be used with --show-only")
+				}
+			}
+
+			if sourceMap != "" {
+				cfg.SourceComments = true
+			}
+
+			var policies *admission.Policies
+			if len(admissionPolicies) > 0 {
+				if policies, err = loadAdmissionPolicies(cfg, admissionPolicies); err != nil {
+					return err
+				}
+			}
+
+			if compositionFile != "" {
+				return renderComposition(compositionFile, client, valueOpts, skipTests, func(rel *release.Release) error {
+					if snapshot != nil {
+						if err := validateCustomResources(snapshot, rel, skipTests); err != nil {
+							return err
 						}
+					}
+					if policies != nil {
+						return validateAdmission(policies, rel, skipTests)
+					}
+					return nil
+				}, out)
+			}
+
+			render := func() error {
+				rel, err := runInstall(args, client, valueOpts, out)
 
+				if err != nil && !settings.Debug {
+					if rel != nil {
+						return fmt.Errorf("%w\n\nUse --debug flag to render out invalid YAML", err)
 					}
+					return err
 				}
 
-				// if we have a list of files to render, then check that each of the
-				// provided files exists in the chart.
-				if len(showFiles) > 0 {
-					// This is necessary to ensure consistent manifest ordering when using --show-only
-					// with globs or directory names.
-					splitManifests := releaseutil.SplitManifests(manifests.String())
-					manifestsKeys := make([]string, 0, len(splitManifests))
-					for k := range splitManifests {
-						manifestsKeys = append(manifestsKeys, k)
+				// We ignore a potential error here because, when the --debug flag was specified,
+				// we always want to print the YAML, even if it is not valid. The error is still returned afterwards.
+				if rel != nil {
+					manifests, merr := templateManifests(rel, client, skipTests)
+					if merr != nil {
+						return merr
 					}
-					sort.Sort(releaseutil.BySplitManifestsOrder(manifestsKeys))
-
-					manifestNameRegex := regexp.MustCompile("# Source: [^/]+/(.+)")
-					var manifestsToRender []string
-					for _, f := range showFiles {
-						missing := true
-						// Use linux-style filepath separators to unify user's input path
-						f = filepath.ToSlash(f)
-						for _, manifestKey := range manifestsKeys {
-							manifest := splitManifests[manifestKey]
-							submatch := manifestNameRegex.FindStringSubmatch(manifest)
-							if len(submatch) == 0 {
-								continue
+
+					// if we have a list of files to render, then check that each of the
+					// provided files exists in the chart.
+					if len(showFiles) > 0 {
+						// This is necessary to ensure consistent manifest ordering when using --show-only
+						// with globs or directory names.
+						splitManifests := releaseutil.SplitManifests(manifests.String())
+						manifestsKeys := make([]string, 0, len(splitManifests))
+						for k := range splitManifests {
+							manifestsKeys = append(manifestsKeys, k)
+						}
+						sort.Sort(releaseutil.BySplitManifestsOrder(manifestsKeys))
+
+						manifestNameRegex := regexp.MustCompile("# Source: [^/]+/(.+)")
+						var manifestsToRender []string
+						for _, f := range showFiles {
+							missing := true
+							// Use linux-style filepath separators to unify user's input path
+							f = filepath.ToSlash(f)
+							for _, manifestKey := range manifestsKeys {
+								manifest := splitManifests[manifestKey]
+								submatch := manifestNameRegex.FindStringSubmatch(manifest)
+								if len(submatch) == 0 {
+									continue
+								}
+								manifestName := submatch[1]
+								// manifest.Name is rendered using linux-style filepath separators on Windows as
+								// well as macOS/linux.
+								manifestPathSplit := strings.Split(manifestName, "/")
+								// manifest.Path is connected using linux-style filepath separators on Windows as
+								// well as macOS/linux
+								manifestPath := strings.Join(manifestPathSplit, "/")
+
+								// if the filepath provided matches a manifest path in the
+								// chart, render that manifest
+								if matched, _ := filepath.Match(f, manifestPath); !matched {
+									continue
+								}
+								manifestsToRender = append(manifestsToRender, manifest)
+								missing = false
 							}
-							manifestName := submatch[1]
-							// manifest.Name is rendered using linux-style filepath separators on Windows as
-							// well as macOS/linux.
-							manifestPathSplit := strings.Split(manifestName, "/")
-							// manifest.Path is connected using linux-style filepath separators on Windows as
-							// well as macOS/linux
-							manifestPath := strings.Join(manifestPathSplit, "/")
-
-							// if the filepath provided matches a manifest path in the
-							// chart, render that manifest
-							if matched, _ := filepath.Match(f, manifestPath); !matched {
-								continue
+							if missing {
+								return fmt.Errorf("could not find template %s in chart", f)
 							}
-							manifestsToRender = append(manifestsToRender, manifest)
-							missing = false
 						}
-						if missing {
-							return fmt.Errorf("could not find template %s in chart", f)
+						for _, m := range manifestsToRender {
+							fmt.Fprintf(out, "---\n%s\n", m)
+						}
+					} else {
+						fmt.Fprintf(out, "%s", manifests.String())
+					}
+
+					if err == nil && sourceMap != "" {
+						if err := writeSourceMap(sourceMap, rel, skipTests); err != nil {
+							return err
+						}
+					}
+					if err == nil && snapshot != nil {
+						if err := validateCustomResources(snapshot, rel, skipTests); err != nil {
+							return err
 						}
 					}
-					for _, m := range manifestsToRender {
-						fmt.Fprintf(out, "---\n%s\n", m)
+					if err == nil && policies != nil {
+						return validateAdmission(policies, rel, skipTests)
 					}
-				} else {
-					fmt.Fprintf(out, "%s", manifests.String())
 				}
+
+				return err
 			}
 
-			return err
+			if !watch {
+				return render()
+			}
+			paths, err := templateWatchPaths(args, client, valueOpts)
+			if err != nil {
+				return err
+			}
+			stop := make(chan os.Signal, 1)
+			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(stop)
+			return watchTemplate(paths, templateWatchInterval, render, c.ErrOrStderr(), stop)
 		},
 	}
 
@@ -195,16 +331,57 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&client.OutputDir, "output-dir", "", "writes the executed templates to files in output-dir instead of stdout")
 	f.BoolVar(&validate, "validate", false, "validate your manifests against the Kubernetes cluster you are currently pointing at. This is the same validation performed on an install")
 	f.BoolVar(&includeCrds, "include-crds", false, "include CRDs in the templated output")
+	f.BoolVar(&client.ExcludeCRDs, "exclude-crds", false, "exclude all CRDs, including those rendered from templates, from the templated output")
+	f.BoolVar(&client.OnlyCRDs, "only-crds", false, "only render CRDs, both from the crds/ directory and templates")
 	f.BoolVar(&skipTests, "skip-tests", false, "skip tests from templated output")
 	f.BoolVar(&client.IsUpgrade, "is-upgrade", false, "set .Release.IsUpgrade instead of .Release.IsInstall")
 	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for Capabilities.KubeVersion")
 	f.StringSliceVarP(&extraAPIs, "api-versions", "a", []string{}, "Kubernetes api versions used for Capabilities.APIVersions")
 	f.BoolVar(&client.UseReleaseName, "release-name", false, "use release name in the output-dir path.")
+	f.StringArrayVar(&admissionPolicies, "admission-policy", []string{}, "validate the rendered objects against the ValidatingAdmissionPolicies of a file or directory, or of the current cluster with 'cluster' (can specify multiple)")
+	f.StringVar(&capabilitiesFile, "capabilities-file", "", "render with the capabilities of a snapshot of a cluster written by 'helm capabilities export', and validate custom resources against its CRD schemas")
+	f.BoolVar(&cfg.SourceComments, "source-comments", false, "annotate each rendered document with a comment identifying the template, lines and chart it comes from")
+	f.StringVar(&sourceMap, "source-map", "", "write the templates, lines and charts the rendered documents come from to the given JSON file. Implies --source-comments")
+	f.BoolVar(&watch, "watch", false, "render the templates again whenever the files of the chart or the values files change")
+	f.StringVar(&compositionFile, "composition", "", "render the releases listed in the given composition file together instead of a single chart")
+	f.BoolVar(&debugInteractive, "debug-interactive", false, "evaluate template expressions and render single template files interactively in the render context of the chart")
 	bindPostRenderFlag(cmd, &client.PostRenderer)
 
 	return cmd
 }
 
+// templateManifests returns the manifest of a rendered release followed by
+// those of its hooks, unless hooks are disabled. With an output directory, the
+// hooks are written to it instead.
+func templateManifests(rel *release.Release, client *action.Install, skipTests bool) (*bytes.Buffer, error) {
+	var manifests bytes.Buffer
+	fmt.Fprintln(&manifests, strings.TrimSpace(rel.Manifest))
+	if client.DisableHooks {
+		return &manifests, nil
+	}
+	fileWritten := make(map[string]bool)
+	for _, m := range rel.Hooks {
+		if skipTests && isTestHook(m) {
+			continue
+		}
+		if client.OutputDir == "" {
+			fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", m.Path, m.Manifest)
+			continue
+		}
+		newDir := client.OutputDir
+		if client.UseReleaseName {
+			newDir = filepath.Join(client.OutputDir, client.ReleaseName)
+		}
+		if _, err := os.Stat(filepath.Join(newDir, m.Path)); err == nil {
+			fileWritten[m.Path] = true
+		}
+		if err := writeToFile(newDir, m.Path, m.Manifest, fileWritten[m.Path]); err != nil {
+			return nil, err
+		}
+	}
+	return &manifests, nil
+}
+
 func isTestHook(h *release.Hook) bool {
 	for _, e := range h.Events {
 		if e == release.HookTest {
@@ -214,6 +391,146 @@ func isTestHook(h *release.Hook) bool {
 	return false
 }
 
+// loadAdmissionPolicies reads the admission policies of the given files and
+// directories, and of the current cluster for the value "cluster".
+func loadAdmissionPolicies(cfg *action.Configuration, sources []string) (*admission.Policies, error) {
+	var paths []string
+	fromCluster := false
+	for _, s := range sources {
+		if s == "cluster" {
+			fromCluster = true
+		} else {
+			paths = append(paths, s)
+		}
+	}
+	policies, err := admission.Load(paths...)
+	if err != nil {
+		return nil, err
+	}
+	if fromCluster {
+		clientset, err := cfg.KubernetesClientSet()
+		if err != nil {
+			return nil, err
+		}
+		p, err := admission.FromCluster(context.Background(), clientset)
+		if err != nil {
+			return nil, err
+		}
+		policies.Policies = append(policies.Policies, p.Policies...)
+		policies.Bindings = append(policies.Bindings, p.Bindings...)
+	}
+	return policies, nil
+}
+
+// validateAdmission evaluates the admission policies against the objects and
+// hooks of a rendered release, printing warnings for the failed validations
+// that do not deny the objects.
+func validateAdmission(policies *admission.Policies, rel *release.Release, skipTests bool) error {
+	var manifests strings.Builder
+	fmt.Fprintln(&manifests, rel.Manifest)
+	for _, h := range rel.Hooks {
+		if skipTests && isTestHook(h) {
+			continue
+		}
+		fmt.Fprintf(&manifests, "---\n%s\n", h.Manifest)
+	}
+	violations, err := policies.ValidateManifest(manifests.String(), rel.Namespace)
+	if err != nil {
+		return err
+	}
+	var denied []string
+	for _, v := range violations {
+		if v.Denied() {
+			denied = append(denied, v.String())
+		} else {
+			warning("%s", v)
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("admission policies denied %d object(s):\n%s", len(denied), strings.Join(denied, "\n"))
+	}
+	return nil
+}
+
+// sourceMapEntry describes the template a rendered document comes from.
+type sourceMapEntry struct {
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+	engine.SourceLocation
+}
+
+// writeSourceMap writes the source locations of the objects and hooks of a
+// rendered release to a JSON file.
+func writeSourceMap(filename string, rel *release.Release, skipTests bool) error {
+	manifests := []string{rel.Manifest}
+	for _, h := range rel.Hooks {
+		if skipTests && isTestHook(h) {
+			continue
+		}
+		manifests = append(manifests, h.Manifest)
+	}
+	entries := []sourceMapEntry{}
+	for _, manifest := range manifests {
+		docs := releaseutil.SplitManifests(manifest)
+		keys := make([]string, 0, len(docs))
+		for k := range docs {
+			keys = append(keys, k)
+		}
+		sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+		for _, k := range keys {
+			location, ok := engine.ParseSourceComment(docs[k])
+			if !ok {
+				continue
+			}
+			var head releaseutil.SimpleHead
+			_ = yaml.Unmarshal([]byte(docs[k]), &head)
+			entry := sourceMapEntry{Kind: head.Kind, SourceLocation: location}
+			if head.Metadata != nil {
+				entry.Name = head.Metadata.Name
+			}
+			entries = append(entries, entry)
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// validateCustomResources validates the custom resources of the objects and
+// hooks of a rendered release against the schemas of their custom resource
+// definitions in a capabilities snapshot.
+func validateCustomResources(snapshot *chartutil.CapabilitiesSnapshot, rel *release.Release, skipTests bool) error {
+	manifests := []string{rel.Manifest}
+	for _, h := range rel.Hooks {
+		if skipTests && isTestHook(h) {
+			continue
+		}
+		manifests = append(manifests, h.Manifest)
+	}
+	var invalid []string
+	for _, manifest := range manifests {
+		for _, doc := range releaseutil.SplitManifests(manifest) {
+			var obj map[string]interface{}
+			if err := yaml.Unmarshal([]byte(doc), &obj); err != nil || obj == nil {
+				continue
+			}
+			if err := snapshot.ValidateObject(obj); err != nil {
+				var name string
+				if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+					name, _ = metadata["name"].(string)
+				}
+				invalid = append(invalid, fmt.Sprintf("%s/%s:\n%s", obj["kind"], name, strings.TrimSpace(err.Error())))
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%d custom resource(s) do not match the schemas of their definitions:\n%s", len(invalid), strings.Join(invalid, "\n"))
+	}
+	return nil
+}
+
 // The following functions (writeToFile, createOrOpenFile, and ensureDirectoryForFile)
 // are copied from the actions package. This is part of a change to correct a
 // bug introduced by #8156. As part of the todo to refactor renderResources
@@ -0,0 +1,233 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// composition lists the releases 'helm template --composition' renders
+// together.
+type composition struct {
+	Releases []composedRelease `json:"releases"`
+}
+
+// composedRelease is a release of a composition.
+type composedRelease struct {
+	// Name is the name of the release, unique in the composition.
+	Name string `json:"name"`
+	// Chart is a chart reference, or the path of a chart relative to the
+	// composition file.
+	Chart string `json:"chart"`
+	// Version constrains the version of the chart.
+	Version string `json:"version,omitempty"`
+	// Namespace is the namespace of the release, the namespace of the
+	// command if empty.
+	Namespace string `json:"namespace,omitempty"`
+	// ValuesFiles are values files, or URLs, of the release. Paths are
+	// relative to the composition file.
+	ValuesFiles []string `json:"valuesFiles,omitempty"`
+	// Values take precedence over those of ValuesFiles.
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// loadComposition reads and checks a composition file. The paths of the
+// charts and values files are made relative to the working directory.
+func loadComposition(path string) (*composition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	comp := &composition{}
+	if err := yaml.UnmarshalStrict(data, comp); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse composition %s", path)
+	}
+	if len(comp.Releases) == 0 {
+		return nil, errors.Errorf("composition %s has no releases", path)
+	}
+
+	dir := filepath.Dir(path)
+	names := map[string]bool{}
+	for i := range comp.Releases {
+		r := &comp.Releases[i]
+		if err := chartutil.ValidateReleaseName(r.Name); err != nil {
+			return nil, errors.Wrapf(err, "composition %s: release %q", path, r.Name)
+		}
+		if names[r.Name] {
+			return nil, errors.Errorf("composition %s: release %s is listed twice", path, r.Name)
+		}
+		names[r.Name] = true
+		if r.Chart == "" {
+			return nil, errors.Errorf("composition %s: release %s has no chart", path, r.Name)
+		}
+		if strings.HasPrefix(r.Chart, ".") {
+			r.Chart = filepath.Join(dir, r.Chart)
+		}
+		for j, f := range r.ValuesFiles {
+			if !strings.Contains(f, "://") && !filepath.IsAbs(f) {
+				r.ValuesFiles[j] = filepath.Join(dir, f)
+			}
+		}
+	}
+	return comp, nil
+}
+
+// composedValuesFile returns the index of the first values file that is a
+// composition, or -1. Compositions are local files holding nothing but a list
+// of releases, which values files passed without a chart cannot be used for.
+func composedValuesFile(valueOpts *values.Options) int {
+	for i, f := range valueOpts.ValueFiles {
+		if strings.Contains(f, "://") || f == "-" {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil || len(doc) != 1 {
+			continue
+		}
+		if _, ok := doc["releases"].([]interface{}); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeValues returns the values of the release: those of its values files
+// merged with its inline values.
+func (r *composedRelease) mergeValues(p getter.Providers) (map[string]interface{}, error) {
+	opts := &values.Options{ValueFiles: r.ValuesFiles}
+	vals, err := opts.MergeValues(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "release %s", r.Name)
+	}
+	return chartutil.MergeTables(r.Values, vals), nil
+}
+
+// renderedRelease is a release of a composition with its manifests.
+type renderedRelease struct {
+	release   *release.Release
+	manifests string
+}
+
+// renderComposition renders the releases of a composition, checks that no
+// two of them render the same resource and writes their manifests to out,
+// each document marked with the release it belongs to. With an output
+// directory, each release is written to a directory named after it.
+func renderComposition(path string, client *action.Install, valueOpts *values.Options, skipTests bool, validate func(*release.Release) error, out io.Writer) error {
+	comp, err := loadComposition(path)
+	if err != nil {
+		return err
+	}
+
+	client.UseReleaseName = true
+	version := client.Version
+	defer func() { client.Version = version }()
+
+	var rendered []renderedRelease
+	for i := range comp.Releases {
+		r := &comp.Releases[i]
+		client.Version = version
+		if r.Version != "" {
+			client.Version = r.Version
+		}
+		rel, err := runComposedInstall([]string{r.Name, r.Chart}, client, valueOpts, r, out)
+		if err != nil {
+			return errors.Wrapf(err, "release %s", r.Name)
+		}
+		manifests, err := templateManifests(rel, client, skipTests)
+		if err != nil {
+			return err
+		}
+		if validate != nil {
+			if err := validate(rel); err != nil {
+				return errors.Wrapf(err, "release %s", r.Name)
+			}
+		}
+		rendered = append(rendered, renderedRelease{rel, manifests.String()})
+	}
+
+	if err := checkComposedResources(rendered); err != nil {
+		return err
+	}
+	for _, r := range rendered {
+		fmt.Fprint(out, markRelease(r.manifests, r.release.Name))
+	}
+	return nil
+}
+
+// markRelease adds a comment naming the release to each document of the
+// manifests, before the comment naming its template.
+func markRelease(manifests, name string) string {
+	return strings.ReplaceAll(manifests, "\n# Source: ", fmt.Sprintf("\n# Release: %s\n# Source: ", name))
+}
+
+// checkComposedResources fails if resources of the same kind, namespace and
+// name are rendered by more than one release.
+func checkComposedResources(rendered []renderedRelease) error {
+	owners := map[string]string{}
+	var conflicts []string
+	for _, r := range rendered {
+		for _, doc := range releaseutil.SplitManifests(r.manifests) {
+			var head struct {
+				Kind     string `json:"kind"`
+				Metadata struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"metadata"`
+			}
+			if err := yaml.Unmarshal([]byte(doc), &head); err != nil || head.Kind == "" || head.Metadata.Name == "" {
+				continue
+			}
+			ns := head.Metadata.Namespace
+			if ns == "" {
+				ns = r.release.Namespace
+			}
+			key := fmt.Sprintf("%s %s/%s", head.Kind, ns, head.Metadata.Name)
+			owner, ok := owners[key]
+			if !ok {
+				owners[key] = r.release.Name
+				continue
+			}
+			if owner != r.release.Name {
+				conflicts = append(conflicts, fmt.Sprintf("%s is rendered by releases %s and %s", key, owner, r.release.Name))
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+	return errors.Errorf("releases of the composition render the same resources:\n%s", strings.Join(conflicts, "\n"))
+}
@@ -0,0 +1,234 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+const templateDebugHelp = `Enter a template or an expression to evaluate it in the render context of the chart:
+
+  {{ .Values.image.tag | b64enc }}    evaluate a template
+  .Capabilities.KubeVersion          evaluate an expression
+  :render FILE                       render a template file, e.g. templates/service.yaml
+  :templates                         list the template files of the chart
+  :reload                            reload the chart and values
+  :help                              show this help
+  :quit                              exit
+
+The chart and values are reloaded when their files change.
+`
+
+// templateDebugger evaluates templates in the render context of a chart,
+// reloading the chart when its files change.
+type templateDebugger struct {
+	client    *action.Install
+	valueOpts *values.Options
+	engine    engine.Engine
+	chartPath string
+
+	chart    *chart.Chart
+	values   chartutil.Values
+	modified time.Time
+}
+
+// runTemplateDebugger runs the interactive template debugger of
+// 'helm template --debug-interactive' until the input ends.
+func runTemplateDebugger(args []string, cfg *action.Configuration, client *action.Install, valueOpts *values.Options, in io.Reader, out io.Writer) error {
+	name, chartRef, err := client.NameAndChart(args)
+	if err != nil {
+		return err
+	}
+	client.ReleaseName = name
+	client.Namespace = settings.Namespace()
+
+	cp, err := client.ChartPathOptions.LocateChart(chartRef, settings)
+	if err != nil {
+		return err
+	}
+
+	d := &templateDebugger{
+		client:    client,
+		valueOpts: valueOpts,
+		engine: engine.Engine{
+			EnableDNS:       client.EnableDNS,
+			MaxIncludeDepth: cfg.MaxIncludeDepth,
+			TemplateTimeout: cfg.TemplateTimeout,
+			SourceComments:  cfg.SourceComments,
+		},
+		chartPath: cp,
+	}
+	if err := d.load(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Loaded chart %s %s. Type :help for the commands.\n", d.chart.Name(), d.chart.Metadata.Version)
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "helm> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		input := strings.TrimSpace(scanner.Text())
+		if input == "" {
+			continue
+		}
+		if input == ":quit" || input == ":q" {
+			return nil
+		}
+		if err := d.run(input, out); err != nil {
+			fmt.Fprintf(out, "Error: %s\n", err)
+		}
+	}
+}
+
+// run executes a command or evaluates a template.
+func (d *templateDebugger) run(input string, out io.Writer) error {
+	command, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case ":help":
+		fmt.Fprint(out, templateDebugHelp)
+		return nil
+	case ":reload":
+		if err := d.load(); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Reloaded the chart.")
+		return nil
+	}
+
+	if d.changed() {
+		if err := d.load(); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "The chart changed and was reloaded.")
+	}
+
+	switch command {
+	case ":templates":
+		for _, name := range templateFiles(d.chart) {
+			fmt.Fprintln(out, name)
+		}
+		return nil
+	case ":render":
+		if arg == "" {
+			return errors.New("the template file to render is required, e.g. :render templates/service.yaml")
+		}
+		name := path.Join(d.chart.Name(), filepath.ToSlash(arg))
+		if strings.HasPrefix(filepath.ToSlash(arg), d.chart.Name()+"/") {
+			name = filepath.ToSlash(arg)
+		}
+		rendered, err := d.engine.RenderFiles(d.chart, d.values, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "---\n# Source: %s\n%s\n", name, strings.TrimSpace(rendered[name]))
+		return nil
+	}
+	if strings.HasPrefix(command, ":") {
+		return errors.Errorf("unknown command %s, type :help for the commands", command)
+	}
+
+	if !strings.Contains(input, "{{") {
+		input = "{{ " + input + " }}"
+	}
+	result, err := d.engine.Eval(d.chart, d.values, input)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, result)
+	return nil
+}
+
+// load loads the chart and values and prepares the render context.
+func (d *templateDebugger) load() error {
+	modified := d.lastModified()
+
+	vals, err := d.valueOpts.MergeValues(getter.All(settings))
+	if err != nil {
+		return err
+	}
+	chrt, err := loader.Load(d.chartPath)
+	if err != nil {
+		return err
+	}
+	if req := chrt.Metadata.Dependencies; req != nil {
+		if err := action.CheckDependencies(chrt, req); err != nil {
+			return errors.Wrap(err, "An error occurred while checking for chart dependencies. You may need to run `helm dependency build` to fetch missing dependencies")
+		}
+	}
+	renderValues, err := d.client.RenderValues(chrt, vals)
+	if err != nil {
+		return err
+	}
+
+	d.chart, d.values, d.modified = chrt, renderValues, modified
+	return nil
+}
+
+// changed reports whether the files of the chart or values changed since
+// they were loaded.
+func (d *templateDebugger) changed() bool {
+	return d.lastModified().After(d.modified)
+}
+
+// lastModified returns the time the files of the chart or the local values
+// files were last modified.
+func (d *templateDebugger) lastModified() time.Time {
+	return latestModTime(append([]string{d.chartPath}, d.valueOpts.ValueFiles...))
+}
+
+// templateFiles returns the sorted paths of the template files of a chart and
+// its dependencies, relative to the chart, without the partials.
+func templateFiles(c *chart.Chart) []string {
+	var names []string
+	var walk func(c *chart.Chart)
+	walk = func(c *chart.Chart) {
+		for _, t := range c.Templates {
+			if t == nil || strings.HasPrefix(path.Base(t.Name), "_") {
+				continue
+			}
+			names = append(names, strings.TrimPrefix(path.Join(c.ChartFullPath(), t.Name), c.Root().Name()+"/"))
+		}
+		for _, dep := range c.Dependencies() {
+			walk(dep)
+		}
+	}
+	walk(c)
+	sort.Strings(names)
+	return names
+}
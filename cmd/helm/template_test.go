@@ -18,8 +18,11 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
+
+	"helm.sh/helm/v3/internal/test"
 )
 
 var chartPath = "testdata/testcharts/subchart"
@@ -91,6 +94,22 @@ func TestTemplateCmd(t *testing.T) {
 			cmd:    fmt.Sprintf("template '%s' --include-crds", chartPath),
 			golden: "output/template-with-crds.txt",
 		},
+		{
+			name:   "template with only CRDs",
+			cmd:    fmt.Sprintf("template '%s' --only-crds", chartPath),
+			golden: "output/template-only-crds.txt",
+		},
+		{
+			name:      "template with CRDs both included and excluded",
+			cmd:       fmt.Sprintf("template '%s' --include-crds --exclude-crds", chartPath),
+			wantError: true,
+			golden:    "output/template-crds-conflict.txt",
+		},
+		{
+			name:   "template with source comments",
+			cmd:    fmt.Sprintf("template '%s' --source-comments --show-only templates/subdir/role.yaml --show-only charts/subcharta/templates/service.yaml", chartPath),
+			golden: "output/template-source-comments.txt",
+		},
 		{
 			name:   "template with show-only one",
 			cmd:    fmt.Sprintf("template '%s' --show-only templates/service.yaml", chartPath),
@@ -161,10 +180,116 @@ func TestTemplateCmd(t *testing.T) {
 			cmd:    fmt.Sprintf("template '%s' -f %s/extra_values.yaml", chartPath, chartPath),
 			golden: "output/template-subchart-cm-set-file.txt",
 		},
+		{
+			name:   "template with admission policies",
+			cmd:    fmt.Sprintf("template '%s' --admission-policy testdata/admission/chart-label.yaml", chartPath),
+			golden: "output/template.txt",
+		},
+		{
+			name:      "template with admission policies denying objects",
+			cmd:       fmt.Sprintf("template '%s' --admission-policy testdata/admission", chartPath),
+			wantError: true,
+			golden:    "output/template-admission-denied.txt",
+		},
+		{
+			name:   "template with capabilities file",
+			cmd:    fmt.Sprintf("template '%s' --capabilities-file testdata/capabilities.json", chartPath),
+			golden: "output/template-with-capabilities-file.txt",
+		},
+		{
+			name:   "template with capabilities file validating custom resources",
+			cmd:    "template testdata/testcharts/chart-with-custom-resource --capabilities-file testdata/capabilities.json",
+			golden: "output/template-custom-resource.txt",
+		},
+		{
+			name:      "template with capabilities file rejecting invalid custom resources",
+			cmd:       "template testdata/testcharts/chart-with-custom-resource --capabilities-file testdata/capabilities.json --set replicas=many --set cronSpec=null",
+			wantError: true,
+			golden:    "output/template-custom-resource-invalid.txt",
+		},
+		{
+			name:      "template with capabilities file rejecting unserved versions",
+			cmd:       "template testdata/testcharts/chart-with-custom-resource --capabilities-file testdata/capabilities.json --set apiVersion=stable.example.com/v1beta1",
+			wantError: true,
+			golden:    "output/template-custom-resource-unserved.txt",
+		},
+	}
+	runTestCmd(t, tests)
+}
+
+func TestTemplateComposition(t *testing.T) {
+	tests := []cmdTestCase{
+		{
+			name:   "composition",
+			cmd:    "template --composition testdata/composition/composition.yaml",
+			golden: "output/template-composition.txt",
+		},
+		{
+			name:   "composition with values from the command line",
+			cmd:    "template --composition testdata/composition/composition.yaml --set Name=all",
+			golden: "output/template-composition-set.txt",
+		},
+		{
+			name:      "composition with conflicting resources",
+			cmd:       "template --composition testdata/composition/conflicting.yaml",
+			wantError: true,
+			golden:    "output/template-composition-conflict.txt",
+		},
+		{
+			name:   "composition passed as a values file",
+			cmd:    "template -f testdata/composition/composition.yaml",
+			golden: "output/template-composition.txt",
+		},
+		{
+			name:   "composition passed as a values file along with values",
+			cmd:    "template -f testdata/composition/composition.yaml -f testdata/composition/all-values.yaml",
+			golden: "output/template-composition-set.txt",
+		},
+		{
+			name:      "composition with a chart argument",
+			cmd:       "template --composition testdata/composition/composition.yaml foo testdata/testcharts/alpine",
+			wantError: true,
+		},
 	}
 	runTestCmd(t, tests)
 }
 
+func TestTemplateDebugInteractive(t *testing.T) {
+	in, err := os.CreateTemp(t.TempDir(), "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(in, `{{ .Values.service.name | b64enc }}
+.Chart.Name
+{{ .Capabilities.KubeVersion.Version }}
+:templates
+:render templates/service.yaml
+.Values.missing | required "missing is required"
+:unknown
+:quit
+`)
+	if _, err := in.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	cmd := fmt.Sprintf("template '%s' --debug-interactive --set service.name=apache", chartPath)
+	_, out, err := executeActionCommandStdinC(storageFixture(), in, cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertGoldenString(t, out, "output/template-debug-interactive.txt")
+}
+
+func TestTemplateSourceMap(t *testing.T) {
+	sourceMap := filepath.Join(t.TempDir(), "sourcemap.json")
+	cmd := fmt.Sprintf("template '%s' --skip-tests --source-map '%s'", chartPath, sourceMap)
+	if _, _, err := executeActionCommandC(storageFixture(), cmd); err != nil {
+		t.Fatal(err)
+	}
+	test.AssertGoldenFile(t, sourceMap, "output/template-source-map.json")
+}
+
 func TestTemplateVersionCompletion(t *testing.T) {
 	repoFile := "testdata/helmhome/helm/repositories.yaml"
 	repoCache := "testdata/helmhome/helm/repository"
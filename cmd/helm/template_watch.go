@@ -0,0 +1,90 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+// templateWatchInterval is how often 'helm template --watch' checks the files
+// of the chart and the values files for changes.
+const templateWatchInterval = time.Second
+
+// watchTemplate renders, and renders again whenever one of the files under
+// the paths changes, until stop receives. Render errors are reported to errOut
+// without ending the watch.
+func watchTemplate(paths []string, interval time.Duration, render func() error, errOut io.Writer, stop <-chan os.Signal) error {
+	modified := latestModTime(paths)
+	if err := render(); err != nil {
+		fmt.Fprintf(errOut, "Error: %s\n", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			latest := latestModTime(paths)
+			if !latest.After(modified) {
+				continue
+			}
+			modified = latest
+			if err := render(); err != nil {
+				fmt.Fprintf(errOut, "Error: %s\n", err)
+			}
+		}
+	}
+}
+
+// templateWatchPaths returns the path of the chart and the local values files
+// to watch for changes.
+func templateWatchPaths(args []string, client *action.Install, valueOpts *values.Options) ([]string, error) {
+	_, chartRef, err := client.NameAndChart(args)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := client.ChartPathOptions.LocateChart(chartRef, settings)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{cp}, valueOpts.ValueFiles...), nil
+}
+
+// latestModTime returns the time a file under the paths was last modified.
+// Paths that do not exist, such as remote values files, are ignored.
+func latestModTime(paths []string) time.Time {
+	var latest time.Time
+	for _, p := range paths {
+		_ = filepath.Walk(p, func(_ string, info fs.FileInfo, err error) error {
+			if err == nil && info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+			return nil
+		})
+	}
+	return latest
+}
@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestWatchTemplate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "values.yaml")
+	if err := os.WriteFile(file, []byte("a: 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	renders := make(chan int, 10)
+	count := 0
+	render := func() error {
+		count++
+		renders <- count
+		if count == 2 {
+			return errors.New("broken template")
+		}
+		return nil
+	}
+
+	stop := make(chan os.Signal)
+	errOut := new(bytes.Buffer)
+	done := make(chan error)
+	go func() {
+		done <- watchTemplate([]string{dir}, 10*time.Millisecond, render, errOut, stop)
+	}()
+
+	<-renders
+	for i := 2; i <= 3; i++ {
+		later := time.Now().Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case n := <-renders:
+			if n != i {
+				t.Fatalf("expected render %d, got %d", i, n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected a render after the change %d", i)
+		}
+	}
+
+	stop <- os.Interrupt
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(errOut.String(), "Error: broken template") {
+		t.Errorf("expected the render error to be reported, got %q", errOut.String())
+	}
+}
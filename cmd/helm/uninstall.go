@@ -35,6 +35,12 @@ as well as the release history, freeing it up for future use.
 
 Use the '--dry-run' flag to see which releases will be uninstalled without actually
 uninstalling them.
+
+Resources are deleted in waves set with the 'helm.sh/uninstall-wave' annotation,
+lowest wave first. A wave is only deleted once the previous one is gone.
+Resources without the annotation belong to wave 0. If resources are stuck on
+finalizers when waiting, they are reported; use '--remove-finalizers' to remove
+the finalizers of kinds where this is known to be safe.
 `
 
 func newUninstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -79,6 +85,8 @@ func newUninstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Wait, "wait", false, "if set, will wait until all the resources are deleted before returning. It will wait for as long as --timeout")
 	f.StringVar(&client.DeletionPropagation, "cascade", "background", "Must be \"background\", \"orphan\", or \"foreground\". Selects the deletion cascading strategy for the dependents. Defaults to background.")
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
+	f.DurationVar(&client.TimeoutPerResource, "timeout-per-resource", 0, "time to wait for each resource to be deleted, waiting for them one after the other. Defaults to --timeout for all resources together")
+	f.BoolVar(&client.RemoveFinalizers, "remove-finalizers", false, "remove the finalizers of resources stuck on deletion, for kinds where this is known to be safe. Only applies when waiting for deletion")
 	f.StringVar(&client.Description, "description", "", "add a custom description")
 
 	return cmd
@@ -74,16 +74,75 @@ or '--set' flags. Priority is given to new values.
 
     $ helm upgrade --reuse-values --set foo=bar --set foo=newbar redis ./redis
 
+To reuse only some of the existing values, and take the chart's defaults for the
+rest, list their dotted paths with the '--reuse-values-for' flag:
+
+    $ helm upgrade --reuse-values-for image,ingress.hosts redis ./redis
+
+Resources may choose how they are updated with the 'helm.sh/upgrade-strategy'
+annotation, which takes precedence over '--force': 'patch' applies a three-way
+merge patch, 'replace' replaces the resource and 'recreate' deletes the resource
+and creates it again if it changed. The latter allows changing immutable fields,
+e.g. of Jobs, without forcing the update of all other resources.
+'recreate-orphan' deletes the resource while keeping its pods. This is the way
+to change immutable fields of StatefulSets: the new StatefulSet adopts the
+running pods, and increased storage requests of its volumeClaimTemplates are
+applied to the existing PersistentVolumeClaims where the StorageClass allows
+volume expansion.
+
+Fields managed by someone else, such as the replicas of a Deployment scaled by a
+HorizontalPodAutoscaler or an injected CA bundle, can be listed in the
+'helm.sh/ignore-differences' annotation of the resource, separated by commas,
+e.g. 'spec.replicas' or 'webhooks[*].clientConfig.caBundle'. Upgrades and
+rollbacks leave these fields of existing resources alone, and '--analyze-impact'
+does not report them as changed.
+
+The '--analyze-impact' flag prints the predicted impact of the upgrade before it
+is applied: which Deployments, StatefulSets and DaemonSets will roll out new pods
+and why, whether their PodDisruptionBudgets allow as many unavailable pods as the
+rollout takes down, and which resources are updated in place, created or deleted.
+Combine it with '--dry-run=server' to review the impact without upgrading.
+
+With '--skip-if-unchanged', the upgrade does nothing and creates no new revision
+if the deployed revision has the same chart, values and resources, and a
+server-side dry run finds that applying the resources would not change them
+either, e.g. because nobody edited them since. This keeps pipelines that upgrade
+on every run from piling up empty revisions. '--skip-if-unchanged=local' skips
+the upgrade without consulting the cluster, as long as the chart, values and
+rendered manifests are the same as in the deployed revision.
+
+With '--atomic', a failed upgrade rolls the release back to its last successful
+revision. With '--atomic-strategy=scoped', only the resources the upgrade changed
+are reverted instead: the resources it created are deleted, and those it updated
+or deleted are restored as they were in the last successful revision. The
+upgrade is marked failed, and resources it did not change are left alone.
+
+The '--retry-attempts' flag applies the resources and waits for them up to the
+given number of times when doing so fails, before the upgrade is failed, rolled
+back or reverted. Only the failures of the classes of '--retry-on' are retried:
+'wait-timeout' when the resources are not ready in time, and 'transient' when the
+Kubernetes API is unavailable, overloaded or an update conflicts with another.
+Resources created or deleted by a failed attempt are updated rather than created
+again, and hooks are not run again. Attempts are '--retry-backoff' apart at
+first, twice as long with every further attempt, and at most
+'--retry-max-backoff' apart.
+
 The --dry-run flag will output all generated chart manifests, including Secrets
-which can contain sensitive values. To hide Kubernetes Secrets use the
---hide-secret flag. Please carefully consider how and when these flags are used.
+which can contain sensitive values. The data of Secrets and the values of keys
+matching the --redact-pattern expressions are masked unless --show-secrets is
+set. To hide Kubernetes Secrets entirely use the --hide-secret flag. Please
+carefully consider how and when these flags are used.
 `
 
 func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewUpgrade(cfg)
 	valueOpts := &values.Options{}
 	var outfmt output.Format
+	var showComputedValues bool
 	var createNamespace bool
+	var analyzeImpact bool
+	retryPolicy := &action.RetryPolicy{}
+	var retryOn []string
 
 	cmd := &cobra.Command{
 		Use:   "upgrade [RELEASE] [CHART]",
@@ -115,6 +174,9 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			if client.DryRunOption == "" {
 				client.DryRunOption = "none"
 			}
+			if showComputedValues && !client.DryRun && !isDryRunOption(client.DryRunOption) {
+				return errors.New("--show-computed-values requires --dry-run")
+			}
 			// Fixes #7002 - Support reading values from STDIN for `upgrade` command
 			// Must load values AFTER determining if we have to call install so that values loaded from stdin are not read twice
 			if client.Install {
@@ -149,7 +211,12 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					instClient.DependencyUpdate = client.DependencyUpdate
 					instClient.Labels = client.Labels
 					instClient.EnableDNS = client.EnableDNS
+					instClient.LabelResources = client.LabelResources
+					instClient.DeployedBy = client.DeployedBy
 					instClient.HideSecret = client.HideSecret
+					instClient.StrictDeprecations = client.StrictDeprecations
+					instClient.ManifestOutput = client.ManifestOutput
+					instClient.OverrideFreeze = client.OverrideFreeze
 
 					if isReleaseUninstalled(versions) {
 						instClient.Replace = true
@@ -159,7 +226,7 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					if err != nil {
 						return err
 					}
-					return outfmt.Write(out, &statusPrinter{rel, settings.Debug, false, false, false, instClient.HideNotes})
+					return outfmt.Write(out, &statusPrinter{redactor.Release(rel), settings.Debug, false, false, false, instClient.HideNotes, showComputedValues})
 				} else if err != nil {
 					return err
 				}
@@ -217,8 +284,26 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				}
 			}
 
-			if ch.Metadata.Deprecated {
-				warning("This chart is deprecated")
+			if d := ch.Metadata.Deprecation(); d != nil {
+				warning("%s", d.Message(time.Now()))
+			}
+
+			if retryPolicy.Attempts > 1 {
+				for _, c := range retryOn {
+					retryPolicy.RetryOn = append(retryPolicy.RetryOn, action.RetryClass(c))
+				}
+				client.RetryPolicy = retryPolicy
+			}
+
+			if analyzeImpact {
+				client.ImpactReview = func(report *action.ImpactReport) error {
+					w := out
+					if outfmt != output.Table {
+						w = os.Stderr
+					}
+					fmt.Fprintf(w, "UPGRADE IMPACT:\n%s\n", report)
+					return nil
+				}
 			}
 
 			// Create context and prepare the handle of SIGTERM
@@ -238,15 +323,17 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 			rel, err := client.RunWithContext(ctx, args[0], ch, vals)
 
-			if err != nil {
+			if err == action.ErrReleaseUnchanged {
+				if outfmt == output.Table {
+					fmt.Fprintf(out, "Release %q is unchanged, no new revision was created.\n", args[0])
+				}
+			} else if err != nil {
 				return errors.Wrap(err, "UPGRADE FAILED")
-			}
-
-			if outfmt == output.Table {
+			} else if outfmt == output.Table {
 				fmt.Fprintf(out, "Release %q has been upgraded. Happy Helming!\n", args[0])
 			}
 
-			return outfmt.Write(out, &statusPrinter{rel, settings.Debug, false, false, false, client.HideNotes})
+			return outfmt.Write(out, &statusPrinter{redactor.Release(rel), settings.Debug, false, false, false, client.HideNotes, showComputedValues})
 		},
 	}
 
@@ -256,6 +343,10 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Devel, "devel", false, "use development versions, too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
 	f.StringVar(&client.DryRunOption, "dry-run", "", "simulate an install. If --dry-run is set with no option being specified or as '--dry-run=client', it will not attempt cluster connections. Setting '--dry-run=server' allows attempting cluster connections.")
 	f.BoolVar(&client.HideSecret, "hide-secret", false, "hide Kubernetes Secrets when also using the --dry-run flag")
+	f.BoolVar(&client.StrictDeprecations, "strict-deprecations", false, "fail if the chart is deprecated or past its end of life")
+	f.BoolVar(&client.OverrideFreeze, "override-freeze", false, "proceed during an active freeze window of the freeze policy, recording the override in the audit log")
+	f.StringVar(&client.ManifestOutput, "output-dir", "", "after a successful upgrade, write the applied manifests to this directory, or to this file if it has a .yaml or .yml extension")
+	f.BoolVar(&showComputedValues, "show-computed-values", false, "print the values the templates are rendered with, coalesced from the chart's values, the reused values and the supplied values, when also using the --dry-run flag")
 	f.Lookup("dry-run").NoOptDefVal = "client"
 	f.BoolVar(&client.Recreate, "recreate-pods", false, "performs pods restart for the resource if applicable")
 	f.MarkDeprecated("recreate-pods", "functionality will no longer be updated. Consult the documentation for other methods to recreate pods")
@@ -267,9 +358,15 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.ResetValues, "reset-values", false, "when upgrading, reset the values to the ones built into the chart")
 	f.BoolVar(&client.ReuseValues, "reuse-values", false, "when upgrading, reuse the last release's values and merge in any overrides from the command line via --set and -f. If '--reset-values' is specified, this is ignored")
 	f.BoolVar(&client.ResetThenReuseValues, "reset-then-reuse-values", false, "when upgrading, reset the values to the ones built into the chart, apply the last release's values and merge in any overrides from the command line via --set and -f. If '--reset-values' or '--reuse-values' is specified, this is ignored")
+	f.StringSliceVar(&client.ReuseValuesFor, "reuse-values-for", []string{}, "when upgrading, reset the values to the ones built into the chart, apply the last release's values at the given dotted paths only (e.g. image,ingress.hosts) and merge in any overrides from the command line via --set and -f. If '--reset-values' or '--reuse-values' is specified, this is ignored")
 	f.BoolVar(&client.Wait, "wait", false, "if set, will wait until all Pods, PVCs, Services, and minimum number of Pods of a Deployment, StatefulSet, or ReplicaSet are in a ready state before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.Atomic, "atomic", false, "if set, upgrade process rolls back changes made in case of failed upgrade. The --wait flag will be set automatically if --atomic is used")
+	f.StringVar((*string)(&client.AtomicStrategy), "atomic-strategy", string(action.AtomicRollback), "how a failed upgrade is reverted with --atomic: 'rollback' rolls the release back to the last successful revision, 'scoped' only reverts the resources changed by the upgrade")
+	f.IntVar(&retryPolicy.Attempts, "retry-attempts", 1, "how often the resources are applied and waited for at most when doing so fails, including the first time")
+	f.DurationVar(&retryPolicy.Backoff, "retry-backoff", 5*time.Second, "time to wait before the first retry of --retry-attempts, doubled with every further retry")
+	f.DurationVar(&retryPolicy.MaxBackoff, "retry-max-backoff", 5*time.Minute, "time to wait before a retry of --retry-attempts at most")
+	f.StringSliceVar(&retryOn, "retry-on", []string{string(action.RetryWaitTimeout), string(action.RetryTransient)}, "the classes of failures retried with --retry-attempts: wait-timeout, transient")
 	f.IntVar(&client.MaxHistory, "history-max", settings.MaxHistory, "limit the maximum number of revisions saved per release. Use 0 for no limit")
 	f.BoolVar(&client.CleanupOnFail, "cleanup-on-fail", false, "allow deletion of new resources created in this upgrade when upgrade fails")
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
@@ -278,6 +375,12 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&client.Description, "description", "", "add a custom description")
 	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing before installing the chart")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
+	f.BoolVar(&client.LabelResources, "label-resources", false, "stamp all resources with labels for the release name, revision, chart and manager, so that they can be selected with 'kubectl get -l app.kubernetes.io/instance=RELEASE'")
+	f.StringVar(&client.DeployedBy, "deployed-by", "", "record who performs the operation, e.g. a person or a pipeline, in the release history")
+	f.BoolVar(&analyzeImpact, "analyze-impact", false, "print which workloads will roll out new pods, whether their PodDisruptionBudgets permit it and which resources change in place before applying the upgrade")
+	f.StringVar((*string)(&client.SkipIfUnchanged), "skip-if-unchanged", "", "do not create a new revision if the upgrade would not change the deployed revision. Must be \"server\" to also require a server-side dry run to find that applying the resources would not change them, which is the default without a value, or \"local\" to only compare the chart, values and rendered manifests")
+	f.Lookup("skip-if-unchanged").NoOptDefVal = string(action.SkipIfUnchangedServer)
+	f.BoolVar(&client.SkipUnchanged, "skip-unchanged", false, "skip re-applying resources whose rendered manifest is identical to the deployed revision. Changes made to those resources outside of Helm are not reverted")
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 	addValueOptionsFlags(f, valueOpts)
 	bindOutputFlag(cmd, &outfmt)
@@ -0,0 +1,108 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// artifactHubSearchPath is the path of the package search API of the
+// Artifact Hub.
+const artifactHubSearchPath = "/api/v1/packages/search"
+
+// artifactHubPageSize is the largest page the Artifact Hub returns.
+const artifactHubPageSize = 60
+
+// artifactHubKindHelm is the kind of Helm chart packages.
+const artifactHubKindHelm = "0"
+
+// artifactHub searches the native API of the Artifact Hub, which supports
+// filtering by license and verified publisher. Signatures are filtered on
+// the results.
+type artifactHub struct {
+	endpoint string
+	header   http.Header
+}
+
+type artifactHubResponse struct {
+	Packages []artifactHubPackage `json:"packages"`
+}
+
+type artifactHubPackage struct {
+	Name           string `json:"name"`
+	NormalizedName string `json:"normalized_name"`
+	Version        string `json:"version"`
+	AppVersion     string `json:"app_version"`
+	Description    string `json:"description"`
+	License        string `json:"license"`
+	Signed         bool   `json:"signed"`
+	Repository     struct {
+		Name              string `json:"name"`
+		URL               string `json:"url"`
+		VerifiedPublisher bool   `json:"verified_publisher"`
+	} `json:"repository"`
+}
+
+// Search requests the results a page at a time, passing them on as each
+// page is received.
+func (a *artifactHub) Search(q Query, fn func(Chart) error) error {
+	for offset := 0; ; offset += artifactHubPageSize {
+		v := url.Values{}
+		v.Set("kind", artifactHubKindHelm)
+		v.Set("limit", strconv.Itoa(artifactHubPageSize))
+		v.Set("offset", strconv.Itoa(offset))
+		if q.Term != "" {
+			v.Set("ts_query_web", q.Term)
+		}
+		if q.License != "" {
+			v.Set("license", q.License)
+		}
+		if q.VerifiedPublisherOnly {
+			v.Set("verified_publisher", "true")
+		}
+
+		res := &artifactHubResponse{}
+		header, err := get(a.endpoint+artifactHubSearchPath+"?"+v.Encode(), a.header, res)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range res.Packages {
+			c := Chart{
+				Name:              p.Name,
+				URL:               a.endpoint + "/packages/helm/" + p.Repository.Name + "/" + p.NormalizedName,
+				Version:           p.Version,
+				AppVersion:        p.AppVersion,
+				Description:       p.Description,
+				License:           p.License,
+				Signed:            p.Signed,
+				VerifiedPublisher: p.Repository.VerifiedPublisher,
+				Repository:        Repository{URL: p.Repository.URL, Name: p.Repository.Name},
+			}
+			if done, err := emit(q, c, fn); done {
+				return err
+			}
+		}
+
+		total, err := strconv.Atoi(header.Get("Pagination-Total-Count"))
+		if len(res.Packages) < artifactHubPageSize || (err == nil && offset+len(res.Packages) >= total) {
+			return nil
+		}
+	}
+}
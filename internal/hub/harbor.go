@@ -0,0 +1,150 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// harborSearchPath is the path of the search API of Harbor.
+const harborSearchPath = "/api/v2.0/search"
+
+// harborArtifactTypeChart is the type of Helm chart artifacts.
+const harborArtifactTypeChart = "CHART"
+
+// harbor searches a Harbor registry. Charts stored as OCI artifacts are
+// found through their repositories, charts of the legacy ChartMuseum
+// integration are returned by the search directly.
+type harbor struct {
+	endpoint string
+	header   http.Header
+}
+
+type harborSearchResponse struct {
+	Repository []struct {
+		ProjectName    string `json:"project_name"`
+		RepositoryName string `json:"repository_name"`
+	} `json:"repository"`
+	Chart []struct {
+		Name  string `json:"Name"`
+		Chart struct {
+			Name        string   `json:"name"`
+			Version     string   `json:"version"`
+			AppVersion  string   `json:"app_version"`
+			Description string   `json:"description"`
+			URLs        []string `json:"urls"`
+		} `json:"Chart"`
+	} `json:"chart"`
+}
+
+type harborArtifact struct {
+	Type string `json:"type"`
+	Tags []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	ExtraAttrs struct {
+		AppVersion  string `json:"appVersion"`
+		Description string `json:"description"`
+	} `json:"extra_attrs"`
+	Accessories []struct {
+		Type string `json:"type"`
+	} `json:"accessories"`
+}
+
+// Search searches the registry. Harbor has no notion of licenses or
+// verified publishers, so those filters are not supported.
+func (h *harbor) Search(q Query, fn func(Chart) error) error {
+	if q.License != "" || q.VerifiedPublisherOnly {
+		return errors.New("the harbor provider does not support filtering by license or publisher")
+	}
+
+	res := &harborSearchResponse{}
+	if _, err := get(h.endpoint+harborSearchPath+"?q="+url.QueryEscape(q.Term), h.header, res); err != nil {
+		return err
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(h.endpoint, "https://"), "http://")
+	for _, r := range res.Repository {
+		artifact, err := h.latestArtifact(r.ProjectName, r.RepositoryName)
+		if err != nil {
+			return err
+		}
+		// Repositories of container images are found as well.
+		if artifact == nil || artifact.Type != harborArtifactTypeChart {
+			continue
+		}
+		c := Chart{
+			Name:        r.RepositoryName[strings.LastIndex(r.RepositoryName, "/")+1:],
+			URL:         "oci://" + host + "/" + r.RepositoryName,
+			AppVersion:  artifact.ExtraAttrs.AppVersion,
+			Description: artifact.ExtraAttrs.Description,
+			Repository:  Repository{URL: "oci://" + host + "/" + r.ProjectName, Name: r.ProjectName},
+		}
+		if len(artifact.Tags) > 0 {
+			c.Version = artifact.Tags[0].Name
+		}
+		for _, a := range artifact.Accessories {
+			if strings.HasPrefix(a.Type, "signature.") {
+				c.Signed = true
+			}
+		}
+		if done, err := emit(q, c, fn); done {
+			return err
+		}
+	}
+
+	for _, r := range res.Chart {
+		project := r.Name
+		if i := strings.Index(r.Name, "/"); i >= 0 {
+			project = r.Name[:i]
+		}
+		c := Chart{
+			Name:        r.Chart.Name,
+			URL:         h.endpoint + "/chartrepo/" + r.Name,
+			Version:     r.Chart.Version,
+			AppVersion:  r.Chart.AppVersion,
+			Description: r.Chart.Description,
+			Repository:  Repository{URL: h.endpoint + "/chartrepo/" + project, Name: project},
+		}
+		if done, err := emit(q, c, fn); done {
+			return err
+		}
+	}
+	return nil
+}
+
+// latestArtifact returns the most recently pushed artifact of a repository,
+// or nil if it has none.
+func (h *harbor) latestArtifact(project, repository string) (*harborArtifact, error) {
+	// Repository names are escaped twice as they can contain slashes.
+	name := url.PathEscape(url.PathEscape(strings.TrimPrefix(repository, project+"/")))
+	u := h.endpoint + "/api/v2.0/projects/" + url.PathEscape(project) + "/repositories/" + name +
+		"/artifacts?page_size=1&with_tag=true&with_accessory=true"
+
+	var artifacts []harborArtifact
+	if _, err := get(u, h.header, &artifacts); err != nil {
+		return nil, err
+	}
+	if len(artifacts) == 0 {
+		return nil, nil
+	}
+	return &artifacts[0], nil
+}
@@ -0,0 +1,241 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hub provides a common interface for searching chart hubs, such as
+// the Artifact Hub, Harbor or internal catalogs implementing the Monocular
+// search API.
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/version"
+)
+
+// The providers of hubs.
+const (
+	// ProviderMonocular is a hub implementing the Monocular search API, which
+	// includes the Artifact Hub.
+	ProviderMonocular = "monocular"
+	// ProviderArtifactHub is the native API of the Artifact Hub.
+	ProviderArtifactHub = "artifacthub"
+	// ProviderHarbor is the API of a Harbor registry.
+	ProviderHarbor = "harbor"
+)
+
+// Providers are the names of the supported providers.
+var Providers = []string{ProviderMonocular, ProviderArtifactHub, ProviderHarbor}
+
+// DefaultEndpoints are the endpoints used when a hub does not set one.
+var DefaultEndpoints = map[string]string{
+	ProviderMonocular:   "https://hub.helm.sh",
+	ProviderArtifactHub: "https://artifacthub.io",
+}
+
+// ErrStop can be returned by the function passed to Search to end the search
+// early without an error.
+var ErrStop = errors.New("stop searching")
+
+// Query describes the charts to search for.
+type Query struct {
+	// Term is the keyword to search for.
+	Term string
+	// License only matches charts with the given SPDX license identifier.
+	License string
+	// SignedOnly only matches signed charts.
+	SignedOnly bool
+	// VerifiedPublisherOnly only matches charts of verified publishers.
+	VerifiedPublisherOnly bool
+}
+
+// filtered returns true if the query filters by more than the term.
+func (q Query) filtered() bool {
+	return q.License != "" || q.SignedOnly || q.VerifiedPublisherOnly
+}
+
+// Match returns true if the chart satisfies the filters of the query.
+func (q Query) Match(c Chart) bool {
+	if q.License != "" && !strings.EqualFold(q.License, c.License) {
+		return false
+	}
+	if q.SignedOnly && !c.Signed {
+		return false
+	}
+	if q.VerifiedPublisherOnly && !c.VerifiedPublisher {
+		return false
+	}
+	return true
+}
+
+// Chart is a chart found in a hub.
+type Chart struct {
+	Name              string     `json:"name"`
+	URL               string     `json:"url"`
+	Version           string     `json:"version"`
+	AppVersion        string     `json:"app_version"`
+	Description       string     `json:"description"`
+	License           string     `json:"license,omitempty"`
+	Signed            bool       `json:"signed,omitempty"`
+	VerifiedPublisher bool       `json:"verified_publisher,omitempty"`
+	Repository        Repository `json:"repository"`
+}
+
+// Repository is the repository a chart is published to.
+type Repository struct {
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+// Provider searches a hub for charts.
+type Provider interface {
+	// Search calls fn for each chart matching the query as the results are
+	// received from the hub. Returning ErrStop from fn ends the search.
+	Search(q Query, fn func(Chart) error) error
+}
+
+// Config describes how to connect to a hub.
+type Config struct {
+	// Name identifies the hub in the hubs file.
+	Name string `json:"name"`
+	// Provider is the API implemented by the hub. It defaults to
+	// ProviderMonocular.
+	Provider string `json:"provider,omitempty"`
+	// Endpoint is the URL of the hub.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Token is sent as a bearer token.
+	Token string `json:"token,omitempty"`
+	// Username and Password are sent using basic authentication.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Headers are added to every request, for example the API key headers
+	// of the Artifact Hub.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// New creates the provider of the hub described by the config.
+func New(c Config) (Provider, error) {
+	if c.Provider == "" {
+		c.Provider = ProviderMonocular
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = DefaultEndpoints[c.Provider]
+	}
+	if c.Endpoint == "" {
+		return nil, errors.Errorf("an endpoint is required for the %s provider", c.Provider)
+	}
+	endpoint := strings.TrimSuffix(c.Endpoint, "/")
+
+	switch c.Provider {
+	case ProviderMonocular:
+		return newMonocular(endpoint, c.header())
+	case ProviderArtifactHub:
+		return &artifactHub{endpoint: endpoint, header: c.header()}, nil
+	case ProviderHarbor:
+		return &harbor{endpoint: endpoint, header: c.header()}, nil
+	}
+	return nil, errors.Errorf("unknown hub provider %q, must be one of %s", c.Provider, strings.Join(Providers, ", "))
+}
+
+func (c Config) header() http.Header {
+	h := http.Header{}
+	for k, v := range c.Headers {
+		h.Set(k, v)
+	}
+	if c.Token != "" {
+		h.Set("Authorization", "Bearer "+c.Token)
+	} else if c.Username != "" || c.Password != "" {
+		req := &http.Request{Header: h}
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return h
+}
+
+// File is a file of named hubs.
+type File struct {
+	Hubs []Config `json:"hubs"`
+}
+
+// LoadFile loads a hubs file.
+func LoadFile(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load hubs file (%s)", path)
+	}
+	f := &File{}
+	if err := yaml.UnmarshalStrict(b, f); err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse hubs file (%s)", path)
+	}
+	return f, nil
+}
+
+// Get returns the hub with the given name.
+func (f *File) Get(name string) (Config, error) {
+	for _, c := range f.Hubs {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return Config{}, errors.Errorf("no hub named %q", name)
+}
+
+// get decodes the JSON response to a GET request into v.
+func get(u string, header http.Header, v interface{}) (http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("User-Agent", version.GetUserAgent())
+	req.Header.Set("Accept", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s : %s", u, res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the response of %s", u)
+	}
+	return res.Header, nil
+}
+
+// emit passes the chart to fn if it matches the query. It returns done when
+// the search must end, along with the error to end it with.
+func emit(q Query, c Chart, fn func(Chart) error) (done bool, err error) {
+	if !q.Match(c) {
+		return false, nil
+	}
+	if err := fn(c); err != nil {
+		if err == ErrStop {
+			return true, nil
+		}
+		return true, err
+	}
+	return false, nil
+}
@@ -0,0 +1,218 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func artifactHubServer(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != artifactHubSearchPath {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.Header.Get("X-API-KEY-ID"); got != "key" {
+			t.Errorf("expected the API key header, got %q", got)
+		}
+		q := r.URL.Query()
+		if q.Get("kind") != "0" || q.Get("ts_query_web") != "nginx" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		res := artifactHubResponse{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			p := artifactHubPackage{
+				Name:           fmt.Sprintf("nginx-%d", i),
+				NormalizedName: fmt.Sprintf("nginx-%d", i),
+				Version:        "1.0.0",
+				License:        q.Get("license"),
+				Signed:         i%2 == 0,
+			}
+			p.Repository.Name = "repo"
+			p.Repository.VerifiedPublisher = q.Get("verified_publisher") == "true"
+			res.Packages = append(res.Packages, p)
+		}
+		w.Header().Set("Pagination-Total-Count", strconv.Itoa(total))
+		json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func search(t *testing.T, p Provider, q Query, limit int) ([]Chart, error) {
+	t.Helper()
+	var charts []Chart
+	err := p.Search(q, func(c Chart) error {
+		charts = append(charts, c)
+		if limit > 0 && len(charts) == limit {
+			return ErrStop
+		}
+		return nil
+	})
+	return charts, err
+}
+
+func TestArtifactHubSearch(t *testing.T) {
+	ts := artifactHubServer(t, 130)
+	p, err := New(Config{Provider: ProviderArtifactHub, Endpoint: ts.URL, Headers: map[string]string{"X-API-KEY-ID": "key"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	charts, err := search(t, p, Query{Term: "nginx"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 130 {
+		t.Fatalf("expected all 130 charts across the pages, got %d", len(charts))
+	}
+	if charts[0].URL != ts.URL+"/packages/helm/repo/nginx-0" {
+		t.Errorf("unexpected URL %q", charts[0].URL)
+	}
+
+	charts, err = search(t, p, Query{Term: "nginx", License: "Apache-2.0", SignedOnly: true, VerifiedPublisherOnly: true}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 65 {
+		t.Fatalf("expected the 65 signed charts, got %d", len(charts))
+	}
+	for _, c := range charts {
+		if !c.Signed || !c.VerifiedPublisher || c.License != "Apache-2.0" {
+			t.Errorf("chart %s does not match the filters", c.Name)
+		}
+	}
+
+	charts, err = search(t, p, Query{Term: "nginx"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 5 {
+		t.Errorf("expected the search to stop after 5 charts, got %d", len(charts))
+	}
+}
+
+func TestMonocularSearch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("expected the bearer token, got %q", got)
+		}
+		fmt.Fprintln(w, `{"data":[{"id":"stable/mariadb","attributes":{"name":"mariadb","repo":{"name":"stable","url":"https://charts.helm.sh/stable"}},"relationships":{"latestChartVersion":{"data":{"version":"7.3.14"}}}}]}`)
+	}))
+	defer ts.Close()
+
+	p, err := New(Config{Endpoint: ts.URL, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	charts, err := search(t, p, Query{Term: "mariadb"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 1 || charts[0].URL != ts.URL+"/charts/stable/mariadb" || charts[0].Version != "7.3.14" {
+		t.Errorf("unexpected charts %+v", charts)
+	}
+
+	if _, err := search(t, p, Query{Term: "mariadb", SignedOnly: true}, 0); err == nil {
+		t.Error("expected an error filtering by signature")
+	}
+}
+
+func TestHarborSearch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "secret" {
+			t.Errorf("expected basic authentication")
+		}
+		switch r.URL.EscapedPath() {
+		case harborSearchPath:
+			fmt.Fprintln(w, `{"repository":[{"project_name":"library","repository_name":"library/charts/nginx"},{"project_name":"library","repository_name":"library/alpine"}],"chart":[{"Name":"legacy/redis","Chart":{"name":"redis","version":"10.0.0"}}]}`)
+		case "/api/v2.0/projects/library/repositories/charts%252Fnginx/artifacts":
+			fmt.Fprintln(w, `[{"type":"CHART","tags":[{"name":"1.2.3"}],"extra_attrs":{"appVersion":"1.25","description":"NGINX"},"accessories":[{"type":"signature.cosign"}]}]`)
+		case "/api/v2.0/projects/library/repositories/alpine/artifacts":
+			fmt.Fprintln(w, `[{"type":"IMAGE","tags":[{"name":"3.19"}]}]`)
+		default:
+			t.Errorf("unexpected path %q", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p, err := New(Config{Provider: ProviderHarbor, Endpoint: ts.URL, Username: "me", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	charts, err := search(t, p, Query{Term: "n"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 2 {
+		t.Fatalf("expected 2 charts, got %+v", charts)
+	}
+	nginx := charts[0]
+	if nginx.Name != "nginx" || nginx.Version != "1.2.3" || nginx.AppVersion != "1.25" || !nginx.Signed {
+		t.Errorf("unexpected chart %+v", nginx)
+	}
+	if charts[1].Name != "redis" || charts[1].Repository.Name != "legacy" {
+		t.Errorf("unexpected chart %+v", charts[1])
+	}
+
+	charts, err = search(t, p, Query{Term: "n", SignedOnly: true}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 1 || charts[0].Name != "nginx" {
+		t.Errorf("expected only the signed chart, got %+v", charts)
+	}
+
+	if _, err := New(Config{Provider: ProviderHarbor}); err == nil {
+		t.Error("expected an error without an endpoint")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hubs.yaml")
+	if err := os.WriteFile(path, []byte("hubs:\n- name: internal\n  provider: harbor\n  endpoint: https://harbor.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := f.Get("internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Provider != ProviderHarbor || c.Endpoint != "https://harbor.example.com" {
+		t.Errorf("unexpected hub %+v", c)
+	}
+	if _, err := f.Get("missing"); err == nil {
+		t.Error("expected an error for a missing hub")
+	}
+
+	if _, err := New(Config{Provider: "unknown", Endpoint: "https://example.com"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
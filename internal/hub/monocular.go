@@ -0,0 +1,75 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/monocular"
+)
+
+// monocularProvider searches hubs implementing the Monocular search API.
+type monocularProvider struct {
+	endpoint string
+	client   *monocular.Client
+}
+
+func newMonocular(endpoint string, header http.Header) (Provider, error) {
+	c, err := monocular.New(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	c.Header = header
+	return &monocularProvider{endpoint: endpoint, client: c}, nil
+}
+
+// Search searches the hub. The Monocular API returns all results at once and
+// does not expose licenses, signatures or publishers, so those filters are
+// not supported.
+func (m *monocularProvider) Search(q Query, fn func(Chart) error) error {
+	if q.filtered() {
+		return errors.New("the monocular provider does not support filtering by license, signature or publisher")
+	}
+	results, err := m.client.Search(q.Term)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		// Backwards compatibility for Monocular
+		url := m.endpoint + "/charts/" + r.ID
+
+		// Check for artifactHub compatibility
+		if r.ArtifactHub.PackageURL != "" {
+			url = r.ArtifactHub.PackageURL
+		}
+
+		c := Chart{
+			Name:        r.Attributes.Name,
+			URL:         url,
+			Version:     r.Relationships.LatestChartVersion.Data.Version,
+			AppVersion:  r.Relationships.LatestChartVersion.Data.AppVersion,
+			Description: r.Attributes.Description,
+			Repository:  Repository{URL: r.Attributes.Repo.URL, Name: r.Attributes.Repo.Name},
+		}
+		if done, err := emit(q, c, fn); done {
+			return err
+		}
+	}
+	return nil
+}
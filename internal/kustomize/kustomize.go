@@ -0,0 +1,234 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kustomize builds kustomizations into the objects of charts.
+package kustomize
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Objects builds the kustomization in a directory, and returns the resulting
+// objects with the paths of the fields set by the patches of the
+// kustomization as their parameters.
+func Objects(dir string) ([]*chartutil.ManifestObject, error) {
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "building the kustomization in %s", dir)
+	}
+	var objects []*chartutil.ManifestObject
+	for _, r := range resources.Resources() {
+		obj, err := r.Map()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, &chartutil.ManifestObject{Object: obj})
+	}
+
+	k, err := readKustomization(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range kustomizationPatches(k) {
+		if err := liftPatch(dir, k, p, objects); err != nil {
+			return nil, err
+		}
+	}
+
+	return objects, nil
+}
+
+func readKustomization(dir string) (*types.Kustomization, error) {
+	for _, n := range konfig.RecognizedKustomizationFileNames() {
+		data, err := os.ReadFile(filepath.Join(dir, n))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		k := &types.Kustomization{}
+		if err := k.Unmarshal(data); err != nil {
+			return nil, errors.Wrapf(err, "reading %s", n)
+		}
+		return k, nil
+	}
+	return nil, errors.Errorf("no kustomization file found in %s", dir)
+}
+
+// kustomizationPatches returns the patches of a kustomization, including the
+// deprecated strategic merge and JSON patches.
+func kustomizationPatches(k *types.Kustomization) []types.Patch {
+	patches := append([]types.Patch{}, k.Patches...)
+	patches = append(patches, k.PatchesJson6902...)
+	for _, p := range k.PatchesStrategicMerge {
+		// A strategic merge patch is either a path or inline content.
+		if strings.Contains(string(p), "\n") {
+			patches = append(patches, types.Patch{Patch: string(p)})
+		} else {
+			patches = append(patches, types.Patch{Path: string(p)})
+		}
+	}
+	return patches
+}
+
+// liftPatch adds the paths of the fields set by a patch to the parameters of
+// the objects it targets.
+func liftPatch(dir string, k *types.Kustomization, p types.Patch, objects []*chartutil.ManifestObject) error {
+	content := []byte(p.Patch)
+	if p.Path != "" {
+		var err error
+		if content, err = os.ReadFile(filepath.Join(dir, p.Path)); err != nil {
+			return err
+		}
+	}
+
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+	for {
+		var patch interface{}
+		if err := decoder.Decode(&patch); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrapf(err, "reading patch %s", p.Path)
+		}
+
+		var paths [][]string
+		target := p.Target
+		switch patch := patch.(type) {
+		case []interface{}:
+			// A JSON patch.
+			for _, op := range patch {
+				op, _ := op.(map[string]interface{})
+				if path, ok := op["path"].(string); ok && op["op"] != "remove" {
+					paths = append(paths, jsonPointer(path))
+				}
+			}
+		case map[string]interface{}:
+			// A strategic merge patch, targeting the object it names unless a
+			// target is given.
+			if target == nil {
+				target = &types.Selector{}
+				target.Kind, _ = patch["kind"].(string)
+				if metadata, ok := patch["metadata"].(map[string]interface{}); ok {
+					target.Name, _ = metadata["name"].(string)
+				}
+			}
+			paths = leafPaths(patch, nil)
+		}
+		if target == nil || len(paths) == 0 {
+			continue
+		}
+
+		selector, err := types.NewSelectorRegex(target)
+		if err != nil {
+			return err
+		}
+		for _, o := range objects {
+			if matchesTarget(o.Object, k, target, selector) {
+				o.Parameters = append(o.Parameters, paths...)
+			}
+		}
+	}
+}
+
+// matchesTarget reports whether an object built from a kustomization is a
+// target of a patch. The name prefix and suffix of the kustomization are
+// ignored, as patches name the objects before they are renamed.
+func matchesTarget(obj map[string]interface{}, k *types.Kustomization, target *types.Selector, selector *types.SelectorRegex) bool {
+	var meta struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			Labels      map[string]string `json:"labels"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	data, err := yaml.Marshal(obj)
+	if err != nil || yaml.Unmarshal(data, &meta) != nil {
+		return false
+	}
+
+	gv, err := schema.ParseGroupVersion(meta.APIVersion)
+	if err != nil || !selector.MatchGvk(resid.Gvk{Group: gv.Group, Version: gv.Version, Kind: meta.Kind}) {
+		return false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(meta.Metadata.Name, k.NamePrefix), k.NameSuffix)
+	if !selector.MatchName(name) && !selector.MatchName(meta.Metadata.Name) {
+		return false
+	}
+	if target.Namespace != "" && !selector.MatchNamespace(meta.Metadata.Namespace) {
+		return false
+	}
+	for sel, set := range map[string]map[string]string{target.LabelSelector: meta.Metadata.Labels, target.AnnotationSelector: meta.Metadata.Annotations} {
+		if sel == "" {
+			continue
+		}
+		s, err := labels.Parse(sel)
+		if err != nil || !s.Matches(labels.Set(set)) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonPointer splits a JSON pointer into its keys.
+func jsonPointer(pointer string) []string {
+	keys := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, k := range keys {
+		keys[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(k)
+	}
+	return keys
+}
+
+// leafPaths returns the paths of the scalars, lists and empty tables set by a
+// strategic merge patch, leaving out the fields identifying the object and the
+// patch directives.
+func leafPaths(patch map[string]interface{}, prefix []string) [][]string {
+	var paths [][]string
+	for k, v := range patch {
+		if strings.HasPrefix(k, "$") || (len(prefix) == 0 && (k == "apiVersion" || k == "kind")) {
+			continue
+		}
+		path := append(append([]string{}, prefix...), k)
+		if len(prefix) == 1 && prefix[0] == "metadata" && (k == "name" || k == "namespace") {
+			continue
+		}
+		if table, ok := v.(map[string]interface{}); ok && len(table) > 0 {
+			paths = append(paths, leafPaths(table, path)...)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
@@ -0,0 +1,69 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestJSONPointer(t *testing.T) {
+	got := jsonPointer("/metadata/annotations/example.com~1owner~0team")
+	expect := []string{"metadata", "annotations", "example.com/owner~team"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %q, got %q", expect, got)
+	}
+}
+
+func TestLeafPaths(t *testing.T) {
+	patch := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":   "web",
+			"labels": map[string]interface{}{"env": "production"},
+		},
+		"spec": map[string]interface{}{
+			"replicas":  6,
+			"$patch":    "merge",
+			"template":  map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{}}},
+			"selector":  map[string]interface{}{},
+			"paused":    false,
+			"strategy":  map[string]interface{}{"type": "Recreate"},
+			"revisions": nil,
+		},
+	}
+	var got []string
+	for _, p := range leafPaths(patch, nil) {
+		got = append(got, strings.Join(p, "."))
+	}
+	sort.Strings(got)
+	expect := []string{
+		"metadata.labels.env",
+		"spec.paused",
+		"spec.replicas",
+		"spec.revisions",
+		"spec.selector",
+		"spec.strategy.type",
+		"spec.template.spec.containers",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected paths %q, got %q", expect, got)
+	}
+}
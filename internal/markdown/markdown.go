@@ -0,0 +1,238 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package markdown reads the Markdown of chart READMEs: it selects sections,
+// extracts tables and renders documents for display in a terminal.
+//
+// It understands the subset of Markdown commonly found in READMEs rather than
+// implementing all of CommonMark.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	atxHeading     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextHeading  = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	fence          = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	tableDelimiter = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+)
+
+// heading returns the level and title of a heading starting at the line i,
+// and the number of lines it spans. The level is 0 if the line does not
+// start a heading.
+func heading(lines []string, i int) (level int, title string, n int) {
+	if m := atxHeading.FindStringSubmatch(lines[i]); m != nil {
+		return len(m[1]), strings.TrimSpace(m[2]), 1
+	}
+	if i+1 < len(lines) && strings.TrimSpace(lines[i]) != "" && !strings.HasPrefix(strings.TrimSpace(lines[i]), "|") {
+		if m := setextHeading.FindStringSubmatch(lines[i+1]); m != nil {
+			level := 1
+			if m[1][0] == '-' {
+				level = 2
+			}
+			return level, strings.TrimSpace(lines[i]), 2
+		}
+	}
+	return 0, "", 0
+}
+
+// fences tracks whether lines are part of a fenced code block.
+type fences struct {
+	open string
+}
+
+// code returns true if the line is part of a code block, including the
+// fences themselves.
+func (f *fences) code(line string) bool {
+	m := fence.FindStringSubmatch(line)
+	switch {
+	case f.open != "":
+		if m != nil && m[1][0] == f.open[0] && len(m[1]) >= len(f.open) && strings.TrimSpace(line[len(m[0]):]) == "" {
+			f.open = ""
+		}
+		return true
+	case m != nil:
+		f.open = m[1]
+		return true
+	}
+	return false
+}
+
+func splitLines(doc []byte) []string {
+	return strings.Split(strings.ReplaceAll(string(doc), "\r\n", "\n"), "\n")
+}
+
+// Section returns the section of the document under the first heading with
+// the given title, compared case-insensitively, including its subsections.
+// It returns false if there is no such heading.
+func Section(doc []byte, title string) ([]byte, bool) {
+	lines := splitLines(doc)
+	var f fences
+	start, level := -1, 0
+	for i := 0; i < len(lines); i++ {
+		if f.code(lines[i]) {
+			continue
+		}
+		l, t, n := heading(lines, i)
+		if l == 0 {
+			continue
+		}
+		if start >= 0 && l <= level {
+			return []byte(strings.TrimRight(strings.Join(lines[start:i], "\n"), "\n") + "\n"), true
+		}
+		if start < 0 && strings.EqualFold(stripInline(t), strings.TrimSpace(title)) {
+			start, level = i, l
+		}
+		i += n - 1
+	}
+	if start < 0 {
+		return nil, false
+	}
+	return []byte(strings.TrimRight(strings.Join(lines[start:], "\n"), "\n") + "\n"), true
+}
+
+// Table is a table of a document.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Tables returns the tables of the document.
+func Tables(doc []byte) []Table {
+	lines := splitLines(doc)
+	var f fences
+	var tables []Table
+	for i := 0; i < len(lines); i++ {
+		if f.code(lines[i]) {
+			continue
+		}
+		if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || !tableDelimiter.MatchString(lines[i+1]) {
+			continue
+		}
+		t := Table{Header: cells(lines[i])}
+		for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+			t.Rows = append(t.Rows, cells(lines[i]))
+		}
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+// ValuesTables returns the tables of the document that describe the values
+// of a chart, as written by hand or by generators such as helm-docs: their
+// first column names the value and another one holds its default.
+func ValuesTables(doc []byte) []Table {
+	var tables []Table
+	for _, t := range Tables(doc) {
+		if len(t.Header) < 2 {
+			continue
+		}
+		switch strings.ToLower(stripInline(t.Header[0])) {
+		case "parameter", "parameters", "key", "name", "value":
+		default:
+			continue
+		}
+		for _, h := range t.Header[1:] {
+			if h := strings.ToLower(stripInline(h)); strings.Contains(h, "default") || h == "value" {
+				tables = append(tables, t)
+				break
+			}
+		}
+	}
+	return tables
+}
+
+// String formats the table as Markdown with aligned columns.
+func (t Table) String() string {
+	escape := func(cells []string) []string {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = strings.ReplaceAll(c, "|", `\|`)
+		}
+		return escaped
+	}
+	header := escape(t.Header)
+	rows := make([][]string, len(t.Rows))
+	for i, r := range t.Rows {
+		rows[i] = escape(r)
+	}
+
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = max(len(h), 3)
+	}
+	for _, r := range rows {
+		for i, c := range r {
+			if i < len(widths) && len(c) > widths[i] {
+				widths[i] = len(c)
+			}
+		}
+	}
+
+	var b strings.Builder
+	row := func(cells []string) {
+		b.WriteString("|")
+		for i, w := range widths {
+			c := ""
+			if i < len(cells) {
+				c = cells[i]
+			}
+			b.WriteString(" " + c + strings.Repeat(" ", w-len(c)) + " |")
+		}
+		b.WriteString("\n")
+	}
+	row(header)
+	delimiters := make([]string, len(widths))
+	for i, w := range widths {
+		delimiters[i] = strings.Repeat("-", w)
+	}
+	row(delimiters)
+	for _, r := range rows {
+		row(r)
+	}
+	return b.String()
+}
+
+// cells splits a table row into its cells, keeping escaped pipes.
+func cells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	code := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '`':
+			code = !code
+			cell.WriteByte('`')
+		case line[i] == '|' && !code:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
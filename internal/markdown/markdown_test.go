@@ -0,0 +1,136 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const readme = `# Chart
+
+An introduction.
+
+## Installing
+
+` + "```console\n# helm install my-release example/chart\n```" + `
+
+## Configuration
+
+The following table lists the parameters.
+
+### Image
+
+| Parameter | Description | Default |
+|-----------|-------------|---------|
+| ` + "`image.tag`" + ` | The tag \| digest | ` + "`1.0`" + ` |
+| image.pullPolicy | The pull policy | IfNotPresent |
+
+Resources
+---------
+
+| Resource | Kind |
+|---|---|
+| web | Deployment |
+
+## Uninstalling
+
+Run helm uninstall.
+`
+
+func TestSection(t *testing.T) {
+	section, ok := Section([]byte(readme), "configuration")
+	if !ok {
+		t.Fatal("expected the configuration section")
+	}
+	s := string(section)
+	if !strings.HasPrefix(s, "## Configuration\n") || !strings.Contains(s, "### Image") {
+		t.Errorf("expected the section with its subsections, got\n%s", s)
+	}
+	if strings.Contains(s, "Resources") || strings.Contains(s, "Uninstalling") {
+		t.Errorf("expected the section to end at the next heading of its level, got\n%s", s)
+	}
+
+	// Headings in code blocks do not count.
+	if _, ok := Section([]byte(readme), "helm install my-release example/chart"); ok {
+		t.Error("expected no section for a comment in a code block")
+	}
+
+	section, ok = Section([]byte(readme), "Resources")
+	if !ok || !strings.HasPrefix(string(section), "Resources\n---------\n") {
+		t.Errorf("expected the section of a setext heading, got %q", section)
+	}
+
+	section, ok = Section([]byte(readme), "Uninstalling")
+	if !ok || string(section) != "## Uninstalling\n\nRun helm uninstall.\n" {
+		t.Errorf("unexpected last section %q", section)
+	}
+}
+
+func TestValuesTables(t *testing.T) {
+	if n := len(Tables([]byte(readme))); n != 2 {
+		t.Errorf("expected 2 tables, got %d", n)
+	}
+
+	tables := ValuesTables([]byte(readme))
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 values table, got %d", len(tables))
+	}
+	expect := "| Parameter        | Description       | Default      |\n" +
+		"| ---------------- | ----------------- | ------------ |\n" +
+		"| `image.tag`      | The tag \\| digest | `1.0`        |\n" +
+		"| image.pullPolicy | The pull policy   | IfNotPresent |\n"
+	if got := tables[0].String(); got != expect {
+		t.Errorf("expected\n%s\ngot\n%s", expect, got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	out := Render([]byte(readme), 40)
+	plain := stripStyles(out)
+
+	for _, s := range []string{
+		styleH1 + "Chart" + reset,
+		styleCode + "# helm install my-release example/chart" + reset,
+		styleCode + "image.tag" + reset,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in the output\n%s", s, out)
+		}
+	}
+	for _, s := range []string{
+		"Parameter        │ Description      │ Default\n",
+		"image.pullPolicy │ The pull policy  │ IfNotPresent\n",
+		"The tag | digest",
+	} {
+		if !strings.Contains(plain, s) {
+			t.Errorf("expected %q in the output\n%s", s, plain)
+		}
+	}
+	if strings.Contains(plain, "```") || strings.Contains(plain, "## ") {
+		t.Errorf("expected the markup to be rendered\n%s", plain)
+	}
+
+	out = Render([]byte("Some **bold** text, *emphasis*, a [link](https://helm.sh) and an \\*escaped\\* star, wrapped at a narrow width.\n"), 30)
+	expect := "Some " + styleBold + "bold" + reset + " text, " + styleEm + "emphasis" + reset + ", a\n" +
+		styleLink + "link" + reset + " (https://helm.sh) and an\n" +
+		"*escaped* star, wrapped at a\n" +
+		"narrow width.\n"
+	if out != expect {
+		t.Errorf("expected\n%q\ngot\n%q", expect, out)
+	}
+}
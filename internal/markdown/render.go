@@ -0,0 +1,271 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// The ANSI escape sequences used to style the output.
+const (
+	reset      = "\x1b[0m"
+	styleBold  = "\x1b[1m"
+	styleFaint = "\x1b[2m"
+	styleEm    = "\x1b[3m"
+	styleLink  = "\x1b[4m"
+	styleCode  = "\x1b[36m"
+	styleH1    = "\x1b[1;4m"
+	styleH2    = "\x1b[1;35m"
+)
+
+var (
+	comment        = regexp.MustCompile(`(?s)<!--.*?-->`)
+	listItem       = regexp.MustCompile(`^([ \t]*)([-*+]|\d+[.)])[ \t]+(.*)$`)
+	blockquote     = regexp.MustCompile(`^[ \t]*>[ \t]?(.*)$`)
+	rule           = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	escaped        = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!|<>])")
+	image          = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	link           = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	autolink       = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	htmlTag        = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	strong         = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	emphasis       = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	underscoreEm   = regexp.MustCompile(`(^|[\s(])_(\S(?:[^_]*?\S)?)_([\s).,;:!?]|$)`)
+	ansiSequence   = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	escapedRuneMin = rune(0xE000)
+)
+
+// DefaultWidth is the width documents are rendered at when the width of the
+// terminal is unknown.
+const DefaultWidth = 80
+
+// Render formats the document for display in a terminal: headings, emphasis,
+// code and links are styled with ANSI escape sequences, tables are aligned
+// and paragraphs are wrapped at width columns.
+func Render(doc []byte, width int) string {
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	lines := splitLines(comment.ReplaceAll(doc, nil))
+
+	var b strings.Builder
+	var para []string
+	blank := true
+	write := func(s string) {
+		b.WriteString(s)
+		b.WriteString("\n")
+		blank = false
+	}
+	flush := func() {
+		if len(para) > 0 {
+			write(wrap(inline(strings.Join(para, " ")), width, ""))
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence.MatchString(line) {
+			flush()
+			var f fences
+			f.code(line)
+			for i++; i < len(lines); i++ {
+				if f.code(lines[i]); f.open == "" {
+					break
+				}
+				write("    " + styleCode + lines[i] + reset)
+			}
+			continue
+		}
+		if len(para) == 0 && trimmed != "" && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")) {
+			write("    " + styleCode + strings.TrimRight(line, " \t") + reset)
+			continue
+		}
+
+		switch level, title, n := heading(lines, i); {
+		case trimmed == "":
+			flush()
+			if !blank {
+				b.WriteString("\n")
+				blank = true
+			}
+		case level > 0:
+			flush()
+			style := styleBold
+			switch level {
+			case 1:
+				style = styleH1
+			case 2:
+				style = styleH2
+			}
+			write(style + stripStyles(inline(title)) + reset)
+			i += n - 1
+		case rule.MatchString(line):
+			flush()
+			write(styleFaint + strings.Repeat("─", width) + reset)
+		case strings.Contains(line, "|") && i+1 < len(lines) && tableDelimiter.MatchString(lines[i+1]):
+			flush()
+			t := Table{Header: cells(line)}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				t.Rows = append(t.Rows, cells(lines[i]))
+			}
+			i--
+			b.WriteString(renderTable(t))
+			blank = false
+		case listItem.MatchString(line):
+			flush()
+			m := listItem.FindStringSubmatch(line)
+			bullet := m[2]
+			if bullet == "-" || bullet == "*" || bullet == "+" {
+				bullet = "•"
+			}
+			indent := strings.ReplaceAll(m[1], "\t", "    ")
+			prefix := indent + bullet + " "
+			write(prefix + wrap(inline(m[3]), width-visibleLen(prefix), strings.Repeat(" ", visibleLen(prefix))))
+		case blockquote.MatchString(line):
+			flush()
+			text := blockquote.FindStringSubmatch(line)[1]
+			write(styleFaint + "│ " + reset + styleEm + wrap(inline(text), width-2, reset+styleFaint+"│ "+reset+styleEm) + reset)
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// renderTable aligns the cells of a table, separating the columns with
+// lines.
+func renderTable(t Table) string {
+	header := make([]string, len(t.Header))
+	widths := make([]int, len(t.Header))
+	for i, h := range t.Header {
+		header[i] = stripStyles(inline(h))
+		widths[i] = visibleLen(header[i])
+	}
+	rows := make([][]string, len(t.Rows))
+	for r, row := range t.Rows {
+		rows[r] = make([]string, len(t.Header))
+		for i := range t.Header {
+			if i < len(row) {
+				rows[r][i] = inline(row[i])
+				widths[i] = max(widths[i], visibleLen(rows[r][i]))
+			}
+		}
+	}
+
+	var b strings.Builder
+	line := func(cells []string, style string) {
+		for i, w := range widths {
+			if i > 0 {
+				b.WriteString(" " + styleFaint + "│" + reset + " ")
+			}
+			b.WriteString(style + cells[i] + reset)
+			if i < len(widths)-1 {
+				b.WriteString(strings.Repeat(" ", w-visibleLen(cells[i])))
+			}
+		}
+		b.WriteString("\n")
+	}
+	line(header, styleBold)
+	separators := make([]string, len(widths))
+	for i, w := range widths {
+		separators[i] = strings.Repeat("─", w)
+	}
+	b.WriteString(styleFaint + strings.Join(separators, "─┼─") + reset + "\n")
+	for _, r := range rows {
+		line(r, "")
+	}
+	return b.String()
+}
+
+// inline styles the inline elements of a line of text.
+func inline(s string) string {
+	// Escaped characters are replaced by runes of the private use area so
+	// that they are not taken for markup.
+	s = escaped.ReplaceAllStringFunc(s, func(m string) string {
+		return string(escapedRuneMin + rune(m[1]))
+	})
+
+	var b strings.Builder
+	for i, part := range strings.Split(s, "`") {
+		if i%2 == 1 {
+			b.WriteString(styleCode + part + reset)
+			continue
+		}
+		part = image.ReplaceAllString(part, "$1")
+		part = link.ReplaceAllStringFunc(part, func(m string) string {
+			sm := link.FindStringSubmatch(m)
+			if sm[1] == sm[2] {
+				return styleLink + sm[2] + reset
+			}
+			return styleLink + sm[1] + reset + " (" + sm[2] + ")"
+		})
+		part = autolink.ReplaceAllString(part, styleLink+"$1"+reset)
+		part = htmlTag.ReplaceAllString(part, "")
+		part = strong.ReplaceAllString(part, styleBold+"$1$2"+reset)
+		part = emphasis.ReplaceAllString(part, styleEm+"$1"+reset)
+		part = underscoreEm.ReplaceAllString(part, "$1"+styleEm+"$2"+reset+"$3")
+		b.WriteString(part)
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r >= escapedRuneMin && r < escapedRuneMin+128 {
+			return r - escapedRuneMin
+		}
+		return r
+	}, b.String())
+}
+
+// wrap wraps the words of the text at width columns, starting each
+// continuation line with indent.
+func wrap(s string, width int, indent string) string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(words[0])
+	col := visibleLen(words[0])
+	for _, w := range words[1:] {
+		if l := visibleLen(w); width > 0 && col+1+l > width {
+			b.WriteString("\n" + indent + w)
+			col = visibleLen(indent) + l
+		} else {
+			b.WriteString(" " + w)
+			col += 1 + l
+		}
+	}
+	return b.String()
+}
+
+func stripStyles(s string) string {
+	return ansiSequence.ReplaceAllString(s, "")
+}
+
+// stripInline removes the inline markup of a line of text.
+func stripInline(s string) string {
+	return strings.TrimSpace(stripStyles(inline(s)))
+}
+
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(stripStyles(s))
+}
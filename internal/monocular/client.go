@@ -18,6 +18,7 @@ package monocular
 
 import (
 	"errors"
+	"net/http"
 	"net/url"
 )
 
@@ -32,6 +33,10 @@ type Client struct {
 
 	// The internal logger to use
 	Log func(string, ...interface{})
+
+	// Header is added to every request, for example to authenticate against
+	// a private hub.
+	Header http.Header
 }
 
 // New creates a new client
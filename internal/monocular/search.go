@@ -119,6 +119,10 @@ func (c *Client) Search(term string) ([]SearchResult, error) {
 		return nil, err
 	}
 
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+
 	// Set the user agent so that monocular can identify where the request
 	// is coming from
 	req.Header.Set("User-Agent", version.GetUserAgent())
@@ -33,13 +33,14 @@ import (
 // in the tree, including root. All errors that arise visiting files and directories
 // are filtered by walkFn. The files are walked in lexical order, which makes the
 // output deterministic but means that for very large directories Walk can be
-// inefficient. Walk follows symbolic links.
+// inefficient. Walk follows symbolic links, and returns an error for a
+// symbolic link that points to a directory containing it.
 func Walk(root string, walkFn filepath.WalkFunc) error {
 	info, err := os.Lstat(root)
 	if err != nil {
 		err = walkFn(root, nil, err)
 	} else {
-		err = symwalk(root, info, walkFn)
+		err = symwalk(root, info, walkFn, map[string]bool{})
 	}
 	if err == filepath.SkipDir {
 		return nil
@@ -63,8 +64,10 @@ func readDirNames(dirname string) ([]string, error) {
 	return names, nil
 }
 
-// symwalk recursively descends path, calling walkFn.
-func symwalk(path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+// symwalk recursively descends path, calling walkFn. ancestors holds the
+// resolved paths of the directories being walked, to detect symbolic link
+// cycles.
+func symwalk(path string, info os.FileInfo, walkFn filepath.WalkFunc, ancestors map[string]bool) error {
 	// Recursively walk symlinked directories.
 	if IsSymlink(info) {
 		resolved, err := filepath.EvalSymlinks(path)
@@ -76,12 +79,24 @@ func symwalk(path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
 		if info, err = os.Lstat(resolved); err != nil {
 			return err
 		}
-		if err := symwalk(path, info, walkFn); err != nil && err != filepath.SkipDir {
+		if err := symwalk(path, info, walkFn, ancestors); err != nil && err != filepath.SkipDir {
 			return err
 		}
 		return nil
 	}
 
+	if info.IsDir() {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return errors.Wrapf(err, "error evaluating symlink %s", path)
+		}
+		if ancestors[resolved] {
+			return errors.Errorf("symbolic link cycle: %s resolves to %s, which contains it", path, resolved)
+		}
+		ancestors[resolved] = true
+		defer delete(ancestors, resolved)
+	}
+
 	if err := walkFn(path, info, nil); err != nil {
 		return err
 	}
@@ -103,7 +118,7 @@ func symwalk(path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
 				return err
 			}
 		} else {
-			err = symwalk(filename, fileInfo, walkFn)
+			err = symwalk(filename, fileInfo, walkFn, ancestors)
 			if err != nil {
 				if (!fileInfo.IsDir() && !IsSymlink(fileInfo)) || err != filepath.SkipDir {
 					return err
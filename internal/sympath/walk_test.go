@@ -23,6 +23,7 @@ package sympath
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -149,3 +150,18 @@ func TestWalk(t *testing.T) {
 		t.Errorf("removeTree: %v", err)
 	}
 }
+
+func TestWalkSymlinkCycle(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", ".."), filepath.Join(root, "a", "b", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Walk(root, func(_ string, _ os.FileInfo, err error) error { return err })
+	if err == nil || !strings.Contains(err.Error(), "symbolic link cycle") {
+		t.Fatalf("expected symbolic link cycle error, got %v", err)
+	}
+}
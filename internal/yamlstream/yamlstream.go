@@ -0,0 +1,93 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package yamlstream splits YAML streams into their documents.
+package yamlstream
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Separator starts the lines separating the documents of a YAML stream.
+const Separator = "---"
+
+// IsSeparator reports whether line separates two documents of a YAML stream.
+// Only whitespace and comments may follow the separator.
+func IsSeparator(line string) bool {
+	if !strings.HasPrefix(line, Separator) {
+		return false
+	}
+	rest := strings.TrimSpace(line[len(Separator):])
+	return rest == "" || rest[0] == '#'
+}
+
+// Document is a document of a YAML stream.
+type Document struct {
+	// Data is the content of the document, without its separators.
+	Data []byte
+	// Line is the line of the stream the content of the document starts
+	// at, starting at 1.
+	Line int
+}
+
+// Reader reads the documents of a YAML stream one at a time, so that large
+// streams are never held in memory as a whole.
+type Reader struct {
+	r    *bufio.Reader
+	line int
+	err  error
+}
+
+// NewReader returns a Reader reading the documents of the stream.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next document of the stream, or io.EOF after the last
+// one. Documents that are empty or only contain whitespace are skipped, but
+// still count towards the line numbers.
+func (r *Reader) Next() (Document, error) {
+	for r.err == nil {
+		doc := Document{Line: r.line + 1}
+		for {
+			line, err := r.r.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				r.err = err
+				return Document{}, err
+			}
+			if len(line) > 0 {
+				r.line++
+			}
+			separator := bytes.HasPrefix(line, []byte(Separator)) && IsSeparator(string(line))
+			if !separator {
+				doc.Data = append(doc.Data, line...)
+			}
+			if err != nil {
+				r.err = err
+				break
+			}
+			if separator {
+				break
+			}
+		}
+		if len(bytes.TrimSpace(doc.Data)) > 0 {
+			return doc, nil
+		}
+	}
+	return Document{}, r.err
+}
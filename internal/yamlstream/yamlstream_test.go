@@ -0,0 +1,61 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package yamlstream
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	stream := "---\n# leading comment\n---\na: 1\n--- # separator comment\n\nb: 2\n---\n  \n---\nc: 3\n---"
+	expect := []Document{
+		{Data: []byte("# leading comment\n"), Line: 2},
+		{Data: []byte("a: 1\n"), Line: 4},
+		{Data: []byte("\nb: 2\n"), Line: 6},
+		{Data: []byte("c: 3\n"), Line: 11},
+	}
+
+	r := NewReader(strings.NewReader(stream))
+	for i, want := range expect {
+		doc, err := r.Next()
+		if err != nil {
+			t.Fatalf("document %d: %s", i+1, err)
+		}
+		if string(doc.Data) != string(want.Data) || doc.Line != want.Line {
+			t.Errorf("expected document %d to be %q at line %d, got %q at line %d", i+1, want.Data, want.Line, doc.Data, doc.Line)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last document, got %v", err)
+	}
+}
+
+func TestIsSeparator(t *testing.T) {
+	for line, want := range map[string]bool{
+		"---":             true,
+		"---\n":           true,
+		"--- # comment\n": true,
+		"---foo\n":        false,
+		"--- foo\n":       false,
+		"a: ---\n":        false,
+	} {
+		if got := IsSeparator(line); got != want {
+			t.Errorf("IsSeparator(%q) = %t, expected %t", line, got, want)
+		}
+	}
+}
@@ -19,11 +19,15 @@ package action
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -32,34 +36,40 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"helm.sh/helm/v3/pkg/audit"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/errcode"
 	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/redact"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
-	"helm.sh/helm/v3/pkg/time"
+	helmtime "helm.sh/helm/v3/pkg/time"
 )
 
 // Timestamper is a function capable of producing a timestamp.Timestamper.
 //
 // By default, this is a time.Time function from the Helm time package. This can
 // be overridden for testing though, so that timestamps are predictable.
-var Timestamper = time.Now
+var Timestamper = helmtime.Now
 
 var (
 	// errMissingChart indicates that a chart was not provided.
-	errMissingChart = errors.New("no chart provided")
+	errMissingChart error = errcode.New(errcode.MissingChart)
 	// errMissingRelease indicates that a release (name) was not provided.
-	errMissingRelease = errors.New("no release provided")
+	errMissingRelease error = errcode.New(errcode.MissingRelease)
 	// errInvalidRevision indicates that an invalid release revision number was provided.
-	errInvalidRevision = errors.New("invalid release revision")
+	errInvalidRevision error = errcode.New(errcode.InvalidRevision)
 	// errPending indicates that another instance of Helm is already applying an operation on a release.
-	errPending = errors.New("another operation (install/upgrade/rollback) is in progress")
+	errPending error = errcode.New(errcode.OperationInProgress)
+	// errBuildObjects indicates that the Kubernetes objects of a manifest could not be built.
+	errBuildObjects error = errcode.New(errcode.InvalidManifest)
 )
 
 // ValidName is a regular expression for resource names.
@@ -78,6 +88,13 @@ var (
 var ValidName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
 
 // Configuration injects the dependencies that all actions share.
+//
+// Once initialized, a Configuration may be shared by actions running
+// concurrently, so that discovery, the REST mapper and the registry client are
+// set up once instead of once per action. Init, RegisterMetrics and changes
+// to the fields of a Configuration must not run concurrently with actions.
+// Client-only installs, e.g. for 'helm template', replace the clients of the
+// Configuration and need a Configuration of their own.
 type Configuration struct {
 	// RESTClientGetter is an interface that loads Kubernetes clients.
 	RESTClientGetter RESTClientGetter
@@ -95,6 +112,108 @@ type Configuration struct {
 	Capabilities *chartutil.Capabilities
 
 	Log func(string, ...interface{})
+
+	// Webhooks are notified of release events of the actions.
+	Webhooks []Webhook
+
+	// AuditLog records the actions changing releases, if set.
+	AuditLog audit.Sink
+
+	// AuditUser is the user recorded in the audit log.
+	AuditUser string
+
+	// Redactor masks secrets in the errors and notes recorded in the audit
+	// log. Nothing is masked if nil.
+	Redactor *redact.Redactor
+
+	// FreezePolicy is the source of the freeze windows during which releases
+	// must not be installed, upgraded or rolled back, see LoadFreezePolicy.
+	FreezePolicy string
+
+	// MetadataPolicy normalizes the labels and annotations of the resources
+	// of releases before they are applied. Resources are applied as rendered
+	// if nil.
+	MetadataPolicy *MetadataPolicy
+
+	// HookImagePolicy requires the images of hooks to be signed before the
+	// hooks are run. The images are not verified if nil.
+	HookImagePolicy *HookImagePolicy
+	// ImageVerifier verifies the signatures of the images of hooks. It
+	// defaults to the cosign command.
+	ImageVerifier downloader.SigstoreVerifier
+	// ImageResolver resolves the images of hooks to the digests that are
+	// verified and run. It defaults to the RegistryClient.
+	ImageResolver ImageResolver
+
+	// SensitiveSecrets keeps the values the charts of releases mark as
+	// sensitive, and the manifests and notes of the releases having them, in
+	// Secrets when the records are stored in ConfigMaps, HelmRelease objects
+	// or a SQL database, see storage.Storage.Sensitive. They are kept in the
+	// records otherwise. It must be set before Init, and reading the releases
+	// then requires access to the Secrets of their namespace.
+	SensitiveSecrets bool
+
+	// ExternalHooks is what hooks of kind ExternalHookKind, which run
+	// commands and send requests from the machine running Helm, may run.
+	// They are refused if nil.
+	ExternalHooks *ExternalHookPolicy
+
+	// HookServiceAccount runs the pods of hooks that name no ServiceAccount
+	// as a ServiceAccount created for the hooks of each event and deleted
+	// once they have completed. It is granted the hook permissions declared by
+	// the chart, see chart.Metadata.HookPermissions.
+	HookServiceAccount bool
+
+	// NamePolicy generates the names of releases installed with
+	// --generate-name and validates the names of new releases. The
+	// DefaultNamePolicy is used if nil.
+	NamePolicy NamePolicy
+
+	// HookOutputFunc, if set, returns the writer the logs of a container of
+	// the pods of a running hook are streamed to, following them while the
+	// hook runs. It is called once per container, possibly from several
+	// goroutines at once.
+	HookOutputFunc func(namespace, pod, container string) io.Writer
+
+	// HookParallelism is how many hooks of the same weight run at the same
+	// time. Hooks run one at a time if it is less than 2.
+	HookParallelism int
+
+	// KindOrder configures the order in which resources are installed and
+	// uninstalled by kind. The default order is used if nil.
+	KindOrder *KindOrder
+
+	// PolicyFiles are loaded into the webhooks and policies above that are
+	// not set, when an action first needs them.
+	PolicyFiles PolicyFiles
+
+	// MaxIncludeDepth and TemplateTimeout limit the rendering of templates,
+	// see engine.Engine.
+	MaxIncludeDepth int
+	TemplateTimeout time.Duration
+	// SourceComments adds a comment identifying the template each rendered
+	// document comes from, see engine.Engine.
+	SourceComments bool
+
+	// Profiler captures profiles of the phases of the actions, if set.
+	Profiler *Profiler
+
+	// annotationProcessors are the processors set by
+	// RegisterAnnotationProcessor.
+	annotationProcessors []registeredAnnotationProcessor
+
+	// metrics are the Prometheus collectors set by RegisterMetrics.
+	metrics *actionMetrics
+
+	// capabilitiesMu guards the lazy initialization of Capabilities.
+	capabilitiesMu sync.Mutex
+
+	// policiesMu guards the lazy loading of the PolicyFiles.
+	policiesMu sync.Mutex
+
+	// kubeUser caches the user the cluster authenticates the client as.
+	kubeUser     *string
+	kubeUserOnce sync.Once
 }
 
 // renderResources renders the templates in a chart
@@ -103,18 +222,18 @@ type Configuration struct {
 // TODO: As part of the refactor the duplicate code in cmd/helm/template.go should be removed
 //
 //	This code has to do with writing files to disk.
-func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Values, releaseName, outputDir string, subNotes, useReleaseName, includeCrds bool, pr postrender.PostRenderer, interactWithRemote, enableDNS, hideSecret bool) ([]*release.Hook, *bytes.Buffer, string, error) {
+func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Values, releaseName, outputDir string, subNotes, useReleaseName bool, crds crdSelection, pr postrender.PostRenderer, interactWithRemote, enableDNS, hideSecret bool) ([]*release.Hook, string, string, error) {
 	hs := []*release.Hook{}
-	b := bytes.NewBuffer(nil)
+	var b strings.Builder
 
 	caps, err := cfg.getCapabilities()
 	if err != nil {
-		return hs, b, "", err
+		return hs, b.String(), "", err
 	}
 
 	if ch.Metadata.KubeVersion != "" {
 		if !chartutil.IsCompatibleRange(ch.Metadata.KubeVersion, caps.KubeVersion.String()) {
-			return hs, b, "", errors.Errorf("chart requires kubeVersion: %s which is incompatible with Kubernetes %s", ch.Metadata.KubeVersion, caps.KubeVersion.String())
+			return hs, b.String(), "", errcode.New(errcode.IncompatibleKubeVersion, ch.Metadata.KubeVersion, caps.KubeVersion.String())
 		}
 	}
 
@@ -127,19 +246,25 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	if interactWithRemote && cfg.RESTClientGetter != nil {
 		restConfig, err := cfg.RESTClientGetter.ToRESTConfig()
 		if err != nil {
-			return hs, b, "", err
+			return hs, b.String(), "", err
 		}
 		e := engine.New(restConfig)
 		e.EnableDNS = enableDNS
+		e.MaxIncludeDepth = cfg.MaxIncludeDepth
+		e.TemplateTimeout = cfg.TemplateTimeout
+		e.SourceComments = cfg.SourceComments
 		files, err2 = e.Render(ch, values)
 	} else {
 		var e engine.Engine
 		e.EnableDNS = enableDNS
+		e.MaxIncludeDepth = cfg.MaxIncludeDepth
+		e.TemplateTimeout = cfg.TemplateTimeout
+		e.SourceComments = cfg.SourceComments
 		files, err2 = e.Render(ch, values)
 	}
 
 	if err2 != nil {
-		return hs, b, "", err2
+		return hs, b.String(), "", err2
 	}
 
 	// NOTES.txt gets rendered like all the other files, but because it's not a hook nor a resource,
@@ -147,25 +272,20 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	// text file. We have to spin through this map because the file contains path information, so we
 	// look for terminating NOTES.txt. We also remove it from the files so that we don't have to skip
 	// it in the sortHooks.
-	var notesBuffer bytes.Buffer
-	for k, v := range files {
-		if strings.HasSuffix(k, notesFileSuffix) {
-			if subNotes || (k == path.Join(ch.Name(), "templates", notesFileSuffix)) {
-				// If buffer contains data, add newline before adding more
-				if notesBuffer.Len() > 0 {
-					notesBuffer.WriteString("\n")
-				}
-				notesBuffer.WriteString(v)
-			}
-			delete(files, k)
-		}
-	}
-	notes := notesBuffer.String()
+	notes := releaseNotes(ch, files, subNotes)
 
 	// Sort hooks, manifests, and partials. Only hooks and manifests are returned,
 	// as partials are not used after renderer.Render. Empty manifests are also
 	// removed here.
-	hs, manifests, err := releaseutil.SortManifests(files, caps.APIVersions, releaseutil.InstallOrder)
+	kindOrder, err := cfg.kindOrder()
+	if err != nil {
+		return hs, b.String(), "", err
+	}
+	installOrder, err := kindOrder.InstallOrder()
+	if err != nil {
+		return hs, b.String(), "", err
+	}
+	hs, manifests, err := releaseutil.SortManifests(files, caps.APIVersions, installOrder)
 	if err != nil {
 		// By catching parse errors here, we can prevent bogus releases from going
 		// to Kubernetes.
@@ -176,22 +296,28 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 			if strings.TrimSpace(content) == "" {
 				continue
 			}
-			fmt.Fprintf(b, "---\n# Source: %s\n%s\n", name, content)
+			fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", name, content)
 		}
-		return hs, b, "", err
+		return hs, b.String(), "", err
 	}
 
-	// Aggregate all valid manifests into one big doc.
+	// Aggregate all valid manifests into one big doc. The buffer is sized up
+	// front so that releases with very large manifests are not copied over and
+	// over while it grows.
 	fileWritten := make(map[string]bool)
+	if outputDir == "" {
+		b.Grow(aggregatedSize(ch, manifests, crds))
+	}
+	hs = crds.hooks(hs)
 
-	if includeCrds {
+	if crds.includesDir() {
 		for _, crd := range ch.CRDObjects() {
 			if outputDir == "" {
-				fmt.Fprintf(b, "---\n# Source: %s\n%s\n", crd.Filename, string(crd.File.Data[:]))
+				fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", crd.Filename, crd.File.Data)
 			} else {
 				err = writeToFile(outputDir, crd.Filename, string(crd.File.Data[:]), fileWritten[crd.Filename])
 				if err != nil {
-					return hs, b, "", err
+					return hs, b.String(), "", err
 				}
 				fileWritten[crd.Filename] = true
 			}
@@ -199,11 +325,14 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	}
 
 	for _, m := range manifests {
+		if !crds.includes(m.Head) {
+			continue
+		}
 		if outputDir == "" {
 			if hideSecret && m.Head.Kind == "Secret" && m.Head.Version == "v1" {
-				fmt.Fprintf(b, "---\n# Source: %s\n# HIDDEN: The Secret output has been suppressed\n", m.Name)
+				fmt.Fprintf(&b, "---\n# Source: %s\n# HIDDEN: The Secret output has been suppressed\n", m.Name)
 			} else {
-				fmt.Fprintf(b, "---\n# Source: %s\n%s\n", m.Name, m.Content)
+				fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", m.Name, m.Content)
 			}
 		} else {
 			newDir := outputDir
@@ -216,20 +345,151 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 			// used by install or upgrade
 			err = writeToFile(newDir, m.Name, m.Content, fileWritten[m.Name])
 			if err != nil {
-				return hs, b, "", err
+				return hs, b.String(), "", err
 			}
 			fileWritten[m.Name] = true
 		}
 	}
 
+	// The manifest is only copied when a post renderer rewrites it.
+	manifest := b.String()
 	if pr != nil {
-		b, err = pr.Run(b)
+		out, err := pr.Run(bytes.NewBufferString(manifest))
 		if err != nil {
-			return hs, b, notes, errors.Wrap(err, "error while running post render on files")
+			return hs, manifest, notes, errors.Wrap(err, "error while running post render on files")
 		}
+		manifest = out.String()
 	}
 
-	return hs, b, notes, nil
+	return hs, manifest, notes, nil
+}
+
+// aggregatedSize returns the size of the manifest aggregated by
+// renderResources.
+func aggregatedSize(ch *chart.Chart, manifests []releaseutil.Manifest, crds crdSelection) int {
+	const header = len("---\n# Source: \n\n")
+	size := 0
+	if crds.includesDir() {
+		for _, crd := range ch.CRDObjects() {
+			size += header + len(crd.Filename) + len(crd.File.Data)
+		}
+	}
+	for _, m := range manifests {
+		if crds.includes(m.Head) {
+			size += header + len(m.Name) + len(m.Content)
+		}
+	}
+	return size
+}
+
+// crdSelection selects the CustomResourceDefinitions that are part of the
+// manifest aggregated by renderResources.
+type crdSelection int
+
+const (
+	// crdsTemplated keeps the CRDs rendered from templates, but not those of
+	// the crds/ directory, which are installed separately.
+	crdsTemplated crdSelection = iota
+	// crdsIncluded also adds the CRDs of the crds/ directory.
+	crdsIncluded
+	// crdsExcluded leaves out all CRDs.
+	crdsExcluded
+	// crdsOnly keeps the CRDs of the crds/ directory and templates, and
+	// nothing else.
+	crdsOnly
+)
+
+// includesDir tells whether the CRDs of the crds/ directory are selected.
+func (s crdSelection) includesDir() bool {
+	return s == crdsIncluded || s == crdsOnly
+}
+
+// includes tells whether a rendered object of the given kind is selected.
+func (s crdSelection) includes(head *releaseutil.SimpleHead) bool {
+	isCRD := head != nil && head.Kind == "CustomResourceDefinition"
+	switch s {
+	case crdsExcluded:
+		return !isCRD
+	case crdsOnly:
+		return isCRD
+	default:
+		return true
+	}
+}
+
+// hooks returns the hooks whose objects are selected.
+func (s crdSelection) hooks(hs []*release.Hook) []*release.Hook {
+	if s != crdsExcluded && s != crdsOnly {
+		return hs
+	}
+	selected := hs[:0]
+	for _, h := range hs {
+		if s.includes(&releaseutil.SimpleHead{Kind: h.Kind}) {
+			selected = append(selected, h)
+		}
+	}
+	return selected
+}
+
+// releaseNotes removes the rendered NOTES.txt from files and joins them into
+// the notes of the release: the notes of the chart, followed by those of the
+// subcharts if requested by subNotes or by the notes settings of Chart.yaml.
+// The subcharts come in the order of the settings, then by path.
+func releaseNotes(ch *chart.Chart, files map[string]string, subNotes bool) string {
+	var opts chart.Notes
+	if ch.Metadata != nil && ch.Metadata.Notes != nil {
+		opts = *ch.Metadata.Notes
+	}
+	subNotes = subNotes || opts.Subcharts
+
+	rootNotes := path.Join(ch.Name(), "templates", notesFileSuffix)
+	var notes []string
+	subcharts := make(map[string]string)
+	for k, v := range files {
+		if !strings.HasSuffix(k, notesFileSuffix) {
+			continue
+		}
+		delete(files, k)
+		if k == rootNotes {
+			notes = append(notes, v)
+		} else if subNotes {
+			// "parent/charts/a/charts/b/templates/NOTES.txt" is the notes of "a/b".
+			sub := strings.TrimSuffix(strings.TrimPrefix(k, ch.Name()+"/charts/"), "/templates/"+notesFileSuffix)
+			subcharts[strings.ReplaceAll(sub, "/charts/", "/")] = v
+		}
+	}
+
+	names := make([]string, 0, len(subcharts))
+	for name := range subcharts {
+		names = append(names, name)
+	}
+	rank := func(name string) int {
+		for i, o := range opts.Order {
+			if o == name || o == path.Base(name) {
+				return i
+			}
+		}
+		return len(opts.Order)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		ri, rj := rank(names[i]), rank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		v := subcharts[name]
+		if opts.Label {
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+			v = fmt.Sprintf("NOTES for %s:\n%s", name, v)
+		}
+		notes = append(notes, v)
+	}
+	return strings.Join(notes, "\n")
 }
 
 // RESTClientGetter gets the rest client
@@ -244,6 +504,9 @@ type DebugLog func(format string, v ...interface{})
 
 // capabilities builds a Capabilities from discovery information.
 func (cfg *Configuration) getCapabilities() (*chartutil.Capabilities, error) {
+	cfg.capabilitiesMu.Lock()
+	defer cfg.capabilitiesMu.Unlock()
+
 	if cfg.Capabilities != nil {
 		return cfg.Capabilities, nil
 	}
@@ -284,6 +547,84 @@ func (cfg *Configuration) getCapabilities() (*chartutil.Capabilities, error) {
 	return cfg.Capabilities, nil
 }
 
+// dependencyCapabilities returns the capabilities the conditions of the
+// dependencies of a chart are evaluated against. Unless the capabilities are
+// known, only the versions of Kubernetes and Helm are discovered, and only if
+// a condition refers to capabilities: the API versions of the cluster may
+// still change when the CRDs of the chart are installed.
+func (cfg *Configuration) dependencyCapabilities(c *chart.Chart) (*chartutil.Capabilities, error) {
+	cfg.capabilitiesMu.Lock()
+	caps := cfg.Capabilities
+	cfg.capabilitiesMu.Unlock()
+	if caps != nil || cfg.RESTClientGetter == nil || !conditionsUseCapabilities(c) {
+		return caps, nil
+	}
+
+	dc, err := cfg.RESTClientGetter.ToDiscoveryClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get Kubernetes discovery client")
+	}
+	kubeVersion, err := dc.ServerVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get server version from Kubernetes")
+	}
+	return &chartutil.Capabilities{
+		KubeVersion: chartutil.KubeVersion{
+			Version: kubeVersion.GitVersion,
+			Major:   kubeVersion.Major,
+			Minor:   kubeVersion.Minor,
+		},
+		HelmVersion: chartutil.DefaultCapabilities.HelmVersion,
+	}, nil
+}
+
+// conditionsUseCapabilities tells whether a condition of a dependency of the
+// chart or of its subcharts refers to capabilities.
+func conditionsUseCapabilities(c *chart.Chart) bool {
+	for _, dep := range c.Metadata.Dependencies {
+		if dep != nil && strings.Contains(dep.Condition, ".Capabilities.") {
+			return true
+		}
+	}
+	for _, sub := range c.Dependencies() {
+		if conditionsUseCapabilities(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidateDiscovery clears the cached discovery information so that
+// resources registered during the action (e.g. CRDs) can be mapped.
+func (cfg *Configuration) invalidateDiscovery() error {
+	if kc, ok := cfg.KubeClient.(kube.InterfaceDiscovery); ok {
+		return kc.InvalidateDiscovery()
+	}
+	if cfg.RESTClientGetter == nil {
+		return nil
+	}
+
+	discoveryClient, err := cfg.RESTClientGetter.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	cfg.Log("Clearing discovery cache")
+	discoveryClient.Invalidate()
+	// Refill the cache right away, so that the new groups are known before
+	// the REST mapper below is reset and queried again.
+	_, _ = discoveryClient.ServerGroups()
+
+	restMapper, err := cfg.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	if resettable, ok := restMapper.(meta.ResettableRESTMapper); ok {
+		cfg.Log("Clearing REST mapper cache")
+		resettable.Reset()
+	}
+	return nil
+}
+
 // KubernetesClientSet creates a new kubernetes ClientSet based on the configuration
 func (cfg *Configuration) KubernetesClientSet() (kubernetes.Interface, error) {
 	conf, err := cfg.RESTClientGetter.ToRESTConfig()
@@ -298,7 +639,7 @@ func (cfg *Configuration) KubernetesClientSet() (kubernetes.Interface, error) {
 //
 // If the configuration has a Timestamper on it, that will be used.
 // Otherwise, this will use time.Now().
-func (cfg *Configuration) Now() time.Time {
+func (cfg *Configuration) Now() helmtime.Time {
 	return Timestamper()
 }
 
@@ -369,8 +710,56 @@ func (cfg *Configuration) recordRelease(r *release.Release) {
 	}
 }
 
+// checkDeprecation fails for a deprecated chart, or a chart past its end of
+// life, if strict.
+func checkDeprecation(ch *chart.Chart, strict bool) error {
+	if !strict {
+		return nil
+	}
+	d := ch.Metadata.Deprecation()
+	if now := Timestamper().Time; d != nil && (d.Deprecated || d.EndOfLifeReached(now)) {
+		return errcode.New(errcode.DeprecatedChart, ch.Name(), ch.Metadata.Version, d.Message(now))
+	}
+	return nil
+}
+
+// addWarnings records the warnings the API server returned while applying
+// resources of a release, such as deprecation notices, in the release.
+func (cfg *Configuration) addWarnings(r *release.Release, result *kube.Result) {
+	if result == nil {
+		return
+	}
+	seen := make(map[string]bool, len(r.Info.Warnings))
+	for _, w := range r.Info.Warnings {
+		seen[w] = true
+	}
+	for _, w := range result.Warnings {
+		cfg.Log("warning: %s", w)
+		if !seen[w] {
+			seen[w] = true
+			r.Info.Warnings = append(r.Info.Warnings, w)
+		}
+	}
+}
+
+// updateResources updates the resources of the release with its apply method.
+func (cfg *Configuration) updateResources(r *release.Release, current, target kube.ResourceList, force bool) (*kube.Result, error) {
+	if r.ApplyMethod != release.ApplyMethodServerSide {
+		return cfg.KubeClient.Update(current, target, force)
+	}
+	kubeClient, ok := cfg.KubeClient.(kube.InterfaceServerSideApply)
+	if !ok {
+		return &kube.Result{}, errors.Errorf("release %s uses server-side apply, which the Kubernetes client does not support", r.Name)
+	}
+	return kubeClient.UpdateServerSide(current, target, force)
+}
+
 // Init initializes the action configuration
 func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namespace, helmDriver string, log DebugLog) error {
+	// Share a single in-memory discovery cache between the action and the
+	// Kubernetes client so that invalidating it is seen by both.
+	getter = kube.NewMemCacheClientGetter(getter)
+
 	kc := kube.New(getter)
 	kc.Log = log
 
@@ -385,10 +774,20 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 		d := driver.NewSecrets(newSecretClient(lazyClient))
 		d.Log = log
 		store = storage.Init(d)
+		store.Sensitive = d
 	case "configmap", "configmaps":
 		d := driver.NewConfigMaps(newConfigMapClient(lazyClient))
 		d.Log = log
 		store = storage.Init(d)
+		store.Sensitive = cfg.sensitiveSecrets(lazyClient, log)
+	case "helmrelease", "helmreleases":
+		d := driver.NewHelmReleases(newHelmReleaseClient(&lazyDynamicClient{
+			namespace: namespace,
+			clientFn:  kc.Factory.DynamicClient,
+		}))
+		d.Log = log
+		store = storage.Init(d)
+		store.Sensitive = cfg.sensitiveSecrets(lazyClient, log)
 	case "memory":
 		var d *driver.Memory
 		if cfg.Releases != nil {
@@ -403,10 +802,26 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 			d = driver.NewMemory()
 		}
 		d.SetNamespace(namespace)
+		// Sensitive values are kept apart from the records, as they are in
+		// the drivers storing them in a cluster.
+		sensitive := driver.NewMemory()
+		if cfg.Releases != nil && cfg.Releases.Driver == d {
+			if mem, ok := cfg.Releases.Sensitive.(*driver.Memory); ok {
+				sensitive = mem
+			}
+		}
+		sensitive.SetNamespace(namespace)
 		store = storage.Init(d)
+		store.Sensitive = sensitive
 	case "sql":
-		d, err := driver.NewSQL(
-			os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING"),
+		connectionString := os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING")
+		password, err := driver.SQLPasswordFromEnv(connectionString)
+		if err != nil {
+			panic(fmt.Sprintf("Unable to instantiate SQL driver: %v", err))
+		}
+		d, err := driver.NewSQLWithPassword(
+			connectionString,
+			password,
 			log,
 			namespace,
 		)
@@ -414,11 +829,16 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 			panic(fmt.Sprintf("Unable to instantiate SQL driver: %v", err))
 		}
 		store = storage.Init(d)
+		store.Sensitive = cfg.sensitiveSecrets(lazyClient, log)
 	default:
 		// Not sure what to do here.
 		panic("Unknown driver in HELM_DRIVER: " + helmDriver)
 	}
 
+	if cfg.metrics != nil {
+		store.Driver = cfg.metrics.instrumentDriver(store.Driver)
+	}
+
 	cfg.RESTClientGetter = getter
 	cfg.KubeClient = kc
 	cfg.Releases = store
@@ -426,3 +846,25 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 
 	return nil
 }
+
+// sensitiveSecrets returns the Secrets driver keeping the sensitive values of
+// releases stored by other drivers if SensitiveSecrets is set, and nil to
+// keep them in the records otherwise.
+func (cfg *Configuration) sensitiveSecrets(client *lazyClient, log DebugLog) driver.Overflower {
+	if !cfg.SensitiveSecrets {
+		return nil
+	}
+	d := driver.NewSecrets(newSecretClient(client))
+	d.Log = log
+	return d
+}
+
+// checkSchema returns an error if the release was written with a schema newer
+// than the one this version of Helm knows about. Releases derived from it
+// would lose the information this version of Helm does not understand.
+func checkSchema(rel *release.Release) error {
+	if !rel.NewerSchema() {
+		return nil
+	}
+	return errcode.New(errcode.NewerReleaseSchema, rel.Name, rel.Version, rel.HelmVersion, rel.SchemaVersion, release.CurrentSchemaVersion)
+}
@@ -16,10 +16,13 @@ limitations under the License.
 package action
 
 import (
+	"bytes"
 	"flag"
+	"fmt"
 	"io"
 	"testing"
 
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
 
 	"helm.sh/helm/v3/pkg/chart"
@@ -27,6 +30,7 @@ import (
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	"helm.sh/helm/v3/pkg/time"
@@ -273,6 +277,28 @@ func namedReleaseStub(name string, status release.Status) *release.Release {
 	}
 }
 
+func TestInitSensitiveSecrets(t *testing.T) {
+	for _, tt := range []struct {
+		driver           string
+		sensitiveSecrets bool
+		want             bool
+	}{
+		{"secret", false, true},
+		{"configmap", false, false},
+		{"configmap", true, true},
+		{"helmrelease", false, false},
+		{"helmrelease", true, true},
+	} {
+		cfg := &Configuration{SensitiveSecrets: tt.sensitiveSecrets}
+		if err := cfg.Init(genericclioptions.NewConfigFlags(true), "default", tt.driver, t.Logf); err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.Releases.Sensitive != nil; got != tt.want {
+			t.Errorf("%s driver with SensitiveSecrets %t: expected sensitive values to be kept apart %t, got %t", tt.driver, tt.sensitiveSecrets, tt.want, got)
+		}
+	}
+}
+
 func TestGetVersionSet(t *testing.T) {
 	client := fakeclientset.NewSimpleClientset()
 
@@ -288,3 +314,66 @@ func TestGetVersionSet(t *testing.T) {
 		t.Error("Non-existent version is reported found.")
 	}
 }
+
+func TestAggregatedSize(t *testing.T) {
+	ch := buildChart()
+	ch.Files = []*chart.File{{Name: "crds/foo.yaml", Data: []byte("kind: CustomResourceDefinition")}}
+	manifests := []releaseutil.Manifest{
+		{Name: "templates/a.yaml", Content: "kind: ConfigMap"},
+		{Name: "templates/b.yaml", Content: "kind: Secret"},
+	}
+
+	var b bytes.Buffer
+	for _, crd := range ch.CRDObjects() {
+		fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", crd.Filename, crd.File.Data)
+	}
+	for _, m := range manifests {
+		fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", m.Name, m.Content)
+	}
+
+	if size := aggregatedSize(ch, manifests, crdsIncluded); size != b.Len() {
+		t.Errorf("expected size %d, got %d", b.Len(), size)
+	}
+	if size := aggregatedSize(ch, manifests, crdsTemplated); size >= b.Len() {
+		t.Errorf("expected CRDs to be left out, got size %d", size)
+	}
+}
+
+func TestReleaseNotes(t *testing.T) {
+	rendered := func() map[string]string {
+		return map[string]string{
+			"hello/templates/NOTES.txt":                                "parent",
+			"hello/charts/web/templates/NOTES.txt":                     "web",
+			"hello/charts/db/templates/NOTES.txt":                      "db",
+			"hello/charts/db/charts/metrics/templates/NOTES.txt":       "metrics",
+			"hello/charts/quiet/templates/NOTES.txt":                   "  \n",
+			"hello/templates/hello":                                    "hello: world",
+			"hello/charts/db/charts/metrics/templates/service-monitor": "kind: ServiceMonitor",
+		}
+	}
+
+	tests := []struct {
+		name     string
+		notes    *chart.Notes
+		subNotes bool
+		expect   string
+	}{
+		{"parent only", nil, false, "parent"},
+		{"all subcharts by path", nil, true, "parent\ndb\nmetrics\n  \n\nweb"},
+		{"shown by Chart.yaml", &chart.Notes{Subcharts: true, Order: []string{"web", "metrics"}}, false, "parent\nweb\nmetrics\ndb\n  \n"},
+		{"labeled", &chart.Notes{Label: true, Order: []string{"db/metrics"}}, true, "parent\nNOTES for db/metrics:\nmetrics\nNOTES for db:\ndb\nNOTES for web:\nweb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := buildChart()
+			ch.Metadata.Notes = tt.notes
+			files := rendered()
+			if got := releaseNotes(ch, files, tt.subNotes); got != tt.expect {
+				t.Errorf("Expected notes %q, got %q", tt.expect, got)
+			}
+			if len(files) != 2 {
+				t.Errorf("Expected the notes to be removed from the rendered files, got %v", files)
+			}
+		})
+	}
+}
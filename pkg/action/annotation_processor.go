@@ -0,0 +1,122 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// AnnotationProcessor implements a convention of an organization based on an
+// annotation of charts, e.g. by injecting values or by checking preconditions
+// before a chart is installed or upgraded. Returning an error aborts the
+// action.
+type AnnotationProcessor func(req *AnnotationRequest) error
+
+// AnnotationRequest is the request to an AnnotationProcessor for a chart
+// having the annotation of the processor.
+type AnnotationRequest struct {
+	// Action is the action processing the chart, "install" or "upgrade".
+	Action string
+	// Annotation and Value are the annotation of the chart the processor was
+	// registered for and its value.
+	Annotation string
+	Value      string
+	// Chart is the chart having the annotation. It is the chart being
+	// installed or upgraded or one of its subcharts.
+	Chart *chart.Chart
+	// ReleaseName and Namespace identify the release.
+	ReleaseName string
+	Namespace   string
+	// Values are the values supplied for the release, before they are
+	// merged with the values of the chart. The processor may change them.
+	Values map[string]interface{}
+	// DryRun is set when the chart is only rendered.
+	DryRun bool
+}
+
+// registeredAnnotationProcessor is an AnnotationProcessor and the annotation
+// it was registered for.
+type registeredAnnotationProcessor struct {
+	annotation string
+	process    AnnotationProcessor
+}
+
+// RegisterAnnotationProcessor registers a processor invoked for the charts,
+// and their subcharts, having the given annotation in their Chart.yaml, when
+// they are installed, upgraded or rendered. Processors are invoked in the
+// order they were registered, before the dependencies of the chart are
+// processed.
+func (cfg *Configuration) RegisterAnnotationProcessor(annotation string, p AnnotationProcessor) error {
+	if annotation == "" {
+		return errors.New("the annotation of an annotation processor is required")
+	}
+	if p == nil {
+		return errors.Errorf("the annotation processor for %s is nil", annotation)
+	}
+	cfg.annotationProcessors = append(cfg.annotationProcessors, registeredAnnotationProcessor{annotation, p})
+	return nil
+}
+
+// processAnnotations invokes the registered annotation processors for the
+// chart and its subcharts, returning the values as changed by the processors.
+func (cfg *Configuration) processAnnotations(action string, chrt *chart.Chart, name, namespace string, vals map[string]interface{}, dryRun bool) (map[string]interface{}, error) {
+	if len(cfg.annotationProcessors) == 0 {
+		return vals, nil
+	}
+	if vals == nil {
+		vals = map[string]interface{}{}
+	}
+
+	var process func(c *chart.Chart) error
+	process = func(c *chart.Chart) error {
+		for _, p := range cfg.annotationProcessors {
+			value, ok := c.Metadata.Annotations[p.annotation]
+			if !ok {
+				continue
+			}
+			req := &AnnotationRequest{
+				Action:      action,
+				Annotation:  p.annotation,
+				Value:       value,
+				Chart:       c,
+				ReleaseName: name,
+				Namespace:   namespace,
+				Values:      vals,
+				DryRun:      dryRun,
+			}
+			if err := p.process(req); err != nil {
+				return errors.Wrapf(err, "chart %s: annotation %s", c.ChartFullPath(), p.annotation)
+			}
+			vals = req.Values
+			if vals == nil {
+				vals = map[string]interface{}{}
+			}
+		}
+		for _, dep := range c.Dependencies() {
+			if err := process(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := process(chrt); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
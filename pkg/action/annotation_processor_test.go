@@ -0,0 +1,62 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestAnnotationProcessors(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	withTeam := func(c *chart.Chart) *chart.Chart {
+		c.Metadata.Annotations = map[string]string{"example.com/team": "payments"}
+		return c
+	}
+	chrt := withTeam(buildChart(withDependency(withName("sub"))))
+	withTeam(chrt.Dependencies()[0])
+
+	instAction := installAction(t)
+	var invoked []string
+	req.NoError(instAction.cfg.RegisterAnnotationProcessor("example.com/team", func(r *AnnotationRequest) error {
+		invoked = append(invoked, r.Action+" "+r.Chart.ChartFullPath())
+		r.Values["team"] = r.Value
+		return nil
+	}))
+	req.Error(instAction.cfg.RegisterAnnotationProcessor("", func(*AnnotationRequest) error { return nil }))
+
+	rel, err := instAction.Run(chrt, nil)
+	req.NoError(err)
+	is.Equal([]string{"install hello", "install hello/charts/sub"}, invoked)
+	is.Equal(map[string]interface{}{"team": "payments"}, rel.Config)
+
+	upAction := upgradeAction(t)
+	upAction.cfg = instAction.cfg
+	req.NoError(upAction.cfg.RegisterAnnotationProcessor("example.com/team", func(r *AnnotationRequest) error {
+		return errors.Errorf("team %s is frozen", r.Value)
+	}))
+	_, err = upAction.Run(rel.Name, withTeam(buildChart()), map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "chart hello: annotation example.com/team: team payments is frozen")
+}
@@ -0,0 +1,123 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os/user"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/audit"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// recordAudit appends the outcome of an action to the audit log. The chart
+// and values of rel are used if the action got that far, the given ones
+// otherwise. Entries that cannot be recorded are logged, they do not fail the
+// action.
+func (cfg *Configuration) recordAudit(action, name, namespace string, chrt *chart.Chart, vals map[string]interface{}, rel *release.Release, err error) {
+	if cfg.AuditLog == nil {
+		return
+	}
+
+	e := audit.Entry{
+		Action:    action,
+		Release:   name,
+		Namespace: namespace,
+		Result:    audit.ResultSuccess,
+	}
+	if err != nil {
+		e.Result = audit.ResultFailure
+		e.Error = err.Error()
+	}
+	if rel != nil {
+		e.Namespace = rel.Namespace
+		e.Revision = rel.Version
+		chrt = rel.Chart
+		vals = rel.Config
+	}
+	if chrt != nil && chrt.Metadata != nil {
+		e.Chart = chrt.Metadata.Name
+		e.ChartVersion = chrt.Metadata.Version
+	}
+	if rel != nil || vals != nil {
+		e.ValuesDigest = audit.ValuesDigest(vals)
+	}
+
+	cfg.recordAuditEntry(e)
+}
+
+// recordAuditEntry appends the entry to the audit log, completing it with the
+// time and the users running the action.
+func (cfg *Configuration) recordAuditEntry(e audit.Entry) {
+	e.Time = time.Now()
+	e.Error = cfg.Redactor.Text(e.Error)
+	e.Note = cfg.Redactor.Text(e.Note)
+	e.User = cfg.AuditUser
+	if u, err := user.Current(); err == nil {
+		e.OSUser = u.Username
+	}
+	if err := cfg.AuditLog.Record(e); err != nil {
+		cfg.Log("warning: unable to record %s of %s in the audit log: %s", e.Action, e.Release, err)
+	}
+}
+
+// Audit is the action for reading the audit log.
+//
+// It provides the implementation of 'helm audit log'.
+type Audit struct {
+	cfg *Configuration
+
+	// Release limits the entries to those of the named release.
+	Release string
+	// Max limits the number of entries to the most recent ones, if positive.
+	Max int
+}
+
+// NewAudit creates a new Audit object with the given configuration.
+func NewAudit(cfg *Configuration) *Audit {
+	return &Audit{
+		cfg: cfg,
+	}
+}
+
+// Run returns the entries of the audit log, oldest first.
+func (a *Audit) Run() ([]audit.Entry, error) {
+	if a.cfg.AuditLog == nil {
+		return nil, errors.New("no audit log configured: set it with --audit-log or $HELM_AUDIT_LOG")
+	}
+	entries, err := a.cfg.AuditLog.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the audit log")
+	}
+
+	if a.Release != "" {
+		var filtered []audit.Entry
+		for _, e := range entries {
+			if e.Release == a.Release {
+				filtered = append(filtered, e)
+			}
+		}
+		entries = filtered
+	}
+	if a.Max > 0 && len(entries) > a.Max {
+		entries = entries[len(entries)-a.Max:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/audit"
+	"helm.sh/helm/v3/pkg/redact"
+)
+
+func TestAuditLog(t *testing.T) {
+	instAction := installAction(t)
+	cfg := instAction.cfg
+
+	_, err := NewAudit(cfg).Run()
+	assert.ErrorContains(t, err, "no audit log configured")
+
+	cfg.AuditLog = audit.NewFile(filepath.Join(t.TempDir(), "audit.log"))
+	cfg.AuditUser = "alice"
+
+	vals := map[string]interface{}{"name": "value"}
+	_, err = instAction.Run(buildChart(), vals)
+	require.NoError(t, err)
+
+	_, err = NewUninstall(cfg).Run("missing")
+	require.Error(t, err)
+
+	// Dry runs are not recorded.
+	dryRun := NewUpgrade(cfg)
+	dryRun.DryRun = true
+	_, err = dryRun.Run(instAction.ReleaseName, buildChart(), vals)
+	require.NoError(t, err)
+
+	entries, err := NewAudit(cfg).Run()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	e := entries[0]
+	assert.Equal(t, "alice", e.User)
+	assert.Equal(t, "install", e.Action)
+	assert.Equal(t, instAction.ReleaseName, e.Release)
+	assert.Equal(t, "spaced", e.Namespace)
+	assert.Equal(t, 1, e.Revision)
+	assert.Equal(t, "hello", e.Chart)
+	assert.Equal(t, "0.1.0", e.ChartVersion)
+	assert.Equal(t, audit.ValuesDigest(vals), e.ValuesDigest)
+	assert.Equal(t, audit.ResultSuccess, e.Result)
+
+	assert.Equal(t, "uninstall", entries[1].Action)
+	assert.Equal(t, audit.ResultFailure, entries[1].Result)
+	assert.NotEmpty(t, entries[1].Error)
+
+	a := NewAudit(cfg)
+	a.Release = instAction.ReleaseName
+	entries, err = a.Run()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	a = NewAudit(cfg)
+	a.Max = 1
+	entries, err = a.Run()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "uninstall", entries[0].Action)
+}
+
+func TestAuditLogRedactsErrors(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	cfg.AuditLog = audit.NewFile(filepath.Join(t.TempDir(), "audit.log"))
+	r, err := redact.New()
+	require.NoError(t, err)
+	cfg.Redactor = r
+
+	cfg.recordAudit("install", "foo", "default", nil, nil, nil, errors.New("invalid value password=hunter2"))
+
+	entries, err := NewAudit(cfg).Run()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "invalid value password=[REDACTED]", entries[0].Error)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// CapabilitiesExport is the action for taking a snapshot of the capabilities
+// of a cluster.
+//
+// It provides the implementation of 'helm capabilities export'.
+type CapabilitiesExport struct {
+	cfg *Configuration
+
+	// SkipCRDs leaves the custom resource definitions of the cluster out of
+	// the snapshot.
+	SkipCRDs bool
+}
+
+// NewCapabilitiesExport creates a new CapabilitiesExport object with the given configuration.
+func NewCapabilitiesExport(cfg *Configuration) *CapabilitiesExport {
+	return &CapabilitiesExport{
+		cfg: cfg,
+	}
+}
+
+// Run executes 'helm capabilities export' against the current cluster.
+func (c *CapabilitiesExport) Run() (*chartutil.CapabilitiesSnapshot, error) {
+	if err := c.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	caps, err := c.cfg.getCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	if c.SkipCRDs {
+		return chartutil.NewCapabilitiesSnapshot(caps, nil), nil
+	}
+
+	conf, err := c.cfg.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate config for kubernetes client")
+	}
+	clientset, err := apiextensionsclientset.NewForConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	crds, err := clientset.ApiextensionsV1().CustomResourceDefinitions().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list custom resource definitions")
+	}
+	return chartutil.NewCapabilitiesSnapshot(caps, crds.Items), nil
+}
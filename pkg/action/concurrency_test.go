@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// TestConcurrentActions runs actions sharing a Configuration concurrently. Run
+// with -race to detect unsynchronized access to the shared state.
+func TestConcurrentActions(t *testing.T) {
+	cfg := actionConfigFixture(t)
+
+	const n = 8
+	for i := 0; i < n; i++ {
+		rel := namedReleaseStub(fmt.Sprintf("release-%d", i), release.StatusDeployed)
+		if err := cfg.Releases.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(name string) {
+			defer wg.Done()
+			upAction := NewUpgrade(cfg)
+			upAction.Namespace = "spaced"
+			upAction.MaxHistory = 2
+			_, err := upAction.Run(name, buildChart(), map[string]interface{}{})
+			errs <- err
+		}(fmt.Sprintf("release-%d", i))
+		go func(name string) {
+			defer wg.Done()
+			_, err := NewStatus(cfg).Run(name)
+			errs <- err
+		}(fmt.Sprintf("release-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Upgrading must not change the history limit of the shared storage.
+	if cfg.Releases.MaxHistory != 0 {
+		t.Errorf("expected the storage history limit to be unchanged, got %d", cfg.Releases.MaxHistory)
+	}
+}
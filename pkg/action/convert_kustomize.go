@@ -0,0 +1,63 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"path/filepath"
+
+	"helm.sh/helm/v3/internal/kustomize"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// ConvertKustomize is the action for converting a kustomization into a chart.
+//
+// It provides the implementation of 'helm convert kustomize'.
+type ConvertKustomize struct {
+	// Name is the name of the chart. It defaults to the name of the
+	// kustomization directory.
+	Name string
+	// Destination is the directory the chart is created in.
+	Destination string
+}
+
+// NewConvertKustomize creates a new ConvertKustomize object.
+func NewConvertKustomize() *ConvertKustomize {
+	return &ConvertKustomize{Destination: "."}
+}
+
+// Run builds the kustomization in the given directory and creates a chart of
+// the resulting objects, returning the path to the chart.
+//
+// The fields set by the patches of the kustomization are lifted into values,
+// in addition to the name, labels, replicas and container images of every
+// object that chartutil.CreateFromObjects parameterizes.
+func (c *ConvertKustomize) Run(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	name := c.Name
+	if name == "" {
+		name = filepath.Base(abs)
+	}
+
+	objects, err := kustomize.Objects(abs)
+	if err != nil {
+		return "", err
+	}
+	return chartutil.CreateFromObjects(name, c.Destination, objects)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestConvertKustomize(t *testing.T) {
+	client := NewConvertKustomize()
+	client.Destination = t.TempDir()
+	dest, err := client.Run("testdata/kustomize/overlays/prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(dest) != "prod" {
+		t.Errorf("expected the chart to be named after the overlay, got %s", dest)
+	}
+
+	if _, err := loader.Load(dest); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := chartutil.ReadValuesFile(filepath.Join(dest, "values.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expect := range map[string]interface{}{
+		"prodWeb.spec.replicas":                         float64(6),
+		"prodWeb.spec.template.spec.serviceAccountName": "web-prod",
+		"prodWeb.containers.migrate.image.tag":          "1.4.2",
+		"prodWeb.labels.env":                            "production",
+		"serviceProdWeb.spec.ports": []interface{}{
+			map[string]interface{}{"port": float64(443), "targetPort": float64(8080)},
+		},
+	} {
+		got, err := vals.PathValue(path)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+		} else if !reflect.DeepEqual(got, expect) {
+			t.Errorf("%s: expected %v, got %v", path, expect, got)
+		}
+	}
+	if _, ok := vals["serviceProdWeb"].(map[string]interface{})["metadata"].(map[string]interface{})["annotations"]; !ok {
+		t.Error("expected the patched annotations of the service to be lifted")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "templates", "prod-web-deployment.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"replicas: {{ .Values.prodWeb.spec.replicas | toJson }}",
+		"{{- toYaml .Values.prodWeb.spec.template.spec.containers | nindent 8 }}",
+		"serviceAccountName: {{ .Values.prodWeb.spec.template.spec.serviceAccountName | toJson }}",
+	} {
+		if !strings.Contains(string(data), expect) {
+			t.Errorf("expected the deployment template to contain %q, got\n%s", expect, data)
+		}
+	}
+	data, err = os.ReadFile(filepath.Join(dest, "templates", "prod-web-service.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `{{ (index .Values.serviceProdWeb "metadata" "annotations" "service.beta.kubernetes.io/aws-load-balancer-type") | toJson }}`; !strings.Contains(string(data), expect) {
+		t.Errorf("expected the service template to contain %q, got\n%s", expect, data)
+	}
+}
+
+func TestConvertKustomizeNoKustomization(t *testing.T) {
+	client := NewConvertKustomize()
+	client.Destination = t.TempDir()
+	if _, err := client.Run(t.TempDir()); err == nil {
+		t.Error("expected an error converting a directory without a kustomization")
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// DocsTemplateFile is the template in a chart its README is generated from,
+// when no other template is given.
+const DocsTemplateFile = "README.md.gotmpl"
+
+// The values table of a README is written between these markers, so that it
+// can be updated without changing the rest of the README.
+const (
+	valuesTableStart = "<!-- helm-docs:values:start -->"
+	valuesTableEnd   = "<!-- helm-docs:values:end -->"
+)
+
+const defaultDocsTemplate = `# {{ .Chart.Name }}
+
+{{ with .Chart.Description }}{{ . }}
+
+{{ end }}![Version: {{ .Chart.Version }}](https://img.shields.io/badge/Version-{{ .Chart.Version | replace "-" "--" }}-informational?style=flat-square)
+{{- with .Chart.AppVersion }} ![AppVersion: {{ . }}](https://img.shields.io/badge/AppVersion-{{ . | replace "-" "--" }}-informational?style=flat-square){{ end }}
+
+## Values
+
+{{ .ValuesTable }}
+`
+
+// DocsData is what the templates of READMEs are rendered with.
+type DocsData struct {
+	// Chart is the metadata of the chart.
+	Chart *chart.Metadata
+	// Values documents the values of the chart.
+	Values []chartutil.ValueDoc
+	// ValuesTable is the Markdown table of the values, between the markers
+	// allowing it to be updated.
+	ValuesTable string
+}
+
+// Docs is the action for generating the README of a chart.
+//
+// It provides the implementation of 'helm docs generate'.
+type Docs struct {
+	// Template is the path to the template the README is rendered from. It
+	// defaults to README.md.gotmpl in the chart.
+	Template string
+	// Output is the name of the README in the chart.
+	Output string
+	// Check returns an error if the README is not up to date rather than
+	// writing it.
+	Check bool
+}
+
+// NewDocs creates a new Docs object.
+func NewDocs() *Docs {
+	return &Docs{Output: "README.md"}
+}
+
+// Run generates the README of the chart in the given directory and returns its
+// path.
+//
+// The values are documented from the comments of values.yaml and from
+// values.schema.json, see chartutil.ValuesDocs. When there is a template, the
+// README is rendered from it. Otherwise only the values table of an existing
+// README is updated, or appended to it if it has none, and a missing README
+// is created from a default template.
+func (d *Docs) Run(path string) (string, error) {
+	if fi, err := os.Stat(path); err != nil {
+		return "", err
+	} else if !fi.IsDir() {
+		return "", errors.Errorf("%s is not a chart directory", path)
+	}
+	chrt, err := loader.LoadDir(path)
+	if err != nil {
+		return "", err
+	}
+	var values []byte
+	for _, f := range chrt.Raw {
+		if f.Name == chartutil.ValuesfileName {
+			values = f.Data
+		}
+	}
+	docs, err := chartutil.ValuesDocs(values, chrt.Schema)
+	if err != nil {
+		return "", err
+	}
+	data := DocsData{
+		Chart:       chrt.Metadata,
+		Values:      docs,
+		ValuesTable: valuesTableStart + "\n" + chartutil.ValuesTable(docs) + valuesTableEnd,
+	}
+
+	out := filepath.Join(path, d.Output)
+	existing, err := os.ReadFile(out)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	tpl, err := d.template(path)
+	if err != nil {
+		return "", err
+	}
+
+	var readme string
+	switch {
+	case tpl == "" && existing != nil:
+		readme = updateValuesTable(string(existing), data.ValuesTable)
+	default:
+		if tpl == "" {
+			tpl = defaultDocsTemplate
+		}
+		if readme, err = renderDocs(tpl, data); err != nil {
+			return "", err
+		}
+	}
+
+	if d.Check {
+		if readme != string(existing) {
+			return out, errors.Errorf("%s is not up to date", out)
+		}
+		return out, nil
+	}
+	return out, os.WriteFile(out, []byte(readme), 0644)
+}
+
+// template returns the template the README is rendered from, or "" if there
+// is none.
+func (d *Docs) template(chartPath string) (string, error) {
+	path := d.Template
+	if path == "" {
+		path = filepath.Join(chartPath, DocsTemplateFile)
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) && d.Template == "" {
+		return "", nil
+	}
+	return string(b), err
+}
+
+func renderDocs(tpl string, data DocsData) (string, error) {
+	t, err := template.New("readme").Funcs(sprig.TxtFuncMap()).Funcs(template.FuncMap{
+		"valuesTable": chartutil.ValuesTable,
+	}).Parse(tpl)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing the README template")
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", errors.Wrap(err, "rendering the README template")
+	}
+	return b.String(), nil
+}
+
+// updateValuesTable replaces the values table between the markers of a README
+// with table, or appends it in a Values section if the README has none.
+func updateValuesTable(readme, table string) string {
+	start := strings.Index(readme, valuesTableStart)
+	end := strings.Index(readme, valuesTableEnd)
+	if start < 0 || end < start {
+		return strings.TrimRight(readme, "\n") + "\n\n## Values\n\n" + table + "\n"
+	}
+	return readme[:start] + table + readme[end+len(valuesTableEnd):]
+}
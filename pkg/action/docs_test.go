@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestDocs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "docs")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	require.NoError(t, chartutil.SaveChartfile(filepath.Join(dir, chartutil.ChartfileName), &chart.Metadata{
+		APIVersion: chart.APIVersionV2, Name: "docs", Description: "A documented chart", Version: "1.0.0-rc.1",
+	}))
+	writeValues := func(values string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, chartutil.ValuesfileName), []byte(values), 0644))
+	}
+	readme := func() string {
+		b, err := os.ReadFile(filepath.Join(dir, "README.md"))
+		require.NoError(t, err)
+		return string(b)
+	}
+	table := "<!-- helm-docs:values:start -->\n" +
+		"| Key | Type | Default | Description |\n" +
+		"|-----|------|---------|-------------|\n" +
+		"| replicaCount | integer | `1` | number of replicas |\n" +
+		"<!-- helm-docs:values:end -->"
+
+	writeValues("# -- number of replicas\nreplicaCount: 1\n")
+	client := NewDocs()
+	path, err := client.Run(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "README.md"), path)
+	assert.Equal(t, "# docs\n\nA documented chart\n\n"+
+		"![Version: 1.0.0-rc.1](https://img.shields.io/badge/Version-1.0.0--rc.1-informational?style=flat-square)\n\n"+
+		"## Values\n\n"+table+"\n", readme())
+
+	// Only the values table of an existing README is updated.
+	require.NoError(t, os.WriteFile(path, []byte("# Docs\n\nWritten by hand.\n\n"+table+"\n\n## License\n"), 0644))
+	writeValues("# -- number of replicas\nreplicaCount: 2\n")
+	client.Check = true
+	_, err = client.Run(dir)
+	assert.EqualError(t, err, path+" is not up to date")
+	client.Check = false
+	_, err = client.Run(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "# Docs\n\nWritten by hand.\n\n"+
+		"<!-- helm-docs:values:start -->\n"+
+		"| Key | Type | Default | Description |\n"+
+		"|-----|------|---------|-------------|\n"+
+		"| replicaCount | integer | `2` | number of replicas |\n"+
+		"<!-- helm-docs:values:end -->\n\n## License\n", readme())
+	client.Check = true
+	_, err = client.Run(dir)
+	assert.NoError(t, err)
+	client.Check = false
+
+	// A README without a values table has one appended.
+	require.NoError(t, os.WriteFile(path, []byte("# Docs\n"), 0644))
+	_, err = client.Run(dir)
+	require.NoError(t, err)
+	assert.Contains(t, readme(), "# Docs\n\n## Values\n\n<!-- helm-docs:values:start -->\n")
+
+	// The template of the chart renders the whole README.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, DocsTemplateFile),
+		[]byte("{{ .Chart.Name | upper }}{{ range .Values }} {{ .Key }}={{ .Default }}{{ end }}\n"), 0644))
+	_, err = client.Run(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "DOCS replicaCount=2\n", readme())
+
+	client.Template = filepath.Join(dir, "missing.gotmpl")
+	_, err = client.Run(dir)
+	assert.Error(t, err)
+}
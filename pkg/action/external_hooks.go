@@ -0,0 +1,278 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/errcode"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+// ExternalHookKind is the kind of the hooks run outside of the cluster, by
+// Helm itself, rather than as resources created in the cluster:
+//
+//	apiVersion: hooks.helm.sh/v1
+//	kind: ExternalHook
+//	metadata:
+//	  name: notify-change-management
+//	  annotations:
+//	    helm.sh/hook: post-upgrade
+//	spec:
+//	  webhook:
+//	    url: https://changes.example.com/api/changes
+//	    body: '{"release": "{{ .Release.Name }}", "revision": {{ .Release.Revision }}}'
+//
+// Like all templates, the spec is rendered with the values of the release.
+// External hooks only run the commands and reach the hosts the
+// ExternalHookPolicy of the configuration allows, as they let charts run
+// commands and send requests from the machine running Helm.
+const ExternalHookKind = "ExternalHook"
+
+// ExternalHookPolicy is what external hooks may run. Without a policy,
+// external hooks are refused.
+type ExternalHookPolicy struct {
+	// Commands are patterns, as matched by path.Match, of the commands hooks
+	// may run as the first element of their command, e.g. "kubectl" or
+	// "/opt/hooks/*".
+	Commands []string
+	// Hosts are patterns, as matched by path.Match, of the hosts webhooks
+	// may send requests to, with a port if it must match too, e.g.
+	// "*.example.com" or "localhost:8080". Redirects are only followed to
+	// allowed hosts.
+	Hosts []string
+	// Env lists the variables of the environment of Helm passed to commands
+	// besides those of plugin.SandboxEnv. A name ending in "*" passes all
+	// variables with that prefix.
+	Env []string
+	// Transport sends the requests of webhooks. It defaults to a transport
+	// like that of the HTTP getters of Helm, which uses the proxy of the
+	// environment.
+	Transport http.RoundTripper
+}
+
+// checkCommand returns an error unless the policy allows the command.
+func (p *ExternalHookPolicy) checkCommand(command string) error {
+	if !matchesAny(p.Commands, command) {
+		return errors.Errorf("command %q is not allowed for external hooks", command)
+	}
+	return nil
+}
+
+// checkURL returns an error unless the policy allows requests to the host of
+// the URL.
+func (p *ExternalHookPolicy) checkURL(u *url.URL) error {
+	if !matchesAny(p.Hosts, u.Host) && !matchesAny(p.Hosts, u.Hostname()) {
+		return errors.Errorf("host %q is not allowed for external hooks", u.Host)
+	}
+	return nil
+}
+
+// client returns the HTTP client of webhooks, which only follows redirects to
+// allowed hosts.
+func (p *ExternalHookPolicy) client() *http.Client {
+	transport := p.Transport
+	if transport == nil {
+		transport = &http.Transport{
+			DisableCompression: true,
+			Proxy:              http.ProxyFromEnvironment,
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return p.checkURL(req.URL)
+		},
+	}
+}
+
+// ExternalHook is a hook of kind ExternalHookKind.
+type ExternalHook struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   metav1.ObjectMeta `json:"metadata"`
+	Spec       ExternalHookSpec  `json:"spec"`
+}
+
+// ExternalHookSpec is what an external hook runs: either a command or a
+// webhook request.
+type ExternalHookSpec struct {
+	// Command is the command run with its arguments. It is run with the
+	// variables of the environment of Helm the ExternalHookPolicy passes,
+	// along with HELM_HOOK_EVENT, HELM_RELEASE_NAME, HELM_RELEASE_NAMESPACE
+	// and HELM_RELEASE_REVISION.
+	Command []string `json:"command,omitempty"`
+	// Webhook is the request sent.
+	Webhook *ExternalHookWebhook `json:"webhook,omitempty"`
+}
+
+// ExternalHookWebhook is the request an external hook sends. The hook fails
+// unless the response has a 2xx status.
+type ExternalHookWebhook struct {
+	URL string `json:"url"`
+	// Method is the method of the request, POST by default.
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the body of the request, sent as application/json unless a
+	// Content-Type header is set.
+	Body string `json:"body,omitempty"`
+}
+
+// parseExternalHook parses and validates the manifest of an external hook.
+func parseExternalHook(manifest string) (*ExternalHook, error) {
+	var h ExternalHook
+	if err := yaml.UnmarshalStrict([]byte(manifest), &h); err != nil {
+		return nil, err
+	}
+	if (len(h.Spec.Command) == 0) == (h.Spec.Webhook == nil) {
+		return nil, errors.New("an external hook must have either a command or a webhook")
+	}
+	if h.Spec.Webhook != nil && h.Spec.Webhook.URL == "" {
+		return nil, errors.New("the webhook of an external hook must have a URL")
+	}
+	return &h, nil
+}
+
+// execExternal runs an external hook, recording its execution like that of
+// the hooks run in the cluster.
+func (r *hookRun) execExternal(h *release.Hook) error {
+	cfg, rl, event, timeout := r.cfg, r.rl, r.event, r.timeoutOf(h)
+	policy := cfg.ExternalHooks
+	if policy == nil {
+		return errors.Errorf("%s hook %s runs outside of the cluster, which is not allowed", event, h.Path)
+	}
+	eh, err := parseExternalHook(h.Manifest)
+	if err != nil {
+		return errors.Wrapf(err, "invalid %s hook %s", event, h.Path)
+	}
+	var target *url.URL
+	if eh.Spec.Webhook != nil {
+		if target, err = url.Parse(eh.Spec.Webhook.URL); err == nil {
+			err = policy.checkURL(target)
+		}
+	} else {
+		err = policy.checkCommand(eh.Spec.Command[0])
+	}
+	if err != nil {
+		return errors.Wrapf(err, "%s hook %s", event, h.Path)
+	}
+
+	r.setLastRun(h, func(e *release.HookExecution) {
+		*e = release.HookExecution{
+			StartedAt: helmtime.Now(),
+			Phase:     release.HookPhaseRunning,
+		}
+	}, true)
+
+	ctx := r.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if eh.Spec.Webhook != nil {
+		err = eh.Spec.Webhook.send(ctx, policy.client(), target)
+	} else {
+		err = cfg.runHookCommand(ctx, policy, eh.Spec.Command, rl, event)
+	}
+
+	r.setLastRun(h, func(e *release.HookExecution) {
+		e.CompletedAt = helmtime.Now()
+		if err != nil && r.ctx.Err() != nil {
+			// The hook was stopped, so whether it would have succeeded is unknown
+			e.Phase = release.HookPhaseUnknown
+		} else if err != nil {
+			e.Phase = release.HookPhaseFailed
+		} else {
+			e.Phase = release.HookPhaseSucceeded
+		}
+	}, false)
+	cfg.observeHook(event, h)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errcode.New(errcode.HookFailed, event, h.Path), err)
+	}
+	return nil
+}
+
+func (cfg *Configuration) runHookCommand(ctx context.Context, policy *ExternalHookPolicy, command []string, rl *release.Release, event release.HookEvent) error {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	allowed := append(append([]string{}, plugin.SandboxEnv...), policy.Env...)
+	cmd.Env = append(plugin.FilterEnv(os.Environ(), allowed),
+		"HELM_HOOK_EVENT="+event.String(),
+		"HELM_RELEASE_NAME="+rl.Name,
+		"HELM_RELEASE_NAMESPACE="+rl.Namespace,
+		"HELM_RELEASE_REVISION="+strconv.Itoa(rl.Version),
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if len(out) > 0 {
+		cfg.Log("%s hook %s: %s", event, command[0], strings.TrimSpace(string(out)))
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.Wrap(err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func (w *ExternalHookWebhook) send(ctx context.Context, client *http.Client, target *url.URL) error {
+	method := w.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), strings.NewReader(w.Body))
+	if err != nil {
+		return err
+	}
+	if w.Body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
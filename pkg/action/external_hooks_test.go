@@ -0,0 +1,162 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/errcode"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func externalHookChart(spec string) *chart.Chart {
+	chrt := buildChart()
+	chrt.Templates = append(chrt.Templates, &chart.File{
+		Name: "templates/notify.yaml",
+		Data: []byte(`apiVersion: hooks.helm.sh/v1
+kind: ExternalHook
+metadata:
+  name: notify
+  annotations:
+    helm.sh/hook: post-install
+spec:
+` + spec),
+	})
+	return chrt
+}
+
+func TestInstallRelease_ExternalHookWebhook(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	var method, body, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, body, auth = r.Method, string(data), r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	instAction := installAction(t)
+	instAction.cfg.ExternalHooks = &ExternalHookPolicy{Hosts: []string{"127.0.0.1"}}
+	res, err := instAction.Run(externalHookChart(`  webhook:
+    url: `+srv.URL+`
+    method: PUT
+    headers:
+      Authorization: Bearer token
+    body: '{"release": "{{ .Release.Name }}"}'
+`), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(http.MethodPut, method)
+	is.Equal(`{"release": "test-install-release"}`, body)
+	is.Equal("Bearer token", auth)
+
+	hook := res.Hooks[len(res.Hooks)-1]
+	is.Equal(ExternalHookKind, hook.Kind)
+	is.Equal(release.HookPhaseSucceeded, hook.LastRun.Phase)
+	is.False(hook.LastRun.CompletedAt.IsZero())
+}
+
+func TestInstallRelease_ExternalHookCommand(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	t.Setenv("HOOK_TOKEN", "secret")
+	t.Setenv("HOOK_PASSED", "passed")
+	out := filepath.Join(t.TempDir(), "out")
+	instAction := installAction(t)
+	instAction.cfg.ExternalHooks = &ExternalHookPolicy{Commands: []string{"sh"}, Env: []string{"HOOK_PASS*"}}
+	_, err := instAction.Run(externalHookChart(`  command: [sh, -c, 'echo "$HELM_HOOK_EVENT $HELM_RELEASE_NAME $HELM_RELEASE_REVISION $HOOK_PASSED $HOOK_TOKEN" > `+out+`']
+`), map[string]interface{}{})
+	req.NoError(err)
+	data, err := os.ReadFile(out)
+	req.NoError(err)
+	is.Equal("post-install test-install-release 1 passed \n", string(data))
+
+	instAction = installAction(t)
+	instAction.cfg.ExternalHooks = &ExternalHookPolicy{Commands: []string{"sh"}}
+	res, err := instAction.Run(externalHookChart(`  command: [sh, -c, 'echo no change ticket >&2; exit 1']
+`), map[string]interface{}{})
+	is.ErrorContains(err, "warning: Hook post-install hello/templates/notify.yaml failed: no change ticket: exit status 1")
+	is.Equal(errcode.HookFailed, errcode.Of(err))
+	is.Equal(release.HookPhaseFailed, res.Hooks[len(res.Hooks)-1].LastRun.Phase)
+}
+
+func TestInstallRelease_ExternalHookNotAllowed(t *testing.T) {
+	instAction := installAction(t)
+	_, err := instAction.Run(externalHookChart("  command: [true]\n"), map[string]interface{}{})
+	assert.ErrorContains(t, err, "post-install hook hello/templates/notify.yaml runs outside of the cluster, which is not allowed")
+
+	instAction = installAction(t)
+	instAction.cfg.ExternalHooks = &ExternalHookPolicy{Commands: []string{"true"}}
+	_, err = instAction.Run(externalHookChart("  command: [true]\n  webhook:\n    url: http://localhost\n"), map[string]interface{}{})
+	assert.ErrorContains(t, err, "invalid post-install hook hello/templates/notify.yaml: an external hook must have either a command or a webhook")
+}
+
+func TestInstallRelease_ExternalHookPolicy(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), http.StatusFound))
+	defer redirect.Close()
+
+	policy := &ExternalHookPolicy{Commands: []string{"/opt/hooks/*"}, Hosts: []string{"127.0.0.1"}}
+	for spec, errText := range map[string]string{
+		"  command: [sh, -c, 'true']\n":               "post-install hook hello/templates/notify.yaml: command \"sh\" is not allowed for external hooks",
+		"  command: [/opt/hooks/../../bin/sh]\n":      "post-install hook hello/templates/notify.yaml: command \"/opt/hooks/../../bin/sh\" is not allowed for external hooks",
+		"  webhook:\n    url: http://example.com\n":   "post-install hook hello/templates/notify.yaml: host \"example.com\" is not allowed for external hooks",
+		"  webhook:\n    url: " + redirect.URL + "\n": "is not allowed for external hooks",
+	} {
+		instAction := installAction(t)
+		instAction.cfg.ExternalHooks = policy
+		_, err := instAction.Run(externalHookChart(spec), map[string]interface{}{})
+		assert.ErrorContains(t, err, errText, spec)
+	}
+	assert.Zero(t, requests, "a request was sent to a host that is not allowed")
+}
+
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestInstallRelease_ExternalHookTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	defer srv.Close()
+
+	transport := &countingTransport{}
+	instAction := installAction(t)
+	instAction.cfg.ExternalHooks = &ExternalHookPolicy{Hosts: []string{"127.0.0.1"}, Transport: transport}
+	_, err := instAction.Run(externalHookChart("  webhook:\n    url: "+srv.URL+"\n"), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, transport.requests)
+}
@@ -0,0 +1,301 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/audit"
+	"helm.sh/helm/v3/pkg/errcode"
+)
+
+// FreezePolicyConfigMapKey is the key of the ConfigMap holding a freeze policy.
+const FreezePolicyConfigMapKey = "freeze-policy.yaml"
+
+// freezePolicyConfigMapPrefix marks a freeze policy source as a ConfigMap in
+// the form "configmap:<namespace>/<name>".
+const freezePolicyConfigMapPrefix = "configmap:"
+
+// FreezePolicy defines the periods during which releases must not be
+// installed, upgraded or rolled back.
+type FreezePolicy struct {
+	Windows []FreezeWindow `json:"freezeWindows"`
+}
+
+// FreezeWindow is a period during which the releases it selects are frozen.
+//
+// A window is either a single period, from Start to End, or a period recurring
+// on Days, from From to To. Both can be combined to limit a recurring window
+// to a range of dates.
+type FreezeWindow struct {
+	// Name identifies the window in errors and in the audit log.
+	Name string `json:"name"`
+	// Reason tells users why changes are frozen.
+	Reason string `json:"reason,omitempty"`
+	// Namespaces are patterns, as matched by path.Match, of the namespaces
+	// of the releases the window applies to. It applies to all namespaces if
+	// empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Selector selects the releases the window applies to by their labels.
+	// It applies to all releases if nil.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// Start and End bound the window. Either may be omitted.
+	Start time.Time `json:"start,omitempty"`
+	End   time.Time `json:"end,omitempty"`
+	// Days are the days of the week, e.g. "Saturday" or "Sat", the window
+	// recurs on.
+	Days []string `json:"days,omitempty"`
+	// From and To are the times of day, e.g. "18:00", between which a
+	// recurring window is active. They default to the whole day. Windows
+	// whose To is before their From span midnight.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// TimeZone is the IANA time zone of Days, From and To. It defaults to
+	// UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	selector labels.Selector
+	days     map[time.Weekday]bool
+	from, to time.Duration
+	location *time.Location
+}
+
+// LoadFreezePolicy reads the freeze policy of the given source, a YAML file or
+// a ConfigMap given as "configmap:<namespace>/<name>". A missing file defines
+// no freeze windows.
+func (cfg *Configuration) LoadFreezePolicy(source string) (*FreezePolicy, error) {
+	if !strings.HasPrefix(source, freezePolicyConfigMapPrefix) {
+		data, err := os.ReadFile(source)
+		if os.IsNotExist(err) {
+			return &FreezePolicy{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		p, err := ParseFreezePolicy(data)
+		return p, errors.Wrapf(err, "invalid freeze policy %s", source)
+	}
+
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(source, freezePolicyConfigMapPrefix), "/")
+	if !ok {
+		return nil, errors.Errorf("invalid freeze policy source %q: ConfigMaps are given as configmap:<namespace>/<name>", source)
+	}
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get freeze policy from ConfigMap %s/%s", namespace, name)
+	}
+	data, ok := cm.Data[FreezePolicyConfigMapKey]
+	if !ok {
+		return nil, errors.Errorf("ConfigMap %s/%s has no %q key", namespace, name, FreezePolicyConfigMapKey)
+	}
+	p, err := ParseFreezePolicy([]byte(data))
+	return p, errors.Wrapf(err, "invalid freeze policy in ConfigMap %s/%s", namespace, name)
+}
+
+// ParseFreezePolicy parses and validates a freeze policy.
+func ParseFreezePolicy(data []byte) (*FreezePolicy, error) {
+	p := &FreezePolicy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, err
+	}
+	for i := range p.Windows {
+		if err := p.Windows[i].init(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (w *FreezeWindow) init() error {
+	if w.Name == "" {
+		return errors.New("freeze window without a name")
+	}
+	if w.Start.IsZero() && w.End.IsZero() && len(w.Days) == 0 {
+		return errors.Errorf("freeze window %q: set start, end or days", w.Name)
+	}
+	if !w.Start.IsZero() && !w.End.IsZero() && !w.Start.Before(w.End) {
+		return errors.Errorf("freeze window %q: end is not after start", w.Name)
+	}
+	for _, pattern := range w.Namespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Errorf("freeze window %q: invalid namespace pattern %q", w.Name, pattern)
+		}
+	}
+
+	w.selector = labels.Everything()
+	if w.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(w.Selector)
+		if err != nil {
+			return errors.Wrapf(err, "freeze window %q: invalid selector", w.Name)
+		}
+		w.selector = selector
+	}
+
+	if len(w.Days) == 0 {
+		if w.From != "" || w.To != "" || w.TimeZone != "" {
+			return errors.Errorf("freeze window %q: from, to and timeZone require days", w.Name)
+		}
+		return nil
+	}
+	w.days = map[time.Weekday]bool{}
+	for _, d := range w.Days {
+		day, ok := parseWeekday(d)
+		if !ok {
+			return errors.Errorf("freeze window %q: invalid day %q", w.Name, d)
+		}
+		w.days[day] = true
+	}
+	var err error
+	if w.from, err = parseTimeOfDay(w.From, 0); err != nil {
+		return errors.Wrapf(err, "freeze window %q: invalid from", w.Name)
+	}
+	if w.to, err = parseTimeOfDay(w.To, 24*time.Hour); err != nil {
+		return errors.Wrapf(err, "freeze window %q: invalid to", w.Name)
+	}
+	if w.location, err = time.LoadLocation(w.TimeZone); err != nil {
+		return errors.Wrapf(err, "freeze window %q: invalid timeZone", w.Name)
+	}
+	return nil
+}
+
+// Applies reports whether the window applies to releases of the namespace
+// with the labels.
+func (w *FreezeWindow) Applies(namespace string, lbls map[string]string) bool {
+	if len(w.Namespaces) > 0 {
+		matched := false
+		for _, pattern := range w.Namespaces {
+			if ok, _ := path.Match(pattern, namespace); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return w.selector.Matches(labels.Set(lbls))
+}
+
+// Active reports whether the window is active at the time.
+func (w *FreezeWindow) Active(t time.Time) bool {
+	if !w.Start.IsZero() && t.Before(w.Start) {
+		return false
+	}
+	if !w.End.IsZero() && !t.Before(w.End) {
+		return false
+	}
+	if len(w.days) == 0 {
+		return true
+	}
+
+	t = t.In(w.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.location)
+	offset := t.Sub(midnight)
+	if w.from < w.to {
+		return w.days[t.Weekday()] && offset >= w.from && offset < w.to
+	}
+	// The window spans midnight, starting on one of its days.
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.days[t.Weekday()] && offset >= w.from) || (w.days[yesterday] && offset < w.to)
+}
+
+// Frozen returns the first window active at the time for releases of the
+// namespace with the labels, or nil.
+func (p *FreezePolicy) Frozen(t time.Time, namespace string, lbls map[string]string) *FreezeWindow {
+	for i := range p.Windows {
+		w := &p.Windows[i]
+		if w.Applies(namespace, lbls) && w.Active(t) {
+			return w
+		}
+	}
+	return nil
+}
+
+// checkFreeze refuses actions on releases frozen by the freeze policy, unless
+// override is set, in which case the override is recorded in the audit log.
+func (cfg *Configuration) checkFreeze(action, name, namespace string, lbls map[string]string, override bool) error {
+	if cfg.FreezePolicy == "" {
+		return nil
+	}
+	policy, err := cfg.LoadFreezePolicy(cfg.FreezePolicy)
+	if err != nil {
+		return err
+	}
+	w := policy.Frozen(cfg.Now().Time, namespace, lbls)
+	if w == nil {
+		return nil
+	}
+
+	reason := ""
+	if w.Reason != "" {
+		reason = ": " + w.Reason
+	}
+	if !override {
+		return errcode.New(errcode.FreezeWindowActive, action, name, w.Name, reason)
+	}
+
+	cfg.Log("warning: overriding freeze window %q for %s of %s", w.Name, action, name)
+	if cfg.AuditLog != nil {
+		cfg.recordAuditEntry(audit.Entry{
+			Action:    "override-freeze",
+			Release:   name,
+			Namespace: namespace,
+			Result:    audit.ResultSuccess,
+			Note:      fmt.Sprintf("%s during freeze window %q", action, w.Name),
+		})
+	}
+	return nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) || strings.EqualFold(s, d.String()[:3]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseTimeOfDay parses a time of day such as "18:00" into the time since
+// midnight. "24:00" is the end of the day.
+func parseTimeOfDay(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("%q is not a time of day such as 18:00", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/audit"
+	"helm.sh/helm/v3/pkg/errcode"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestParseFreezePolicy(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		policy string
+		err    string
+	}{
+		{"valid", `
+freezeWindows:
+- name: year-end
+  start: "2023-12-20T00:00:00Z"
+  end: "2024-01-02T00:00:00Z"
+- name: weekend
+  days: [Saturday, sun]
+  from: "18:00"
+  to: "06:00"
+  timeZone: Europe/Berlin
+`, ""},
+		{"no name", "freezeWindows: [{days: [Mon]}]", "freeze window without a name"},
+		{"no period", "freezeWindows: [{name: never}]", `freeze window "never": set start, end or days`},
+		{"end before start", `freezeWindows: [{name: w, start: "2024-01-02T00:00:00Z", end: "2024-01-01T00:00:00Z"}]`, `freeze window "w": end is not after start`},
+		{"invalid day", "freezeWindows: [{name: w, days: [Someday]}]", `freeze window "w": invalid day "Someday"`},
+		{"invalid time", `freezeWindows: [{name: w, days: [Mon], from: "6pm"}]`, `freeze window "w": invalid from: "6pm" is not a time of day such as 18:00`},
+		{"time without days", `freezeWindows: [{name: w, start: "2024-01-01T00:00:00Z", from: "18:00"}]`, `freeze window "w": from, to and timeZone require days`},
+		{"unknown field", "freezeWindows: [{name: w, days: [Mon], namespace: prod}]", `unknown field "namespace"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFreezePolicy([]byte(tt.policy))
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestFreezeWindowActive(t *testing.T) {
+	p, err := ParseFreezePolicy([]byte(`
+freezeWindows:
+- name: year-end
+  start: "2023-12-20T00:00:00Z"
+  end: "2024-01-02T00:00:00Z"
+- name: friday-night
+  days: [Fri]
+  from: "18:00"
+  to: "06:00"
+- name: sunday
+  days: [Sunday]
+  timeZone: America/New_York
+`))
+	require.NoError(t, err)
+	yearEnd, fridayNight, sunday := &p.Windows[0], &p.Windows[1], &p.Windows[2]
+
+	for _, tt := range []struct {
+		window *FreezeWindow
+		time   string
+		active bool
+	}{
+		{yearEnd, "2023-12-19T23:59:59Z", false},
+		{yearEnd, "2023-12-20T00:00:00Z", true},
+		{yearEnd, "2024-01-02T00:00:00Z", false},
+		// 2024-03-01 is a Friday.
+		{fridayNight, "2024-03-01T17:59:00Z", false},
+		{fridayNight, "2024-03-01T18:00:00Z", true},
+		{fridayNight, "2024-03-02T05:59:00Z", true},
+		{fridayNight, "2024-03-02T06:00:00Z", false},
+		{fridayNight, "2024-03-02T18:00:00Z", false},
+		// Sunday in New York starts at 05:00 UTC.
+		{sunday, "2024-03-03T04:59:00Z", false},
+		{sunday, "2024-03-03T05:00:00Z", true},
+		{sunday, "2024-03-04T04:59:00Z", true},
+		{sunday, "2024-03-04T05:00:00Z", false},
+	} {
+		at, err := time.Parse(time.RFC3339, tt.time)
+		require.NoError(t, err)
+		assert.Equal(t, tt.active, tt.window.Active(at), "%s at %s", tt.window.Name, tt.time)
+	}
+}
+
+func TestFreezeWindowApplies(t *testing.T) {
+	p, err := ParseFreezePolicy([]byte(`
+freezeWindows:
+- name: prod-frontend
+  start: "2023-12-20T00:00:00Z"
+  namespaces: ["prod-*", "payments"]
+  selector:
+    matchLabels:
+      tier: frontend
+`))
+	require.NoError(t, err)
+	w := &p.Windows[0]
+
+	assert.True(t, w.Applies("prod-eu", map[string]string{"tier": "frontend"}))
+	assert.True(t, w.Applies("payments", map[string]string{"tier": "frontend", "team": "a"}))
+	assert.False(t, w.Applies("staging", map[string]string{"tier": "frontend"}))
+	assert.False(t, w.Applies("prod-eu", map[string]string{"tier": "backend"}))
+	assert.False(t, w.Applies("prod-eu", nil))
+}
+
+func TestUpgradeRelease_Freeze(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	upAction.cfg.FreezePolicy = filepath.Join(t.TempDir(), "freeze-policy.yaml")
+	req.NoError(os.WriteFile(upAction.cfg.FreezePolicy, []byte(`
+freezeWindows:
+- name: always
+  start: "2000-01-01T00:00:00Z"
+  reason: migration in progress
+`), 0644))
+
+	rel := releaseStub()
+	rel.Name = "frozen"
+	rel.Info.Status = release.StatusDeployed
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	is.EqualError(err, `upgrade of release frozen is not allowed during freeze window "always": migration in progress (use --override-freeze to proceed anyway)`)
+	is.Equal(errcode.FreezeWindowActive, errcode.Of(err))
+
+	upAction.cfg.AuditLog = audit.NewFile(filepath.Join(t.TempDir(), "audit.log"))
+	upAction.OverrideFreeze = true
+	res, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(release.StatusDeployed, res.Info.Status)
+	entries, err := upAction.cfg.AuditLog.List()
+	req.NoError(err)
+	req.NotEmpty(entries)
+	is.Equal("override-freeze", entries[0].Action)
+	is.Equal(`upgrade during freeze window "always"`, entries[0].Note)
+}
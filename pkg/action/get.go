@@ -17,6 +17,9 @@ limitations under the License.
 package action
 
 import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/release"
 )
 
@@ -28,6 +31,8 @@ type Get struct {
 
 	// Initializing Version to 0 will get the latest revision of the release.
 	Version int
+	// SubNotes renders the notes of the subcharts as well in RenderNotes.
+	SubNotes bool
 }
 
 // NewGet creates a new Get object with the given configuration.
@@ -45,3 +50,33 @@ func (g *Get) Run(name string) (*release.Release, error) {
 
 	return g.cfg.releaseContent(name, g.Version)
 }
+
+// RenderNotes renders the notes of the release again from its stored chart and
+// values, rather than returning the notes rendered when it was deployed.
+func (g *Get) RenderNotes(rel *release.Release) (string, error) {
+	if rel.Chart == nil {
+		return "", errors.Errorf("release %s revision %d has no chart to render notes from", rel.Name, rel.Version)
+	}
+
+	options := chartutil.ReleaseOptions{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+		IsInstall: rel.Version == 1,
+		IsUpgrade: rel.Version > 1,
+	}
+	caps, err := g.cfg.getCapabilities()
+	if err != nil {
+		return "", err
+	}
+	valuesToRender, err := chartutil.ToRenderValues(rel.Chart, rel.Config, options, caps)
+	if err != nil {
+		return "", err
+	}
+
+	_, _, notes, err := g.cfg.renderResources(rel.Chart, valuesToRender, "", "", g.SubNotes, false, crdsTemplated, nil, true, false, false)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to render notes of release %s revision %d", rel.Name, rel.Version)
+	}
+	return notes, nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/version"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// ReleaseFormatBundle is the format of a gzipped tar archive holding
+// everything needed to reproduce a release revision.
+const ReleaseFormatBundle = "bundle"
+
+// Files of a release bundle.
+const (
+	BundleMetadataFile       = "metadata.json"
+	BundleChartDir           = "chart"
+	BundleValuesFile         = "values.yaml"
+	BundleComputedValuesFile = "computed-values.yaml"
+	BundleManifestFile       = "manifest.yaml"
+	BundleHooksFile          = "hooks.json"
+	BundleNotesFile          = "notes.txt"
+)
+
+// BundleMetadata is the metadata of a release bundle.
+type BundleMetadata struct {
+	Metadata
+	Description   string            `json:"description"`
+	FirstDeployed string            `json:"firstDeployed"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	// HelmVersion is the version of Helm that exported the bundle.
+	HelmVersion string `json:"helmVersion"`
+}
+
+// GetRelease is the action for exporting a given release.
+//
+// It provides the implementation of 'helm get release'.
+type GetRelease struct {
+	cfg *Configuration
+
+	Version int
+	Format  string
+}
+
+// NewGetRelease creates a new GetRelease object with the given configuration.
+func NewGetRelease(cfg *Configuration) *GetRelease {
+	return &GetRelease{
+		cfg:    cfg,
+		Format: ReleaseFormatBundle,
+	}
+}
+
+// Run executes 'helm get release' against the given release and writes the
+// export to out.
+//
+// A bundle contains the chart reconstructed from the release, the user
+// supplied and computed values, the manifest, the hooks including the results
+// of their last run, the notes and the metadata of the revision. Subcharts are
+// not stored with a release, so their output is only captured by the manifest
+// and the computed values.
+func (g *GetRelease) Run(name string, out io.Writer) (*release.Release, error) {
+	if g.Format != ReleaseFormatBundle {
+		return nil, errors.Errorf("unsupported release format %q", g.Format)
+	}
+	if err := g.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	rel, err := g.cfg.releaseContent(name, g.Version)
+	if err != nil {
+		return nil, err
+	}
+	return rel, writeReleaseBundle(out, rel)
+}
+
+func writeReleaseBundle(out io.Writer, rel *release.Release) error {
+	files, err := releaseBundleFiles(rel)
+	if err != nil {
+		return err
+	}
+
+	zipper := gzip.NewWriter(out)
+	twriter := tar.NewWriter(zipper)
+	modTime := time.Now()
+	if !rel.Info.LastDeployed.IsZero() {
+		modTime = rel.Info.LastDeployed.Time
+	}
+	for _, f := range files {
+		h := &tar.Header{
+			Name:    f.Name,
+			Mode:    0644,
+			Size:    int64(len(f.Data)),
+			ModTime: modTime,
+		}
+		if err := twriter.WriteHeader(h); err != nil {
+			return err
+		}
+		if _, err := twriter.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	if err := twriter.Close(); err != nil {
+		return err
+	}
+	return zipper.Close()
+}
+
+func releaseBundleFiles(rel *release.Release) ([]*chart.File, error) {
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return nil, errors.Errorf("release %q has no chart", rel.Name)
+	}
+
+	metadata, err := json.MarshalIndent(BundleMetadata{
+		Metadata: Metadata{
+			Name:       rel.Name,
+			Chart:      rel.Chart.Metadata.Name,
+			Version:    rel.Chart.Metadata.Version,
+			AppVersion: rel.Chart.Metadata.AppVersion,
+			Namespace:  rel.Namespace,
+			Revision:   rel.Version,
+			Status:     rel.Info.Status.String(),
+			DeployedAt: rel.Info.LastDeployed.Format(time.RFC3339),
+		},
+		Description:   rel.Info.Description,
+		FirstDeployed: rel.Info.FirstDeployed.Format(time.RFC3339),
+		Labels:        rel.Labels,
+		HelmVersion:   version.GetVersion(),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var archive bytes.Buffer
+	if err := chartutil.SaveArchive(storedChart(rel.Chart), &archive); err != nil {
+		return nil, errors.Wrap(err, "unable to reconstruct the chart of the release")
+	}
+
+	values, err := yaml.Marshal(rel.Config)
+	if err != nil {
+		return nil, err
+	}
+	computed, err := chartutil.CoalesceValues(rel.Chart, rel.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to compute the values of the release")
+	}
+	computedValues, err := yaml.Marshal(computed)
+	if err != nil {
+		return nil, err
+	}
+	hooks, err := json.MarshalIndent(rel.Hooks, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []*chart.File{
+		{Name: BundleMetadataFile, Data: metadata},
+		{Name: fmt.Sprintf("%s/%s-%s.tgz", BundleChartDir, rel.Chart.Name(), rel.Chart.Metadata.Version), Data: archive.Bytes()},
+		{Name: BundleValuesFile, Data: values},
+		{Name: BundleComputedValuesFile, Data: computedValues},
+		{Name: BundleManifestFile, Data: []byte(rel.Manifest)},
+		{Name: BundleHooksFile, Data: hooks},
+	}
+	if rel.Info.Notes != "" {
+		files = append(files, &chart.File{Name: BundleNotesFile, Data: []byte(rel.Info.Notes)})
+	}
+	return files, nil
+}
+
+// storedChart returns a copy of a chart loaded from a release that can be
+// archived again. The raw files of a chart are not stored with a release, so
+// its values file is recreated from the default values.
+func storedChart(c *chart.Chart) *chart.Chart {
+	for _, f := range c.Raw {
+		if f.Name == chartutil.ValuesfileName {
+			return c
+		}
+	}
+	copied := *c
+	if data, err := yaml.Marshal(c.Values); err == nil && len(c.Values) > 0 {
+		copied.Raw = append(append([]*chart.File{}, c.Raw...), &chart.File{Name: chartutil.ValuesfileName, Data: data})
+	}
+	return &copied
+}
@@ -0,0 +1,108 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestGetReleaseBundle(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	rel := releaseStub()
+	rel.Chart = buildChart(withSampleValues(), withSampleTemplates())
+	rel.Info.Notes = "some notes"
+	if err := cfg.Releases.Create(rel); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := NewGetRelease(cfg).Run(rel.Name, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readTarGz(t, &buf)
+	for _, name := range []string{
+		BundleMetadataFile,
+		"chart/hello-0.1.0.tgz",
+		BundleValuesFile,
+		BundleComputedValuesFile,
+		BundleManifestFile,
+		BundleHooksFile,
+		BundleNotesFile,
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected bundle to contain %s", name)
+		}
+	}
+
+	chrt, err := loader.LoadArchive(bytes.NewReader(files["chart/hello-0.1.0.tgz"]))
+	if err != nil {
+		t.Fatalf("unable to load the chart of the bundle: %s", err)
+	}
+	if len(chrt.Templates) != len(rel.Chart.Templates) {
+		t.Errorf("expected %d templates, got %d", len(rel.Chart.Templates), len(chrt.Templates))
+	}
+	if chrt.Values["someKey"] != "someValue" {
+		t.Errorf("expected the chart values to be restored, got %v", chrt.Values)
+	}
+	computed, err := chartutil.ReadValues(files[BundleComputedValuesFile])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if computed["name"] != "value" || computed["someKey"] != "someValue" {
+		t.Errorf("expected computed values to merge user and chart values, got %v", computed)
+	}
+}
+
+func TestGetReleaseUnsupportedFormat(t *testing.T) {
+	client := NewGetRelease(actionConfigFixture(t))
+	client.Format = "zip"
+	if _, err := client.Run("angry-panda", io.Discard); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func readTarGz(t *testing.T, r io.Reader) map[string][]byte {
+	t.Helper()
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(zr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[h.Name] = data
+	}
+}
@@ -18,6 +18,8 @@ package action
 
 import (
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/redact"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 // GetValues is the action for checking a given release's values.
@@ -28,6 +30,9 @@ type GetValues struct {
 
 	Version   int
 	AllValues bool
+	// Reveal shows the values the chart marks as sensitive instead of
+	// masking them, see chartutil.SensitiveValues.
+	Reveal bool
 }
 
 // NewGetValues creates a new GetValues object with the given configuration.
@@ -48,13 +53,29 @@ func (g *GetValues) Run(name string) (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	// If the user wants all values, compute the values and return.
+	vals := rel.Config
+	// If the user wants all values, compute the values.
 	if g.AllValues {
-		cfg, err := chartutil.CoalesceValues(rel.Chart, rel.Config)
+		computed, err := ComputedValues(rel)
 		if err != nil {
 			return nil, err
 		}
-		return cfg, nil
+		vals = computed
 	}
-	return rel.Config, nil
+	if g.Reveal {
+		return vals, nil
+	}
+	paths, err := chartutil.SensitiveValues(rel.Chart)
+	if err != nil {
+		return nil, err
+	}
+	return redact.Paths(vals, paths), nil
+}
+
+// ComputedValues returns the values the templates of a release are rendered
+// with: the values of its chart and of the enabled subcharts, coalesced with
+// the values supplied for the release. For the releases of a dry-run install
+// or upgrade, this includes the values reused from the previous release.
+func ComputedValues(rel *release.Release) (chartutil.Values, error) {
+	return chartutil.CoalesceValues(rel.Chart, rel.Config)
 }
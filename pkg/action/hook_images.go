@@ -0,0 +1,268 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// HookImagePolicy requires the container images of hooks to be signed before
+// the hooks are run: hooks run arbitrary code in the cluster. Each image must
+// match a rule, e.g.:
+//
+//	rules:
+//	- images: registry.example.com/*
+//	  sigstore:
+//	  - subject: https://github.com/example/hooks/.github/workflows/release.yaml@refs/heads/main
+//	    issuer: https://token.actions.githubusercontent.com
+//
+// Hooks with images that match no rule, or that are not signed by one of the
+// identities of their rule, are refused. Tags are mutable, so each image is
+// resolved to the digest of its manifest, and the digest that is verified is
+// the one the hook runs.
+type HookImagePolicy struct {
+	Rules []HookImageRule `json:"rules"`
+}
+
+// HookImageRule lists the identities allowed to sign images.
+type HookImageRule struct {
+	// Images is a pattern of the references of the images of the rule, in
+	// which "*" matches any characters, including "/".
+	Images string `json:"images"`
+	// Sigstore lists the identities allowed to sign the images with
+	// Sigstore.
+	Sigstore []downloader.SigstoreIdentity `json:"sigstore"`
+}
+
+// LoadHookImagePolicy reads the hook image policy of the given YAML file. A
+// missing file defines no policy, so that the images of hooks are not
+// verified.
+func LoadHookImagePolicy(path string) (*HookImagePolicy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseHookImagePolicy(data)
+	return p, errors.Wrapf(err, "invalid hook image policy %s", path)
+}
+
+// ParseHookImagePolicy parses and validates a hook image policy.
+func ParseHookImagePolicy(data []byte) (*HookImagePolicy, error) {
+	var p HookImagePolicy
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, err
+	}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Images == "" {
+			return nil, errors.Errorf("rule %d has no images", i)
+		}
+		if len(r.Sigstore) == 0 {
+			return nil, errors.Errorf("the rule of images %s has no Sigstore identities", r.Images)
+		}
+		for _, id := range r.Sigstore {
+			if id.Subject == "" || id.Issuer == "" {
+				return nil, errors.Errorf("the Sigstore identities of images %s need a subject and an issuer", r.Images)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// Match returns the first rule matching the image, or nil.
+func (p *HookImagePolicy) Match(image string) *HookImageRule {
+	for i, r := range p.Rules {
+		pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(r.Images), `\*`, ".*") + "$"
+		if ok, _ := regexp.MatchString(pattern, image); ok {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// ImageResolver resolves the references of images to the digests of their
+// manifests.
+type ImageResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// verifyHookImages verifies the signatures of the images of the resources of
+// a hook against the hook image policy of the configuration, if any, and pins
+// the images of the resources to the digests that were verified.
+func (cfg *Configuration) verifyHookImages(resources kube.ResourceList) error {
+	policy, err := cfg.hookImagePolicy()
+	if policy == nil || err != nil {
+		return err
+	}
+	verifier := cfg.ImageVerifier
+	if verifier == nil {
+		verifier = downloader.CosignVerifier{}
+	}
+	resolver, err := cfg.imageResolver()
+	if err != nil {
+		return err
+	}
+	images, err := containerImages(resources)
+	if err != nil {
+		return err
+	}
+	pinned := make(map[string]string, len(images))
+	for _, image := range images {
+		rule := policy.Match(image)
+		if rule == nil {
+			return errors.Errorf("image %s matches no rule of the hook image policy", image)
+		}
+		ref, err := pinImage(resolver, image)
+		if err != nil {
+			return errors.Wrapf(err, "image %s", image)
+		}
+		if err := verifier.VerifyImage(ref, rule.Sigstore); err != nil {
+			return errors.Wrapf(err, "image %s", image)
+		}
+		pinned[image] = ref
+	}
+	return setImages(resources, pinned)
+}
+
+func (cfg *Configuration) imageResolver() (ImageResolver, error) {
+	if cfg.ImageResolver != nil {
+		return cfg.ImageResolver, nil
+	}
+	if cfg.RegistryClient != nil {
+		return cfg.RegistryClient, nil
+	}
+	return registry.NewClient()
+}
+
+// pinImage returns the reference of the image by the digest of its manifest,
+// resolving its tag unless the image already names a digest.
+func pinImage(resolver ImageResolver, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid image reference")
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		return reference.FamiliarName(named) + "@" + digested.Digest().String(), nil
+	}
+	digest, err := resolver.Resolve(reference.TagNameOnly(named).String())
+	if err != nil {
+		return "", errors.Wrap(err, "unable to resolve the digest")
+	}
+	return reference.FamiliarName(named) + "@" + digest, nil
+}
+
+// setImages replaces the images of the containers of the pod specs of the
+// resources with the images they map to.
+func setImages(resources kube.ResourceList, images map[string]string) error {
+	for _, r := range resources {
+		err := editObject(r.Object, func(content map[string]interface{}) {
+			visitContainers(content, func(c map[string]interface{}) {
+				if image, ok := c["image"].(string); ok && images[image] != "" {
+					c["image"] = images[image]
+				}
+			})
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to pin the images of %s", r.Name)
+		}
+	}
+	return nil
+}
+
+// containerImages returns the sorted images of the containers, init
+// containers and ephemeral containers of the pod specs of the resources,
+// wherever they are nested, such as in the template of a Job.
+func containerImages(resources kube.ResourceList) ([]string, error) {
+	seen := map[string]bool{}
+	for _, r := range resources {
+		var content map[string]interface{}
+		if u, ok := r.Object.(*unstructured.Unstructured); ok {
+			content = u.Object
+		} else {
+			var err error
+			if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(r.Object); err != nil {
+				return nil, errors.Wrapf(err, "unable to read the images of %s", r.Name)
+			}
+		}
+		collectImages(content, seen)
+	}
+	images := make([]string, 0, len(seen))
+	for image := range seen {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+func collectImages(obj map[string]interface{}, images map[string]bool) {
+	visitContainers(obj, func(c map[string]interface{}) {
+		if image, ok := c["image"].(string); ok && image != "" {
+			images[image] = true
+		}
+	})
+}
+
+// visitContainers calls fn with the containers, init containers and
+// ephemeral containers of the pod specs of a decoded object.
+func visitContainers(obj map[string]interface{}, fn func(c map[string]interface{})) {
+	visitPodSpecs(obj, func(spec map[string]interface{}) {
+		for _, key := range []string{"containers", "initContainers", "ephemeralContainers"} {
+			containers, _ := spec[key].([]interface{})
+			for _, c := range containers {
+				if c, ok := c.(map[string]interface{}); ok {
+					fn(c)
+				}
+			}
+		}
+	})
+}
+
+// visitPodSpecs calls fn with the pod specs of a decoded object, wherever they
+// are nested, such as in the template of a Job. A pod spec is an object with
+// a list of containers.
+func visitPodSpecs(v interface{}, fn func(spec map[string]interface{})) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if _, ok := v["containers"].([]interface{}); ok {
+			fn(v)
+			return
+		}
+		for _, value := range v {
+			visitPodSpecs(value, fn)
+		}
+	case []interface{}:
+		for _, item := range v {
+			visitPodSpecs(item, fn)
+		}
+	}
+}
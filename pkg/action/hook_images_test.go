@@ -0,0 +1,159 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/downloader"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+type fakeImageVerifier struct {
+	signed   map[string]bool
+	verified []string
+}
+
+func (v *fakeImageVerifier) VerifyBlob(_, _ string, _ []downloader.SigstoreIdentity) error {
+	return errors.New("not implemented")
+}
+
+func (v *fakeImageVerifier) VerifyImage(ref string, _ []downloader.SigstoreIdentity) error {
+	v.verified = append(v.verified, ref)
+	if !v.signed[ref] {
+		return errors.New("no signature found")
+	}
+	return nil
+}
+
+type fakeImageResolver map[string]string
+
+func (r fakeImageResolver) Resolve(ref string) (string, error) {
+	if digest, ok := r[ref]; ok {
+		return digest, nil
+	}
+	return "", errors.New("not found")
+}
+
+const (
+	migrateDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	waitDigest    = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func TestInstallRelease_HookImagePolicy(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	policy, err := ParseHookImagePolicy([]byte(`rules:
+- images: registry.example.com/*
+  sigstore:
+  - subject: release@example.com
+    issuer: https://accounts.example.com
+`))
+	req.NoError(err)
+
+	hookChart := func(images ...string) *chart.Chart {
+		containers := ""
+		for _, image := range images {
+			containers += "\n        - name: c\n          image: " + image
+		}
+		chrt := buildChart()
+		chrt.Templates = []*chart.File{{
+			Name: "templates/job.yaml",
+			Data: []byte(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+spec:
+  template:
+    spec:
+      containers:` + containers),
+		}}
+		return chrt
+	}
+
+	for _, tt := range []struct {
+		name   string
+		images []string
+		pinned []string
+		err    string
+	}{{
+		name:   "signed images",
+		images: []string{"registry.example.com/hooks/migrate:1", "registry.example.com/hooks/wait:1"},
+		pinned: []string{"registry.example.com/hooks/migrate@" + migrateDigest, "registry.example.com/hooks/wait@" + waitDigest},
+	}, {
+		name:   "image by digest",
+		images: []string{"registry.example.com/hooks/migrate:1@" + migrateDigest},
+		pinned: []string{"registry.example.com/hooks/migrate@" + migrateDigest},
+	}, {
+		name:   "image matching no rule",
+		images: []string{"registry.example.com/hooks/migrate:1", "busybox:1"},
+		err:    "refusing to run pre-install hook hello/templates/job.yaml: image busybox:1 matches no rule of the hook image policy",
+	}, {
+		name:   "unsigned image",
+		images: []string{"registry.example.com/hooks/unsigned:1"},
+		err:    "refusing to run pre-install hook hello/templates/job.yaml: image registry.example.com/hooks/unsigned:1: no signature found",
+	}, {
+		name:   "unresolved image",
+		images: []string{"registry.example.com/hooks/missing:1"},
+		err:    "refusing to run pre-install hook hello/templates/job.yaml: image registry.example.com/hooks/missing:1: unable to resolve the digest: not found",
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			instAction := installAction(t)
+			failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+			failer.ParseManifests = true
+			// Only the digests are signed, not the tags.
+			verifier := &fakeImageVerifier{signed: map[string]bool{
+				"registry.example.com/hooks/migrate@" + migrateDigest: true,
+				"registry.example.com/hooks/wait@" + waitDigest:       true,
+			}}
+			instAction.cfg.HookImagePolicy = policy
+			instAction.cfg.ImageVerifier = verifier
+			instAction.cfg.ImageResolver = fakeImageResolver{
+				"registry.example.com/hooks/migrate:1":  migrateDigest,
+				"registry.example.com/hooks/wait:1":     waitDigest,
+				"registry.example.com/hooks/unsigned:1": "sha256:3333333333333333333333333333333333333333333333333333333333333333",
+			}
+
+			_, err := instAction.Run(hookChart(tt.images...), map[string]interface{}{})
+			if tt.err == "" {
+				req.NoError(err)
+				is.Equal(tt.pinned, verifier.verified)
+				// The hook runs the digests that were verified.
+				created := failer.OperationsOf(kubefake.VerbCreate)
+				req.Len(created, 1)
+				images, err := containerImages(created[0].Resources)
+				req.NoError(err)
+				is.Equal(tt.pinned, images)
+				return
+			}
+			is.ErrorContains(err, tt.err)
+			// The hook is not created.
+			is.Empty(failer.OperationsOf(kubefake.VerbCreate))
+		})
+	}
+
+	_, err = ParseHookImagePolicy([]byte("rules:\n- images: '*'\n"))
+	is.EqualError(err, "the rule of images * has no Sigstore identities")
+}
@@ -0,0 +1,251 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// HookLogs returns the logs of the last execution of a hook of a release
+// running pods, such as a Job, so they can be kept beyond the lifetime of its
+// pods.
+//
+// The logs of all containers of all pods of the hook are read in turn. If
+// there is more than one container, the logs of each are preceded by a
+// "==> pod/container <==" line. The caller must close the returned reader.
+func (cfg *Configuration) HookLogs(rel *release.Release, hookName string) (io.ReadCloser, error) {
+	var hook *release.Hook
+	for _, h := range rel.Hooks {
+		if h.Name == hookName {
+			hook = h
+			break
+		}
+	}
+	if hook == nil {
+		return nil, errors.Errorf("release %s has no hook %s", rel.Name, hookName)
+	}
+
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get kubernetes client to fetch hook logs")
+	}
+	return hookLogs(client, rel.Namespace, hook)
+}
+
+func hookLogs(client kubernetes.Interface, namespace string, h *release.Hook) (io.ReadCloser, error) {
+	if !hookPodKinds[h.Kind] {
+		return nil, errors.Errorf("hook %s is a %s, which has no logs", h.Name, h.Kind)
+	}
+	pods, err := hookPods(client, namespace, h)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get the pods of hook %s", h.Name)
+	}
+	if len(pods) == 0 {
+		return nil, errors.Errorf("no pods of hook %s found", h.Name)
+	}
+
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	r := &hookLogReader{client: client, namespace: namespace}
+	for _, pod := range pods {
+		for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			r.containers = append(r.containers, [2]string{pod.Name, c.Name})
+		}
+	}
+	r.headers = len(r.containers) > 1
+	return r, nil
+}
+
+// hookLogReader reads the logs of a list of containers one after the other,
+// only requesting the logs of a container when it gets to it.
+type hookLogReader struct {
+	client    kubernetes.Interface
+	namespace string
+	// containers are the pod and container names of the logs still to read.
+	containers [][2]string
+	headers    bool
+	current    io.Reader
+	closer     io.Closer
+}
+
+func (r *hookLogReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.containers) == 0 {
+				return 0, io.EOF
+			}
+			pod, container := r.containers[0][0], r.containers[0][1]
+			r.containers = r.containers[1:]
+			req := r.client.CoreV1().Pods(r.namespace).GetLogs(pod, &corev1.PodLogOptions{Container: container})
+			stream, err := req.Stream(context.Background())
+			if err != nil {
+				return 0, errors.Wrapf(err, "unable to get logs of container %s of pod %s", container, pod)
+			}
+			r.current, r.closer = stream, stream
+			if r.headers {
+				r.current = io.MultiReader(strings.NewReader(fmt.Sprintf("==> %s/%s <==\n", pod, container)), stream)
+			}
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			err = r.Close()
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close closes the logs of the container being read.
+func (r *hookLogReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	err := r.closer.Close()
+	r.current, r.closer = nil, nil
+	return err
+}
+
+// hookLogPollInterval is how often the pods of a running hook are checked for
+// containers whose logs to follow, and hookLogGrace how long the logs of a
+// completed hook are still read before they are cut off.
+var (
+	hookLogPollInterval = time.Second
+	hookLogGrace        = 2 * time.Second
+)
+
+// followHookLogs streams the logs of the containers of a hook running pods to
+// HookOutputFunc while the hook runs, until the returned function is called
+// once the hook completed.
+func (cfg *Configuration) followHookLogs(ctx context.Context, namespace string, h *release.Hook) (stop func()) {
+	if cfg.HookOutputFunc == nil || !hookPodKinds[h.Kind] {
+		return func() {}
+	}
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		cfg.Log("warning: unable to follow the logs of hook %s: %s", h.Name, err)
+		return func() {}
+	}
+	return followHookLogs(ctx, client, namespace, h, cfg.HookOutputFunc, cfg.Log)
+}
+
+func followHookLogs(ctx context.Context, client kubernetes.Interface, namespace string, h *release.Hook, output func(namespace, pod, container string) io.Writer, log func(string, ...interface{})) (stop func()) {
+	streamCtx, cancelStreams := context.WithCancel(ctx)
+	pollCtx, cancelPoll := context.WithCancel(streamCtx)
+	var streams sync.WaitGroup
+	followed := map[[2]string]bool{}
+
+	// poll starts following the containers of the hook that have started.
+	poll := func() {
+		pods, err := hookPods(client, namespace, h)
+		if err != nil {
+			log("warning: unable to get the pods of hook %s to follow their logs: %s", h.Name, err)
+			return
+		}
+		for _, pod := range pods {
+			for _, c := range startedContainers(pod) {
+				key := [2]string{pod.Name, c}
+				if followed[key] {
+					continue
+				}
+				followed[key] = true
+				streams.Add(1)
+				go func(pod, container string) {
+					defer streams.Done()
+					if err := streamContainerLogs(streamCtx, client, namespace, pod, container, output(namespace, pod, container)); err != nil && streamCtx.Err() == nil {
+						log("warning: unable to follow the logs of container %s of pod %s: %s", container, pod, err)
+					}
+				}(pod.Name, c)
+			}
+		}
+	}
+
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		ticker := time.NewTicker(hookLogPollInterval)
+		defer ticker.Stop()
+		for {
+			poll()
+			select {
+			case <-pollCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancelPoll()
+		<-polled
+		// Containers that ran between the last poll and the completion of
+		// the hook still have their logs read.
+		if streamCtx.Err() == nil {
+			poll()
+		}
+		done := make(chan struct{})
+		go func() {
+			streams.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(hookLogGrace):
+		}
+		cancelStreams()
+		<-done
+	}
+}
+
+// startedContainers returns the names of the init and regular containers of
+// a pod that are running or have terminated.
+func startedContainers(pod corev1.Pod) []string {
+	var names []string
+	for _, s := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if s.State.Running != nil || s.State.Terminated != nil {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// streamContainerLogs copies the logs of a container to out as they are
+// written, until the container terminates or ctx is done.
+func streamContainerLogs(ctx context.Context, client kubernetes.Interface, namespace, pod, container string, out io.Writer) error {
+	req := client.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{Container: container, Follow: true})
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(out, stream)
+	return err
+}
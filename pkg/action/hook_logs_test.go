@@ -0,0 +1,109 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestHookLogs(t *testing.T) {
+	pod := func(name string, labels map[string]string, containers ...string) *corev1.Pod {
+		p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+		for _, c := range containers {
+			p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Name: c})
+		}
+		return p
+	}
+	client := fake.NewSimpleClientset(
+		pod("check", nil, "main"),
+		pod("migrate-b", map[string]string{"job-name": "migrate"}, "main"),
+		pod("migrate-a", map[string]string{"job-name": "migrate"}, "main", "sidecar"),
+	)
+
+	readAll := func(h *release.Hook) string {
+		t.Helper()
+		r, err := hookLogs(client, "default", h)
+		require.NoError(t, err)
+		defer r.Close()
+		logs, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(logs)
+	}
+
+	// The fake client returns "fake logs" for any container.
+	assert.Equal(t, "fake logs", readAll(&release.Hook{Name: "check", Kind: "Pod"}))
+	assert.Equal(t, "==> migrate-a/main <==\nfake logs==> migrate-a/sidecar <==\nfake logs==> migrate-b/main <==\nfake logs",
+		readAll(&release.Hook{Name: "migrate", Kind: "Job"}))
+
+	_, err := hookLogs(client, "default", &release.Hook{Name: "seed", Kind: "Job"})
+	assert.EqualError(t, err, "no pods of hook seed found")
+	_, err = hookLogs(client, "default", &release.Hook{Name: "config", Kind: "ConfigMap"})
+	assert.EqualError(t, err, "hook config is a ConfigMap, which has no logs")
+}
+
+func TestHookLogsUnknownHook(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	_, err := cfg.HookLogs(releaseStub(), "missing")
+	assert.EqualError(t, err, "release angry-panda has no hook missing")
+}
+
+func TestFollowHookLogs(t *testing.T) {
+	defer func(interval time.Duration) { hookLogPollInterval = interval }(hookLogPollInterval)
+	hookLogPollInterval = 10 * time.Millisecond
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "migrate-a", Namespace: "default", Labels: map[string]string{"job-name": "migrate"}}}
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{Name: "init", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{Name: "main", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+		{Name: "sidecar", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}},
+	}
+	client := fake.NewSimpleClientset(pod)
+
+	var mu sync.Mutex
+	logs := map[string]*bytes.Buffer{}
+	output := func(namespace, pod, container string) io.Writer {
+		mu.Lock()
+		defer mu.Unlock()
+		b := &bytes.Buffer{}
+		logs[namespace+"/"+pod+"/"+container] = b
+		return b
+	}
+
+	stop := followHookLogs(context.Background(), client, "default", &release.Hook{Name: "migrate", Kind: "Job"}, output, t.Logf)
+	stop()
+
+	// The fake client returns "fake logs" for any container. Containers that
+	// have not started are not followed.
+	require.Len(t, logs, 2)
+	assert.Equal(t, "fake logs", logs["default/migrate-a/init"].String())
+	assert.Equal(t, "fake logs", logs["default/migrate-a/main"].String())
+}
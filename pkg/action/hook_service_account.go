@@ -0,0 +1,143 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// createHookServiceAccount creates the ServiceAccount the pods of the hooks of
+// a release run as, bound to a Role granting the hook permissions declared by
+// the chart and its dependencies. It returns the name of the ServiceAccount
+// and a function deleting it again along with its Role.
+func (cfg *Configuration) createHookServiceAccount(rl *release.Release) (string, func(), error) {
+	name := rl.Name + "-hooks"
+	manifest, err := hookServiceAccountManifest(name, rl.Namespace, hookPermissions(rl.Chart))
+	if err != nil {
+		return "", nil, err
+	}
+	resources, err := cfg.KubeClient.Build(strings.NewReader(manifest), false)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "unable to build the hook service account")
+	}
+	// A ServiceAccount left behind by an interrupted operation is replaced.
+	_, _ = cfg.KubeClient.Delete(resources)
+	if _, err := cfg.KubeClient.Create(resources); err != nil {
+		return "", nil, errors.Wrapf(err, "unable to create the hook service account %s", name)
+	}
+	cfg.Log("created the hook service account %s", name)
+	cleanup := func() {
+		if _, errs := cfg.KubeClient.Delete(resources); len(errs) > 0 {
+			cfg.Log("warning: unable to delete the hook service account %s: %s", name, joinErrors(errs))
+		}
+	}
+	return name, cleanup, nil
+}
+
+// hookPermissions returns the rules granting the hook permissions of a chart
+// and its dependencies.
+func hookPermissions(ch *chart.Chart) []rbacv1.PolicyRule {
+	if ch == nil || ch.Metadata == nil {
+		return nil
+	}
+	var rules []rbacv1.PolicyRule
+	for _, p := range ch.Metadata.HookPermissions {
+		apiGroups := p.APIGroups
+		if len(apiGroups) == 0 {
+			apiGroups = []string{""}
+		}
+		rules = append(rules, rbacv1.PolicyRule{APIGroups: apiGroups, Resources: p.Resources, Verbs: p.Verbs})
+	}
+	for _, dep := range ch.Dependencies() {
+		rules = append(rules, hookPermissions(dep)...)
+	}
+	return rules
+}
+
+// hookServiceAccountManifest returns the manifest of the hook ServiceAccount,
+// and of its Role and RoleBinding if there are rules.
+func hookServiceAccountManifest(name, namespace string, rules []rbacv1.PolicyRule) (string, error) {
+	meta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{"app.kubernetes.io/managed-by": "Helm"},
+	}
+	objs := []interface{}{&corev1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta: meta,
+	}}
+	if len(rules) > 0 {
+		objs = append(objs, &rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+			ObjectMeta: meta,
+			Rules:      rules,
+		}, &rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+			ObjectMeta: meta,
+			RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: name},
+			Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: name, Namespace: namespace}},
+		})
+	}
+	var docs []string
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(data))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// setServiceAccount makes the pods of the resources run as the
+// ServiceAccount, unless they name one themselves.
+func setServiceAccount(resources kube.ResourceList, name string) error {
+	for _, r := range resources {
+		err := editObject(r.Object, func(content map[string]interface{}) {
+			visitPodSpecs(content, func(spec map[string]interface{}) {
+				if spec["serviceAccountName"] == nil && spec["serviceAccount"] == nil {
+					spec["serviceAccountName"] = name
+				}
+			})
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to set the service account of %s", r.Name)
+		}
+	}
+	return nil
+}
+
+// runInCluster reports whether any of the hooks runs in the cluster, rather
+// than being an external hook.
+func runInCluster(hooks []*release.Hook) bool {
+	for _, h := range hooks {
+		if h.Kind != ExternalHookKind {
+			return true
+		}
+	}
+	return false
+}
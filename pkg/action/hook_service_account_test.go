@@ -0,0 +1,93 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"helm.sh/helm/v3/pkg/chart"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+func TestInstallRelease_HookServiceAccount(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	job := func(name, serviceAccount string) *chart.File {
+		sa := ""
+		if serviceAccount != "" {
+			sa = "\n      serviceAccountName: " + serviceAccount
+		}
+		return &chart.File{
+			Name: "templates/" + name + ".yaml",
+			Data: []byte(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ` + name + `
+  annotations:
+    helm.sh/hook: pre-install
+spec:
+  template:
+    spec:` + sa + `
+      containers:
+      - name: c
+        image: busybox`),
+		}
+	}
+	chrt := buildChart()
+	chrt.Metadata.HookPermissions = []*chart.HookPermission{{Resources: []string{"configmaps"}, Verbs: []string{"get", "create"}}}
+	chrt.Templates = []*chart.File{job("migrate", ""), job("own", "custom")}
+
+	instAction := installAction(t)
+	instAction.ReleaseName = "sa"
+	instAction.cfg.HookServiceAccount = true
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	_, err := instAction.Run(chrt, map[string]interface{}{})
+	req.NoError(err)
+
+	// The ServiceAccount, its Role and RoleBinding are created before the
+	// hooks, and deleted after them.
+	creates := failer.OperationsOf(kubefake.VerbCreate)
+	req.Len(creates, 3)
+	var kinds []string
+	for _, r := range creates[0].Resources {
+		kinds = append(kinds, r.Object.GetObjectKind().GroupVersionKind().Kind+"/"+r.Name)
+	}
+	is.Equal([]string{"ServiceAccount/sa-hooks", "Role/sa-hooks", "RoleBinding/sa-hooks"}, kinds)
+	rules, _, _ := unstructured.NestedSlice(creates[0].Resources[1].Object.(*unstructured.Unstructured).Object, "rules")
+	is.Equal([]interface{}{map[string]interface{}{
+		"apiGroups": []interface{}{""},
+		"resources": []interface{}{"configmaps"},
+		"verbs":     []interface{}{"get", "create"},
+	}}, rules)
+
+	serviceAccount := func(i int) string {
+		name, _, _ := unstructured.NestedString(creates[i].Resources[0].Object.(*unstructured.Unstructured).Object, "spec", "template", "spec", "serviceAccountName")
+		return name
+	}
+	is.Equal("sa-hooks", serviceAccount(1))
+	is.Equal("custom", serviceAccount(2))
+
+	deletes := failer.OperationsOf(kubefake.VerbDelete)
+	req.NotEmpty(deletes)
+	is.Equal("sa-hooks", deletes[len(deletes)-1].Resources[0].Name)
+}
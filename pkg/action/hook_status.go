@@ -0,0 +1,213 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// hookStatuses returns the last execution of the hooks of a release, with the
+// exit codes of the containers of their pods if these can be listed.
+func (cfg *Configuration) hookStatuses(rel *release.Release) []release.HookStatus {
+	var client kubernetes.Interface
+	if cfg.RESTClientGetter != nil {
+		var err error
+		if client, err = cfg.KubernetesClientSet(); err != nil {
+			cfg.Log("unable to get the pods of hooks: %s", err)
+		}
+	}
+	statuses := make([]release.HookStatus, 0, len(rel.Hooks))
+	for _, h := range rel.Hooks {
+		var pods []corev1.Pod
+		if client != nil && !h.LastRun.StartedAt.IsZero() {
+			var err error
+			if pods, err = hookPods(client, rel.Namespace, h); err != nil {
+				cfg.Log("unable to get the pods of hook %s: %s", h.Name, err)
+			}
+		}
+		statuses = append(statuses, hookStatus(h, pods))
+	}
+	return statuses
+}
+
+// hookPodKinds are the kinds of hooks that run pods, whose logs can be read.
+var hookPodKinds = map[string]bool{
+	"Pod":         true,
+	"Job":         true,
+	"CronJob":     true,
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+}
+
+// hookPods returns the pods of the last execution of a hook of one of the
+// hookPodKinds, as far as they still exist.
+//
+// The pods of Jobs are found by their job-name label, those of CronJobs
+// through the Jobs the CronJob owns, and those of Deployments, StatefulSets
+// and DaemonSets by the selector of the workload.
+func hookPods(client kubernetes.Interface, namespace string, h *release.Hook) ([]corev1.Pod, error) {
+	ctx := context.Background()
+	var pods []corev1.Pod
+	switch h.Kind {
+	case "Pod":
+		pod, err := client.CoreV1().Pods(namespace).Get(ctx, h.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		pods = []corev1.Pod{*pod}
+	case "Job":
+		var err error
+		if pods, err = listPods(ctx, client, namespace, "job-name="+h.Name); err != nil {
+			return nil, err
+		}
+	case "CronJob":
+		jobs, err := client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range jobs.Items {
+			if !ownedBy(job.OwnerReferences, "CronJob", h.Name) {
+				continue
+			}
+			jobPods, err := listPods(ctx, client, namespace, "job-name="+job.Name)
+			if err != nil {
+				return nil, err
+			}
+			pods = append(pods, jobPods...)
+		}
+	case "Deployment", "StatefulSet", "DaemonSet":
+		selector, err := workloadSelector(ctx, client, namespace, h)
+		if err != nil || selector == nil {
+			return nil, err
+		}
+		if pods, err = listPods(ctx, client, namespace, selector.String()); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	// Pods of earlier executions may not have been deleted yet. Creation
+	// times only have a precision of seconds.
+	started := h.LastRun.StartedAt.Time.Truncate(time.Second)
+	var result []corev1.Pod
+	for _, pod := range pods {
+		if !pod.CreationTimestamp.Time.Before(started) {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+func listPods(ctx context.Context, client kubernetes.Interface, namespace, selector string) ([]corev1.Pod, error) {
+	list, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// workloadSelector returns the selector of the pods of a Deployment,
+// StatefulSet or DaemonSet hook, or nil if the hook no longer exists.
+func workloadSelector(ctx context.Context, client kubernetes.Interface, namespace string, h *release.Hook) (labels.Selector, error) {
+	var selector *metav1.LabelSelector
+	var err error
+	switch h.Kind {
+	case "Deployment":
+		var d *appsv1.Deployment
+		if d, err = client.AppsV1().Deployments(namespace).Get(ctx, h.Name, metav1.GetOptions{}); err == nil {
+			selector = d.Spec.Selector
+		}
+	case "StatefulSet":
+		var s *appsv1.StatefulSet
+		if s, err = client.AppsV1().StatefulSets(namespace).Get(ctx, h.Name, metav1.GetOptions{}); err == nil {
+			selector = s.Spec.Selector
+		}
+	case "DaemonSet":
+		var d *appsv1.DaemonSet
+		if d, err = client.AppsV1().DaemonSets(namespace).Get(ctx, h.Name, metav1.GetOptions{}); err == nil {
+			selector = d.Spec.Selector
+		}
+	}
+	if apierrors.IsNotFound(err) || (err == nil && selector == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// ownedBy returns whether the owner references name an owner of the kind.
+func ownedBy(refs []metav1.OwnerReference, kind, name string) bool {
+	for _, ref := range refs {
+		if ref.Kind == kind && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hookStatus returns the last execution of a hook, with the exit codes of the
+// terminated containers of its pods.
+func hookStatus(h *release.Hook, pods []corev1.Pod) release.HookStatus {
+	status := release.HookStatus{
+		Name:   h.Name,
+		Kind:   h.Kind,
+		Events: h.Events,
+	}
+	if !h.LastRun.StartedAt.IsZero() {
+		status.Phase = h.LastRun.Phase
+		status.StartedAt = h.LastRun.StartedAt
+		status.CompletedAt = h.LastRun.CompletedAt
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for _, pod := range pods {
+		containers := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, c := range containers {
+			terminated := c.State.Terminated
+			if terminated == nil {
+				terminated = c.LastTerminationState.Terminated
+			}
+			if terminated == nil {
+				continue
+			}
+			status.ExitCodes = append(status.ExitCodes, release.ContainerExitCode{
+				Pod:       pod.Name,
+				Container: c.Name,
+				ExitCode:  terminated.ExitCode,
+				Reason:    terminated.Reason,
+			})
+		}
+	}
+	return status
+}
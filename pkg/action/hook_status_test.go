@@ -0,0 +1,186 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+func TestHookPods(t *testing.T) {
+	started := time.Now()
+	pod := func(name string, created time.Time, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            labels,
+			CreationTimestamp: metav1.NewTime(created),
+		}}
+	}
+	client := fake.NewSimpleClientset(
+		pod("migrate-abc", started.Add(time.Second), map[string]string{"job-name": "migrate"}),
+		pod("migrate-old", started.Add(-time.Hour), map[string]string{"job-name": "migrate"}),
+		pod("seed-xyz", started, map[string]string{"job-name": "seed"}),
+		pod("check", started, nil),
+	)
+	hook := func(kind, name string) *release.Hook {
+		return &release.Hook{Kind: kind, Name: name, LastRun: release.HookExecution{StartedAt: helmtime.Time{Time: started}}}
+	}
+
+	pods, err := hookPods(client, "default", hook("Job", "migrate"))
+	assert.NoError(t, err)
+	if assert.Len(t, pods, 1, "pods of earlier executions are skipped") {
+		assert.Equal(t, "migrate-abc", pods[0].Name)
+	}
+
+	pods, err = hookPods(client, "default", hook("Pod", "check"))
+	assert.NoError(t, err)
+	assert.Len(t, pods, 1)
+
+	pods, err = hookPods(client, "default", hook("Pod", "deleted"))
+	assert.NoError(t, err)
+	assert.Empty(t, pods)
+
+	pods, err = hookPods(client, "default", hook("ConfigMap", "check"))
+	assert.NoError(t, err)
+	assert.Empty(t, pods)
+}
+
+func TestHookPodsOfWorkloads(t *testing.T) {
+	started := time.Now()
+	pod := func(name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            labels,
+			CreationTimestamp: metav1.NewTime(started),
+		}}
+	}
+	selector := func(app string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}
+	}
+	meta := func(name string, owners ...metav1.OwnerReference) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "default", OwnerReferences: owners}
+	}
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: meta("warmup"), Spec: appsv1.DeploymentSpec{Selector: selector("warmup")}},
+		&appsv1.StatefulSet{ObjectMeta: meta("quorum"), Spec: appsv1.StatefulSetSpec{Selector: selector("quorum")}},
+		&appsv1.DaemonSet{ObjectMeta: meta("prepull"), Spec: appsv1.DaemonSetSpec{Selector: selector("prepull")}},
+		&batchv1.Job{ObjectMeta: meta("backup-123", metav1.OwnerReference{Kind: "CronJob", Name: "backup"})},
+		&batchv1.Job{ObjectMeta: meta("cleanup-123", metav1.OwnerReference{Kind: "CronJob", Name: "cleanup"})},
+		pod("warmup-abc", map[string]string{"app": "warmup"}),
+		pod("quorum-0", map[string]string{"app": "quorum"}),
+		pod("prepull-abc", map[string]string{"app": "prepull"}),
+		pod("backup-123-abc", map[string]string{"job-name": "backup-123"}),
+		pod("cleanup-123-abc", map[string]string{"job-name": "cleanup-123"}),
+	)
+
+	for _, tt := range []struct{ kind, name, pod string }{
+		{"Deployment", "warmup", "warmup-abc"},
+		{"StatefulSet", "quorum", "quorum-0"},
+		{"DaemonSet", "prepull", "prepull-abc"},
+		{"CronJob", "backup", "backup-123-abc"},
+	} {
+		pods, err := hookPods(client, "default", &release.Hook{Kind: tt.kind, Name: tt.name, LastRun: release.HookExecution{StartedAt: helmtime.Time{Time: started}}})
+		assert.NoError(t, err)
+		if assert.Len(t, pods, 1, tt.kind) {
+			assert.Equal(t, tt.pod, pods[0].Name, tt.kind)
+		}
+	}
+
+	pods, err := hookPods(client, "default", &release.Hook{Kind: "Deployment", Name: "deleted"})
+	assert.NoError(t, err)
+	assert.Empty(t, pods)
+}
+
+func TestHookStatus(t *testing.T) {
+	started := helmtime.Unix(1452902400, 0)
+	h := &release.Hook{
+		Name:   "migrate",
+		Kind:   "Job",
+		Events: []release.HookEvent{release.HookPreUpgrade},
+		LastRun: release.HookExecution{
+			StartedAt:   started,
+			CompletedAt: started.Add(time.Minute),
+			Phase:       release.HookPhaseFailed,
+		},
+	}
+	terminated := func(code int32, reason string) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: code, Reason: reason}}
+	}
+	pods := []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate-b"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				// A restarted container reports the exit code of its last run.
+				{Name: "main", LastTerminationState: terminated(1, "Error")},
+			},
+		},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate-a"},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "wait", State: terminated(0, "Completed")},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main", State: terminated(137, "OOMKilled")},
+				{Name: "sidecar", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}}
+
+	status := hookStatus(h, pods)
+	assert.Equal(t, "migrate", status.Name)
+	assert.Equal(t, release.HookPhaseFailed, status.Phase)
+	assert.Equal(t, time.Minute, status.CompletedAt.Sub(status.StartedAt))
+	assert.Equal(t, []release.ContainerExitCode{
+		{Pod: "migrate-a", Container: "wait", ExitCode: 0, Reason: "Completed"},
+		{Pod: "migrate-a", Container: "main", ExitCode: 137, Reason: "OOMKilled"},
+		{Pod: "migrate-b", Container: "main", ExitCode: 1, Reason: "Error"},
+	}, status.ExitCodes)
+
+	// A hook that never ran has no phase.
+	status = hookStatus(&release.Hook{Name: "seed", Kind: "Job", LastRun: release.HookExecution{Phase: release.HookPhaseUnknown}}, nil)
+	assert.Empty(t, status.Phase)
+	assert.Empty(t, status.ExitCodes)
+}
+
+func TestStatusShowHooks(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	rel := releaseStub()
+	assert.NoError(t, cfg.Releases.Create(rel))
+
+	client := NewStatus(cfg)
+	res, err := client.Run(rel.Name)
+	assert.NoError(t, err)
+	assert.Empty(t, res.Info.HookStatuses)
+
+	client.ShowHooks = true
+	res, err = client.Run(rel.Name)
+	assert.NoError(t, err)
+	assert.Len(t, res.Info.HookStatuses, len(rel.Hooks))
+}
@@ -16,19 +16,49 @@ limitations under the License.
 package action
 
 import (
-	"bytes"
+	"context"
+	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"helm.sh/helm/v3/pkg/errcode"
 	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/release"
 	helmtime "helm.sh/helm/v3/pkg/time"
 )
 
 // execHook executes all of the hooks for the given hook event.
-func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent, timeout time.Duration) error {
+//
+// Hooks run in the order of their weight. When HookParallelism is greater
+// than 1, the hooks of the same weight run in parallel, that many at a time,
+// and all of them complete before the hooks of the next weight start.
+//
+// Once ctx is done, hooks in flight are stopped, with their phase recorded as
+// unknown, and no further hooks run. The error of hooks that failed is a
+// *hookError.
+func (cfg *Configuration) execHook(ctx context.Context, rl *release.Release, hook release.HookEvent, timeout time.Duration) error {
+	if err := cfg.runHooks(ctx, rl, hook, timeout); err != nil {
+		return &hookError{event: hook, err: err}
+	}
+	return nil
+}
+
+// hookError is the error of the hooks of an event that failed.
+type hookError struct {
+	event release.HookEvent
+	err   error
+}
+
+func (e *hookError) Error() string { return e.err.Error() }
+
+func (e *hookError) Unwrap() error { return e.err }
+
+// runHooks executes the hooks for the given hook event, see execHook.
+func (cfg *Configuration) runHooks(ctx context.Context, rl *release.Release, hook release.HookEvent, timeout time.Duration) error {
 	executingHooks := []*release.Hook{}
 
 	for _, h := range rl.Hooks {
@@ -51,63 +81,268 @@ func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent,
 			//                 current release.
 			h.DeletePolicies = []release.HookDeletePolicy{release.HookBeforeHookCreation}
 		}
+	}
 
-		if err := cfg.deleteHookByPolicy(h, release.HookBeforeHookCreation, timeout); err != nil {
+	run := &hookRun{ctx: ctx, cfg: cfg, rl: rl, event: hook, timeout: timeout}
+	if cfg.HookServiceAccount && runInCluster(executingHooks) {
+		name, cleanup, err := cfg.createHookServiceAccount(rl)
+		if err != nil {
 			return err
 		}
+		defer cleanup()
+		run.serviceAccount = name
+	}
 
-		resources, err := cfg.KubeClient.Build(bytes.NewBufferString(h.Manifest), true)
-		if err != nil {
-			return errors.Wrapf(err, "unable to build kubernetes object for %s hook %s", hook, h.Path)
+	for _, tier := range hookWeightTiers(executingHooks) {
+		if err := run.execTier(tier); err != nil {
+			return err
 		}
+	}
 
-		// Record the time at which the hook was applied to the cluster
-		h.LastRun = release.HookExecution{
-			StartedAt: helmtime.Now(),
-			Phase:     release.HookPhaseRunning,
+	// If all hooks are successful, check the annotation of each hook to determine whether the hook should be deleted
+	// under succeeded condition. If so, then clear the corresponding resource object in each hook
+	for _, h := range executingHooks {
+		if err := cfg.deleteHookByPolicy(ctx, h, release.HookSucceeded, timeout); err != nil {
+			return err
 		}
-		cfg.recordRelease(rl)
+	}
+
+	return nil
+}
 
-		// As long as the implementation of WatchUntilReady does not panic, HookPhaseFailed or HookPhaseSucceeded
-		// should always be set by this function. If we fail to do that for any reason, then HookPhaseUnknown is
-		// the most appropriate value to surface.
-		h.LastRun.Phase = release.HookPhaseUnknown
+// hookRun is the execution of the hooks of an event of a release.
+type hookRun struct {
+	ctx            context.Context
+	cfg            *Configuration
+	rl             *release.Release
+	event          release.HookEvent
+	timeout        time.Duration
+	serviceAccount string
 
-		// Create hook resources
-		if _, err := cfg.KubeClient.Create(resources); err != nil {
-			h.LastRun.CompletedAt = helmtime.Now()
-			h.LastRun.Phase = release.HookPhaseFailed
-			return errors.Wrapf(err, "warning: Hook %s %s failed", hook, h.Path)
+	// mu guards the release, including the last executions of its hooks,
+	// while hooks run in parallel.
+	mu sync.Mutex
+}
+
+// hookWeightTiers splits hooks sorted by weight into the hooks of each
+// weight.
+func hookWeightTiers(hooks []*release.Hook) [][]*release.Hook {
+	var tiers [][]*release.Hook
+	for start := 0; start < len(hooks); {
+		end := start + 1
+		for end < len(hooks) && hooks[end].Weight == hooks[start].Weight {
+			end++
 		}
+		tiers = append(tiers, hooks[start:end])
+		start = end
+	}
+	return tiers
+}
 
-		// Watch hook resources until they have completed
-		err = cfg.KubeClient.WatchUntilReady(resources, timeout)
-		// Note the time of success/failure
-		h.LastRun.CompletedAt = helmtime.Now()
-		// Mark hook as succeeded or failed
-		if err != nil {
-			h.LastRun.Phase = release.HookPhaseFailed
-			// If a hook is failed, check the annotation of the hook to determine whether the hook should be deleted
-			// under failed condition. If so, then clear the corresponding resource object in the hook
-			if err := cfg.deleteHookByPolicy(h, release.HookFailed, timeout); err != nil {
+// execTier executes hooks of the same weight, in parallel if the
+// configuration allows it. Hooks run in parallel all complete before the
+// error of the first of them that failed is returned.
+func (r *hookRun) execTier(hooks []*release.Hook) error {
+	if r.cfg.HookParallelism < 2 || len(hooks) < 2 {
+		for _, h := range hooks {
+			if err := r.exec(h); err != nil {
 				return err
 			}
-			return err
 		}
-		h.LastRun.Phase = release.HookPhaseSucceeded
+		return nil
 	}
 
-	// If all hooks are successful, check the annotation of each hook to determine whether the hook should be deleted
-	// under succeeded condition. If so, then clear the corresponding resource object in each hook
-	for _, h := range executingHooks {
-		if err := cfg.deleteHookByPolicy(h, release.HookSucceeded, timeout); err != nil {
+	errs := make([]error, len(hooks))
+	workers := make(chan struct{}, r.cfg.HookParallelism)
+	var wg sync.WaitGroup
+	for i, h := range hooks {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(i int, h *release.Hook) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			errs[i] = r.exec(h)
+		}(i, h)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// setLastRun updates the last execution of a hook, recording the release if
+// record is set.
+func (r *hookRun) setLastRun(h *release.Hook, update func(*release.HookExecution), record bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	update(&h.LastRun)
+	if record {
+		r.cfg.recordRelease(r.rl)
+	}
+}
+
+// exec executes a hook and waits for it to complete.
+func (r *hookRun) exec(h *release.Hook) error {
+	ctx, cfg, hook, timeout := r.ctx, r.cfg, r.event, r.timeoutOf(h)
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrapf(err, "%s hook %s was not run", hook, h.Path)
+	}
+	if err := cfg.deleteHookByPolicy(ctx, h, release.HookBeforeHookCreation, timeout); err != nil {
+		return err
+	}
+
+	if h.Kind == ExternalHookKind {
+		return r.execExternal(h)
+	}
 
+	resources, err := cfg.KubeClient.Build(strings.NewReader(h.Manifest), true)
+	if err != nil {
+		return errors.Wrapf(err, "unable to build kubernetes object for %s hook %s", hook, h.Path)
+	}
+	if r.serviceAccount != "" {
+		if err := setServiceAccount(resources, r.serviceAccount); err != nil {
+			return errors.Wrapf(err, "unable to prepare %s hook %s", hook, h.Path)
+		}
+	}
+	if err := cfg.verifyHookImages(resources); err != nil {
+		return errors.Wrapf(err, "refusing to run %s hook %s", hook, h.Path)
+	}
+
+	// Record the time at which the hook was applied to the cluster
+	r.setLastRun(h, func(e *release.HookExecution) {
+		*e = release.HookExecution{
+			StartedAt: helmtime.Now(),
+			Phase:     release.HookPhaseRunning,
+		}
+	}, true)
+
+	// As long as the implementation of WatchUntilReady does not panic, HookPhaseFailed or HookPhaseSucceeded
+	// should always be set by this function. If we fail to do that for any reason, then HookPhaseUnknown is
+	// the most appropriate value to surface.
+	r.setLastRun(h, func(e *release.HookExecution) { e.Phase = release.HookPhaseUnknown }, false)
+
+	// Create hook resources and watch them until they have completed,
+	// re-creating them as often as the hook may be retried
+	var created bool
+	for attempt := 1; ; attempt++ {
+		r.setLastRun(h, func(e *release.HookExecution) { e.Attempts = attempt }, false)
+		created, err = r.create(h, resources)
+		if err == nil || attempt > h.Retries || ctx.Err() != nil {
+			break
+		}
+		backoff := hookRetryBackoff(h, attempt)
+		cfg.Log("%s hook %s failed, retrying in %s (retry %d of %d): %s", hook, h.Path, backoff, attempt, h.Retries, err)
+		if created {
+			if err := cfg.deleteHookResources(ctx, h, timeout); err != nil {
+				return errors.Wrapf(err, "unable to delete %s hook %s for a retry", hook, h.Path)
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		// The hook was stopped, so whether it would have succeeded is unknown
+		r.setLastRun(h, func(e *release.HookExecution) {
+			e.CompletedAt = helmtime.Now()
+			e.Phase = release.HookPhaseUnknown
+		}, false)
+		cfg.observeHook(hook, h)
+		return errors.Wrapf(ctx.Err(), "%s hook %s was cancelled", hook, h.Path)
+	}
+	if !created {
+		r.setLastRun(h, func(e *release.HookExecution) {
+			e.CompletedAt = helmtime.Now()
+			e.Phase = release.HookPhaseFailed
+		}, false)
+		cfg.observeHook(hook, h)
+		return fmt.Errorf("%w: %w", errcode.New(errcode.HookFailed, hook, h.Path), err)
+	}
+	// Note the time of success/failure and mark hook as succeeded or failed
+	r.setLastRun(h, func(e *release.HookExecution) {
+		e.CompletedAt = helmtime.Now()
+		if err != nil {
+			e.Phase = release.HookPhaseFailed
+		} else {
+			e.Phase = release.HookPhaseSucceeded
+		}
+	}, false)
+	cfg.observeHook(hook, h)
+	if err != nil {
+		// If a hook is failed, check the annotation of the hook to determine whether the hook should be deleted
+		// under failed condition. If so, then clear the corresponding resource object in the hook
+		if err := cfg.deleteHookByPolicy(ctx, h, release.HookFailed, timeout); err != nil {
+			return err
+		}
+		return err
+	}
 	return nil
 }
 
+// create creates the resources of a hook and watches them until they have
+// completed, reporting whether they were created. The logs of the hook are
+// streamed to HookOutputFunc meanwhile.
+func (r *hookRun) create(h *release.Hook, resources kube.ResourceList) (bool, error) {
+	kubeClient, withContext := r.cfg.KubeClient.(kube.InterfaceContext)
+	var result *kube.Result
+	var err error
+	if withContext {
+		result, err = kubeClient.CreateWithContext(r.ctx, resources)
+	} else {
+		result, err = r.cfg.KubeClient.Create(resources)
+	}
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	r.cfg.addWarnings(r.rl, result)
+	r.mu.Unlock()
+	stopLogs := r.cfg.followHookLogs(r.ctx, r.rl.Namespace, h)
+	defer stopLogs()
+	if withContext {
+		return true, kubeClient.WatchUntilReadyWithContext(r.ctx, resources, r.timeoutOf(h))
+	}
+	return true, r.cfg.KubeClient.WatchUntilReady(resources, r.timeoutOf(h))
+}
+
+// timeoutOf returns the time to wait for a hook: its own timeout, if it has
+// one, or else that of the action.
+func (r *hookRun) timeoutOf(h *release.Hook) time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return r.timeout
+}
+
+// defaultHookRetryBackoff is the wait before the first retry of hooks without
+// a retry backoff, and maxHookRetryBackoff caps the doubling waits.
+const (
+	defaultHookRetryBackoff = 10 * time.Second
+	maxHookRetryBackoff     = 5 * time.Minute
+)
+
+// hookRetryBackoff returns the wait before the given retry of a hook.
+func hookRetryBackoff(h *release.Hook, retry int) time.Duration {
+	backoff := h.RetryBackoff
+	if backoff == 0 {
+		backoff = defaultHookRetryBackoff
+	}
+	for i := 1; i < retry && backoff < maxHookRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxHookRetryBackoff {
+		backoff = maxHookRetryBackoff
+	}
+	return backoff
+}
+
 // hookByWeight is a sorter for hooks
 type hookByWeight []*release.Hook
 
@@ -121,27 +356,37 @@ func (x hookByWeight) Less(i, j int) bool {
 }
 
 // deleteHookByPolicy deletes a hook if the hook policy instructs it to
-func (cfg *Configuration) deleteHookByPolicy(h *release.Hook, policy release.HookDeletePolicy, timeout time.Duration) error {
+func (cfg *Configuration) deleteHookByPolicy(ctx context.Context, h *release.Hook, policy release.HookDeletePolicy, timeout time.Duration) error {
 	// Never delete CustomResourceDefinitions; this could cause lots of
-	// cascading garbage collection.
-	if h.Kind == "CustomResourceDefinition" {
+	// cascading garbage collection. External hooks create no resources.
+	if h.Kind == "CustomResourceDefinition" || h.Kind == ExternalHookKind {
 		return nil
 	}
 	if hookHasDeletePolicy(h, policy) {
-		resources, err := cfg.KubeClient.Build(bytes.NewBufferString(h.Manifest), false)
-		if err != nil {
-			return errors.Wrapf(err, "unable to build kubernetes object for deleting hook %s", h.Path)
-		}
-		_, errs := cfg.KubeClient.Delete(resources)
-		if len(errs) > 0 {
-			return errors.New(joinErrors(errs))
-		}
+		return cfg.deleteHookResources(ctx, h, timeout)
+	}
+	return nil
+}
 
-		//wait for resources until they are deleted to avoid conflicts
-		if kubeClient, ok := cfg.KubeClient.(kube.InterfaceExt); ok {
-			if err := kubeClient.WaitForDelete(resources, timeout); err != nil {
-				return err
-			}
+// deleteHookResources deletes the resources of a hook and waits for them to
+// be gone, or ctx to be done.
+func (cfg *Configuration) deleteHookResources(ctx context.Context, h *release.Hook, timeout time.Duration) error {
+	resources, err := cfg.KubeClient.Build(strings.NewReader(h.Manifest), false)
+	if err != nil {
+		return errors.Wrapf(err, "unable to build kubernetes object for deleting hook %s", h.Path)
+	}
+	_, errs := cfg.KubeClient.Delete(resources)
+	if len(errs) > 0 {
+		return errors.New(joinErrors(errs))
+	}
+
+	//wait for resources until they are deleted to avoid conflicts
+	if kubeClient, ok := cfg.KubeClient.(kube.InterfaceContext); ok {
+		return kubeClient.WaitForDeleteWithContext(ctx, resources, timeout)
+	}
+	if kubeClient, ok := cfg.KubeClient.(kube.InterfaceExt); ok {
+		if err := kubeClient.WaitForDelete(resources, timeout); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -0,0 +1,259 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestInstallRelease_ParallelHooks(t *testing.T) {
+	job := func(name, weight string) *chart.File {
+		return &chart.File{
+			Name: "templates/" + name + ".yaml",
+			Data: []byte(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ` + name + `
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "` + weight + `"
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: busybox`),
+		}
+	}
+
+	// hookOperations returns the creations and watches of the hooks, in the
+	// order they completed.
+	hookOperations := func(t *testing.T, parallelism int) []string {
+		t.Helper()
+		chrt := buildChart()
+		chrt.Templates = []*chart.File{job("a", "0"), job("b", "0"), job("c", "0"), job("d", "1")}
+
+		instAction := installAction(t)
+		instAction.cfg.HookParallelism = parallelism
+		failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+		failer.ParseManifests = true
+		failer.Latency = 50 * time.Millisecond
+		rel, err := instAction.Run(chrt, map[string]interface{}{})
+		require.NoError(t, err)
+		for _, h := range rel.Hooks {
+			assert.Equal(t, "Succeeded", h.LastRun.Phase.String(), h.Name)
+		}
+
+		var ops []string
+		for _, op := range failer.Operations() {
+			if op.Verb != kubefake.VerbCreate && op.Verb != kubefake.VerbWatchUntilReady {
+				continue
+			}
+			for _, r := range op.Resources {
+				if r.Object.GetObjectKind().GroupVersionKind().Kind == "Job" {
+					ops = append(ops, string(op.Verb)+" "+r.Name)
+				}
+			}
+		}
+		return ops
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"create a", "watch-until-ready a",
+			"create b", "watch-until-ready b",
+			"create c", "watch-until-ready c",
+			"create d", "watch-until-ready d",
+		}, hookOperations(t, 0))
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		ops := hookOperations(t, 3)
+		require.Len(t, ops, 8)
+		// The hooks of weight 0 run together, and complete before the hook
+		// of weight 1 starts.
+		creates, watches := ops[:3], ops[3:6]
+		sort.Strings(creates)
+		sort.Strings(watches)
+		assert.Equal(t, []string{"create a", "create b", "create c"}, creates)
+		assert.Equal(t, []string{"watch-until-ready a", "watch-until-ready b", "watch-until-ready c"}, watches)
+		assert.Equal(t, []string{"create d", "watch-until-ready d"}, ops[6:])
+	})
+}
+
+func TestHookWeightTiers(t *testing.T) {
+	hooks := []*release.Hook{{Name: "a", Weight: -1}, {Name: "b"}, {Name: "c"}, {Name: "d", Weight: 5}}
+	tiers := hookWeightTiers(hooks)
+	require.Len(t, tiers, 3)
+	assert.Equal(t, hooks[:1], tiers[0])
+	assert.Equal(t, hooks[1:3], tiers[1])
+	assert.Equal(t, hooks[3:], tiers[2])
+	assert.Empty(t, hookWeightTiers(nil))
+}
+
+func TestInstallRelease_HookRetries(t *testing.T) {
+	chrt := func() *chart.Chart {
+		c := buildChart()
+		c.Templates = []*chart.File{{Name: "templates/migrate.yaml", Data: []byte(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-retries: "2"
+    helm.sh/hook-retry-backoff: 1ms
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: busybox`)}}
+		return c
+	}
+	install := func(t *testing.T, failures int) (*release.Release, *kubefake.FailingKubeClient, error) {
+		t.Helper()
+		instAction := installAction(t)
+		failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+		failer.ParseManifests = true
+		failer.Failures = []kubefake.Failure{{Verb: kubefake.VerbWatchUntilReady, Name: "migrate", Times: failures, Err: errors.New("job failed")}}
+		rel, err := instAction.Run(chrt(), map[string]interface{}{})
+		return rel, failer, err
+	}
+
+	t.Run("recovers", func(t *testing.T) {
+		rel, failer, err := install(t, 2)
+		require.NoError(t, err)
+		require.Len(t, rel.Hooks, 1)
+		assert.Equal(t, 2, rel.Hooks[0].Retries)
+		assert.Equal(t, time.Millisecond, rel.Hooks[0].RetryBackoff)
+		assert.Equal(t, release.HookPhaseSucceeded, rel.Hooks[0].LastRun.Phase)
+		assert.Equal(t, 3, rel.Hooks[0].LastRun.Attempts)
+		// The failed job is deleted before it is created again, after the
+		// deletion of the before-hook-creation policy.
+		var ops []string
+		for _, op := range failer.Operations() {
+			if op.Verb == kubefake.VerbCreate || op.Verb == kubefake.VerbDelete {
+				ops = append(ops, string(op.Verb))
+			}
+		}
+		require.GreaterOrEqual(t, len(ops), 6)
+		assert.Equal(t, []string{"delete", "create", "delete", "create", "delete", "create"}, ops[:6])
+	})
+
+	t.Run("fails", func(t *testing.T) {
+		rel, _, err := install(t, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "job failed")
+		require.Len(t, rel.Hooks, 1)
+		assert.Equal(t, release.HookPhaseFailed, rel.Hooks[0].LastRun.Phase)
+		assert.Equal(t, 3, rel.Hooks[0].LastRun.Attempts)
+	})
+}
+
+func TestHookRetryBackoff(t *testing.T) {
+	assert.Equal(t, defaultHookRetryBackoff, hookRetryBackoff(&release.Hook{}, 1))
+	h := &release.Hook{RetryBackoff: time.Second}
+	assert.Equal(t, time.Second, hookRetryBackoff(h, 1))
+	assert.Equal(t, 4*time.Second, hookRetryBackoff(h, 3))
+	assert.Equal(t, maxHookRetryBackoff, hookRetryBackoff(h, 20))
+}
+
+func TestExecHook_Cancelled(t *testing.T) {
+	job := func(name string) *release.Hook {
+		return &release.Hook{
+			Name: name,
+			Kind: "Job",
+			Path: "templates/" + name + ".yaml",
+			Manifest: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ` + name,
+			Events: []release.HookEvent{release.HookPreInstall},
+			Weight: len(name),
+		}
+	}
+	rel := releaseStub()
+	rel.Hooks = []*release.Hook{job("migrate"), job("seed-database")}
+
+	cfg := actionConfigFixture(t)
+	failer := cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	failer.WatchDuration = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err := cfg.execHook(ctx, rel, release.HookPreInstall, time.Minute)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 10*time.Second, "expected the hook to stop when cancelled")
+
+	// The hook in flight is stopped and the next one never runs.
+	assert.Equal(t, release.HookPhaseUnknown, rel.Hooks[0].LastRun.Phase)
+	assert.False(t, rel.Hooks[0].LastRun.CompletedAt.IsZero())
+	assert.Empty(t, rel.Hooks[1].LastRun.Phase)
+	assert.Len(t, failer.OperationsOf(kubefake.VerbCreate), 1)
+}
+
+// watchTimeouts records the timeouts resources are watched with.
+type watchTimeouts struct {
+	kube.Interface
+	timeouts []time.Duration
+}
+
+func (c *watchTimeouts) WatchUntilReady(resources kube.ResourceList, timeout time.Duration) error {
+	c.timeouts = append(c.timeouts, timeout)
+	return c.Interface.WatchUntilReady(resources, timeout)
+}
+
+func TestExecHook_Timeout(t *testing.T) {
+	job := func(name string, weight int, timeout time.Duration) *release.Hook {
+		return &release.Hook{
+			Name: name,
+			Kind: "Job",
+			Path: "templates/" + name + ".yaml",
+			Manifest: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ` + name,
+			Events:  []release.HookEvent{release.HookPreInstall},
+			Weight:  weight,
+			Timeout: timeout,
+		}
+	}
+	rel := releaseStub()
+	rel.Hooks = []*release.Hook{job("migrate", 0, 30*time.Minute), job("seed", 1, 0)}
+
+	cfg := actionConfigFixture(t)
+	cfg.KubeClient.(*kubefake.FailingKubeClient).ParseManifests = true
+	client := &watchTimeouts{Interface: cfg.KubeClient}
+	cfg.KubeClient = client
+
+	require.NoError(t, cfg.execHook(context.Background(), rel, release.HookPreInstall, 5*time.Minute))
+	assert.Equal(t, []time.Duration{30 * time.Minute, 5 * time.Minute}, client.timeouts)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// ciEnvVars are the environment variables describing the CI job running Helm
+// that are recorded with a release.
+var ciEnvVars = []string{
+	// GitHub Actions
+	"GITHUB_REPOSITORY",
+	"GITHUB_WORKFLOW",
+	"GITHUB_RUN_ID",
+	"GITHUB_ACTOR",
+	"GITHUB_SHA",
+	// GitLab CI
+	"CI_PROJECT_PATH",
+	"CI_PIPELINE_ID",
+	"CI_JOB_URL",
+	"GITLAB_USER_LOGIN",
+	"CI_COMMIT_SHA",
+	// Jenkins
+	"JOB_NAME",
+	"BUILD_URL",
+	"GIT_COMMIT",
+}
+
+// identity returns who performs an action: the user the cluster authenticates
+// the client as, the given description and the metadata of the CI job, if any.
+// It returns nil when none of them are known.
+func (cfg *Configuration) identity(deployedBy string) *release.Identity {
+	id := &release.Identity{
+		User:       cfg.currentUser(),
+		DeployedBy: deployedBy,
+	}
+	for _, name := range ciEnvVars {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			if id.CI == nil {
+				id.CI = map[string]string{}
+			}
+			id.CI[name] = v
+		}
+	}
+	if id.User == "" && id.DeployedBy == "" && id.CI == nil {
+		return nil
+	}
+	return id
+}
+
+// currentUser returns the user the cluster authenticates the client as. It is
+// looked up once; failures are logged as the identity is informational only.
+func (cfg *Configuration) currentUser() string {
+	cfg.kubeUserOnce.Do(func() {
+		user := ""
+		if kubeClient, ok := cfg.KubeClient.(kube.InterfaceIdentity); ok {
+			var err error
+			if user, err = kubeClient.CurrentUser(); err != nil {
+				cfg.Log("unable to determine the current user: %s", err)
+			}
+		}
+		cfg.kubeUser = &user
+	})
+	return *cfg.kubeUser
+}
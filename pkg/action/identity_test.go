@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func unsetCIEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range ciEnvVars {
+		t.Setenv(name, "")
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	unsetCIEnv(t)
+	cfg := actionConfigFixture(t)
+
+	if id := cfg.identity(""); id != nil {
+		t.Errorf("expected no identity, got %+v", id)
+	}
+
+	cfg = actionConfigFixture(t)
+	cfg.KubeClient = &kubefake.PrintingKubeClient{User: "alice@example.com"}
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_REPOSITORY", "example/app")
+
+	expected := &release.Identity{
+		User:       "alice@example.com",
+		DeployedBy: "release-pipeline",
+		CI: map[string]string{
+			"GITHUB_RUN_ID":     "42",
+			"GITHUB_REPOSITORY": "example/app",
+		},
+	}
+	if id := cfg.identity("release-pipeline"); !reflect.DeepEqual(id, expected) {
+		t.Errorf("expected %+v, got %+v", expected, id)
+	}
+	if s := expected.String(); s != "release-pipeline (alice@example.com)" {
+		t.Errorf("unexpected description %q", s)
+	}
+}
+
+func TestInstallRecordsIdentity(t *testing.T) {
+	unsetCIEnv(t)
+	instAction := installAction(t)
+	instAction.cfg.KubeClient = &kubefake.FailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard, User: "alice@example.com"}}
+	instAction.DeployedBy = "release-pipeline"
+
+	res, err := instAction.Run(buildChart(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+	expected := &release.Identity{User: "alice@example.com", DeployedBy: "release-pipeline"}
+	if !reflect.DeepEqual(res.Info.DeployedBy, expected) {
+		t.Errorf("expected %+v, got %+v", expected, res.Info.DeployedBy)
+	}
+}
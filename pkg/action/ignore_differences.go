@@ -0,0 +1,187 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// IgnoreDifferencesAnno lists the fields of a resource, separated by commas,
+// that Helm stops managing once the resource exists: upgrades and rollbacks
+// neither change them nor report them as changed. The fields are paths such
+// as "spec.replicas" for a Deployment scaled by a HorizontalPodAutoscaler, or
+// "webhooks[*].clientConfig.caBundle" for a webhook configuration whose CA
+// bundle is injected. "[*]" selects all items of a list, "[0]" the first one.
+const IgnoreDifferencesAnno = "helm.sh/ignore-differences"
+
+// fieldStep is a step of the path of an ignored field: the key of an object,
+// or the index of an item of a list, -1 for all items.
+type fieldStep struct {
+	key   string
+	index int
+	item  bool
+}
+
+var fieldStepRegex = regexp.MustCompile(`^([^\[\]]*)((?:\[(?:\*|\d+)\])*)$`)
+
+// parseFieldPath parses the path of an ignored field.
+func parseFieldPath(path string) ([]fieldStep, error) {
+	var steps []fieldStep
+	for _, part := range strings.Split(path, ".") {
+		m := fieldStepRegex.FindStringSubmatch(part)
+		if m == nil || (m[1] == "" && m[2] == "") {
+			return nil, errors.Errorf("invalid field path %q", path)
+		}
+		if m[1] != "" {
+			steps = append(steps, fieldStep{key: m[1]})
+		}
+		for _, index := range strings.Split(strings.Trim(m[2], "[]"), "][") {
+			if index == "" {
+				continue
+			}
+			step := fieldStep{index: -1, item: true}
+			if index != "*" {
+				step.index, _ = strconv.Atoi(index)
+			}
+			steps = append(steps, step)
+		}
+	}
+	if len(steps) == 0 || steps[0].item {
+		return nil, errors.Errorf("invalid field path %q", path)
+	}
+	return steps, nil
+}
+
+// ignoredFields returns the paths of the ignored fields of an object.
+func ignoredFields(obj runtime.Object) ([][]fieldStep, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, nil
+	}
+	value := accessor.GetAnnotations()[IgnoreDifferencesAnno]
+	var paths [][]fieldStep
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		steps, err := parseFieldPath(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "annotation %s of %s %q", IgnoreDifferencesAnno, obj.GetObjectKind().GroupVersionKind().Kind, accessor.GetName())
+		}
+		paths = append(paths, steps)
+	}
+	return paths, nil
+}
+
+// removeField removes the field at the path from a decoded object.
+func removeField(obj interface{}, path []fieldStep) {
+	step := path[0]
+	last := len(path) == 1
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		if step.item {
+			return
+		}
+		if last {
+			delete(v, step.key)
+		} else if child, ok := v[step.key]; ok {
+			removeField(child, path[1:])
+		}
+	case []interface{}:
+		if !step.item || last {
+			// Items are not removed, so that the indexes of the list stay.
+			return
+		}
+		for i, item := range v {
+			if step.index < 0 || step.index == i {
+				removeField(item, path[1:])
+			}
+		}
+	}
+}
+
+// withoutFields returns a copy of a decoded object without the fields at the
+// paths.
+func withoutFields(obj map[string]interface{}, paths [][]fieldStep) map[string]interface{} {
+	if len(paths) == 0 {
+		return obj
+	}
+	obj = runtime.DeepCopyJSON(obj)
+	for _, path := range paths {
+		removeField(obj, path)
+	}
+	return obj
+}
+
+// pruneIgnoredFields removes the ignored fields of the target resources from
+// them and from the current resources, so that updating the resources leaves
+// the fields alone. Resources that do not exist yet keep the fields, so that
+// they are created with them.
+func pruneIgnoredFields(current, target kube.ResourceList) error {
+	existing := make(map[string]runtime.Object, len(current))
+	for _, r := range current {
+		existing[objectKey(r)] = r.Object
+	}
+	for _, r := range target {
+		paths, err := ignoredFields(r.Object)
+		if err != nil {
+			return err
+		}
+		cur, ok := existing[objectKey(r)]
+		if !ok || len(paths) == 0 {
+			continue
+		}
+		for _, obj := range []runtime.Object{r.Object, cur} {
+			if err := removeObjectFields(obj, paths); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeObjectFields removes the fields at the paths from an object.
+func removeObjectFields(obj runtime.Object, paths [][]fieldStep) error {
+	return editObject(obj, func(content map[string]interface{}) {
+		for _, path := range paths {
+			removeField(content, path)
+		}
+	})
+}
+
+// editObject edits the decoded content of an object, typed or not.
+func editObject(obj runtime.Object, edit func(content map[string]interface{})) error {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		edit(u.Object)
+		return nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	edit(content)
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"helm.sh/helm/v3/pkg/chart"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+func TestRemoveIgnoredFields(t *testing.T) {
+	obj := func() map[string]interface{} {
+		return map[string]interface{}{
+			"spec": map[string]interface{}{"replicas": int64(3), "paused": false},
+			"webhooks": []interface{}{
+				map[string]interface{}{"name": "a", "clientConfig": map[string]interface{}{"caBundle": "x", "url": "a"}},
+				map[string]interface{}{"name": "b", "clientConfig": map[string]interface{}{"caBundle": "y", "url": "b"}},
+			},
+		}
+	}
+	for _, tt := range []struct {
+		path   string
+		expect map[string]interface{}
+	}{{
+		path: "spec.replicas",
+		expect: map[string]interface{}{
+			"spec":     map[string]interface{}{"paused": false},
+			"webhooks": obj()["webhooks"],
+		},
+	}, {
+		path: "webhooks[*].clientConfig.caBundle",
+		expect: map[string]interface{}{
+			"spec": obj()["spec"],
+			"webhooks": []interface{}{
+				map[string]interface{}{"name": "a", "clientConfig": map[string]interface{}{"url": "a"}},
+				map[string]interface{}{"name": "b", "clientConfig": map[string]interface{}{"url": "b"}},
+			},
+		},
+	}, {
+		path: "webhooks[1].clientConfig",
+		expect: map[string]interface{}{
+			"spec": obj()["spec"],
+			"webhooks": []interface{}{
+				map[string]interface{}{"name": "a", "clientConfig": map[string]interface{}{"caBundle": "x", "url": "a"}},
+				map[string]interface{}{"name": "b"},
+			},
+		},
+	}, {
+		path:   "spec.missing.field",
+		expect: obj(),
+	}} {
+		steps, err := parseFieldPath(tt.path)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.path, err)
+		}
+		original := obj()
+		got := withoutFields(original, [][]fieldStep{steps})
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.expect, got)
+		}
+		if !reflect.DeepEqual(original, obj()) {
+			t.Errorf("%s: the original object was changed", tt.path)
+		}
+	}
+
+	for _, path := range []string{"", "[0].name", "spec..replicas", "webhooks[x]", "spec.replicas]"} {
+		if _, err := parseFieldPath(path); err == nil {
+			t.Errorf("expected path %q to be invalid", path)
+		}
+	}
+}
+
+func TestUpgradeRelease_IgnoreDifferences(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	deployment := func(replicas, image string) []*chart.File {
+		return []*chart.File{{
+			Name: "templates/web.yaml",
+			Data: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+    helm.sh/ignore-differences: spec.replicas
+spec:
+  replicas: ` + replicas + `
+  template:
+    spec:
+      containers:
+      - name: web
+        image: ` + image),
+		}}
+	}
+
+	instAction := installAction(t)
+	instAction.ReleaseName = "ignored"
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	chrt := buildChart()
+	chrt.Templates = deployment("1", "web:1.0")
+	_, err := instAction.Run(chrt, map[string]interface{}{})
+	req.NoError(err)
+
+	// The resource is created with its replicas, but updated without them.
+	creates := failer.OperationsOf(kubefake.VerbCreate)
+	req.Len(creates, 1)
+	replicas, found, _ := unstructured.NestedInt64(creates[0].Resources[0].Object.(*unstructured.Unstructured).Object, "spec", "replicas")
+	is.True(found)
+	is.Equal(int64(1), replicas)
+
+	upAction := upgradeAction(t)
+	upAction.cfg = instAction.cfg
+	chrt = buildChart()
+	chrt.Templates = deployment("2", "web:2.0")
+	_, err = upAction.Run("ignored", chrt, map[string]interface{}{})
+	req.NoError(err)
+
+	updates := failer.OperationsOf(kubefake.VerbUpdate)
+	req.Len(updates, 1)
+	obj := updates[0].Resources[0].Object.(*unstructured.Unstructured).Object
+	_, found, _ = unstructured.NestedFieldNoCopy(obj, "spec", "replicas")
+	is.False(found)
+	containers, _, _ := unstructured.NestedSlice(obj, "spec", "template", "spec", "containers")
+	is.Equal("web:2.0", containers[0].(map[string]interface{})["image"])
+
+	// The ignored field does not change, the image does.
+	report, err := AnalyzeImpact(
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  annotations:\n    helm.sh/ignore-differences: spec.replicas\nspec:\n  replicas: 1\n",
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  annotations:\n    helm.sh/ignore-differences: spec.replicas\nspec:\n  replicas: 2\n",
+		"default", nil)
+	req.NoError(err)
+	is.Empty(report.Resources)
+
+	chrt = buildChart()
+	chrt.Templates = []*chart.File{{
+		Name: "templates/config.yaml",
+		Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n  annotations:\n    helm.sh/ignore-differences: data[0\n"),
+	}}
+	_, err = upAction.Run("ignored", chrt, map[string]interface{}{})
+	is.ErrorContains(err, `annotation helm.sh/ignore-differences of ConfigMap "web": invalid field path "data[0"`)
+}
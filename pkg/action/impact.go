@@ -0,0 +1,539 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// ImpactAction is what an upgrade does to a resource.
+type ImpactAction string
+
+const (
+	// ImpactCreate is a resource created by the upgrade.
+	ImpactCreate ImpactAction = "create"
+	// ImpactDelete is a resource deleted by the upgrade.
+	ImpactDelete ImpactAction = "delete"
+	// ImpactRollout is a workload whose pods are replaced by the upgrade.
+	ImpactRollout ImpactAction = "rollout"
+	// ImpactInPlace is a resource updated without replacing any pods.
+	ImpactInPlace ImpactAction = "in-place"
+)
+
+// maxImpactChanges is the number of changed fields listed per resource.
+const maxImpactChanges = 10
+
+// ResourceImpact is the predicted effect of an upgrade on a resource.
+type ResourceImpact struct {
+	Kind      string       `json:"kind"`
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace"`
+	Action    ImpactAction `json:"action"`
+	// Changes are the changed fields of the resource. For rollouts, these
+	// are the fields of the pod template.
+	Changes []string `json:"changes,omitempty"`
+	// Replicas is the number of pods of a rolled out workload, 0 if it is
+	// not known, as for DaemonSets.
+	Replicas int32 `json:"replicas,omitempty"`
+	// MaxUnavailable is the number of pods that can be unavailable at once
+	// during the rollout.
+	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
+	// Budgets are the disruption budgets covering the pods of a rolled out
+	// workload.
+	Budgets []BudgetImpact `json:"budgets,omitempty"`
+	// Note explains the action, such as pods only being replaced when
+	// deleted.
+	Note string `json:"note,omitempty"`
+}
+
+// BudgetImpact is a PodDisruptionBudget covering a rolled out workload.
+type BudgetImpact struct {
+	Name string `json:"name"`
+	// AllowedDisruptions is the number of pods the budget allows to be
+	// unavailable.
+	AllowedDisruptions int32 `json:"allowedDisruptions"`
+	// Permits is true if the rollout stays within the budget.
+	Permits bool `json:"permits"`
+}
+
+// Blocked returns true if a disruption budget of the workload does not
+// permit its rollout.
+func (r ResourceImpact) Blocked() bool {
+	for _, b := range r.Budgets {
+		if !b.Permits {
+			return true
+		}
+	}
+	return false
+}
+
+// ImpactReport is the predicted effect of an upgrade on the resources of a
+// release. Unchanged resources are left out.
+type ImpactReport struct {
+	Resources []ResourceImpact `json:"resources"`
+}
+
+// Rollouts returns the workloads whose pods are replaced.
+func (r *ImpactReport) Rollouts() []ResourceImpact {
+	var rollouts []ResourceImpact
+	for _, ri := range r.Resources {
+		if ri.Action == ImpactRollout {
+			rollouts = append(rollouts, ri)
+		}
+	}
+	return rollouts
+}
+
+// AnalyzeImpact predicts the effect of replacing the current manifest of a
+// release with the target one: which workloads roll out their pods, which
+// resources are updated in place, created or deleted, and whether the
+// disruption budgets permit the rollouts.
+//
+// Rollouts are not subject to disruption budgets, which only apply to
+// evictions, so a budget not permitting a rollout means that the rollout
+// can take down more pods than the budget allows. Budgets of the target
+// manifest are evaluated against their spec, the given budgets, typically
+// those in the cluster, against their status. Resources without a namespace
+// are in the given namespace.
+func AnalyzeImpact(current, target, namespace string, budgets []policyv1.PodDisruptionBudget) (*ImpactReport, error) {
+	currentObjs, err := impactObjects(current, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse current manifest")
+	}
+	targetObjs, err := impactObjects(target, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse target manifest")
+	}
+
+	// Budgets of the target manifest replace those of the same name.
+	type budget struct {
+		pdb     policyv1.PodDisruptionBudget
+		desired bool
+	}
+	allBudgets := map[string]budget{}
+	for _, b := range budgets {
+		if b.Namespace == "" {
+			b.Namespace = namespace
+		}
+		allBudgets[b.Namespace+"/"+b.Name] = budget{pdb: b}
+	}
+	for _, o := range targetObjs {
+		if o.kind != "PodDisruptionBudget" {
+			continue
+		}
+		var pdb policyv1.PodDisruptionBudget
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(o.obj, &pdb); err != nil {
+			return nil, errors.Wrapf(err, "invalid PodDisruptionBudget %s", o.name)
+		}
+		pdb.Namespace = o.namespace
+		allBudgets[o.namespace+"/"+o.name] = budget{pdb: pdb, desired: true}
+	}
+	budgetNames := make([]string, 0, len(allBudgets))
+	for k := range allBudgets {
+		budgetNames = append(budgetNames, k)
+	}
+	sort.Strings(budgetNames)
+
+	report := &ImpactReport{}
+	for _, key := range sortedImpactKeys(targetObjs) {
+		t := targetObjs[key]
+		c, ok := currentObjs[key]
+		if !ok {
+			report.Resources = append(report.Resources, t.impact(ImpactCreate))
+			continue
+		}
+		// Fields the target ignores are left alone by upgrades, so they do not
+		// change.
+		ignored, err := ignoredFields(&unstructured.Unstructured{Object: t.obj})
+		if err != nil {
+			return nil, err
+		}
+		cobj, tobj := withoutFields(c.obj, ignored), withoutFields(t.obj, ignored)
+		changes := changedPaths(cobj, tobj, "")
+		if len(changes) == 0 {
+			continue
+		}
+
+		w, err := t.workload()
+		if err != nil {
+			return nil, err
+		}
+		if w == nil {
+			ri := t.impact(ImpactInPlace)
+			ri.Changes = limitChanges(changes)
+			report.Resources = append(report.Resources, ri)
+			continue
+		}
+		ctemplate, _ := nestedMap(cobj, "spec", "template")
+		ttemplate, _ := nestedMap(tobj, "spec", "template")
+		templateChanges := changedPaths(ctemplate, ttemplate, "spec.template")
+		if len(templateChanges) == 0 || w.note != "" {
+			ri := t.impact(ImpactInPlace)
+			ri.Changes = limitChanges(changes)
+			if len(templateChanges) > 0 {
+				ri.Note = w.note
+			}
+			report.Resources = append(report.Resources, ri)
+			continue
+		}
+
+		ri := t.impact(ImpactRollout)
+		ri.Changes = limitChanges(templateChanges)
+		ri.Replicas = w.replicas
+		ri.MaxUnavailable = w.maxUnavailable
+		podLabels := labels.Set(w.podLabels)
+		for _, name := range budgetNames {
+			b := allBudgets[name]
+			if b.pdb.Namespace != t.namespace || b.pdb.Spec.Selector == nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(b.pdb.Spec.Selector)
+			if err != nil || selector.Empty() || !selector.Matches(podLabels) {
+				continue
+			}
+			allowed := b.pdb.Status.DisruptionsAllowed
+			if b.desired {
+				allowed = allowedDisruptions(b.pdb.Spec, w.replicas)
+			}
+			ri.Budgets = append(ri.Budgets, BudgetImpact{
+				Name:               b.pdb.Name,
+				AllowedDisruptions: allowed,
+				Permits:            w.maxUnavailable <= allowed,
+			})
+		}
+		report.Resources = append(report.Resources, ri)
+	}
+
+	for _, key := range sortedImpactKeys(currentObjs) {
+		if _, ok := targetObjs[key]; !ok {
+			report.Resources = append(report.Resources, currentObjs[key].impact(ImpactDelete))
+		}
+	}
+	return report, nil
+}
+
+// impactObject is a resource of a manifest.
+type impactObject struct {
+	kind, name, namespace string
+	obj                   map[string]interface{}
+}
+
+func (o impactObject) impact(action ImpactAction) ResourceImpact {
+	return ResourceImpact{Kind: o.kind, Name: o.name, Namespace: o.namespace, Action: action}
+}
+
+// impactWorkload is the part of a workload relevant to its rollouts.
+type impactWorkload struct {
+	podLabels      map[string]string
+	replicas       int32
+	maxUnavailable int32
+	// note is set if changes to the pod template do not roll out.
+	note string
+}
+
+// workload returns the workload of a Deployment, StatefulSet or DaemonSet,
+// or nil for other resources.
+func (o impactObject) workload() (*impactWorkload, error) {
+	w := &impactWorkload{}
+	var err error
+	switch o.kind {
+	case "Deployment":
+		var d appsv1.Deployment
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(o.obj, &d); err != nil {
+			break
+		}
+		w.podLabels, w.replicas = d.Spec.Template.Labels, replicasOrDefault(d.Spec.Replicas)
+		if d.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType {
+			w.maxUnavailable = w.replicas
+			break
+		}
+		maxUnavailable := intstr.FromString("25%")
+		if ru := d.Spec.Strategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil {
+			maxUnavailable = *ru.MaxUnavailable
+		}
+		w.maxUnavailable = scaledValue(maxUnavailable, w.replicas, false)
+	case "StatefulSet":
+		var s appsv1.StatefulSet
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(o.obj, &s); err != nil {
+			break
+		}
+		w.podLabels, w.replicas = s.Spec.Template.Labels, replicasOrDefault(s.Spec.Replicas)
+		if s.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+			w.note = "pods are only replaced when deleted (OnDelete update strategy)"
+		}
+		w.maxUnavailable = 1
+		if ru := s.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil {
+			w.maxUnavailable = scaledValue(*ru.MaxUnavailable, w.replicas, false)
+		}
+	case "DaemonSet":
+		var d appsv1.DaemonSet
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(o.obj, &d); err != nil {
+			break
+		}
+		w.podLabels = d.Spec.Template.Labels
+		if d.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+			w.note = "pods are only replaced when deleted (OnDelete update strategy)"
+		}
+		w.maxUnavailable = 1
+		if ru := d.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil && ru.MaxUnavailable.Type == intstr.Int {
+			w.maxUnavailable = ru.MaxUnavailable.IntVal
+		}
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s %s", o.kind, o.name)
+	}
+	if w.maxUnavailable < 1 {
+		w.maxUnavailable = 1
+	}
+	return w, nil
+}
+
+// impactObjects parses the resources of a manifest, keyed by kind,
+// namespace and name.
+func impactObjects(manifest, namespace string) (map[string]impactObject, error) {
+	objs := map[string]impactObject{}
+	for _, m := range releaseutil.SplitManifests(manifest) {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(m), &obj); err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			continue
+		}
+		o := impactObject{obj: obj}
+		o.kind, _ = obj["kind"].(string)
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			o.name, _ = metadata["name"].(string)
+			o.namespace, _ = metadata["namespace"].(string)
+		}
+		if o.namespace == "" {
+			o.namespace = namespace
+		}
+		objs[o.kind+"/"+o.namespace+"/"+o.name] = o
+	}
+	return objs, nil
+}
+
+func sortedImpactKeys(objs map[string]impactObject) []string {
+	keys := make([]string, 0, len(objs))
+	for k := range objs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// changedPaths returns the paths of the fields that differ between two
+// values. Lists of objects with names, such as containers, are compared by
+// name.
+func changedPaths(a, b interface{}, path string) []string {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if aok && bok {
+		keys := map[string]bool{}
+		for k := range am {
+			keys[k] = true
+		}
+		for k := range bm {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		var paths []string
+		for _, k := range sorted {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			paths = append(paths, changedPaths(am[k], bm[k], p)...)
+		}
+		return paths
+	}
+
+	al, aok := a.([]interface{})
+	bl, bok := b.([]interface{})
+	if aok && bok {
+		an, bn := namedItems(al), namedItems(bl)
+		if an != nil && bn != nil && len(an) == len(al) && len(bn) == len(bl) {
+			var paths []string
+			for _, name := range sortedItemNames(an, bn) {
+				paths = append(paths, changedPaths(an[name], bn[name], fmt.Sprintf("%s[%s]", path, name))...)
+			}
+			return paths
+		}
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+	return []string{path}
+}
+
+// namedItems returns the items of a list of objects by name, or nil if the
+// list is empty.
+func namedItems(l []interface{}) map[string]interface{} {
+	if len(l) == 0 {
+		return nil
+	}
+	items := map[string]interface{}{}
+	for _, i := range l {
+		if m, ok := i.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				items[name] = m
+			}
+		}
+	}
+	return items
+}
+
+func sortedItemNames(a, b map[string]interface{}) []string {
+	names := map[string]bool{}
+	for k := range a {
+		names[k] = true
+	}
+	for k := range b {
+		names[k] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for k := range names {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func limitChanges(changes []string) []string {
+	if len(changes) > maxImpactChanges {
+		return append(changes[:maxImpactChanges:maxImpactChanges], fmt.Sprintf("and %d more", len(changes)-maxImpactChanges))
+	}
+	return changes
+}
+
+func nestedMap(obj map[string]interface{}, fields ...string) (map[string]interface{}, bool) {
+	for _, f := range fields {
+		next, ok := obj[f].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		obj = next
+	}
+	return obj, true
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+func scaledValue(v intstr.IntOrString, total int32, roundUp bool) int32 {
+	n, err := intstr.GetScaledValueFromIntOrPercent(&v, int(total), roundUp)
+	if err != nil {
+		return 0
+	}
+	return int32(n)
+}
+
+// allowedDisruptions returns the number of pods of a workload with the
+// given replicas that a budget allows to be unavailable.
+func allowedDisruptions(spec policyv1.PodDisruptionBudgetSpec, replicas int32) int32 {
+	var allowed int32
+	switch {
+	case spec.MaxUnavailable != nil:
+		allowed = scaledValue(*spec.MaxUnavailable, replicas, true)
+	case spec.MinAvailable != nil:
+		allowed = replicas - scaledValue(*spec.MinAvailable, replicas, true)
+	}
+	if allowed < 0 {
+		return 0
+	}
+	return allowed
+}
+
+// clusterBudgets returns the disruption budgets in the namespace, or none if
+// they cannot be listed.
+func (cfg *Configuration) clusterBudgets(namespace string) []policyv1.PodDisruptionBudget {
+	if cfg.RESTClientGetter == nil {
+		return nil
+	}
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		cfg.Log("unable to list disruption budgets: %s", err)
+		return nil
+	}
+	list, err := client.PolicyV1().PodDisruptionBudgets(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		cfg.Log("unable to list disruption budgets: %s", err)
+		return nil
+	}
+	return list.Items
+}
+
+// String formats the report for people to review.
+func (r *ImpactReport) String() string {
+	if len(r.Resources) == 0 {
+		return "no resources change\n"
+	}
+	var b strings.Builder
+	for _, ri := range r.Resources {
+		fmt.Fprintf(&b, "%-9s %s/%s", strings.ToUpper(string(ri.Action)), ri.Kind, ri.Name)
+		if ri.Action == ImpactRollout {
+			if ri.Replicas > 0 {
+				fmt.Fprintf(&b, " (%d replicas, up to %d unavailable)", ri.Replicas, ri.MaxUnavailable)
+			} else {
+				fmt.Fprintf(&b, " (up to %d unavailable)", ri.MaxUnavailable)
+			}
+		}
+		b.WriteString("\n")
+		if ri.Note != "" {
+			fmt.Fprintf(&b, "          note: %s\n", ri.Note)
+		}
+		for _, c := range ri.Changes {
+			fmt.Fprintf(&b, "          changed: %s\n", c)
+		}
+		for _, bi := range ri.Budgets {
+			verdict := "permits the rollout"
+			if !bi.Permits {
+				verdict = "does not permit the rollout"
+			}
+			fmt.Fprintf(&b, "          PodDisruptionBudget/%s allows %d unavailable: %s\n", bi.Name, bi.AllowedDisruptions, verdict)
+		}
+	}
+	return b.String()
+}
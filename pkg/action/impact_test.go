@@ -0,0 +1,292 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+const impactCurrent = `---
+# Source: app/templates/web.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: web:1.0
+      - name: sidecar
+        image: proxy:1.0
+---
+# Source: app/templates/worker.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  replicas: 2
+  template:
+    metadata:
+      labels:
+        app: worker
+    spec:
+      containers:
+      - name: worker
+        image: worker:1.0
+---
+# Source: app/templates/db.yaml
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  updateStrategy:
+    type: OnDelete
+  template:
+    spec:
+      containers:
+      - name: db
+        image: db:1.0
+---
+# Source: app/templates/config.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: old
+---
+# Source: app/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: old
+`
+
+const impactTarget = `---
+# Source: app/templates/web.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: web:2.0
+      - name: sidecar
+        image: proxy:1.0
+---
+# Source: app/templates/worker.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  replicas: 4
+  strategy:
+    type: RollingUpdate
+    rollingUpdate:
+      maxUnavailable: 2
+  template:
+    metadata:
+      labels:
+        app: worker
+    spec:
+      containers:
+      - name: worker
+        image: worker:1.0
+---
+# Source: app/templates/db.yaml
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  updateStrategy:
+    type: OnDelete
+  template:
+    spec:
+      containers:
+      - name: db
+        image: db:2.0
+---
+# Source: app/templates/config.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: new
+---
+# Source: app/templates/pdb.yaml
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+spec:
+  minAvailable: 3
+  selector:
+    matchLabels:
+      app: web
+`
+
+func TestAnalyzeImpact(t *testing.T) {
+	clusterBudget := policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "spaced"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+			Selector:       &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpExists}}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+	}
+
+	report, err := AnalyzeImpact(impactCurrent, impactTarget, "spaced", []policyv1.PodDisruptionBudget{clusterBudget})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []ResourceImpact{{
+		Kind: "ConfigMap", Name: "config", Namespace: "spaced", Action: ImpactInPlace,
+		Changes: []string{"data.key"},
+	}, {
+		Kind: "Deployment", Name: "web", Namespace: "spaced", Action: ImpactRollout,
+		Changes:        []string{"spec.template.spec.containers[web].image"},
+		Replicas:       3,
+		MaxUnavailable: 1,
+		Budgets: []BudgetImpact{
+			{Name: "all", AllowedDisruptions: 1, Permits: true},
+			{Name: "web", AllowedDisruptions: 0, Permits: false},
+		},
+	}, {
+		Kind: "Deployment", Name: "worker", Namespace: "spaced", Action: ImpactInPlace,
+		Changes: []string{"spec.replicas", "spec.strategy"},
+	}, {
+		Kind: "PodDisruptionBudget", Name: "web", Namespace: "spaced", Action: ImpactCreate,
+	}, {
+		Kind: "StatefulSet", Name: "db", Namespace: "spaced", Action: ImpactInPlace,
+		Changes: []string{"spec.template.spec.containers[db].image"},
+		Note:    "pods are only replaced when deleted (OnDelete update strategy)",
+	}, {
+		Kind: "Secret", Name: "old", Namespace: "spaced", Action: ImpactDelete,
+	}}
+	if !reflect.DeepEqual(report.Resources, expect) {
+		t.Errorf("expected\n%+v\ngot\n%+v", expect, report.Resources)
+	}
+
+	rollouts := report.Rollouts()
+	if len(rollouts) != 1 || rollouts[0].Name != "web" || !rollouts[0].Blocked() {
+		t.Errorf("expected the blocked rollout of web, got %+v", rollouts)
+	}
+
+	expectText := `IN-PLACE  ConfigMap/config
+          changed: data.key
+ROLLOUT   Deployment/web (3 replicas, up to 1 unavailable)
+          changed: spec.template.spec.containers[web].image
+          PodDisruptionBudget/all allows 1 unavailable: permits the rollout
+          PodDisruptionBudget/web allows 0 unavailable: does not permit the rollout
+IN-PLACE  Deployment/worker
+          changed: spec.replicas
+          changed: spec.strategy
+CREATE    PodDisruptionBudget/web
+IN-PLACE  StatefulSet/db
+          note: pods are only replaced when deleted (OnDelete update strategy)
+          changed: spec.template.spec.containers[db].image
+DELETE    Secret/old
+`
+	if got := report.String(); got != expectText {
+		t.Errorf("expected\n%s\ngot\n%s", expectText, got)
+	}
+}
+
+func TestAllowedDisruptions(t *testing.T) {
+	pct := func(s string) *intstr.IntOrString { v := intstr.FromString(s); return &v }
+	num := func(i int) *intstr.IntOrString { v := intstr.FromInt32(int32(i)); return &v }
+	for _, tt := range []struct {
+		spec     policyv1.PodDisruptionBudgetSpec
+		replicas int32
+		expect   int32
+	}{
+		{policyv1.PodDisruptionBudgetSpec{MinAvailable: num(2)}, 3, 1},
+		{policyv1.PodDisruptionBudgetSpec{MinAvailable: num(5)}, 3, 0},
+		{policyv1.PodDisruptionBudgetSpec{MinAvailable: pct("50%")}, 5, 2},
+		{policyv1.PodDisruptionBudgetSpec{MaxUnavailable: pct("50%")}, 5, 3},
+		{policyv1.PodDisruptionBudgetSpec{MaxUnavailable: num(1)}, 5, 1},
+	} {
+		if got := allowedDisruptions(tt.spec, tt.replicas); got != tt.expect {
+			t.Errorf("expected %d disruptions allowed by %+v for %d replicas, got %d", tt.expect, tt.spec, tt.replicas, got)
+		}
+	}
+}
+
+func TestUpgradeRelease_ImpactReview(t *testing.T) {
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "impact"
+	rel.Info.Status = release.StatusDeployed
+	upAction.cfg.Releases.Create(rel)
+
+	var report *ImpactReport
+	upAction.ImpactReview = func(r *ImpactReport) error {
+		report = r
+		return errors.New("rejected")
+	}
+	ch := buildChart(withSampleSecret())
+	_, err := upAction.Run(rel.Name, ch, map[string]interface{}{})
+	if err == nil || err.Error() != "rejected" {
+		t.Fatalf("expected the review to abort the upgrade, got %v", err)
+	}
+	if report == nil {
+		t.Fatal("expected the impact to be reviewed")
+	}
+	var created bool
+	for _, r := range report.Resources {
+		created = created || (r.Kind == "Secret" && r.Action == ImpactCreate)
+	}
+	if !created {
+		t.Errorf("expected the Secret to be created, got %+v", report.Resources)
+	}
+	if last, _ := upAction.cfg.Releases.Last(rel.Name); last.Version != rel.Version {
+		t.Error("expected no new revision to be recorded")
+	}
+}
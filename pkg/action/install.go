@@ -34,15 +34,16 @@ import (
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/resource"
 	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/errcode"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
@@ -96,12 +97,38 @@ type Install struct {
 	HideNotes                bool
 	DisableOpenAPIValidation bool
 	IncludeCRDs              bool
-	Labels                   map[string]string
+	// ExcludeCRDs leaves all CRDs out of the rendered manifest, including
+	// those rendered from templates. It requires a dry-run mode.
+	ExcludeCRDs bool
+	// OnlyCRDs only renders the CRDs of the crds/ directory and templates. It
+	// requires a dry-run mode.
+	OnlyCRDs bool
+	Labels   map[string]string
+	// LabelResources stamps the release name, revision, chart and manager as
+	// labels on all resources of the release.
+	LabelResources bool
+	// DeployedBy describes who performs the operation, e.g. a person or a
+	// pipeline. It is recorded in the release along with the cluster user.
+	DeployedBy string
+	// StrictDeprecations fails the install of a deprecated chart, or of a
+	// chart past its end of life.
+	StrictDeprecations bool
+	// ManifestOutput is where the resources applied for the release are
+	// written to once it is deployed: a single file if it has a .yaml or .yml
+	// extension, a directory with a file per resource otherwise.
+	ManifestOutput string
+	// OverrideFreeze proceeds during an active freeze window of the freeze
+	// policy of the configuration, recording the override in the audit log.
+	OverrideFreeze bool
 	// KubeVersion allows specifying a custom kubernetes version to use and
 	// APIVersions allows a manual set of supported API Versions to be passed
 	// (for things like templating). These are ignored if ClientOnly is false
 	KubeVersion *chartutil.KubeVersion
 	APIVersions chartutil.VersionSet
+	// Capabilities replaces the default capabilities used when ClientOnly is
+	// true, e.g. with those of a snapshot of a cluster. KubeVersion and
+	// APIVersions still apply on top of them.
+	Capabilities *chartutil.Capabilities
 	// Used by helm template to render charts with .Release.IsUpgrade. Ignored if Dry-Run is false
 	IsUpgrade bool
 	// Enable DNS lookups when rendering templates
@@ -128,6 +155,7 @@ type ChartPathOptions struct {
 	Username              string // --username
 	Verify                bool   // --verify
 	Version               string // --version
+	Digest                string // --digest
 
 	// registryClient provides a registry client but is not added with
 	// options from a flag
@@ -182,33 +210,11 @@ func (i *Install) installCRDs(crds []chart.CRD) error {
 			return err
 		}
 
-		// If we have already gathered the capabilities, we need to invalidate
-		// the cache so that the new CRDs are recognized. This should only be
-		// the case when an action configuration is reused for multiple actions,
-		// as otherwise it is later loaded by ourselves when getCapabilities
-		// is called later on in the installation process.
-		if i.cfg.Capabilities != nil {
-			discoveryClient, err := i.cfg.RESTClientGetter.ToDiscoveryClient()
-			if err != nil {
-				return err
-			}
-
-			i.cfg.Log("Clearing discovery cache")
-			discoveryClient.Invalidate()
-
-			_, _ = discoveryClient.ServerGroups()
-		}
-
-		// Invalidate the REST mapper, since it will not have the new CRDs
-		// present.
-		restMapper, err := i.cfg.RESTClientGetter.ToRESTMapper()
-		if err != nil {
+		// Invalidate the discovery cache and the REST mapper, since they will
+		// not have the new CRDs present.
+		if err := i.cfg.invalidateDiscovery(); err != nil {
 			return err
 		}
-		if resettable, ok := restMapper.(meta.ResettableRESTMapper); ok {
-			i.cfg.Log("Clearing REST mapper cache")
-			resettable.Reset()
-		}
 	}
 	return nil
 }
@@ -222,11 +228,73 @@ func (i *Install) Run(chrt *chart.Chart, vals map[string]interface{}) (*release.
 	return i.RunWithContext(ctx, chrt, vals)
 }
 
+// mockCluster sets up the configuration to not use the Kube API server, with
+// the capabilities of the Install.
+func (i *Install) mockCluster() {
+	// Add mock objects in here so it doesn't use Kube API server
+	// NOTE(bacongobbler): used for `helm template`
+	i.cfg.Capabilities = chartutil.DefaultCapabilities.Copy()
+	if i.Capabilities != nil {
+		i.cfg.Capabilities = i.Capabilities.Copy()
+	}
+	if i.KubeVersion != nil {
+		i.cfg.Capabilities.KubeVersion = *i.KubeVersion
+	}
+	i.cfg.Capabilities.APIVersions = append(i.cfg.Capabilities.APIVersions, i.APIVersions...)
+	i.cfg.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard}
+
+	mem := driver.NewMemory()
+	mem.SetNamespace(i.Namespace)
+	i.cfg.Releases = storage.Init(mem)
+}
+
+// RenderValues processes the dependencies of a chart and returns the values
+// its templates are rendered with, as Run does in client-only mode. It does
+// not render the chart.
+func (i *Install) RenderValues(chrt *chart.Chart, vals map[string]interface{}) (chartutil.Values, error) {
+	i.mockCluster()
+	vals, err := i.cfg.processAnnotations("install", chrt, i.ReleaseName, i.Namespace, vals, true)
+	if err != nil {
+		return nil, err
+	}
+	depCaps, err := i.cfg.dependencyCapabilities(chrt)
+	if err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessDependenciesWithCapabilities(chrt, vals, depCaps); err != nil {
+		return nil, err
+	}
+	caps, err := i.cfg.getCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	isUpgrade := i.IsUpgrade && i.isDryRun()
+	options := chartutil.ReleaseOptions{
+		Name:      i.ReleaseName,
+		Namespace: i.Namespace,
+		Revision:  1,
+		IsInstall: !isUpgrade,
+		IsUpgrade: isUpgrade,
+	}
+	return chartutil.ToRenderValues(chrt, vals, options, caps)
+}
+
 // Run executes the installation with Context
 //
 // When the task is cancelled through ctx, the function returns and the install
 // proceeds in the background.
 func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals map[string]interface{}) (*release.Release, error) {
+	start := time.Now()
+	rel, err := i.runWithContext(ctx, chrt, vals)
+	i.cfg.observeAction("install", start, err)
+	if !i.ClientOnly && !i.isDryRun() {
+		i.cfg.notifyWebhooks("install", EventInstalled, i.ReleaseName, rel, err)
+		i.cfg.recordAudit("install", i.ReleaseName, i.Namespace, chrt, vals, rel, err)
+	}
+	return rel, err
+}
+
+func (i *Install) runWithContext(ctx context.Context, chrt *chart.Chart, vals map[string]interface{}) (*release.Release, error) {
 	// Check reachability of cluster unless in client-only mode (e.g. `helm template` without `--validate`)
 	if !i.ClientOnly {
 		if err := i.cfg.KubeClient.IsReachable(); err != nil {
@@ -239,11 +307,49 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		return nil, errors.New("Hiding Kubernetes secrets requires a dry-run mode")
 	}
 
+	if i.ExcludeCRDs || i.OnlyCRDs {
+		if !i.isDryRun() {
+			return nil, errors.New("Excluding or only rendering CRDs requires a dry-run mode")
+		}
+		if i.ExcludeCRDs && (i.OnlyCRDs || i.IncludeCRDs) {
+			return nil, errors.New("CRDs cannot be both excluded and included")
+		}
+	}
+
 	if err := i.availableName(); err != nil {
 		return nil, err
 	}
 
-	if err := chartutil.ProcessDependenciesWithMerge(chrt, vals); err != nil {
+	if !i.ClientOnly && !i.isDryRun() {
+		if err := i.cfg.checkFreeze("install", i.ReleaseName, i.Namespace, i.Labels, i.OverrideFreeze); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkDeprecation(chrt, i.StrictDeprecations); err != nil {
+		return nil, err
+	}
+
+	// The chart is hashed as given, before its disabled dependencies are
+	// removed.
+	chartDigest := release.ChartDigest(chrt)
+
+	vals, err := i.cfg.processAnnotations("install", chrt, i.ReleaseName, i.Namespace, vals, i.isDryRun())
+	if err != nil {
+		return nil, err
+	}
+
+	if i.ClientOnly {
+		i.mockCluster()
+	} else if !i.ClientOnly && len(i.APIVersions) > 0 {
+		i.cfg.Log("API Version list given outside of client only mode, this list will be ignored")
+	}
+
+	depCaps, err := i.cfg.dependencyCapabilities(chrt)
+	if err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessDependenciesWithCapabilities(chrt, vals, depCaps); err != nil {
 		return nil, err
 	}
 
@@ -263,23 +369,6 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		}
 	}
 
-	if i.ClientOnly {
-		// Add mock objects in here so it doesn't use Kube API server
-		// NOTE(bacongobbler): used for `helm template`
-		i.cfg.Capabilities = chartutil.DefaultCapabilities.Copy()
-		if i.KubeVersion != nil {
-			i.cfg.Capabilities.KubeVersion = *i.KubeVersion
-		}
-		i.cfg.Capabilities.APIVersions = append(i.cfg.Capabilities.APIVersions, i.APIVersions...)
-		i.cfg.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard}
-
-		mem := driver.NewMemory()
-		mem.SetNamespace(i.Namespace)
-		i.cfg.Releases = storage.Init(mem)
-	} else if !i.ClientOnly && len(i.APIVersions) > 0 {
-		i.cfg.Log("API Version list given outside of client only mode, this list will be ignored")
-	}
-
 	// Make sure if Atomic is set, that wait is set as well. This makes it so
 	// the user doesn't have to specify both
 	i.Wait = i.Wait || i.Atomic
@@ -308,13 +397,12 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 	}
 
 	rel := i.createRelease(chrt, vals, i.Labels)
+	rel.ChartDigest, rel.ValuesDigest = chartDigest, release.ValuesDigest(vals)
 
-	var manifestDoc *bytes.Buffer
-	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.IncludeCRDs, i.PostRenderer, interactWithRemote, i.EnableDNS, i.HideSecret)
-	// Even for errors, attach this if available
-	if manifestDoc != nil {
-		rel.Manifest = manifestDoc.String()
-	}
+	stopProfile := i.cfg.profile("install", PhaseRender)
+	// Even for errors, the manifest is attached if available
+	rel.Hooks, rel.Manifest, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.crdSelection(), i.PostRenderer, interactWithRemote, i.EnableDNS, i.HideSecret)
+	stopProfile()
 	// Check error from render
 	if err != nil {
 		rel.SetStatus(release.StatusFailed, fmt.Sprintf("failed to render resource: %s", err.Error()))
@@ -326,16 +414,28 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 	rel.SetStatus(release.StatusPendingInstall, "Initial install underway")
 
 	var toBeAdopted kube.ResourceList
-	resources, err := i.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), !i.DisableOpenAPIValidation)
+	resources, err := i.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), !i.DisableOpenAPIValidation)
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to build kubernetes objects from release manifest")
+		return nil, fmt.Errorf("%w from release manifest: %w", errBuildObjects, err)
 	}
+	if err := i.cfg.applyMetadataPolicy(nil, resources); err != nil {
+		return nil, err
+	}
+	if rel.ResourceHashes, err = resourceHashes(resources); err != nil {
+		return nil, err
+	}
+	rel.Namespaces = resourceNamespaces(resources, rel.Namespace)
 
 	// It is safe to use "force" here because these are resources currently rendered by the chart.
 	err = resources.Visit(setMetadataVisitor(rel.Name, rel.Namespace, true))
 	if err != nil {
 		return nil, err
 	}
+	if i.LabelResources {
+		if err := resources.Visit(setReleaseLabelsVisitor(rel)); err != nil {
+			return nil, err
+		}
+	}
 
 	// Install requires an extra validation step of checking that resources
 	// don't already exist before we actually create resources. If we continue
@@ -356,6 +456,10 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		return rel, nil
 	}
 
+	if err := i.cfg.checkAccess(resources, rel.Namespace, "create"); err != nil {
+		return nil, errors.Wrap(err, "Unable to continue with install")
+	}
+
 	if i.CreateNamespace {
 		ns := &v1.Namespace{
 			TypeMeta: metav1.TypeMeta{
@@ -391,6 +495,7 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 
 	// Store the release in history before continuing (new in Helm 3). We always know
 	// that this is a create operation.
+	journal(rel, release.JournalCreated)
 	if err := i.cfg.Releases.Create(rel); err != nil {
 		// We could try to recover gracefully here, but since nothing has been installed
 		// yet, this is probably safer than trying to continue when we know storage is
@@ -400,9 +505,14 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 
 	rel, err = i.performInstallCtx(ctx, rel, toBeAdopted, resources)
 	if err != nil {
-		rel, err = i.failRelease(rel, err)
+		return i.failRelease(rel, err)
 	}
-	return rel, err
+	if i.ManifestOutput != "" {
+		if err := writeAppliedManifests(i.ManifestOutput, resources); err != nil {
+			return rel, errors.Wrapf(err, "release %s was installed, but its applied manifests could not be written", rel.Name)
+		}
+	}
+	return rel, nil
 }
 
 func (i *Install) performInstallCtx(ctx context.Context, rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
@@ -413,7 +523,7 @@ func (i *Install) performInstallCtx(ctx context.Context, rel *release.Release, t
 	resultChan := make(chan Msg, 1)
 
 	go func() {
-		rel, err := i.performInstall(rel, toBeAdopted, resources)
+		rel, err := i.performInstall(ctx, rel, toBeAdopted, resources)
 		resultChan <- Msg{rel, err}
 	}()
 	select {
@@ -433,43 +543,52 @@ func (i *Install) isDryRun() bool {
 	return false
 }
 
-func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
+func (i *Install) performInstall(ctx context.Context, rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
+	if err := i.cfg.writeJournal(rel, release.JournalApplying); err != nil {
+		return rel, err
+	}
+
 	var err error
 	// pre-install hooks
 	if !i.DisableHooks {
-		if err := i.cfg.execHook(rel, release.HookPreInstall, i.Timeout); err != nil {
-			return rel, fmt.Errorf("failed pre-install: %s", err)
+		if err := i.cfg.execHook(ctx, rel, release.HookPreInstall, i.Timeout); err != nil {
+			return rel, fmt.Errorf("failed pre-install: %w", err)
 		}
 	}
 
 	// At this point, we can do the install. Note that before we were detecting whether to
 	// do an update, but it's not clear whether we WANT to do an update if the re-use is set
 	// to true, since that is basically an upgrade operation.
+	var result *kube.Result
+	stopProfile := i.cfg.profile("install", PhaseApply)
 	if len(toBeAdopted) == 0 && len(resources) > 0 {
-		_, err = i.cfg.KubeClient.Create(resources)
+		result, err = i.cfg.KubeClient.Create(resources)
 	} else if len(resources) > 0 {
-		_, err = i.cfg.KubeClient.Update(toBeAdopted, resources, i.Force)
+		result, err = i.cfg.KubeClient.Update(toBeAdopted, resources, i.Force)
 	}
+	stopProfile()
+	i.cfg.addWarnings(rel, result)
 	if err != nil {
 		return rel, err
 	}
+	if err := i.cfg.writeJournal(rel, release.JournalApplied); err != nil {
+		return rel, err
+	}
 
 	if i.Wait {
-		if i.WaitForJobs {
-			err = i.cfg.KubeClient.WaitWithJobs(resources, i.Timeout)
-		} else {
-			err = i.cfg.KubeClient.Wait(resources, i.Timeout)
-		}
-		if err != nil {
+		if err := i.cfg.waitForResources("install", resources, i.Timeout, i.WaitForJobs); err != nil {
 			return rel, err
 		}
 	}
 
 	if !i.DisableHooks {
-		if err := i.cfg.execHook(rel, release.HookPostInstall, i.Timeout); err != nil {
-			return rel, fmt.Errorf("failed post-install: %s", err)
+		if err := i.cfg.execHook(ctx, rel, release.HookPostInstall, i.Timeout); err != nil {
+			return rel, fmt.Errorf("failed post-install: %w", err)
 		}
 	}
+	if err := i.cfg.writeJournal(rel, release.JournalCompleted); err != nil {
+		return rel, err
+	}
 
 	if len(i.Description) > 0 {
 		rel.SetStatus(release.StatusDeployed, i.Description)
@@ -522,6 +641,13 @@ func (i *Install) availableName() error {
 	if err := chartutil.ValidateReleaseName(start); err != nil {
 		return errors.Wrapf(err, "release name %q", start)
 	}
+	namePolicy, err := i.cfg.namePolicy()
+	if err != nil {
+		return err
+	}
+	if err := namePolicy.ValidateName(start); err != nil {
+		return err
+	}
 	// On dry run, bail here
 	if i.isDryRun() {
 		return nil
@@ -537,7 +663,22 @@ func (i *Install) availableName() error {
 	if st := rel.Info.Status; i.Replace && (st == release.StatusUninstalled || st == release.StatusFailed) {
 		return nil
 	}
-	return errors.New("cannot re-use a name that is still in use")
+	return errcode.New(errcode.ReleaseNameInUse)
+}
+
+// crdSelection returns the CRDs selected by IncludeCRDs, ExcludeCRDs and
+// OnlyCRDs.
+func (i *Install) crdSelection() crdSelection {
+	switch {
+	case i.OnlyCRDs:
+		return crdsOnly
+	case i.ExcludeCRDs:
+		return crdsExcluded
+	case i.IncludeCRDs:
+		return crdsIncluded
+	default:
+		return crdsTemplated
+	}
 }
 
 // createRelease creates a new release object
@@ -552,6 +693,8 @@ func (i *Install) createRelease(chrt *chart.Chart, rawVals map[string]interface{
 			FirstDeployed: ts,
 			LastDeployed:  ts,
 			Status:        release.StatusUnknown,
+			DeployedBy:    i.cfg.identity(i.DeployedBy),
+			Deprecation:   chrt.Metadata.Deprecation(),
 		},
 		Version: 1,
 		Labels:  labels,
@@ -671,7 +814,7 @@ func (i *Install) NameAndChart(args []string) (string, string, error) {
 	}
 
 	base := filepath.Base(args[0])
-	if base == "." || base == "" {
+	if base == "." || base == "" || base == StdinChart {
 		base = "chart"
 	}
 	// if present, strip out the file extension from the name
@@ -679,7 +822,12 @@ func (i *Install) NameAndChart(args []string) (string, string, error) {
 		base = base[0:idx]
 	}
 
-	return fmt.Sprintf("%s-%d", base, time.Now().Unix()), args[0], nil
+	namePolicy, err := i.cfg.namePolicy()
+	if err != nil {
+		return "", args[0], err
+	}
+	name, err := namePolicy.GenerateName(base)
+	return name, args[0], err
 }
 
 // TemplateName renders a name template, returning the name or an error.
@@ -776,6 +924,11 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 	if c.Verify {
 		dl.Verify = downloader.VerifyAlways
 	}
+	policy, err := downloader.LoadTrustPolicy(settings.TrustPolicy)
+	if err != nil {
+		return "", err
+	}
+	dl.TrustPolicy = policy
 	if c.RepoURL != "" {
 		chartURL, err := repo.FindChartInAuthAndTLSAndPassRepoURL(c.RepoURL, c.Username, c.Password, name, version,
 			c.CertFile, c.KeyFile, c.CaFile, c.InsecureSkipTLSverify, c.PassCredentialsAll, getter.All(settings))
@@ -822,3 +975,59 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 	}
 	return lname, nil
 }
+
+// StdinChart is the chart name that reads the chart archive from stdin.
+const StdinChart = "-"
+
+// IsStreamedChart reports whether the chart name is streamed into the loader
+// rather than located on disk: StdinChart, or an http(s) URL when a digest is
+// given.
+func (c *ChartPathOptions) IsStreamedChart(name string) bool {
+	name = strings.TrimSpace(name)
+	if name == StdinChart {
+		return true
+	}
+	return c.Digest != "" && (strings.HasPrefix(name, "https://") || strings.HasPrefix(name, "http://"))
+}
+
+// LoadStreamedChart loads a chart archive read from in for StdinChart, or
+// fetched from the URL name. The archive is loaded from memory, without
+// writing it to a temporary file. A URL requires Digest to be set, and when
+// it is set the archive must match it.
+func (c *ChartPathOptions) LoadStreamedChart(name string, in io.Reader, settings *cli.EnvSettings) (*chart.Chart, error) {
+	if c.Verify {
+		return nil, errors.New("--verify is not supported for charts read from stdin or a URL, use --digest instead")
+	}
+
+	name = strings.TrimSpace(name)
+	if name != StdinChart {
+		if c.Digest == "" {
+			return nil, errors.Errorf("a digest is required to stream the chart %q", name)
+		}
+		u, err := url.Parse(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid chart URL %q", name)
+		}
+		g, err := getter.All(settings).ByScheme(u.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		data, err := g.Get(name,
+			getter.WithURL(name),
+			getter.WithBasicAuth(c.Username, c.Password),
+			getter.WithPassCredentialsAll(c.PassCredentialsAll),
+			getter.WithTLSClientConfig(c.CertFile, c.KeyFile, c.CaFile),
+			getter.WithInsecureSkipVerifyTLS(c.InsecureSkipTLSverify),
+			getter.WithPlainHTTP(c.PlainHTTP),
+		)
+		if err != nil {
+			return nil, err
+		}
+		in = data
+	}
+
+	if c.Digest == "" {
+		return loader.LoadArchive(in)
+	}
+	return loader.LoadArchiveDigest(in, c.Digest)
+}
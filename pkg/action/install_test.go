@@ -30,6 +30,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"helm.sh/helm/v3/internal/test"
 	"helm.sh/helm/v3/pkg/chart"
@@ -295,6 +296,49 @@ func TestInstallRelease_DryRunHiddenSecret(t *testing.T) {
 	}
 }
 
+func TestInstallRelease_DryRunCRDs(t *testing.T) {
+	is := assert.New(t)
+	withCRDs := func(opts *chartOptions) {
+		opts.Templates = append(opts.Templates, &chart.File{
+			Name: "templates/crd.yaml",
+			Data: []byte("apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: templated\n"),
+		})
+		opts.Files = append(opts.Files, &chart.File{
+			Name: "crds/crd.yaml",
+			Data: []byte("apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: bundled\n"),
+		})
+	}
+
+	instAction := installAction(t)
+	instAction.DryRun = true
+	instAction.OnlyCRDs = true
+	res, err := instAction.Run(buildChart(withCRDs), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+	is.Contains(res.Manifest, "name: templated")
+	is.Contains(res.Manifest, "name: bundled")
+	is.NotContains(res.Manifest, "hello: world")
+	is.Len(res.Hooks, 0)
+
+	instAction = installAction(t)
+	instAction.DryRun = true
+	instAction.ExcludeCRDs = true
+	res, err = instAction.Run(buildChart(withCRDs), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+	is.NotContains(res.Manifest, "name: templated")
+	is.NotContains(res.Manifest, "name: bundled")
+	is.Contains(res.Manifest, "hello: world")
+	is.Len(res.Hooks, 1)
+
+	// Ensure there is an error when selecting CRDs outside of a dry-run mode
+	instAction.DryRun = false
+	_, err = instAction.Run(buildChart(withCRDs), map[string]interface{}{})
+	is.Error(err)
+}
+
 // Regression test for #7955
 func TestInstallRelease_DryRun_Lookup(t *testing.T) {
 	is := assert.New(t)
@@ -362,6 +406,68 @@ func TestInstallRelease_FailedHooks(t *testing.T) {
 	is.Equal(release.StatusFailed, res.Info.Status)
 }
 
+func TestInstallRelease_HookCreateFailure(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ReleaseName = "hook-create-failure"
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	failer.Failures = []kubefake.Failure{{
+		Verb: kubefake.VerbCreate,
+		GVK:  schema.GroupVersionKind{Kind: "ConfigMap"},
+		Name: "test-cm",
+		Err:  fmt.Errorf("exceeded quota"),
+	}}
+
+	chrt := buildChart()
+	chrt.Templates[0].Data = []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: hello")
+	res, err := instAction.Run(chrt, map[string]interface{}{})
+	is.ErrorContains(err, "exceeded quota")
+	is.Equal(release.StatusFailed, res.Info.Status)
+
+	// The resources of the release are created before the post-install hook.
+	creates := failer.OperationsOf(kubefake.VerbCreate)
+	is.Len(creates, 2)
+	is.NoError(creates[0].Err)
+	is.Error(creates[1].Err)
+	is.Equal("test-cm", creates[1].Resources[0].Name)
+	is.Empty(failer.OperationsOf(kubefake.VerbWatchUntilReady))
+}
+
+func TestInstallRelease_Warnings(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.Warnings = []string{`Deployment "web": apps/v1beta1 Deployment is deprecated`}
+	instAction.cfg.KubeClient = failer
+
+	vals := map[string]interface{}{}
+	res, err := instAction.Run(buildChart(), vals)
+	is.NoError(err)
+	// The warnings of the hooks and of the resources are only recorded once.
+	is.Equal([]string{`Deployment "web": apps/v1beta1 Deployment is deprecated`}, res.Info.Warnings)
+
+	rel, err := instAction.cfg.Releases.Get(res.Name, res.Version)
+	is.NoError(err)
+	is.Equal(res.Info.Warnings, rel.Info.Warnings)
+}
+
+func TestInstallRelease_StrictDeprecations(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+
+	chrt := buildChart()
+	chrt.Metadata.Annotations = map[string]string{chart.AnnotationEndOfLife: "2000-01-01"}
+	res, err := instAction.Run(chrt, map[string]interface{}{})
+	is.NoError(err)
+	is.Equal(&chart.Deprecation{EndOfLife: "2000-01-01"}, res.Info.Deprecation)
+
+	instAction = installAction(t)
+	instAction.StrictDeprecations = true
+	_, err = instAction.Run(chrt, map[string]interface{}{})
+	is.EqualError(err, "chart hello-0.1.0: This chart reached its end of life on 2000-01-01")
+}
+
 func TestInstallRelease_ReplaceRelease(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
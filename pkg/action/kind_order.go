@@ -0,0 +1,94 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// KindOrder configures the order in which the resources of releases are
+// installed and uninstalled by kind, placing kinds unknown to Helm, or moving
+// known ones, relative to other kinds, e.g.:
+//
+//	kinds:
+//	- kind: Issuer
+//	  after: Secret
+//	- kind: Certificate
+//	  after: Issuer
+//
+// Kinds are uninstalled in the opposite order they are installed in: a kind
+// placed after another one when installing is placed before it when
+// uninstalling.
+type KindOrder struct {
+	Kinds []releaseutil.KindPlacement `json:"kinds"`
+}
+
+// LoadKindOrder reads the kind order of the given YAML file. A missing file
+// configures the default order.
+func LoadKindOrder(path string) (*KindOrder, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o, err := ParseKindOrder(data)
+	return o, errors.Wrapf(err, "invalid kind order %s", path)
+}
+
+// ParseKindOrder parses and validates a kind order.
+func ParseKindOrder(data []byte) (*KindOrder, error) {
+	var o KindOrder
+	if err := yaml.UnmarshalStrict(data, &o); err != nil {
+		return nil, err
+	}
+	if _, err := o.InstallOrder(); err != nil {
+		return nil, err
+	}
+	if _, err := o.UninstallOrder(); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// InstallOrder returns the order in which resources are installed by kind,
+// releaseutil.InstallOrder if the kind order is nil.
+func (o *KindOrder) InstallOrder() (releaseutil.KindSortOrder, error) {
+	if o == nil {
+		return releaseutil.InstallOrder, nil
+	}
+	return releaseutil.InstallOrder.Place(o.Kinds...)
+}
+
+// UninstallOrder returns the order in which resources are uninstalled by
+// kind, releaseutil.UninstallOrder if the kind order is nil.
+func (o *KindOrder) UninstallOrder() (releaseutil.KindSortOrder, error) {
+	if o == nil {
+		return releaseutil.UninstallOrder, nil
+	}
+	placements := make([]releaseutil.KindPlacement, len(o.Kinds))
+	for i, p := range o.Kinds {
+		placements[i] = releaseutil.KindPlacement{Kind: p.Kind, Before: p.After, After: p.Before}
+	}
+	return releaseutil.UninstallOrder.Place(placements...)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+func TestInstallRelease_KindOrder(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	kindOrder, err := ParseKindOrder([]byte("kinds:\n- kind: Issuer\n  before: ConfigMap\n"))
+	req.NoError(err)
+
+	instAction := installAction(t)
+	instAction.cfg.KindOrder = kindOrder
+	chrt := buildChart()
+	chrt.Templates = []*chart.File{
+		{Name: "templates/config.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n")},
+		{Name: "templates/issuer.yaml", Data: []byte("apiVersion: cert-manager.io/v1\nkind: Issuer\nmetadata:\n  name: issuer\n")},
+	}
+	res, err := instAction.Run(chrt, map[string]interface{}{})
+	req.NoError(err)
+	is.Less(strings.Index(res.Manifest, "kind: Issuer"), strings.Index(res.Manifest, "kind: ConfigMap"))
+
+	uninstallOrder, err := kindOrder.UninstallOrder()
+	req.NoError(err)
+	is.Less(indexOf(uninstallOrder, "ConfigMap"), indexOf(uninstallOrder, "Issuer"))
+
+	var defaults *KindOrder
+	installOrder, err := defaults.InstallOrder()
+	req.NoError(err)
+	is.Equal(releaseutil.InstallOrder, installOrder)
+
+	_, err = ParseKindOrder([]byte("kinds:\n- kind: Issuer\n"))
+	is.EqualError(err, `kind "Issuer" must be placed either before or after another kind`)
+	_, err = ParseKindOrder([]byte("kind:\n- kind: Issuer\n"))
+	is.Error(err)
+}
+
+func indexOf(order releaseutil.KindSortOrder, kind string) int {
+	for i, k := range order {
+		if k == kind {
+			return i
+		}
+	}
+	return -1
+}
@@ -22,11 +22,15 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	applycorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 // lazyClient is a workaround to deal with Kubernetes having an unstable client API.
@@ -195,3 +199,115 @@ func (c *configMapClient) Apply(ctx context.Context, configMap *applycorev1.Conf
 	}
 	return c.client.CoreV1().ConfigMaps(c.namespace).Apply(ctx, configMap, opts)
 }
+
+// lazyDynamicClient is the dynamic client counterpart of lazyClient.
+type lazyDynamicClient struct {
+	// client caches an initialized dynamic client
+	initClient sync.Once
+	client     dynamic.Interface
+	clientErr  error
+
+	// clientFn loads a dynamic client
+	clientFn func() (dynamic.Interface, error)
+
+	// namespace passed to each client request
+	namespace string
+}
+
+func (d *lazyDynamicClient) init() error {
+	d.initClient.Do(func() {
+		d.client, d.clientErr = d.clientFn()
+	})
+	return d.clientErr
+}
+
+func (d *lazyDynamicClient) resource() dynamic.ResourceInterface {
+	return d.client.Resource(driver.HelmReleaseGroupVersionResource).Namespace(d.namespace)
+}
+
+// helmReleaseClient implements a dynamic.ResourceInterface for HelmRelease
+// custom resources
+type helmReleaseClient struct{ *lazyDynamicClient }
+
+var _ dynamic.ResourceInterface = (*helmReleaseClient)(nil)
+
+func newHelmReleaseClient(lc *lazyDynamicClient) *helmReleaseClient {
+	return &helmReleaseClient{lazyDynamicClient: lc}
+}
+
+func (h *helmReleaseClient) Create(ctx context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().Create(ctx, obj, opts, subresources...)
+}
+
+func (h *helmReleaseClient) Update(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().Update(ctx, obj, opts, subresources...)
+}
+
+func (h *helmReleaseClient) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().UpdateStatus(ctx, obj, opts)
+}
+
+func (h *helmReleaseClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions, subresources ...string) error {
+	if err := h.init(); err != nil {
+		return err
+	}
+	return h.resource().Delete(ctx, name, opts, subresources...)
+}
+
+func (h *helmReleaseClient) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	if err := h.init(); err != nil {
+		return err
+	}
+	return h.resource().DeleteCollection(ctx, opts, listOpts)
+}
+
+func (h *helmReleaseClient) Get(ctx context.Context, name string, opts metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().Get(ctx, name, opts, subresources...)
+}
+
+func (h *helmReleaseClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().List(ctx, opts)
+}
+
+func (h *helmReleaseClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().Watch(ctx, opts)
+}
+
+func (h *helmReleaseClient) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().Patch(ctx, name, pt, data, opts, subresources...)
+}
+
+func (h *helmReleaseClient) Apply(ctx context.Context, name string, obj *unstructured.Unstructured, opts metav1.ApplyOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().Apply(ctx, name, obj, opts, subresources...)
+}
+
+func (h *helmReleaseClient) ApplyStatus(ctx context.Context, name string, obj *unstructured.Unstructured, opts metav1.ApplyOptions) (*unstructured.Unstructured, error) {
+	if err := h.init(); err != nil {
+		return nil, err
+	}
+	return h.resource().ApplyStatus(ctx, name, obj, opts)
+}
@@ -17,12 +17,14 @@ limitations under the License.
 package action
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
 
+	"helm.sh/helm/v3/pkg/admission"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/lint"
 	"helm.sh/helm/v3/pkg/lint/support"
@@ -37,6 +39,12 @@ type Lint struct {
 	WithSubcharts bool
 	Quiet         bool
 	KubeVersion   *chartutil.KubeVersion
+	// KubeVersions, if set, are the Kubernetes versions the charts are linted
+	// against in turn, instead of KubeVersion. Messages found with some of
+	// the versions only are annotated with them.
+	KubeVersions []*chartutil.KubeVersion
+	// AdmissionPolicies, if set, are evaluated against the rendered templates.
+	AdmissionPolicies *admission.Policies
 }
 
 // LintResult is the result of Lint
@@ -59,15 +67,15 @@ func (l *Lint) Run(paths []string, vals map[string]interface{}) *LintResult {
 	}
 	result := &LintResult{}
 	for _, path := range paths {
-		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion)
+		messages, err := l.lintChart(path, vals)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			continue
 		}
 
-		result.Messages = append(result.Messages, linter.Messages...)
+		result.Messages = append(result.Messages, messages...)
 		result.TotalChartsLinted++
-		for _, msg := range linter.Messages {
+		for _, msg := range messages {
 			if msg.Severity >= lowestTolerance {
 				result.Errors = append(result.Errors, msg.Err)
 			}
@@ -76,6 +84,53 @@ func (l *Lint) Run(paths []string, vals map[string]interface{}) *LintResult {
 	return result
 }
 
+// lintChart lints a chart against KubeVersion, or against each of
+// KubeVersions.
+func (l *Lint) lintChart(path string, vals map[string]interface{}) ([]support.Message, error) {
+	if len(l.KubeVersions) == 0 {
+		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion, l.AdmissionPolicies)
+		return linter.Messages, err
+	}
+
+	// Messages are merged across the versions in the order they are first
+	// found, recording the versions each one was found with.
+	var messages []support.Message
+	found := map[string][]string{}
+	for _, kubeVersion := range l.KubeVersions {
+		linter, err := lintChart(path, vals, l.Namespace, kubeVersion, l.AdmissionPolicies)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range linter.Messages {
+			key := msg.Error()
+			if _, ok := found[key]; !ok {
+				messages = append(messages, msg)
+			}
+			found[key] = append(found[key], kubeVersion.String())
+		}
+	}
+	for i, msg := range messages {
+		if versions := found[msg.Error()]; len(versions) < len(l.KubeVersions) {
+			messages[i].Err = kubeVersionsError{err: msg.Err, versions: versions}
+		}
+	}
+	return messages, nil
+}
+
+// kubeVersionsError is a lint error found with some Kubernetes versions only.
+type kubeVersionsError struct {
+	err      error
+	versions []string
+}
+
+func (e kubeVersionsError) Error() string {
+	return fmt.Sprintf("%s (Kubernetes %s)", e.err, strings.Join(e.versions, ", "))
+}
+
+func (e kubeVersionsError) Unwrap() error {
+	return e.err
+}
+
 // HasWarningsOrErrors checks is LintResult has any warnings or errors
 func HasWarningsOrErrors(result *LintResult) bool {
 	for _, msg := range result.Messages {
@@ -86,7 +141,7 @@ func HasWarningsOrErrors(result *LintResult) bool {
 	return len(result.Errors) > 0
 }
 
-func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) (support.Linter, error) {
+func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion, policies *admission.Policies) (support.Linter, error) {
 	var chartPath string
 	linter := support.Linter{}
 
@@ -125,5 +180,5 @@ func lintChart(path string, vals map[string]interface{}, namespace string, kubeV
 		return linter, errors.Wrap(err, "unable to check Chart.yaml file in chart")
 	}
 
-	return lint.AllWithKubeVersion(chartPath, vals, namespace, kubeVersion), nil
+	return lint.AllWithAdmissionPolicies(chartPath, vals, namespace, kubeVersion, policies), nil
 }
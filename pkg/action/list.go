@@ -19,6 +19,7 @@ package action
 import (
 	"path"
 	"regexp"
+	"time"
 
 	"k8s.io/apimachinery/pkg/labels"
 
@@ -134,6 +135,15 @@ type List struct {
 	Failed       bool
 	Pending      bool
 	Selector     string
+	// Health evaluates the live health of the resources of each listed
+	// release, setting the ResourceStatuses and Health of its info.
+	Health bool
+	// HealthCache is the directory in which the health of releases is
+	// cached, so that repeated listings do not query the cluster each time.
+	HealthCache string
+	// HealthCacheTTL is how long a cached health remains valid. Nothing is
+	// cached if it is zero.
+	HealthCacheTTL time.Duration
 }
 
 // NewList constructs a new *List
@@ -213,6 +223,10 @@ func (l *List) Run() ([]*release.Release, error) {
 	}
 	results = results[l.Offset:last]
 
+	if l.Health {
+		l.evaluateHealth(results)
+	}
+
 	return results, err
 }
 
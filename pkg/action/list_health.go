@@ -0,0 +1,180 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// listHealthConcurrency is the number of releases whose health is evaluated
+// at the same time.
+const listHealthConcurrency = 4
+
+// cachedHealth is the health of a revision of a release in the health
+// cache.
+type cachedHealth struct {
+	Revision         int                      `json:"revision"`
+	Checked          time.Time                `json:"checked"`
+	Health           string                   `json:"health"`
+	ResourceStatuses []release.ResourceStatus `json:"resource_statuses,omitempty"`
+}
+
+// evaluateHealth sets the live health of the releases that have deployed
+// resources.
+func (l *List) evaluateHealth(rels []*release.Release) {
+	sem := make(chan struct{}, listHealthConcurrency)
+	var wg sync.WaitGroup
+	for _, rel := range rels {
+		switch rel.Info.Status {
+		case release.StatusUninstalled, release.StatusUninstalling, release.StatusSuperseded:
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(rel *release.Release) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			l.releaseHealth(rel)
+		}(rel)
+	}
+	wg.Wait()
+}
+
+// releaseHealth sets the health of a release from the cache or the cluster.
+func (l *List) releaseHealth(rel *release.Release) {
+	cacheFile := ""
+	if l.HealthCache != "" && l.HealthCacheTTL > 0 {
+		cacheFile = filepath.Join(l.HealthCache, rel.Namespace, rel.Name+".json")
+		if c, ok := readCachedHealth(cacheFile); ok && c.Revision == rel.Version && time.Since(c.Checked) < l.HealthCacheTTL {
+			rel.Info.Health = c.Health
+			rel.Info.ResourceStatuses = c.ResourceStatuses
+			return
+		}
+	}
+
+	statuses, err := l.cfg.liveResourceStatuses(rel)
+	if err != nil {
+		l.cfg.Log("unable to evaluate the health of release %s: %s", rel.Name, err)
+		rel.Info.Health = release.HealthUnknown
+		return
+	}
+	rel.Info.ResourceStatuses = statuses
+	rel.Info.Health = release.SummarizeHealth(statuses)
+
+	if cacheFile != "" {
+		c := cachedHealth{Revision: rel.Version, Checked: time.Now(), Health: rel.Info.Health, ResourceStatuses: statuses}
+		if err := writeCachedHealth(cacheFile, c); err != nil {
+			l.cfg.Log("unable to cache the health of release %s: %s", rel.Name, err)
+		}
+	}
+}
+
+func readCachedHealth(file string) (cachedHealth, bool) {
+	var c cachedHealth
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return c, false
+	}
+	return c, json.Unmarshal(data, &c) == nil
+}
+
+func writeCachedHealth(file string, c cachedHealth) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// liveResourceStatuses returns the live status of the resources of a
+// release. Resources without a namespace are in the namespace of the
+// release, which need not be the namespace of the client when listing
+// releases across namespaces.
+func (cfg *Configuration) liveResourceStatuses(rel *release.Release) ([]release.ResourceStatus, error) {
+	statusClient, ok := cfg.KubeClient.(kube.InterfaceStatus)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceStatus")
+	}
+
+	var resources kube.ResourceList
+	var err error
+	if objClient, ok := cfg.KubeClient.(kube.InterfaceObjects); ok {
+		var objs []unstructured.Unstructured
+		if objs, err = manifestObjects(rel.Manifest, rel.Namespace); err != nil {
+			return nil, err
+		}
+		resources, err = objClient.BuildFromObjects(objs, false)
+	} else {
+		resources, err = cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := statusClient.Status(resources)
+	if err != nil {
+		return nil, err
+	}
+	return releaseResourceStatuses(statuses), nil
+}
+
+// manifestObjects decodes the resources of a manifest, setting the given
+// namespace on those without one.
+func manifestObjects(manifest, namespace string) ([]unstructured.Unstructured, error) {
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var objs []unstructured.Unstructured
+	for _, k := range keys {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(docs[k]), &obj); err != nil {
+			return nil, errors.Wrap(err, "unable to decode release manifest")
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		u := unstructured.Unstructured{Object: obj}
+		if u.GetNamespace() == "" {
+			u.SetNamespace(namespace)
+		}
+		objs = append(objs, u)
+	}
+	return objs, nil
+}
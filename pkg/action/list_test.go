@@ -17,10 +17,13 @@ limitations under the License.
 package action
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage"
 )
@@ -76,6 +79,42 @@ func TestList_OneNamespace(t *testing.T) {
 	is.Len(list, 3)
 }
 
+func TestList_Health(t *testing.T) {
+	is := assert.New(t)
+	lister := newListFixture(t)
+	makeMeSomeReleases(lister.cfg.Releases, t)
+	failer := lister.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.BuildDummy = true
+	lister.Health = true
+	lister.HealthCache = t.TempDir()
+	lister.HealthCacheTTL = time.Minute
+
+	list, err := lister.Run()
+	is.NoError(err)
+	is.Len(list, 3)
+	for _, rel := range list {
+		is.Equal(release.HealthGreen, rel.Info.Health)
+		is.NotEmpty(rel.Info.ResourceStatuses)
+	}
+	is.Len(failer.OperationsOf(kubefake.VerbStatus), 3)
+
+	// The cached health is used while it is valid.
+	failer.GetError = errors.New("cluster unreachable")
+	list, err = lister.Run()
+	is.NoError(err)
+	for _, rel := range list {
+		is.Equal(release.HealthGreen, rel.Info.Health)
+	}
+	is.Len(failer.OperationsOf(kubefake.VerbStatus), 3)
+
+	lister.HealthCacheTTL = 0
+	list, err = lister.Run()
+	is.NoError(err)
+	for _, rel := range list {
+		is.Equal(release.HealthUnknown, rel.Info.Health)
+	}
+}
+
 func TestList_AllNamespaces(t *testing.T) {
 	is := assert.New(t)
 	lister := newListFixture(t)
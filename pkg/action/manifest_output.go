@@ -0,0 +1,67 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// writeAppliedManifests writes the resources applied for a release, with the
+// labels and annotations Helm sets on them, to path. Paths with a .yaml or
+// .yml extension get all the resources; any other path is a directory that
+// gets a file per resource, in a directory per namespace.
+func writeAppliedManifests(path string, resources kube.ResourceList) error {
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		var b bytes.Buffer
+		for _, r := range resources {
+			data, err := yaml.Marshal(r.Object)
+			if err != nil {
+				return errors.Wrapf(err, "unable to encode %s", r.ObjectName())
+			}
+			b.WriteString("---\n")
+			b.Write(data)
+		}
+		if err := ensureDirectoryForFile(path); err != nil {
+			return err
+		}
+		return os.WriteFile(path, b.Bytes(), 0644)
+	}
+
+	for _, r := range resources {
+		data, err := yaml.Marshal(r.Object)
+		if err != nil {
+			return errors.Wrapf(err, "unable to encode %s", r.ObjectName())
+		}
+		kind := strings.ToLower(r.Object.GetObjectKind().GroupVersionKind().Kind)
+		name := filepath.Join(path, r.Namespace, kind+"-"+r.Name+".yaml")
+		if err := ensureDirectoryForFile(name); err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
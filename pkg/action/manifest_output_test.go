@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+func TestWriteAppliedManifests(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	newResource := func(kind, namespace, name string) *resource.Info {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetLabels(map[string]string{"app.kubernetes.io/managed-by": "Helm"})
+		return &resource.Info{Name: name, Namespace: namespace, Object: obj}
+	}
+	resources := kube.ResourceList{
+		newResource("ConfigMap", "default", "config"),
+		newResource("Namespace", "", "apps"),
+	}
+
+	dir := t.TempDir()
+	req.NoError(writeAppliedManifests(filepath.Join(dir, "out", "manifests.yaml"), resources))
+	data, err := os.ReadFile(filepath.Join(dir, "out", "manifests.yaml"))
+	req.NoError(err)
+	is.Equal(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  labels:
+    app.kubernetes.io/managed-by: Helm
+  name: config
+  namespace: default
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  labels:
+    app.kubernetes.io/managed-by: Helm
+  name: apps
+`, string(data))
+
+	req.NoError(writeAppliedManifests(filepath.Join(dir, "manifests"), resources))
+	data, err = os.ReadFile(filepath.Join(dir, "manifests", "default", "configmap-config.yaml"))
+	req.NoError(err)
+	is.Contains(string(data), "name: config")
+	data, err = os.ReadFile(filepath.Join(dir, "manifests", "namespace-apps.yaml"))
+	req.NoError(err)
+	is.Contains(string(data), "name: apps")
+}
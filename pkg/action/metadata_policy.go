@@ -0,0 +1,306 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// Annotations of Argo CD set by a MetadataPolicy.
+const (
+	argoCDSyncOptionsAnnotation    = "argocd.argoproj.io/sync-options"
+	argoCDCompareOptionsAnnotation = "argocd.argoproj.io/compare-options"
+)
+
+// MetadataPolicy normalizes the labels and annotations of the resources of
+// releases after they are rendered and post-rendered, before they are applied,
+// so that Helm and GitOps tools such as Flux and Argo CD managing the same
+// resources stop fighting over their metadata.
+//
+// The metadata Helm tracks the ownership of resources with, the
+// app.kubernetes.io/managed-by label and the meta.helm.sh annotations, is
+// never changed by a policy.
+type MetadataPolicy struct {
+	Labels      MetadataRules `json:"labels,omitempty"`
+	Annotations MetadataRules `json:"annotations,omitempty"`
+	// ArgoCD adds options to the Argo CD annotations of all resources.
+	ArgoCD *ArgoCDMetadata `json:"argocd,omitempty"`
+}
+
+// MetadataRules normalize the keys of labels or annotations. Keys are matched
+// by patterns as matched by path.Match, e.g. "kustomize.toolkit.fluxcd.io/*".
+type MetadataRules struct {
+	// Strip are the patterns of the keys removed from the rendered resources.
+	// Helm no longer manages them: updates leave their values on the cluster
+	// alone.
+	Strip []string `json:"strip,omitempty"`
+	// Preserve are the patterns of the keys whose values on the cluster are
+	// kept over the rendered ones, e.g. because another tool sets them.
+	Preserve []string `json:"preserve,omitempty"`
+	// Set are added to the rendered resources, replacing the rendered values.
+	Set map[string]string `json:"set,omitempty"`
+}
+
+// ArgoCDMetadata are options of Argo CD added to the annotations of the
+// resources, merged with the options the chart sets.
+type ArgoCDMetadata struct {
+	// SyncOptions are added to the argocd.argoproj.io/sync-options
+	// annotation, e.g. "Prune=false" or "ServerSideApply=true".
+	SyncOptions []string `json:"syncOptions,omitempty"`
+	// CompareOptions are added to the argocd.argoproj.io/compare-options
+	// annotation, e.g. "IgnoreExtraneous".
+	CompareOptions []string `json:"compareOptions,omitempty"`
+}
+
+// LoadMetadataPolicy reads the metadata policy of the given YAML file. A
+// missing file defines no policy.
+func LoadMetadataPolicy(path string) (*MetadataPolicy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseMetadataPolicy(data)
+	return p, errors.Wrapf(err, "invalid metadata policy %s", path)
+}
+
+// ParseMetadataPolicy parses and validates a metadata policy.
+func ParseMetadataPolicy(data []byte) (*MetadataPolicy, error) {
+	var p MetadataPolicy
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, err
+	}
+	if err := p.Labels.validate("labels", true); err != nil {
+		return nil, err
+	}
+	if err := p.Annotations.validate("annotations", false); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r MetadataRules) validate(field string, labels bool) error {
+	for _, patterns := range [][]string{r.Strip, r.Preserve} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.Wrapf(err, "%s: invalid pattern %q", field, pattern)
+			}
+		}
+	}
+	for key, value := range r.Set {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return errors.Errorf("%s: invalid key %q: %s", field, key, strings.Join(errs, "; "))
+		}
+		if labels {
+			if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+				return errors.Errorf("%s: invalid value %q of %s: %s", field, value, key, strings.Join(errs, "; "))
+			}
+		}
+	}
+	return nil
+}
+
+// protectedMetadata are the keys Helm tracks the ownership of resources with.
+var protectedMetadata = map[string]bool{
+	appManagedByLabel:              true,
+	helmReleaseNameAnnotation:      true,
+	helmReleaseNamespaceAnnotation: true,
+}
+
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// strip removes the stripped keys from m.
+func (r MetadataRules) strip(m map[string]string) {
+	for key := range m {
+		if !protectedMetadata[key] && matchesAny(r.Strip, key) {
+			delete(m, key)
+		}
+	}
+}
+
+// preserve replaces the values of the preserved keys of m with those of live,
+// removing the keys live does not have.
+func (r MetadataRules) preserve(m, live map[string]string) {
+	for key := range m {
+		if !protectedMetadata[key] && matchesAny(r.Preserve, key) {
+			if _, ok := live[key]; !ok {
+				delete(m, key)
+			}
+		}
+	}
+	for key, value := range live {
+		if !protectedMetadata[key] && matchesAny(r.Preserve, key) {
+			m[key] = value
+		}
+	}
+}
+
+// normalize strips and sets the keys of m, returning the result.
+func (r MetadataRules) normalize(m map[string]string) map[string]string {
+	if m == nil {
+		m = map[string]string{}
+	}
+	r.strip(m)
+	for key, value := range r.Set {
+		if !protectedMetadata[key] {
+			m[key] = value
+		}
+	}
+	return m
+}
+
+// mergeOptions adds the options missing from a comma-separated list of
+// options, keeping their order.
+func mergeOptions(list string, options []string) string {
+	var merged []string
+	seen := map[string]bool{}
+	for _, o := range strings.Split(list, ",") {
+		if o = strings.TrimSpace(o); o != "" && !seen[o] {
+			seen[o] = true
+			merged = append(merged, o)
+		}
+	}
+	for _, o := range options {
+		if !seen[o] {
+			seen[o] = true
+			merged = append(merged, o)
+		}
+	}
+	return strings.Join(merged, ",")
+}
+
+// hasPreserve tells whether the policy keeps values found on the cluster,
+// which requires getting the live resources.
+func (p *MetadataPolicy) hasPreserve() bool {
+	return len(p.Labels.Preserve) > 0 || len(p.Annotations.Preserve) > 0
+}
+
+// applyMetadataPolicy applies the metadata policy of the configuration to the
+// target resources about to be applied. Stripped keys are also removed from
+// the current resources, the original side of the three-way merge of
+// updates, so that updates leave their values on the cluster alone.
+func (cfg *Configuration) applyMetadataPolicy(current, target kube.ResourceList) error {
+	p, err := cfg.metadataPolicy()
+	if p == nil || err != nil {
+		return err
+	}
+
+	for _, info := range current {
+		if err := p.strip(info); err != nil {
+			return err
+		}
+	}
+	for _, info := range target {
+		if err := p.apply(info); err != nil {
+			return errors.Wrapf(err, "unable to apply the metadata policy to %s", resourceString(info))
+		}
+	}
+	return nil
+}
+
+func (p *MetadataPolicy) strip(info *resource.Info) error {
+	labels, err := accessor.Labels(info.Object)
+	if err != nil {
+		return err
+	}
+	annotations, err := accessor.Annotations(info.Object)
+	if err != nil {
+		return err
+	}
+	p.Labels.strip(labels)
+	p.Annotations.strip(annotations)
+	if err := accessor.SetLabels(info.Object, labels); err != nil {
+		return err
+	}
+	return accessor.SetAnnotations(info.Object, annotations)
+}
+
+func (p *MetadataPolicy) apply(info *resource.Info) error {
+	labels, err := accessor.Labels(info.Object)
+	if err != nil {
+		return err
+	}
+	annotations, err := accessor.Annotations(info.Object)
+	if err != nil {
+		return err
+	}
+	labels = p.Labels.normalize(labels)
+	annotations = p.Annotations.normalize(annotations)
+
+	if p.ArgoCD != nil {
+		for key, options := range map[string][]string{
+			argoCDSyncOptionsAnnotation:    p.ArgoCD.SyncOptions,
+			argoCDCompareOptionsAnnotation: p.ArgoCD.CompareOptions,
+		} {
+			if len(options) > 0 {
+				annotations[key] = mergeOptions(annotations[key], options)
+			}
+		}
+	}
+
+	if p.hasPreserve() && info.Client != nil {
+		live, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+			// New resources are created with the rendered values.
+		case err != nil:
+			return err
+		default:
+			liveLabels, err := accessor.Labels(live)
+			if err != nil {
+				return err
+			}
+			liveAnnotations, err := accessor.Annotations(live)
+			if err != nil {
+				return err
+			}
+			p.Labels.preserve(labels, liveLabels)
+			p.Annotations.preserve(annotations, liveAnnotations)
+		}
+	}
+
+	if err := accessor.SetLabels(info.Object, emptyToNil(labels)); err != nil {
+		return err
+	}
+	return accessor.SetAnnotations(info.Object, emptyToNil(annotations))
+}
+
+func emptyToNil(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
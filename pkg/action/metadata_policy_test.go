@@ -0,0 +1,111 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+func TestParseMetadataPolicy(t *testing.T) {
+	p, err := ParseMetadataPolicy([]byte(`
+labels:
+  strip: ["app.kubernetes.io/instance"]
+annotations:
+  preserve: ["*.fluxcd.io/*"]
+  set:
+    team: platform
+argocd:
+  syncOptions: ["Prune=false"]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.kubernetes.io/instance"}, p.Labels.Strip)
+	assert.Equal(t, []string{"Prune=false"}, p.ArgoCD.SyncOptions)
+
+	for _, invalid := range []string{
+		"labels:\n  strip: [\"[\"]\n",
+		"labels:\n  set:\n    team: \"not valid\"\n",
+		"annotations:\n  set:\n    \"bad key!\": x\n",
+		"unknown: true\n",
+	} {
+		_, err := ParseMetadataPolicy([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMetadataRules(t *testing.T) {
+	rules := MetadataRules{
+		Strip:    []string{"app.kubernetes.io/*"},
+		Preserve: []string{"*.fluxcd.io/*"},
+		Set:      map[string]string{"team": "platform"},
+	}
+	m := rules.normalize(map[string]string{
+		"app.kubernetes.io/instance":   "web",
+		"app.kubernetes.io/managed-by": "Helm",
+		"helm.toolkit.fluxcd.io/name":  "rendered",
+		"stale.fluxcd.io/name":         "rendered",
+	})
+	rules.preserve(m, map[string]string{"helm.toolkit.fluxcd.io/name": "live"})
+	assert.Equal(t, map[string]string{
+		"app.kubernetes.io/managed-by": "Helm",
+		"helm.toolkit.fluxcd.io/name":  "live",
+		"team":                         "platform",
+	}, m)
+
+	assert.Equal(t, "Validate=false,Prune=false", mergeOptions("Validate=false, Prune=false", []string{"Prune=false"}))
+}
+
+func TestInstallRelease_MetadataPolicy(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	instAction.cfg.MetadataPolicy = &MetadataPolicy{
+		Labels: MetadataRules{Strip: []string{"app.kubernetes.io/instance"}},
+		ArgoCD: &ArgoCDMetadata{SyncOptions: []string{"ServerSideApply=true"}},
+	}
+
+	chrt := buildChart()
+	chrt.Templates[0].Data = []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hello
+  labels:
+    app.kubernetes.io/instance: hello
+  annotations:
+    argocd.argoproj.io/sync-options: Prune=false
+`)
+	_, err := instAction.Run(chrt, map[string]interface{}{})
+	is.NoError(err)
+
+	var created *unstructured.Unstructured
+	for _, op := range failer.OperationsOf(kubefake.VerbCreate) {
+		for _, info := range op.Resources {
+			if info.Name == "hello" {
+				created = info.Object.(*unstructured.Unstructured)
+			}
+		}
+	}
+	is.NotNil(created)
+	is.NotContains(created.GetLabels(), "app.kubernetes.io/instance")
+	is.Equal("Helm", created.GetLabels()["app.kubernetes.io/managed-by"])
+	is.Equal("Prune=false,ServerSideApply=true", created.GetAnnotations()["argocd.argoproj.io/sync-options"])
+}
@@ -0,0 +1,292 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"helm.sh/helm/v3/pkg/errcode"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// Failure reasons reported by the helm_action_failures_total metric.
+const (
+	FailureReasonTimeout    = "timeout"
+	FailureReasonHook       = "hook"
+	FailureReasonConflict   = "conflict"
+	FailureReasonInvalid    = "invalid"
+	FailureReasonForbidden  = "forbidden"
+	FailureReasonNotFound   = "not_found"
+	FailureReasonKubernetes = "kubernetes"
+	FailureReasonOther      = "other"
+)
+
+// actionMetrics holds the Prometheus collectors of a Configuration.
+type actionMetrics struct {
+	operations        *prometheus.CounterVec
+	operationDuration *prometheus.HistogramVec
+	failures          *prometheus.CounterVec
+	hookDuration      *prometheus.HistogramVec
+	waitDuration      *prometheus.HistogramVec
+	storageDuration   *prometheus.HistogramVec
+}
+
+// RegisterMetrics registers Prometheus metrics of the actions run with this
+// configuration on reg:
+//
+//   - helm_action_operations_total: actions run by action and result
+//   - helm_action_duration_seconds: duration of actions by action
+//   - helm_action_failures_total: failed actions by action and failure reason
+//   - helm_hook_duration_seconds: duration of hooks by event and phase
+//   - helm_wait_duration_seconds: duration of waits for resources by action and result
+//   - helm_storage_operation_duration_seconds: latency of the release storage by driver, operation and result
+//
+// Metrics are not collected unless registered. To include the storage
+// latency, RegisterMetrics must be called after the release storage is set,
+// e.g. after Init.
+func (cfg *Configuration) RegisterMetrics(reg prometheus.Registerer) error {
+	buckets := []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600}
+	m := &actionMetrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "helm",
+			Subsystem: "action",
+			Name:      "operations_total",
+			Help:      "Number of actions run by action and result.",
+		}, []string{"action", "result"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "helm",
+			Subsystem: "action",
+			Name:      "duration_seconds",
+			Help:      "Duration of actions in seconds.",
+			Buckets:   buckets,
+		}, []string{"action"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "helm",
+			Subsystem: "action",
+			Name:      "failures_total",
+			Help:      "Number of failed actions by action and failure reason.",
+		}, []string{"action", "reason"}),
+		hookDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "helm",
+			Subsystem: "hook",
+			Name:      "duration_seconds",
+			Help:      "Duration of hooks in seconds by event and phase.",
+			Buckets:   buckets,
+		}, []string{"event", "phase"}),
+		waitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "helm",
+			Subsystem: "wait",
+			Name:      "duration_seconds",
+			Help:      "Duration of waits for resources to be ready in seconds.",
+			Buckets:   buckets,
+		}, []string{"action", "result"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "helm",
+			Subsystem: "storage",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of release storage operations in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"driver", "operation", "result"}),
+	}
+	for _, c := range []prometheus.Collector{m.operations, m.operationDuration, m.failures, m.hookDuration, m.waitDuration, m.storageDuration} {
+		if err := reg.Register(c); err != nil {
+			return errors.Wrap(err, "unable to register metrics")
+		}
+	}
+
+	cfg.metrics = m
+	if cfg.Releases != nil {
+		cfg.Releases.Driver = m.instrumentDriver(cfg.Releases.Driver)
+	}
+	return nil
+}
+
+// observeAction records the result and duration of an action.
+func (cfg *Configuration) observeAction(action string, start time.Time, err error) {
+	m := cfg.metrics
+	if m == nil {
+		return
+	}
+	m.operationDuration.WithLabelValues(action).Observe(time.Since(start).Seconds())
+	m.operations.WithLabelValues(action, result(err)).Inc()
+	if err != nil {
+		m.failures.WithLabelValues(action, failureReason(err)).Inc()
+	}
+}
+
+// observeHook records the duration of a hook that ran.
+func (cfg *Configuration) observeHook(event release.HookEvent, h *release.Hook) {
+	if cfg.metrics == nil || h.LastRun.StartedAt.IsZero() || h.LastRun.CompletedAt.IsZero() {
+		return
+	}
+	d := h.LastRun.CompletedAt.Sub(h.LastRun.StartedAt)
+	cfg.metrics.hookDuration.WithLabelValues(event.String(), h.LastRun.Phase.String()).Observe(d.Seconds())
+}
+
+// waitForResources waits for the given resources to be ready, including Jobs
+// if waitForJobs is set, and records the duration of the wait for action.
+func (cfg *Configuration) waitForResources(action string, resources kube.ResourceList, timeout time.Duration, waitForJobs bool) error {
+	start := time.Now()
+	defer cfg.profile(action, PhaseWait)()
+	var err error
+	if waitForJobs {
+		err = cfg.KubeClient.WaitWithJobs(resources, timeout)
+	} else {
+		err = cfg.KubeClient.Wait(resources, timeout)
+	}
+	cfg.observeWait(action, start, err)
+	return err
+}
+
+// observeWait records the duration of a wait for resources.
+func (cfg *Configuration) observeWait(action string, start time.Time, err error) {
+	if cfg.metrics == nil {
+		return
+	}
+	cfg.metrics.waitDuration.WithLabelValues(action, result(err)).Observe(time.Since(start).Seconds())
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// failureReason classifies the error of a failed action.
+func failureReason(err error) string {
+	var hookErr *hookError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), wait.Interrupted(err):
+		return FailureReasonTimeout
+	case errors.As(err, &hookErr):
+		return FailureReasonHook
+	}
+	if reason, ok := codeFailureReasons[errcode.Of(err)]; ok {
+		return reason
+	}
+	switch {
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return FailureReasonConflict
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return FailureReasonInvalid
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return FailureReasonForbidden
+	case apierrors.ReasonForError(err) != "":
+		return FailureReasonKubernetes
+	default:
+		return FailureReasonOther
+	}
+}
+
+// codeFailureReasons are the failure reasons of the errors of the catalog.
+var codeFailureReasons = map[errcode.Code]string{
+	errcode.ReleaseNotFound:         FailureReasonNotFound,
+	errcode.NoDeployedReleases:      FailureReasonNotFound,
+	errcode.ReleaseExists:           FailureReasonConflict,
+	errcode.OperationInProgress:     FailureReasonConflict,
+	errcode.ReleaseNameInUse:        FailureReasonConflict,
+	errcode.ResourceConflict:        FailureReasonConflict,
+	errcode.InvalidManifest:         FailureReasonInvalid,
+	errcode.HookFailed:              FailureReasonHook,
+	errcode.InvalidRevision:         FailureReasonInvalid,
+	errcode.InvalidReleaseName:      FailureReasonInvalid,
+	errcode.ReleaseNameNotAllowed:   FailureReasonInvalid,
+	errcode.MissingRelease:          FailureReasonInvalid,
+	errcode.MissingChart:            FailureReasonInvalid,
+	errcode.IncompatibleKubeVersion: FailureReasonInvalid,
+	errcode.DeprecatedChart:         FailureReasonInvalid,
+	errcode.InvalidValues:           FailureReasonInvalid,
+	errcode.NewerReleaseSchema:      FailureReasonInvalid,
+	errcode.FreezeWindowActive:      FailureReasonForbidden,
+}
+
+// instrumentDriver wraps d to record the latency of its operations.
+func (m *actionMetrics) instrumentDriver(d driver.Driver) driver.Driver {
+	if _, ok := d.(*metricsDriver); ok {
+		return d
+	}
+	return &metricsDriver{Driver: d, duration: m.storageDuration}
+}
+
+// metricsDriver is a storage driver that records the latency of the
+// operations of the driver it wraps.
+type metricsDriver struct {
+	driver.Driver
+	duration *prometheus.HistogramVec
+}
+
+// Unwrap returns the driver d wraps.
+func (d *metricsDriver) Unwrap() driver.Driver { return d.Driver }
+
+func (d *metricsDriver) observe(operation string, start time.Time, err error) {
+	// A missing release is an expected answer of the storage, not a failure.
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		err = nil
+	}
+	d.duration.WithLabelValues(d.Driver.Name(), operation, result(err)).Observe(time.Since(start).Seconds())
+}
+
+func (d *metricsDriver) Create(key string, rls *release.Release) error {
+	start := time.Now()
+	err := d.Driver.Create(key, rls)
+	d.observe("create", start, err)
+	return err
+}
+
+func (d *metricsDriver) Update(key string, rls *release.Release) error {
+	start := time.Now()
+	err := d.Driver.Update(key, rls)
+	d.observe("update", start, err)
+	return err
+}
+
+func (d *metricsDriver) Delete(key string) (*release.Release, error) {
+	start := time.Now()
+	rls, err := d.Driver.Delete(key)
+	d.observe("delete", start, err)
+	return rls, err
+}
+
+func (d *metricsDriver) Get(key string) (*release.Release, error) {
+	start := time.Now()
+	rls, err := d.Driver.Get(key)
+	d.observe("get", start, err)
+	return rls, err
+}
+
+func (d *metricsDriver) List(filter func(*release.Release) bool) ([]*release.Release, error) {
+	start := time.Now()
+	rls, err := d.Driver.List(filter)
+	d.observe("list", start, err)
+	return rls, err
+}
+
+func (d *metricsDriver) Query(labels map[string]string) ([]*release.Release, error) {
+	start := time.Now()
+	rls, err := d.Driver.Query(labels)
+	d.observe("query", start, err)
+	return rls, err
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"helm.sh/helm/v3/pkg/errcode"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	is := assert.New(t)
+	cfg := actionConfigFixture(t)
+	reg := prometheus.NewRegistry()
+	is.NoError(cfg.RegisterMetrics(reg))
+	is.IsType(&metricsDriver{}, cfg.Releases.Driver)
+
+	instAction := installAction(t)
+	instAction.cfg = cfg
+	instAction.Wait = true
+	_, err := instAction.Run(buildChart(), nil)
+	is.NoError(err)
+
+	failing := cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failing.WaitError = wait.ErrorInterrupted(fmt.Errorf("timed out waiting for the condition"))
+	instAction = installAction(t)
+	instAction.cfg = cfg
+	instAction.ReleaseName = "failing"
+	instAction.Wait = true
+	_, err = instAction.Run(buildChart(), nil)
+	is.Error(err)
+
+	is.Equal(1.0, testutil.ToFloat64(cfg.metrics.operations.WithLabelValues("install", "success")))
+	is.Equal(1.0, testutil.ToFloat64(cfg.metrics.operations.WithLabelValues("install", "failure")))
+	is.Equal(1.0, testutil.ToFloat64(cfg.metrics.failures.WithLabelValues("install", FailureReasonTimeout)))
+	is.Equal(2, testutil.CollectAndCount(cfg.metrics.waitDuration))
+	is.Equal(1, testutil.CollectAndCount(cfg.metrics.hookDuration))
+	is.NotZero(testutil.CollectAndCount(cfg.metrics.storageDuration))
+
+	// Registering twice on the same registry fails.
+	is.Error(cfg.RegisterMetrics(reg))
+}
+
+func TestFailureReason(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{context.DeadlineExceeded, FailureReasonTimeout},
+		{errors.Wrap(&hookError{event: release.HookPreInstall, err: context.DeadlineExceeded}, "failed pre-install"), FailureReasonTimeout},
+		{fmt.Errorf("failed pre-install: %w", &hookError{event: release.HookPreInstall, err: errors.New("job failed: BackoffLimitExceeded")}), FailureReasonHook},
+		{errors.New("failed pre-install: job failed: BackoffLimitExceeded"), FailureReasonOther},
+		{errors.Wrap(driver.ErrReleaseNotFound, "upgrade"), FailureReasonNotFound},
+		{errors.Wrap(errPending, "upgrade"), FailureReasonConflict},
+		{errcode.New(errcode.ResourceConflict, "ConfigMap \"foo\"", "invalid ownership metadata"), FailureReasonConflict},
+		{apierrors.NewAlreadyExists(gr, "foo"), FailureReasonConflict},
+		{errcode.New(errcode.InvalidValues, "- foo: bar is required"), FailureReasonInvalid},
+		{fmt.Errorf("%w from release manifest: %w", errBuildObjects, errors.New("no matches for kind")), FailureReasonInvalid},
+		{fmt.Errorf("%w: %w", errcode.New(errcode.HookFailed, "post-install", "hello/templates/notify.yaml"), errors.New("exit status 1")), FailureReasonHook},
+		{errcode.New(errcode.FreezeWindowActive, "upgrade", "foo", "release", ""), FailureReasonForbidden},
+		{apierrors.NewForbidden(gr, "foo", errors.New("denied")), FailureReasonForbidden},
+		{apierrors.NewInternalError(errors.New("boom")), FailureReasonKubernetes},
+		{errors.New("boom"), FailureReasonOther},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, failureReason(tt.err), tt.err.Error())
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// MigrateApplyMethod is the action for switching a release from client-side
+// to server-side apply.
+//
+// It provides the implementation of 'helm release migrate-apply-method'.
+// The ownership of the fields Helm set on the resources of the release is
+// transferred to server-side apply, without changing or recreating the
+// resources. Later upgrades and rollbacks of the release apply its resources
+// server-side.
+type MigrateApplyMethod struct {
+	cfg *Configuration
+}
+
+// NewMigrateApplyMethod creates a new MigrateApplyMethod object with the given configuration.
+func NewMigrateApplyMethod(cfg *Configuration) *MigrateApplyMethod {
+	return &MigrateApplyMethod{
+		cfg: cfg,
+	}
+}
+
+// Run executes 'helm release migrate-apply-method' against the given release.
+func (m *MigrateApplyMethod) Run(name string) (*release.Release, error) {
+	if err := m.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return nil, errors.Errorf("release name is invalid: %s", name)
+	}
+
+	kubeClient, ok := m.cfg.KubeClient.(kube.InterfaceServerSideApply)
+	if !ok {
+		return nil, errors.New("the Kubernetes client does not support server-side apply")
+	}
+
+	rel, err := m.cfg.Releases.Last(name)
+	if err != nil {
+		return nil, err
+	}
+	if rel.Info.Status.IsPending() {
+		return nil, errPending
+	}
+	if err := checkSchema(rel); err != nil {
+		return nil, err
+	}
+	if rel.ApplyMethod == release.ApplyMethodServerSide {
+		return nil, errors.Errorf("release %s already uses server-side apply", name)
+	}
+
+	resources, err := m.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
+	if err != nil {
+		return nil, fmt.Errorf("%w from release manifest: %w", errBuildObjects, err)
+	}
+
+	m.cfg.Log("migrating %d resources of %s to server-side apply", len(resources), name)
+	if len(resources) > 0 {
+		if err := kubeClient.MigrateToServerSideApply(resources); err != nil {
+			return nil, errors.Wrapf(err, "unable to migrate release %s to server-side apply", name)
+		}
+	}
+
+	// The release is only recorded as migrated once all of its resources are,
+	// so that a failed migration can be run again.
+	rel.ApplyMethod = release.ApplyMethodServerSide
+	if err := m.cfg.Releases.Update(rel); err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
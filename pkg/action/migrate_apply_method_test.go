@@ -0,0 +1,71 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestMigrateApplyMethod(t *testing.T) {
+	is := assert.New(t)
+	config := actionConfigFixture(t)
+
+	rel := releaseStub()
+	rel.Name = "migrate"
+	rel.Info.Status = release.StatusDeployed
+	is.NoError(config.Releases.Create(rel))
+
+	migrated, err := NewMigrateApplyMethod(config).Run(rel.Name)
+	is.NoError(err)
+	is.Equal(release.ApplyMethodServerSide, migrated.ApplyMethod)
+	is.Equal(1, migrated.Version)
+
+	_, err = NewMigrateApplyMethod(config).Run(rel.Name)
+	is.EqualError(err, "release migrate already uses server-side apply")
+
+	upAction := NewUpgrade(config)
+	upgraded, err := upAction.RunWithContext(context.Background(), rel.Name, buildChart(), map[string]interface{}{})
+	is.NoError(err)
+	is.Equal(release.ApplyMethodServerSide, upgraded.ApplyMethod)
+}
+
+func TestMigrateApplyMethod_Error(t *testing.T) {
+	is := assert.New(t)
+	config := actionConfigFixture(t)
+	failer := config.KubeClient.(*kubefake.FailingKubeClient)
+	failer.MigrateError = errors.New("conflict")
+	failer.BuildDummy = true
+
+	rel := releaseStub()
+	rel.Name = "migrate"
+	rel.Info.Status = release.StatusDeployed
+	is.NoError(config.Releases.Create(rel))
+
+	_, err := NewMigrateApplyMethod(config).Run(rel.Name)
+	is.EqualError(err, "unable to migrate release migrate to server-side apply: conflict")
+
+	last, err := config.Releases.Last(rel.Name)
+	is.NoError(err)
+	is.Empty(last.ApplyMethod)
+}
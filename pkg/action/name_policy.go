@@ -0,0 +1,181 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/errcode"
+)
+
+// NamePolicy generates the names of the releases installed with
+// --generate-name and validates the names of new releases, e.g. to enforce
+// the naming conventions of a platform. Names must also be valid release
+// names, see chartutil.ValidateReleaseName.
+//
+// Only the names of new releases are validated: releases installed before a
+// policy was set can still be upgraded, rolled back and uninstalled.
+type NamePolicy interface {
+	// GenerateName returns a name for a new release of the chart whose name,
+	// derived from the chart reference, is base.
+	GenerateName(base string) (string, error)
+	// ValidateName returns an error explaining how the name of a new release
+	// violates the policy, or nil if it does not.
+	ValidateName(name string) error
+}
+
+// DefaultNamePolicy suffixes the name of the chart with the current Unix time
+// and accepts any valid release name. It is the policy of a Configuration
+// without a NamePolicy.
+type DefaultNamePolicy struct{}
+
+// GenerateName returns base suffixed with the current Unix time.
+func (DefaultNamePolicy) GenerateName(base string) (string, error) {
+	return fmt.Sprintf("%s-%d", base, time.Now().Unix()), nil
+}
+
+// ValidateName accepts any name.
+func (DefaultNamePolicy) ValidateName(string) error {
+	return nil
+}
+
+// Generators of NameRules.
+const (
+	// NameGeneratorTimestamp suffixes names with the current Unix time.
+	NameGeneratorTimestamp = "timestamp"
+	// NameGeneratorRandom suffixes names with 5 random characters.
+	NameGeneratorRandom = "random"
+)
+
+// NameRules is a NamePolicy read from a file, e.g.:
+//
+//	prefix: team-a-
+//	maxLength: 40
+//	pattern: ^team-a-[a-z]+(-[a-z0-9]+)*$
+//	generator: random
+//
+// Generated names are the prefix, the name of the chart and a suffix, with
+// the name of the chart shortened to respect the maximum length.
+type NameRules struct {
+	// Prefix is the prefix names must start with.
+	Prefix string `json:"prefix,omitempty"`
+	// MaxLength is the maximum length of names, e.g. for a system deriving
+	// longer names from them. Release names are limited to 53 characters
+	// anyway.
+	MaxLength int `json:"maxLength,omitempty"`
+	// Pattern is a regular expression names must match.
+	Pattern string `json:"pattern,omitempty"`
+	// Generator is how generated names are suffixed: NameGeneratorTimestamp,
+	// the default, or NameGeneratorRandom.
+	Generator string `json:"generator,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// LoadNameRules reads the name rules of the given YAML file. A missing file
+// defines no rules.
+func LoadNameRules(path string) (*NameRules, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r, err := ParseNameRules(data)
+	return r, errors.Wrapf(err, "invalid name policy %s", path)
+}
+
+// ParseNameRules parses and validates name rules.
+func ParseNameRules(data []byte) (*NameRules, error) {
+	var r NameRules
+	if err := yaml.UnmarshalStrict(data, &r); err != nil {
+		return nil, err
+	}
+	if r.MaxLength < 0 || r.MaxLength > releaseNameMaxLen {
+		return nil, errors.Errorf("maxLength must be at most %d", releaseNameMaxLen)
+	}
+	if r.MaxLength > 0 && len(r.Prefix) >= r.MaxLength {
+		return nil, errors.Errorf("prefix %q leaves no room for names of at most %d characters", r.Prefix, r.MaxLength)
+	}
+	if r.Pattern != "" {
+		p, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid pattern")
+		}
+		r.pattern = p
+	}
+	switch r.Generator {
+	case "", NameGeneratorTimestamp, NameGeneratorRandom:
+	default:
+		return nil, errors.Errorf("unknown generator %q: must be %s or %s", r.Generator, NameGeneratorTimestamp, NameGeneratorRandom)
+	}
+	return &r, nil
+}
+
+// releaseNameMaxLen is the maximum length of release names, as enforced by
+// chartutil.ValidateReleaseName.
+const releaseNameMaxLen = 53
+
+// GenerateName returns the prefix, base and a suffix joined by dashes. base
+// is shortened for the name to respect the maximum length, and omitted if
+// there is no room left for it.
+func (r *NameRules) GenerateName(base string) (string, error) {
+	suffix := fmt.Sprint(time.Now().Unix())
+	if r.Generator == NameGeneratorRandom {
+		suffix = rand.String(5)
+	}
+
+	maxLen := r.MaxLength
+	if maxLen == 0 {
+		maxLen = releaseNameMaxLen
+	}
+	base = strings.TrimPrefix(base, r.Prefix)
+	if room := maxLen - len(r.Prefix) - len(suffix) - 1; len(base) > room {
+		base = strings.TrimRight(base[:max(room, 0)], "-")
+	}
+	if base == "" {
+		return r.Prefix + suffix, nil
+	}
+	return r.Prefix + base + "-" + suffix, nil
+}
+
+// ValidateName returns an error unless the name starts with the prefix,
+// respects the maximum length and matches the pattern.
+func (r *NameRules) ValidateName(name string) error {
+	violation := func(format string, args ...interface{}) error {
+		return errcode.New(errcode.ReleaseNameNotAllowed, name, fmt.Sprintf(format, args...))
+	}
+	if !strings.HasPrefix(name, r.Prefix) {
+		return violation("must start with %q", r.Prefix)
+	}
+	if r.MaxLength > 0 && len(name) > r.MaxLength {
+		return violation("must be at most %d characters long", r.MaxLength)
+	}
+	if r.pattern != nil && !r.pattern.MatchString(name) {
+		return violation("must match %s", r.Pattern)
+	}
+	return nil
+}
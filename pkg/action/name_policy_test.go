@@ -0,0 +1,115 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v3/pkg/errcode"
+)
+
+func TestParseNameRules(t *testing.T) {
+	r, err := ParseNameRules([]byte(`
+prefix: team-a-
+maxLength: 20
+pattern: ^team-a-[a-z0-9-]+$
+generator: random
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a-", r.Prefix)
+	assert.Equal(t, 20, r.MaxLength)
+
+	for _, invalid := range []string{
+		"maxLength: 54\n",
+		"maxLength: -1\n",
+		"prefix: team-a-\nmaxLength: 7\n",
+		"pattern: \"[\"\n",
+		"generator: uuid\n",
+		"unknown: true\n",
+	} {
+		_, err := ParseNameRules([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestNameRules(t *testing.T) {
+	is := assert.New(t)
+	r, err := ParseNameRules([]byte("prefix: team-a-\nmaxLength: 30\npattern: ^[a-z0-9-]+$\n"))
+	is.NoError(err)
+
+	name, err := r.GenerateName("nginx")
+	is.NoError(err)
+	is.True(strings.HasPrefix(name, "team-a-nginx-"), name)
+	is.NoError(r.ValidateName(name))
+
+	// The name of the chart is shortened to respect the maximum length.
+	name, err = r.GenerateName("a-very-long-chart-name")
+	is.NoError(err)
+	is.True(strings.HasPrefix(name, "team-a-a-very-long-"), name)
+	is.LessOrEqual(len(name), 30)
+	is.NoError(r.ValidateName(name))
+
+	// The prefix is not repeated.
+	name, err = r.GenerateName("team-a-web")
+	is.NoError(err)
+	is.True(strings.HasPrefix(name, "team-a-web-"), name)
+
+	r.Generator = NameGeneratorRandom
+	name, err = r.GenerateName("web")
+	is.NoError(err)
+	is.Len(name, len("team-a-web-")+5)
+
+	for name, reason := range map[string]string{
+		"web":                                "must start with \"team-a-\"",
+		"team-a-a-name-longer-than-thirty-c": "must be at most 30 characters long",
+	} {
+		err := r.ValidateName(name)
+		is.Equal(errcode.ReleaseNameNotAllowed, errcode.Of(err))
+		is.ErrorContains(err, reason)
+	}
+
+	r, err = ParseNameRules([]byte("pattern: ^[a-z]+-(dev|prod)$\n"))
+	is.NoError(err)
+	is.NoError(r.ValidateName("web-prod"))
+	is.ErrorContains(r.ValidateName("web-test"), `release name "web-test" violates the name policy: must match ^[a-z]+-(dev|prod)$`)
+}
+
+func TestInstallRelease_NamePolicy(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	rules, err := ParseNameRules([]byte("prefix: team-a-\n"))
+	is.NoError(err)
+	instAction.cfg.NamePolicy = rules
+
+	instAction.ReleaseName = ""
+	instAction.GenerateName = true
+	name, _, err := instAction.NameAndChart([]string{"./nginx"})
+	is.NoError(err)
+	is.True(strings.HasPrefix(name, "team-a-nginx-"), name)
+
+	instAction.ReleaseName = "web"
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.Equal(errcode.ReleaseNameNotAllowed, errcode.Of(err))
+	is.ErrorContains(err, `release name "web" violates the name policy: must start with "team-a-"`)
+
+	instAction.ReleaseName = "team-a-web"
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.NoError(err)
+}
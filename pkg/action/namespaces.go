@@ -0,0 +1,85 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// resourceNamespaces returns the sorted namespaces of the namespaced
+// resources of a release if some of them are deployed outside of the
+// namespace of the release, and nil otherwise.
+//
+// Charts may deploy resources into several namespaces by setting their
+// metadata.namespace. Resources without a namespace are deployed into the
+// namespace of the release.
+func resourceNamespaces(resources kube.ResourceList, releaseNamespace string) []string {
+	seen := map[string]bool{}
+	multi := false
+	for _, r := range resources {
+		if r.Namespace == "" || r.Mapping != nil && !r.Namespaced() {
+			continue
+		}
+		seen[r.Namespace] = true
+		if r.Namespace != releaseNamespace {
+			multi = true
+		}
+	}
+	if !multi {
+		return nil
+	}
+	namespaces := make([]string, 0, len(seen))
+	for ns := range seen {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// checkAccess verifies that the client may use the verbs on the resources of
+// a release deployed into several namespaces before any of them is changed,
+// so that a missing permission in one of the namespaces does not leave the
+// release partially applied. Releases deployed into their own namespace only
+// are not checked, nor are they when the kube client cannot review access.
+func (cfg *Configuration) checkAccess(resources kube.ResourceList, releaseNamespace string, verbs ...string) error {
+	if resourceNamespaces(resources, releaseNamespace) == nil {
+		return nil
+	}
+	reviewer, ok := cfg.KubeClient.(kube.InterfaceAccess)
+	if !ok {
+		return nil
+	}
+	denials, err := reviewer.ReviewAccess(resources, verbs...)
+	if err != nil {
+		// The access is checked again when the resources are changed.
+		cfg.Log("warning: %s", err)
+		return nil
+	}
+	if len(denials) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(denials))
+	for _, d := range denials {
+		msgs = append(msgs, d.String())
+	}
+	return errors.Errorf("missing permissions in the namespaces of the release:\n  %s", strings.Join(msgs, "\n  "))
+}
@@ -0,0 +1,126 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// multiNamespaceChart deploys a ConfigMap into the namespace of the release
+// and another one into the namespace monitoring.
+func multiNamespaceChart() *chart.Chart {
+	chrt := buildChart()
+	chrt.Templates = []*chart.File{{Name: "templates/configmaps.yaml", Data: []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: dashboards
+  namespace: monitoring
+`)}}
+	return chrt
+}
+
+func TestInstallRelease_MultiNamespace(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.Namespace = "default"
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+
+	rel, err := instAction.Run(multiNamespaceChart(), map[string]interface{}{})
+	is.NoError(err)
+	is.Equal([]string{"default", "monitoring"}, rel.Namespaces)
+
+	// Releases deployed into their own namespace only record no namespaces.
+	instAction = installAction(t)
+	instAction.Namespace = "default"
+	instAction.cfg.KubeClient.(*kubefake.FailingKubeClient).ParseManifests = true
+	chrt := buildChart()
+	chrt.Templates = multiNamespaceChart().Templates[:1]
+	chrt.Templates[0].Data = []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n")
+	rel, err = instAction.Run(chrt, map[string]interface{}{})
+	is.NoError(err)
+	is.Nil(rel.Namespaces)
+}
+
+func TestInstallRelease_MultiNamespaceAccessDenied(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.Namespace = "default"
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	failer.AccessDenials = []kube.AccessDenial{{Verb: "create", Resource: "configmaps", Namespace: "monitoring"}}
+
+	_, err := instAction.Run(multiNamespaceChart(), map[string]interface{}{})
+	is.ErrorContains(err, `cannot create configmaps in namespace "monitoring"`)
+	is.Empty(failer.OperationsOf(kubefake.VerbCreate))
+}
+
+func TestUpgradeRelease_MultiNamespaceAccessDenied(t *testing.T) {
+	is := assert.New(t)
+	upAction := upgradeAction(t)
+	upAction.Namespace = "default"
+	failer := upAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	failer.AccessDenials = []kube.AccessDenial{{Verb: "patch", Resource: "configmaps", Namespace: "monitoring"}}
+
+	rel := releaseStub()
+	rel.Name = "multi"
+	rel.Namespace = "default"
+	rel.Info.Status = release.StatusDeployed
+	is.NoError(upAction.cfg.Releases.Create(rel))
+
+	_, err := upAction.Run(rel.Name, multiNamespaceChart(), map[string]interface{}{})
+	is.ErrorContains(err, `cannot patch configmaps in namespace "monitoring"`)
+	is.Empty(failer.OperationsOf(kubefake.VerbCreate))
+	is.Empty(failer.OperationsOf(kubefake.VerbUpdate))
+}
+
+func TestUninstallRelease_MultiNamespaceAccessDenied(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.Namespace = "default"
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	rel, err := instAction.Run(multiNamespaceChart(), map[string]interface{}{})
+	is.NoError(err)
+
+	failer.AccessDenials = []kube.AccessDenial{{Verb: "delete", Resource: "configmaps", Namespace: "monitoring"}}
+	unAction := NewUninstall(instAction.cfg)
+	_, err = unAction.Run(rel.Name)
+	is.ErrorContains(err, `cannot delete configmaps in namespace "monitoring"`)
+	is.Empty(failer.OperationsOf(kubefake.VerbDelete))
+
+	last, err := instAction.cfg.Releases.Last(rel.Name)
+	is.NoError(err)
+	is.Equal(release.StatusDeployed, last.Info.Status)
+
+	failer.AccessDenials = nil
+	_, err = unAction.Run(rel.Name)
+	is.NoError(err)
+}
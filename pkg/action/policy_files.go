@@ -0,0 +1,115 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+// PolicyFiles are the paths of the files defining the webhooks and policies
+// of a Configuration, see LoadWebhooks, LoadMetadataPolicy, LoadNameRules,
+// LoadKindOrder and LoadHookImagePolicy. Each file is loaded the first time
+// an action needs what it defines, so that a malformed file only fails the
+// actions using it. A file is ignored if the Configuration field it defines
+// is set.
+type PolicyFiles struct {
+	Webhooks        string
+	MetadataPolicy  string
+	NamePolicy      string
+	KindOrder       string
+	HookImagePolicy string
+}
+
+// webhooks returns the webhooks of the configuration, loading their file if
+// needed.
+func (cfg *Configuration) webhooks() ([]Webhook, error) {
+	cfg.policiesMu.Lock()
+	defer cfg.policiesMu.Unlock()
+	if cfg.Webhooks == nil && cfg.PolicyFiles.Webhooks != "" {
+		webhooks, err := LoadWebhooks(cfg.PolicyFiles.Webhooks)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Webhooks, cfg.PolicyFiles.Webhooks = webhooks, ""
+	}
+	return cfg.Webhooks, nil
+}
+
+// metadataPolicy returns the metadata policy of the configuration, loading
+// its file if needed.
+func (cfg *Configuration) metadataPolicy() (*MetadataPolicy, error) {
+	cfg.policiesMu.Lock()
+	defer cfg.policiesMu.Unlock()
+	if cfg.MetadataPolicy == nil && cfg.PolicyFiles.MetadataPolicy != "" {
+		p, err := LoadMetadataPolicy(cfg.PolicyFiles.MetadataPolicy)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MetadataPolicy, cfg.PolicyFiles.MetadataPolicy = p, ""
+	}
+	return cfg.MetadataPolicy, nil
+}
+
+// namePolicy returns the name policy of the configuration, loading its file
+// if needed, and the DefaultNamePolicy if it has none.
+func (cfg *Configuration) namePolicy() (NamePolicy, error) {
+	if cfg == nil {
+		return DefaultNamePolicy{}, nil
+	}
+	cfg.policiesMu.Lock()
+	defer cfg.policiesMu.Unlock()
+	if cfg.NamePolicy == nil && cfg.PolicyFiles.NamePolicy != "" {
+		rules, err := LoadNameRules(cfg.PolicyFiles.NamePolicy)
+		if err != nil {
+			return nil, err
+		}
+		if rules != nil {
+			cfg.NamePolicy = rules
+		}
+		cfg.PolicyFiles.NamePolicy = ""
+	}
+	if cfg.NamePolicy == nil {
+		return DefaultNamePolicy{}, nil
+	}
+	return cfg.NamePolicy, nil
+}
+
+// kindOrder returns the kind order of the configuration, loading its file if
+// needed.
+func (cfg *Configuration) kindOrder() (*KindOrder, error) {
+	cfg.policiesMu.Lock()
+	defer cfg.policiesMu.Unlock()
+	if cfg.KindOrder == nil && cfg.PolicyFiles.KindOrder != "" {
+		o, err := LoadKindOrder(cfg.PolicyFiles.KindOrder)
+		if err != nil {
+			return nil, err
+		}
+		cfg.KindOrder, cfg.PolicyFiles.KindOrder = o, ""
+	}
+	return cfg.KindOrder, nil
+}
+
+// hookImagePolicy returns the hook image policy of the configuration, loading
+// its file if needed.
+func (cfg *Configuration) hookImagePolicy() (*HookImagePolicy, error) {
+	cfg.policiesMu.Lock()
+	defer cfg.policiesMu.Unlock()
+	if cfg.HookImagePolicy == nil && cfg.PolicyFiles.HookImagePolicy != "" {
+		p, err := LoadHookImagePolicy(cfg.PolicyFiles.HookImagePolicy)
+		if err != nil {
+			return nil, err
+		}
+		cfg.HookImagePolicy, cfg.PolicyFiles.HookImagePolicy = p, ""
+	}
+	return cfg.HookImagePolicy, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyFiles(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		req.NoError(os.WriteFile(path, []byte(data), 0644))
+		return path
+	}
+
+	// A malformed file fails the actions needing it, not the others.
+	instAction := installAction(t)
+	instAction.cfg.PolicyFiles = PolicyFiles{
+		NamePolicy: write("names.yaml", "prefix: team-a-\n"),
+		KindOrder:  write("kind-order.yaml", "kinds: [\n"),
+	}
+	instAction.ReleaseName = ""
+	instAction.GenerateName = true
+	name, _, err := instAction.NameAndChart([]string{"./nginx"})
+	req.NoError(err)
+	is.True(strings.HasPrefix(name, "team-a-nginx-"), name)
+
+	instAction.ReleaseName = name
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.ErrorContains(err, "kind-order.yaml")
+
+	// A malformed webhooks file is logged, as webhooks failing are.
+	instAction = installAction(t)
+	instAction.cfg.PolicyFiles = PolicyFiles{Webhooks: write("webhooks.yaml", "webhooks: [{}]\n")}
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.NoError(err)
+
+	// The fields of the configuration win over the files.
+	rules, err := ParseNameRules([]byte("prefix: team-b-\n"))
+	req.NoError(err)
+	instAction.cfg.NamePolicy = rules
+	instAction.cfg.PolicyFiles.NamePolicy = filepath.Join(dir, "names.yaml")
+	policy, err := instAction.cfg.namePolicy()
+	req.NoError(err)
+	is.Equal(rules, policy)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of profiles captured by a Profiler.
+const (
+	ProfileCPU   = "cpu"
+	ProfileMem   = "mem"
+	ProfileTrace = "trace"
+)
+
+// Phases of the actions profiled by a Profiler.
+const (
+	PhaseRender = "render"
+	PhaseApply  = "apply"
+	PhaseWait   = "wait"
+)
+
+// Profiler captures profiles of the phases of the actions run with a
+// Configuration: rendering the chart, applying its resources and waiting for
+// them to be ready. Each phase is written to a file of its own in Dir, named
+// <action>-<phase>.pprof, or <action>-<phase>.trace for execution traces.
+//
+// CPU profiles and traces can be read with 'go tool pprof' and 'go tool
+// trace'. Memory profiles are heap profiles taken at the end of the phase.
+type Profiler struct {
+	// Kind is the kind of profile captured: ProfileCPU, ProfileMem or
+	// ProfileTrace.
+	Kind string
+	// Dir is the directory the profiles are written to.
+	Dir string
+
+	mu    sync.Mutex
+	files []string
+}
+
+// NewProfiler creates a Profiler of a kind, writing to the directory dir. The
+// directory is created if it does not exist.
+func NewProfiler(kind, dir string) (*Profiler, error) {
+	switch kind {
+	case ProfileCPU, ProfileMem, ProfileTrace:
+	default:
+		return nil, errors.Errorf("invalid profile %q: must be one of %s, %s or %s", kind, ProfileCPU, ProfileMem, ProfileTrace)
+	}
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "unable to create profile directory")
+	}
+	return &Profiler{Kind: kind, Dir: dir}, nil
+}
+
+// Files returns the files the profiler wrote, in order.
+func (p *Profiler) Files() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.files...)
+}
+
+// start starts profiling a phase of an action, and returns a function
+// stopping it. Profiling never fails the action: errors are only logged.
+func (p *Profiler) start(action, phase string, log DebugLog) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ext := ".pprof"
+	if p.Kind == ProfileTrace {
+		ext = ".trace"
+	}
+	name := filepath.Join(p.Dir, fmt.Sprintf("%s-%s%s", action, phase, ext))
+	for i := 2; p.written(name); i++ {
+		name = filepath.Join(p.Dir, fmt.Sprintf("%s-%s-%d%s", action, phase, i, ext))
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		log("unable to profile %s of %s: %s", phase, action, err)
+		return func() {}
+	}
+	p.files = append(p.files, name)
+
+	start := time.Now()
+	switch p.Kind {
+	case ProfileCPU:
+		err = pprof.StartCPUProfile(f)
+	case ProfileTrace:
+		err = trace.Start(f)
+	}
+	if err != nil {
+		log("unable to profile %s of %s: %s", phase, action, err)
+		f.Close()
+		return func() {}
+	}
+
+	return func() {
+		switch p.Kind {
+		case ProfileCPU:
+			pprof.StopCPUProfile()
+		case ProfileTrace:
+			trace.Stop()
+		case ProfileMem:
+			runtime.GC()
+			err = pprof.WriteHeapProfile(f)
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log("unable to write profile of %s of %s: %s", phase, action, err)
+			return
+		}
+		log("%s of %s took %s, profile written to %s", phase, action, time.Since(start), name)
+	}
+}
+
+func (p *Profiler) written(name string) bool {
+	for _, f := range p.files {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// profile starts profiling a phase of an action if the configuration has a
+// profiler, and returns a function stopping it.
+func (cfg *Configuration) profile(action, phase string) func() {
+	if cfg.Profiler == nil {
+		return func() {}
+	}
+	return cfg.Profiler.start(action, phase, cfg.Log)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiler(t *testing.T) {
+	for _, kind := range []string{ProfileCPU, ProfileMem, ProfileTrace} {
+		t.Run(kind, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "profiles")
+			profiler, err := NewProfiler(kind, dir)
+			require.NoError(t, err)
+
+			instAction := installAction(t)
+			instAction.cfg.Profiler = profiler
+			instAction.Wait = true
+			_, err = instAction.Run(buildChart(), map[string]interface{}{})
+			require.NoError(t, err)
+
+			upAction := upgradeAction(t)
+			upAction.cfg = instAction.cfg
+			_, err = upAction.Run(instAction.ReleaseName, buildChart(), map[string]interface{}{})
+			require.NoError(t, err)
+
+			ext := ".pprof"
+			if kind == ProfileTrace {
+				ext = ".trace"
+			}
+			var want []string
+			for _, name := range []string{"install-render", "install-apply", "install-wait", "upgrade-render", "upgrade-apply"} {
+				want = append(want, filepath.Join(dir, name+ext))
+			}
+			assert.Equal(t, want, profiler.Files())
+			for _, f := range want {
+				info, err := os.Stat(f)
+				require.NoError(t, err)
+				assert.NotZero(t, info.Size(), "profile %s is empty", f)
+			}
+		})
+	}
+}
+
+func TestProfilerRepeatedPhases(t *testing.T) {
+	profiler, err := NewProfiler(ProfileMem, t.TempDir())
+	require.NoError(t, err)
+	cfg := actionConfigFixture(t)
+	cfg.Profiler = profiler
+
+	cfg.profile("upgrade", PhaseWait)()
+	cfg.profile("upgrade", PhaseWait)()
+	assert.Equal(t, []string{
+		filepath.Join(profiler.Dir, "upgrade-wait.pprof"),
+		filepath.Join(profiler.Dir, "upgrade-wait-2.pprof"),
+	}, profiler.Files())
+}
+
+func TestNewProfilerInvalidKind(t *testing.T) {
+	_, err := NewProfiler("block", t.TempDir())
+	assert.ErrorContains(t, err, `invalid profile "block"`)
+}
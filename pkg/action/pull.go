@@ -71,15 +71,22 @@ func NewPullWithOpts(opts ...PullOpt) *Pull {
 	return p
 }
 
-// SetRegistryClient sets the registry client on the pull configuration object.
+// SetRegistryClient sets the registry client to use when pulling charts. It
+// takes precedence over the registry client of the configuration, which is
+// left untouched so that it can be shared with other actions.
 func (p *Pull) SetRegistryClient(client *registry.Client) {
-	p.cfg.RegistryClient = client
+	p.ChartPathOptions.registryClient = client
 }
 
 // Run executes 'helm pull' against the given release.
 func (p *Pull) Run(chartRef string) (string, error) {
 	var out strings.Builder
 
+	registryClient := p.ChartPathOptions.registryClient
+	if registryClient == nil && p.cfg != nil {
+		registryClient = p.cfg.RegistryClient
+	}
+
 	c := downloader.ChartDownloader{
 		Out:     &out,
 		Keyring: p.Keyring,
@@ -92,15 +99,21 @@ func (p *Pull) Run(chartRef string) (string, error) {
 			getter.WithInsecureSkipVerifyTLS(p.InsecureSkipTLSverify),
 			getter.WithPlainHTTP(p.PlainHTTP),
 		},
-		RegistryClient:   p.cfg.RegistryClient,
+		RegistryClient:   registryClient,
 		RepositoryConfig: p.Settings.RepositoryConfig,
 		RepositoryCache:  p.Settings.RepositoryCache,
 	}
 
+	policy, err := downloader.LoadTrustPolicy(p.Settings.TrustPolicy)
+	if err != nil {
+		return out.String(), err
+	}
+	c.TrustPolicy = policy
+
 	if registry.IsOCI(chartRef) {
 		c.Options = append(c.Options,
-			getter.WithRegistryClient(p.cfg.RegistryClient))
-		c.RegistryClient = p.cfg.RegistryClient
+			getter.WithRegistryClient(registryClient))
+		c.RegistryClient = registryClient
 	}
 
 	if p.Verify {
@@ -113,7 +126,6 @@ func (p *Pull) Run(chartRef string) (string, error) {
 	// verification.
 	dest := p.DestDir
 	if p.Untar {
-		var err error
 		dest, err = os.MkdirTemp("", "helm-")
 		if err != nil {
 			return out.String(), errors.Wrap(err, "failed to untar")
@@ -134,7 +146,8 @@ func (p *Pull) Run(chartRef string) (string, error) {
 		return out.String(), err
 	}
 
-	if p.Verify {
+	// The trust policy may require verification even if it was not asked for.
+	if v != nil && v.SignedBy != nil {
 		for name := range v.SignedBy.Identities {
 			fmt.Fprintf(&out, "Signed by: %v\n", name)
 		}
@@ -17,9 +17,16 @@ limitations under the License.
 package action
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/pusher"
 	"helm.sh/helm/v3/pkg/registry"
@@ -38,6 +45,21 @@ type Push struct {
 	insecureSkipTLSverify bool
 	plainHTTP             bool
 	out                   io.Writer
+
+	// Sign signs the chart with Key from Keyring before pushing it, writing
+	// its provenance file next to it.
+	Sign           bool
+	Key            string
+	Keyring        string
+	PassphraseFile string
+	// SBOM is the path of an SPDX or CycloneDX SBOM attached to the chart.
+	SBOM string
+	// Attachments are other files attached to the chart, such as test
+	// results, given as <path>:<artifact type>.
+	Attachments []string
+	// Annotations are set on the chart manifest, e.g. the source revision
+	// or the URL of the build.
+	Annotations map[string]string
 }
 
 // PushOpt is a type of function that sets options for a push action.
@@ -90,9 +112,27 @@ func NewPushWithOpts(opts ...PushOpt) *Push {
 }
 
 // Run executes 'helm push' against the given chart archive.
+//
+// For registries, the chart and everything attached to it are pushed in one
+// operation: the chart is only pushed once the attachments are, and those are
+// deleted if it fails.
 func (p *Push) Run(chartRef string, remote string) (string, error) {
 	var out strings.Builder
 
+	referrers, err := p.referrers()
+	if err != nil {
+		return out.String(), err
+	}
+	if !registry.IsOCI(remote) && (len(referrers) > 0 || len(p.Annotations) > 0) {
+		return out.String(), errors.New("attachments and annotations can only be pushed to OCI registries")
+	}
+	if p.Sign {
+		signer := &Package{Key: p.Key, Keyring: p.Keyring, PassphraseFile: p.PassphraseFile}
+		if err := signer.Clearsign(chartRef); err != nil {
+			return out.String(), errors.Wrap(err, "failed to sign the chart")
+		}
+	}
+
 	c := uploader.ChartUploader{
 		Out:     &out,
 		Pushers: pusher.All(p.Settings),
@@ -105,8 +145,71 @@ func (p *Push) Run(chartRef string, remote string) (string, error) {
 
 	if registry.IsOCI(remote) {
 		// Don't use the default registry client if tls options are set.
-		c.Options = append(c.Options, pusher.WithRegistryClient(p.cfg.RegistryClient))
+		c.Options = append(c.Options,
+			pusher.WithRegistryClient(p.cfg.RegistryClient),
+			pusher.WithAnnotations(p.Annotations),
+			pusher.WithReferrers(referrers...))
 	}
 
 	return out.String(), c.UploadTo(chartRef, remote)
 }
+
+// referrers reads the SBOM and the attachments to push along with the chart.
+func (p *Push) referrers() ([]registry.Referrer, error) {
+	var referrers []registry.Referrer
+	if p.SBOM != "" {
+		data, err := os.ReadFile(p.SBOM)
+		if err != nil {
+			return nil, err
+		}
+		artifactType, err := sbomArtifactType(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "SBOM %s", p.SBOM)
+		}
+		referrers = append(referrers, newReferrer(p.SBOM, artifactType, data))
+	}
+	for _, a := range p.Attachments {
+		i := strings.Index(a, ":")
+		if i <= 0 || i == len(a)-1 {
+			return nil, errors.Errorf("attachment %q must be given as <path>:<artifact type>", a)
+		}
+		data, err := os.ReadFile(a[:i])
+		if err != nil {
+			return nil, err
+		}
+		referrers = append(referrers, newReferrer(a[:i], a[i+1:], data))
+	}
+	return referrers, nil
+}
+
+func newReferrer(path, artifactType string, data []byte) registry.Referrer {
+	return registry.Referrer{
+		ArtifactType: artifactType,
+		Data:         data,
+		Annotations:  map[string]string{ocispec.AnnotationTitle: filepath.Base(path)},
+	}
+}
+
+// sbomArtifactType detects the format of an SBOM.
+func sbomArtifactType(data []byte) (string, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("SPDXVersion:")):
+		return "text/spdx", nil
+	case bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(trimmed, []byte("cyclonedx.org/schema/bom")):
+		return "application/vnd.cyclonedx+xml", nil
+	}
+	var doc struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+	if err := json.Unmarshal(trimmed, &doc); err == nil {
+		switch {
+		case doc.SPDXVersion != "":
+			return "application/spdx+json", nil
+		case doc.BOMFormat == "CycloneDX":
+			return "application/vnd.cyclonedx+json", nil
+		}
+	}
+	return "", errors.New("unrecognized format, expected SPDX or CycloneDX")
+}
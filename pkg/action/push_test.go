@@ -0,0 +1,93 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSBOMArtifactType(t *testing.T) {
+	tests := []struct {
+		name, data, expect string
+	}{
+		{"spdx json", `{"spdxVersion": "SPDX-2.3", "name": "mychart"}`, "application/spdx+json"},
+		{"spdx tag-value", "SPDXVersion: SPDX-2.3\nDataLicense: CC0-1.0\n", "text/spdx"},
+		{"cyclonedx json", `{"bomFormat": "CycloneDX", "specVersion": "1.5"}`, "application/vnd.cyclonedx+json"},
+		{"cyclonedx xml", `<?xml version="1.0"?><bom xmlns="http://cyclonedx.org/schema/bom/1.5"></bom>`, "application/vnd.cyclonedx+xml"},
+		{"unknown", `{"name": "mychart"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sbomArtifactType([]byte(tt.data))
+			if tt.expect == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expect {
+				t.Errorf("expected %s, got %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestPushReferrers(t *testing.T) {
+	dir := t.TempDir()
+	sbom := filepath.Join(dir, "sbom.json")
+	if err := os.WriteFile(sbom, []byte(`{"spdxVersion": "SPDX-2.3"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results := filepath.Join(dir, "results.xml")
+	if err := os.WriteFile(results, []byte("<testsuites/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPushWithOpts()
+	p.SBOM = sbom
+	p.Attachments = []string{results + ":application/vnd.junit+xml"}
+	referrers, err := p.referrers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(referrers) != 2 {
+		t.Fatalf("expected 2 referrers, got %d", len(referrers))
+	}
+	if referrers[0].ArtifactType != "application/spdx+json" {
+		t.Errorf("unexpected SBOM artifact type %s", referrers[0].ArtifactType)
+	}
+	if referrers[1].ArtifactType != "application/vnd.junit+xml" || string(referrers[1].Data) != "<testsuites/>" {
+		t.Errorf("unexpected attachment %+v", referrers[1])
+	}
+	if title := referrers[1].Annotations["org.opencontainers.image.title"]; title != "results.xml" {
+		t.Errorf("expected the attachment to be titled results.xml, got %s", title)
+	}
+
+	p.Attachments = []string{results}
+	if _, err := p.referrers(); err == nil {
+		t.Error("expected an error for an attachment without artifact type")
+	}
+
+	if _, err := p.Run(filepath.Join(dir, "mychart-0.1.0.tgz"), "https://example.com/charts"); err == nil {
+		t.Error("expected an error attaching artifacts to a chart pushed to a repository")
+	}
+}
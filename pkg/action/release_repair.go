@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// RepairOutcome is how far the interrupted operation of a repaired revision
+// got, as told by its journal.
+type RepairOutcome string
+
+const (
+	// RepairNeverStarted means the operation never changed the cluster.
+	RepairNeverStarted RepairOutcome = "never started"
+	// RepairPartiallyApplied means the operation was interrupted while it
+	// ran the pre hooks, applied the resources, waited for them or ran the
+	// post hooks.
+	RepairPartiallyApplied RepairOutcome = "partially applied"
+	// RepairCompleted means the operation applied the resources and ran the
+	// post hooks, and was only interrupted before marking the revision
+	// deployed.
+	RepairCompleted RepairOutcome = "completed"
+)
+
+// ReleaseRepair is the action for settling a revision left pending by an
+// interrupted install, upgrade or rollback.
+//
+// It provides the implementation of 'helm release repair'. The journal of the
+// revision tells how far the operation got: a revision whose operation
+// completed is marked deployed, superseding the previously deployed revision,
+// and any other revision is marked failed. It must only be run when
+// no operation on the release is in progress.
+type ReleaseRepair struct {
+	cfg *Configuration
+}
+
+// NewReleaseRepair creates a new ReleaseRepair object with the given
+// configuration.
+func NewReleaseRepair(cfg *Configuration) *ReleaseRepair {
+	return &ReleaseRepair{
+		cfg: cfg,
+	}
+}
+
+// Run executes 'helm release repair' against the given release.
+func (r *ReleaseRepair) Run(name string) (*release.Release, RepairOutcome, error) {
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return nil, "", errors.Errorf("release name is invalid: %s", name)
+	}
+
+	rel, err := r.cfg.Releases.Last(name)
+	if err != nil {
+		return nil, "", err
+	}
+	switch rel.Info.Status {
+	case release.StatusPendingInstall, release.StatusPendingUpgrade, release.StatusPendingRollback:
+	case release.StatusUninstalling:
+		return nil, "", errors.Errorf("release %s is %s, run 'helm uninstall' again to complete the uninstall", name, rel.Info.Status)
+	default:
+		return nil, "", errors.Errorf("release %s is %s, only pending releases need to be repaired", name, rel.Info.Status)
+	}
+	if !rel.Journaled(release.JournalCreated) {
+		return nil, "", errors.Errorf("revision %d of release %s has no journal, the outcome of its operation cannot be determined", rel.Version, name)
+	}
+
+	var outcome RepairOutcome
+	switch {
+	case rel.Journaled(release.JournalCompleted):
+		outcome = RepairCompleted
+		deployed, err := r.cfg.Releases.DeployedAll(name)
+		if err != nil && !errors.Is(err, driver.ErrNoDeployedReleases) {
+			return nil, "", err
+		}
+		for _, d := range deployed {
+			d.Info.Status = release.StatusSuperseded
+			if err := r.cfg.Releases.Update(d); err != nil {
+				return nil, "", err
+			}
+		}
+		rel.SetStatus(release.StatusDeployed, "Repaired: the operation completed before it was interrupted")
+	case rel.Journaled(release.JournalApplied):
+		outcome = RepairPartiallyApplied
+		rel.SetStatus(release.StatusFailed, "Repaired: the operation was interrupted after applying the resources, which may not be ready, and the post hooks may not have run")
+	case rel.Journaled(release.JournalApplying):
+		outcome = RepairPartiallyApplied
+		rel.SetStatus(release.StatusFailed, "Repaired: the operation was interrupted while applying the resources, which may be partially applied")
+	default:
+		outcome = RepairNeverStarted
+		rel.SetStatus(release.StatusFailed, "Repaired: the operation was interrupted before it changed the cluster")
+	}
+	return rel, outcome, r.cfg.Releases.Update(rel)
+}
+
+// journal records in a revision that the operation creating it reached a
+// phase.
+func journal(rel *release.Release, phase release.JournalPhase) {
+	rel.Journal = append(rel.Journal, release.JournalEntry{Phase: phase, Time: Timestamper()})
+}
+
+// writeJournal records that the operation creating a revision reached a phase
+// and writes the revision to the storage before the operation goes on, so
+// that the phase is known if the operation is interrupted.
+func (cfg *Configuration) writeJournal(rel *release.Release, phase release.JournalPhase) error {
+	journal(rel, phase)
+	return errors.Wrapf(cfg.Releases.Update(rel), "unable to journal the %s phase of release %s", phase, rel.Name)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func journalPhases(rel *release.Release) []release.JournalPhase {
+	var phases []release.JournalPhase
+	for _, e := range rel.Journal {
+		phases = append(phases, e.Phase)
+	}
+	return phases
+}
+
+func TestInstallJournal(t *testing.T) {
+	instAction := installAction(t)
+	rel, err := instAction.Run(buildChart(), nil)
+	require.NoError(t, err)
+
+	stored, err := instAction.cfg.Releases.Get(rel.Name, rel.Version)
+	require.NoError(t, err)
+	assert.Equal(t, []release.JournalPhase{release.JournalCreated, release.JournalApplying, release.JournalApplied, release.JournalCompleted}, journalPhases(stored))
+}
+
+func TestReleaseRepair(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	tests := []struct {
+		name    string
+		phases  []release.JournalPhase
+		outcome RepairOutcome
+		status  release.Status
+	}{
+		{"never started", []release.JournalPhase{release.JournalCreated}, RepairNeverStarted, release.StatusFailed},
+		{"partially applied", []release.JournalPhase{release.JournalCreated, release.JournalApplying}, RepairPartiallyApplied, release.StatusFailed},
+		{"applied", []release.JournalPhase{release.JournalCreated, release.JournalApplying, release.JournalApplied}, RepairPartiallyApplied, release.StatusFailed},
+		{"completed", []release.JournalPhase{release.JournalCreated, release.JournalApplying, release.JournalApplied, release.JournalCompleted}, RepairCompleted, release.StatusDeployed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(_ *testing.T) {
+			config := actionConfigFixture(t)
+			deployed := namedReleaseStub("angry-bird", release.StatusDeployed)
+			pending := namedReleaseStub("angry-bird", release.StatusPendingUpgrade)
+			pending.Version = 2
+			for _, phase := range tt.phases {
+				journal(pending, phase)
+			}
+			req.NoError(config.Releases.Create(deployed))
+			req.NoError(config.Releases.Create(pending))
+
+			rel, outcome, err := NewReleaseRepair(config).Run("angry-bird")
+			req.NoError(err)
+			is.Equal(tt.outcome, outcome)
+			is.Equal(tt.status, rel.Info.Status)
+
+			previous, err := config.Releases.Get("angry-bird", 1)
+			req.NoError(err)
+			if tt.status == release.StatusDeployed {
+				is.Equal(release.StatusSuperseded, previous.Info.Status)
+			} else {
+				is.Equal(release.StatusDeployed, previous.Info.Status)
+			}
+
+			_, _, err = NewReleaseRepair(config).Run("angry-bird")
+			is.Error(err, "a repaired release is no longer pending")
+		})
+	}
+}
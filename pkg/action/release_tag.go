@@ -0,0 +1,173 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// tagLabelPrefix prefixes the labels of the release that tag a revision.
+const tagLabelPrefix = "tag.helm.sh/"
+
+// validTag matches the names revisions can be tagged with. Tags start with a
+// letter so that they are never mistaken for revision numbers.
+var validTag = regexp.MustCompile(`^[a-zA-Z]([-a-zA-Z0-9_.]{0,61}[a-zA-Z0-9])?$`)
+
+// ReleaseTag is the action for giving a revision of a release a name.
+//
+// It provides the implementation of 'helm release tag'. A tag names a single
+// revision of a release: tagging a revision moves the tag from the revision
+// that had it. Tags are stored as labels of the release and can be used
+// wherever a revision number is accepted.
+type ReleaseTag struct {
+	cfg *Configuration
+
+	// Version is the revision to tag. Initializing it to 0 tags the latest
+	// revision of the release.
+	Version int
+	// Delete removes the tag instead.
+	Delete bool
+}
+
+// NewReleaseTag creates a new ReleaseTag object with the given configuration.
+func NewReleaseTag(cfg *Configuration) *ReleaseTag {
+	return &ReleaseTag{
+		cfg: cfg,
+	}
+}
+
+// Run executes 'helm release tag' against the given release.
+func (t *ReleaseTag) Run(name, tag string) (*release.Release, error) {
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return nil, errors.Errorf("release name is invalid: %s", name)
+	}
+	if err := validateTag(tag); err != nil {
+		return nil, err
+	}
+
+	history, err := t.cfg.Releases.History(name)
+	if err != nil {
+		return nil, err
+	}
+	var tagged []*release.Release
+	for _, rel := range history {
+		if rel.Labels[tagLabelPrefix+tag] != "" {
+			tagged = append(tagged, rel)
+		}
+	}
+
+	if t.Delete {
+		if len(tagged) == 0 {
+			return nil, errors.Errorf("release %s has no revision tagged %q", name, tag)
+		}
+		for _, rel := range tagged {
+			delete(rel.Labels, tagLabelPrefix+tag)
+			if err := t.cfg.Releases.Update(rel); err != nil {
+				return nil, err
+			}
+		}
+		return tagged[0], nil
+	}
+
+	var rel *release.Release
+	if t.Version == 0 {
+		rel, err = t.cfg.Releases.Last(name)
+	} else {
+		rel, err = t.cfg.Releases.Get(name, t.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range tagged {
+		if other.Version == rel.Version {
+			continue
+		}
+		delete(other.Labels, tagLabelPrefix+tag)
+		if err := t.cfg.Releases.Update(other); err != nil {
+			return nil, err
+		}
+	}
+	if rel.Labels == nil {
+		rel.Labels = map[string]string{}
+	}
+	rel.Labels[tagLabelPrefix+tag] = "true"
+	return rel, t.cfg.Releases.Update(rel)
+}
+
+// ResolveRevision returns the revision of the release given by revision,
+// which is either a revision number or a tag of a revision.
+func ResolveRevision(cfg *Configuration, name, revision string) (int, error) {
+	if version, err := strconv.Atoi(revision); err == nil {
+		return version, nil
+	}
+	if err := validateTag(revision); err != nil {
+		return 0, errors.Errorf("revision %q is neither a number nor a tag", revision)
+	}
+
+	history, err := cfg.Releases.History(name)
+	if err != nil {
+		return 0, err
+	}
+	for _, rel := range history {
+		if rel.Labels[tagLabelPrefix+revision] != "" {
+			return rel.Version, nil
+		}
+	}
+	return 0, errors.Errorf("release %s has no revision tagged %q", name, revision)
+}
+
+// ReleaseTags returns the tags of the revision, sorted.
+func ReleaseTags(rel *release.Release) []string {
+	var tags []string
+	for k := range rel.Labels {
+		if tag, ok := strings.CutPrefix(k, tagLabelPrefix); ok {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// withoutTags returns the labels of a release without the tags, for a new
+// revision derived from it.
+func withoutTags(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	untagged := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if !strings.HasPrefix(k, tagLabelPrefix) {
+			untagged[k] = v
+		}
+	}
+	return untagged
+}
+
+func validateTag(tag string) error {
+	if !validTag.MatchString(tag) {
+		return errors.Errorf("invalid tag %q: tags start with a letter and contain at most 63 letters, digits, '-', '_' or '.'", tag)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestReleaseTag(t *testing.T) {
+	is := assert.New(t)
+	config := actionConfigFixture(t)
+
+	for version := 1; version <= 3; version++ {
+		rel := namedReleaseStub("tagged", release.StatusSuperseded)
+		rel.Version = version
+		is.NoError(config.Releases.Create(rel))
+	}
+
+	tag := NewReleaseTag(config)
+	tag.Version = 2
+	rel, err := tag.Run("tagged", "stable")
+	is.NoError(err)
+	is.Equal(2, rel.Version)
+	is.Equal([]string{"stable"}, ReleaseTags(rel))
+
+	version, err := ResolveRevision(config, "tagged", "stable")
+	is.NoError(err)
+	is.Equal(2, version)
+
+	// Tagging another revision moves the tag.
+	tag.Version = 0
+	rel, err = tag.Run("tagged", "stable")
+	is.NoError(err)
+	is.Equal(3, rel.Version)
+	version, err = ResolveRevision(config, "tagged", "stable")
+	is.NoError(err)
+	is.Equal(3, version)
+	previous, err := config.Releases.Get("tagged", 2)
+	is.NoError(err)
+	is.Empty(ReleaseTags(previous))
+
+	// Revisions derived from a tagged one are not tagged.
+	rollback := NewRollback(config)
+	rollback.Version = 3
+	is.NoError(rollback.Run("tagged"))
+	last, err := config.Releases.Last("tagged")
+	is.NoError(err)
+	is.Equal(4, last.Version)
+	is.Empty(ReleaseTags(last))
+
+	upgraded, err := NewUpgrade(config).RunWithContext(context.Background(), "tagged", buildChart(), map[string]interface{}{})
+	is.NoError(err)
+	is.Empty(ReleaseTags(upgraded))
+
+	tag.Delete = true
+	rel, err = tag.Run("tagged", "stable")
+	is.NoError(err)
+	is.Equal(3, rel.Version)
+	_, err = ResolveRevision(config, "tagged", "stable")
+	is.EqualError(err, `release tagged has no revision tagged "stable"`)
+}
+
+func TestResolveRevision(t *testing.T) {
+	is := assert.New(t)
+	config := actionConfigFixture(t)
+
+	version, err := ResolveRevision(config, "any", "12")
+	is.NoError(err)
+	is.Equal(12, version)
+
+	_, err = ResolveRevision(config, "any", "12-stable")
+	is.EqualError(err, `revision "12-stable" is neither a number nor a tag`)
+
+	_, err = NewReleaseTag(config).Run("any", "-invalid")
+	is.Error(err)
+}
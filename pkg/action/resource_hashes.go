@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// resourceHashes returns the hashes of the rendered resources by objectKey.
+//
+// The resources must not have been modified since they were built from the
+// manifest, so that the hashes only change when the rendered output does.
+func resourceHashes(resources kube.ResourceList) (map[string]string, error) {
+	hashes := make(map[string]string, len(resources))
+	for _, r := range resources {
+		data, err := json.Marshal(r.Object)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to hash %s", objectKey(r))
+		}
+		sum := sha256.Sum256(data)
+		hashes[objectKey(r)] = hex.EncodeToString(sum[:])
+	}
+	return hashes, nil
+}
+
+// unchangedResources returns the keys of the resources whose hash is the same
+// in both revisions.
+func unchangedResources(deployed, target map[string]string) map[string]bool {
+	unchanged := make(map[string]bool)
+	for key, hash := range target {
+		if deployed[key] == hash {
+			unchanged[key] = true
+		}
+	}
+	return unchanged
+}
+
+// withoutResources returns the resources whose objectKey is not in keys.
+func withoutResources(resources kube.ResourceList, keys map[string]bool) kube.ResourceList {
+	if len(keys) == 0 {
+		return resources
+	}
+	var result kube.ResourceList
+	for _, r := range resources {
+		if !keys[objectKey(r)] {
+			result = append(result, r)
+		}
+	}
+	return result
+}
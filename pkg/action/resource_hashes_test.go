@@ -0,0 +1,49 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+func TestResourceHashes(t *testing.T) {
+	replicas := int32(3)
+	current := kube.ResourceList{newDeploymentResource("foo", "ns"), newDeploymentResource("bar", "ns")}
+	target := kube.ResourceList{newDeploymentResource("foo", "ns"), newDeploymentResource("bar", "ns"), newDeploymentResource("baz", "ns")}
+	target[1].Object.(*appsv1.Deployment).Spec.Replicas = &replicas
+
+	deployed, err := resourceHashes(current)
+	require.NoError(t, err)
+	hashes, err := resourceHashes(target)
+	require.NoError(t, err)
+	assert.Len(t, hashes, 3)
+	assert.Equal(t, deployed[objectKey(current[0])], hashes[objectKey(target[0])])
+	assert.NotEqual(t, deployed[objectKey(current[1])], hashes[objectKey(target[1])])
+
+	unchanged := unchangedResources(deployed, hashes)
+	assert.Equal(t, map[string]bool{objectKey(target[0]): true}, unchanged)
+
+	assert.Equal(t, kube.ResourceList{current[1]}, withoutResources(current, unchanged))
+	assert.Equal(t, kube.ResourceList{target[1], target[2]}, withoutResources(target, unchanged))
+	assert.Equal(t, target, withoutResources(target, nil))
+}
@@ -17,7 +17,7 @@ limitations under the License.
 package action
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -45,6 +45,15 @@ type Rollback struct {
 	Force         bool // will (if true) force resource upgrade through uninstall/recreate if needed
 	CleanupOnFail bool
 	MaxHistory    int // MaxHistory limits the maximum number of revisions saved per release
+	// LabelResources stamps the release name, revision, chart and manager as
+	// labels on all resources of the release.
+	LabelResources bool
+	// DeployedBy describes who performs the operation, e.g. a person or a
+	// pipeline. It is recorded in the release along with the cluster user.
+	DeployedBy string
+	// OverrideFreeze proceeds during an active freeze window of the freeze
+	// policy of the configuration, recording the override in the audit log.
+	OverrideFreeze bool
 }
 
 // NewRollback creates a new Rollback object with the given configuration.
@@ -56,37 +65,53 @@ func NewRollback(cfg *Configuration) *Rollback {
 
 // Run executes 'helm rollback' against the given release.
 func (r *Rollback) Run(name string) error {
-	if err := r.cfg.KubeClient.IsReachable(); err != nil {
-		return err
+	start := time.Now()
+	rel, err := r.run(name)
+	r.cfg.observeAction("rollback", start, err)
+	if !r.DryRun {
+		r.cfg.notifyWebhooks("rollback", EventRolledBack, name, rel, err)
+		r.cfg.recordAudit("rollback", name, "", nil, nil, rel, err)
 	}
+	return err
+}
 
-	r.cfg.Releases.MaxHistory = r.MaxHistory
+func (r *Rollback) run(name string) (*release.Release, error) {
+	if err := r.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
 
 	r.cfg.Log("preparing rollback of %s", name)
 	currentRelease, targetRelease, err := r.prepareRollback(name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if !r.DryRun {
+		if err := r.cfg.checkFreeze("rollback", name, targetRelease.Namespace, targetRelease.Labels, r.OverrideFreeze); err != nil {
+			return nil, err
+		}
 	}
 
 	if !r.DryRun {
 		r.cfg.Log("creating rolled back release for %s", name)
-		if err := r.cfg.Releases.Create(targetRelease); err != nil {
-			return err
+		journal(targetRelease, release.JournalCreated)
+		if err := r.cfg.Releases.CreateWithMaxHistory(targetRelease, r.MaxHistory); err != nil {
+			return targetRelease, err
 		}
 	}
 
 	r.cfg.Log("performing rollback of %s", name)
 	if _, err := r.performRollback(currentRelease, targetRelease); err != nil {
-		return err
+		return targetRelease, err
 	}
 
 	if !r.DryRun {
 		r.cfg.Log("updating status for rolled back release for %s", name)
 		if err := r.cfg.Releases.Update(targetRelease); err != nil {
-			return err
+			return targetRelease, err
 		}
 	}
-	return nil
+	return targetRelease, nil
 }
 
 // prepareRollback finds the previous release and prepares a new release object with
@@ -134,6 +159,11 @@ func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Rele
 	if err != nil {
 		return nil, nil, err
 	}
+	for _, rel := range []*release.Release{currentRelease, previousRelease} {
+		if err := checkSchema(rel); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	// Store a new release object with previous release's configuration
 	targetRelease := &release.Release{
@@ -141,6 +171,10 @@ func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Rele
 		Namespace: currentRelease.Namespace,
 		Chart:     previousRelease.Chart,
 		Config:    previousRelease.Config,
+		// The chart of the revision lacks its disabled dependencies, so its
+		// digest is taken over rather than computed again.
+		ChartDigest:  previousRelease.ChartDigest,
+		ValuesDigest: previousRelease.ValuesDigest,
 		Info: &release.Info{
 			FirstDeployed: currentRelease.Info.FirstDeployed,
 			LastDeployed:  helmtime.Now(),
@@ -149,11 +183,16 @@ func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Rele
 			// Because we lose the reference to previous version elsewhere, we set the
 			// message here, and only override it later if we experience failure.
 			Description: fmt.Sprintf("Rollback to %d", previousVersion),
+			DeployedBy:  r.cfg.identity(r.DeployedBy),
 		},
 		Version:  currentRelease.Version + 1,
-		Labels:   previousRelease.Labels,
+		Labels:   withoutTags(previousRelease.Labels),
 		Manifest: previousRelease.Manifest,
 		Hooks:    previousRelease.Hooks,
+		// The manifest is the same, and so are the hashes of its resources.
+		ResourceHashes: previousRelease.ResourceHashes,
+		// Rolling back does not undo a migration to server-side apply.
+		ApplyMethod: currentRelease.ApplyMethod,
 	}
 
 	return currentRelease, targetRelease, nil
@@ -165,18 +204,32 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		return targetRelease, nil
 	}
 
-	current, err := r.cfg.KubeClient.Build(bytes.NewBufferString(currentRelease.Manifest), false)
+	current, err := r.cfg.KubeClient.Build(strings.NewReader(currentRelease.Manifest), false)
 	if err != nil {
-		return targetRelease, errors.Wrap(err, "unable to build kubernetes objects from current release manifest")
+		return targetRelease, fmt.Errorf("%w from current release manifest: %w", errBuildObjects, err)
 	}
-	target, err := r.cfg.KubeClient.Build(bytes.NewBufferString(targetRelease.Manifest), false)
+	if err := addStampedLabels(currentRelease, current); err != nil {
+		return targetRelease, err
+	}
+	target, err := r.cfg.KubeClient.Build(strings.NewReader(targetRelease.Manifest), false)
 	if err != nil {
-		return targetRelease, errors.Wrap(err, "unable to build kubernetes objects from new release manifest")
+		return targetRelease, fmt.Errorf("%w from new release manifest: %w", errBuildObjects, err)
+	}
+
+	if err := r.cfg.applyMetadataPolicy(current, target); err != nil {
+		return targetRelease, err
+	}
+	if err := pruneIgnoredFields(current, target); err != nil {
+		return targetRelease, err
+	}
+
+	if err := r.cfg.writeJournal(targetRelease, release.JournalApplying); err != nil {
+		return targetRelease, err
 	}
 
 	// pre-rollback hooks
 	if !r.DisableHooks {
-		if err := r.cfg.execHook(targetRelease, release.HookPreRollback, r.Timeout); err != nil {
+		if err := r.cfg.execHook(context.Background(), targetRelease, release.HookPreRollback, r.Timeout); err != nil {
 			return targetRelease, err
 		}
 	} else {
@@ -188,7 +241,15 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 	if err != nil {
 		return targetRelease, errors.Wrap(err, "unable to set metadata visitor from target release")
 	}
-	results, err := r.cfg.KubeClient.Update(current, target, r.Force)
+	if r.LabelResources {
+		if err := target.Visit(setReleaseLabelsVisitor(targetRelease)); err != nil {
+			return targetRelease, errors.Wrap(err, "unable to label resources of target release")
+		}
+	}
+	stopProfile := r.cfg.profile("rollback", PhaseApply)
+	results, err := r.cfg.updateResources(targetRelease, current, target, r.Force)
+	stopProfile()
+	r.cfg.addWarnings(targetRelease, results)
 
 	if err != nil {
 		msg := fmt.Sprintf("Rollback %q failed: %s", targetRelease.Name, err)
@@ -212,6 +273,9 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		}
 		return targetRelease, err
 	}
+	if err := r.cfg.writeJournal(targetRelease, release.JournalApplied); err != nil {
+		return targetRelease, err
+	}
 
 	if r.Recreate {
 		// NOTE: Because this is not critical for a release to succeed, we just
@@ -224,29 +288,23 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 	}
 
 	if r.Wait {
-		if r.WaitForJobs {
-			if err := r.cfg.KubeClient.WaitWithJobs(target, r.Timeout); err != nil {
-				targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
-				r.cfg.recordRelease(currentRelease)
-				r.cfg.recordRelease(targetRelease)
-				return targetRelease, errors.Wrapf(err, "release %s failed", targetRelease.Name)
-			}
-		} else {
-			if err := r.cfg.KubeClient.Wait(target, r.Timeout); err != nil {
-				targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
-				r.cfg.recordRelease(currentRelease)
-				r.cfg.recordRelease(targetRelease)
-				return targetRelease, errors.Wrapf(err, "release %s failed", targetRelease.Name)
-			}
+		if err := r.cfg.waitForResources("rollback", target, r.Timeout, r.WaitForJobs); err != nil {
+			targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
+			r.cfg.recordRelease(currentRelease)
+			r.cfg.recordRelease(targetRelease)
+			return targetRelease, errors.Wrapf(err, "release %s failed", targetRelease.Name)
 		}
 	}
 
 	// post-rollback hooks
 	if !r.DisableHooks {
-		if err := r.cfg.execHook(targetRelease, release.HookPostRollback, r.Timeout); err != nil {
+		if err := r.cfg.execHook(context.Background(), targetRelease, release.HookPostRollback, r.Timeout); err != nil {
 			return targetRelease, err
 		}
 	}
+	if err := r.cfg.writeJournal(targetRelease, release.JournalCompleted); err != nil {
+		return targetRelease, err
+	}
 
 	deployed, err := r.cfg.Releases.DeployedAll(currentRelease.Name)
 	if err != nil && !strings.Contains(err.Error(), "has no deployed releases") {
@@ -25,6 +25,7 @@ import (
 	"k8s.io/cli-runtime/pkg/printers"
 	"sigs.k8s.io/yaml"
 
+	"helm.sh/helm/v3/internal/markdown"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
@@ -61,6 +62,15 @@ type Show struct {
 	Devel            bool
 	OutputFormat     ShowOutputFormat
 	JSONPathTemplate string
+	// ReadmeSection only shows the section of the README under the heading
+	// with the given title, including its subsections.
+	ReadmeSection string
+	// ReadmeValuesTable only shows the tables of the README describing the
+	// values of the chart.
+	ReadmeValuesTable bool
+	// GenerateSkeleton shows a values file generated from the values schema
+	// of the chart instead of its values.yaml.
+	GenerateSkeleton bool
 	chart            *chart.Chart // for testing
 }
 
@@ -91,7 +101,7 @@ func (s *Show) SetRegistryClient(client *registry.Client) {
 // Run executes 'helm show' against the given release.
 func (s *Show) Run(chartpath string) (string, error) {
 	if s.chart == nil {
-		chrt, err := loader.Load(chartpath)
+		chrt, err := s.loadChart(chartpath)
 		if err != nil {
 			return "", err
 		}
@@ -111,7 +121,19 @@ func (s *Show) Run(chartpath string) (string, error) {
 		if s.OutputFormat == ShowAll {
 			fmt.Fprintln(&out, "---")
 		}
-		if s.JSONPathTemplate != "" {
+		if s.GenerateSkeleton {
+			if s.JSONPathTemplate != "" {
+				return "", errors.New("cannot generate a values skeleton with a jsonpath expression")
+			}
+			if s.chart.Schema == nil {
+				return "", errors.Errorf("chart %s has no values schema to generate a skeleton from", s.chart.Name())
+			}
+			skeleton, err := chartutil.GenerateValuesSkeleton(s.chart.Schema)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintln(&out, string(skeleton))
+		} else if s.JSONPathTemplate != "" {
 			printer, err := printers.NewJSONPathPrinter(s.JSONPathTemplate)
 			if err != nil {
 				return "", errors.Wrapf(err, "error parsing jsonpath %s", s.JSONPathTemplate)
@@ -132,7 +154,13 @@ func (s *Show) Run(chartpath string) (string, error) {
 			if s.OutputFormat == ShowAll {
 				fmt.Fprintln(&out, "---")
 			}
-			fmt.Fprintf(&out, "%s\n", readme.Data)
+			data, err := s.readme(readme)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&out, "%s\n", data)
+		} else if s.ReadmeSection != "" || s.ReadmeValuesTable {
+			return "", errors.Errorf("chart %s has no README", s.chart.Name())
 		}
 	}
 
@@ -150,15 +178,83 @@ func (s *Show) Run(chartpath string) (string, error) {
 	return out.String(), nil
 }
 
-func findReadme(files []*chart.File) (file *chart.File) {
-	for _, file := range files {
-		for _, n := range readmeFileNames {
-			if file == nil {
+// loadChart loads the parts of the chart needed for the output format. The
+// templates and other files of the chart are only read to show all of it or
+// its CRDs.
+func (s *Show) loadChart(chartpath string) (*chart.Chart, error) {
+	if s.OutputFormat == ShowAll || s.OutputFormat == ShowCRDs {
+		return loader.Load(chartpath)
+	}
+
+	lc, err := loader.Open(chartpath)
+	if err != nil {
+		return nil, err
+	}
+	c := &chart.Chart{
+		Metadata: lc.Metadata,
+		Lock:     lc.Lock,
+		Values:   lc.Values,
+		Schema:   lc.Schema,
+	}
+	if data, err := lc.ReadFile(chartutil.ValuesfileName); err == nil {
+		c.Raw = append(c.Raw, &chart.File{Name: chartutil.ValuesfileName, Data: data})
+	}
+	if s.OutputFormat == ShowReadme {
+		for _, name := range lc.Files() {
+			if !isReadme(name) {
 				continue
 			}
-			if strings.EqualFold(file.Name, n) {
-				return file
+			data, err := lc.ReadFile(name)
+			if err != nil {
+				return nil, err
 			}
+			c.Files = append(c.Files, &chart.File{Name: name, Data: data})
+		}
+	}
+	return c, nil
+}
+
+// readme returns the part of the README selected by the section and values
+// table options.
+func (s *Show) readme(readme *chart.File) ([]byte, error) {
+	data := readme.Data
+	if s.ReadmeSection != "" {
+		section, ok := markdown.Section(data, s.ReadmeSection)
+		if !ok {
+			return nil, errors.Errorf("no section %q in the README of chart %s", s.ReadmeSection, s.chart.Name())
+		}
+		data = section
+	}
+	if s.ReadmeValuesTable {
+		tables := markdown.ValuesTables(data)
+		if len(tables) == 0 {
+			return nil, errors.Errorf("no table of values in the README of chart %s", s.chart.Name())
+		}
+		var b strings.Builder
+		for i, t := range tables {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(t.String())
+		}
+		data = []byte(b.String())
+	}
+	return data, nil
+}
+
+func isReadme(name string) bool {
+	for _, n := range readmeFileNames {
+		if strings.EqualFold(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func findReadme(files []*chart.File) (file *chart.File) {
+	for _, file := range files {
+		if file != nil && isReadme(file.Name) {
+			return file
 		}
 	}
 	return nil
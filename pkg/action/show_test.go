@@ -151,3 +151,43 @@ bar
 		t.Errorf("Expected\n%q\nGot\n%q\n", expect, output)
 	}
 }
+
+func TestShowReadmeSection(t *testing.T) {
+	client := NewShow(ShowReadme)
+	client.chart = &chart.Chart{
+		Metadata: &chart.Metadata{Name: "alpine"},
+		Files: []*chart.File{
+			{Name: "README.md", Data: []byte("# Alpine\n\n## Configuration\n\n| Parameter | Default |\n|---|---|\n| image | alpine |\n\n## License\n\nApache\n")},
+		},
+	}
+
+	client.ReadmeSection = "configuration"
+	output, err := client.Run("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "## Configuration\n\n| Parameter | Default |\n|---|---|\n| image | alpine |\n\n"
+	if output != expect {
+		t.Errorf("Expected\n%q\nGot\n%q\n", expect, output)
+	}
+
+	client.ReadmeValuesTable = true
+	output, err = client.Run("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = "| Parameter | Default |\n| --------- | ------- |\n| image     | alpine  |\n\n"
+	if output != expect {
+		t.Errorf("Expected\n%q\nGot\n%q\n", expect, output)
+	}
+
+	client.ReadmeSection = "License"
+	if _, err := client.Run(""); err == nil || err.Error() != "no table of values in the README of chart alpine" {
+		t.Errorf("expected an error for a section without values, got %v", err)
+	}
+
+	client.ReadmeSection = "Missing"
+	if _, err := client.Run(""); err == nil || err.Error() != `no section "Missing" in the README of chart alpine` {
+		t.Errorf("expected an error for a missing section, got %v", err)
+	}
+}
@@ -17,11 +17,15 @@ limitations under the License.
 package action
 
 import (
-	"bytes"
 	"errors"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
 )
 
 // Status is the action for checking the deployment status of releases.
@@ -44,6 +48,11 @@ type Status struct {
 	// ShowResourcesTable is used with ShowResources. When true this will cause
 	// the resulting objects to be retrieved as a kind=table.
 	ShowResourcesTable bool
+
+	// ShowHooks sets if the last execution of the hooks should be retrieved
+	// with the status, including the exit codes of the containers of their
+	// pods.
+	ShowHooks bool
 }
 
 // NewStatus creates a new Status object with the given configuration.
@@ -59,37 +68,93 @@ func (s *Status) Run(name string) (*release.Release, error) {
 		return nil, err
 	}
 
-	if !s.ShowResources {
-		return s.cfg.releaseContent(name, s.Version)
-	}
-
 	rel, err := s.cfg.releaseContent(name, s.Version)
 	if err != nil {
 		return nil, err
 	}
 
-	if kubeClient, ok := s.cfg.KubeClient.(kube.InterfaceResources); ok {
-		var resources kube.ResourceList
+	if s.ShowHooks {
+		rel.Info.HookStatuses = s.cfg.hookStatuses(rel)
+	}
+	if !s.ShowResources {
+		return rel, nil
+	}
+
+	kubeClient, ok := s.cfg.KubeClient.(kube.InterfaceResources)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceResources")
+	}
+
+	// The live status of the resources includes everything needed for a
+	// table, so only fetch the resources as a table when it is unavailable.
+	statusClient, hasStatus := s.cfg.KubeClient.(kube.InterfaceStatus)
+	if hasStatus {
+		resources, err := s.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
+		if err != nil {
+			return nil, err
+		}
+		statuses, err := statusClient.Status(resources)
+		if err != nil {
+			return nil, err
+		}
+		rel.Info.ResourceStatuses = releaseResourceStatuses(statuses)
 		if s.ShowResourcesTable {
-			resources, err = kubeClient.BuildTable(bytes.NewBufferString(rel.Manifest), false)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			resources, err = s.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
-			if err != nil {
-				return nil, err
-			}
+			return rel, nil
 		}
+	}
 
-		resp, err := kubeClient.Get(resources, true)
+	var resources kube.ResourceList
+	if s.ShowResourcesTable {
+		resources, err = kubeClient.BuildTable(strings.NewReader(rel.Manifest), false)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		resources, err = s.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		rel.Info.Resources = resp
+	resp, err := kubeClient.Get(resources, true)
+	if err != nil {
+		return nil, err
+	}
 
-		return rel, nil
+	rel.Info.Resources = resp
+
+	return rel, nil
+}
+
+// releaseResourceStatuses converts the live status of resources for a
+// release, grouped by kind.
+func releaseResourceStatuses(statuses []kube.ResourceStatus) []release.ResourceStatus {
+	result := make([]release.ResourceStatus, 0, len(statuses))
+	for _, st := range statuses {
+		var gvk schema.GroupVersionKind
+		if st.Info.Mapping != nil {
+			gvk = st.Info.Mapping.GroupVersionKind
+		} else if st.Info.Object != nil {
+			gvk = st.Info.Object.GetObjectKind().GroupVersionKind()
+		}
+		rs := release.ResourceStatus{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Name:       st.Info.Name,
+			Namespace:  st.Info.Namespace,
+			Health:     st.Health,
+			Message:    st.Message,
+		}
+		if !st.CreationTimestamp.IsZero() {
+			rs.Created = helmtime.Time{Time: st.CreationTimestamp}
+		}
+		result = append(result, rs)
 	}
-	return nil, errors.New("unable to get kubeClient with interface InterfaceResources")
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].APIVersion != result[j].APIVersion {
+			return result[i].APIVersion < result[j].APIVersion
+		}
+		return result[i].Kind < result[j].Kind
+	})
+	return result
 }
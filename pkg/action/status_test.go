@@ -0,0 +1,72 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+func TestStatusShowResources(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	cfg.KubeClient = &kubefake.FailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}, BuildDummy: true}
+	rel := releaseStub()
+	assert.NoError(t, cfg.Releases.Create(rel))
+
+	client := NewStatus(cfg)
+	client.ShowResources = true
+	client.ShowResourcesTable = true
+	res, err := client.Run(rel.Name)
+	assert.NoError(t, err)
+	if assert.Len(t, res.Info.ResourceStatuses, 1) {
+		assert.Equal(t, "dummyName", res.Info.ResourceStatuses[0].Name)
+		assert.Equal(t, kube.HealthReady, res.Info.ResourceStatuses[0].Health)
+	}
+	// The table is built from the statuses, so the resources are not fetched.
+	assert.Nil(t, res.Info.Resources)
+}
+
+func TestReleaseResourceStatuses(t *testing.T) {
+	info := func(kind, name string) *resource.Info {
+		return &resource.Info{
+			Name: name,
+			Mapping: &meta.RESTMapping{
+				GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: kind},
+			},
+		}
+	}
+	statuses := releaseResourceStatuses([]kube.ResourceStatus{
+		{Info: info("Service", "a"), Health: kube.HealthReady},
+		{Info: info("ConfigMap", "b"), Health: kube.HealthMissing},
+		{Info: info("Service", "c"), Health: kube.HealthNotReady},
+	})
+
+	var names []string
+	for _, st := range statuses {
+		names = append(names, st.Kind+"/"+st.Name)
+	}
+	assert.Equal(t, []string{"ConfigMap/b", "Service/a", "Service/c"}, names)
+	assert.True(t, statuses[0].Created.IsZero())
+}
@@ -0,0 +1,308 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+// SuspendPolicyAnno is the annotation of workloads that controls whether they
+// are scaled to zero when their release is suspended.
+const SuspendPolicyAnno = "helm.sh/suspend-policy"
+
+// SuspendSkipPolicy is the value of SuspendPolicyAnno that leaves a workload
+// running when its release is suspended.
+const SuspendSkipPolicy = "skip"
+
+// suspendableKinds are the kinds of the workloads scaled by a suspension.
+var suspendableKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+}
+
+// Suspend is the action for scaling the workloads of a release to zero.
+//
+// It provides the implementation of 'helm suspend'. The replicas the
+// workloads ran are recorded in the release, so that Resume can scale them
+// back. Workloads annotated with SuspendPolicyAnno "skip" keep running.
+type Suspend struct {
+	cfg *Configuration
+}
+
+// NewSuspend creates a new Suspend object with the given configuration.
+func NewSuspend(cfg *Configuration) *Suspend {
+	return &Suspend{
+		cfg: cfg,
+	}
+}
+
+// Run executes 'helm suspend' against the given release.
+func (s *Suspend) Run(name string) (*release.Release, error) {
+	start := time.Now()
+	rel, err := s.run(name)
+	s.cfg.observeAction("suspend", start, err)
+	s.cfg.recordAudit("suspend", name, "", nil, nil, rel, err)
+	return rel, err
+}
+
+func (s *Suspend) run(name string) (*release.Release, error) {
+	kubeClient, rel, err := s.cfg.scalableRelease(name)
+	if err != nil {
+		return nil, err
+	}
+	if rel.Suspension != nil {
+		return nil, errors.Errorf("release %s is already suspended", name)
+	}
+	if rel.Info.Status != release.StatusDeployed {
+		return nil, errors.Errorf("release %s is %s, only deployed releases can be suspended", name, rel.Info.Status)
+	}
+
+	workloads, _, err := s.cfg.releaseWorkloads(rel)
+	if err != nil {
+		return nil, err
+	}
+	replicas, err := kubeClient.Replicas(workloads)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get the replicas of the workloads of release %s", name)
+	}
+
+	// Workloads already scaled to zero have nothing to restore.
+	suspension := &release.Suspension{Suspended: helmtime.Now()}
+	var running kube.ResourceList
+	for i, info := range workloads {
+		if replicas[i] == 0 {
+			continue
+		}
+		running = append(running, info)
+		suspension.Workloads = append(suspension.Workloads, release.SuspendedWorkload{
+			Kind:      info.Mapping.GroupVersionKind.Kind,
+			Name:      info.Name,
+			Namespace: info.Namespace,
+			Replicas:  replicas[i],
+		})
+	}
+	if len(running) == 0 {
+		return nil, errors.Errorf("release %s has no running workloads to suspend", name)
+	}
+
+	// The suspension is recorded before scaling, so that a release whose
+	// workloads were only partially scaled can be resumed.
+	rel.Suspension = suspension
+	if err := s.cfg.Releases.Update(rel); err != nil {
+		return nil, err
+	}
+
+	s.cfg.Log("scaling %d workloads of %s to zero", len(running), name)
+	if err := kubeClient.Scale(running, make([]int32, len(running))); err != nil {
+		return rel, errors.Wrapf(err, "unable to suspend release %s", name)
+	}
+	return rel, nil
+}
+
+// Resume is the action for scaling the workloads of a suspended release back
+// to the replicas they ran before.
+//
+// It provides the implementation of 'helm resume'. Replicas are kept within
+// the bounds of the HorizontalPodAutoscalers of the release that target the
+// workloads.
+type Resume struct {
+	cfg *Configuration
+
+	Wait    bool
+	Timeout time.Duration
+}
+
+// NewResume creates a new Resume object with the given configuration.
+func NewResume(cfg *Configuration) *Resume {
+	return &Resume{
+		cfg: cfg,
+	}
+}
+
+// Run executes 'helm resume' against the given release.
+func (r *Resume) Run(name string) (*release.Release, error) {
+	start := time.Now()
+	rel, err := r.run(name)
+	r.cfg.observeAction("resume", start, err)
+	r.cfg.recordAudit("resume", name, "", nil, nil, rel, err)
+	return rel, err
+}
+
+func (r *Resume) run(name string) (*release.Release, error) {
+	kubeClient, rel, err := r.cfg.scalableRelease(name)
+	if err != nil {
+		return nil, err
+	}
+	if rel.Suspension == nil {
+		return nil, errors.Errorf("release %s is not suspended", name)
+	}
+
+	workloads, bounds, err := r.cfg.releaseWorkloads(rel)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*resource.Info, len(workloads))
+	for _, info := range workloads {
+		byKey[workloadKey(info.Mapping.GroupVersionKind.Kind, info.Namespace, info.Name)] = info
+	}
+
+	var resumed kube.ResourceList
+	var replicas []int32
+	for _, w := range rel.Suspension.Workloads {
+		key := workloadKey(w.Kind, w.Namespace, w.Name)
+		info, ok := byKey[key]
+		if !ok {
+			r.cfg.Log("skipping %s %s, which is no longer a suspendable workload of %s", w.Kind, w.Name, name)
+			continue
+		}
+		n := w.Replicas
+		if b, ok := bounds[key]; ok {
+			n = b.clamp(n)
+		}
+		resumed = append(resumed, info)
+		replicas = append(replicas, n)
+	}
+
+	r.cfg.Log("scaling %d workloads of %s back up", len(resumed), name)
+	if len(resumed) > 0 {
+		if err := kubeClient.Scale(resumed, replicas); err != nil {
+			return rel, errors.Wrapf(err, "unable to resume release %s", name)
+		}
+	}
+
+	rel.Suspension = nil
+	if err := r.cfg.Releases.Update(rel); err != nil {
+		return rel, err
+	}
+
+	if r.Wait && len(resumed) > 0 {
+		if err := r.cfg.waitForResources("resume", resumed, r.Timeout, false); err != nil {
+			return rel, errors.Wrapf(err, "release %s was resumed, but its workloads did not become ready", name)
+		}
+	}
+	return rel, nil
+}
+
+// scalableRelease returns the client to scale the workloads of the last
+// revision of the named release with, and that revision.
+func (cfg *Configuration) scalableRelease(name string) (kube.InterfaceScale, *release.Release, error) {
+	if err := cfg.KubeClient.IsReachable(); err != nil {
+		return nil, nil, err
+	}
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return nil, nil, errors.Errorf("release name is invalid: %s", name)
+	}
+	kubeClient, ok := cfg.KubeClient.(kube.InterfaceScale)
+	if !ok {
+		return nil, nil, errors.New("the Kubernetes client does not support scaling")
+	}
+
+	rel, err := cfg.Releases.Last(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rel.Info.Status.IsPending() {
+		return nil, nil, errPending
+	}
+	if err := checkSchema(rel); err != nil {
+		return nil, nil, err
+	}
+	return kubeClient, rel, nil
+}
+
+// replicaBounds are the minimum and maximum replicas of a workload set by a
+// HorizontalPodAutoscaler.
+type replicaBounds struct {
+	min, max int32
+}
+
+func (b replicaBounds) clamp(n int32) int32 {
+	if n < b.min {
+		return b.min
+	}
+	if b.max > 0 && n > b.max {
+		return b.max
+	}
+	return n
+}
+
+// releaseWorkloads returns the workloads of a release that can be suspended,
+// leaving out those annotated to be skipped, and the replica bounds of its
+// HorizontalPodAutoscalers by the key of the workload they target.
+func (cfg *Configuration) releaseWorkloads(rel *release.Release) (kube.ResourceList, map[string]replicaBounds, error) {
+	resources, err := cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w from release manifest: %w", errBuildObjects, err)
+	}
+
+	var workloads kube.ResourceList
+	bounds := map[string]replicaBounds{}
+	for _, info := range resources {
+		kind := info.Mapping.GroupVersionKind.Kind
+		switch {
+		case suspendableKinds[kind]:
+			accessor, err := meta.Accessor(info.Object)
+			if err != nil {
+				return nil, nil, err
+			}
+			policy := accessor.GetAnnotations()[SuspendPolicyAnno]
+			if strings.ToLower(strings.TrimSpace(policy)) == SuspendSkipPolicy {
+				cfg.Log("skipping %s %s as annotated with %s", kind, info.Name, SuspendPolicyAnno)
+				continue
+			}
+			workloads = append(workloads, info)
+		case kind == "HorizontalPodAutoscaler":
+			obj, err := unstructuredContent(info.Object)
+			if err != nil {
+				return nil, nil, err
+			}
+			targetKind, _, _ := unstructured.NestedString(obj, "spec", "scaleTargetRef", "kind")
+			targetName, _, _ := unstructured.NestedString(obj, "spec", "scaleTargetRef", "name")
+			minReplicas, found, _ := unstructured.NestedInt64(obj, "spec", "minReplicas")
+			if !found {
+				minReplicas = 1
+			}
+			maxReplicas, _, _ := unstructured.NestedInt64(obj, "spec", "maxReplicas")
+			bounds[workloadKey(targetKind, info.Namespace, targetName)] = replicaBounds{min: int32(minReplicas), max: int32(maxReplicas)}
+		}
+	}
+	return workloads, bounds, nil
+}
+
+func workloadKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+func unstructuredContent(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.Object, nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
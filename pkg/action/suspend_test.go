@@ -0,0 +1,143 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+const suspendManifest = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  annotations:
+    helm.sh/suspend-policy: skip
+spec:
+  replicas: 1
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  replicas: 4
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: worker
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: worker
+  minReplicas: 2
+  maxReplicas: 3
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`
+
+func TestSuspendResume(t *testing.T) {
+	is := assert.New(t)
+	config := actionConfigFixture(t)
+	failer := config.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	rel := releaseStub()
+	rel.Manifest = suspendManifest
+	is.NoError(config.Releases.Create(rel))
+
+	suspended, err := NewSuspend(config).Run(rel.Name)
+	is.NoError(err)
+	is.Equal([]release.SuspendedWorkload{
+		{Kind: "Deployment", Name: "web", Namespace: "default", Replicas: 3},
+		{Kind: "Deployment", Name: "worker", Namespace: "default", Replicas: 4},
+	}, suspended.Suspension.Workloads)
+
+	stored, err := config.Releases.Get(rel.Name, rel.Version)
+	is.NoError(err)
+	is.NotNil(stored.Suspension)
+
+	scales := failer.OperationsOf(kubefake.VerbScale)
+	is.Len(scales, 1)
+	is.Len(scales[0].Resources, 2)
+	replicas, err := failer.Replicas(scales[0].Resources)
+	is.NoError(err)
+	is.Equal([]int32{0, 0}, replicas)
+
+	_, err = NewSuspend(config).Run(rel.Name)
+	is.EqualError(err, "release angry-panda is already suspended")
+
+	resumed, err := NewResume(config).Run(rel.Name)
+	is.NoError(err)
+	is.Nil(resumed.Suspension)
+
+	// The worker is kept within the bounds of its autoscaler.
+	replicas, err = failer.Replicas(scales[0].Resources)
+	is.NoError(err)
+	is.Equal([]int32{3, 3}, replicas)
+
+	_, err = NewResume(config).Run(rel.Name)
+	is.EqualError(err, "release angry-panda is not suspended")
+}
+
+func TestSuspend_ScaleFailure(t *testing.T) {
+	is := assert.New(t)
+	config := actionConfigFixture(t)
+	failer := config.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	failer.Failures = []kubefake.Failure{{Verb: kubefake.VerbScale, Times: 1, Err: errors.New("forbidden")}}
+	rel := releaseStub()
+	rel.Manifest = suspendManifest
+	is.NoError(config.Releases.Create(rel))
+
+	_, err := NewSuspend(config).Run(rel.Name)
+	is.ErrorContains(err, "forbidden")
+
+	// The suspension is recorded, so that the release can be resumed.
+	stored, err := config.Releases.Get(rel.Name, rel.Version)
+	is.NoError(err)
+	is.NotNil(stored.Suspension)
+	_, err = NewResume(config).Run(rel.Name)
+	is.NoError(err)
+}
+
+func TestSuspend_NotDeployed(t *testing.T) {
+	config := actionConfigFixture(t)
+	rel := namedReleaseStub("failed", release.StatusFailed)
+	assert.NoError(t, config.Releases.Create(rel))
+
+	_, err := NewSuspend(config).Run(rel.Name)
+	assert.EqualError(t, err, "release failed is failed, only deployed releases can be suspended")
+}
@@ -17,12 +17,17 @@ limitations under the License.
 package action
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/kube"
@@ -31,6 +36,14 @@ import (
 	helmtime "helm.sh/helm/v3/pkg/time"
 )
 
+// UninstallWaveAnno is the annotation that assigns a resource to a deletion
+// wave on uninstall.
+//
+// Resources are deleted wave by wave in ascending order, and each wave is only
+// deleted once all resources of the previous wave are gone. Resources without
+// the annotation belong to wave 0.
+const UninstallWaveAnno = "helm.sh/uninstall-wave"
+
 // Uninstall is the action for uninstalling releases.
 //
 // It provides the implementation of 'helm uninstall'.
@@ -44,7 +57,14 @@ type Uninstall struct {
 	Wait                bool
 	DeletionPropagation string
 	Timeout             time.Duration
-	Description         string
+	// TimeoutPerResource limits how long to wait for any single resource to
+	// be deleted, waiting for the resources one after the other. If unset,
+	// Timeout is used for all resources together.
+	TimeoutPerResource time.Duration
+	// RemoveFinalizers removes the finalizers of resources that are stuck on
+	// deletion, as long as their kind is listed in kube.FinalizerRemovableKinds.
+	RemoveFinalizers bool
+	Description      string
 }
 
 // NewUninstall creates a new Uninstall object with the given configuration.
@@ -56,6 +76,21 @@ func NewUninstall(cfg *Configuration) *Uninstall {
 
 // Run uninstalls the given release.
 func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error) {
+	start := time.Now()
+	res, err := u.run(name)
+	u.cfg.observeAction("uninstall", start, err)
+	if !u.DryRun && (res != nil || err != nil) {
+		var rel *release.Release
+		if res != nil {
+			rel = res.Release
+		}
+		u.cfg.notifyWebhooks("uninstall", EventUninstalled, name, rel, err)
+		u.cfg.recordAudit("uninstall", name, "", nil, nil, rel, err)
+	}
+	return res, err
+}
+
+func (u *Uninstall) run(name string) (*release.UninstallReleaseResponse, error) {
 	if err := u.cfg.KubeClient.IsReachable(); err != nil {
 		return nil, err
 	}
@@ -86,6 +121,12 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 
 	releaseutil.SortByRevision(rels)
 	rel := rels[len(rels)-1]
+	if rel.NewerSchema() {
+		// The record keeps the fields this version of Helm does not know
+		// about, so uninstalling is safe, if not as thorough as the newer
+		// version of Helm would be.
+		u.cfg.Log("warning: release %s was written by Helm %s with a newer release schema", name, rel.HelmVersion)
+	}
 
 	// TODO: Are there any cases where we want to force a delete even if it's
 	// already marked deleted?
@@ -99,6 +140,10 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 		return nil, errors.Errorf("the release named %q is already deleted", name)
 	}
 
+	if err := u.checkDeleteAccess(rel); err != nil {
+		return nil, err
+	}
+
 	u.cfg.Log("uninstall: Deleting %s", name)
 	rel.Info.Status = release.StatusUninstalling
 	rel.Info.Deleted = helmtime.Now()
@@ -106,7 +151,7 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 	res := &release.UninstallReleaseResponse{Release: rel}
 
 	if !u.DisableHooks {
-		if err := u.cfg.execHook(rel, release.HookPreDelete, u.Timeout); err != nil {
+		if err := u.cfg.execHook(context.Background(), rel, release.HookPreDelete, u.Timeout); err != nil {
 			return res, err
 		}
 	} else {
@@ -131,15 +176,13 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 	res.Info = kept
 
 	if u.Wait {
-		if kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceExt); ok {
-			if err := kubeClient.WaitForDelete(deletedResources, u.Timeout); err != nil {
-				errs = append(errs, err)
-			}
+		if err := u.waitForDeletion(deletedResources); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
 	if !u.DisableHooks {
-		if err := u.cfg.execHook(rel, release.HookPostDelete, u.Timeout); err != nil {
+		if err := u.cfg.execHook(context.Background(), rel, release.HookPostDelete, u.Timeout); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -201,8 +244,16 @@ func (u *Uninstall) deleteRelease(rel *release.Release) (kube.ResourceList, stri
 		return nil, rel.Manifest, []error{errors.Wrap(err, "could not get apiVersions from Kubernetes")}
 	}
 
+	kindOrder, err := u.cfg.kindOrder()
+	if err != nil {
+		return nil, rel.Manifest, []error{err}
+	}
+	uninstallOrder, err := kindOrder.UninstallOrder()
+	if err != nil {
+		return nil, rel.Manifest, []error{err}
+	}
 	manifests := releaseutil.SplitManifests(rel.Manifest)
-	_, files, err := releaseutil.SortManifests(manifests, caps.APIVersions, releaseutil.UninstallOrder)
+	_, files, err := releaseutil.SortManifests(manifests, caps.APIVersions, uninstallOrder)
 	if err != nil {
 		// We could instead just delete everything in no particular order.
 		// FIXME: One way to delete at this point would be to try a label-based
@@ -217,23 +268,187 @@ func (u *Uninstall) deleteRelease(rel *release.Release) (kube.ResourceList, stri
 		kept += "[" + f.Head.Kind + "] " + f.Head.Metadata.Name + "\n"
 	}
 
+	var resources kube.ResourceList
+	waves := splitUninstallWaves(filesToDelete)
+	for i, wave := range waves {
+		var builder strings.Builder
+		for _, file := range wave {
+			builder.WriteString("\n---\n" + file.Content)
+		}
+
+		res, err := u.cfg.KubeClient.Build(strings.NewReader(builder.String()), false)
+		if err != nil {
+			return nil, "", []error{fmt.Errorf("%w for delete: %w", errBuildObjects, err)}
+		}
+		if len(res) == 0 {
+			continue
+		}
+		resources = append(resources, res...)
+		if errs = u.deleteResources(res); errs != nil {
+			return resources, kept, errs
+		}
+
+		// The next wave is only deleted once the current one is gone.
+		if i < len(waves)-1 {
+			u.cfg.Log("uninstall: Waiting for deletion wave %d of %d", i+1, len(waves))
+			if err := u.waitForDeletion(res); err != nil {
+				return resources, kept, []error{err}
+			}
+		}
+	}
+	return resources, kept, nil
+}
+
+// checkDeleteAccess verifies that the resources of a release deployed into
+// several namespaces may be deleted, other than those kept by the resource
+// policy, before the hooks and the deletion of the release start.
+func (u *Uninstall) checkDeleteAccess(rel *release.Release) error {
+	if len(rel.Namespaces) == 0 {
+		return nil
+	}
+	manifests := releaseutil.SplitManifests(rel.Manifest)
+	files := make([]releaseutil.Manifest, 0, len(manifests))
+	for name, content := range manifests {
+		var head releaseutil.SimpleHead
+		if err := yaml.Unmarshal([]byte(content), &head); err != nil {
+			return errors.Wrapf(err, "YAML parse error on %s", name)
+		}
+		files = append(files, releaseutil.Manifest{Name: name, Content: content, Head: &head})
+	}
+	_, filesToDelete := filterManifestsToKeep(files)
+
 	var builder strings.Builder
 	for _, file := range filesToDelete {
 		builder.WriteString("\n---\n" + file.Content)
 	}
-
 	resources, err := u.cfg.KubeClient.Build(strings.NewReader(builder.String()), false)
 	if err != nil {
-		return nil, "", []error{errors.Wrap(err, "unable to build kubernetes objects for delete")}
+		return fmt.Errorf("%w for delete: %w", errBuildObjects, err)
 	}
-	if len(resources) > 0 {
-		if kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceDeletionPropagation); ok {
-			_, errs = kubeClient.DeleteWithPropagationPolicy(resources, parseCascadingFlag(u.cfg, u.DeletionPropagation))
-			return resources, kept, errs
+	return errors.Wrap(u.cfg.checkAccess(resources, rel.Namespace, "delete"), "uninstall")
+}
+
+func (u *Uninstall) deleteResources(resources kube.ResourceList) []error {
+	if kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceDeletionPropagation); ok {
+		_, errs := kubeClient.DeleteWithPropagationPolicy(resources, parseCascadingFlag(u.cfg, u.DeletionPropagation))
+		return errs
+	}
+	_, errs := u.cfg.KubeClient.Delete(resources)
+	return errs
+}
+
+// splitUninstallWaves groups the manifests by their deletion wave, in the
+// order the waves are to be deleted. The order of the manifests within a wave
+// is kept.
+func splitUninstallWaves(manifests []releaseutil.Manifest) [][]releaseutil.Manifest {
+	byWave := make(map[int][]releaseutil.Manifest)
+	for _, m := range manifests {
+		wave := 0
+		if m.Head.Metadata != nil {
+			if w, ok := m.Head.Metadata.Annotations[UninstallWaveAnno]; ok {
+				// Like hook weights, invalid waves fall back to 0.
+				wave, _ = strconv.Atoi(strings.TrimSpace(w))
+			}
 		}
-		_, errs = u.cfg.KubeClient.Delete(resources)
+		byWave[wave] = append(byWave[wave], m)
+	}
+
+	order := make([]int, 0, len(byWave))
+	for wave := range byWave {
+		order = append(order, wave)
+	}
+	sort.Ints(order)
+
+	waves := make([][]releaseutil.Manifest, 0, len(order))
+	for _, wave := range order {
+		waves = append(waves, byWave[wave])
+	}
+	return waves
+}
+
+// defaultDeletionTimeout is how long the deletion of resources is waited for
+// when neither Timeout nor TimeoutPerResource is set, as by SDK callers.
+const defaultDeletionTimeout = 5 * time.Minute
+
+// waitForDeletion waits for the given resources to be deleted.
+//
+// If the wait fails, resources that are held back by finalizers are reported.
+// With RemoveFinalizers set, the finalizers of supported kinds are removed and
+// the wait is retried once.
+func (u *Uninstall) waitForDeletion(resources kube.ResourceList) error {
+	kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceExt)
+	if !ok {
+		return nil
+	}
+
+	err := u.waitForDelete(kubeClient, resources)
+	if err == nil {
+		return nil
+	}
+	finalizerClient, ok := u.cfg.KubeClient.(kube.InterfaceFinalizers)
+	if !ok {
+		return err
+	}
+	stuck, stuckErr := finalizerClient.StuckOnFinalizers(resources)
+	if stuckErr != nil || len(stuck) == 0 {
+		return err
+	}
+
+	if u.RemoveFinalizers {
+		var removable kube.ResourceList
+		for _, s := range stuck {
+			if kube.IsFinalizerRemovable(s.Info.Mapping.GroupVersionKind.Kind) {
+				removable = append(removable, s.Info)
+			}
+		}
+		if len(removable) > 0 {
+			if err := finalizerClient.RemoveFinalizers(removable); err != nil {
+				return err
+			}
+			if err = u.waitForDelete(kubeClient, resources); err == nil {
+				return nil
+			}
+			if stuck, stuckErr = finalizerClient.StuckOnFinalizers(resources); stuckErr != nil || len(stuck) == 0 {
+				return err
+			}
+		}
+	}
+	return stuckFinalizersError(stuck, u.RemoveFinalizers)
+}
+
+// waitForDelete waits for the resources to be deleted, up to
+// TimeoutPerResource for each resource if set, or else up to Timeout for all
+// of them.
+func (u *Uninstall) waitForDelete(kubeClient kube.InterfaceExt, resources kube.ResourceList) error {
+	if u.TimeoutPerResource <= 0 {
+		timeout := u.Timeout
+		if timeout <= 0 {
+			timeout = defaultDeletionTimeout
+		}
+		return kubeClient.WaitForDelete(resources, timeout)
+	}
+	for _, r := range resources {
+		if err := kubeClient.WaitForDelete(kube.ResourceList{r}, u.TimeoutPerResource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stuckFinalizersError describes the resources stuck on finalizers and how to
+// resolve the situation.
+func stuckFinalizersError(stuck []kube.StuckResource, removeFinalizers bool) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d resource(s) could not be deleted because of pending finalizers:", len(stuck))
+	for _, s := range stuck {
+		b.WriteString("\n  " + s.String())
+	}
+	b.WriteString("\nMake sure the controllers handling these finalizers are running, or remove the finalizers manually")
+	b.WriteString(" (e.g. kubectl patch <kind> <name> --type=merge -p '{\"metadata\":{\"finalizers\":null}}')")
+	if !removeFinalizers {
+		fmt.Fprintf(&b, ". For the kinds %s this can be done with --remove-finalizers", strings.Join(kube.FinalizerRemovableKinds, ", "))
 	}
-	return resources, kept, errs
+	return errors.New(b.String())
 }
 
 func parseCascadingFlag(cfg *Configuration, cascadingFlag string) v1.DeletionPropagation {
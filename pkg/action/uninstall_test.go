@@ -19,11 +19,17 @@ package action
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
 
+	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 )
 
 func uninstallAction(t *testing.T) *Uninstall {
@@ -138,3 +144,138 @@ func TestUninstallRelease_Cascade(t *testing.T) {
 	is.Error(err)
 	is.Contains(err.Error(), "failed to delete release: come-fail-away")
 }
+
+func TestSplitUninstallWaves(t *testing.T) {
+	is := assert.New(t)
+
+	manifest := func(name, wave string) releaseutil.Manifest {
+		m := releaseutil.Manifest{Name: name, Head: &releaseutil.SimpleHead{Kind: "ConfigMap"}}
+		m.Head.Metadata = &struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		}{Name: name}
+		if wave != "" {
+			m.Head.Metadata.Annotations = map[string]string{UninstallWaveAnno: wave}
+		}
+		return m
+	}
+
+	waves := splitUninstallWaves([]releaseutil.Manifest{
+		manifest("a", "1"),
+		manifest("b", ""),
+		manifest("c", "-1"),
+		manifest("d", "invalid"),
+		manifest("e", " 1 "),
+	})
+
+	var names [][]string
+	for _, wave := range waves {
+		var ns []string
+		for _, m := range wave {
+			ns = append(ns, m.Name)
+		}
+		names = append(names, ns)
+	}
+	is.Equal([][]string{{"c"}, {"b", "d"}, {"a", "e"}}, names)
+}
+
+func TestUninstallRelease_deleteInWaves(t *testing.T) {
+	is := assert.New(t)
+
+	unAction := uninstallAction(t)
+	unAction.DisableHooks = true
+
+	rel := releaseStub()
+	rel.Name = "waves"
+	rel.Manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+  annotations:
+    helm.sh/uninstall-wave: "-1"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: last
+`
+	unAction.cfg.Releases.Create(rel)
+
+	res, err := unAction.Run(rel.Name)
+	is.NoError(err)
+	is.Equal(release.StatusUninstalled, res.Release.Info.Status)
+}
+
+// deleteWaits records the waits for the deletion of resources.
+type deleteWaits struct {
+	*kubefake.FailingKubeClient
+	waits []string
+}
+
+func (c *deleteWaits) WaitForDelete(resources kube.ResourceList, timeout time.Duration) error {
+	c.waits = append(c.waits, fmt.Sprintf("%d resource(s) for %s", len(resources), timeout))
+	return c.FailingKubeClient.WaitForDelete(resources, timeout)
+}
+
+func TestUninstallRelease_waitBetweenWaves(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first-a
+  annotations:
+    helm.sh/uninstall-wave: "-1"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first-b
+  annotations:
+    helm.sh/uninstall-wave: "-1"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: last
+`
+	uninstall := func(t *testing.T, timeout, perResource time.Duration) []string {
+		t.Helper()
+		unAction := uninstallAction(t)
+		unAction.DisableHooks = true
+		unAction.Timeout = timeout
+		unAction.TimeoutPerResource = perResource
+		failer := unAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+		failer.ParseManifests = true
+		client := &deleteWaits{FailingKubeClient: failer}
+		unAction.cfg.KubeClient = client
+
+		rel := releaseStub()
+		rel.Name = "waves"
+		rel.Manifest = manifest
+		unAction.cfg.Releases.Create(rel)
+		_, err := unAction.Run(rel.Name)
+		assert.NoError(t, err)
+		return client.waits
+	}
+
+	assert.Equal(t, []string{"2 resource(s) for 1m0s"}, uninstall(t, time.Minute, 0))
+	assert.Equal(t, []string{"2 resource(s) for 5m0s"}, uninstall(t, 0, 0), "expected a default timeout for SDK callers")
+	assert.Equal(t, []string{"1 resource(s) for 10s", "1 resource(s) for 10s"}, uninstall(t, time.Minute, 10*time.Second))
+}
+
+func TestUninstallRelease_stuckOnFinalizers(t *testing.T) {
+	is := assert.New(t)
+
+	info := &resource.Info{
+		Name:      "data",
+		Namespace: "default",
+		Mapping:   &meta.RESTMapping{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}},
+	}
+	stuck := []kube.StuckResource{{Info: info, Finalizers: []string{"kubernetes.io/pvc-protection"}}}
+
+	err := stuckFinalizersError(stuck, false)
+	is.Contains(err.Error(), "PersistentVolumeClaim default/data is waiting on finalizers [kubernetes.io/pvc-protection]")
+	is.Contains(err.Error(), "--remove-finalizers")
+
+	err = stuckFinalizersError(stuck, true)
+	is.NotContains(err.Error(), "--remove-finalizers")
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package action
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"strings"
@@ -30,6 +29,7 @@ import (
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/errcode"
 	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/registry"
@@ -87,12 +87,19 @@ type Upgrade struct {
 	ReuseValues bool
 	// ResetThenReuseValues will reset the values to the chart's built-ins then merge with user's last supplied values.
 	ResetThenReuseValues bool
+	// ReuseValuesFor, if set, resets the values to the chart's built-ins then
+	// merges with the user's last supplied values at the given dotted paths
+	// only, e.g. "image" or "ingress.hosts".
+	ReuseValuesFor []string
 	// Recreate will (if true) recreate pods after a rollback.
 	Recreate bool
 	// MaxHistory limits the maximum number of revisions saved per release
 	MaxHistory int
 	// Atomic, if true, will roll back on failure.
 	Atomic bool
+	// AtomicStrategy is how an atomic upgrade is reverted on failure,
+	// AtomicRollback if empty.
+	AtomicStrategy AtomicStrategy
 	// CleanupOnFail will, if true, cause the upgrade to delete newly-created resources on a failed update.
 	CleanupOnFail bool
 	// SubNotes determines whether sub-notes are rendered in the chart.
@@ -115,8 +122,72 @@ type Upgrade struct {
 	Lock sync.Mutex
 	// Enable DNS lookups when rendering templates
 	EnableDNS bool
+	// LabelResources stamps the release name, revision, chart and manager as
+	// labels on all resources of the release.
+	LabelResources bool
+	// SkipUnchanged skips applying resources whose rendered output is the same
+	// as in the deployed revision and in the revisions that failed after it.
+	// Changes made to those resources outside of Helm are not reverted.
+	SkipUnchanged bool
+	// SkipIfUnchanged, if set, skips the upgrade without creating a new
+	// revision when it would not change the deployed revision, as found out
+	// the way the mode describes. Run then returns the deployed release with
+	// ErrReleaseUnchanged.
+	SkipIfUnchanged SkipIfUnchangedMode
+	// DeployedBy describes who performs the operation, e.g. a person or a
+	// pipeline. It is recorded in the release along with the cluster user.
+	DeployedBy string
+	// StrictDeprecations fails the upgrade to a deprecated chart, or to a
+	// chart past its end of life.
+	StrictDeprecations bool
+	// ManifestOutput is where the resources applied for the release are
+	// written to once it is deployed: a single file if it has a .yaml or .yml
+	// extension, a directory with a file per resource otherwise.
+	ManifestOutput string
+	// OverrideFreeze proceeds during an active freeze window of the freeze
+	// policy of the configuration, recording the override in the audit log.
+	OverrideFreeze bool
+	// ImpactReview, if set, is called with the predicted impact of the
+	// upgrade on the resources of the release before it is applied, also on
+	// dry runs. Returning an error aborts the upgrade.
+	ImpactReview func(*ImpactReport) error
+	// RetryPolicy, if set, applies the resources and waits for them again
+	// when the upgrade fails doing so, before it is failed.
+	RetryPolicy *RetryPolicy
 }
 
+// AtomicStrategy is how an atomic upgrade is reverted when it fails.
+type AtomicStrategy string
+
+const (
+	// AtomicRollback rolls the release back to the last successful revision,
+	// creating a new revision.
+	AtomicRollback AtomicStrategy = "rollback"
+	// AtomicScoped only reverts the resources the upgrade changed to the last
+	// successful revision, and leaves the upgrade failed.
+	AtomicScoped AtomicStrategy = "scoped"
+)
+
+// SkipIfUnchangedMode is how an upgrade finds out that it would not change
+// the deployed revision.
+type SkipIfUnchangedMode string
+
+const (
+	// SkipIfUnchangedServer skips upgrades with the same chart, values and
+	// resources as the deployed revision, for which a server-side dry run
+	// finds that applying the resources would not change them either.
+	SkipIfUnchangedServer SkipIfUnchangedMode = "server"
+	// SkipIfUnchangedLocal skips upgrades with the same chart, values,
+	// rendered manifests and hooks as the deployed revision. The cluster is
+	// not consulted, so changes made to the resources outside of Helm are not
+	// reverted.
+	SkipIfUnchangedLocal SkipIfUnchangedMode = "local"
+)
+
+// ErrReleaseUnchanged is returned along with the deployed release by upgrades
+// with SkipIfUnchanged that would not change the release.
+var ErrReleaseUnchanged error = errcode.New(errcode.ReleaseUnchanged)
+
 type resultMessage struct {
 	r *release.Release
 	e error
@@ -145,6 +216,21 @@ func (u *Upgrade) Run(name string, chart *chart.Chart, vals map[string]interface
 
 // RunWithContext executes the upgrade on the given release with context.
 func (u *Upgrade) RunWithContext(ctx context.Context, name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, error) {
+	start := time.Now()
+	rel, err := u.runWithContext(ctx, name, chart, vals)
+	if err == ErrReleaseUnchanged {
+		u.cfg.observeAction("upgrade", start, nil)
+		return rel, err
+	}
+	u.cfg.observeAction("upgrade", start, err)
+	if !u.isDryRun() {
+		u.cfg.notifyWebhooks("upgrade", EventUpgraded, name, rel, err)
+		u.cfg.recordAudit("upgrade", name, u.Namespace, chart, vals, rel, err)
+	}
+	return rel, err
+}
+
+func (u *Upgrade) runWithContext(ctx context.Context, name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, error) {
 	if err := u.cfg.KubeClient.IsReachable(); err != nil {
 		return nil, err
 	}
@@ -153,20 +239,42 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, chart *chart.
 	// the user doesn't have to specify both
 	u.Wait = u.Wait || u.Atomic
 
+	switch u.AtomicStrategy {
+	case "", AtomicRollback, AtomicScoped:
+	default:
+		return nil, errors.Errorf("invalid atomic strategy %q, must be %q or %q", u.AtomicStrategy, AtomicRollback, AtomicScoped)
+	}
+
+	switch u.SkipIfUnchanged {
+	case "", SkipIfUnchangedServer, SkipIfUnchangedLocal:
+	default:
+		return nil, errors.Errorf("invalid skip-if-unchanged mode %q, must be %q or %q", u.SkipIfUnchanged, SkipIfUnchangedServer, SkipIfUnchangedLocal)
+	}
+
+	if u.RetryPolicy != nil {
+		if err := u.RetryPolicy.validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := chartutil.ValidateReleaseName(name); err != nil {
 		return nil, errors.Errorf("release name is invalid: %s", name)
 	}
 
 	u.cfg.Log("preparing upgrade for %s", name)
-	currentRelease, upgradedRelease, err := u.prepareUpgrade(name, chart, vals)
+	currentRelease, upgradedRelease, target, err := u.prepareUpgrade(name, chart, vals)
 	if err != nil {
 		return nil, err
 	}
 
-	u.cfg.Releases.MaxHistory = u.MaxHistory
+	if !u.isDryRun() {
+		if err := u.cfg.checkFreeze("upgrade", name, upgradedRelease.Namespace, upgradedRelease.Labels, u.OverrideFreeze); err != nil {
+			return nil, err
+		}
+	}
 
 	u.cfg.Log("performing update for %s", name)
-	res, err := u.performUpgrade(ctx, currentRelease, upgradedRelease)
+	res, applied, err := u.performUpgrade(ctx, currentRelease, upgradedRelease, target)
 	if err != nil {
 		return res, err
 	}
@@ -177,11 +285,77 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, chart *chart.
 		if err := u.cfg.Releases.Update(upgradedRelease); err != nil {
 			return res, err
 		}
+		if u.ManifestOutput != "" {
+			if err := writeAppliedManifests(u.ManifestOutput, applied); err != nil {
+				return res, errors.Wrapf(err, "release %s was upgraded, but its applied manifests could not be written", name)
+			}
+		}
 	}
 
 	return res, nil
 }
 
+// sameInputs returns whether the upgraded release is rendered from the same
+// chart and values as the original release, which is deployed and its last
+// revision. Releases deployed without digests never match.
+func sameInputs(original, upgraded *release.Release) bool {
+	if original.Info.Status != release.StatusDeployed || original.Version != upgraded.Version-1 {
+		return false
+	}
+	return original.ChartDigest != "" && original.ChartDigest == upgraded.ChartDigest && original.ValuesDigest == upgraded.ValuesDigest
+}
+
+// releaseUnchanged returns whether upgrading the deployed release would not
+// change it: it is the last revision, the chart, values and labels to set are
+// the same, and so are either its rendered manifests and hooks, or, with
+// SkipIfUnchangedServer, its set of resources, which a server-side dry run of
+// applying leaves as they are. Clients unable to dry run always upgrade.
+func (u *Upgrade) releaseUnchanged(original, upgraded *release.Release, current, target kube.ResourceList) (bool, error) {
+	if !sameInputs(original, upgraded) {
+		return false, nil
+	}
+	for k, v := range u.Labels {
+		if original.Labels[k] != v {
+			return false, nil
+		}
+	}
+	if u.SkipIfUnchanged == SkipIfUnchangedLocal {
+		return sameRendering(original, upgraded), nil
+	}
+	if len(current) != len(target) || len(current.Difference(target)) > 0 {
+		return false, nil
+	}
+	kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceUnchanged)
+	if !ok {
+		return false, nil
+	}
+	unchanged, err := kubeClient.Unchanged(target)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to dry-run the upgrade")
+	}
+	for i, same := range unchanged {
+		if !same {
+			u.cfg.Log("%s %q would be changed by the upgrade", target[i].Mapping.GroupVersionKind.Kind, target[i].Name)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sameRendering returns whether the upgraded release has the same manifest
+// and hooks as the original release.
+func sameRendering(original, upgraded *release.Release) bool {
+	if original.Manifest != upgraded.Manifest || len(original.Hooks) != len(upgraded.Hooks) {
+		return false
+	}
+	for i, h := range upgraded.Hooks {
+		if h.Path != original.Hooks[i].Path || h.Manifest != original.Hooks[i].Manifest {
+			return false
+		}
+	}
+	return true
+}
+
 // isDryRun returns true if Upgrade is set to run as a DryRun
 func (u *Upgrade) isDryRun() bool {
 	if u.DryRun || u.DryRunOption == "client" || u.DryRunOption == "server" || u.DryRunOption == "true" {
@@ -190,15 +364,20 @@ func (u *Upgrade) isDryRun() bool {
 	return false
 }
 
-// prepareUpgrade builds an upgraded release for an upgrade operation.
-func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, *release.Release, error) {
+// prepareUpgrade builds an upgraded release for an upgrade operation, and the
+// resources of its manifest.
+func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, *release.Release, kube.ResourceList, error) {
 	if chart == nil {
-		return nil, nil, errMissingChart
+		return nil, nil, nil, errMissingChart
+	}
+
+	if err := checkDeprecation(chart, u.StrictDeprecations); err != nil {
+		return nil, nil, nil, err
 	}
 
 	// HideSecret must be used with dry run. Otherwise, return an error.
 	if !u.isDryRun() && u.HideSecret {
-		return nil, nil, errors.New("Hiding Kubernetes secrets requires a dry-run mode")
+		return nil, nil, nil, errors.New("Hiding Kubernetes secrets requires a dry-run mode")
 	}
 
 	// finds the last non-deleted release with the given name
@@ -206,14 +385,17 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 	if err != nil {
 		// to keep existing behavior of returning the "%q has no deployed releases" error when an existing release does not exist
 		if errors.Is(err, driver.ErrReleaseNotFound) {
-			return nil, nil, driver.NewErrNoDeployedReleases(name)
+			return nil, nil, nil, driver.NewErrNoDeployedReleases(name)
 		}
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Concurrent `helm upgrade`s will either fail here with `errPending` or when creating the release with "already exists". This should act as a pessimistic lock.
 	if lastRelease.Info.Status.IsPending() {
-		return nil, nil, errPending
+		return nil, nil, nil, errPending
+	}
+	if err := checkSchema(lastRelease); err != nil {
+		return nil, nil, nil, err
 	}
 
 	var currentRelease *release.Release
@@ -228,7 +410,7 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 				(lastRelease.Info.Status == release.StatusFailed || lastRelease.Info.Status == release.StatusSuperseded) {
 				currentRelease = lastRelease
 			} else {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 		}
 	}
@@ -236,11 +418,24 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 	// determine if values will be reused
 	vals, err = u.reuseValues(chart, currentRelease, vals)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	if err := chartutil.ProcessDependenciesWithMerge(chart, vals); err != nil {
-		return nil, nil, err
+	// The chart is hashed as given, before its disabled dependencies are
+	// removed.
+	chartDigest := release.ChartDigest(chart)
+
+	vals, err = u.cfg.processAnnotations("upgrade", chart, name, u.Namespace, vals, u.isDryRun())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	depCaps, err := u.cfg.dependencyCapabilities(chart)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := chartutil.ProcessDependenciesWithCapabilities(chart, vals, depCaps); err != nil {
+		return nil, nil, nil, err
 	}
 
 	// Increment revision count. This is passed to templates, and also stored on
@@ -256,11 +451,11 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 
 	caps, err := u.cfg.getCapabilities()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	valuesToRender, err := chartutil.ToRenderValues(chart, vals, options, caps)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Determine whether or not to interact with remote
@@ -269,61 +464,95 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 		interactWithRemote = true
 	}
 
-	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, false, u.PostRenderer, interactWithRemote, u.EnableDNS, u.HideSecret)
+	stopProfile := u.cfg.profile("upgrade", PhaseRender)
+	hooks, manifest, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, crdsTemplated, u.PostRenderer, interactWithRemote, u.EnableDNS, u.HideSecret)
+	stopProfile()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	if driver.ContainsSystemLabels(u.Labels) {
-		return nil, nil, fmt.Errorf("user suplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels())
+		return nil, nil, nil, fmt.Errorf("user suplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels())
 	}
 
 	// Store an upgraded release.
 	upgradedRelease := &release.Release{
-		Name:      name,
-		Namespace: currentRelease.Namespace,
-		Chart:     chart,
-		Config:    vals,
+		Name:         name,
+		Namespace:    currentRelease.Namespace,
+		Chart:        chart,
+		Config:       vals,
+		ChartDigest:  chartDigest,
+		ValuesDigest: release.ValuesDigest(vals),
 		Info: &release.Info{
 			FirstDeployed: currentRelease.Info.FirstDeployed,
 			LastDeployed:  Timestamper(),
 			Status:        release.StatusPendingUpgrade,
 			Description:   "Preparing upgrade", // This should be overwritten later.
+			DeployedBy:    u.cfg.identity(u.DeployedBy),
+			Deprecation:   chart.Metadata.Deprecation(),
 		},
 		Version:  revision,
-		Manifest: manifestDoc.String(),
+		Manifest: manifest,
 		Hooks:    hooks,
-		Labels:   mergeCustomLabels(lastRelease.Labels, u.Labels),
+		Labels:   mergeCustomLabels(withoutTags(lastRelease.Labels), u.Labels),
+		// Once migrated, a release keeps being applied server-side.
+		ApplyMethod: lastRelease.ApplyMethod,
 	}
 
 	if len(notesTxt) > 0 {
 		upgradedRelease.Info.Notes = notesTxt
 	}
-	err = validateManifest(u.cfg.KubeClient, manifestDoc.Bytes(), !u.DisableOpenAPIValidation)
-	return currentRelease, upgradedRelease, err
+	// The resources are built once, both to validate the manifest and to
+	// perform the upgrade.
+	target, err := u.cfg.KubeClient.Build(strings.NewReader(manifest), !u.DisableOpenAPIValidation)
+	return currentRelease, upgradedRelease, target, err
 }
 
-func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedRelease *release.Release) (*release.Release, error) {
-	current, err := u.cfg.KubeClient.Build(bytes.NewBufferString(originalRelease.Manifest), false)
+func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedRelease *release.Release, target kube.ResourceList) (*release.Release, kube.ResourceList, error) {
+	current, err := u.cfg.KubeClient.Build(strings.NewReader(originalRelease.Manifest), false)
 	if err != nil {
 		// Checking for removed Kubernetes API error so can provide a more informative error message to the user
 		// Ref: https://github.com/helm/helm/issues/7219
 		if strings.Contains(err.Error(), "unable to recognize \"\": no matches for kind") {
-			return upgradedRelease, errors.Wrap(err, "current release manifest contains removed kubernetes api(s) for this "+
+			return upgradedRelease, nil, errors.Wrap(err, "current release manifest contains removed kubernetes api(s) for this "+
 				"kubernetes version and it is therefore unable to build the kubernetes "+
 				"objects for performing the diff. error from kubernetes")
 		}
-		return upgradedRelease, errors.Wrap(err, "unable to build kubernetes objects from current release manifest")
+		return upgradedRelease, nil, fmt.Errorf("%w from current release manifest: %w", errBuildObjects, err)
+	}
+	if err := addStampedLabels(originalRelease, current); err != nil {
+		return upgradedRelease, nil, err
+	}
+	if err := u.cfg.applyMetadataPolicy(current, target); err != nil {
+		return upgradedRelease, nil, err
 	}
-	target, err := u.cfg.KubeClient.Build(bytes.NewBufferString(upgradedRelease.Manifest), !u.DisableOpenAPIValidation)
+	unchanged, err := u.unchangedResources(originalRelease, upgradedRelease, current, target)
 	if err != nil {
-		return upgradedRelease, errors.Wrap(err, "unable to build kubernetes objects from new release manifest")
+		return upgradedRelease, nil, err
+	}
+	if err := pruneIgnoredFields(current, target); err != nil {
+		return upgradedRelease, nil, err
+	}
+
+	if u.ImpactReview != nil {
+		report, err := AnalyzeImpact(originalRelease.Manifest, upgradedRelease.Manifest, upgradedRelease.Namespace, u.cfg.clusterBudgets(upgradedRelease.Namespace))
+		if err != nil {
+			return upgradedRelease, nil, errors.Wrap(err, "unable to analyze the impact of the upgrade")
+		}
+		if err := u.ImpactReview(report); err != nil {
+			return upgradedRelease, nil, err
+		}
 	}
 
 	// It is safe to use force only on target because these are resources currently rendered by the chart.
 	err = target.Visit(setMetadataVisitor(upgradedRelease.Name, upgradedRelease.Namespace, true))
 	if err != nil {
-		return upgradedRelease, err
+		return upgradedRelease, nil, err
+	}
+	if u.LabelResources {
+		if err := target.Visit(setReleaseLabelsVisitor(upgradedRelease)); err != nil {
+			return upgradedRelease, nil, err
+		}
 	}
 
 	// Do a basic diff using gvk + name to figure out what new resources are being created so we can validate they don't already exist
@@ -341,7 +570,7 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 
 	toBeUpdated, err := existingResourceConflict(toBeCreated, upgradedRelease.Name, upgradedRelease.Namespace)
 	if err != nil {
-		return nil, errors.Wrap(err, "Unable to continue with update")
+		return nil, nil, errors.Wrap(err, "Unable to continue with update")
 	}
 
 	toBeUpdated.Visit(func(r *resource.Info, err error) error {
@@ -360,24 +589,43 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 		} else {
 			upgradedRelease.Info.Description = "Dry run complete"
 		}
-		return upgradedRelease, nil
+		return upgradedRelease, nil, nil
+	}
+
+	if u.SkipIfUnchanged != "" {
+		unchanged, err := u.releaseUnchanged(originalRelease, upgradedRelease, current, target)
+		if err != nil {
+			return nil, nil, err
+		}
+		if unchanged {
+			u.cfg.Log("release %s is unchanged, skipping the upgrade", upgradedRelease.Name)
+			return originalRelease, nil, ErrReleaseUnchanged
+		}
+	}
+
+	if err := u.cfg.checkAccess(target, upgradedRelease.Namespace, "create", "patch"); err != nil {
+		return nil, nil, errors.Wrap(err, "Unable to continue with update")
+	}
+	if err := u.cfg.checkAccess(current.Difference(target), upgradedRelease.Namespace, "delete"); err != nil {
+		return nil, nil, errors.Wrap(err, "Unable to continue with update")
 	}
 
 	u.cfg.Log("creating upgraded release for %s", upgradedRelease.Name)
-	if err := u.cfg.Releases.Create(upgradedRelease); err != nil {
-		return nil, err
+	journal(upgradedRelease, release.JournalCreated)
+	if err := u.cfg.Releases.CreateWithMaxHistory(upgradedRelease, u.MaxHistory); err != nil {
+		return nil, nil, err
 	}
 	rChan := make(chan resultMessage)
 	ctxChan := make(chan resultMessage)
 	doneChan := make(chan interface{})
 	defer close(doneChan)
-	go u.releasingUpgrade(rChan, upgradedRelease, current, target, originalRelease)
+	go u.releasingUpgrade(ctx, rChan, upgradedRelease, current, target, unchanged, originalRelease)
 	go u.handleContext(ctx, doneChan, ctxChan, upgradedRelease)
 	select {
 	case result := <-rChan:
-		return result.r, result.e
+		return result.r, target, result.e
 	case result := <-ctxChan:
-		return result.r, result.e
+		return result.r, target, result.e
 	}
 }
 
@@ -405,61 +653,95 @@ func (u *Upgrade) handleContext(ctx context.Context, done chan interface{}, c ch
 		return
 	}
 }
-func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *release.Release, current kube.ResourceList, target kube.ResourceList, originalRelease *release.Release) {
-	// pre-upgrade hooks
 
-	if !u.DisableHooks {
-		if err := u.cfg.execHook(upgradedRelease, release.HookPreUpgrade, u.Timeout); err != nil {
-			u.reportToPerformUpgrade(c, upgradedRelease, kube.ResourceList{}, fmt.Errorf("pre-upgrade hooks failed: %s", err))
-			return
+// unchangedResources hashes the resources of the upgraded release and returns
+// the keys of those that can be skipped as they are the same as in the
+// original release.
+func (u *Upgrade) unchangedResources(originalRelease, upgradedRelease *release.Release, current, target kube.ResourceList) (map[string]bool, error) {
+	hashes, err := resourceHashes(target)
+	if err != nil {
+		return nil, err
+	}
+	upgradedRelease.ResourceHashes = hashes
+	upgradedRelease.Namespaces = resourceNamespaces(target, upgradedRelease.Namespace)
+
+	// The revision label changes with every upgrade, so no resource is ever
+	// unchanged when labeling resources, or when removing the labels.
+	if !u.SkipUnchanged || u.LabelResources || len(originalRelease.ResourceLabels) > 0 {
+		return nil, nil
+	}
+	// The workloads of a suspended release are scaled to zero, so they differ
+	// from their rendered resources whatever their hash.
+	if originalRelease.Suspension != nil {
+		return nil, nil
+	}
+	deployed := originalRelease.ResourceHashes
+	if deployed == nil {
+		// Releases created before resources were hashed.
+		if deployed, err = resourceHashes(current); err != nil {
+			return nil, err
 		}
-	} else {
-		u.cfg.Log("upgrade hooks disabled for %s", upgradedRelease.Name)
 	}
+	unchanged := unchangedResources(deployed, hashes)
 
-	results, err := u.cfg.KubeClient.Update(current, target, u.Force)
+	// Revisions that failed after the deployed one may have left their own
+	// resources in the cluster, so only the resources all of them applied
+	// the same way are skipped.
+	history, err := u.cfg.Releases.History(originalRelease.Name)
 	if err != nil {
-		u.cfg.recordRelease(originalRelease)
-		u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
+		return nil, err
+	}
+	for _, rel := range history {
+		if rel.Version <= originalRelease.Version || rel.Version >= upgradedRelease.Version {
+			continue
+		}
+		if rel.ResourceHashes == nil {
+			return nil, nil
+		}
+		for key := range unchanged {
+			if rel.ResourceHashes[key] != hashes[key] {
+				delete(unchanged, key)
+			}
+		}
+	}
+	u.cfg.Log("skipping %d of %d resources unchanged since revision %d", len(unchanged), len(target), originalRelease.Version)
+	return unchanged, nil
+}
+
+func (u *Upgrade) releasingUpgrade(ctx context.Context, c chan<- resultMessage, upgradedRelease *release.Release, current kube.ResourceList, target kube.ResourceList, unchanged map[string]bool, originalRelease *release.Release) {
+	if err := u.cfg.writeJournal(upgradedRelease, release.JournalApplying); err != nil {
+		u.reportToPerformUpgrade(c, upgradedRelease, kube.ResourceList{}, err)
 		return
 	}
 
-	if u.Recreate {
-		// NOTE: Because this is not critical for a release to succeed, we just
-		// log if an error occurs and continue onward. If we ever introduce log
-		// levels, we should make these error level logs so users are notified
-		// that they'll need to go do the cleanup on their own
-		if err := recreate(u.cfg, results.Updated); err != nil {
-			u.cfg.Log(err.Error())
+	// pre-upgrade hooks
+	if !u.DisableHooks {
+		if err := u.cfg.execHook(ctx, upgradedRelease, release.HookPreUpgrade, u.Timeout); err != nil {
+			u.reportToPerformUpgrade(c, upgradedRelease, kube.ResourceList{}, fmt.Errorf("pre-upgrade hooks failed: %w", err))
+			return
 		}
+	} else {
+		u.cfg.Log("upgrade hooks disabled for %s", upgradedRelease.Name)
 	}
 
-	if u.Wait {
-		u.cfg.Log(
-			"waiting for release %s resources (created: %d updated: %d  deleted: %d)",
-			upgradedRelease.Name, len(results.Created), len(results.Updated), len(results.Deleted))
-		if u.WaitForJobs {
-			if err := u.cfg.KubeClient.WaitWithJobs(target, u.Timeout); err != nil {
-				u.cfg.recordRelease(originalRelease)
-				u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
-				return
-			}
-		} else {
-			if err := u.cfg.KubeClient.Wait(target, u.Timeout); err != nil {
-				u.cfg.recordRelease(originalRelease)
-				u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
-				return
-			}
-		}
+	_, created, err := u.applyResources(ctx, upgradedRelease, current, target, unchanged)
+	if err != nil {
+		u.cfg.recordRelease(originalRelease)
+		u.reportToPerformUpgrade(c, upgradedRelease, created, err)
+		return
 	}
 
 	// post-upgrade hooks
 	if !u.DisableHooks {
-		if err := u.cfg.execHook(upgradedRelease, release.HookPostUpgrade, u.Timeout); err != nil {
-			u.reportToPerformUpgrade(c, upgradedRelease, results.Created, fmt.Errorf("post-upgrade hooks failed: %s", err))
+		if err := u.cfg.execHook(ctx, upgradedRelease, release.HookPostUpgrade, u.Timeout); err != nil {
+			u.reportToPerformUpgrade(c, upgradedRelease, created, fmt.Errorf("post-upgrade hooks failed: %w", err))
 			return
 		}
 	}
+	if err := u.cfg.writeJournal(upgradedRelease, release.JournalCompleted); err != nil {
+		u.reportToPerformUpgrade(c, upgradedRelease, created, err)
+		return
+	}
 
 	originalRelease.Info.Status = release.StatusSuperseded
 	u.cfg.recordRelease(originalRelease)
@@ -493,8 +775,6 @@ func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, e
 		u.cfg.Log("Resource cleanup complete")
 	}
 	if u.Atomic {
-		u.cfg.Log("Upgrade failed and atomic is set, rolling back to last successful release")
-
 		// As a protection, get the last successful release before rollback.
 		// If there are no successful releases, bail out
 		hist := NewHistory(u.cfg)
@@ -515,6 +795,18 @@ func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, e
 
 		releaseutil.Reverse(filteredHistory, releaseutil.SortByRevision)
 
+		if u.AtomicStrategy == AtomicScoped {
+			u.cfg.Log("Upgrade failed and atomic is set, reverting the resources changed by the upgrade")
+			reverted, rerr := u.revertChanges(rel, filteredHistory[0])
+			if rerr != nil {
+				return rel, errors.Wrapf(rerr, "an error occurred while reverting the resources changed by the upgrade. original upgrade error: %s", err)
+			}
+			rel.Info.Description = fmt.Sprintf("%s; reverted the %d resources changed by the upgrade to revision %d", msg, reverted, filteredHistory[0].Version)
+			u.cfg.recordRelease(rel)
+			return rel, errors.Wrapf(err, "release %s failed, and the %d resources changed by the upgrade have been reverted due to atomic being set", rel.Name, reverted)
+		}
+
+		u.cfg.Log("Upgrade failed and atomic is set, rolling back to last successful release")
 		rollin := NewRollback(u.cfg)
 		rollin.Version = filteredHistory[0].Version
 		rollin.Wait = true
@@ -532,6 +824,79 @@ func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, e
 	return rel, err
 }
 
+// revertChanges reverts the resources a failed upgrade changed to those of
+// a previous revision, leaving the others alone: the resources the upgrade
+// created are deleted, and those it updated or deleted are applied again as
+// they were in the previous revision. The hashes of the resources of both
+// revisions tell which resources changed; all of them are reverted if the
+// hashes of either revision are not known. It returns the number of reverted
+// resources.
+func (u *Upgrade) revertChanges(rel, previous *release.Release) (int, error) {
+	target, err := u.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
+	if err != nil {
+		return 0, fmt.Errorf("%w from the failed release manifest: %w", errBuildObjects, err)
+	}
+	original, err := u.cfg.KubeClient.Build(strings.NewReader(previous.Manifest), false)
+	if err != nil {
+		return 0, fmt.Errorf("%w from the previous release manifest: %w", errBuildObjects, err)
+	}
+	if err := addStampedLabels(rel, target); err != nil {
+		return 0, err
+	}
+	if err := addStampedLabels(previous, original); err != nil {
+		return 0, err
+	}
+
+	changed := func(key string) bool {
+		if rel.ResourceHashes == nil || previous.ResourceHashes == nil {
+			return true
+		}
+		hash, ok := previous.ResourceHashes[key]
+		return !ok || hash != rel.ResourceHashes[key]
+	}
+	inPrevious := make(map[string]bool)
+	var restore kube.ResourceList
+	for _, r := range original {
+		key := objectKey(r)
+		inPrevious[key] = true
+		if changed(key) {
+			restore = append(restore, r)
+		}
+	}
+	var created, updated kube.ResourceList
+	for _, r := range target {
+		key := objectKey(r)
+		if !inPrevious[key] {
+			created = append(created, r)
+		} else if changed(key) {
+			updated = append(updated, r)
+		}
+	}
+
+	if len(restore) > 0 {
+		if err := pruneIgnoredFields(updated, restore); err != nil {
+			return 0, err
+		}
+		if err := restore.Visit(setMetadataVisitor(rel.Name, rel.Namespace, true)); err != nil {
+			return 0, errors.Wrap(err, "unable to set metadata visitor from the previous release")
+		}
+		if _, err := u.cfg.updateResources(rel, updated, restore, u.Force); err != nil {
+			return 0, err
+		}
+	}
+	if len(created) > 0 {
+		if _, errs := u.cfg.KubeClient.Delete(created); len(errs) > 0 {
+			return 0, errors.New(joinErrors(errs))
+		}
+	}
+	if u.Wait && len(restore) > 0 {
+		if err := u.cfg.waitForResources("upgrade", restore, u.Timeout, u.WaitForJobs); err != nil {
+			return 0, err
+		}
+	}
+	return len(restore) + len(created), nil
+}
+
 // reuseValues copies values from the current release to a new release if the
 // new release does not have any values.
 //
@@ -565,10 +930,16 @@ func (u *Upgrade) reuseValues(chart *chart.Chart, current *release.Release, newV
 	}
 
 	// If the ResetThenReuseValues flag is set, we use the new chart's values, but we copy the old config's values over the new config's values.
-	if u.ResetThenReuseValues {
-		u.cfg.Log("merging values from old release to new values")
+	if u.ResetThenReuseValues || len(u.ReuseValuesFor) > 0 {
+		oldVals := current.Config
+		if len(u.ReuseValuesFor) > 0 {
+			u.cfg.Log("merging values from old release at %s to new values", strings.Join(u.ReuseValuesFor, ", "))
+			oldVals = u.valuesAtPaths(current.Config, u.ReuseValuesFor)
+		} else {
+			u.cfg.Log("merging values from old release to new values")
+		}
 
-		newVals = chartutil.CoalesceTables(newVals, current.Config)
+		newVals = chartutil.CoalesceTables(newVals, oldVals)
 
 		return newVals, nil
 	}
@@ -580,9 +951,37 @@ func (u *Upgrade) reuseValues(chart *chart.Chart, current *release.Release, newV
 	return newVals, nil
 }
 
-func validateManifest(c kube.Interface, manifest []byte, openAPIValidation bool) error {
-	_, err := c.Build(bytes.NewReader(manifest), openAPIValidation)
-	return err
+// valuesAtPaths returns the values at the given dotted paths, leaving out all
+// other values.
+func (u *Upgrade) valuesAtPaths(vals map[string]interface{}, paths []string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, p := range paths {
+		keys := strings.Split(p, ".")
+		src, dst := vals, out
+		for i, k := range keys {
+			v, ok := src[k]
+			if !ok {
+				u.cfg.Log("no values from old release at %s", p)
+				break
+			}
+			if i == len(keys)-1 {
+				dst[k] = v
+				break
+			}
+			next, ok := v.(map[string]interface{})
+			if !ok {
+				u.cfg.Log("no values from old release at %s, as %s is not a table", p, strings.Join(keys[:i+1], "."))
+				break
+			}
+			table, ok := dst[k].(map[string]interface{})
+			if !ok {
+				table = map[string]interface{}{}
+				dst[k] = table
+			}
+			src, dst = next, table
+		}
+	}
+	return out
 }
 
 // recreate captures all the logic for recreating pods for both upgrade and
@@ -0,0 +1,214 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// RetryClass is a class of failures of an upgrade that can be retried.
+type RetryClass string
+
+const (
+	// RetryWaitTimeout retries when the resources are not ready in time.
+	RetryWaitTimeout RetryClass = "wait-timeout"
+	// RetryTransient retries when the Kubernetes API fails in a way that
+	// may not last, e.g. when it is unavailable, overloaded or an update
+	// conflicts with another.
+	RetryTransient RetryClass = "transient"
+)
+
+// defaultMaxRetryBackoff is how long to wait before a retry at most when the
+// RetryPolicy does not say.
+const defaultMaxRetryBackoff = 5 * time.Minute
+
+// RetryPolicy is how a failed upgrade applies its resources and waits for
+// them again before it is failed. Hooks are not run again.
+//
+// Each attempt takes the resources created and deleted by the previous ones
+// into account, so that it updates rather than creates them again, and
+// waits for the full timeout of the upgrade.
+type RetryPolicy struct {
+	// Attempts is how often the resources are applied at most, including the
+	// first time.
+	Attempts int
+	// Backoff is how long to wait before the first retry. It doubles with
+	// every further retry, up to MaxBackoff.
+	Backoff time.Duration
+	// MaxBackoff is how long to wait before a retry at most, 5 minutes if
+	// zero.
+	MaxBackoff time.Duration
+	// RetryOn are the classes of failures that are retried, all of them if
+	// empty.
+	RetryOn []RetryClass
+}
+
+// validate checks that the policy only retries known classes of failures.
+func (p *RetryPolicy) validate() error {
+	for _, c := range p.RetryOn {
+		if c != RetryWaitTimeout && c != RetryTransient {
+			return errors.Errorf("invalid retry class %q, must be %q or %q", c, RetryWaitTimeout, RetryTransient)
+		}
+	}
+	return nil
+}
+
+// retries returns whether the policy retries err after the given attempt,
+// and how long to wait before doing so. waiting tells whether err occurred
+// while waiting for the resources rather than applying them.
+func (p *RetryPolicy) retries(attempt int, waiting bool, err error) (time.Duration, bool) {
+	if p == nil || attempt >= p.Attempts {
+		return 0, false
+	}
+	class, ok := retryClass(waiting, err)
+	if !ok {
+		return 0, false
+	}
+	if len(p.RetryOn) > 0 {
+		retried := false
+		for _, c := range p.RetryOn {
+			retried = retried || c == class
+		}
+		if !retried {
+			return 0, false
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff returns how long to wait after the given attempt: Backoff doubled
+// with every attempt after the first, but no more than MaxBackoff.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = defaultMaxRetryBackoff
+	}
+	backoff := p.Backoff
+	for i := 1; i < attempt && backoff < limit; i++ {
+		backoff *= 2
+	}
+	if backoff > limit || backoff < 0 {
+		return limit
+	}
+	return backoff
+}
+
+// retryClass returns the class of a failure, if it is retriable at all.
+func retryClass(waiting bool, err error) (RetryClass, bool) {
+	switch {
+	case waiting && (errors.Is(err, context.DeadlineExceeded) || wait.Interrupted(err) || strings.Contains(err.Error(), "timed out")):
+		return RetryWaitTimeout, true
+	case apierrors.IsServerTimeout(err), apierrors.IsTimeout(err), apierrors.IsTooManyRequests(err),
+		apierrors.IsServiceUnavailable(err), apierrors.IsInternalError(err), apierrors.IsConflict(err),
+		utilnet.IsConnectionReset(err), utilnet.IsConnectionRefused(err), utilnet.IsProbableEOF(err):
+		return RetryTransient, true
+	}
+	return "", false
+}
+
+// applyResources applies the target resources of the upgrade and waits for
+// them if Wait is set, as often as the RetryPolicy allows. It stops waiting
+// for the next attempt when ctx is done. It returns the result of the last
+// attempt and the resources created by all of them.
+func (u *Upgrade) applyResources(ctx context.Context, rel *release.Release, current, target kube.ResourceList, unchanged map[string]bool) (*kube.Result, kube.ResourceList, error) {
+	var created kube.ResourceList
+	applied := false
+	for attempt := 1; ; attempt++ {
+		// Unchanged resources are left out on both sides, so that they are
+		// neither updated nor deleted. They are still waited for below.
+		stopProfile := u.cfg.profile("upgrade", PhaseApply)
+		results, err := u.cfg.updateResources(rel, withoutResources(current, unchanged), withoutResources(target, unchanged), u.Force)
+		stopProfile()
+		u.cfg.addWarnings(rel, results)
+		if results != nil {
+			created = append(created, results.Created...)
+		}
+		waiting := false
+		if err == nil && !applied {
+			applied = true
+			if err := u.cfg.writeJournal(rel, release.JournalApplied); err != nil {
+				return results, created, err
+			}
+			if u.Recreate {
+				// NOTE: Because this is not critical for a release to succeed, we just
+				// log if an error occurs and continue onward. If we ever introduce log
+				// levels, we should make these error level logs so users are notified
+				// that they'll need to go do the cleanup on their own
+				if err := recreate(u.cfg, results.Updated); err != nil {
+					u.cfg.Log(err.Error())
+				}
+			}
+		}
+		if err == nil && u.Wait {
+			u.cfg.Log(
+				"waiting for release %s resources (created: %d updated: %d  deleted: %d)",
+				rel.Name, len(results.Created), len(results.Updated), len(results.Deleted))
+			err = u.cfg.waitForResources("upgrade", target, u.Timeout, u.WaitForJobs)
+			waiting = true
+		}
+		if err == nil {
+			return results, created, nil
+		}
+
+		backoff, ok := u.RetryPolicy.retries(attempt, waiting, err)
+		if !ok {
+			return results, created, err
+		}
+		u.cfg.Log("attempt %d of %d to upgrade %s failed, retrying in %s: %s", attempt, u.RetryPolicy.Attempts, rel.Name, backoff, err)
+		select {
+		case <-ctx.Done():
+			return results, created, errors.Wrapf(ctx.Err(), "retrying the upgrade of %s", rel.Name)
+		case <-time.After(backoff):
+		}
+		if results != nil {
+			current = afterAttempt(current, results)
+		}
+	}
+}
+
+// afterAttempt returns the resources of the release once an attempt to
+// apply its resources created and deleted those of result.
+func afterAttempt(current kube.ResourceList, result *kube.Result) kube.ResourceList {
+	deleted := make(map[string]bool, len(result.Deleted))
+	for _, r := range result.Deleted {
+		deleted[objectKey(r)] = true
+	}
+	existing := make(map[string]bool, len(current))
+	var next kube.ResourceList
+	for _, r := range current {
+		if !deleted[objectKey(r)] {
+			next = append(next, r)
+			existing[objectKey(r)] = true
+		}
+	}
+	for _, r := range result.Created {
+		if !existing[objectKey(r)] {
+			next = append(next, r)
+		}
+	}
+	return next
+}
@@ -0,0 +1,184 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestUpgradeRetryPolicy(t *testing.T) {
+	timeout := fmt.Errorf("timed out waiting for the condition")
+	unavailable := apierrors.NewServiceUnavailable("etcd is down")
+	tests := []struct {
+		name     string
+		policy   *RetryPolicy
+		failures []kubefake.Failure
+		updates  int
+		errText  string
+	}{{
+		name:     "wait timeout is retried",
+		policy:   &RetryPolicy{Attempts: 3},
+		failures: []kubefake.Failure{{Verb: kubefake.VerbWait, Times: 2, Err: timeout}},
+		updates:  3,
+	}, {
+		name:     "transient API error is retried",
+		policy:   &RetryPolicy{Attempts: 2, RetryOn: []RetryClass{RetryTransient}},
+		failures: []kubefake.Failure{{Verb: kubefake.VerbUpdate, Times: 1, Err: unavailable}},
+		updates:  2,
+	}, {
+		name:     "attempts are exhausted",
+		policy:   &RetryPolicy{Attempts: 2},
+		failures: []kubefake.Failure{{Verb: kubefake.VerbWait, Err: timeout}},
+		updates:  2,
+		errText:  "timed out waiting for the condition",
+	}, {
+		name:     "class not retried",
+		policy:   &RetryPolicy{Attempts: 3, RetryOn: []RetryClass{RetryTransient}},
+		failures: []kubefake.Failure{{Verb: kubefake.VerbWait, Times: 1, Err: timeout}},
+		updates:  1,
+		errText:  "timed out waiting for the condition",
+	}, {
+		name:     "permanent error is not retried",
+		policy:   &RetryPolicy{Attempts: 3},
+		failures: []kubefake.Failure{{Verb: kubefake.VerbUpdate, Times: 1, Err: apierrors.NewBadRequest("invalid")}},
+		updates:  1,
+		errText:  "invalid",
+	}, {
+		name:     "no policy",
+		failures: []kubefake.Failure{{Verb: kubefake.VerbWait, Times: 1, Err: timeout}},
+		updates:  1,
+		errText:  "timed out waiting for the condition",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upAction := upgradeAction(t)
+			rel := releaseStub()
+			rel.Name = "retried"
+			rel.Info.Status = release.StatusDeployed
+			require.NoError(t, upAction.cfg.Releases.Create(rel))
+
+			failer := upAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+			failer.Failures = tt.failures
+			upAction.Wait = true
+			upAction.RetryPolicy = tt.policy
+
+			res, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+			assert.Len(t, failer.OperationsOf(kubefake.VerbUpdate), tt.updates)
+			if tt.errText != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errText)
+				assert.Equal(t, release.StatusFailed, res.Info.Status)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, release.StatusDeployed, res.Info.Status)
+			// The hooks run once whatever the number of attempts.
+			assert.Equal(t, []release.JournalPhase{release.JournalCreated, release.JournalApplying, release.JournalApplied, release.JournalCompleted}, journalPhases(res))
+		})
+	}
+}
+
+func TestUpgradeRetryPolicyInvalidClass(t *testing.T) {
+	upAction := upgradeAction(t)
+	upAction.RetryPolicy = &RetryPolicy{Attempts: 2, RetryOn: []RetryClass{"always"}}
+	_, err := upAction.Run("retried", buildChart(), map[string]interface{}{})
+	assert.EqualError(t, err, `invalid retry class "always", must be "wait-timeout" or "transient"`)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{Attempts: 4, Backoff: 1000}
+	for attempt, want := range map[int]int64{1: 1000, 2: 2000, 3: 4000} {
+		_, ok := p.retries(attempt, false, context.DeadlineExceeded)
+		assert.False(t, ok, "a timeout while applying is not a wait timeout")
+		backoff, ok := p.retries(attempt, true, context.DeadlineExceeded)
+		assert.True(t, ok)
+		assert.EqualValues(t, want, backoff)
+	}
+	_, ok := p.retries(4, true, context.DeadlineExceeded)
+	assert.False(t, ok)
+}
+
+func TestRetryPolicyMaxBackoff(t *testing.T) {
+	p := &RetryPolicy{Attempts: 100, Backoff: time.Second, MaxBackoff: 3 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 3 * time.Second, 99: 3 * time.Second} {
+		backoff, ok := p.retries(attempt, true, context.DeadlineExceeded)
+		assert.True(t, ok)
+		assert.Equal(t, want, backoff)
+	}
+
+	p.MaxBackoff = 0
+	backoff, _ := p.retries(99, true, context.DeadlineExceeded)
+	assert.Equal(t, defaultMaxRetryBackoff, backoff)
+}
+
+func TestUpgradeRetryBackoffCanceled(t *testing.T) {
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	require.NoError(t, upAction.cfg.Releases.Create(rel))
+	failer := upAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.Failures = []kubefake.Failure{{Verb: kubefake.VerbWait, Err: fmt.Errorf("timed out waiting for the condition")}}
+	upAction.Wait = true
+	upAction.RetryPolicy = &RetryPolicy{Attempts: 2, Backoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := upAction.applyResources(ctx, rel, nil, nil, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Minute):
+		t.Fatal("the backoff is not cut short by the context")
+	}
+	assert.Len(t, failer.OperationsOf(kubefake.VerbUpdate), 1)
+}
+
+func TestAfterAttempt(t *testing.T) {
+	info := func(name string) *resource.Info {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+		obj.SetName(name)
+		return &resource.Info{Name: name, Namespace: "default", Object: obj}
+	}
+	current := kube.ResourceList{info("a"), info("b")}
+	next := afterAttempt(current, &kube.Result{
+		Created: kube.ResourceList{info("c"), info("a")},
+		Deleted: kube.ResourceList{info("b")},
+	})
+	var names []string
+	for _, r := range next {
+		names = append(names, r.Name)
+	}
+	assert.Equal(t, []string{"a", "c"}, names)
+}
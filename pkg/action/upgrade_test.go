@@ -19,6 +19,7 @@ package action
 import (
 	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"testing"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
 	"helm.sh/helm/v3/pkg/release"
 	helmtime "helm.sh/helm/v3/pkg/time"
@@ -69,6 +71,138 @@ func TestUpgradeRelease_Success(t *testing.T) {
 	is.Equal(lastRelease.Info.Status, release.StatusDeployed)
 }
 
+type countingBuilds struct {
+	*kubefake.FailingKubeClient
+	builds int
+}
+
+func (c *countingBuilds) Build(reader io.Reader, validate bool) (kube.ResourceList, error) {
+	c.builds++
+	return c.FailingKubeClient.Build(reader, validate)
+}
+
+func TestUpgradeRelease_BuildsManifestsOnce(t *testing.T) {
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Info.Status = release.StatusDeployed
+	req.NoError(upAction.cfg.Releases.Create(rel))
+	builds := &countingBuilds{FailingKubeClient: upAction.cfg.KubeClient.(*kubefake.FailingKubeClient)}
+	upAction.cfg.KubeClient = builds
+	upAction.DisableHooks = true
+
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.NoError(err)
+	// The current manifest and the upgraded manifest are built once each.
+	assert.Equal(t, 2, builds.builds)
+}
+
+type recordingUpdates struct {
+	*kubefake.FailingKubeClient
+	original, target kube.ResourceList
+}
+
+func (c *recordingUpdates) Update(original, target kube.ResourceList, force bool) (*kube.Result, error) {
+	c.original, c.target = original, target
+	return c.FailingKubeClient.Update(original, target, force)
+}
+
+func TestUpgradeRelease_RemovesStampedLabels(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	instAction := installAction(t)
+	instAction.LabelResources = true
+	installed, err := instAction.Run(buildChart(), nil)
+	req.NoError(err)
+	is.Equal("1", installed.ResourceLabels[helmRevisionLabel])
+
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	updates := &recordingUpdates{FailingKubeClient: failer}
+	instAction.cfg.KubeClient = updates
+	upAction := upgradeAction(t)
+	upAction.cfg = instAction.cfg
+	upgraded, err := upAction.Run(installed.Name, buildChart(), nil)
+	req.NoError(err)
+	is.Empty(upgraded.ResourceLabels)
+
+	// The labels stamped by the installation are part of the original
+	// resources, so that updating them to the target resources removes them.
+	req.NotEmpty(updates.original)
+	for _, r := range updates.original {
+		labels, err := accessor.Labels(r.Object)
+		req.NoError(err)
+		is.Equal("1", labels[helmRevisionLabel], r.Name)
+	}
+	for _, r := range updates.target {
+		labels, err := accessor.Labels(r.Object)
+		req.NoError(err)
+		is.NotContains(labels, helmRevisionLabel, r.Name)
+	}
+}
+
+func TestUpgradeRelease_Digests(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	// The dependency is disabled, and removed from the chart while installing.
+	chrt := func() *chart.Chart {
+		return buildChart(
+			withDependency(withName("child")),
+			withMetadataDependency(chart.Dependency{Name: "child", Condition: "child.enabled"}),
+		)
+	}
+	vals := map[string]interface{}{"child": map[string]interface{}{"enabled": false}}
+
+	instAction := installAction(t)
+	instAction.ReleaseName = "digests"
+	installed, err := instAction.Run(chrt(), vals)
+	req.NoError(err)
+	is.Empty(installed.Chart.Dependencies())
+	is.Equal(release.ChartDigest(chrt()), installed.ChartDigest)
+	is.Equal(release.ValuesDigest(vals), installed.ValuesDigest)
+	is.NotEqual(release.ChartDigest(buildChart()), installed.ChartDigest)
+
+	upAction := upgradeAction(t)
+	upAction.cfg = instAction.cfg
+	upgraded, err := upAction.Run("digests", chrt(), map[string]interface{}{"replicas": 2})
+	req.NoError(err)
+	is.Equal(installed.ChartDigest, upgraded.ChartDigest)
+	is.NotEqual(installed.ValuesDigest, upgraded.ValuesDigest)
+
+	// A rollback takes over the digests of the revision rolled back to.
+	rollAction := NewRollback(instAction.cfg)
+	rollAction.Version = 1
+	req.NoError(rollAction.Run("digests"))
+	rolledBack, err := instAction.cfg.Releases.Get("digests", 3)
+	req.NoError(err)
+	is.Equal(installed.ChartDigest, rolledBack.ChartDigest)
+	is.Equal(installed.ValuesDigest, rolledBack.ValuesDigest)
+}
+
+func TestUpgradeRelease_NewerSchema(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "previous-release"
+	rel.Info.Status = release.StatusDeployed
+	rel.HelmVersion = "v3.99"
+	rel.SchemaVersion = release.CurrentSchemaVersion + 1
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "was written by Helm v3.99 with release schema version")
+
+	lastRelease, err := upAction.cfg.Releases.Last(rel.Name)
+	req.NoError(err)
+	is.Equal(1, lastRelease.Version)
+}
+
 func TestUpgradeRelease_Wait(t *testing.T) {
 	is := assert.New(t)
 	req := require.New(t)
@@ -190,6 +324,71 @@ func TestUpgradeRelease_Atomic(t *testing.T) {
 	})
 }
 
+func TestUpgradeRelease_AtomicScoped(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	configMaps := func(data map[string]string) []*chart.File {
+		var files []*chart.File
+		for _, name := range []string{"a", "b", "c", "d"} {
+			if value, ok := data[name]; ok {
+				files = append(files, &chart.File{
+					Name: "templates/" + name,
+					Data: []byte(fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\ndata:\n  value: %s", name, value)),
+				})
+			}
+		}
+		return files
+	}
+
+	instAction := installAction(t)
+	instAction.ReleaseName = "scoped"
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	chrt := buildChart()
+	chrt.Templates = configMaps(map[string]string{"a": "1", "b": "1", "c": "1"})
+	_, err := instAction.Run(chrt, map[string]interface{}{})
+	req.NoError(err)
+
+	upAction := upgradeAction(t)
+	upAction.cfg = instAction.cfg
+	upAction.Atomic = true
+	upAction.AtomicStrategy = AtomicScoped
+	failer.Failures = []kubefake.Failure{{Verb: kubefake.VerbWait, Times: 1, Err: fmt.Errorf("not ready")}}
+	chrt = buildChart()
+	chrt.Templates = configMaps(map[string]string{"a": "1", "b": "2", "d": "1"})
+	res, err := upAction.Run("scoped", chrt, map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "the 3 resources changed by the upgrade have been reverted")
+	is.Equal(release.StatusFailed, res.Info.Status)
+	is.Contains(res.Info.Description, "reverted the 3 resources changed by the upgrade to revision 1")
+
+	// The changed resources are restored and the created one deleted, the
+	// unchanged one is left alone.
+	updates := failer.OperationsOf(kubefake.VerbUpdate)
+	req.Len(updates, 2)
+	var restored []string
+	for _, r := range updates[1].Resources {
+		restored = append(restored, r.Name)
+	}
+	is.Equal([]string{"b", "c"}, restored)
+	deletes := failer.OperationsOf(kubefake.VerbDelete)
+	req.Len(deletes, 1)
+	is.Equal("d", deletes[0].Resources[0].Name)
+
+	// No revision is created, and the previous one stays deployed.
+	history, err := upAction.cfg.Releases.History("scoped")
+	req.NoError(err)
+	is.Len(history, 2)
+	previous, err := upAction.cfg.Releases.Get("scoped", 1)
+	req.NoError(err)
+	is.Equal(release.StatusDeployed, previous.Info.Status)
+
+	upAction.AtomicStrategy = "partial"
+	_, err = upAction.Run("scoped", chrt, map[string]interface{}{})
+	is.ErrorContains(err, `invalid atomic strategy "partial"`)
+}
+
 func TestUpgradeRelease_ReuseValues(t *testing.T) {
 	is := assert.New(t)
 
@@ -361,6 +560,77 @@ func TestUpgradeRelease_ResetThenReuseValues(t *testing.T) {
 	})
 }
 
+func TestUpgradeRelease_ReuseValuesFor(t *testing.T) {
+	is := assert.New(t)
+
+	upAction := upgradeAction(t)
+
+	existingValues := map[string]interface{}{
+		"replicas": 2,
+		"image": map[string]interface{}{
+			"repository": "registry.example.com/web",
+			"tag":        "1.2.3",
+		},
+		"ingress": map[string]interface{}{
+			"enabled": true,
+			"hosts":   []interface{}{"web.example.com"},
+		},
+		"resources": "large",
+	}
+	newValues := map[string]interface{}{
+		"image": map[string]interface{}{
+			"tag": "1.2.4",
+		},
+	}
+	expectedValues := map[string]interface{}{
+		"image": map[string]interface{}{
+			"repository": "registry.example.com/web",
+			"tag":        "1.2.4",
+		},
+		"ingress": map[string]interface{}{
+			"hosts": []interface{}{"web.example.com"},
+		},
+	}
+
+	rel := releaseStub()
+	rel.Name = "nuketown"
+	rel.Info.Status = release.StatusDeployed
+	rel.Config = existingValues
+
+	err := upAction.cfg.Releases.Create(rel)
+	is.NoError(err)
+
+	// Paths that are missing or go through a value that is not a table are
+	// left out.
+	upAction.ReuseValuesFor = []string{"image", "ingress.hosts", "missing", "replicas.count"}
+	res, err := upAction.Run(rel.Name, buildChart(), newValues)
+	is.NoError(err)
+
+	updatedRes, err := upAction.cfg.Releases.Get(res.Name, 2)
+	is.NoError(err)
+	is.Equal(expectedValues, updatedRes.Config)
+}
+
+func TestUpgradeRelease_ComputedValues(t *testing.T) {
+	is := assert.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "computed"
+	rel.Info.Status = release.StatusDeployed
+	rel.Config = map[string]interface{}{"replicas": 2}
+	is.NoError(upAction.cfg.Releases.Create(rel))
+
+	upAction.ReuseValues = true
+	upAction.DryRun = true
+	res, err := upAction.Run(rel.Name, buildChart(withValues(map[string]interface{}{"image": "web"})), map[string]interface{}{"tag": "1.2.3"})
+	is.NoError(err)
+
+	vals, err := ComputedValues(res)
+	is.NoError(err)
+	is.Equal(map[string]interface{}{"replicas": 2, "tag": "1.2.3"}, vals.AsMap())
+}
+
 func TestUpgradeRelease_Pending(t *testing.T) {
 	req := require.New(t)
 
@@ -586,3 +856,118 @@ func TestUpgradeRelease_DryRun(t *testing.T) {
 	done()
 	req.Error(err)
 }
+
+func TestUpgradeRelease_SkipIfUnchanged(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	instAction := installAction(t)
+	instAction.ReleaseName = "unchanged"
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.ParseManifests = true
+	vals := map[string]interface{}{}
+	_, err := instAction.Run(multiNamespaceChart(), vals)
+	req.NoError(err)
+
+	upAction := upgradeAction(t)
+	upAction.cfg = instAction.cfg
+	upAction.SkipIfUnchanged = SkipIfUnchangedServer
+	res, err := upAction.Run("unchanged", multiNamespaceChart(), vals)
+	is.Equal(ErrReleaseUnchanged, err)
+	req.NotNil(res)
+	is.Equal(1, res.Version)
+	_, err = instAction.cfg.Releases.Get("unchanged", 2)
+	is.Error(err, "expected no new revision")
+
+	// Resources changed in the cluster are applied again.
+	failer.Changed = []string{"dashboards"}
+	res, err = upAction.Run("unchanged", multiNamespaceChart(), vals)
+	req.NoError(err)
+	is.Equal(2, res.Version)
+
+	// So are changed values.
+	failer.Changed = nil
+	res, err = upAction.Run("unchanged", multiNamespaceChart(), map[string]interface{}{"replicas": 2})
+	req.NoError(err)
+	is.Equal(3, res.Version)
+}
+
+func TestUpgradeRelease_SkipIfUnchangedLocal(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	instAction := installAction(t)
+	instAction.ReleaseName = "identical"
+	vals := map[string]interface{}{}
+	_, err := instAction.Run(buildChart(withSampleTemplates()), vals)
+	req.NoError(err)
+
+	upAction := upgradeAction(t)
+	upAction.cfg = instAction.cfg
+	upAction.SkipIfUnchanged = SkipIfUnchangedLocal
+	res, err := upAction.Run("identical", buildChart(withSampleTemplates()), vals)
+	is.Equal(ErrReleaseUnchanged, err)
+	req.NotNil(res)
+	is.Equal(1, res.Version)
+	history, err := instAction.cfg.Releases.History("identical")
+	req.NoError(err)
+	is.Len(history, 1)
+
+	// New labels make a new revision.
+	upAction.Labels = map[string]string{"team": "payments"}
+	res, err = upAction.Run("identical", buildChart(withSampleTemplates()), vals)
+	req.NoError(err)
+	is.Equal(2, res.Version)
+
+	// Labels already set do not.
+	res, err = upAction.Run("identical", buildChart(withSampleTemplates()), vals)
+	is.Equal(ErrReleaseUnchanged, err)
+	is.Equal(2, res.Version)
+
+	// So does a different chart.
+	res, err = upAction.Run("identical", buildChart(withSampleTemplates(), withNotes("new notes")), vals)
+	req.NoError(err)
+	is.Equal(3, res.Version)
+
+	upAction.SkipIfUnchanged = "remote"
+	_, err = upAction.Run("identical", buildChart(withSampleTemplates()), vals)
+	is.ErrorContains(err, `invalid skip-if-unchanged mode "remote"`)
+}
+
+func TestUpgradeRelease_SkipUnchangedAfterFailure(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	upAction.SkipUnchanged = true
+
+	resources := kube.ResourceList{newDeploymentResource("foo", "spaced"), newDeploymentResource("bar", "spaced")}
+	hashes, err := resourceHashes(resources)
+	req.NoError(err)
+
+	deployed := namedReleaseStub("skip-unchanged", release.StatusDeployed)
+	deployed.ResourceHashes = hashes
+	req.NoError(upAction.cfg.Releases.Create(deployed))
+
+	// The failed revision applied its own version of bar.
+	failed := namedReleaseStub("skip-unchanged", release.StatusFailed)
+	failed.Version = 2
+	failed.ResourceHashes = map[string]string{
+		objectKey(resources[0]): hashes[objectKey(resources[0])],
+		objectKey(resources[1]): "changed",
+	}
+	req.NoError(upAction.cfg.Releases.Create(failed))
+
+	upgraded := namedReleaseStub("skip-unchanged", release.StatusPendingUpgrade)
+	upgraded.Version = 3
+	unchanged, err := upAction.unchangedResources(deployed, upgraded, resources, resources)
+	req.NoError(err)
+	is.Equal(map[string]bool{objectKey(resources[0]): true}, unchanged)
+
+	// Revisions that failed before resources were hashed skip nothing.
+	failed.ResourceHashes = nil
+	req.NoError(upAction.cfg.Releases.Update(failed))
+	unchanged, err = upAction.unchangedResources(deployed, upgraded, resources, resources)
+	req.NoError(err)
+	is.Empty(unchanged)
+}
@@ -18,6 +18,8 @@ package action
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -25,7 +27,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/resource"
 
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/errcode"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 var accessor = meta.NewAccessor()
@@ -37,6 +42,15 @@ const (
 	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
 )
 
+// Labels stamped on the resources of a release when resource labeling is
+// enabled, so that `kubectl get -l` selects the resources of a release
+// without cooperation of the chart.
+const (
+	appInstanceLabel  = "app.kubernetes.io/instance"
+	helmChartLabel    = "helm.sh/chart"
+	helmRevisionLabel = "helm.sh/revision"
+)
+
 func existingResourceConflict(resources kube.ResourceList, releaseName, releaseNamespace string) (kube.ResourceList, error) {
 	var requireUpdate kube.ResourceList
 
@@ -56,7 +70,7 @@ func existingResourceConflict(resources kube.ResourceList, releaseName, releaseN
 
 		// Allow adoption of the resource if it is managed by Helm and is annotated with correct release name and namespace.
 		if err := checkOwnership(existing, releaseName, releaseNamespace); err != nil {
-			return fmt.Errorf("%s exists and cannot be imported into the current release: %s", resourceString(info), err)
+			return errcode.New(errcode.ResourceConflict, resourceString(info), err)
 		}
 
 		requireUpdate.Append(info)
@@ -147,6 +161,52 @@ func setMetadataVisitor(releaseName, releaseNamespace string, force bool) resour
 	}
 }
 
+// setReleaseLabelsVisitor stamps the release name, revision, chart and manager
+// as labels on all resources. Only the resources themselves are labeled, not
+// embedded templates such as the pod template of a Deployment, as changing
+// those would restart the pods on every upgrade. The labels are recorded as
+// the ResourceLabels of the release.
+func setReleaseLabelsVisitor(rel *release.Release) resource.VisitorFunc {
+	labels := map[string]string{
+		appManagedByLabel: appManagedByHelm,
+		appInstanceLabel:  chartutil.LabelValue(rel.Name),
+		helmRevisionLabel: strconv.Itoa(rel.Version),
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		// The same value as the helm.sh/chart label of the helpers of
+		// 'helm create', which replace the '+' of versions by '_'.
+		labels[helmChartLabel] = chartutil.LabelValue(strings.ReplaceAll(rel.Chart.Name()+"-"+rel.Chart.Metadata.Version, "+", "_"))
+	}
+	rel.ResourceLabels = labels
+	return setLabelsVisitor(labels)
+}
+
+// addStampedLabels adds the labels the release stamped on its resources to
+// the resources built from its manifest, so that updating them to other
+// resources removes the labels those are not stamped with.
+func addStampedLabels(rel *release.Release, resources kube.ResourceList) error {
+	if len(rel.ResourceLabels) == 0 {
+		return nil
+	}
+	return resources.Visit(setLabelsVisitor(rel.ResourceLabels))
+}
+
+// setLabelsVisitor merges the labels into those of all resources.
+func setLabelsVisitor(labels map[string]string) resource.VisitorFunc {
+	return func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := mergeLabels(info.Object, labels); err != nil {
+			return fmt.Errorf(
+				"%s labels could not be updated: %s",
+				resourceString(info), err,
+			)
+		}
+		return nil
+	}
+}
+
 func resourceString(info *resource.Info) string {
 	_, k := info.Mapping.GroupVersionKind.ToAPIVersionAndKind()
 	return fmt.Sprintf(
@@ -121,3 +121,29 @@ func TestSetMetadataVisitor(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), `Deployment "baz" in namespace "" cannot be owned`)
 }
+
+func TestSetReleaseLabelsVisitor(t *testing.T) {
+	deployFoo := newDeploymentResource("foo", "ns-a")
+	_ = accessor.SetLabels(deployFoo.Object, map[string]string{"app": "foo"})
+	resources := kube.ResourceList{deployFoo}
+
+	rel := releaseStub()
+	rel.Version = 3
+	rel.Chart.Metadata.Version = "1.0.0+build.1"
+
+	err := resources.Visit(setReleaseLabelsVisitor(rel))
+	assert.NoError(t, err)
+
+	labels, err := accessor.Labels(deployFoo.Object)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"app":             "foo",
+		appManagedByLabel: appManagedByHelm,
+		appInstanceLabel:  "angry-panda",
+		helmChartLabel:    "hello-1.0.0_build.1",
+		helmRevisionLabel: "3",
+	}, labels)
+
+	// The pod template is left untouched to not restart pods on upgrades.
+	assert.Empty(t, deployFoo.Object.(*appsv1.Deployment).Spec.Template.Labels)
+}
@@ -0,0 +1,276 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// ReleaseEvent is a lifecycle event of a release that webhooks are notified of.
+type ReleaseEvent string
+
+const (
+	// EventInstalled is sent when a release was installed.
+	EventInstalled ReleaseEvent = "installed"
+	// EventUpgraded is sent when a release was upgraded.
+	EventUpgraded ReleaseEvent = "upgraded"
+	// EventRolledBack is sent when a release was rolled back.
+	EventRolledBack ReleaseEvent = "rolled-back"
+	// EventUninstalled is sent when a release was uninstalled.
+	EventUninstalled ReleaseEvent = "uninstalled"
+	// EventFailed is sent when installing, upgrading, rolling back or
+	// uninstalling a release failed.
+	EventFailed ReleaseEvent = "failed"
+)
+
+const (
+	defaultWebhookTimeout       = 10 * time.Second
+	defaultWebhookRetryInterval = time.Second
+)
+
+// Webhook is an HTTP endpoint notified of release events.
+type Webhook struct {
+	// URL is the endpoint the events are posted to.
+	URL string `json:"url"`
+	// Events limits the events sent to the webhook. All events are sent if
+	// it is empty.
+	Events []ReleaseEvent `json:"events,omitempty"`
+	// Payload is a Go template rendering the request body from a
+	// WebhookPayload, with the Sprig functions available. The
+	// WebhookPayload is sent as JSON if it is empty.
+	Payload string `json:"payload,omitempty"`
+	// Headers are set on the requests, e.g. for authentication.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Retries is the number of times a request is retried after a network
+	// error or a 429 or 5xx response.
+	Retries int `json:"retries,omitempty"`
+	// RetryInterval is the time to wait before the first retry. It doubles
+	// with every retry. The default is 1s.
+	RetryInterval metav1.Duration `json:"retryInterval,omitempty"`
+	// Timeout is the timeout of a single request. The default is 10s.
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// WebhookPayload is the data sent to webhooks for a release event.
+type WebhookPayload struct {
+	// Event is the release event.
+	Event ReleaseEvent `json:"event"`
+	// Action is the action that caused the event, e.g. "upgrade".
+	Action string `json:"action"`
+	// Release describes the release.
+	Release WebhookRelease `json:"release"`
+	// Error is the error of a failed action.
+	Error string `json:"error,omitempty"`
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+}
+
+// WebhookRelease describes the release of a WebhookPayload.
+type WebhookRelease struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace,omitempty"`
+	Revision     int    `json:"revision,omitempty"`
+	Chart        string `json:"chart,omitempty"`
+	ChartVersion string `json:"chartVersion,omitempty"`
+	AppVersion   string `json:"appVersion,omitempty"`
+	Status       string `json:"status,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// webhooksFile is the format of the webhooks configuration file.
+type webhooksFile struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// LoadWebhooks reads the webhooks configured in the given YAML file. A missing
+// file configures no webhooks.
+func LoadWebhooks(path string) ([]Webhook, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f webhooksFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, errors.Wrapf(err, "invalid webhooks configuration %s", path)
+	}
+	for i, w := range f.Webhooks {
+		if w.URL == "" {
+			return nil, errors.Errorf("invalid webhooks configuration %s: webhook %d has no url", path, i)
+		}
+		if w.Payload != "" {
+			if _, err := parseWebhookPayload(w.Payload); err != nil {
+				return nil, errors.Wrapf(err, "invalid webhooks configuration %s: webhook %s", path, w.URL)
+			}
+		}
+	}
+	return f.Webhooks, nil
+}
+
+func parseWebhookPayload(payload string) (*template.Template, error) {
+	return template.New("payload").Funcs(sprig.TxtFuncMap()).Parse(payload)
+}
+
+// notifyWebhooks sends the event for the outcome of an action to the
+// configured webhooks. rel may be nil if the action failed before the release
+// was loaded. Webhooks failing to receive the event are logged, they do not
+// fail the action.
+func (cfg *Configuration) notifyWebhooks(action string, event ReleaseEvent, name string, rel *release.Release, err error) {
+	webhooks, loadErr := cfg.webhooks()
+	if loadErr != nil {
+		cfg.Log("warning: unable to notify the webhooks: %s", loadErr)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload := WebhookPayload{
+		Event:   event,
+		Action:  action,
+		Release: WebhookRelease{Name: name},
+		Time:    time.Now(),
+	}
+	if err != nil {
+		payload.Event = EventFailed
+		payload.Error = err.Error()
+	}
+	if rel != nil {
+		payload.Release.Namespace = rel.Namespace
+		payload.Release.Revision = rel.Version
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			payload.Release.Chart = rel.Chart.Metadata.Name
+			payload.Release.ChartVersion = rel.Chart.Metadata.Version
+			payload.Release.AppVersion = rel.Chart.Metadata.AppVersion
+		}
+		if rel.Info != nil {
+			payload.Release.Status = rel.Info.Status.String()
+			payload.Release.Description = rel.Info.Description
+		}
+	}
+
+	for _, w := range webhooks {
+		if !w.wants(payload.Event) {
+			continue
+		}
+		if err := w.send(payload); err != nil {
+			cfg.Log("warning: webhook %s failed: %s", w.URL, err)
+		}
+	}
+}
+
+func (w Webhook) wants(event ReleaseEvent) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// send posts the payload to the webhook, retrying as configured.
+func (w Webhook) send(payload WebhookPayload) error {
+	body, contentType, err := w.body(payload)
+	if err != nil {
+		return err
+	}
+
+	interval := w.RetryInterval.Duration
+	if interval <= 0 {
+		interval = defaultWebhookRetryInterval
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body, contentType)
+		if err == nil || !retry || attempt >= w.Retries {
+			return err
+		}
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+func (w Webhook) body(payload WebhookPayload) ([]byte, string, error) {
+	if w.Payload == "" {
+		data, err := json.Marshal(payload)
+		return data, "application/json", err
+	}
+	tpl, err := parseWebhookPayload(w.Payload)
+	if err != nil {
+		return nil, "", err
+	}
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, payload); err != nil {
+		return nil, "", errors.Wrap(err, "unable to render payload")
+	}
+	contentType := "text/plain"
+	if json.Valid(b.Bytes()) {
+		contentType = "application/json"
+	}
+	return b.Bytes(), contentType, nil
+}
+
+// post sends a single request and reports whether a failure may be retried.
+func (w Webhook) post(body []byte, contentType string) (bool, error) {
+	timeout := w.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected response status %s", resp.Status)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+// webhookRecorder is a webhook endpoint recording the requests it receives.
+type webhookRecorder struct {
+	mu       sync.Mutex
+	bodies   []string
+	types    []string
+	failures int
+}
+
+func (w *webhookRecorder) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	w.bodies = append(w.bodies, string(body))
+	w.types = append(w.types, r.Header.Get("Content-Type"))
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	dir := t.TempDir()
+
+	webhooks, err := LoadWebhooks(filepath.Join(dir, "missing.yaml"))
+	require.NoError(t, err)
+	assert.Nil(t, webhooks)
+
+	file := filepath.Join(dir, "webhooks.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`webhooks:
+- url: https://example.com/hook
+  events: [failed, uninstalled]
+  payload: '{"text": "{{ .Release.Name }} {{ .Event }}"}'
+  retries: 3
+  retryInterval: 2s
+`), 0644))
+	webhooks, err = LoadWebhooks(file)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, []ReleaseEvent{EventFailed, EventUninstalled}, webhooks[0].Events)
+	assert.Equal(t, 3, webhooks[0].Retries)
+	assert.Equal(t, 2*time.Second, webhooks[0].RetryInterval.Duration)
+
+	for content, expect := range map[string]string{
+		"webhooks:\n- events: [failed]\n":                 "webhook 0 has no url",
+		"webhooks:\n- url: x\n  payload: '{{ .Release'\n": "webhook x",
+		"webhooks:\n- url: x\n  retry: 3\n":               "unknown field",
+	} {
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+		_, err := LoadWebhooks(file)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), expect)
+		}
+	}
+}
+
+func TestNotifyWebhooks(t *testing.T) {
+	all := &webhookRecorder{failures: 2}
+	failures := &webhookRecorder{}
+	allServer := httptest.NewServer(all)
+	defer allServer.Close()
+	failuresServer := httptest.NewServer(failures)
+	defer failuresServer.Close()
+
+	instAction := installAction(t)
+	instAction.cfg.Webhooks = []Webhook{
+		{URL: allServer.URL, Retries: 2, RetryInterval: metav1.Duration{Duration: time.Millisecond}},
+		{URL: failuresServer.URL, Events: []ReleaseEvent{EventFailed}, Payload: `{"text": "{{ .Release.Name }} {{ .Event }}: {{ .Error }}"}`},
+	}
+
+	_, err := instAction.Run(buildChart(), nil)
+	require.NoError(t, err)
+
+	require.Len(t, all.bodies, 1)
+	assert.Empty(t, failures.bodies)
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal([]byte(all.bodies[0]), &payload))
+	assert.Equal(t, EventInstalled, payload.Event)
+	assert.Equal(t, "install", payload.Action)
+	assert.Equal(t, WebhookRelease{
+		Name:         "test-install-release",
+		Namespace:    "spaced",
+		Revision:     1,
+		Chart:        "hello",
+		ChartVersion: "0.1.0",
+		Status:       "deployed",
+		Description:  "Install complete",
+	}, payload.Release)
+
+	upAction := NewUpgrade(instAction.cfg)
+	upAction.cfg.KubeClient = &kubefake.FailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}, UpdateError: context.DeadlineExceeded}
+	_, err = upAction.Run("test-install-release", buildChart(), nil)
+	require.Error(t, err)
+
+	require.Len(t, all.bodies, 2)
+	require.Len(t, failures.bodies, 1)
+	assert.Equal(t, `{"text": "test-install-release failed: context deadline exceeded"}`, failures.bodies[0])
+	assert.Equal(t, "application/json", failures.types[0])
+}
@@ -0,0 +1,515 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package admission evaluates the ValidatingAdmissionPolicies of a cluster
+against rendered manifests offline, so that objects the API server would
+reject are caught before a chart is deployed.
+
+The CEL expressions of the policies are compiled and evaluated with cel-go and
+the CEL environment and libraries of the API server, so that they behave as
+they do in the cluster. Expressions using the authorizer cannot be evaluated
+offline, and fail as the failure policy of their policy says.
+*/
+package admission // import "helm.sh/helm/v3/pkg/admission"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apiserver/pkg/admission"
+	plugincel "k8s.io/apiserver/pkg/admission/plugin/cel"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/validating"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/matchconditions"
+	"k8s.io/apiserver/pkg/apis/cel"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/cel/environment"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// Policies holds ValidatingAdmissionPolicies and their bindings.
+type Policies struct {
+	Policies []admissionv1.ValidatingAdmissionPolicy
+	Bindings []admissionv1.ValidatingAdmissionPolicyBinding
+}
+
+// Violation is a validation of a policy failed by a rendered object.
+type Violation struct {
+	// Policy and Binding are the names of the failed policy and of the
+	// binding applying it.
+	Policy  string
+	Binding string
+	// Object identifies the object, e.g. "Deployment/default/web".
+	Object string
+	// Message explains the failure.
+	Message string
+	// Actions are the validation actions of the binding.
+	Actions []admissionv1.ValidationAction
+}
+
+// Denied reports whether the API server would reject the object.
+func (v Violation) Denied() bool {
+	for _, a := range v.Actions {
+		if a == admissionv1.Deny {
+			return true
+		}
+	}
+	return false
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: ValidatingAdmissionPolicy '%s' with binding '%s' denied request: %s", v.Object, v.Policy, v.Binding, v.Message)
+}
+
+// Load reads the policies and bindings from the given YAML files, or from the
+// .yaml, .yml and .json files of the given directories. Documents of other
+// kinds are ignored, and lists such as the output of
+// "kubectl get validatingadmissionpolicies -o yaml" are expanded.
+func Load(paths ...string) (*Policies, error) {
+	p := &Policies{}
+	for _, path := range paths {
+		files := []string{path}
+		if fi, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if fi.IsDir() {
+			files = nil
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				switch filepath.Ext(e.Name()) {
+				case ".yaml", ".yml", ".json":
+					if !e.IsDir() {
+						files = append(files, filepath.Join(path, e.Name()))
+					}
+				}
+			}
+		}
+		for _, f := range files {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.add(string(data)); err != nil {
+				return nil, errors.Wrapf(err, "reading admission policies from %s", f)
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *Policies) add(manifest string) error {
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+	for _, k := range keys {
+		data, err := yaml.YAMLToJSON([]byte(docs[k]))
+		if err != nil {
+			return err
+		}
+		if err := p.addJSON(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Policies) addJSON(data []byte) error {
+	var meta struct {
+		Kind  string            `json:"kind"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return err
+	}
+	switch {
+	case meta.Kind == "ValidatingAdmissionPolicy":
+		var policy admissionv1.ValidatingAdmissionPolicy
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return err
+		}
+		p.Policies = append(p.Policies, policy)
+	case meta.Kind == "ValidatingAdmissionPolicyBinding":
+		var binding admissionv1.ValidatingAdmissionPolicyBinding
+		if err := json.Unmarshal(data, &binding); err != nil {
+			return err
+		}
+		p.Bindings = append(p.Bindings, binding)
+	case strings.HasSuffix(meta.Kind, "List"):
+		for _, item := range meta.Items {
+			if err := p.addJSON(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FromCluster reads the policies and bindings of a cluster, using the v1beta1
+// API on clusters older than Kubernetes 1.30.
+func FromCluster(ctx context.Context, client kubernetes.Interface) (*Policies, error) {
+	p := &Policies{}
+	policies, err := client.AdmissionregistrationV1().ValidatingAdmissionPolicies().List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return fromClusterV1beta1(ctx, client)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the ValidatingAdmissionPolicies of the cluster")
+	}
+	bindings, err := client.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the ValidatingAdmissionPolicyBindings of the cluster")
+	}
+	p.Policies = policies.Items
+	p.Bindings = bindings.Items
+	return p, nil
+}
+
+func fromClusterV1beta1(ctx context.Context, client kubernetes.Interface) (*Policies, error) {
+	p := &Policies{}
+	policies, err := client.AdmissionregistrationV1beta1().ValidatingAdmissionPolicies().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the ValidatingAdmissionPolicies of the cluster")
+	}
+	bindings, err := client.AdmissionregistrationV1beta1().ValidatingAdmissionPolicyBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the ValidatingAdmissionPolicyBindings of the cluster")
+	}
+	// The v1beta1 and v1 types share their schema.
+	if err := convert(policies.Items, &p.Policies); err != nil {
+		return nil, err
+	}
+	if err := convert(bindings.Items, &p.Bindings); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func convert(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// ValidateManifest validates the objects of a manifest, as rendered for a
+// release in the given namespace, against the policies.
+func (p *Policies) ValidateManifest(manifest, namespace string) ([]Violation, error) {
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var objects []*unstructured.Unstructured
+	for _, k := range keys {
+		data, err := yaml.YAMLToJSON([]byte(docs[k]))
+		if err != nil {
+			return nil, err
+		}
+		if string(data) == "null" {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+	return p.Validate(objects, namespace)
+}
+
+// Validate evaluates the policies against the given objects, as if they were
+// created in the given namespace, and returns the failed validations.
+//
+// Policies apply through their bindings. If there are no bindings at all,
+// every policy applies as if bound with the Deny action. Policies with
+// parameters are skipped, as are the namespace selectors, since the
+// parameters and the namespaces are not known offline.
+//
+// Policies with expressions using the authorizer cannot be evaluated, so they
+// fail every object they match unless their failure policy is Ignore.
+func (p *Policies) Validate(objects []*unstructured.Unstructured, namespace string) ([]Violation, error) {
+	bindings := p.Bindings
+	if len(bindings) == 0 {
+		for _, policy := range p.Policies {
+			b := admissionv1.ValidatingAdmissionPolicyBinding{}
+			b.Name = policy.Name
+			b.Spec.PolicyName = policy.Name
+			b.Spec.ValidationActions = []admissionv1.ValidationAction{admissionv1.Deny}
+			bindings = append(bindings, b)
+		}
+	}
+
+	var violations []Violation
+	for i := range p.Policies {
+		policy := &p.Policies[i]
+		if policy.Spec.ParamKind != nil {
+			continue
+		}
+		compiled, usesAuthorizer, err := compile(policy)
+		if err != nil {
+			return nil, err
+		}
+		if usesAuthorizer && policy.Spec.FailurePolicy != nil && *policy.Spec.FailurePolicy == admissionv1.Ignore {
+			continue
+		}
+		for _, binding := range bindings {
+			if binding.Spec.PolicyName != policy.Name {
+				continue
+			}
+			for _, obj := range objects {
+				if !matches(policy.Spec.MatchConstraints, obj) || !matches(binding.Spec.MatchResources, obj) {
+					continue
+				}
+				messages := []string{"cannot evaluate the policy offline: its expressions use the authorizer"}
+				if !usesAuthorizer {
+					messages = validate(compiled, obj, namespace)
+				}
+				for _, message := range messages {
+					violations = append(violations, Violation{
+						Policy:  policy.Name,
+						Binding: binding.Name,
+						Object:  objectName(obj, namespace),
+						Message: message,
+						Actions: binding.Spec.ValidationActions,
+					})
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+func objectName(obj *unstructured.Unstructured, namespace string) string {
+	if ns := obj.GetNamespace(); ns != "" {
+		namespace = ns
+	}
+	if namespace == "" {
+		return obj.GetKind() + "/" + obj.GetName()
+	}
+	return obj.GetKind() + "/" + namespace + "/" + obj.GetName()
+}
+
+// matches reports whether the object is selected by the resource rules and
+// the object selector. Without a cluster, objects are only ever created.
+func matches(m *admissionv1.MatchResources, obj *unstructured.Unstructured) bool {
+	if m == nil {
+		return true
+	}
+	if m.ObjectSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(m.ObjectSelector)
+		if err != nil || !selector.Matches(labels.Set(obj.GetLabels())) {
+			return false
+		}
+	}
+	if len(m.ResourceRules) > 0 && !matchesAny(m.ResourceRules, obj) {
+		return false
+	}
+	return !matchesAny(m.ExcludeResourceRules, obj)
+}
+
+func matchesAny(rules []admissionv1.NamedRuleWithOperations, obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	resource := pluralize(gvk.Kind)
+	for _, r := range rules {
+		if len(r.ResourceNames) > 0 && !contains(r.ResourceNames, obj.GetName()) {
+			continue
+		}
+		operations := make([]string, len(r.Operations))
+		for i, o := range r.Operations {
+			operations[i] = string(o)
+		}
+		resources := make([]string, len(r.Resources))
+		for i, res := range r.Resources {
+			// Subresources are never created along with the objects.
+			resources[i] = strings.TrimSuffix(res, "/*")
+		}
+		if containsOrAll(operations, string(admissionv1.Create)) &&
+			containsOrAll(r.APIGroups, gvk.Group) &&
+			containsOrAll(r.APIVersions, gvk.Version) &&
+			containsOrAll(resources, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// pluralize guesses the resource of a kind, as the REST mapper is not
+// available offline.
+func pluralize(kind string) string {
+	r := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(r, "s"), strings.HasSuffix(r, "x"), strings.HasSuffix(r, "ch"), strings.HasSuffix(r, "sh"):
+		return r + "es"
+	case strings.HasSuffix(r, "y") && !strings.HasSuffix(r, "ay") && !strings.HasSuffix(r, "ey") && !strings.HasSuffix(r, "oy"):
+		return strings.TrimSuffix(r, "y") + "ies"
+	}
+	return r + "s"
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOrAll(list []string, s string) bool {
+	return contains(list, s) || contains(list, "*")
+}
+
+// compile compiles the expressions of a policy with the CEL environment and
+// libraries of the API server, as the ValidatingAdmissionPolicy admission
+// plugin does, and reports whether they use the authorizer.
+func compile(policy *admissionv1.ValidatingAdmissionPolicy) (validating.Validator, bool, error) {
+	envSet, err := baseEnvSet()
+	if err != nil {
+		return nil, false, err
+	}
+	compiler, err := plugincel.NewCompositedCompiler(envSet)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to build the CEL environment")
+	}
+	// Expressions are also compiled without the authorizer to find those
+	// that use it, as only they fail to compile then.
+	probe, err := plugincel.NewCompositedCompiler(envSet)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to build the CEL environment")
+	}
+	optionalVars := plugincel.OptionalVariableDeclarations{HasAuthorizer: true}
+	messageVars := plugincel.OptionalVariableDeclarations{}
+	usesAuthorizer := false
+	needsAuthorizer := func(with, without plugincel.CompilationResult) {
+		if with.Error == nil && without.Error != nil {
+			usesAuthorizer = true
+		}
+	}
+
+	for _, v := range policy.Spec.Variables {
+		variable := &validating.Variable{Name: v.Name, Expression: v.Expression}
+		needsAuthorizer(
+			compiler.CompileAndStoreVariable(variable, optionalVars, environment.StoredExpressions),
+			probe.CompileAndStoreVariable(variable, plugincel.OptionalVariableDeclarations{}, environment.StoredExpressions),
+		)
+	}
+	probeAll := func(accessors []plugincel.ExpressionAccessor) {
+		for _, a := range accessors {
+			if a != nil {
+				needsAuthorizer(
+					compiler.CompileCELExpression(a, optionalVars, environment.StoredExpressions),
+					probe.CompileCELExpression(a, plugincel.OptionalVariableDeclarations{}, environment.StoredExpressions),
+				)
+			}
+		}
+	}
+
+	var matcher matchconditions.Matcher
+	if conditions := policy.Spec.MatchConditions; len(conditions) > 0 {
+		accessors := make([]plugincel.ExpressionAccessor, len(conditions))
+		for i := range conditions {
+			accessors[i] = (*matchconditions.MatchCondition)(&conditions[i])
+		}
+		probeAll(accessors)
+		matcher = matchconditions.NewMatcher(compiler.Compile(accessors, optionalVars, environment.StoredExpressions), policy.Spec.FailurePolicy, "policy", "validate", policy.Name)
+	}
+
+	validations := make([]plugincel.ExpressionAccessor, len(policy.Spec.Validations))
+	messages := make([]plugincel.ExpressionAccessor, len(policy.Spec.Validations))
+	for i, v := range policy.Spec.Validations {
+		validations[i] = &validating.ValidationCondition{Expression: v.Expression, Message: v.Message, Reason: v.Reason}
+		if v.MessageExpression != "" {
+			messages[i] = &validating.MessageExpressionCondition{MessageExpression: v.MessageExpression}
+		}
+	}
+	probeAll(validations)
+	return validating.NewValidator(
+		compiler.Compile(validations, optionalVars, environment.StoredExpressions),
+		matcher,
+		compiler.Compile(nil, optionalVars, environment.StoredExpressions),
+		compiler.Compile(messages, messageVars, environment.StoredExpressions),
+		policy.Spec.FailurePolicy,
+	), usesAuthorizer, nil
+}
+
+var (
+	envSetOnce sync.Once
+	envSet     *environment.EnvSet
+	envSetErr  error
+)
+
+// baseEnvSet returns the CEL environment of the API server, which is built
+// the first time policies are compiled.
+func baseEnvSet() (*environment.EnvSet, error) {
+	envSetOnce.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				envSetErr = errors.Errorf("unable to build the CEL environment: %v", r)
+			}
+		}()
+		envSet = environment.MustBaseEnvSet(environment.DefaultCompatibilityVersion())
+	})
+	return envSet, envSetErr
+}
+
+// validate returns the messages of the validations the object fails, as
+// created in the namespace by an anonymous user with a dry run.
+func validate(validator validating.Validator, obj *unstructured.Unstructured, namespace string) []string {
+	gvk := obj.GroupVersionKind()
+	gvr := gvk.GroupVersion().WithResource(pluralize(gvk.Kind))
+	if ns := obj.GetNamespace(); ns != "" {
+		namespace = ns
+	}
+	dryRun := true
+	attr := admission.NewAttributesRecord(obj, nil, gvk, namespace, obj.GetName(), gvr, "", admission.Create,
+		&metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}, dryRun, &user.DefaultInfo{})
+	versioned := &admission.VersionedAttributes{Attributes: attr, VersionedKind: gvk, VersionedObject: obj}
+
+	result := validator.Validate(context.Background(), gvr, versioned, nil, nil, cel.RuntimeCELCostBudget, nil)
+	var messages []string
+	for _, d := range result.Decisions {
+		if d.Action == validating.ActionDeny {
+			messages = append(messages, d.Message)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,218 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+)
+
+const manifest = `---
+# Source: chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    team: frontend
+spec:
+  replicas: 10
+---
+# Source: chart/templates/exempt.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: batch
+  labels:
+    exempt: "true"
+spec:
+  replicas: 10
+---
+# Source: chart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+`
+
+func TestLoad(t *testing.T) {
+	p, err := Load("testdata/policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Policies) != 2 || len(p.Bindings) != 2 {
+		t.Fatalf("expected 2 policies and 2 bindings, got %d and %d", len(p.Policies), len(p.Bindings))
+	}
+	if _, err := Load("testdata/missing"); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
+
+func TestValidateManifest(t *testing.T) {
+	p, err := Load("testdata/policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations, err := p.ValidateManifest(manifest, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(violations))
+	for i, v := range violations {
+		got[i] = v.String()
+	}
+	expected := []string{
+		"Deployment/default/batch: ValidatingAdmissionPolicy 'require-team' with binding 'require-team-binding' denied request: the team label is required",
+		"Service/default/web: ValidatingAdmissionPolicy 'require-team' with binding 'require-team-binding' denied request: the team label is required",
+		"Deployment/default/web: ValidatingAdmissionPolicy 'max-replicas' with binding 'max-replicas-binding' denied request: replicas must be no greater than 5, got 10",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected violations\n%q\ngot\n%q", expected, got)
+	}
+	if violations[0].Denied() || !violations[2].Denied() {
+		t.Error("expected only the violation of the Deny binding to be denied")
+	}
+
+	// The match condition excludes system namespaces.
+	violations, err = p.ValidateManifest(manifest, "kube-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].Policy != "max-replicas" {
+		t.Errorf("expected only the max-replicas violation, got %v", violations)
+	}
+}
+
+func TestValidateFailurePolicy(t *testing.T) {
+	policy := admissionv1.ValidatingAdmissionPolicy{}
+	policy.Name = "broken"
+	policy.Spec.Validations = []admissionv1.Validation{{Expression: "object.spec.missing == 1"}}
+	p := &Policies{Policies: []admissionv1.ValidatingAdmissionPolicy{policy}}
+
+	// Without bindings, policies are denied by default and fail closed.
+	violations, err := p.ValidateManifest(manifest, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 4 || !violations[0].Denied() {
+		t.Fatalf("expected 4 denied violations, got %v", violations)
+	}
+
+	ignore := admissionv1.Ignore
+	p.Policies[0].Spec.FailurePolicy = &ignore
+	violations, err = p.ValidateManifest(manifest, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("expected errors to be ignored, got %v", violations)
+	}
+}
+
+func TestValidateKubernetesLibraries(t *testing.T) {
+	policy := admissionv1.ValidatingAdmissionPolicy{}
+	policy.Name = "quantity"
+	policy.Spec.MatchConstraints = &admissionv1.MatchResources{ResourceRules: []admissionv1.NamedRuleWithOperations{{
+		RuleWithOperations: admissionv1.RuleWithOperations{
+			Operations: []admissionv1.OperationType{admissionv1.Create},
+			Rule:       admissionv1.Rule{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"configmaps"}},
+		},
+	}}}
+	policy.Spec.Validations = []admissionv1.Validation{{
+		Expression:        "quantity(object.data.size).isLessThan(quantity('1Gi'))",
+		MessageExpression: "'size ' + object.data.size + ' is too large'",
+	}}
+	p := &Policies{Policies: []admissionv1.ValidatingAdmissionPolicy{policy}}
+
+	violations, err := p.ValidateManifest(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: small
+data:
+  size: 512Mi
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: large
+data:
+  size: 2Gi
+`, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].Object != "ConfigMap/default/large" || violations[0].Message != "size 2Gi is too large" {
+		t.Errorf("expected only the large ConfigMap to be denied, got %v", violations)
+	}
+
+}
+
+func TestValidateAuthorizer(t *testing.T) {
+	policy := admissionv1.ValidatingAdmissionPolicy{}
+	policy.Name = "authorized"
+	policy.Spec.MatchConstraints = &admissionv1.MatchResources{ResourceRules: []admissionv1.NamedRuleWithOperations{{
+		RuleWithOperations: admissionv1.RuleWithOperations{
+			Operations: []admissionv1.OperationType{admissionv1.Create},
+			Rule:       admissionv1.Rule{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"configmaps"}},
+		},
+	}}}
+	policy.Spec.Variables = []admissionv1.Variable{{Name: "allowed", Expression: "authorizer.group('').resource('configmaps').check('create').allowed()"}}
+	policy.Spec.Validations = []admissionv1.Validation{{Expression: "variables.allowed"}}
+	p := &Policies{Policies: []admissionv1.ValidatingAdmissionPolicy{policy}}
+
+	// The authorizer is not available offline, so the policy fails closed.
+	violations, err := p.ValidateManifest(manifest, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || !violations[0].Denied() || !strings.Contains(violations[0].Message, "cannot evaluate") {
+		t.Errorf("expected the authorizer to fail closed, got %v", violations)
+	}
+
+	ignore := admissionv1.Ignore
+	p.Policies[0].Spec.FailurePolicy = &ignore
+	violations, err = p.ValidateManifest(manifest, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("expected the policy to be ignored, got %v", violations)
+	}
+
+	// Validations using the authorizer directly are found as well.
+	p.Policies[0].Spec.FailurePolicy = nil
+	p.Policies[0].Spec.Variables = nil
+	p.Policies[0].Spec.Validations = []admissionv1.Validation{{Expression: "authorizer.group('').resource('configmaps').check('create').allowed()"}}
+	violations, err = p.ValidateManifest(manifest, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || !strings.Contains(violations[0].Message, "cannot evaluate") {
+		t.Errorf("expected the policy not to be evaluated, got %v", violations)
+	}
+}
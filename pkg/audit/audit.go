@@ -0,0 +1,127 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package audit records an append-only trail of the Helm operations changing
+releases, for compliance in shared clusters.
+
+Entries are written to a Sink: a file of JSON lines, a stream of ConfigMaps or
+Secrets, or a table in the database of the SQL storage driver.
+*/
+package audit // import "helm.sh/helm/v3/pkg/audit"
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+const (
+	// ResultSuccess is the result of an operation that succeeded.
+	ResultSuccess = "success"
+	// ResultFailure is the result of an operation that failed.
+	ResultFailure = "failure"
+)
+
+// Entry is a Helm operation recorded in the audit log.
+type Entry struct {
+	// Time is when the operation completed.
+	Time time.Time `json:"time"`
+	// User is the Kubernetes user that ran the operation.
+	User string `json:"user,omitempty"`
+	// OSUser is the operating system user that ran Helm.
+	OSUser string `json:"osUser,omitempty"`
+	// Action is the operation, e.g. "upgrade".
+	Action string `json:"action"`
+	// Release is the name of the release.
+	Release string `json:"release"`
+	// Namespace is the namespace of the release.
+	Namespace string `json:"namespace,omitempty"`
+	// Revision is the revision of the release the operation created or
+	// removed.
+	Revision int `json:"revision,omitempty"`
+	// Chart is the name of the chart of the release.
+	Chart string `json:"chart,omitempty"`
+	// ChartVersion is the version of the chart of the release.
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// ValuesDigest is the digest of the values supplied by the user, see
+	// ValuesDigest. The values themselves are not recorded as they may hold
+	// secrets.
+	ValuesDigest string `json:"valuesDigest,omitempty"`
+	// Result is either ResultSuccess or ResultFailure.
+	Result string `json:"result"`
+	// Error is the error of a failed operation.
+	Error string `json:"error,omitempty"`
+	// Note is additional information about the operation, e.g. the freeze
+	// window an operation was allowed to run in.
+	Note string `json:"note,omitempty"`
+}
+
+// Sink stores audit log entries.
+type Sink interface {
+	// Record appends an entry to the audit log.
+	Record(e Entry) error
+	// List returns the entries of the audit log, oldest first.
+	List() ([]Entry, error)
+}
+
+// ValuesDigest returns the SHA-256 digest of values, so that operations using
+// the same values can be recognized without recording them. It is the values
+// digest of the revisions of releases, see release.ValuesDigest.
+func ValuesDigest(values map[string]interface{}) string {
+	return release.ValuesDigest(values)
+}
+
+// Open returns the sink described by spec:
+//
+//   - "file:<path>" appends JSON lines to the file at path.
+//   - "configmap" and "secret" create a ConfigMap or Secret per entry in the
+//     given namespace.
+//   - "sql" writes to a table of the database given by the
+//     HELM_DRIVER_SQL_CONNECTION_STRING and HELM_DRIVER_SQL_AUTH environment
+//     variables, the ones used by the SQL storage driver.
+//
+// clientset is only called for the "configmap" and "secret" sinks, the first
+// time an entry is recorded or listed.
+func Open(spec, namespace string, clientset func() (kubernetes.Interface, error)) (Sink, error) {
+	switch {
+	case strings.HasPrefix(spec, "file:"):
+		path := strings.TrimPrefix(spec, "file:")
+		if path == "" {
+			return nil, errors.New("audit log file path is empty")
+		}
+		return NewFile(path), nil
+	case spec == "configmap" || spec == "configmaps":
+		return NewConfigMaps(namespace, clientset), nil
+	case spec == "secret" || spec == "secrets":
+		return NewSecrets(namespace, clientset), nil
+	case spec == "sql":
+		connectionString := os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING")
+		password, err := driver.SQLPasswordFromEnv(connectionString)
+		if err != nil {
+			return nil, err
+		}
+		return NewSQL(connectionString, password)
+	default:
+		return nil, errors.Errorf("unknown audit log %q: must be file:<path>, configmap, secret or sql", spec)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testEntries() []Entry {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []Entry{
+		{Time: now, User: "alice", Action: "install", Release: "foo", Namespace: "default", Revision: 1, Chart: "hello", ChartVersion: "0.1.0", Result: ResultSuccess},
+		{Time: now.Add(time.Minute), User: "bob", Action: "upgrade", Release: "foo", Namespace: "default", Revision: 2, Result: ResultFailure, Error: "timed out"},
+	}
+}
+
+func TestValuesDigest(t *testing.T) {
+	a := ValuesDigest(map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": "d"}})
+	b := ValuesDigest(map[string]interface{}{"b": map[string]interface{}{"c": "d"}, "a": 1})
+	assert.Equal(t, a, b)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", a)
+	assert.NotEqual(t, a, ValuesDigest(map[string]interface{}{"a": 2}))
+	assert.Equal(t, ValuesDigest(nil), ValuesDigest(map[string]interface{}{}))
+}
+
+func TestOpen(t *testing.T) {
+	clientset := func() (kubernetes.Interface, error) { return fake.NewSimpleClientset(), nil }
+
+	s, err := Open("file:/tmp/audit.log", "default", clientset)
+	require.NoError(t, err)
+	assert.IsType(t, &File{}, s)
+	s, err = Open("secret", "default", clientset)
+	require.NoError(t, err)
+	assert.True(t, s.(*KubeObjects).secrets)
+
+	for _, spec := range []string{"file:", "s3", ""} {
+		_, err := Open(spec, "default", clientset)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestFile(t *testing.T) {
+	f := NewFile(filepath.Join(t.TempDir(), "audit", "audit.log"))
+
+	entries, err := f.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	for _, e := range testEntries() {
+		require.NoError(t, f.Record(e))
+	}
+	entries, err = f.List()
+	require.NoError(t, err)
+	assert.Equal(t, testEntries(), entries)
+}
+
+func TestKubeObjects(t *testing.T) {
+	for _, secrets := range []bool{false, true} {
+		client := fake.NewSimpleClientset()
+		clientset := func() (kubernetes.Interface, error) { return client, nil }
+		k := NewConfigMaps("audit", clientset)
+		if secrets {
+			k = NewSecrets("audit", clientset)
+		}
+
+		// Record in reverse to check that entries are listed by time.
+		for i := len(testEntries()) - 1; i >= 0; i-- {
+			require.NoError(t, k.Record(testEntries()[i]))
+		}
+		entries, err := k.List()
+		require.NoError(t, err)
+		assert.Equal(t, testEntries(), entries)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &SQL{db: sqlx.NewDb(db, "sqlmock")}
+
+	e := testEntries()[0]
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log_v1 (time, release, namespace, action, result, body) VALUES ($1, $2, $3, $4, $5, $6)")).
+		WithArgs(e.Time, e.Release, e.Namespace, e.Action, e.Result, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	require.NoError(t, s.Record(e))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT body FROM audit_log_v1 ORDER BY id")).
+		WillReturnRows(sqlmock.NewRows([]string{"body"}).
+			AddRow(`{"time":"2024-05-01T12:00:00Z","action":"install","release":"foo","result":"success"}`))
+	entries, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "install", entries[0].Action)
+	assert.True(t, entries[0].Time.Equal(e.Time))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
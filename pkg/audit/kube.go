@@ -0,0 +1,150 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kblabels "k8s.io/apimachinery/pkg/labels"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+)
+
+var _ Sink = (*KubeObjects)(nil)
+
+const (
+	// auditLabel marks the ConfigMaps and Secrets holding audit log entries.
+	auditLabel = "helm.sh/audit"
+	// entryKey is the data key holding the entry.
+	entryKey = "entry"
+)
+
+// KubeObjects is a Sink creating a ConfigMap or Secret for every entry.
+// Entries are never updated, and the RBAC rules of the namespace decide who
+// can record and read them.
+type KubeObjects struct {
+	secrets   bool
+	namespace string
+
+	clientset func() (kubernetes.Interface, error)
+	once      sync.Once
+	client    kubernetes.Interface
+	clientErr error
+}
+
+// NewConfigMaps returns a Sink recording entries as ConfigMaps in namespace.
+func NewConfigMaps(namespace string, clientset func() (kubernetes.Interface, error)) *KubeObjects {
+	return &KubeObjects{namespace: namespace, clientset: clientset}
+}
+
+// NewSecrets returns a Sink recording entries as Secrets in namespace.
+func NewSecrets(namespace string, clientset func() (kubernetes.Interface, error)) *KubeObjects {
+	return &KubeObjects{secrets: true, namespace: namespace, clientset: clientset}
+}
+
+func (k *KubeObjects) getClient() (kubernetes.Interface, error) {
+	k.once.Do(func() {
+		k.client, k.clientErr = k.clientset()
+	})
+	return k.client, k.clientErr
+}
+
+// Record creates an object holding the entry.
+func (k *KubeObjects) Record(e Entry) error {
+	client, err := k.getClient()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	meta := metav1.ObjectMeta{
+		// The time prefix keeps the objects in order when listed by name.
+		Name: fmt.Sprintf("helm-audit.%d.%s", e.Time.UnixNano(), utilrand.String(5)),
+		Labels: map[string]string{
+			"owner":    "helm",
+			auditLabel: "true",
+			"name":     e.Release,
+		},
+	}
+	ctx := context.Background()
+	if k.secrets {
+		_, err = client.CoreV1().Secrets(k.namespace).Create(ctx, &v1.Secret{
+			ObjectMeta: meta,
+			Type:       "helm.sh/audit.v1",
+			Data:       map[string][]byte{entryKey: data},
+		}, metav1.CreateOptions{})
+	} else {
+		_, err = client.CoreV1().ConfigMaps(k.namespace).Create(ctx, &v1.ConfigMap{
+			ObjectMeta: meta,
+			Data:       map[string]string{entryKey: string(data)},
+		}, metav1.CreateOptions{})
+	}
+	return errors.Wrap(err, "unable to record audit log entry")
+}
+
+// List returns the entries held by the objects of the namespace.
+func (k *KubeObjects) List() ([]Entry, error) {
+	client, err := k.getClient()
+	if err != nil {
+		return nil, err
+	}
+	opts := metav1.ListOptions{
+		LabelSelector: kblabels.Set{"owner": "helm", auditLabel: "true"}.AsSelector().String(),
+	}
+
+	var raw [][]byte
+	ctx := context.Background()
+	if k.secrets {
+		list, err := client.CoreV1().Secrets(k.namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			raw = append(raw, item.Data[entryKey])
+		}
+	} else {
+		list, err := client.CoreV1().ConfigMaps(k.namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			raw = append(raw, []byte(item.Data[entryKey]))
+		}
+	}
+
+	entries := make([]Entry, 0, len(raw))
+	for _, data := range raw {
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, errors.Wrap(err, "invalid audit log entry")
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
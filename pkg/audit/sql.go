@@ -0,0 +1,123 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	migrate "github.com/rubenv/sql-migrate"
+
+	// Import pq for postgres dialect
+	_ "github.com/lib/pq"
+
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+var _ Sink = (*SQL)(nil)
+
+const (
+	postgreSQLDialect = "postgres"
+
+	sqlAuditTableName = "audit_log_v1"
+	// sqlAuditMigrationsTableName keeps the migrations of the audit log apart
+	// from those of the SQL storage driver sharing the database.
+	sqlAuditMigrationsTableName = "helm_audit_migrations"
+)
+
+// SQL is a Sink appending entries to a table of a PostgreSQL database.
+type SQL struct {
+	db *sqlx.DB
+}
+
+// NewSQL connects to the database and creates the audit log table if needed.
+// If password is not nil, it provides the password of every connection.
+func NewSQL(connectionString string, password driver.SQLPasswordFunc) (*SQL, error) {
+	db, err := driver.ConnectSQL(connectionString, password)
+	if err != nil {
+		return nil, err
+	}
+	s := &SQL{db: db}
+	if err := s.ensureDBSetup(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQL) ensureDBSetup() error {
+	migrations := &migrate.MemoryMigrationSource{
+		Migrations: []*migrate.Migration{
+			{
+				Id: "init",
+				Up: []string{
+					fmt.Sprintf(`
+						CREATE TABLE %s (
+							id SERIAL PRIMARY KEY,
+							time TIMESTAMPTZ NOT NULL,
+							release VARCHAR(64) NOT NULL,
+							namespace VARCHAR(64) NOT NULL,
+							action VARCHAR(16) NOT NULL,
+							result VARCHAR(16) NOT NULL,
+							body TEXT NOT NULL
+						);
+						CREATE INDEX ON %s (namespace, release);
+
+						GRANT SELECT, INSERT ON %s TO PUBLIC;
+						GRANT USAGE ON SEQUENCE %s_id_seq TO PUBLIC;
+					`, sqlAuditTableName, sqlAuditTableName, sqlAuditTableName, sqlAuditTableName),
+				},
+				Down: []string{
+					fmt.Sprintf(`DROP TABLE %s;`, sqlAuditTableName),
+				},
+			},
+		},
+	}
+	set := migrate.MigrationSet{TableName: sqlAuditMigrationsTableName}
+	_, err := set.Exec(s.db.DB, postgreSQLDialect, migrations, migrate.Up)
+	return err
+}
+
+// Record inserts an entry into the audit log table.
+func (s *SQL) Record(e Entry) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		fmt.Sprintf("INSERT INTO %s (time, release, namespace, action, result, body) VALUES ($1, $2, $3, $4, $5, $6)", sqlAuditTableName),
+		e.Time, e.Release, e.Namespace, e.Action, e.Result, string(body),
+	)
+	return err
+}
+
+// List returns the entries of the audit log table.
+func (s *SQL) List() ([]Entry, error) {
+	var bodies []string
+	if err := s.db.Select(&bodies, fmt.Sprintf("SELECT body FROM %s ORDER BY id", sqlAuditTableName)); err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(bodies))
+	for _, body := range bodies {
+		var e Entry
+		if err := json.Unmarshal([]byte(body), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -48,6 +48,9 @@ type Chart struct {
 	Values map[string]interface{} `json:"values"`
 	// Schema is an optional JSON schema for imposing structure on Values
 	Schema []byte `json:"schema"`
+	// ValuesMigrations map the values keys the chart renamed, moved or
+	// removed onto its current values.
+	ValuesMigrations []*ValuesMigration `json:"valuesMigrations,omitempty"`
 	// Files are miscellaneous files in a chart archive,
 	// e.g. README, LICENSE, etc.
 	Files []*File `json:"files"`
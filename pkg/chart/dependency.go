@@ -36,14 +36,19 @@ type Dependency struct {
 	// Appending `index.yaml` to this string should result in a URL that can be
 	// used to fetch the repository index.
 	Repository string `json:"repository"`
-	// A yaml path that resolves to a boolean, used for enabling/disabling charts (e.g. subchart1.enabled )
+	// A yaml path that resolves to a boolean, used for enabling/disabling charts (e.g. subchart1.enabled ),
+	// or an expression of values and capabilities (e.g. subchart1.enabled && .Capabilities.KubeVersion >= "1.25").
+	// Alternatives are separated by commas, the first that resolves to a boolean is used. An expression that
+	// does not parse is an error.
 	Condition string `json:"condition,omitempty"`
 	// Tags can be used to group charts for enabling/disabling together
 	Tags []string `json:"tags,omitempty"`
 	// Enabled bool determines if chart should be loaded
 	Enabled bool `json:"enabled,omitempty"`
 	// ImportValues holds the mapping of source values to parent key to be imported. Each item can be a
-	// string or pair of child/parent sublist items.
+	// string or pair of child/parent sublist items. The child of a pair may be a table, a list or a
+	// single value, and the pair may set a default used when the child does not set the value. A
+	// parent ending with "[]" appends the value, or the items of a list, to the list of the parent.
 	ImportValues []interface{} `json:"import-values,omitempty"`
 	// Alias usable alias to be used for the chart
 	Alias string `json:"alias,omitempty"`
@@ -0,0 +1,97 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// AnnotationEndOfLife is the annotation of a chart giving the date, as
+	// YYYY-MM-DD, after which the chart is no longer maintained.
+	AnnotationEndOfLife = "helm.sh/end-of-life"
+	// AnnotationReplacement is the annotation of a chart naming the chart
+	// that replaces it, such as "repo/chart".
+	AnnotationReplacement = "helm.sh/replacement"
+)
+
+// EndOfLifeLayout is the layout of the end-of-life date of a chart.
+const EndOfLifeLayout = "2006-01-02"
+
+// Deprecation describes the deprecation of a chart.
+type Deprecation struct {
+	// Deprecated is whether the chart is marked as deprecated.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// EndOfLife is the date after which the chart is no longer maintained.
+	EndOfLife string `json:"end_of_life,omitempty"`
+	// Replacement is the chart replacing this chart.
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Deprecation returns the deprecation of a chart, or nil if the chart is
+// neither deprecated nor has an end-of-life date.
+func (md *Metadata) Deprecation() *Deprecation {
+	if md == nil {
+		return nil
+	}
+	d := &Deprecation{
+		Deprecated:  md.Deprecated,
+		EndOfLife:   strings.TrimSpace(md.Annotations[AnnotationEndOfLife]),
+		Replacement: strings.TrimSpace(md.Annotations[AnnotationReplacement]),
+	}
+	if !d.Deprecated && d.EndOfLife == "" {
+		return nil
+	}
+	return d
+}
+
+// EndOfLifeReached reports whether the end-of-life date of a chart is past.
+// An end-of-life date that cannot be parsed is never reached.
+func (d *Deprecation) EndOfLifeReached(now time.Time) bool {
+	if d == nil || d.EndOfLife == "" {
+		return false
+	}
+	eol, err := time.Parse(EndOfLifeLayout, d.EndOfLife)
+	if err != nil {
+		return false
+	}
+	return !now.Before(eol)
+}
+
+// Message describes the deprecation of a chart as of the given time.
+func (d *Deprecation) Message(now time.Time) string {
+	if d == nil {
+		return ""
+	}
+	var parts []string
+	switch {
+	case d.EndOfLifeReached(now):
+		parts = append(parts, fmt.Sprintf("This chart reached its end of life on %s", d.EndOfLife))
+	case d.Deprecated && d.EndOfLife != "":
+		parts = append(parts, fmt.Sprintf("This chart is deprecated and reaches its end of life on %s", d.EndOfLife))
+	case d.Deprecated:
+		parts = append(parts, "This chart is deprecated")
+	default:
+		parts = append(parts, fmt.Sprintf("This chart reaches its end of life on %s", d.EndOfLife))
+	}
+	if d.Replacement != "" {
+		parts = append(parts, fmt.Sprintf("use %s instead", d.Replacement))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeprecation(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		md       *Metadata
+		reached  bool
+		expected string
+	}{
+		{
+			name: "not deprecated",
+			md:   &Metadata{},
+		},
+		{
+			name:     "deprecated",
+			md:       &Metadata{Deprecated: true},
+			expected: "This chart is deprecated",
+		},
+		{
+			name: "deprecated with end of life and replacement",
+			md: &Metadata{Deprecated: true, Annotations: map[string]string{
+				AnnotationEndOfLife:   "2025-12-31",
+				AnnotationReplacement: "example/web",
+			}},
+			expected: "This chart is deprecated and reaches its end of life on 2025-12-31, use example/web instead",
+		},
+		{
+			name:     "end of life",
+			md:       &Metadata{Annotations: map[string]string{AnnotationEndOfLife: "2025-12-31"}},
+			expected: "This chart reaches its end of life on 2025-12-31",
+		},
+		{
+			name:     "end of life reached",
+			md:       &Metadata{Annotations: map[string]string{AnnotationEndOfLife: "2025-06-01"}},
+			reached:  true,
+			expected: "This chart reached its end of life on 2025-06-01",
+		},
+		{
+			name:     "invalid end of life",
+			md:       &Metadata{Annotations: map[string]string{AnnotationEndOfLife: "June 2025"}},
+			expected: "This chart reaches its end of life on June 2025",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.md.Deprecation()
+			if tt.expected == "" {
+				if d != nil {
+					t.Fatalf("expected no deprecation, got %+v", d)
+				}
+				return
+			}
+			if d.EndOfLifeReached(now) != tt.reached {
+				t.Errorf("expected end of life reached to be %t", tt.reached)
+			}
+			if msg := d.Message(now); msg != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, msg)
+			}
+		})
+	}
+}
@@ -20,7 +20,11 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
@@ -107,26 +111,84 @@ func isGZipApplication(data []byte) bool {
 	return bytes.HasPrefix(data, sig)
 }
 
+// MaxDecompressedChartSize is the maximum size of all files of a chart, as
+// decompressed from an archive or read from a directory. The default is 100 MiB.
+var MaxDecompressedChartSize int64 = 100 * 1024 * 1024
+
+// MaxDecompressedFileSize is the maximum size of a single file of a chart, as
+// decompressed from an archive or read from a directory. The default is 5 MiB.
+var MaxDecompressedFileSize int64 = 5 * 1024 * 1024
+
 // LoadArchiveFiles reads in files out of an archive into memory. This function
 // performs important path security checks and should always be used before
 // expanding a tarball
 func LoadArchiveFiles(in io.Reader) ([]*BufferedFile, error) {
-	unzipped, err := gzip.NewReader(in)
+	files := []*BufferedFile{}
+	err := walkArchive(in, func(name string, size int64, r io.Reader) error {
+		data, err := readArchiveFile(r, size)
+		if err != nil {
+			return err
+		}
+		files = append(files, &BufferedFile{Name: name, Data: data})
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no files in chart archive")
+	}
+	return files, nil
+}
+
+// walkArchive calls fn for each file of a chart archive, in the order of the
+// archive, after checking its path and size. The name passed to fn is relative
+// to the chart directory and uses / as separator. fn may read the content of
+// the file from r.
+func walkArchive(in io.Reader, fn func(name string, size int64, r io.Reader) error) error {
+	unzipped, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
 	defer unzipped.Close()
 
-	files := []*BufferedFile{}
-	tr := tar.NewReader(unzipped)
+	ar := newArchiveReader(unzipped)
 	for {
-		b := bytes.NewBuffer(nil)
-		hd, err := tr.Next()
+		name, size, err := ar.next()
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(name, size, ar.tr); err != nil {
+			return err
+		}
+	}
+}
+
+// archiveReader iterates over the files of a decompressed chart archive.
+type archiveReader struct {
+	tr        *tar.Reader
+	remaining int64
+}
+
+func newArchiveReader(r io.Reader) *archiveReader {
+	return &archiveReader{
+		tr:        tar.NewReader(r),
+		remaining: MaxDecompressedChartSize,
+	}
+}
+
+// next advances to the next file of the archive, checking its path and size.
+// The content of the file can then be read from the tar reader. It returns
+// io.EOF at the end of the archive.
+func (a *archiveReader) next() (string, int64, error) {
+	for {
+		hd, err := a.tr.Next()
+		if err != nil {
+			return "", 0, err
 		}
 
 		if hd.FileInfo().IsDir() {
@@ -139,59 +201,78 @@ func LoadArchiveFiles(in io.Reader) ([]*BufferedFile, error) {
 		// We don't want to process these extension header files.
 		case tar.TypeXGlobalHeader, tar.TypeXHeader:
 			continue
+		// Links would be loaded as empty files, or could point anywhere once
+		// the chart is expanded.
+		case tar.TypeSymlink, tar.TypeLink:
+			return "", 0, errors.Errorf("chart illegally contains a link: %q", hd.Name)
 		}
 
-		// Archive could contain \ if generated on Windows
-		delimiter := "/"
-		if strings.ContainsRune(hd.Name, '\\') {
-			delimiter = "\\"
-		}
-
-		parts := strings.Split(hd.Name, delimiter)
-		n := strings.Join(parts[1:], delimiter)
-
-		// Normalize the path to the / delimiter
-		n = strings.ReplaceAll(n, delimiter, "/")
-
-		if path.IsAbs(n) {
-			return nil, errors.New("chart illegally contains absolute paths")
+		n, err := archiveFileName(hd.Name)
+		if err != nil {
+			return "", 0, err
 		}
 
-		n = path.Clean(n)
-		if n == "." {
-			// In this case, the original path was relative when it should have been absolute.
-			return nil, errors.Errorf("chart illegally contains content outside the base directory: %q", hd.Name)
+		if hd.Size > MaxDecompressedFileSize {
+			return "", 0, errors.Errorf("decompressed chart file %q is larger than the maximum file size %d", n, MaxDecompressedFileSize)
 		}
-		if strings.HasPrefix(n, "..") {
-			return nil, errors.New("chart illegally references parent directory")
+		a.remaining -= hd.Size
+		if a.remaining < 0 {
+			return "", 0, errors.Errorf("decompressed chart is larger than the maximum size %d", MaxDecompressedChartSize)
 		}
+		return n, hd.Size, nil
+	}
+}
 
-		// In some particularly arcane acts of path creativity, it is possible to intermix
-		// UNIX and Windows style paths in such a way that you produce a result of the form
-		// c:/foo even after all the built-in absolute path checks. So we explicitly check
-		// for this condition.
-		if drivePathPattern.MatchString(n) {
-			return nil, errors.New("chart contains illegally named files")
-		}
+// archiveFileName checks the path of a file in a chart archive and returns it
+// relative to the chart directory.
+func archiveFileName(name string) (string, error) {
+	// Archive could contain \ if generated on Windows
+	delimiter := "/"
+	if strings.ContainsRune(name, '\\') {
+		delimiter = "\\"
+	}
 
-		if parts[0] == "Chart.yaml" {
-			return nil, errors.New("chart yaml not in base directory")
-		}
+	parts := strings.Split(name, delimiter)
+	n := strings.Join(parts[1:], delimiter)
 
-		if _, err := io.Copy(b, tr); err != nil {
-			return nil, err
-		}
+	// Normalize the path to the / delimiter
+	n = strings.ReplaceAll(n, delimiter, "/")
+
+	if path.IsAbs(n) {
+		return "", errors.New("chart illegally contains absolute paths")
+	}
 
-		data := bytes.TrimPrefix(b.Bytes(), utf8bom)
+	n = path.Clean(n)
+	if n == "." {
+		// In this case, the original path was relative when it should have been absolute.
+		return "", errors.Errorf("chart illegally contains content outside the base directory: %q", name)
+	}
+	if strings.HasPrefix(n, "..") {
+		return "", errors.New("chart illegally references parent directory")
+	}
 
-		files = append(files, &BufferedFile{Name: n, Data: data})
-		b.Reset()
+	// In some particularly arcane acts of path creativity, it is possible to intermix
+	// UNIX and Windows style paths in such a way that you produce a result of the form
+	// c:/foo even after all the built-in absolute path checks. So we explicitly check
+	// for this condition.
+	if drivePathPattern.MatchString(n) {
+		return "", errors.New("chart contains illegally named files")
 	}
 
-	if len(files) == 0 {
-		return nil, errors.New("no files in chart archive")
+	if parts[0] == "Chart.yaml" {
+		return "", errors.New("chart yaml not in base directory")
 	}
-	return files, nil
+	return n, nil
+}
+
+// readArchiveFile reads a file of the given size from an archive, dropping a
+// leading UTF-8 byte order mark.
+func readArchiveFile(r io.Reader, size int64) ([]byte, error) {
+	b := bytes.NewBuffer(make([]byte, 0, size))
+	if _, err := io.Copy(b, io.LimitReader(r, size)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(b.Bytes(), utf8bom), nil
 }
 
 // LoadArchive loads from a reader containing a compressed tar archive.
@@ -203,3 +284,63 @@ func LoadArchive(in io.Reader) (*chart.Chart, error) {
 
 	return LoadFiles(files)
 }
+
+// LoadArchiveDigest loads from a reader containing a compressed tar archive,
+// failing unless the archive matches digest. The digest has the form
+// "algorithm:hex", where the algorithm is sha256 or sha512.
+//
+// The stream is untrusted until its digest is verified, so it is first copied
+// to a temporary file, up to MaxDecompressedChartSize bytes, and only
+// decompressed and parsed once the digest matches.
+func LoadArchiveDigest(in io.Reader, digest string) (*chart.Chart, error) {
+	alg, h, want, err := parseDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "helm-chart-*.tgz")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(in, MaxDecompressedChartSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the chart archive")
+	}
+	if n > MaxDecompressedChartSize {
+		return nil, errors.Errorf("chart archive is larger than the maximum size %d", MaxDecompressedChartSize)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return nil, errors.Errorf("chart archive digest mismatch: expected %s, got %s:%s", digest, alg, got)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return LoadArchive(tmp)
+}
+
+// parseDigest returns the algorithm of digest, a hash for it and the
+// lowercased hex encoded value.
+func parseDigest(digest string) (string, hash.Hash, string, error) {
+	alg, value, ok := strings.Cut(digest, ":")
+	if !ok {
+		return "", nil, "", errors.Errorf("invalid digest %q: expected the form algorithm:hex, e.g. sha256:...", digest)
+	}
+	var h hash.Hash
+	switch alg {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", nil, "", errors.Errorf("invalid digest %q: unsupported algorithm %q", digest, alg)
+	}
+	value = strings.ToLower(value)
+	if b, err := hex.DecodeString(value); err != nil || len(b) != h.Size() {
+		return "", nil, "", errors.Errorf("invalid digest %q: expected %d hex encoded bytes", digest, h.Size())
+	}
+	return alg, h, value, nil
+}
@@ -20,6 +20,10 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -88,3 +92,53 @@ func TestLoadArchiveFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadArchiveDigest(t *testing.T) {
+	data, err := os.ReadFile("testdata/frobnitz-1.2.3.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+
+	c, err := LoadArchiveDigest(bytes.NewReader(data), digest)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.Name() != "frobnitz" {
+		t.Errorf("expected chart frobnitz, got %q", c.Name())
+	}
+
+	// The digest is compared case insensitively.
+	if _, err := LoadArchiveDigest(bytes.NewReader(data), "sha256:"+strings.ToUpper(digest[len("sha256:"):])); err != nil {
+		t.Errorf("unexpected error for an upper case digest: %s", err)
+	}
+
+	tcs := []struct {
+		digest string
+		errMsg string
+	}{
+		{fmt.Sprintf("sha256:%x", sha256.Sum256(nil)), "chart archive digest mismatch"},
+		{"abcdef", "expected the form algorithm:hex"},
+		{"md5:d41d8cd98f00b204e9800998ecf8427e", `unsupported algorithm "md5"`},
+		{"sha256:abc", "expected 32 hex encoded bytes"},
+	}
+	for _, tc := range tcs {
+		_, err := LoadArchiveDigest(bytes.NewReader(data), tc.digest)
+		if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+			t.Errorf("digest %q: expected error containing %q, got %v", tc.digest, tc.errMsg, err)
+		}
+	}
+
+	// Streams are not decompressed before their digest is verified.
+	garbage := []byte("not a chart archive")
+	if _, err := LoadArchiveDigest(bytes.NewReader(garbage), digest); err == nil || !strings.Contains(err.Error(), "chart archive digest mismatch") {
+		t.Errorf("expected a digest mismatch for a stream that is not an archive, got %v", err)
+	}
+
+	// Streams larger than the maximum size are not read to their end.
+	defer func(size int64) { MaxDecompressedChartSize = size }(MaxDecompressedChartSize)
+	MaxDecompressedChartSize = int64(len(data) - 1)
+	if _, err := LoadArchiveDigest(bytes.NewReader(data), digest); err == nil || !strings.Contains(err.Error(), "larger than the maximum size") {
+		t.Errorf("expected an error for a stream larger than the maximum size, got %v", err)
+	}
+}
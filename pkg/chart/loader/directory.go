@@ -44,27 +44,51 @@ func (l DirLoader) Load() (*chart.Chart, error) {
 //
 // This loads charts only from directories.
 func LoadDir(dir string) (*chart.Chart, error) {
-	topdir, err := filepath.Abs(dir)
+	// Just used for errors.
+	c := &chart.Chart{}
+
+	files := []*BufferedFile{}
+	err := walkDir(dir, func(n, name string, _ os.FileInfo) error {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return errors.Wrapf(err, "error reading %s", n)
+		}
+
+		data = bytes.TrimPrefix(data, utf8bom)
+
+		files = append(files, &BufferedFile{Name: n, Data: data})
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return c, err
 	}
 
-	// Just used for errors.
-	c := &chart.Chart{}
+	return LoadFiles(files)
+}
+
+// walkDir calls fn for each file of a chart directory that is not ignored by
+// its .helmignore file, after checking its type and size. n is the name of the
+// file relative to the chart directory using / as separator, and name its path
+// on disk.
+func walkDir(dir string, fn func(n, name string, fi os.FileInfo) error) error {
+	topdir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
 
 	rules := ignore.Empty()
 	ifile := filepath.Join(topdir, ignore.HelmIgnore)
 	if _, err := os.Stat(ifile); err == nil {
 		r, err := ignore.ParseFile(ifile)
 		if err != nil {
-			return c, err
+			return err
 		}
 		rules = r
 	}
 	rules.AddDefaults()
 
-	files := []*BufferedFile{}
 	topdir += string(filepath.Separator)
+	remaining := MaxDecompressedChartSize
 
 	walk := func(name string, fi os.FileInfo, err error) error {
 		n := strings.TrimPrefix(name, topdir)
@@ -101,19 +125,15 @@ func LoadDir(dir string) (*chart.Chart, error) {
 			return fmt.Errorf("cannot load irregular file %s as it has file mode type bits set", name)
 		}
 
-		data, err := os.ReadFile(name)
-		if err != nil {
-			return errors.Wrapf(err, "error reading %s", n)
+		if fi.Size() > MaxDecompressedFileSize {
+			return errors.Errorf("chart file %q is larger than the maximum file size %d", n, MaxDecompressedFileSize)
+		}
+		remaining -= fi.Size()
+		if remaining < 0 {
+			return errors.Errorf("chart is larger than the maximum size %d", MaxDecompressedChartSize)
 		}
 
-		data = bytes.TrimPrefix(data, utf8bom)
-
-		files = append(files, &BufferedFile{Name: n, Data: data})
-		return nil
+		return fn(n, name, fi)
 	}
-	if err = sympath.Walk(topdir, walk); err != nil {
-		return c, err
-	}
-
-	return LoadFiles(files)
+	return sympath.Walk(topdir, walk)
 }
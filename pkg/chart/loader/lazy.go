@@ -0,0 +1,242 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// lazyEagerFiles are the files of a LazyChart that are loaded when it is
+// opened. They hold the metadata, values and dependencies of the chart.
+var lazyEagerFiles = map[string]bool{
+	"Chart.yaml":             true,
+	"Chart.lock":             true,
+	"values.yaml":            true,
+	"values.schema.json":     true,
+	"values-migrations.yaml": true,
+	"requirements.yaml":      true,
+	"requirements.lock":      true,
+}
+
+// LazyChart is a chart of which only the metadata, values, schema and lock
+// files are loaded. The content of templates and other files is read on
+// demand, which makes it cheaper than Load for large charts when only a few
+// files are needed, e.g. to show the metadata of a chart.
+type LazyChart struct {
+	// Metadata is the contents of the Chart.yaml file.
+	Metadata *chart.Metadata
+	// Lock is the contents of the Chart.lock file.
+	Lock *chart.Lock
+	// Values are the default configuration of the chart.
+	Values map[string]interface{}
+	// Schema is an optional JSON schema for imposing structure on Values.
+	Schema []byte
+	// ValuesMigrations map renamed, moved or removed values keys onto the
+	// current values.
+	ValuesMigrations []*chart.ValuesMigration
+
+	path    string
+	archive bool
+	names   []string
+	eager   map[string][]byte
+	sizes   map[string]int64
+	paths   map[string]string
+}
+
+// Open opens the chart archive or directory at the given path, loading its
+// metadata and values.
+//
+// The same path, size and .helmignore rules apply as for Load. The content of
+// an archive is decompressed once to list its files, but only the eagerly
+// loaded files are kept in memory.
+func Open(name string) (*LazyChart, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &LazyChart{
+		path:    name,
+		archive: !fi.IsDir(),
+		eager:   make(map[string][]byte),
+		sizes:   make(map[string]int64),
+		paths:   make(map[string]string),
+	}
+	var files []*BufferedFile
+	if c.archive {
+		raw, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer raw.Close()
+		if err := ensureArchive(name, raw); err != nil {
+			return nil, err
+		}
+
+		err = walkArchive(raw, func(n string, size int64, r io.Reader) error {
+			c.add(n, size)
+			if !lazyEagerFiles[n] {
+				return nil
+			}
+			data, err := readArchiveFile(r, size)
+			if err != nil {
+				return err
+			}
+			files = append(files, &BufferedFile{Name: n, Data: data})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = walkDir(name, func(n, path string, fi os.FileInfo) error {
+			c.add(n, fi.Size())
+			c.paths[n] = path
+			if !lazyEagerFiles[n] {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "error reading %s", n)
+			}
+			files = append(files, &BufferedFile{Name: n, Data: bytes.TrimPrefix(data, utf8bom)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	partial, err := LoadFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		c.eager[f.Name] = f.Data
+	}
+	c.Metadata = partial.Metadata
+	c.Lock = partial.Lock
+	c.Values = partial.Values
+	c.Schema = partial.Schema
+	c.ValuesMigrations = partial.ValuesMigrations
+	return c, nil
+}
+
+func (c *LazyChart) add(name string, size int64) {
+	c.names = append(c.names, name)
+	c.sizes[name] = size
+}
+
+// Name returns the name of the chart.
+func (c *LazyChart) Name() string {
+	if c.Metadata == nil {
+		return ""
+	}
+	return c.Metadata.Name
+}
+
+// Files returns the names of all files of the chart, including those of its
+// subcharts, relative to the chart directory.
+func (c *LazyChart) Files() []string {
+	return append([]string(nil), c.names...)
+}
+
+// Size returns the size of the named file.
+func (c *LazyChart) Size(name string) (int64, bool) {
+	size, ok := c.sizes[name]
+	return size, ok
+}
+
+// Open streams the content of the named file. The caller must close it.
+func (c *LazyChart) Open(name string) (io.ReadCloser, error) {
+	if _, ok := c.sizes[name]; !ok {
+		return nil, errors.Errorf("chart %s has no file %s", c.Name(), name)
+	}
+	if data, ok := c.eager[name]; ok {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	if !c.archive {
+		return os.Open(c.paths[name])
+	}
+
+	raw, err := os.Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+	unzipped, err := gzip.NewReader(raw)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	ar := newArchiveReader(unzipped)
+	for {
+		n, size, err := ar.next()
+		if err == io.EOF {
+			err = errors.Errorf("chart %s has no file %s", c.Name(), name)
+		}
+		if err != nil {
+			unzipped.Close()
+			raw.Close()
+			return nil, err
+		}
+		if n == name {
+			return &archiveFile{Reader: io.LimitReader(ar.tr, size), unzipped: unzipped, raw: raw}, nil
+		}
+	}
+}
+
+// ReadFile reads the content of the named file, dropping a leading UTF-8 byte
+// order mark like Load does.
+func (c *LazyChart) ReadFile(name string) ([]byte, error) {
+	if data, ok := c.eager[name]; ok {
+		return data, nil
+	}
+	r, err := c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", name)
+	}
+	return bytes.TrimPrefix(data, utf8bom), nil
+}
+
+// Load loads the whole chart.
+func (c *LazyChart) Load() (*chart.Chart, error) {
+	return Load(c.path)
+}
+
+// archiveFile is a file streamed from a chart archive.
+type archiveFile struct {
+	io.Reader
+	unzipped *gzip.Reader
+	raw      *os.File
+}
+
+func (f *archiveFile) Close() error {
+	f.unzipped.Close()
+	return f.raw.Close()
+}
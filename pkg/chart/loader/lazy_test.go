@@ -0,0 +1,84 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	for _, name := range []string{"testdata/frobnitz", "testdata/frobnitz-1.2.3.tgz"} {
+		t.Run(name, func(t *testing.T) {
+			lc, err := Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lc.Name() != "frobnitz" {
+				t.Errorf("expected chart name frobnitz, got %q", lc.Name())
+			}
+			if lc.Lock == nil || len(lc.Lock.Dependencies) == 0 {
+				t.Error("expected Chart.lock to be loaded")
+			}
+			if len(lc.Values) == 0 {
+				t.Error("expected values to be loaded")
+			}
+			if len(lc.eager) != 3 {
+				t.Errorf("expected only Chart.yaml, Chart.lock and values.yaml to be loaded, got %d files", len(lc.eager))
+			}
+
+			c, err := Load(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := make(map[string]bool)
+			for _, f := range lc.Files() {
+				files[f] = true
+			}
+			for _, f := range []string{"Chart.yaml", "templates/template.tpl", "charts/alpine/Chart.yaml", "charts/mariner-4.3.2.tgz"} {
+				if !files[f] {
+					t.Errorf("expected file %s to be listed, got %v", f, lc.Files())
+				}
+			}
+
+			tpl := c.Templates[0]
+			data, err := lc.ReadFile(tpl.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tpl.Data) {
+				t.Errorf("expected %s to be %q, got %q", tpl.Name, tpl.Data, data)
+			}
+			if size, ok := lc.Size(tpl.Name); !ok || size != int64(len(tpl.Data)) {
+				t.Errorf("expected size of %s to be %d, got %d", tpl.Name, len(tpl.Data), size)
+			}
+
+			r, err := lc.Open("charts/mariner-4.3.2.tgz")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if _, err := LoadArchive(r); err != nil {
+				t.Errorf("expected streamed subchart archive to load, got %s", err)
+			}
+
+			if _, err := lc.ReadFile("templates/missing.yaml"); err == nil {
+				t.Error("expected error reading a missing file")
+			}
+		})
+	}
+}
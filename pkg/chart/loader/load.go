@@ -109,6 +109,17 @@ func LoadFiles(files []*BufferedFile) (*chart.Chart, error) {
 			}
 		case f.Name == "values.schema.json":
 			c.Schema = f.Data
+		case f.Name == "values-migrations.yaml":
+			migrations := new(chart.ValuesMigrations)
+			if err := yaml.Unmarshal(f.Data, migrations); err != nil {
+				return c, errors.Wrap(err, "cannot load values-migrations.yaml")
+			}
+			for _, m := range migrations.Migrations {
+				if err := m.Validate(); err != nil {
+					return c, errors.Wrap(err, "cannot load values-migrations.yaml")
+				}
+			}
+			c.ValuesMigrations = migrations.Migrations
 
 		// Deprecated: requirements.yaml is deprecated use Chart.yaml.
 		// We will handle it for you because we are nice people
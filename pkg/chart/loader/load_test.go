@@ -306,6 +306,38 @@ icon: https://example.com/64x64.png
 	}
 }
 
+func TestLoadFilesValuesMigrations(t *testing.T) {
+	chartfile := &BufferedFile{Name: "Chart.yaml", Data: []byte("apiVersion: v2\nname: frobnitz\nversion: 1.2.3\n")}
+
+	c, err := LoadFiles([]*BufferedFile{chartfile, {
+		Name: "values-migrations.yaml",
+		Data: []byte("migrations:\n- from: image.name\n  to: image.repository\n- from: debug\n  message: no longer used\n"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.ValuesMigrations) != 2 {
+		t.Fatalf("Expected 2 values migrations, got %d", len(c.ValuesMigrations))
+	}
+	if m := c.ValuesMigrations[0]; m.From != "image.name" || m.To != "image.repository" {
+		t.Errorf("Unexpected values migration %+v", m)
+	}
+	if len(c.Files) != 0 {
+		t.Errorf("Expected values-migrations.yaml not to be a chart file, got %d files", len(c.Files))
+	}
+
+	for data, expectError := range map[string]string{
+		"migrations:\n- to: image.repository\n":          "must have a from key",
+		"migrations:\n- from: image\n  to: image.name\n": "must not move it into itself",
+		"migrations:\n- from: image..name\n":             "is not a valid dotted path",
+	} {
+		_, err := LoadFiles([]*BufferedFile{chartfile, {Name: "values-migrations.yaml", Data: []byte(data)}})
+		if err == nil || !strings.Contains(err.Error(), expectError) {
+			t.Errorf("Expected error to contain %q, got %v", expectError, err)
+		}
+	}
+}
+
 // Test the order of file loading. The Chart.yaml file needs to come first for
 // later comparison checks. See https://github.com/helm/helm/pull/8948
 func TestLoadFilesOrder(t *testing.T) {
@@ -646,3 +678,45 @@ func verifyBomStripped(t *testing.T, files []*chart.File) {
 		}
 	}
 }
+
+func TestLoadSizeLimits(t *testing.T) {
+	defer func(chartSize, fileSize int64) {
+		MaxDecompressedChartSize, MaxDecompressedFileSize = chartSize, fileSize
+	}(MaxDecompressedChartSize, MaxDecompressedFileSize)
+
+	for _, name := range []string{"testdata/frobnitz", "testdata/frobnitz-1.2.3.tgz"} {
+		MaxDecompressedChartSize, MaxDecompressedFileSize = 100*1024*1024, 64
+		if _, err := Load(name); err == nil || !strings.Contains(err.Error(), "larger than the maximum file size 64") {
+			t.Errorf("expected file size error loading %s, got %v", name, err)
+		}
+
+		MaxDecompressedChartSize, MaxDecompressedFileSize = 1024, 5*1024*1024
+		if _, err := Load(name); err == nil || !strings.Contains(err.Error(), "larger than the maximum size 1024") {
+			t.Errorf("expected chart size error loading %s, got %v", name, err)
+		}
+		if _, err := Open(name); err == nil {
+			t.Errorf("expected chart size error opening %s", name)
+		}
+	}
+}
+
+func TestLoadArchiveWithLink(t *testing.T) {
+	var buf bytes.Buffer
+	zipper := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zipper)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     "chart/templates/secret.yaml",
+		Typeflag: tar.TypeSymlink,
+		Linkname: "/etc/passwd",
+		Mode:     0644,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	zipper.Close()
+
+	_, err := LoadArchive(&buf)
+	if err == nil || !strings.Contains(err.Error(), "chart illegally contains a link") {
+		t.Errorf("expected error loading an archive with a link, got %v", err)
+	}
+}
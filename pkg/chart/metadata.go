@@ -81,6 +81,66 @@ type Metadata struct {
 	Dependencies []*Dependency `json:"dependencies,omitempty"`
 	// Specifies the chart type: application or library
 	Type string `json:"type,omitempty"`
+	// Features lists the optional Helm features the chart opts into.
+	Features []string `json:"features,omitempty"`
+	// Notes controls how the release notes of the subcharts are shown.
+	Notes *Notes `json:"notes,omitempty"`
+	// Globals lists the global values the chart consumes. When set, the
+	// chart only receives these globals from its parents.
+	Globals []string `json:"globals,omitempty"`
+	// GlobalsPropagation scopes global values to subcharts: each global it
+	// lists is only passed to the subcharts, by name or alias, it maps to.
+	// Globals it does not list are passed to all subcharts.
+	GlobalsPropagation map[string][]string `json:"globalsPropagation,omitempty"`
+	// HookPermissions are the permissions the hooks of the chart need in the
+	// namespace of the release. Helm grants them to the ServiceAccount it
+	// creates for the hooks when asked to.
+	HookPermissions []*HookPermission `json:"hookPermissions,omitempty"`
+}
+
+// HookPermission allows the verbs on the resources of the API groups, as a
+// rule of a Kubernetes Role does.
+type HookPermission struct {
+	// APIGroups are the API groups of the resources, the core group if
+	// empty.
+	APIGroups []string `json:"apiGroups,omitempty"`
+	Resources []string `json:"resources"`
+	Verbs     []string `json:"verbs"`
+}
+
+// Notes controls how the NOTES.txt of the subcharts of a chart are shown
+// along with its own.
+type Notes struct {
+	// Subcharts shows the notes of the subcharts, as --render-subchart-notes
+	// does. Leave it unset when NOTES.txt composes them through
+	// .Subcharts.<name>.Notes.
+	Subcharts bool `json:"subcharts,omitempty"`
+	// Order lists the subcharts whose notes come first, in that order, by
+	// name or by path such as "database/metrics" for nested subcharts. The
+	// notes of the other subcharts follow by path.
+	Order []string `json:"order,omitempty"`
+	// Label precedes the notes of each subchart with a line naming it.
+	Label bool `json:"label,omitempty"`
+}
+
+// FeatureTemplateExtensions makes the extension template functions available
+// to the templates of a chart.
+const FeatureTemplateExtensions = "templateExtensions"
+
+// knownFeatures are the features a chart can opt into.
+var knownFeatures = []string{FeatureTemplateExtensions}
+
+// HasFeature reports whether the chart opts into the given feature.
+func (md *Metadata) HasFeature(feature string) bool {
+	if md == nil {
+		return false
+	}
+	for _, f := range md.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
 }
 
 // Validate checks the metadata for known issues and sanitizes string
@@ -126,12 +186,24 @@ func (md *Metadata) Validate() error {
 		return ValidationError("chart.metadata.type must be application or library")
 	}
 
+	for _, f := range md.Features {
+		if !isKnownFeature(f) {
+			return ValidationErrorf("chart.metadata.features: unknown feature %q", f)
+		}
+	}
+
 	for _, m := range md.Maintainers {
 		if err := m.Validate(); err != nil {
 			return err
 		}
 	}
 
+	for i, p := range md.HookPermissions {
+		if p == nil || len(p.Resources) == 0 || len(p.Verbs) == 0 {
+			return ValidationErrorf("chart.metadata.hookPermissions[%d] needs resources and verbs", i)
+		}
+	}
+
 	// Aliases need to be validated here to make sure that the alias name does
 	// not contain any illegal characters.
 	dependencies := map[string]*Dependency{}
@@ -148,6 +220,15 @@ func (md *Metadata) Validate() error {
 		}
 		dependencies[key] = dependency
 	}
+	if len(md.Dependencies) > 0 {
+		for global, subcharts := range md.GlobalsPropagation {
+			for _, name := range subcharts {
+				if dependencies[name] == nil {
+					return ValidationErrorf("chart.metadata.globalsPropagation: global %q is scoped to %q, which is not a dependency", global, name)
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -159,6 +240,15 @@ func isValidChartType(in string) bool {
 	return false
 }
 
+func isKnownFeature(f string) bool {
+	for _, k := range knownFeatures {
+		if f == k {
+			return true
+		}
+	}
+	return false
+}
+
 func isValidSemver(v string) bool {
 	_, err := semver.NewVersion(v)
 	return err == nil
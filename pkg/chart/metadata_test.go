@@ -55,6 +55,16 @@ func TestValidate(t *testing.T) {
 			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Type: "test"},
 			ValidationError("chart.metadata.type must be application or library"),
 		},
+		{
+			"chart with known feature",
+			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Features: []string{FeatureTemplateExtensions}},
+			nil,
+		},
+		{
+			"chart with unknown feature",
+			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Features: []string{"teleport"}},
+			ValidationError("chart.metadata.features: unknown feature \"teleport\""),
+		},
 		{
 			"chart without dependency",
 			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Type: "application"},
@@ -172,6 +182,31 @@ func TestValidate(t *testing.T) {
 			},
 			ValidationError("maintainers must not contain empty or null nodes"),
 		},
+		{
+			"globals scoped to unknown subchart",
+			&Metadata{
+				Name:       "test",
+				APIVersion: "v2",
+				Version:    "1.0",
+				Type:       "application",
+				Dependencies: []*Dependency{
+					{Name: "foo", Alias: "bar"},
+				},
+				GlobalsPropagation: map[string][]string{"image": {"foo"}},
+			},
+			ValidationError("chart.metadata.globalsPropagation: global \"image\" is scoped to \"foo\", which is not a dependency"),
+		},
+		{
+			"hook permission without verbs",
+			&Metadata{
+				Name:            "test",
+				APIVersion:      "v2",
+				Version:         "1.0",
+				Type:            "application",
+				HookPermissions: []*HookPermission{{Resources: []string{"configmaps"}}},
+			},
+			ValidationError("chart.metadata.hookPermissions[0] needs resources and verbs"),
+		},
 		{
 			"version invalid",
 			&Metadata{APIVersion: "v2", Name: "test", Version: "1.2.3.4"},
@@ -0,0 +1,62 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValuesMigrations is the contents of the values-migrations.yaml file of a
+// chart.
+type ValuesMigrations struct {
+	// Migrations are applied in order to the values supplied for the chart.
+	Migrations []*ValuesMigration `json:"migrations"`
+}
+
+// ValuesMigration describes a values key that was renamed, moved or removed.
+type ValuesMigration struct {
+	// From is the dotted path of the deprecated key, e.g. "image.name".
+	From string `json:"from"`
+	// To is the dotted path the value moved to, e.g. "image.repository". If
+	// empty, the key is only deprecated and its value is left in place.
+	To string `json:"to,omitempty"`
+	// Message is shown to users still setting the deprecated key.
+	Message string `json:"message,omitempty"`
+}
+
+// Validate checks that the migration is well-formed.
+func (m *ValuesMigration) Validate() error {
+	if m == nil {
+		return errors.New("values migrations must not be empty")
+	}
+	if m.From == "" {
+		return errors.New("values migrations must have a from key")
+	}
+	for _, p := range []string{m.From, m.To} {
+		if p != "" && (strings.HasPrefix(p, ".") || strings.HasSuffix(p, ".") || strings.Contains(p, "..")) {
+			return errors.Errorf("values migration key %q is not a valid dotted path", p)
+		}
+	}
+	if m.From == m.To {
+		return errors.Errorf("values migration of %q must not move it onto itself", m.From)
+	}
+	if m.To != "" && strings.HasPrefix(m.To+".", m.From+".") {
+		return errors.Errorf("values migration of %q must not move it into itself", m.From)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// CapabilitiesSnapshot is a snapshot of the capabilities of a Kubernetes
+// cluster. It allows charts to be rendered and validated offline as they would
+// be for the cluster.
+type CapabilitiesSnapshot struct {
+	// KubeVersion is the Kubernetes version of the cluster.
+	KubeVersion string `json:"kubeVersion"`
+	// APIVersions are the API versions served by the cluster.
+	APIVersions []string `json:"apiVersions"`
+	// CRDs are the custom resource definitions of the cluster.
+	CRDs []CRDSnapshot `json:"crds,omitempty"`
+}
+
+// CRDSnapshot describes a custom resource definition of a cluster.
+type CRDSnapshot struct {
+	Name     string               `json:"name"`
+	Group    string               `json:"group"`
+	Kind     string               `json:"kind"`
+	Versions []CRDVersionSnapshot `json:"versions"`
+}
+
+// CRDVersionSnapshot describes a version of a custom resource definition.
+type CRDVersionSnapshot struct {
+	Name   string                           `json:"name"`
+	Served bool                             `json:"served"`
+	Schema *apiextensionsv1.JSONSchemaProps `json:"openAPIV3Schema,omitempty"`
+}
+
+// NewCapabilitiesSnapshot creates a snapshot of the given capabilities and
+// custom resource definitions.
+func NewCapabilitiesSnapshot(caps *Capabilities, crds []apiextensionsv1.CustomResourceDefinition) *CapabilitiesSnapshot {
+	s := &CapabilitiesSnapshot{
+		KubeVersion: caps.KubeVersion.Version,
+		APIVersions: append([]string{}, caps.APIVersions...),
+	}
+	sort.Strings(s.APIVersions)
+	for _, crd := range crds {
+		c := CRDSnapshot{
+			Name:  crd.Name,
+			Group: crd.Spec.Group,
+			Kind:  crd.Spec.Names.Kind,
+		}
+		for _, v := range crd.Spec.Versions {
+			cv := CRDVersionSnapshot{Name: v.Name, Served: v.Served}
+			if v.Schema != nil {
+				cv.Schema = v.Schema.OpenAPIV3Schema
+			}
+			c.Versions = append(c.Versions, cv)
+		}
+		s.CRDs = append(s.CRDs, c)
+	}
+	sort.Slice(s.CRDs, func(i, j int) bool { return s.CRDs[i].Name < s.CRDs[j].Name })
+	return s
+}
+
+// LoadCapabilitiesSnapshot reads a capabilities snapshot from a JSON or YAML file.
+func LoadCapabilitiesSnapshot(filename string) (*CapabilitiesSnapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := &CapabilitiesSnapshot{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse capabilities file %s", filename)
+	}
+	if s.KubeVersion == "" {
+		return nil, errors.Errorf("capabilities file %s has no kubeVersion", filename)
+	}
+	return s, nil
+}
+
+// Capabilities returns the capabilities of the snapshot.
+func (s *CapabilitiesSnapshot) Capabilities() (*Capabilities, error) {
+	kubeVersion, err := ParseKubeVersion(s.KubeVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid kube version %q", s.KubeVersion)
+	}
+	return &Capabilities{
+		KubeVersion: *kubeVersion,
+		APIVersions: VersionSet(append([]string{}, s.APIVersions...)),
+		HelmVersion: DefaultCapabilities.HelmVersion,
+	}, nil
+}
+
+// ValidateObject validates an object against the schema of its custom
+// resource definition. Objects that are not custom resources of the snapshot
+// are not validated.
+func (s *CapabilitiesSnapshot) ValidateObject(obj map[string]interface{}) error {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	group, version := "", apiVersion
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		group, version = apiVersion[:i], apiVersion[i+1:]
+	}
+	for _, crd := range s.CRDs {
+		if crd.Group != group || crd.Kind != kind {
+			continue
+		}
+		for _, v := range crd.Versions {
+			if v.Name != version {
+				continue
+			}
+			if !v.Served {
+				return errors.Errorf("version %s of %s is not served", version, crd.Name)
+			}
+			if v.Schema == nil {
+				return nil
+			}
+			schema, err := json.Marshal(v.Schema)
+			if err != nil {
+				return err
+			}
+			return ValidateAgainstSingleSchema(obj, schema)
+		}
+		return errors.Errorf("%s has no version %s", crd.Name, version)
+	}
+	return nil
+}
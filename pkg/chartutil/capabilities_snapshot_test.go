@@ -0,0 +1,109 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCapabilitiesSnapshot(t *testing.T) {
+	caps := &Capabilities{
+		KubeVersion: KubeVersion{Version: "v1.28.3", Major: "1", Minor: "28"},
+		APIVersions: VersionSet{"v1", "stable.example.com/v1", "apps/v1"},
+	}
+	crd := apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "crontabs.stable.example.com"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "stable.example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "CronTab"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:   "v1",
+				Served: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"spec": {
+								Type:       "object",
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{"replicas": {Type: "integer"}},
+							},
+						},
+					},
+				},
+			}},
+		},
+	}
+
+	snapshot := NewCapabilitiesSnapshot(caps, []apiextensionsv1.CustomResourceDefinition{crd})
+	data, err := yaml.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "caps.yaml")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCapabilitiesSnapshot(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loaded.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.KubeVersion.Version != "v1.28.3" || got.KubeVersion.Minor != "28" {
+		t.Errorf("unexpected kube version %+v", got.KubeVersion)
+	}
+	if !got.APIVersions.Has("stable.example.com/v1") || got.APIVersions.Has("batch/v1") {
+		t.Errorf("unexpected API versions %v", got.APIVersions)
+	}
+
+	for _, tt := range []struct {
+		obj     string
+		wantErr bool
+	}{
+		{"apiVersion: stable.example.com/v1\nkind: CronTab\nspec:\n  replicas: 1", false},
+		{"apiVersion: stable.example.com/v1\nkind: CronTab\nspec:\n  replicas: one", true},
+		{"apiVersion: stable.example.com/v2\nkind: CronTab", true},
+		{"apiVersion: v1\nkind: ConfigMap\nspec:\n  replicas: one", false},
+	} {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(tt.obj), &obj); err != nil {
+			t.Fatal(err)
+		}
+		if err := loaded.ValidateObject(obj); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateObject(%q): got error %v, want error %t", tt.obj, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadCapabilitiesSnapshotWithoutKubeVersion(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "caps.json")
+	if err := os.WriteFile(filename, []byte(`{"apiVersions": ["v1"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCapabilitiesSnapshot(filename); err == nil {
+		t.Error("expected an error for a snapshot without a kube version")
+	}
+}
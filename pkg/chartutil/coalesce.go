@@ -19,6 +19,7 @@ package chartutil
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/mitchellh/copystructure"
 	"github.com/pkg/errors"
@@ -97,10 +98,74 @@ type printFn func(format string, v ...interface{})
 // or CoalesceValues. Coalescing removes null values and their keys in some
 // situations while merging keeps the null values.
 func coalesce(printf printFn, ch *chart.Chart, dest map[string]interface{}, prefix string, merge bool) (map[string]interface{}, error) {
+	migrateValues(printf, ch, dest, prefix)
 	coalesceValues(printf, ch, dest, prefix, merge)
 	return coalesceDeps(printf, ch, dest, prefix, merge)
 }
 
+// migrateValues applies the values migrations of a chart to the values given
+// for it, before they are coalesced with the chart's defaults. Values set for
+// keys the chart renamed or moved are moved to their new keys, and a warning
+// is printed for every deprecated key still in use.
+func migrateValues(printf printFn, c *chart.Chart, v map[string]interface{}, prefix string) {
+	subPrefix := concatPrefix(prefix, c.Metadata.Name)
+	for _, m := range c.ValuesMigrations {
+		from := strings.Split(m.From, ".")
+		parent, ok := tableAt(v, from[:len(from)-1], false)
+		if !ok {
+			continue
+		}
+		val, ok := parent[from[len(from)-1]]
+		if !ok {
+			continue
+		}
+
+		msg := ""
+		if m.Message != "" {
+			msg = ": " + m.Message
+		}
+		if m.To == "" {
+			printf("warning: %s is deprecated%s", concatPrefix(subPrefix, m.From), msg)
+			continue
+		}
+
+		to := strings.Split(m.To, ".")
+		dest, ok := tableAt(v, to[:len(to)-1], true)
+		if !ok {
+			printf("warning: %s is deprecated, but cannot be moved to %s as %s is not a table%s", concatPrefix(subPrefix, m.From), concatPrefix(subPrefix, m.To), concatPrefix(subPrefix, m.To), msg)
+			continue
+		}
+		delete(parent, from[len(from)-1])
+		if _, ok := dest[to[len(to)-1]]; ok {
+			printf("warning: %s is deprecated and ignored, as %s is also set%s", concatPrefix(subPrefix, m.From), concatPrefix(subPrefix, m.To), msg)
+			continue
+		}
+		dest[to[len(to)-1]] = val
+		printf("warning: %s is deprecated, use %s instead%s", concatPrefix(subPrefix, m.From), concatPrefix(subPrefix, m.To), msg)
+	}
+}
+
+// tableAt returns the table at the given path of keys, optionally creating
+// the missing tables along the way.
+func tableAt(v map[string]interface{}, path []string, create bool) (map[string]interface{}, bool) {
+	for _, k := range path {
+		next, ok := v[k]
+		if !ok || next == nil {
+			if !create {
+				return nil, false
+			}
+			next = make(map[string]interface{})
+			v[k] = next
+		}
+		table, ok := next.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v = table
+	}
+	return v, true
+}
+
 // coalesceDeps coalesces the dependencies of the given chart.
 func coalesceDeps(printf printFn, chrt *chart.Chart, dest map[string]interface{}, prefix string, merge bool) (map[string]interface{}, error) {
 	for _, subchart := range chrt.Dependencies() {
@@ -114,7 +179,7 @@ func coalesceDeps(printf printFn, chrt *chart.Chart, dest map[string]interface{}
 			dvmap := dv.(map[string]interface{})
 			subPrefix := concatPrefix(prefix, chrt.Metadata.Name)
 			// Get globals out of dest and merge them into dvmap.
-			coalesceGlobals(printf, dvmap, dest, subPrefix, merge)
+			coalesceGlobals(printf, dvmap, scopeGlobals(chrt, subchart, dest), subPrefix, merge)
 			// Now coalesce the rest of the values.
 			var err error
 			dest[subchart.Name()], err = coalesce(printf, subchart, dvmap, subPrefix, merge)
@@ -126,6 +191,40 @@ func coalesceDeps(printf printFn, chrt *chart.Chart, dest map[string]interface{}
 	return dest, nil
 }
 
+// scopeGlobals returns the values of a chart with only the globals passed to
+// the given subchart: those the chart scopes to the subchart or does not
+// scope, and that the subchart consumes if it declares the globals it
+// consumes.
+func scopeGlobals(chrt, subchart *chart.Chart, vals map[string]interface{}) map[string]interface{} {
+	propagation := chrt.Metadata.GlobalsPropagation
+	consumed := subchart.Metadata.Globals
+	globals, ok := vals[GlobalKey].(map[string]interface{})
+	if !ok || (len(propagation) == 0 && len(consumed) == 0) {
+		return vals
+	}
+
+	scoped := make(map[string]interface{}, len(globals))
+	for key, val := range globals {
+		if subcharts, ok := propagation[key]; ok && !containsString(subcharts, subchart.Name()) {
+			continue
+		}
+		if len(consumed) > 0 && !containsString(consumed, key) {
+			continue
+		}
+		scoped[key] = val
+	}
+	return map[string]interface{}{GlobalKey: scoped}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 // coalesceGlobals copies the globals out of src and merges them into dest.
 //
 // For convenience, returns dest.
@@ -694,6 +694,102 @@ func TestCoalesceValuesWarnings(t *testing.T) {
 
 }
 
+func TestCoalesceValuesScopedGlobals(t *testing.T) {
+	c := withDeps(&chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:               "umbrella",
+			GlobalsPropagation: map[string][]string{"database": {"api"}},
+		},
+		Values: map[string]interface{}{
+			"global": map[string]interface{}{
+				"database": "postgres",
+				"image":    "registry.example.com",
+				"region":   "eu",
+			},
+		},
+	},
+		&chart.Chart{Metadata: &chart.Metadata{Name: "api"}},
+		&chart.Chart{Metadata: &chart.Metadata{Name: "web", Globals: []string{"image", "database"}}},
+	)
+
+	v, err := CoalesceValues(c, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	is := assert.New(t)
+	is.Equal(map[string]interface{}{
+		"database": "postgres",
+		"image":    "registry.example.com",
+		"region":   "eu",
+	}, v["api"].(map[string]interface{})["global"])
+	is.Equal(map[string]interface{}{
+		"image": "registry.example.com",
+	}, v["web"].(map[string]interface{})["global"])
+	is.Len(v["global"], 3, "the parent keeps all of its globals")
+}
+
+func TestCoalesceValuesMigrations(t *testing.T) {
+	c := withDeps(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "parent"},
+		Values: map[string]interface{}{
+			"child": map[string]interface{}{"legacy": "from-parent"},
+		},
+		ValuesMigrations: []*chart.ValuesMigration{
+			{From: "image.name", To: "image.repository"},
+			{From: "port", To: "service.port", Message: "the service settings moved under service"},
+			{From: "debug", Message: "debug has no effect since 2.0"},
+			{From: "old", To: "new"},
+			{From: "missing", To: "image.tag"},
+		},
+	},
+		&chart.Chart{
+			Metadata: &chart.Metadata{Name: "child"},
+			Values: map[string]interface{}{
+				"current": "default",
+			},
+			ValuesMigrations: []*chart.ValuesMigration{
+				{From: "legacy", To: "current"},
+			},
+		},
+	)
+	c.Values["image"] = map[string]interface{}{"repository": "nginx", "tag": "latest"}
+
+	vals := map[string]interface{}{
+		"image": map[string]interface{}{"name": "httpd"},
+		"port":  8080,
+		"debug": true,
+		"old":   "ignored",
+		"new":   "kept",
+	}
+
+	warnings := make([]string, 0)
+	printf := func(format string, v ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, v...))
+	}
+
+	v, err := coalesce(printf, c, vals, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]interface{}{"repository": "httpd", "tag": "latest"}, v["image"])
+	assert.Equal(t, map[string]interface{}{"port": 8080}, v["service"])
+	assert.NotContains(t, v, "port")
+	assert.Equal(t, true, v["debug"])
+	assert.Equal(t, "kept", v["new"])
+	assert.NotContains(t, v, "old")
+	assert.Equal(t, "from-parent", v["child"].(map[string]interface{})["current"])
+
+	assert.Equal(t, []string{
+		"warning: parent.image.name is deprecated, use parent.image.repository instead",
+		"warning: parent.port is deprecated, use parent.service.port instead: the service settings moved under service",
+		"warning: parent.debug is deprecated: debug has no effect since 2.0",
+		"warning: parent.old is deprecated and ignored, as parent.new is also set",
+		"warning: parent.child.legacy is deprecated, use parent.child.current instead",
+	}, warnings)
+}
+
 func TestConcatPrefix(t *testing.T) {
 	assert.Equal(t, "b", concatPrefix("", "b"))
 	assert.Equal(t, "a.b", concatPrefix("a", "b"))
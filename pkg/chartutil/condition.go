@@ -0,0 +1,503 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+// plainConditionPath matches the alternatives of conditions that are the path
+// of a value, which keep their original semantics: a path that is not set or
+// not a boolean is skipped.
+var plainConditionPath = regexp.MustCompile(`^[^.\s!=<>&|()"'][^\s!=<>&|()"']*$`)
+
+// splitCondition splits a condition into its alternatives, ignoring commas in
+// quoted strings.
+func splitCondition(condition string) []string {
+	var (
+		alternatives []string
+		quote        rune
+		start        int
+	)
+	for i, r := range condition {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			alternatives = append(alternatives, strings.TrimSpace(condition[start:i]))
+			start = i + 1
+		}
+	}
+	return append(alternatives, strings.TrimSpace(condition[start:]))
+}
+
+// conditionScope resolves the references of a condition expression.
+type conditionScope struct {
+	values Values
+	path   string
+	caps   *Capabilities
+}
+
+func (s *conditionScope) resolve(ref string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(ref, ".Capabilities."):
+		return s.capability(strings.TrimPrefix(ref, ".Capabilities."))
+	case strings.HasPrefix(ref, ".Values."):
+		ref = strings.TrimPrefix(ref, ".Values.")
+	case strings.HasPrefix(ref, "."):
+		return nil, errors.Errorf("unknown reference %s", ref)
+	}
+	v, err := s.values.PathValue(s.path + ref)
+	if _, ok := err.(ErrNoValue); ok {
+		return nil, nil
+	}
+	return v, err
+}
+
+func (s *conditionScope) capability(name string) (interface{}, error) {
+	caps := s.caps
+	if caps == nil {
+		caps = DefaultCapabilities
+	}
+	switch name {
+	case "KubeVersion", "KubeVersion.Version", "KubeVersion.GitVersion":
+		return parseConditionVersion(caps.KubeVersion.Version)
+	case "KubeVersion.Major":
+		return caps.KubeVersion.Major, nil
+	case "KubeVersion.Minor":
+		return caps.KubeVersion.Minor, nil
+	case "HelmVersion", "HelmVersion.Version":
+		return parseConditionVersion(caps.HelmVersion.Version)
+	}
+	return nil, errors.Errorf("unknown capability .Capabilities.%s", name)
+}
+
+// parseConditionVersion parses a version, dropping its pre-release and
+// metadata, e.g. those of the versions of managed Kubernetes services.
+func parseConditionVersion(s string) (*semver.Version, error) {
+	v, err := semver.NewVersion(s)
+	if err != nil {
+		return nil, err
+	}
+	return semver.New(v.Major(), v.Minor(), v.Patch(), "", ""), nil
+}
+
+// evalCondition evaluates a condition expression.
+func evalCondition(expr string, scope *conditionScope) (interface{}, error) {
+	node, err := parseCondition(expr)
+	if err != nil {
+		return nil, err
+	}
+	return node.eval(scope)
+}
+
+// parseCondition parses a condition expression.
+func parseCondition(expr string) (conditionNode, error) {
+	p := &conditionParser{lexer: conditionLexer{input: expr}}
+	p.next()
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, errors.Errorf("unexpected %q at position %d", p.tok.text, p.tok.pos)
+	}
+	return node, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokRef
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type conditionLexer struct {
+	input string
+	pos   int
+}
+
+func (l *conditionLexer) next() (token, error) {
+	for l.pos < len(l.input) && (l.input[l.pos] == ' ' || l.input[l.pos] == '\t') {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+	c := l.input[l.pos]
+	switch {
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(l.input[l.pos+1:], c)
+		if end < 0 {
+			return token{}, errors.Errorf("unterminated string at position %d", start)
+		}
+		l.pos += end + 2
+		return token{kind: tokString, text: l.input[start+1 : l.pos-1], pos: start}, nil
+	case strings.HasPrefix(l.input[l.pos:], "&&"), strings.HasPrefix(l.input[l.pos:], "||"),
+		strings.HasPrefix(l.input[l.pos:], "=="), strings.HasPrefix(l.input[l.pos:], "!="),
+		strings.HasPrefix(l.input[l.pos:], "<="), strings.HasPrefix(l.input[l.pos:], ">="):
+		l.pos += 2
+		return token{kind: tokOp, text: l.input[start:l.pos], pos: start}, nil
+	case strings.ContainsRune("!<>()", rune(c)):
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}, nil
+	}
+	for l.pos < len(l.input) && !strings.ContainsRune(" \t!=<>&|()\"'", rune(l.input[l.pos])) {
+		l.pos++
+	}
+	text := l.input[start:l.pos]
+	if text == "" {
+		return token{}, errors.Errorf("unexpected %q at position %d", l.input[start:start+1], start)
+	}
+	switch text {
+	case "and":
+		return token{kind: tokOp, text: "&&", pos: start}, nil
+	case "or":
+		return token{kind: tokOp, text: "||", pos: start}, nil
+	case "not":
+		return token{kind: tokOp, text: "!", pos: start}, nil
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return token{kind: tokNumber, text: text, pos: start}, nil
+	}
+	return token{kind: tokRef, text: text, pos: start}, nil
+}
+
+type conditionNode interface {
+	eval(scope *conditionScope) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(*conditionScope) (interface{}, error) { return n.value, nil }
+
+type refNode struct{ ref string }
+
+func (n refNode) eval(scope *conditionScope) (interface{}, error) { return scope.resolve(n.ref) }
+
+type notNode struct{ operand conditionNode }
+
+func (n notNode) eval(scope *conditionScope) (interface{}, error) {
+	b, err := evalBool(n.operand, scope, "!")
+	return !b, err
+}
+
+type logicalNode struct {
+	op          string
+	left, right conditionNode
+}
+
+func (n logicalNode) eval(scope *conditionScope) (interface{}, error) {
+	left, err := evalBool(n.left, scope, n.op)
+	if err != nil {
+		return nil, err
+	}
+	// Operands are evaluated lazily, so that e.g. a comparison is only
+	// evaluated if a value it depends on is set.
+	if (n.op == "&&" && !left) || (n.op == "||" && left) {
+		return left, nil
+	}
+	return evalBool(n.right, scope, n.op)
+}
+
+type compareNode struct {
+	op          string
+	left, right conditionNode
+}
+
+func (n compareNode) eval(scope *conditionScope) (interface{}, error) {
+	left, err := n.left.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	return compareCondition(n.op, left, right)
+}
+
+func evalBool(n conditionNode, scope *conditionScope, op string) (bool, error) {
+	v, err := n.eval(scope)
+	if err != nil {
+		return false, err
+	}
+	switch b := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return b, nil
+	}
+	return false, errors.Errorf("operand of %s is not a boolean: %v", op, v)
+}
+
+type conditionParser struct {
+	lexer conditionLexer
+	tok   token
+	err   error
+}
+
+func (p *conditionParser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+}
+
+func (p *conditionParser) isOp(ops ...string) bool {
+	if p.err != nil || p.tok.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.isOp("||") {
+		p.next()
+		var right conditionNode
+		right, err = p.parseAnd()
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseNot()
+	for err == nil && p.isOp("&&") {
+		p.next()
+		var right conditionNode
+		right, err = p.parseNot()
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *conditionParser) parseNot() (conditionNode, error) {
+	if p.isOp("!") {
+		p.next()
+		operand, err := p.parseNot()
+		return notNode{operand: operand}, err
+	}
+	return p.parseCompare()
+}
+
+func (p *conditionParser) parseCompare() (conditionNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("==", "!=", "<", "<=", ">", ">=") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parsePrimary()
+		return compareNode{op: op, left: left, right: right}, err
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parsePrimary() (conditionNode, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch tok.kind {
+	case tokString:
+		p.next()
+		return literalNode{tok.text}, p.err
+	case tokNumber:
+		p.next()
+		f, _ := strconv.ParseFloat(tok.text, 64)
+		return literalNode{f}, p.err
+	case tokRef:
+		p.next()
+		switch tok.text {
+		case "true":
+			return literalNode{true}, p.err
+		case "false":
+			return literalNode{false}, p.err
+		case "null", "nil":
+			return literalNode{nil}, p.err
+		}
+		return refNode{tok.text}, p.err
+	case tokOp:
+		if tok.text == "(" {
+			p.next()
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOp(")") {
+				return nil, errors.Errorf("missing ) at position %d", p.tok.pos)
+			}
+			p.next()
+			return node, p.err
+		}
+	case tokEOF:
+		return nil, errors.New("unexpected end of expression")
+	}
+	return nil, errors.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+// compareCondition compares two values of a condition expression.
+func compareCondition(op string, left, right interface{}) (bool, error) {
+	left, right = normalizeConditionValue(left), normalizeConditionValue(right)
+
+	var cmp int
+	switch l := left.(type) {
+	case *semver.Version:
+		r, err := conditionVersion(right)
+		if err != nil {
+			return false, err
+		}
+		cmp = l.Compare(r)
+	case float64:
+		if r, ok := right.(float64); ok {
+			cmp = compareFloats(l, r)
+			break
+		}
+		if r, ok := right.(*semver.Version); ok {
+			return compareCondition(reverseOp(op), r, l)
+		}
+		return equalityOnly(op, false, left, right)
+	case string:
+		if r, ok := right.(*semver.Version); ok {
+			return compareCondition(reverseOp(op), r, l)
+		}
+		r, ok := right.(string)
+		if !ok {
+			return equalityOnly(op, false, left, right)
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		if r, ok := right.(*semver.Version); ok && left != nil {
+			return compareCondition(reverseOp(op), r, left)
+		}
+		return equalityOnly(op, left == right, left, right)
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// equalityOnly compares values that are only equal or not, e.g. booleans or
+// values of different types.
+func equalityOnly(op string, equal bool, left, right interface{}) (bool, error) {
+	switch op {
+	case "==":
+		return equal, nil
+	case "!=":
+		return !equal, nil
+	}
+	return false, errors.Errorf("cannot compare %v %s %v", left, op, right)
+}
+
+func reverseOp(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+func compareFloats(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+// conditionVersion converts a value compared with a version to a version.
+func conditionVersion(v interface{}) (*semver.Version, error) {
+	switch v := v.(type) {
+	case *semver.Version:
+		return v, nil
+	case string:
+		return parseConditionVersion(v)
+	case float64:
+		return parseConditionVersion(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return nil, errors.Errorf("cannot compare %v with a version", v)
+}
+
+// normalizeConditionValue converts the numbers of values to float64.
+func normalizeConditionValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case json.Number:
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+		return n.String()
+	}
+	return v
+}
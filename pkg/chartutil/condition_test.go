@@ -0,0 +1,138 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"testing"
+)
+
+func TestEvalCondition(t *testing.T) {
+	caps := DefaultCapabilities.Copy()
+	caps.KubeVersion = KubeVersion{Version: "v1.28.3-eks-4f4795d", Major: "1", Minor: "28"}
+	scope := &conditionScope{
+		values: Values{
+			"sub": map[string]interface{}{
+				"enabled":  true,
+				"mode":     "minimal",
+				"replicas": 3,
+			},
+			"legacy": map[string]interface{}{"enabled": false},
+		},
+		path: "sub.",
+		caps: caps,
+	}
+
+	tests := []struct {
+		expr    string
+		want    interface{}
+		wantErr bool
+	}{
+		{expr: `enabled && .Capabilities.KubeVersion >= "1.25"`, want: true},
+		{expr: `.Capabilities.KubeVersion < "v1.28.3"`, want: false},
+		{expr: `"1.29" > .Capabilities.KubeVersion`, want: true},
+		{expr: `.Capabilities.KubeVersion.Minor == "28"`, want: true},
+		{expr: `.Values.mode == 'minimal' and not enabled`, want: false},
+		{expr: `!(missing || mode != "minimal")`, want: true},
+		{expr: `replicas >= 2 && replicas < 5`, want: true},
+		{expr: `missing == null`, want: true},
+		{expr: `missing && replicas`, want: false},
+		{expr: `enabled && replicas`, wantErr: true},
+		{expr: `mode < 3`, wantErr: true},
+		{expr: `(enabled`, wantErr: true},
+		{expr: `enabled ==`, wantErr: true},
+		{expr: `.Release.Name == "x"`, wantErr: true},
+		{expr: `mode == "unterminated`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := evalCondition(tt.expr, scope)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", tt.expr, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestSplitCondition(t *testing.T) {
+	got := splitCondition(`a.enabled, mode == "a,b" ,b.enabled`)
+	want := []string{"a.enabled", `mode == "a,b"`, "b.enabled"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %q, got %q", want[i], got[i])
+		}
+	}
+}
+
+func TestDependencyConditionExpressions(t *testing.T) {
+	caps := DefaultCapabilities.Copy()
+	caps.KubeVersion = KubeVersion{Version: "v1.24.0", Major: "1", Minor: "24"}
+
+	c := loadChart(t, "testdata/subpop")
+	for _, dep := range c.Metadata.Dependencies {
+		switch dep.Name {
+		case "subchart1":
+			// The expression decides, the path is not reached.
+			dep.Condition = `subchart1.enabled && .Capabilities.KubeVersion >= "1.25",subchart1.enabled`
+		case "subchart2":
+			// The expression fails to evaluate, the path decides.
+			dep.Condition = `.Release.Name == "x",subchart2.enabled`
+		}
+	}
+	v := map[string]interface{}{
+		"subchart1": map[string]interface{}{"enabled": true},
+		"subchart2": map[string]interface{}{"enabled": true},
+	}
+	if err := processDependencyEnabled(c, v, "", caps); err != nil {
+		t.Fatal(err)
+	}
+	names := extractChartNames(c)
+	for _, n := range names {
+		if n == "parentchart.subchart1" {
+			t.Errorf("expected subchart1 to be disabled on Kubernetes 1.24, got %v", names)
+		}
+	}
+	found := false
+	for _, n := range names {
+		found = found || n == "parentchart.subchart2"
+	}
+	if !found {
+		t.Errorf("expected the failing expression to fall back to the path enabling subchart2, got %v", names)
+	}
+
+	c = loadChart(t, "testdata/subpop")
+	for _, dep := range c.Metadata.Dependencies {
+		if dep.Name == "subchart2" {
+			dep.Condition = `invalid ==,subchart2.enabled`
+		}
+	}
+	err := processDependencyEnabled(c, v, "", caps)
+	expectErr := "condition 'invalid ==' for chart subchart2 is invalid: unexpected end of expression"
+	if err == nil || err.Error() != expectErr {
+		t.Errorf("expected an error %q, got %v", expectErr, err)
+	}
+}
@@ -44,6 +44,8 @@ const (
 	ValuesfileName = "values.yaml"
 	// SchemafileName is the default values schema file name.
 	SchemafileName = "values.schema.json"
+	// ValuesMigrationsfileName is the values migrations file name.
+	ValuesMigrationsfileName = "values-migrations.yaml"
 	// TemplatesDir is the relative directory name for templates.
 	TemplatesDir = "templates"
 	// ChartsDir is the relative directory name for charts dependencies.
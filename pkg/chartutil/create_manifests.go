@@ -0,0 +1,515 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultImageHelper formats the image values of a container in the charts
+// created from manifests.
+const defaultImageHelper = `
+{{/*
+Container image from its repository and tag values
+*/}}
+{{- define "<CHARTNAME>.image" -}}
+{{ .repository }}{{ with .tag }}:{{ . }}{{ end }}
+{{- end }}
+`
+
+// podSpecPaths are the paths of the pod specs of the workload kinds.
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// replicatedKinds are the kinds with a spec.replicas field.
+var replicatedKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"ReplicaSet":  true,
+}
+
+// ManifestObject is a Kubernetes object to create a chart template from.
+type ManifestObject struct {
+	// Object is the object, as decoded from YAML or JSON.
+	Object map[string]interface{}
+	// Parameters are the paths of the fields of the object to lift into
+	// values, in addition to the name, labels, replicas and container images.
+	// A path leading into a list lifts the whole list.
+	Parameters [][]string
+}
+
+// CreateFromManifests creates a new chart in a directory from existing
+// Kubernetes manifests.
+//
+// The manifests are the YAML or JSON files at the given paths, or in the
+// given directories. Every object becomes a template, as for
+// CreateFromObjects.
+//
+// The returned string will point to the newly created directory, as for
+// Create.
+func CreateFromManifests(name, dir string, manifests ...string) (string, error) {
+	var objects []*ManifestObject
+	for _, m := range manifests {
+		objs, err := readManifests(m)
+		if err != nil {
+			return "", err
+		}
+		for _, obj := range objs {
+			objects = append(objects, &ManifestObject{Object: obj})
+		}
+	}
+	if len(objects) == 0 {
+		return "", errors.Errorf("no Kubernetes objects found in %s", strings.Join(manifests, ", "))
+	}
+	return CreateFromObjects(name, dir, objects)
+}
+
+// CreateFromObjects creates a new chart in a directory from Kubernetes
+// objects.
+//
+// Every object becomes a template, in which the name, labels, replicas and
+// container images, and the fields given as its parameters, are parameterized by
+// values under a key derived from the name of the object, with the values of
+// the object as defaults. The common chart labels are added to every object.
+// The namespace and the status, and the fields set by the API server in
+// exported objects, are dropped.
+//
+// The returned string will point to the newly created directory, as for
+// Create.
+func CreateFromObjects(name, dir string, objects []*ManifestObject) (string, error) {
+	if err := validateChartName(name); err != nil {
+		return "", err
+	}
+	if len(objects) == 0 {
+		return "", errors.New("no Kubernetes objects to create a chart from")
+	}
+
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return path, err
+	}
+	if fi, err := os.Stat(path); err != nil {
+		return path, err
+	} else if !fi.IsDir() {
+		return path, errors.Errorf("no such directory %s", path)
+	}
+	cdir := filepath.Join(path, name)
+	if fi, err := os.Stat(cdir); err == nil && !fi.IsDir() {
+		return cdir, errors.Errorf("file %s already exists and is not a directory", cdir)
+	}
+
+	values := map[string]interface{}{
+		"nameOverride":     "",
+		"fullnameOverride": "",
+	}
+	files := map[string][]byte{
+		ChartfileName:  []byte(fmt.Sprintf(defaultChartfile, name)),
+		IgnorefileName: []byte(defaultIgnore),
+		HelpersName:    transform(defaultHelpers+defaultImageHelper, name),
+	}
+	for _, o := range objects {
+		obj := o.Object
+		kind, _ := obj["kind"].(string)
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		objName, _ := metadata["name"].(string)
+		if kind == "" || objName == "" {
+			return "", errors.New("every Kubernetes object must have a kind and a name")
+		}
+
+		key := valuesKey(objName)
+		if _, ok := values[key]; ok || key == "" || unicode.IsDigit(rune(key[0])) {
+			key = valuesKey(kind + "-" + objName)
+		}
+		if _, ok := values[key]; ok {
+			return "", errors.Errorf("%s %s is defined more than once", kind, objName)
+		}
+
+		t := &manifestTemplate{chart: name, key: key, values: map[string]interface{}{}}
+		data, err := t.render(obj, o.Parameters)
+		if err != nil {
+			return "", errors.Wrapf(err, "creating a template for %s %s", kind, objName)
+		}
+		values[key] = t.values
+
+		fname := filepath.Join(TemplatesDir, strings.ToLower(objName+"-"+kind)+".yaml")
+		if _, ok := files[fname]; ok {
+			return "", errors.Errorf("%s %s is defined more than once", kind, objName)
+		}
+		files[fname] = data
+	}
+
+	v, err := yaml.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	files[ValuesfileName] = append([]byte(fmt.Sprintf("# Default values for %s.\n# They were extracted from the manifests the chart was created from.\n\n", name)), v...)
+
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		p := filepath.Join(cdir, n)
+		if _, err := os.Stat(p); err == nil {
+			// There is no handle to a preferred output stream here.
+			fmt.Fprintf(Stderr, "WARNING: File %q already exists. Overwriting.\n", p)
+		}
+		if err := writeFile(p, files[n]); err != nil {
+			return cdir, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(cdir, ChartsDir), 0755); err != nil {
+		return cdir, err
+	}
+	return cdir, nil
+}
+
+// readManifests reads the Kubernetes objects of a manifest file, or of the
+// .yaml, .yml and .json files of a directory. Lists are expanded.
+func readManifests(path string) ([]map[string]interface{}, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if fi.IsDir() {
+		files = nil
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			switch filepath.Ext(p) {
+			case ".yaml", ".yml", ".json":
+				if !info.IsDir() {
+					files = append(files, p)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []map[string]interface{}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+		for {
+			var obj map[string]interface{}
+			if err := decoder.Decode(&obj); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, errors.Wrapf(err, "reading manifests from %s", f)
+			}
+			if obj == nil {
+				continue
+			}
+			if items, ok := obj["items"].([]interface{}); ok && strings.HasSuffix(fmt.Sprint(obj["kind"]), "List") {
+				for _, item := range items {
+					if o, ok := item.(map[string]interface{}); ok {
+						objects = append(objects, o)
+					}
+				}
+				continue
+			}
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// valuesKey turns the name of an object into a values key, e.g. "my-app.web"
+// into "myAppWeb".
+func valuesKey(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '-' || r == '.' || r == '_':
+			upper = b.Len() > 0
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		case b.Len() == 0:
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// manifestTemplate turns an object into a template, collecting the values it
+// parameterizes.
+type manifestTemplate struct {
+	chart  string
+	key    string
+	values map[string]interface{}
+	// placeholders are the template actions replacing, by index, the
+	// placeholders set in the object once it is marshaled.
+	placeholders []placeholderAction
+}
+
+// placeholderAction is a template action. The pipeline of a block action
+// renders YAML, which is indented below the key of the placeholder.
+type placeholderAction struct {
+	pipeline string
+	block    bool
+}
+
+var placeholderPattern = regexp.MustCompile(` HELM_PLACEHOLDER_\d+`)
+
+// placeholder returns a placeholder for a template action, which is marshaled
+// as a plain scalar.
+func (t *manifestTemplate) placeholder(pipeline string) string {
+	t.placeholders = append(t.placeholders, placeholderAction{pipeline: pipeline})
+	return fmt.Sprintf("HELM_PLACEHOLDER_%d", len(t.placeholders)-1)
+}
+
+// blockPlaceholder returns a placeholder for a template action rendering
+// YAML.
+func (t *manifestTemplate) blockPlaceholder(pipeline string) string {
+	t.placeholders = append(t.placeholders, placeholderAction{pipeline: pipeline, block: true})
+	return fmt.Sprintf("HELM_PLACEHOLDER_%d", len(t.placeholders)-1)
+}
+
+// isPlaceholder reports whether a field was already parameterized.
+func isPlaceholder(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && strings.HasPrefix(s, "HELM_PLACEHOLDER_")
+}
+
+func (t *manifestTemplate) render(obj map[string]interface{}, parameters [][]string) ([]byte, error) {
+	kind := obj["kind"].(string)
+	delete(obj, "status")
+
+	metadata := obj["metadata"].(map[string]interface{})
+	for _, f := range []string{"namespace", "uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink"} {
+		delete(metadata, f)
+	}
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+		delete(annotations, "deployment.kubernetes.io/revision")
+		if len(annotations) == 0 {
+			delete(metadata, "annotations")
+		}
+	}
+
+	for _, p := range parameters {
+		t.lift(obj, p)
+	}
+
+	t.values["name"] = metadata["name"]
+	metadata["name"] = t.placeholder(".Values." + t.key + ".name")
+
+	labels, _ := metadata["labels"].(map[string]interface{})
+	if labels == nil {
+		labels = map[string]interface{}{}
+	}
+	t.values["labels"] = labels
+	// The labels of the object take precedence over the common labels.
+	metadata["labels"] = t.blockPlaceholder(fmt.Sprintf(`merge (dict) .Values.%s.labels (include "%s.labels" . | fromYaml) | toYaml`, t.key, t.chart))
+
+	if replicatedKinds[kind] {
+		if spec, ok := obj["spec"].(map[string]interface{}); ok {
+			replicas, ok := spec["replicas"]
+			if !ok {
+				replicas = 1
+			}
+			if !isPlaceholder(replicas) {
+				t.values["replicaCount"] = replicas
+				spec["replicas"] = t.placeholder(".Values." + t.key + ".replicaCount")
+			}
+		}
+	}
+
+	if path, ok := podSpecPaths[kind]; ok {
+		spec := lookupTable(obj, path)
+		containers := map[string]interface{}{}
+		for _, field := range []string{"initContainers", "containers"} {
+			list, _ := spec[field].([]interface{})
+			for _, c := range list {
+				c, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				cname, _ := c["name"].(string)
+				image, _ := c["image"].(string)
+				ckey := valuesKey(cname)
+				if image == "" || ckey == "" || unicode.IsDigit(rune(ckey[0])) {
+					continue
+				}
+				if _, ok := containers[ckey]; ok {
+					continue
+				}
+				repository, tag := splitImage(image)
+				containers[ckey] = map[string]interface{}{
+					"image": map[string]interface{}{"repository": repository, "tag": tag},
+				}
+				c["image"] = t.placeholder(fmt.Sprintf(`include "%s.image" .Values.%s.containers.%s.image | quote`, t.chart, t.key, ckey))
+			}
+		}
+		if len(containers) > 0 {
+			t.values["containers"] = containers
+		}
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		lines[i] = placeholderPattern.ReplaceAllStringFunc(line, func(m string) string {
+			n, _ := strconv.Atoi(m[len(" HELM_PLACEHOLDER_"):])
+			action := t.placeholders[n]
+			if !action.block {
+				return " {{ " + action.pipeline + " }}"
+			}
+			// Indent the block below the key, which follows the dash of a
+			// list item.
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+			if strings.HasPrefix(line[indent:], "- ") {
+				indent += 2
+			}
+			indent += 2
+			return fmt.Sprintf("\n%s{{- %s | nindent %d }}", strings.Repeat(" ", indent), action.pipeline, indent)
+		})
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// lift parameterizes the field of an object at the given path, keeping its
+// value as the default at the same path under the values of the object. The
+// name, namespace and labels are left alone, as they are parameterized anyway.
+func (t *manifestTemplate) lift(obj map[string]interface{}, path []string) {
+	if len(path) == 0 || path[0] == "apiVersion" || path[0] == "kind" || path[0] == "status" {
+		return
+	}
+	if path[0] == "metadata" && (len(path) == 1 || path[1] == "name" || path[1] == "namespace" || path[1] == "labels") {
+		return
+	}
+
+	parent := obj
+	for i, k := range path {
+		v, ok := parent[k]
+		if !ok || isPlaceholder(v) {
+			return
+		}
+		next, ok := v.(map[string]interface{})
+		if i < len(path)-1 && ok {
+			parent = next
+			continue
+		}
+		// The field is a scalar, a list or the table at the end of the path.
+		values := t.values
+		for _, vk := range path[:i] {
+			table, ok := values[vk].(map[string]interface{})
+			if !ok {
+				table = map[string]interface{}{}
+				values[vk] = table
+			}
+			values = table
+		}
+		values[k] = v
+		ref := valuesRef(t.key, path[:i+1])
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			parent[k] = t.blockPlaceholder("toYaml " + ref)
+		default:
+			parent[k] = t.placeholder(ref + " | toJson")
+		}
+		return
+	}
+}
+
+// valuesRef returns a template expression referring to the values at the
+// given path under a key.
+func valuesRef(key string, path []string) string {
+	ref := ".Values." + key
+	for _, k := range path {
+		if !isIdentifier(k) {
+			quoted := make([]string, len(path))
+			for i, k := range path {
+				quoted[i] = strconv.Quote(k)
+			}
+			return fmt.Sprintf("(index .Values.%s %s)", key, strings.Join(quoted, " "))
+		}
+		ref += "." + k
+	}
+	return ref
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// lookupTable returns the table at the given path of keys of an object, or
+// nil.
+func lookupTable(obj map[string]interface{}, path []string) map[string]interface{} {
+	for _, k := range path {
+		next, ok := obj[k].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		obj = next
+	}
+	return obj
+}
+
+// splitImage splits an image reference into its repository and tag. A digest
+// is kept in the repository.
+func splitImage(image string) (string, string) {
+	if strings.Contains(image, "@") {
+		return image, ""
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
@@ -20,6 +20,8 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v3/pkg/chart"
@@ -170,3 +172,140 @@ func TestValidateChartName(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateFromManifests(t *testing.T) {
+	tdir := t.TempDir()
+	manifest := filepath.Join(tdir, "manifest.yaml")
+	if err := os.WriteFile(manifest, []byte(`apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: 2nd-backup
+  namespace: ops
+spec:
+  schedule: "0 2 * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            image: backup@sha256:0123456789abcdef
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := CreateFromManifests("backups", tdir, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := loader.LoadDir(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.Templates) != 2 {
+		t.Fatalf("Expected 2 templates, got %d", len(ch.Templates))
+	}
+
+	values, ok := ch.Values["cronJob2ndBackup"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected values for the CronJob, got %v", ch.Values)
+	}
+	if values["name"] != "2nd-backup" {
+		t.Errorf("Expected the name of the CronJob as default, got %v", values["name"])
+	}
+	image := values["containers"].(map[string]interface{})["backup"].(map[string]interface{})["image"]
+	if !reflect.DeepEqual(image, map[string]interface{}{"repository": "backup@sha256:0123456789abcdef", "tag": ""}) {
+		t.Errorf("Unexpected image values %v", image)
+	}
+	for _, tpl := range ch.Templates {
+		if tpl.Name != "templates/2nd-backup-cronjob.yaml" {
+			continue
+		}
+		if strings.Contains(string(tpl.Data), "namespace") {
+			t.Errorf("Expected the namespace to be dropped, got\n%s", tpl.Data)
+		}
+		if !strings.Contains(string(tpl.Data), `image: {{ include "backups.image" .Values.cronJob2ndBackup.containers.backup.image | quote }}`) {
+			t.Errorf("Expected the image to be parameterized, got\n%s", tpl.Data)
+		}
+	}
+
+	if _, err := CreateFromManifests("empty", tdir, t.TempDir()); err == nil {
+		t.Error("Expected an error without manifests")
+	}
+}
+
+// TestCreateFromManifestsTemplates pins the templates and values created from
+// plain manifests, which parameterize the names, labels, replicas and images
+// of the objects only.
+func TestCreateFromManifestsTemplates(t *testing.T) {
+	c, err := CreateFromManifests("shop", t.TempDir(), "testdata/manifests")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"values.yaml",
+		"templates/web-deployment.yaml",
+		"templates/web-service.yaml",
+		"templates/web-settings-configmap.yaml",
+	} {
+		expect, err := os.ReadFile(filepath.Join("testdata/manifests-chart", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(c, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, expect) {
+			t.Errorf("Expected %s to be\n%s\ngot\n%s", name, expect, got)
+		}
+	}
+
+	ch, err := loader.LoadDir(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The helpers, the image helper included, and one template per object.
+	if len(ch.Templates) != 4 {
+		t.Errorf("Expected 4 templates, got %d", len(ch.Templates))
+	}
+}
+
+func TestCreateFromManifestsPaths(t *testing.T) {
+	c, err := CreateFromManifests("shop", t.TempDir(), "testdata/manifests/settings.json", "testdata/manifests/web.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := loader.LoadDir(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"web", "serviceWeb", "webSettings"} {
+		if _, ok := ch.Values[key]; !ok {
+			t.Errorf("Expected values for %s, got %v", key, ch.Values)
+		}
+	}
+
+	if _, err := CreateFromManifests("$shop", t.TempDir(), "testdata/manifests"); err == nil {
+		t.Error("Expected an error for an invalid chart name")
+	}
+	if _, err := CreateFromManifests("shop", t.TempDir(), "testdata/manifests/missing.yaml"); err == nil {
+		t.Error("Expected an error for a missing manifest")
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	for image, expected := range map[string][2]string{
+		"nginx":                         {"nginx", ""},
+		"nginx:1.25":                    {"nginx", "1.25"},
+		"localhost:5000/app":            {"localhost:5000/app", ""},
+		"localhost:5000/app:v1":         {"localhost:5000/app", "v1"},
+		"nginx@sha256:0123456789abcdef": {"nginx@sha256:0123456789abcdef", ""},
+	} {
+		repository, tag := splitImage(image)
+		if repository != expected[0] || tag != expected[1] {
+			t.Errorf("Expected %s to split into %v, got %s and %s", image, expected, repository, tag)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/mitchellh/copystructure"
+	"github.com/pkg/errors"
 
 	"helm.sh/helm/v3/pkg/chart"
 )
@@ -28,7 +29,7 @@ import (
 //
 // TODO: For Helm v4 this can be combined with or turned into ProcessDependenciesWithMerge
 func ProcessDependencies(c *chart.Chart, v Values) error {
-	if err := processDependencyEnabled(c, v, ""); err != nil {
+	if err := processDependencyEnabled(c, v, "", nil); err != nil {
 		return err
 	}
 	return processDependencyImportValues(c, false)
@@ -38,36 +39,84 @@ func ProcessDependencies(c *chart.Chart, v Values) error {
 // It is similar to ProcessDependencies but it does not remove nil values during
 // the import/export handling process.
 func ProcessDependenciesWithMerge(c *chart.Chart, v Values) error {
-	if err := processDependencyEnabled(c, v, ""); err != nil {
+	return ProcessDependenciesWithCapabilities(c, v, nil)
+}
+
+// ProcessDependenciesWithCapabilities is like ProcessDependenciesWithMerge,
+// evaluating the conditions of dependencies against the given capabilities,
+// DefaultCapabilities if nil.
+func ProcessDependenciesWithCapabilities(c *chart.Chart, v Values, caps *Capabilities) error {
+	if err := processDependencyEnabled(c, v, "", caps); err != nil {
 		return err
 	}
 	return processDependencyImportValues(c, true)
 }
 
-// processDependencyConditions disables charts based on condition path value in values
-func processDependencyConditions(reqs []*chart.Dependency, cvals Values, cpath string) {
+// processDependencyConditions disables charts based on their conditions.
+//
+// A dependency condition is a comma-separated list of alternatives, the first
+// of which that yields a boolean decides whether the dependency is enabled.
+//
+// An alternative is either the path of a value, e.g. "subchart.enabled", or
+// an expression combining values and capabilities, e.g.
+//
+//	subchart.enabled && .Capabilities.KubeVersion >= "1.25"
+//	!(legacy.enabled || .Values.mode == "minimal")
+//
+// Expressions support the operators && (and), || (or), ! (not), ==, !=, <,
+// <=, > and >=, parentheses, and string, number, boolean and null literals.
+// Values are referred to by their path, optionally prefixed with ".Values.",
+// relative to the values of the chart declaring the dependency. Values that
+// are not set are null, which is false. Versions, .Capabilities.KubeVersion
+// and .Capabilities.HelmVersion, are compared by their major, minor and patch
+// numbers with versions given as strings, e.g. "1.25" or "v1.25.3".
+//
+// An expression that does not parse is an error. Like a path that is not set
+// or not a boolean, an expression that fails to evaluate or is not a boolean
+// is skipped with a warning.
+func processDependencyConditions(reqs []*chart.Dependency, cvals Values, cpath string, caps *Capabilities) error {
 	if reqs == nil {
-		return
+		return nil
 	}
+	scope := &conditionScope{values: cvals, path: cpath, caps: caps}
 	for _, r := range reqs {
-		for _, c := range strings.Split(strings.TrimSpace(r.Condition), ",") {
-			if len(c) > 0 {
-				// retrieve value
-				vv, err := cvals.PathValue(cpath + c)
-				if err == nil {
-					// if not bool, warn
-					if bv, ok := vv.(bool); ok {
-						r.Enabled = bv
-						break
-					}
-					log.Printf("Warning: Condition path '%s' for chart %s returned non-bool value", c, r.Name)
-				} else if _, ok := err.(ErrNoValue); !ok {
-					// this is a real error
-					log.Printf("Warning: PathValue returned error %v", err)
+		for _, c := range splitCondition(strings.TrimSpace(r.Condition)) {
+			if len(c) == 0 {
+				continue
+			}
+			if !plainConditionPath.MatchString(c) {
+				node, err := parseCondition(c)
+				if err != nil {
+					return errors.Wrapf(err, "condition '%s' for chart %s is invalid", c, r.Name)
 				}
+				v, err := node.eval(scope)
+				if err != nil {
+					log.Printf("Warning: Condition '%s' for chart %s failed to evaluate: %s", c, r.Name, err)
+					continue
+				}
+				if bv, ok := v.(bool); ok {
+					r.Enabled = bv
+					break
+				}
+				log.Printf("Warning: Condition '%s' for chart %s returned non-bool value", c, r.Name)
+				continue
+			}
+			// retrieve value
+			vv, err := cvals.PathValue(cpath + c)
+			if err == nil {
+				// if not bool, warn
+				if bv, ok := vv.(bool); ok {
+					r.Enabled = bv
+					break
+				}
+				log.Printf("Warning: Condition path '%s' for chart %s returned non-bool value", c, r.Name)
+			} else if _, ok := err.(ErrNoValue); !ok {
+				// this is a real error
+				log.Printf("Warning: PathValue returned error %v", err)
 			}
 		}
 	}
+	return nil
 }
 
 // processDependencyTags disables charts based on tags in values
@@ -128,7 +177,7 @@ func getAliasDependency(charts []*chart.Chart, dep *chart.Dependency) *chart.Cha
 }
 
 // processDependencyEnabled removes disabled charts from dependencies
-func processDependencyEnabled(c *chart.Chart, v map[string]interface{}, path string) error {
+func processDependencyEnabled(c *chart.Chart, v map[string]interface{}, path string, caps *Capabilities) error {
 	if c.Metadata.Dependencies == nil {
 		return nil
 	}
@@ -172,7 +221,9 @@ Loop:
 	}
 	// flag dependencies as enabled/disabled
 	processDependencyTags(c.Metadata.Dependencies, cvals)
-	processDependencyConditions(c.Metadata.Dependencies, cvals, path)
+	if err := processDependencyConditions(c.Metadata.Dependencies, cvals, path, caps); err != nil {
+		return err
+	}
 	// make a map of charts to remove
 	rm := map[string]struct{}{}
 	for _, r := range c.Metadata.Dependencies {
@@ -201,7 +252,7 @@ Loop:
 	// recursively call self to process sub dependencies
 	for _, t := range cd {
 		subpath := path + t.Metadata.Name + "."
-		if err := processDependencyEnabled(t, cvals, subpath); err != nil {
+		if err := processDependencyEnabled(t, cvals, subpath, caps); err != nil {
 			return err
 		}
 	}
@@ -249,31 +300,51 @@ func processImportValues(c *chart.Chart, merge bool) error {
 		return err
 	}
 	b := make(map[string]interface{})
+	var lists []listImport
 	// import values from each dependency if specified in import-values
 	for _, r := range c.Metadata.Dependencies {
 		var outiv []interface{}
 		for _, riv := range r.ImportValues {
 			switch iv := riv.(type) {
 			case map[string]interface{}:
-				child := iv["child"].(string)
-				parent := iv["parent"].(string)
+				child, _ := iv["child"].(string)
+				parent, _ := iv["parent"].(string)
 
 				outiv = append(outiv, map[string]string{
 					"child":  child,
 					"parent": parent,
 				})
 
-				// get child table
-				vv, err := cvals.Table(r.Name + "." + child)
+				// get child value, which may be a table, a list or a scalar
+				value, err := importedValue(cvals, r.Name+"."+child)
 				if err != nil {
-					log.Printf("Warning: ImportValues missing table from chart %s: %v", r.Name, err)
+					def, ok := iv["default"]
+					if !ok {
+						log.Printf("Warning: ImportValues missing table from chart %s: %v", r.Name, err)
+						continue
+					}
+					value = deepCopyValue(def)
+				}
+				if strings.HasSuffix(parent, "[]") {
+					lists = append(lists, listImport{path: strings.TrimSuffix(parent, "[]"), value: deepCopyValue(value)})
+					continue
+				}
+				vm, ok := value.(map[string]interface{})
+				if !ok && parent == "." {
+					log.Printf("Warning: ImportValues cannot import %s of chart %s into the root of the values: it is not a table", child, r.Name)
 					continue
 				}
 				// create value map from child to be merged into parent
+				var pm map[string]interface{}
+				if ok {
+					pm = pathToMap(parent, vm)
+				} else {
+					pm = pathToValue(parent, value)
+				}
 				if merge {
-					b = MergeTables(b, pathToMap(parent, vv.AsMap()))
+					b = MergeTables(b, pm)
 				} else {
-					b = CoalesceTables(b, pathToMap(parent, vv.AsMap()))
+					b = CoalesceTables(b, pm)
 				}
 			case string:
 				child := "exports." + iv
@@ -314,9 +385,84 @@ func processImportValues(c *chart.Chart, merge bool) error {
 		c.Values = CoalesceTables(cvals, b)
 	}
 
+	// Values imported into lists are appended to the items of the parent.
+	for _, l := range lists {
+		if err := appendToList(c.Values, l.path, l.value); err != nil {
+			log.Printf("Warning: ImportValues cannot import into %s of chart %s: %v", l.path, c.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// listImport is a value imported into the list of the parent at a path.
+type listImport struct {
+	path  string
+	value interface{}
+}
+
+// importedValue returns the table or value of a child chart to import.
+func importedValue(cvals Values, path string) (interface{}, error) {
+	t, err := cvals.Table(path)
+	if err == nil {
+		return t.AsMap(), nil
+	}
+	if v, verr := cvals.PathValue(path); verr == nil && v != nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// pathToValue creates a nested map holding the value at the given YAML path
+// in dot notation.
+func pathToValue(path string, value interface{}) map[string]interface{} {
+	keys := parsePath(path)
+	m := map[string]interface{}{keys[len(keys)-1]: value}
+	for i := len(keys) - 2; i >= 0; i-- {
+		m = map[string]interface{}{keys[i]: m}
+	}
+	return m
+}
+
+// appendToList appends a value to the list at the given path of the values,
+// creating the list if needed. The items of a list value are appended one by
+// one.
+func appendToList(vals map[string]interface{}, path string, value interface{}) error {
+	keys := parsePath(path)
+	t := vals
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := t[k].(map[string]interface{})
+		if !ok {
+			if t[k] != nil {
+				return errors.Errorf("%s is not a table", k)
+			}
+			next = map[string]interface{}{}
+			t[k] = next
+		}
+		t = next
+	}
+	key := keys[len(keys)-1]
+	list, ok := t[key].([]interface{})
+	if !ok && t[key] != nil {
+		return errors.Errorf("%s is not a list", key)
+	}
+	if items, ok := value.([]interface{}); ok {
+		list = append(list, items...)
+	} else {
+		list = append(list, value)
+	}
+	t[key] = list
 	return nil
 }
 
+func deepCopyValue(v interface{}) interface{} {
+	c, err := copystructure.Copy(v)
+	if err != nil {
+		return v
+	}
+	return c
+}
+
 func deepCopyMap(vals map[string]interface{}) map[string]interface{} {
 	valsCopy, err := copystructure.Copy(vals)
 	if err != nil {
@@ -17,6 +17,7 @@ package chartutil
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"testing"
@@ -116,7 +117,7 @@ func TestDependencyEnabled(t *testing.T) {
 	for _, tc := range tests {
 		c := loadChart(t, "testdata/subpop")
 		t.Run(tc.name, func(t *testing.T) {
-			if err := processDependencyEnabled(c, tc.v, ""); err != nil {
+			if err := processDependencyEnabled(c, tc.v, "", nil); err != nil {
 				t.Fatalf("error processing enabled dependencies %v", err)
 			}
 
@@ -329,7 +330,7 @@ func TestProcessDependencyImportValuesForEnabledCharts(t *testing.T) {
 		t.Fatalf("expected 2 dependencies for this chart, but got %d", len(c.Dependencies()))
 	}
 
-	if err := processDependencyEnabled(c, c.Values, ""); err != nil {
+	if err := processDependencyEnabled(c, c.Values, "", nil); err != nil {
 		t.Fatalf("expected no errors but got %q", err)
 	}
 
@@ -394,7 +395,7 @@ func TestDependentChartAliases(t *testing.T) {
 		t.Fatalf("expected 2 dependencies for this chart, but got %d", len(c.Dependencies()))
 	}
 
-	if err := processDependencyEnabled(c, c.Values, ""); err != nil {
+	if err := processDependencyEnabled(c, c.Values, "", nil); err != nil {
 		t.Fatalf("expected no errors but got %q", err)
 	}
 
@@ -433,7 +434,7 @@ func TestDependentChartWithSubChartsAbsentInDependency(t *testing.T) {
 		t.Fatalf("expected 2 dependencies for this chart, but got %d", len(c.Dependencies()))
 	}
 
-	if err := processDependencyEnabled(c, c.Values, ""); err != nil {
+	if err := processDependencyEnabled(c, c.Values, "", nil); err != nil {
 		t.Fatalf("expected no errors but got %q", err)
 	}
 
@@ -470,7 +471,7 @@ func TestDependentChartsWithSubchartsAllSpecifiedInDependency(t *testing.T) {
 		t.Fatalf("expected 2 dependencies for this chart, but got %d", len(c.Dependencies()))
 	}
 
-	if err := processDependencyEnabled(c, c.Values, ""); err != nil {
+	if err := processDependencyEnabled(c, c.Values, "", nil); err != nil {
 		t.Fatalf("expected no errors but got %q", err)
 	}
 
@@ -490,7 +491,7 @@ func TestDependentChartsWithSomeSubchartsSpecifiedInDependency(t *testing.T) {
 		t.Fatalf("expected 2 dependencies for this chart, but got %d", len(c.Dependencies()))
 	}
 
-	if err := processDependencyEnabled(c, c.Values, ""); err != nil {
+	if err := processDependencyEnabled(c, c.Values, "", nil); err != nil {
 		t.Fatalf("expected no errors but got %q", err)
 	}
 
@@ -502,3 +503,64 @@ func TestDependentChartsWithSomeSubchartsSpecifiedInDependency(t *testing.T) {
 		t.Fatalf("expected 1 dependency specified in Chart.yaml, got %d", len(c.Metadata.Dependencies))
 	}
 }
+
+func TestProcessDependencyImportValuesTransformations(t *testing.T) {
+	child := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "child", Version: "0.1.0", APIVersion: chart.APIVersionV2},
+		Values: map[string]interface{}{
+			"service": map[string]interface{}{"port": 8080, "name": "web"},
+			"sidecars": []interface{}{
+				map[string]interface{}{"name": "proxy"},
+				map[string]interface{}{"name": "logger"},
+			},
+		},
+	}
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "parent",
+			Version:    "0.1.0",
+			APIVersion: chart.APIVersionV2,
+			Dependencies: []*chart.Dependency{{
+				Name:    "child",
+				Version: "0.1.0",
+				ImportValues: []interface{}{
+					map[string]interface{}{"child": "service.port", "parent": "frontend.backendPort"},
+					map[string]interface{}{"child": "service.timeout", "parent": "frontend.timeout", "default": 30},
+					map[string]interface{}{"child": "service.missing", "parent": "frontend.missing"},
+					map[string]interface{}{"child": "sidecars", "parent": "extraContainers[]"},
+					map[string]interface{}{"child": "service.name", "parent": "frontend.services[]"},
+				},
+			}},
+		},
+		Values: map[string]interface{}{
+			"extraContainers": []interface{}{map[string]interface{}{"name": "main"}},
+		},
+	}
+	parent.AddDependency(child)
+
+	if err := processDependencyImportValues(parent, true); err != nil {
+		t.Fatal(err)
+	}
+	vals := Values(parent.Values)
+
+	for path, expected := range map[string]interface{}{
+		"frontend.backendPort": 8080,
+		"frontend.timeout":     30,
+	} {
+		if v, err := vals.PathValue(path); err != nil || v != expected {
+			t.Errorf("expected %v at %s, got %v (%v)", expected, path, v, err)
+		}
+	}
+	if _, err := vals.PathValue("frontend.missing"); err == nil {
+		t.Error("expected a value the child does not set to be left out")
+	}
+
+	containers := parent.Values["extraContainers"].([]interface{})
+	if len(containers) != 3 || containers[0].(map[string]interface{})["name"] != "main" || containers[2].(map[string]interface{})["name"] != "logger" {
+		t.Errorf("expected the sidecars to be appended to the containers of the parent, got %v", containers)
+	}
+	services := parent.Values["frontend"].(map[string]interface{})["services"]
+	if !reflect.DeepEqual(services, []interface{}{"web"}) {
+		t.Errorf("expected the service name to be imported into a new list, got %v", services)
+	}
+}
@@ -41,5 +41,18 @@ into a Chart.
 
 When creating charts in memory, use the 'helm.sh/helm/pkg/chart'
 package directly.
+
+To change a loaded chart, use 'AddTemplate', 'SetValues', 'BumpVersion',
+'SetDependency' and their siblings rather than editing its files. They
+validate the change and keep the chart consistent, so that it can be saved
+with 'SaveDir' or 'Save' without loading it again:
+
+	chart, err := loader.Load(dir)
+	...
+	err = chartutil.SetValue(chart, "image.tag", "1.2.0")
+	...
+	version, err := chartutil.BumpVersion(chart, chartutil.VersionMinor)
+	...
+	path, err := chartutil.Save(chart, outDir)
 */
 package chartutil // import "helm.sh/helm/v3/pkg/chartutil"
@@ -0,0 +1,213 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// The functions below change a loaded chart in place, keeping it consistent
+// and valid, so that it can be saved again with Save or SaveDir without
+// being loaded again.
+
+// AddTemplate adds a template to the chart, or replaces the template of the
+// same name. The name is the path of the template in the chart, e.g.
+// "templates/service.yaml".
+func AddTemplate(c *chart.Chart, name string, data []byte) error {
+	name, err := templateName(name)
+	if err != nil {
+		return err
+	}
+	for _, t := range c.Templates {
+		if t.Name == name {
+			t.Data = data
+			return nil
+		}
+	}
+	c.Templates = append(c.Templates, &chart.File{Name: name, Data: data})
+	return nil
+}
+
+// RemoveTemplate removes a template from the chart.
+func RemoveTemplate(c *chart.Chart, name string) error {
+	name, err := templateName(name)
+	if err != nil {
+		return err
+	}
+	for i, t := range c.Templates {
+		if t.Name == name {
+			c.Templates = append(c.Templates[:i], c.Templates[i+1:]...)
+			return nil
+		}
+	}
+	return errors.Errorf("chart %s has no template %s", c.Name(), name)
+}
+
+// templateName returns the clean name of a template, which must be in the
+// templates directory.
+func templateName(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if !strings.HasPrefix(clean, TemplatesDir+"/") {
+		return "", errors.Errorf("template %s is not in the %s directory", name, TemplatesDir)
+	}
+	return clean, nil
+}
+
+// SetValues replaces the default values of the chart. The values are
+// validated against the schema of the chart, if any. Comments of the values
+// file are not kept.
+func SetValues(c *chart.Chart, vals map[string]interface{}) error {
+	if vals == nil {
+		vals = map[string]interface{}{}
+	}
+	if c.Schema != nil {
+		if err := ValidateAgainstSingleSchema(vals, c.Schema); err != nil {
+			return errors.Wrapf(err, "values of chart %s", c.Name())
+		}
+	}
+	data, err := yaml.Marshal(vals)
+	if err != nil {
+		return errors.Wrapf(err, "cannot encode values of chart %s", c.Name())
+	}
+	c.Values = vals
+	for _, f := range c.Raw {
+		if f.Name == ValuesfileName {
+			f.Data = data
+			return nil
+		}
+	}
+	c.Raw = append(c.Raw, &chart.File{Name: ValuesfileName, Data: data})
+	return nil
+}
+
+// SetValue sets a default value of the chart at the given dotted path, e.g.
+// "image.tag", creating the tables on the way.
+func SetValue(c *chart.Chart, path string, value interface{}) error {
+	// Work on a copy, so that the chart is unchanged if the value is invalid.
+	vals, err := copyValues(c.Values)
+	if err != nil {
+		return err
+	}
+	keys := parsePath(path)
+	table := vals
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := table[k].(map[string]interface{})
+		if !ok {
+			if _, exists := table[k]; exists {
+				return errors.Errorf("cannot set %s: %s is not a table", path, k)
+			}
+			next = map[string]interface{}{}
+			table[k] = next
+		}
+		table = next
+	}
+	table[keys[len(keys)-1]] = value
+	return SetValues(c, vals)
+}
+
+// The parts of a version BumpVersion increments.
+const (
+	VersionMajor = "major"
+	VersionMinor = "minor"
+	VersionPatch = "patch"
+)
+
+// SetVersion sets the version of the chart, which must be a semantic version.
+func SetVersion(c *chart.Chart, version string) error {
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		return errors.Wrapf(err, "version %q of chart %s", version, c.Name())
+	}
+	c.Metadata.Version = version
+	return nil
+}
+
+// BumpVersion increments the given part of the version of the chart, one of
+// VersionMajor, VersionMinor or VersionPatch, and returns the new version.
+// Build metadata is dropped, and so is the pre-release, which makes the patch
+// of a pre-release its release, e.g. 1.2.3-rc.1 becomes 1.2.3.
+func BumpVersion(c *chart.Chart, part string) (string, error) {
+	v, err := semver.NewVersion(c.Metadata.Version)
+	if err != nil {
+		return "", errors.Wrapf(err, "version %q of chart %s", c.Metadata.Version, c.Name())
+	}
+	var next semver.Version
+	switch part {
+	case VersionMajor:
+		next = v.IncMajor()
+	case VersionMinor:
+		next = v.IncMinor()
+	case VersionPatch:
+		next = v.IncPatch()
+	default:
+		return "", errors.Errorf("cannot bump version part %q: must be one of %s, %s or %s", part, VersionMajor, VersionMinor, VersionPatch)
+	}
+	c.Metadata.Version = next.String()
+	return c.Metadata.Version, nil
+}
+
+// SetDependency adds a dependency to the chart, or replaces the dependency
+// of the same name, or alias if set. The lock of the dependencies of the
+// chart is dropped, as it no longer matches them; it is recreated by
+// 'helm dependency update'.
+func SetDependency(c *chart.Chart, dep *chart.Dependency) error {
+	if err := dep.Validate(); err != nil {
+		return err
+	}
+	if dep.Name == "" {
+		return errors.Errorf("dependency of chart %s has no name", c.Name())
+	}
+	if _, err := semver.NewConstraint(dep.Version); err != nil {
+		return errors.Wrapf(err, "version %q of dependency %s", dep.Version, dep.Name)
+	}
+	c.Lock = nil
+	for i, d := range c.Metadata.Dependencies {
+		if dependencyName(d) == dependencyName(dep) {
+			c.Metadata.Dependencies[i] = dep
+			return nil
+		}
+	}
+	c.Metadata.Dependencies = append(c.Metadata.Dependencies, dep)
+	return nil
+}
+
+// RemoveDependency removes the dependency of the given name, or alias, from
+// the chart, along with its lock.
+func RemoveDependency(c *chart.Chart, name string) error {
+	for i, d := range c.Metadata.Dependencies {
+		if dependencyName(d) == name {
+			c.Metadata.Dependencies = append(c.Metadata.Dependencies[:i], c.Metadata.Dependencies[i+1:]...)
+			c.Lock = nil
+			return nil
+		}
+	}
+	return errors.Errorf("chart %s has no dependency %s", c.Name(), name)
+}
+
+// dependencyName returns the name a dependency is known as in the chart.
+func dependencyName(d *chart.Dependency) string {
+	if d.Alias != "" {
+		return d.Alias
+	}
+	return d.Name
+}
@@ -0,0 +1,143 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+func TestMutateChart(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "web", Version: "1.2.3-rc.1"},
+		Templates: []*chart.File{
+			{Name: "templates/service.yaml", Data: []byte("kind: Service")},
+			{Name: "templates/obsolete.yaml", Data: []byte("kind: ConfigMap")},
+		},
+		Lock: &chart.Lock{Digest: "sha256:abc"},
+	}
+
+	if err := AddTemplate(c, "templates/./service.yaml", []byte("kind: Service\n# changed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddTemplate(c, "templates/deployment.yaml", []byte("kind: Deployment")); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddTemplate(c, "../deployment.yaml", nil); err == nil {
+		t.Error("expected an error for a template outside of the templates directory")
+	}
+	if err := RemoveTemplate(c, "templates/obsolete.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveTemplate(c, "templates/obsolete.yaml"); err == nil {
+		t.Error("expected an error removing a missing template")
+	}
+
+	if err := SetValues(c, map[string]interface{}{"replicas": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(c, "image.tag", "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(c, "replicas.max", 2); err == nil {
+		t.Error("expected an error setting a value below a scalar")
+	}
+
+	if v, err := BumpVersion(c, VersionPatch); err != nil || v != "1.2.3" {
+		t.Errorf("expected the patch of a pre-release to be its release, got %q, %v", v, err)
+	}
+	if v, err := BumpVersion(c, VersionMinor); err != nil || v != "1.3.0" {
+		t.Errorf("expected version 1.3.0, got %q, %v", v, err)
+	}
+	if _, err := BumpVersion(c, "micro"); err == nil {
+		t.Error("expected an error for an unknown version part")
+	}
+	if err := SetVersion(c, "v2"); err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+
+	if err := SetDependency(c, &chart.Dependency{Name: "db", Version: "^1.0", Repository: "https://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetDependency(c, &chart.Dependency{Name: "db", Version: "^2.0", Repository: "https://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetDependency(c, &chart.Dependency{Name: "cache", Version: "not a version"}); err == nil {
+		t.Error("expected an error for an invalid version constraint")
+	}
+	if c.Lock != nil {
+		t.Error("expected the lock to be dropped")
+	}
+
+	// The chart is saved as changed.
+	tmp := t.TempDir()
+	if err := SaveDir(c, tmp); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loader.Load(tmp + "/web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Metadata.Version != "1.3.0" {
+		t.Errorf("expected version 1.3.0, got %s", saved.Metadata.Version)
+	}
+	expectedValues := map[string]interface{}{
+		"replicas": float64(1),
+		"image":    map[string]interface{}{"tag": "1.0"},
+	}
+	if !reflect.DeepEqual(saved.Values, expectedValues) {
+		t.Errorf("expected values %v, got %v", expectedValues, saved.Values)
+	}
+	templates := map[string]string{}
+	for _, f := range saved.Templates {
+		templates[f.Name] = string(f.Data)
+	}
+	expectedTemplates := map[string]string{
+		"templates/service.yaml":    "kind: Service\n# changed",
+		"templates/deployment.yaml": "kind: Deployment",
+	}
+	if !reflect.DeepEqual(templates, expectedTemplates) {
+		t.Errorf("expected templates %v, got %v", expectedTemplates, templates)
+	}
+	if len(saved.Metadata.Dependencies) != 1 || saved.Metadata.Dependencies[0].Version != "^2.0" {
+		t.Errorf("expected the replaced dependency, got %v", saved.Metadata.Dependencies)
+	}
+
+	if err := RemoveDependency(c, "db"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Metadata.Dependencies) != 0 {
+		t.Errorf("expected no dependencies, got %v", c.Metadata.Dependencies)
+	}
+}
+
+func TestSetValuesSchema(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "web", Version: "1.0.0"},
+		Schema:   []byte(`{"properties": {"replicas": {"type": "integer"}}}`),
+		Values:   map[string]interface{}{"replicas": 1},
+	}
+	if err := SetValue(c, "replicas", "many"); err == nil {
+		t.Fatal("expected values not matching the schema to be rejected")
+	}
+	if c.Values["replicas"] != 1 {
+		t.Errorf("expected the values to be unchanged, got %v", c.Values)
+	}
+}
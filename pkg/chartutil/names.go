@@ -0,0 +1,112 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// nameHashLen is the number of hex digits of the hash TruncHash appends to
+// truncated identifiers.
+const nameHashLen = 8
+
+// K8sName turns s into a DNS-1123 label, the name most Kubernetes resources
+// require: invalid characters are replaced by dashes, upper case letters are
+// lowered, and names longer than 63 characters are truncated by TruncHash.
+func K8sName(s string) (string, error) {
+	name := sanitizeName(strings.ToLower(s), isDNS1123Char, "-")
+	name, err := TruncHash(validation.DNS1123LabelMaxLength, name)
+	if err != nil {
+		return "", err
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return "", errors.Errorf("%q cannot be made a valid name: %s", s, strings.Join(errs, "; "))
+	}
+	return name, nil
+}
+
+// DNS1123Subdomain turns s into a DNS-1123 subdomain like K8sName does into a
+// label, keeping dots, for names of up to 253 characters.
+func DNS1123Subdomain(s string) (string, error) {
+	name := sanitizeName(strings.ToLower(s), func(r rune) bool { return isDNS1123Char(r) || r == '.' }, "-.")
+	name, err := TruncHash(validation.DNS1123SubdomainMaxLength, name)
+	if err != nil {
+		return "", err
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", errors.Errorf("%q cannot be made a valid name: %s", s, strings.Join(errs, "; "))
+	}
+	return name, nil
+}
+
+// LabelValue turns s into a valid label value: characters other than letters,
+// digits, dashes, underscores and dots are replaced by dashes, dashes,
+// underscores and dots are trimmed from both ends, and values longer than 63
+// characters are truncated by TruncHash. Unlike names, label values may be
+// empty.
+func LabelValue(s string) string {
+	isLabelChar := func(r rune) bool {
+		return isDNS1123Char(r) || r >= 'A' && r <= 'Z' || r == '_' || r == '.'
+	}
+	// The maximum length leaves room for the hash, so this cannot fail.
+	value, _ := TruncHash(validation.LabelValueMaxLength, sanitizeName(s, isLabelChar, "-_."))
+	return value
+}
+
+// TruncHash truncates s to at most max characters. Unlike a plain truncation,
+// the end of a truncated s is replaced by a dash and a hash of all of s, so
+// different long identifiers stay different, and separators are not left at
+// the end.
+func TruncHash(max int, s string) (string, error) {
+	if len(s) <= max {
+		return s, nil
+	}
+	if max <= nameHashLen+1 {
+		return "", errors.Errorf("length must be greater than %d, got %d", nameHashLen+1, max)
+	}
+	sum := sha256.Sum256([]byte(s))
+	prefix := strings.TrimRight(s[:max-nameHashLen-1], "-_.")
+	return prefix + "-" + hex.EncodeToString(sum[:])[:nameHashLen], nil
+}
+
+// sanitizeName replaces the runs of characters of s that are not valid by a
+// dash, and trims the given separators from both ends.
+func sanitizeName(s string, valid func(rune) bool, separators string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if valid(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.Trim(b.String(), separators)
+}
+
+func isDNS1123Char(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-'
+}
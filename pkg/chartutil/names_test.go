@@ -0,0 +1,54 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestLabelValue(t *testing.T) {
+	for in, expected := range map[string]string{
+		"hello-1.0.0+build.1":           "hello-1.0.0-build.1",
+		"a-" + strings.Repeat("_", 70):  "a",
+		"_leading.and.trailing-":        "leading.and.trailing",
+		"+1.0.0":                        "1.0.0",
+		"":                              "",
+		"Chart_Name":                    "Chart_Name",
+		"name with spaces/and/slashes!": "name-with-spaces-and-slashes",
+	} {
+		assert.Equal(t, expected, LabelValue(in), in)
+	}
+
+	long := LabelValue(strings.Repeat("a", 70))
+	assert.Len(t, long, 63)
+	assert.Empty(t, validation.IsValidLabelValue(long))
+	assert.NotEqual(t, long, LabelValue(strings.Repeat("a", 71)), "truncated values keep a hash of the whole value")
+}
+
+func TestTruncHash(t *testing.T) {
+	got, err := TruncHash(20, "a-very-long-identifier-indeed")
+	assert.NoError(t, err)
+	assert.Len(t, got, 20)
+	assert.Equal(t, "a-very-long-", got[:12])
+
+	_, err = TruncHash(9, "a-very-long-identifier")
+	assert.EqualError(t, err, "length must be greater than 9, got 9")
+}
@@ -21,6 +21,7 @@ import (
 	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -56,10 +57,10 @@ func SaveDir(c *chart.Chart, dest string) error {
 		return err
 	}
 
-	// Save values.yaml
+	// Save values.yaml and values-migrations.yaml
 	for _, f := range c.Raw {
-		if f.Name == ValuesfileName {
-			vf := filepath.Join(outdir, ValuesfileName)
+		if f.Name == ValuesfileName || f.Name == ValuesMigrationsfileName {
+			vf := filepath.Join(outdir, f.Name)
 			if err := writeFile(vf, f.Data); err != nil {
 				return err
 			}
@@ -128,28 +129,42 @@ func Save(c *chart.Chart, outDir string) (string, error) {
 		return "", err
 	}
 
+	if err := writeArchive(f, c); err != nil {
+		f.Close()
+		os.Remove(filename)
+		return filename, err
+	}
+	return filename, f.Close()
+}
+
+// SaveArchive writes a chart as a gzipped tar archive to w.
+//
+// The archive has the same layout as the one written by Save.
+func SaveArchive(c *chart.Chart, w io.Writer) error {
+	if err := c.Validate(); err != nil {
+		return errors.Wrap(err, "chart validation")
+	}
+	return writeArchive(w, c)
+}
+
+func writeArchive(w io.Writer, c *chart.Chart) error {
 	// Wrap in gzip writer
-	zipper := gzip.NewWriter(f)
+	zipper := gzip.NewWriter(w)
 	zipper.Header.Extra = headerBytes
 	zipper.Header.Comment = "Helm"
 
 	// Wrap in tar writer
 	twriter := tar.NewWriter(zipper)
-	rollback := false
-	defer func() {
+	if err := writeTarContents(twriter, c, ""); err != nil {
 		twriter.Close()
 		zipper.Close()
-		f.Close()
-		if rollback {
-			os.Remove(filename)
-		}
-	}()
-
-	if err := writeTarContents(twriter, c, ""); err != nil {
-		rollback = true
-		return filename, err
+		return err
+	}
+	if err := twriter.Close(); err != nil {
+		zipper.Close()
+		return err
 	}
-	return filename, nil
+	return zipper.Close()
 }
 
 func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
@@ -191,10 +206,10 @@ func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
 		}
 	}
 
-	// Save values.yaml
+	// Save values.yaml and values-migrations.yaml
 	for _, f := range c.Raw {
-		if f.Name == ValuesfileName {
-			if err := writeToTar(out, filepath.Join(base, ValuesfileName), f.Data); err != nil {
+		if f.Name == ValuesfileName || f.Name == ValuesMigrationsfileName {
+			if err := writeToTar(out, filepath.Join(base, f.Name), f.Data); err != nil {
 				return err
 			}
 		}
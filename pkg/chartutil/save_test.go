@@ -228,6 +228,9 @@ func TestSaveDir(t *testing.T) {
 		Templates: []*chart.File{
 			{Name: path.Join(TemplatesDir, "nested", "dir", "thing.yaml"), Data: []byte("abc: {{ .Values.abc }}")},
 		},
+		Raw: []*chart.File{
+			{Name: ValuesMigrationsfileName, Data: []byte("migrations:\n- from: old\n  to: abc\n")},
+		},
 	}
 
 	if err := SaveDir(c, tmp); err != nil {
@@ -251,6 +254,10 @@ func TestSaveDir(t *testing.T) {
 		t.Fatal("Files data did not match")
 	}
 
+	if len(c2.ValuesMigrations) != 1 || c2.ValuesMigrations[0].To != "abc" {
+		t.Fatal("Values migrations did not match")
+	}
+
 	tmp2 := t.TempDir()
 	c.Metadata.Name = "../ahab"
 	pth := filepath.Join(tmp2, "tmpcharts")
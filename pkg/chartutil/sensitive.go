@@ -0,0 +1,105 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// SensitiveKeyword marks the values that are sensitive in the schema of a
+// chart, e.g.
+//
+//	"password": {"type": "string", "x-helm-sensitive": true}
+//
+// Sensitive values are stored apart from the records of releases where the
+// storage keeps them confidential, see storage.Storage.Sensitive, and masked
+// when the values of a release are shown.
+const SensitiveKeyword = "x-helm-sensitive"
+
+// SensitiveValues returns the paths, as dot-separated keys, of the values the
+// schemas of the chart and its subcharts mark as sensitive. The paths of the
+// values of subcharts are prefixed with their name.
+func SensitiveValues(ch *chart.Chart) ([]string, error) {
+	var paths []string
+	if ch == nil {
+		return paths, nil
+	}
+	if len(ch.Schema) > 0 {
+		var schema map[string]interface{}
+		if err := json.Unmarshal(ch.Schema, &schema); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse the values schema of chart %s", ch.Name())
+		}
+		paths = appendSensitive(paths, "", schema)
+	}
+	for _, sub := range ch.Dependencies() {
+		subPaths, err := SensitiveValues(sub)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range subPaths {
+			paths = append(paths, sub.Name()+"."+p)
+		}
+	}
+	sort.Strings(paths)
+	return compact(paths), nil
+}
+
+// compact removes the repeated paths of sorted paths, e.g. paths marked
+// sensitive in several subschemas.
+func compact(paths []string) []string {
+	out := paths[:0]
+	for i, p := range paths {
+		if i == 0 || p != paths[i-1] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// appendSensitive appends the paths of the sensitive properties of the schema
+// at path.
+func appendSensitive(paths []string, path string, schema map[string]interface{}) []string {
+	if sensitive, _ := schema[SensitiveKeyword].(bool); sensitive && path != "" {
+		return append(paths, path)
+	}
+	if props, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, prop := range props {
+			if sub, ok := prop.(map[string]interface{}); ok {
+				p := name
+				if path != "" {
+					p = path + "." + name
+				}
+				paths = appendSensitive(paths, p, sub)
+			}
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if subs, ok := schema[keyword].([]interface{}); ok {
+			for _, s := range subs {
+				if sub, ok := s.(map[string]interface{}); ok {
+					paths = appendSensitive(paths, path, sub)
+				}
+			}
+		}
+	}
+	return paths
+}
@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestSensitiveValues(t *testing.T) {
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "cache"},
+		Schema:   []byte(`{"properties": {"token": {"type": "string", "x-helm-sensitive": true}}}`),
+	}
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app"},
+		Schema: []byte(`{
+  "properties": {
+    "image": {"type": "string"},
+    "db": {
+      "properties": {
+        "user": {"type": "string"},
+        "password": {"type": "string", "x-helm-sensitive": true}
+      }
+    },
+    "tls": {"type": "object", "x-helm-sensitive": true}
+  },
+  "allOf": [{"properties": {"db": {"properties": {"password": {"x-helm-sensitive": true}}}}}]
+}`),
+	}
+	ch.AddDependency(sub)
+
+	paths, err := SensitiveValues(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cache.token", "db.password", "tls"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected %v, got %v", want, paths)
+	}
+
+	paths, err = SensitiveValues(&chart.Chart{Metadata: &chart.Metadata{Name: "plain"}})
+	if err != nil || len(paths) != 0 {
+		t.Errorf("Expected no sensitive values, got %v, %v", paths, err)
+	}
+
+	if _, err := SensitiveValues(&chart.Chart{Metadata: &chart.Metadata{Name: "bad"}, Schema: []byte("{")}); err == nil {
+		t.Error("Expected an error for an invalid schema")
+	}
+}
@@ -0,0 +1,316 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// maxSkeletonDepth bounds the nesting of a values skeleton, which guards
+// against recursive references in the schema.
+const maxSkeletonDepth = 32
+
+// skeletonSchema is the part of a JSON schema describing values that is
+// used to generate a values skeleton.
+type skeletonSchema struct {
+	Ref         string                     `json:"$ref"`
+	Type        interface{}                `json:"type"`
+	Title       string                     `json:"title"`
+	Description string                     `json:"description"`
+	Default     interface{}                `json:"default"`
+	Enum        []interface{}              `json:"enum"`
+	Required    []string                   `json:"required"`
+	Properties  skeletonProperties         `json:"properties"`
+	Items       *skeletonSchema            `json:"items"`
+	Definitions map[string]*skeletonSchema `json:"definitions"`
+	Defs        map[string]*skeletonSchema `json:"$defs"`
+}
+
+// skeletonProperty is a property of an object schema.
+type skeletonProperty struct {
+	Name   string
+	Schema *skeletonSchema
+}
+
+// skeletonProperties keeps the properties in the order of the schema, so
+// that the skeleton follows the order chosen by the chart author.
+type skeletonProperties []skeletonProperty
+
+func (p *skeletonProperties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return errors.New("properties must be an object")
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		s := &skeletonSchema{}
+		if err := dec.Decode(s); err != nil {
+			return errors.Wrapf(err, "invalid schema of property %v", t)
+		}
+		*p = append(*p, skeletonProperty{Name: t.(string), Schema: s})
+	}
+	return nil
+}
+
+// GenerateValuesSkeleton generates a values file from the JSON schema of the
+// values of a chart. Each value is preceded by comments giving its
+// description, type and whether it is required. Values are set to their
+// defaults; optional values without a default are commented out, and
+// required ones are set to the zero value of their type.
+func GenerateValuesSkeleton(schemaJSON []byte) ([]byte, error) {
+	root := &skeletonSchema{}
+	if err := json.Unmarshal(schemaJSON, root); err != nil {
+		return nil, errors.Wrap(err, "unable to parse values schema")
+	}
+	g := &skeletonGenerator{root: root}
+	s, err := g.resolve(root)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	if d, ok := s.Default.(map[string]interface{}); ok || s.Default == nil {
+		lines, _, err = g.properties(s, d, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+type skeletonGenerator struct {
+	root *skeletonSchema
+}
+
+// resolve follows the local reference of a schema.
+func (g *skeletonGenerator) resolve(s *skeletonSchema) (*skeletonSchema, error) {
+	for i := 0; s.Ref != ""; i++ {
+		if i == maxSkeletonDepth {
+			return nil, errors.Errorf("too many references resolving %q", s.Ref)
+		}
+		var defs map[string]*skeletonSchema
+		var name string
+		switch {
+		case strings.HasPrefix(s.Ref, "#/definitions/"):
+			defs, name = g.root.Definitions, strings.TrimPrefix(s.Ref, "#/definitions/")
+		case strings.HasPrefix(s.Ref, "#/$defs/"):
+			defs, name = g.root.Defs, strings.TrimPrefix(s.Ref, "#/$defs/")
+		default:
+			return nil, errors.Errorf("unsupported reference %q, only local definitions are supported", s.Ref)
+		}
+		def, ok := defs[name]
+		if !ok {
+			return nil, errors.Errorf("reference %q not found", s.Ref)
+		}
+		// Annotations next to a reference take precedence over the
+		// definition.
+		resolved := *def
+		if s.Description != "" {
+			resolved.Description = s.Description
+		}
+		if s.Default != nil {
+			resolved.Default = s.Default
+		}
+		s = &resolved
+	}
+	return s, nil
+}
+
+// properties returns the lines of the properties of an object schema, and
+// whether any of them is set rather than commented out.
+func (g *skeletonGenerator) properties(s *skeletonSchema, defaults map[string]interface{}, depth int) ([]string, bool, error) {
+	if depth == maxSkeletonDepth {
+		return nil, false, errors.New("values schema is nested too deeply")
+	}
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+
+	var lines []string
+	set := false
+	for i, p := range s.Properties {
+		ps, err := g.resolve(p.Schema)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "property %s", p.Name)
+		}
+		def := ps.Default
+		if v, ok := defaults[p.Name]; ok && def == nil {
+			def = v
+		}
+		block, blockSet, err := g.property(p.Name, ps, def, required[p.Name], depth)
+		if err != nil {
+			return nil, false, err
+		}
+		if i > 0 && depth == 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+		set = set || blockSet
+	}
+	return lines, set, nil
+}
+
+// property returns the lines of a property, and whether it is set rather
+// than commented out.
+func (g *skeletonGenerator) property(name string, s *skeletonSchema, def interface{}, required bool, depth int) ([]string, bool, error) {
+	indent := strings.Repeat("  ", depth)
+	lines := skeletonComments(s, required, indent)
+
+	types := skeletonTypes(s)
+	if len(s.Properties) > 0 && (def == nil || isMap(def)) {
+		defaults, _ := def.(map[string]interface{})
+		children, set, err := g.properties(s, defaults, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		if set || required || def != nil {
+			lines = append(lines, indent+skeletonKey(name)+":")
+			return append(lines, children...), true, nil
+		}
+		// Without any value set the object would be null, so it is
+		// commented out as a whole.
+		lines = append(lines, indent+"# "+skeletonKey(name)+":")
+		for _, c := range children {
+			lines = append(lines, indent+"# "+strings.TrimPrefix(c, indent))
+		}
+		return lines, false, nil
+	}
+
+	value, set := def, true
+	if value == nil {
+		value, set = skeletonZero(types), required
+	}
+	out, err := yaml.Marshal(map[string]interface{}{name: value})
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "unable to write the default of %s", name)
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		if set {
+			lines = append(lines, indent+l)
+		} else {
+			lines = append(lines, indent+"# "+l)
+		}
+	}
+	return lines, set, nil
+}
+
+// skeletonComments returns the comments describing a value.
+func skeletonComments(s *skeletonSchema, required bool, indent string) []string {
+	var lines []string
+	description := s.Description
+	if description == "" {
+		description = s.Title
+	}
+	for _, l := range strings.Split(strings.TrimSpace(description), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, indent+"# "+l)
+		}
+	}
+
+	var notes []string
+	if types := skeletonTypes(s); len(types) > 0 {
+		t := strings.Join(types, " or ")
+		if s.Items != nil {
+			if items := skeletonTypes(s.Items); len(items) > 0 && t == "array" {
+				t = "array of " + strings.Join(items, " or ")
+			}
+		}
+		notes = append(notes, t)
+	}
+	if required {
+		notes = append(notes, "required")
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		notes = append(notes, "one of: "+strings.Join(values, ", "))
+	}
+	if len(notes) > 0 {
+		lines = append(lines, indent+"# ("+strings.Join(notes, ", ")+")")
+	}
+	return lines
+}
+
+// skeletonTypes returns the types of a schema, inferring an object from its
+// properties.
+func skeletonTypes(s *skeletonSchema) []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, v := range t {
+			if v, ok := v.(string); ok {
+				types = append(types, v)
+			}
+		}
+		sort.SliceStable(types, func(i, j int) bool { return types[j] == "null" && types[i] != "null" })
+		return types
+	}
+	if len(s.Properties) > 0 {
+		return []string{"object"}
+	}
+	return nil
+}
+
+// skeletonZero returns the zero value of the first type that is not null.
+func skeletonZero(types []string) interface{} {
+	for _, t := range types {
+		switch t {
+		case "string":
+			return ""
+		case "integer", "number":
+			return 0
+		case "boolean":
+			return false
+		case "array":
+			return []interface{}{}
+		case "object":
+			return map[string]interface{}{}
+		}
+	}
+	return nil
+}
+
+// skeletonKey quotes keys that YAML would not read as the same string.
+func skeletonKey(name string) string {
+	out, err := yaml.Marshal(map[string]interface{}{name: nil})
+	if err != nil {
+		return name
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(out), "\n"), ": null")
+}
+
+func isMap(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
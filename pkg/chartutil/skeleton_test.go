@@ -0,0 +1,113 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"testing"
+)
+
+func TestGenerateValuesSkeleton(t *testing.T) {
+	schema := `{
+  "$schema": "https://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicaCount": {"type": "integer", "description": "Number of replicas", "default": 1},
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {
+        "repository": {"type": "string", "description": "The image repository"},
+        "tag": {"type": "string"},
+        "pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"], "default": "IfNotPresent"}
+      }
+    },
+    "ingress": {
+      "type": "object",
+      "properties": {
+        "hosts": {"type": "array", "items": {"type": "string"}},
+        "annotations": {"$ref": "#/definitions/annotations"}
+      }
+    },
+    "resources": {"type": ["object", "null"], "default": {"limits": {"cpu": "100m"}}},
+    "node.selector": {"type": "string", "title": "Node selector"}
+  },
+  "definitions": {"annotations": {"type": "object", "description": "Annotations to add"}}
+}`
+
+	expect := `# Number of replicas
+# (integer)
+replicaCount: 1
+
+# (object, required)
+image:
+  # The image repository
+  # (string, required)
+  repository: ""
+  # (string)
+  # tag: ""
+  # (string, one of: Always, IfNotPresent)
+  pullPolicy: IfNotPresent
+
+# (object)
+# ingress:
+#   # (array of string)
+#   # hosts: []
+#   # Annotations to add
+#   # (object)
+#   # annotations: {}
+
+# (object or null)
+resources:
+  limits:
+    cpu: 100m
+
+# Node selector
+# (string)
+# node.selector: ""
+`
+
+	skeleton, err := GenerateValuesSkeleton([]byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(skeleton) != expect {
+		t.Errorf("Expected\n%s\nGot\n%s", expect, skeleton)
+	}
+
+	// The skeleton must be valid against the schema it was generated from.
+	values, err := ReadValues(skeleton)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateAgainstSingleSchema(values, []byte(schema)); err != nil {
+		t.Errorf("skeleton is not valid against its schema: %s", err)
+	}
+}
+
+func TestGenerateValuesSkeletonErrors(t *testing.T) {
+	for _, schema := range []string{
+		`{"properties": []}`,
+		`{"properties": {"a": {"$ref": "#/definitions/missing"}}}`,
+		`{"properties": {"a": {"$ref": "https://example.com/schema.json"}}}`,
+		`{"properties": {"a": {"$ref": "#/definitions/a"}}, "definitions": {"a": {"$ref": "#/definitions/a"}}}`,
+	} {
+		if _, err := GenerateValuesSkeleton([]byte(schema)); err == nil {
+			t.Errorf("expected an error for schema %s", schema)
+		}
+	}
+}
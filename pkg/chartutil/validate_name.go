@@ -21,6 +21,8 @@ import (
 	"regexp"
 
 	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/errcode"
 )
 
 // validName is a regular expression for resource names.
@@ -40,9 +42,7 @@ var (
 	errMissingName = errors.New("no name provided")
 
 	// errInvalidName indicates that an invalid release name was provided
-	errInvalidName = fmt.Errorf(
-		"invalid release name, must match regex %s and the length must not be longer than 53",
-		validName.String())
+	errInvalidName error = errcode.New(errcode.InvalidReleaseName, validName.String())
 
 	// errInvalidKubernetesName indicates that the name does not meet the Kubernetes
 	// restrictions on metadata names.
@@ -17,7 +17,6 @@ limitations under the License.
 package chartutil
 
 import (
-	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -26,6 +25,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/errcode"
 )
 
 // GlobalKey is the name of the Values key that is used for storing global vars.
@@ -157,8 +157,7 @@ func ToRenderValues(chrt *chart.Chart, chrtVals map[string]interface{}, options
 	}
 
 	if err := ValidateAgainstSchema(chrt, vals); err != nil {
-		errFmt := "values don't meet the specifications of the schema(s) in the following chart(s):\n%s"
-		return top, fmt.Errorf(errFmt, err.Error())
+		return top, errcode.New(errcode.InvalidValues, err.Error())
 	}
 
 	top["Values"] = vals
@@ -0,0 +1,206 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ValueDoc documents a value of a chart.
+type ValueDoc struct {
+	// Key is the path to the value, its keys joined by dots.
+	Key string
+	// Type is the type of the value, as given by the schema of the values or
+	// else by the type of its default.
+	Type string
+	// Default is the default of the value as JSON, or the text given by an
+	// @default comment.
+	Default string
+	// Description is the description given by the comment above the value, or
+	// else by the schema of the values.
+	Description string
+}
+
+// ValuesDocs documents the values of a chart from the comments of its
+// values.yaml and its values.schema.json, which may be nil.
+//
+// The comment directly above a key describes it. When the comment has a line
+// starting with "-- ", the description starts there and the lines above are
+// ignored, as helm-docs does. A line starting with "@default -- " replaces the
+// default shown for the value. Maps are documented value by value, unless they
+// are empty or described themselves.
+func ValuesDocs(values, schema []byte) ([]ValueDoc, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(values, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing values.yaml")
+	}
+	var s *valuesSchema
+	if len(schema) > 0 {
+		s = &valuesSchema{}
+		if err := json.Unmarshal(schema, s); err != nil {
+			return nil, errors.Wrap(err, "parsing values.schema.json")
+		}
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return nil, errors.New("values.yaml is not a map")
+	}
+	var docs []ValueDoc
+	if err := documentValues(&docs, "", root, s); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// valuesSchema is the part of a JSON schema describing values.
+type valuesSchema struct {
+	Type        interface{}             `json:"type"`
+	Description string                  `json:"description"`
+	Properties  map[string]valuesSchema `json:"properties"`
+}
+
+func (s *valuesSchema) property(name string) *valuesSchema {
+	if s == nil {
+		return nil
+	}
+	p, ok := s.Properties[name]
+	if !ok {
+		return nil
+	}
+	return &p
+}
+
+// typeName returns the type of the schema, or the types it allows joined by
+// " or ".
+func (s *valuesSchema) typeName() string {
+	if s == nil {
+		return ""
+	}
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []interface{}:
+		var types []string
+		for _, v := range t {
+			types = append(types, fmt.Sprint(v))
+		}
+		return strings.Join(types, " or ")
+	}
+	return ""
+}
+
+func documentValues(docs *[]ValueDoc, prefix string, m *yamlv3.Node, s *valuesSchema) error {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], m.Content[i+1]
+		key := prefix + k.Value
+		ps := s.property(k.Value)
+		description, def := parseValueComment(k.HeadComment)
+		if v.Kind == yamlv3.MappingNode && len(v.Content) > 0 && description == "" {
+			if err := documentValues(docs, key+".", v, ps); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if description == "" && ps != nil {
+			description = ps.Description
+		}
+		if def == "" {
+			var value interface{}
+			if err := v.Decode(&value); err != nil {
+				return errors.Wrapf(err, "decoding the default of %s", key)
+			}
+			b, err := json.Marshal(value)
+			if err != nil {
+				return errors.Wrapf(err, "encoding the default of %s", key)
+			}
+			def = string(b)
+		}
+		typ := ps.typeName()
+		if typ == "" {
+			typ = nodeType(v)
+		}
+		*docs = append(*docs, ValueDoc{Key: key, Type: typ, Default: def, Description: description})
+	}
+	return nil
+}
+
+// parseValueComment returns the description and the @default text of a
+// comment above a value.
+func parseValueComment(comment string) (description, def string) {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+		switch {
+		case strings.HasPrefix(line, "@default -- "):
+			def = strings.TrimPrefix(line, "@default -- ")
+		case line == "--" || strings.HasPrefix(line, "-- "):
+			lines = []string{strings.TrimSpace(strings.TrimPrefix(line, "--"))}
+		case line != "":
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " "), def
+}
+
+// nodeType returns the type of the value of a node, as named by JSON schemas.
+func nodeType(n *yamlv3.Node) string {
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		return "object"
+	case yamlv3.SequenceNode:
+		return "array"
+	case yamlv3.AliasNode:
+		return nodeType(n.Alias)
+	}
+	switch n.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+// ValuesTable formats the documentation of values as a Markdown table.
+func ValuesTable(docs []ValueDoc) string {
+	var b strings.Builder
+	b.WriteString("| Key | Type | Default | Description |\n")
+	b.WriteString("|-----|------|---------|-------------|\n")
+	for _, d := range docs {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+			markdownCell(d.Key), markdownCell(d.Type), "`"+markdownCell(d.Default)+"`", markdownCell(d.Description))
+	}
+	return b.String()
+}
+
+// markdownCell escapes text to be written in a cell of a Markdown table.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
@@ -0,0 +1,79 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuesDocs(t *testing.T) {
+	values := []byte(`# Default values for the chart.
+
+# -- number of replicas
+replicaCount: 1
+
+image:
+  # the repository
+  # of the image
+  repository: nginx
+  # @default -- the appVersion of the chart
+  tag: ""
+
+# Ignored.
+# -- labels added to all resources
+labels: {}
+
+# -- settings of the probes
+probes:
+  liveness: true
+
+ports:
+  - 80
+timeout:
+`)
+	schema := []byte(`{
+  "properties": {
+    "image": {"properties": {"repository": {"type": "string", "description": "ignored"}}},
+    "timeout": {"type": ["string", "null"], "description": "how long to wait"}
+  }
+}`)
+
+	docs, err := ValuesDocs(values, schema)
+	require.NoError(t, err)
+	assert.Equal(t, []ValueDoc{
+		{Key: "replicaCount", Type: "integer", Default: "1", Description: "number of replicas"},
+		{Key: "image.repository", Type: "string", Default: `"nginx"`, Description: "the repository of the image"},
+		{Key: "image.tag", Type: "string", Default: "the appVersion of the chart"},
+		{Key: "labels", Type: "object", Default: "{}", Description: "labels added to all resources"},
+		{Key: "probes", Type: "object", Default: `{"liveness":true}`, Description: "settings of the probes"},
+		{Key: "ports", Type: "array", Default: "[80]"},
+		{Key: "timeout", Type: "string or null", Default: "null", Description: "how long to wait"},
+	}, docs)
+
+	_, err = ValuesDocs([]byte("- a\n"), nil)
+	assert.EqualError(t, err, "values.yaml is not a map")
+}
+
+func TestValuesTable(t *testing.T) {
+	table := ValuesTable([]ValueDoc{{Key: "sep", Type: "string", Default: `"a|b"`, Description: "the separator"}})
+	assert.Equal(t, "| Key | Type | Default | Description |\n"+
+		"|-----|------|---------|-------------|\n"+
+		"| sep | string | `\"a\\|b\"` | the separator |\n", table)
+}
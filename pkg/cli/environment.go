@@ -29,6 +29,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -47,6 +48,18 @@ const defaultBurstLimit = 100
 // defaultQPS sets the default QPS value to 0 to use library defaults unless specified
 const defaultQPS = float32(0)
 
+// defaultMaxIncludeDepth sets how deeply include and tpl calls may nest in templates
+const defaultMaxIncludeDepth = 1000
+
+// defaultMaxNotesSize and defaultMaxManifestSize set no size above which notes
+// and manifests are stored apart from the records of releases: versions of
+// Helm and tools that do not know about it would read such records as having
+// no notes or manifest
+const (
+	defaultMaxNotesSize    = 0
+	defaultMaxManifestSize = 0
+)
+
 // EnvSettings describes all of the environment settings.
 type EnvSettings struct {
 	namespace string
@@ -82,12 +95,89 @@ type EnvSettings struct {
 	RepositoryCache string
 	// PluginsDirectory is the path to the plugins directory.
 	PluginsDirectory string
+	// PluginSandbox runs all plugins in a sandbox, even those not declaring
+	// one.
+	PluginSandbox bool
 	// MaxHistory is the max release history maintained.
 	MaxHistory int
 	// BurstLimit is the default client-side throttling limit.
 	BurstLimit int
 	// QPS is queries per second which may be used to avoid throttling.
 	QPS float32
+	// WaitStatusMappings is a file, or a ConfigMap given as
+	// "configmap:<namespace>/<name>", defining the readiness of custom
+	// resources when waiting.
+	WaitStatusMappings string
+	// WebhooksConfig is the path to the file configuring the webhooks notified
+	// of release events.
+	WebhooksConfig string
+	// AuditLog is where Helm operations are recorded: "file:<path>",
+	// "configmap", "secret" or "sql". Operations are not recorded if empty.
+	AuditLog string
+	// TrustPolicy is the path to the file defining how charts must be
+	// verified per repository or registry.
+	TrustPolicy string
+	// FreezePolicy is a file, or a ConfigMap given as
+	// "configmap:<namespace>/<name>", defining the freeze windows during
+	// which releases must not be changed.
+	FreezePolicy string
+	// MetadataPolicy is the path to the file defining how the labels and
+	// annotations of the resources of releases are normalized.
+	MetadataPolicy string
+	// NamePolicy is the path to the file defining the naming conventions of
+	// new releases and how names are generated.
+	NamePolicy string
+	// HookImagePolicy is the path to the file defining who must have signed
+	// the images of hooks before they are run.
+	HookImagePolicy string
+	// SensitiveSecrets keeps the sensitive values of releases in Secrets
+	// when the storage driver is not the secret driver.
+	SensitiveSecrets bool
+	// ExternalHookCommands and ExternalHookHosts are the patterns of the
+	// commands the hooks of charts may run, and of the hosts they may send
+	// requests to, from the machine running Helm.
+	ExternalHookCommands []string
+	ExternalHookHosts    []string
+	// HookServiceAccount runs the pods of hooks as a ServiceAccount created
+	// for them with the permissions the chart declares.
+	HookServiceAccount bool
+	// HookParallelism is how many hooks of the same weight run at the same
+	// time.
+	HookParallelism int
+	// HookLogs prints the logs of the containers of hooks running pods while
+	// they run.
+	HookLogs bool
+	// KindOrder is the path to the file defining the order in which
+	// resources are installed and uninstalled by kind.
+	KindOrder string
+	// MaxIncludeDepth limits how deeply include and tpl calls nest when
+	// rendering templates.
+	MaxIncludeDepth int
+	// TemplateTimeout limits how long rendering a single template may take.
+	// Zero means no limit.
+	TemplateTimeout time.Duration
+	// Profile is the kind of profile captured of the phases of actions:
+	// "cpu", "mem" or "trace". No profiles are captured if empty.
+	Profile string
+	// ProfileDir is the directory profiles are written to.
+	ProfileDir string
+	// ErrorFormat is the format errors are printed in: "text" or "json".
+	ErrorFormat string
+	// MaxNotesSize and MaxManifestSize are the sizes in bytes above which the
+	// notes and the manifest of a release are stored apart from its record,
+	// see storage.Storage. Zero means no limit.
+	MaxNotesSize    int
+	MaxManifestSize int
+	// ShowSecrets turns off the masking of secrets in the output and logs of
+	// Helm, see RedactPatterns.
+	ShowSecrets bool
+	// RedactPatterns are regular expressions matching the keys of values
+	// that are secrets. The default patterns of package redact are used if
+	// empty.
+	RedactPatterns []string
+	// Messages is the path to a file translating the messages of errors by
+	// code, see errcode.Translations.
+	Messages string
 }
 
 func New() *EnvSettings {
@@ -103,11 +193,37 @@ func New() *EnvSettings {
 		KubeTLSServerName:         os.Getenv("HELM_KUBETLS_SERVER_NAME"),
 		KubeInsecureSkipTLSVerify: envBoolOr("HELM_KUBEINSECURE_SKIP_TLS_VERIFY", false),
 		PluginsDirectory:          envOr("HELM_PLUGINS", helmpath.DataPath("plugins")),
+		PluginSandbox:             envBoolOr("HELM_PLUGIN_SANDBOX", false),
 		RegistryConfig:            envOr("HELM_REGISTRY_CONFIG", helmpath.ConfigPath("registry/config.json")),
 		RepositoryConfig:          envOr("HELM_REPOSITORY_CONFIG", helmpath.ConfigPath("repositories.yaml")),
 		RepositoryCache:           envOr("HELM_REPOSITORY_CACHE", helmpath.CachePath("repository")),
 		BurstLimit:                envIntOr("HELM_BURST_LIMIT", defaultBurstLimit),
 		QPS:                       envFloat32Or("HELM_QPS", defaultQPS),
+		WaitStatusMappings:        os.Getenv("HELM_WAIT_STATUS_MAPPINGS"),
+		WebhooksConfig:            envOr("HELM_WEBHOOKS_CONFIG", helmpath.ConfigPath("webhooks.yaml")),
+		AuditLog:                  os.Getenv("HELM_AUDIT_LOG"),
+		TrustPolicy:               envOr("HELM_TRUST_POLICY", helmpath.ConfigPath("trust-policy.yaml")),
+		FreezePolicy:              envOr("HELM_FREEZE_POLICY", helmpath.ConfigPath("freeze-policy.yaml")),
+		MetadataPolicy:            envOr("HELM_METADATA_POLICY", helmpath.ConfigPath("metadata-policy.yaml")),
+		NamePolicy:                envOr("HELM_NAME_POLICY", helmpath.ConfigPath("name-policy.yaml")),
+		KindOrder:                 envOr("HELM_KIND_ORDER", helmpath.ConfigPath("kind-order.yaml")),
+		SensitiveSecrets:          envBoolOr("HELM_DRIVER_SENSITIVE_SECRETS", false),
+		ExternalHookCommands:      envCSV("HELM_EXTERNAL_HOOK_COMMANDS"),
+		ExternalHookHosts:         envCSV("HELM_EXTERNAL_HOOK_HOSTS"),
+		HookServiceAccount:        envBoolOr("HELM_HOOK_SERVICE_ACCOUNT", false),
+		HookParallelism:           envIntOr("HELM_HOOK_PARALLELISM", 1),
+		HookLogs:                  envBoolOr("HELM_HOOK_LOGS", false),
+		HookImagePolicy:           envOr("HELM_HOOK_IMAGE_POLICY", helmpath.ConfigPath("hook-image-policy.yaml")),
+		MaxIncludeDepth:           envIntOr("HELM_MAX_INCLUDE_DEPTH", defaultMaxIncludeDepth),
+		TemplateTimeout:           envDurationOr("HELM_TEMPLATE_TIMEOUT", 0),
+		Profile:                   os.Getenv("HELM_PROFILE"),
+		ProfileDir:                envOr("HELM_PROFILE_DIR", "."),
+		ErrorFormat:               envOr("HELM_ERROR_FORMAT", "text"),
+		Messages:                  os.Getenv("HELM_MESSAGES"),
+		MaxNotesSize:              envIntOr("HELM_MAX_NOTES_SIZE", defaultMaxNotesSize),
+		MaxManifestSize:           envIntOr("HELM_MAX_MANIFEST_SIZE", defaultMaxManifestSize),
+		ShowSecrets:               envBoolOr("HELM_SHOW_SECRETS", false),
+		RedactPatterns:            envCSV("HELM_REDACT_PATTERNS"),
 	}
 	env.Debug, _ = strconv.ParseBool(os.Getenv("HELM_DEBUG"))
 
@@ -159,6 +275,31 @@ func (s *EnvSettings) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.RepositoryCache, "repository-cache", s.RepositoryCache, "path to the file containing cached repository indexes")
 	fs.IntVar(&s.BurstLimit, "burst-limit", s.BurstLimit, "client-side default throttling limit")
 	fs.Float32Var(&s.QPS, "qps", s.QPS, "queries per second used when communicating with the Kubernetes API, not including bursting")
+	fs.StringVar(&s.WaitStatusMappings, "wait-status-mappings", s.WaitStatusMappings, "file, or ConfigMap given as configmap:<namespace>/<name>, defining the readiness conditions of custom resources when waiting")
+	fs.StringVar(&s.WebhooksConfig, "webhooks-config", s.WebhooksConfig, "path to the file configuring the webhooks notified of release events")
+	fs.StringVar(&s.AuditLog, "audit-log", s.AuditLog, "record operations changing releases in an audit log: file:<path>, configmap, secret or sql")
+	fs.StringVar(&s.TrustPolicy, "trust-policy", s.TrustPolicy, "path to the file defining how charts must be verified per repository or registry")
+	fs.StringVar(&s.FreezePolicy, "freeze-policy", s.FreezePolicy, "file, or ConfigMap given as configmap:<namespace>/<name>, defining the freeze windows during which releases must not be installed, upgraded or rolled back")
+	fs.StringVar(&s.MetadataPolicy, "metadata-policy", s.MetadataPolicy, "path to the file defining how the labels and annotations of the resources of releases are stripped, preserved or set before they are applied")
+	fs.StringVar(&s.NamePolicy, "name-policy", s.NamePolicy, "path to the file defining the prefix, maximum length and pattern of the names of new releases, and how --generate-name generates them")
+	fs.BoolVar(&s.PluginSandbox, "plugin-sandbox", s.PluginSandbox, "run all plugins with a minimal environment and a scratch home directory, even those not declaring a sandbox")
+	fs.StringSliceVar(&s.ExternalHookCommands, "external-hook-command", s.ExternalHookCommands, "pattern of the commands the hooks of charts may run on this machine rather than in the cluster, e.g. /opt/hooks/* (can specify multiple)")
+	fs.StringSliceVar(&s.ExternalHookHosts, "external-hook-host", s.ExternalHookHosts, "pattern of the hosts the hooks of charts may send requests to from this machine, e.g. *.example.com (can specify multiple)")
+	fs.BoolVar(&s.HookServiceAccount, "hook-service-account", s.HookServiceAccount, "run the pods of hooks as a temporary ServiceAccount granted the hook permissions the chart declares, unless they name a ServiceAccount")
+	fs.IntVar(&s.HookParallelism, "hook-parallelism", s.HookParallelism, "how many hooks of the same weight run at the same time. Hooks of the next weight start once all of them have completed")
+	fs.BoolVar(&s.HookLogs, "hook-logs", s.HookLogs, "print the logs of the containers of hooks running pods, such as Jobs, to stderr while the hooks run")
+	fs.StringVar(&s.HookImagePolicy, "hook-image-policy", s.HookImagePolicy, "path to the file defining who must have signed the container images of hooks before they are run")
+	fs.StringVar(&s.KindOrder, "kind-order", s.KindOrder, "path to the file placing kinds in the order in which resources are installed and uninstalled")
+	fs.IntVar(&s.MaxIncludeDepth, "max-include-depth", s.MaxIncludeDepth, "how deeply include and tpl calls may nest when rendering templates")
+	fs.DurationVar(&s.TemplateTimeout, "template-timeout", s.TemplateTimeout, "time to wait for a single template to render (0 for no limit)")
+	fs.StringVar(&s.Profile, "profile", s.Profile, "capture profiles of the render, apply and wait phases of actions: cpu, mem or trace")
+	fs.StringVar(&s.ProfileDir, "profile-dir", s.ProfileDir, "directory profiles are written to")
+	fs.StringVar(&s.ErrorFormat, "error-format", s.ErrorFormat, "format errors are printed in: text, or json with the code of the error")
+	fs.StringVar(&s.Messages, "messages", s.Messages, "path to a file translating the messages of errors by code")
+	fs.IntVar(&s.MaxNotesSize, "max-notes-size", s.MaxNotesSize, "size in bytes above which the notes of releases are stored apart from their records, or truncated if the storage driver cannot (0 for no limit). Older versions of Helm read such releases without notes")
+	fs.BoolVar(&s.ShowSecrets, "show-secrets", s.ShowSecrets, "show the data of Secrets and the values of secret keys in dry-run output, debug logs and audit log errors instead of masking them")
+	fs.StringSliceVar(&s.RedactPatterns, "redact-pattern", s.RedactPatterns, "regular expression matching the keys of values that are secrets, replacing the default patterns (can specify multiple)")
+	fs.IntVar(&s.MaxManifestSize, "max-manifest-size", s.MaxManifestSize, "size in bytes above which the manifests of releases are stored apart from their records (0 for no limit). Older versions of Helm and other tools reading release records cannot upgrade or uninstall such releases")
 }
 
 func envOr(name, def string) string {
@@ -204,6 +345,18 @@ func envFloat32Or(name string, def float32) float32 {
 	return float32(ret)
 }
 
+func envDurationOr(name string, def time.Duration) time.Duration {
+	if name == "" {
+		return def
+	}
+	envVal := envOr(name, def.String())
+	ret, err := time.ParseDuration(envVal)
+	if err != nil {
+		return def
+	}
+	return ret
+}
+
 func envCSV(name string) (ls []string) {
 	trimmed := strings.Trim(os.Getenv(name), ", ")
 	if trimmed != "" {
@@ -228,6 +381,33 @@ func (s *EnvSettings) EnvVars() map[string]string {
 		"HELM_BURST_LIMIT":       strconv.Itoa(s.BurstLimit),
 		"HELM_QPS":               strconv.FormatFloat(float64(s.QPS), 'f', 2, 32),
 
+		"HELM_WAIT_STATUS_MAPPINGS":     s.WaitStatusMappings,
+		"HELM_WEBHOOKS_CONFIG":          s.WebhooksConfig,
+		"HELM_AUDIT_LOG":                s.AuditLog,
+		"HELM_TRUST_POLICY":             s.TrustPolicy,
+		"HELM_FREEZE_POLICY":            s.FreezePolicy,
+		"HELM_METADATA_POLICY":          s.MetadataPolicy,
+		"HELM_NAME_POLICY":              s.NamePolicy,
+		"HELM_KIND_ORDER":               s.KindOrder,
+		"HELM_HOOK_IMAGE_POLICY":        s.HookImagePolicy,
+		"HELM_EXTERNAL_HOOK_COMMANDS":   strings.Join(s.ExternalHookCommands, ","),
+		"HELM_EXTERNAL_HOOK_HOSTS":      strings.Join(s.ExternalHookHosts, ","),
+		"HELM_DRIVER_SENSITIVE_SECRETS": strconv.FormatBool(s.SensitiveSecrets),
+		"HELM_PLUGIN_SANDBOX":           strconv.FormatBool(s.PluginSandbox),
+		"HELM_HOOK_SERVICE_ACCOUNT":     strconv.FormatBool(s.HookServiceAccount),
+		"HELM_HOOK_PARALLELISM":         strconv.Itoa(s.HookParallelism),
+		"HELM_HOOK_LOGS":                strconv.FormatBool(s.HookLogs),
+		"HELM_MAX_INCLUDE_DEPTH":        strconv.Itoa(s.MaxIncludeDepth),
+		"HELM_TEMPLATE_TIMEOUT":         s.TemplateTimeout.String(),
+		"HELM_PROFILE":                  s.Profile,
+		"HELM_PROFILE_DIR":              s.ProfileDir,
+		"HELM_ERROR_FORMAT":             s.ErrorFormat,
+		"HELM_MESSAGES":                 s.Messages,
+		"HELM_MAX_NOTES_SIZE":           strconv.Itoa(s.MaxNotesSize),
+		"HELM_MAX_MANIFEST_SIZE":        strconv.Itoa(s.MaxManifestSize),
+		"HELM_SHOW_SECRETS":             strconv.FormatBool(s.ShowSecrets),
+		"HELM_REDACT_PATTERNS":          strings.Join(s.RedactPatterns, ","),
+
 		// broken, these are populated from helm flags and not kubeconfig.
 		"HELM_KUBECONTEXT":                  s.KubeContext,
 		"HELM_KUBETOKEN":                    s.KubeToken,
@@ -0,0 +1,28 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package diff compares Kubernetes manifests the way Helm does.
+
+Manifests compares two rendered manifests, e.g. those of two revisions of a
+release, and reports the resources that are added, removed or modified, with
+JSON patches (RFC 6902) of the modifications. Resources are matched on kind,
+namespace and name, as when Helm updates a release.
+
+MergePatch computes the patch Helm sends to the API server to update a
+resource, from its last applied, desired and live states.
+*/
+package diff // import "helm.sh/helm/v3/pkg/diff"
@@ -0,0 +1,101 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff // import "helm.sh/helm/v3/pkg/diff"
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation is an operation of a JSON patch (RFC 6902).
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON leaves out the value of remove operations, which have none.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(map[string]string{"op": o.Op, "path": o.Path})
+	}
+	type operation Operation
+	return json.Marshal(operation(o))
+}
+
+// Patch is a JSON patch (RFC 6902).
+type Patch []Operation
+
+// CreateJSONPatch returns the JSON patch turning the original into the target
+// document, as decoded from JSON. Objects are compared field by field. Lists
+// of the same length are compared item by item and replaced otherwise.
+func CreateJSONPatch(original, target interface{}) Patch {
+	return appendPatch(nil, "", original, target)
+}
+
+func appendPatch(patch Patch, path string, original, target interface{}) Patch {
+	if reflect.DeepEqual(original, target) {
+		return patch
+	}
+	switch o := original.(type) {
+	case map[string]interface{}:
+		t, ok := target.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, k := range sortedKeys(o) {
+			if _, ok := t[k]; !ok {
+				patch = append(patch, Operation{Op: "remove", Path: path + "/" + escape(k)})
+			}
+		}
+		for _, k := range sortedKeys(t) {
+			if ov, ok := o[k]; ok {
+				patch = appendPatch(patch, path+"/"+escape(k), ov, t[k])
+			} else {
+				patch = append(patch, Operation{Op: "add", Path: path + "/" + escape(k), Value: t[k]})
+			}
+		}
+		return patch
+	case []interface{}:
+		t, ok := target.([]interface{})
+		if !ok || len(o) != len(t) {
+			break
+		}
+		for i := range o {
+			patch = appendPatch(patch, path+"/"+strconv.Itoa(i), o[i], t[i])
+		}
+		return patch
+	}
+	return append(patch, Operation{Op: "replace", Path: path, Value: target})
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escape escapes a key for a JSON pointer (RFC 6901).
+func escape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
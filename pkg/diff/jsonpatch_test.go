@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCreateJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		target   string
+		expected string
+	}{
+		{
+			name:     "equal",
+			original: `{"a":[1,2]}`,
+			target:   `{"a":[1,2]}`,
+			expected: `null`,
+		},
+		{
+			name:     "list items",
+			original: `{"a":[{"b":1},{"b":2}]}`,
+			target:   `{"a":[{"b":1},{"b":3}]}`,
+			expected: `[{"op":"replace","path":"/a/1/b","value":3}]`,
+		},
+		{
+			name:     "list length",
+			original: `{"a":[1]}`,
+			target:   `{"a":[1,2]}`,
+			expected: `[{"op":"replace","path":"/a","value":[1,2]}]`,
+		},
+		{
+			name:     "falsy values",
+			original: `{"a":true,"b":"x"}`,
+			target:   `{"a":false,"b":""}`,
+			expected: `[{"op":"replace","path":"/a","value":false},{"op":"replace","path":"/b","value":""}]`,
+		},
+		{
+			name:     "type change",
+			original: `{"a":{"b":1}}`,
+			target:   `{"a":"b"}`,
+			expected: `[{"op":"replace","path":"/a","value":"b"}]`,
+		},
+		{
+			name:     "escaped keys",
+			original: `{}`,
+			target:   `{"a~b/c":null}`,
+			expected: `[{"op":"add","path":"/a~0b~1c","value":null}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var original, target interface{}
+			if err := json.Unmarshal([]byte(tt.original), &original); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.target), &target); err != nil {
+				t.Fatal(err)
+			}
+			patch, err := json.Marshal(CreateJSONPatch(original, target))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(patch) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, patch)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff // import "helm.sh/helm/v3/pkg/diff"
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// ChangeType is the type of a change to a resource.
+type ChangeType string
+
+// The types of changes to resources.
+const (
+	// Added resources are only in the target manifest.
+	Added ChangeType = "added"
+	// Removed resources are only in the original manifest.
+	Removed ChangeType = "removed"
+	// Modified resources are in both manifests, with differences.
+	Modified ChangeType = "modified"
+)
+
+// ResourceKey identifies a resource across manifests. Helm matches resources
+// on their kind, namespace and name, regardless of their API version.
+type ResourceKey struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (k ResourceKey) String() string {
+	if k.Namespace == "" {
+		return fmt.Sprintf("%s/%s", k.Kind, k.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", k.Namespace, k.Kind, k.Name)
+}
+
+// Change is the change to a resource between two manifests.
+type Change struct {
+	Type ChangeType  `json:"type"`
+	Key  ResourceKey `json:"key"`
+	// Original is the resource in the original manifest, nil if added.
+	Original map[string]interface{} `json:"original,omitempty"`
+	// Target is the resource in the target manifest, nil if removed.
+	Target map[string]interface{} `json:"target,omitempty"`
+	// Patch turns the original into the target resource. It is only set for
+	// modified resources.
+	Patch Patch `json:"patch,omitempty"`
+}
+
+// Manifests returns the changes to the resources from the original to the
+// target manifest, ordered by key. Unchanged resources are left out.
+//
+// Resources without namespace are considered to be in the given namespace,
+// the namespace they are installed in.
+func Manifests(original, target, namespace string) ([]Change, error) {
+	from, err := parseManifest(original, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse original manifest")
+	}
+	to, err := parseManifest(target, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse target manifest")
+	}
+
+	var changes []Change
+	for key, o := range from {
+		t, ok := to[key]
+		if !ok {
+			changes = append(changes, Change{Type: Removed, Key: key, Original: o})
+			continue
+		}
+		if patch := CreateJSONPatch(o, t); len(patch) > 0 {
+			changes = append(changes, Change{Type: Modified, Key: key, Original: o, Target: t, Patch: patch})
+		}
+	}
+	for key, t := range to {
+		if _, ok := from[key]; !ok {
+			changes = append(changes, Change{Type: Added, Key: key, Target: t})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key.String() < changes[j].Key.String()
+	})
+	return changes, nil
+}
+
+// parseManifest returns the resources of the manifest by key.
+func parseManifest(manifest, namespace string) (map[ResourceKey]map[string]interface{}, error) {
+	resources := map[ResourceKey]map[string]interface{}{}
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		key, err := resourceKey(obj, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := resources[key]; ok {
+			return nil, errors.Errorf("resource %s is defined more than once", key)
+		}
+		resources[key] = obj
+	}
+	return resources, nil
+}
+
+func resourceKey(obj map[string]interface{}, namespace string) (ResourceKey, error) {
+	kind, _ := obj["kind"].(string)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if kind == "" || name == "" {
+		return ResourceKey{}, errors.New("resource without kind or metadata.name")
+	}
+	if ns, ok := metadata["namespace"].(string); ok && ns != "" {
+		namespace = ns
+	}
+	return ResourceKey{Kind: kind, Namespace: namespace, Name: name}, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const originalManifest = `---
+# Source: web/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+data:
+  color: blue
+  size: large
+---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+# Source: web/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web
+  namespace: other
+`
+
+const targetManifest = `---
+# Source: web/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+  namespace: default
+data:
+  color: red
+  a/b: "true"
+---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`
+
+func TestManifests(t *testing.T) {
+	changes, err := Manifests(originalManifest, targetManifest, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		typ   ChangeType
+		key   string
+		patch string
+	}{
+		{Modified, "default/ConfigMap/web", `[{"op":"remove","path":"/data/size"},{"op":"add","path":"/data/a~1b","value":"true"},{"op":"replace","path":"/data/color","value":"red"},{"op":"add","path":"/metadata/namespace","value":"default"}]`},
+		{Added, "default/Deployment/web", "null"},
+		{Removed, "other/Secret/web", "null"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+	for i, e := range expected {
+		c := changes[i]
+		if c.Type != e.typ || c.Key.String() != e.key {
+			t.Errorf("expected change %d to be %s %s, got %s %s", i, e.typ, e.key, c.Type, c.Key)
+		}
+		patch, err := json.Marshal(c.Patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(patch) != e.patch {
+			t.Errorf("expected patch of %s to be\n%s\ngot\n%s", e.key, e.patch, patch)
+		}
+	}
+}
+
+func TestManifestsErrors(t *testing.T) {
+	duplicate := "kind: ConfigMap\nmetadata:\n  name: a\n---\nkind: ConfigMap\nmetadata:\n  name: a\n"
+	if _, err := Manifests(duplicate, "", "default"); err == nil {
+		t.Error("expected an error for a resource defined twice")
+	}
+	if _, err := Manifests("", "kind: ConfigMap\n", "default"); err == nil {
+		t.Error("expected an error for a resource without name")
+	}
+}
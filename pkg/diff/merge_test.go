@@ -0,0 +1,54 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestMergePatch(t *testing.T) {
+	original := []byte(`{"metadata":{"name":"web","labels":{"a":"1"}}}`)
+	modified := []byte(`{"metadata":{"name":"web","labels":{"a":"2"}}}`)
+	// Labels added by others are kept by the three-way merge.
+	current := []byte(`{"metadata":{"name":"web","labels":{"a":"1","b":"1"}}}`)
+
+	patch, patchType, err := MergePatch(original, modified, current, &v1.ConfigMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patchType != types.StrategicMergePatchType {
+		t.Errorf("expected a strategic merge patch, got %s", patchType)
+	}
+	if string(patch) != `{"metadata":{"labels":{"a":"2"}}}` {
+		t.Errorf("unexpected patch %s", patch)
+	}
+
+	patch, patchType, err = MergePatch(original, modified, current, &unstructured.Unstructured{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patchType != types.MergePatchType {
+		t.Errorf("expected a JSON merge patch, got %s", patchType)
+	}
+	if string(patch) != `{"metadata":{"labels":{"a":"2"}}}` {
+		t.Errorf("unexpected patch %s", patch)
+	}
+}
@@ -73,6 +73,16 @@ type ChartDownloader struct {
 	RegistryClient   *registry.Client
 	RepositoryConfig string
 	RepositoryCache  string
+	// TrustPolicy is enforced on the downloaded charts regardless of Verify.
+	TrustPolicy *TrustPolicy
+	// SigstoreVerifier verifies the Sigstore signatures the trust policy
+	// requires. It defaults to the cosign command.
+	SigstoreVerifier SigstoreVerifier
+
+	// resolvedRepo and resolvedDigest are the name of the repository the
+	// chart was resolved in, and the digest its index lists for the chart.
+	resolvedRepo   string
+	resolvedDigest string
 }
 
 // DownloadTo retrieves a chart. Depending on the settings, it may also download a provenance file.
@@ -97,7 +107,18 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 		return "", nil, err
 	}
 
-	data, err := g.Get(u.String(), c.Options...)
+	// The signatures of charts in registries are attached to the digests of
+	// their manifests, while tags are mutable: pull the digest the tag
+	// resolves to, so that the chart verified is the chart pulled.
+	href := u.String()
+	rule := c.TrustPolicy.Match(c.resolvedRepo, href)
+	if rule != nil && len(rule.Sigstore) > 0 && u.Scheme == registry.OCIScheme {
+		if href, err = c.pinOCI(href); err != nil {
+			return "", nil, err
+		}
+	}
+
+	data, err := g.Get(href, c.Options...)
 	if err != nil {
 		return "", nil, err
 	}
@@ -138,9 +159,43 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 			}
 		}
 	}
+
+	if rule != nil {
+		v, err := rule.enforce(c, g, href, destfile)
+		if err != nil {
+			// Do not leave untrusted charts behind.
+			os.Remove(destfile)
+			os.Remove(destfile + ".prov")
+			os.Remove(destfile + SigstoreBundleExt)
+			return destfile, nil, errors.Wrapf(err, "chart %s does not satisfy the trust policy for %s", ref, rule.Repository)
+		}
+		if v != nil {
+			ver = v
+		}
+	}
 	return destfile, ver, nil
 }
 
+// pinOCI returns the reference of the OCI chart by the digest of its manifest.
+func (c *ChartDownloader) pinOCI(href string) (string, error) {
+	client := c.RegistryClient
+	if client == nil {
+		var err error
+		if client, err = registry.NewClient(); err != nil {
+			return "", err
+		}
+	}
+	ref := strings.TrimPrefix(href, registry.OCIScheme+"://")
+	digest, err := client.Resolve(ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to resolve the digest of %s", ref)
+	}
+	if i := strings.LastIndexByte(ref, ':'); i > strings.LastIndexByte(ref, '/') {
+		ref = ref[:i]
+	}
+	return fmt.Sprintf("%s://%s@%s", registry.OCIScheme, ref, digest), nil
+}
+
 func (c *ChartDownloader) getOciURI(ref, version string, u *url.URL) (*url.URL, error) {
 	var tag string
 	var err error
@@ -190,6 +245,7 @@ func (c *ChartDownloader) getOciURI(ref, version string, u *url.URL) (*url.URL,
 //   - If version is empty, this will return the URL for the latest version
 //   - If no version can be found, an error is returned
 func (c *ChartDownloader) ResolveChartVersion(ref, version string) (*url.URL, error) {
+	c.resolvedRepo, c.resolvedDigest = "", ""
 	u, err := url.Parse(ref)
 	if err != nil {
 		return nil, errors.Errorf("invalid chart URL format: %s", ref)
@@ -292,6 +348,7 @@ func (c *ChartDownloader) ResolveChartVersion(ref, version string) (*url.URL, er
 	if len(cv.URLs) == 0 {
 		return u, errors.Errorf("chart %q has no downloadable URLs", ref)
 	}
+	c.resolvedRepo, c.resolvedDigest = rc.Name, cv.Digest
 
 	// TODO: Seems that picking first URL is not fully correct
 	resolvedURL, err := repo.ResolveReferenceURL(rc.URL, cv.URLs[0])
@@ -387,6 +444,7 @@ func (c *ChartDownloader) scanReposForURL(u string, rf *repo.File) (*repo.Entry,
 			for _, ver := range entry {
 				for _, dl := range ver.URLs {
 					if urlutil.Equal(u, dl) {
+						c.resolvedRepo, c.resolvedDigest = rc.Name, ver.Digest
 						return rc, nil
 					}
 				}
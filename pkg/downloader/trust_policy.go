@@ -0,0 +1,290 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloader
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/fileutil"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// SigstoreBundleExt is the extension of the Sigstore bundles published next to
+// chart archives.
+const SigstoreBundleExt = ".sigstore.json"
+
+// TrustPolicy defines, per repository or registry, how charts must be verified
+// before they are used. It is enforced whenever a chart is downloaded, whether
+// or not verification was requested.
+type TrustPolicy struct {
+	APIVersion string `json:"apiVersion"`
+	// Rules are the verification requirements per repository or registry.
+	Rules []*TrustRule `json:"rules"`
+}
+
+// TrustRule is the verification requirements of the charts of a repository or
+// registry.
+type TrustRule struct {
+	// Repository is the name of a repository, the URL of a repository or the
+	// oci:// reference of a registry or a namespace in it. URLs match all the
+	// charts below them. "*" matches all charts no other rule matches.
+	Repository string `json:"repository"`
+	// Keyring is the PGP keyring the provenance of the charts is verified
+	// with. Relative paths are relative to the policy file. It defaults to
+	// the keyring of the command when Signers are set.
+	Keyring string `json:"keyring,omitempty"`
+	// Signers restricts the keys the charts may be signed with. A signer is
+	// either the name or email of an identity of the key, or its fingerprint.
+	Signers []string `json:"signers,omitempty"`
+	// Sigstore lists the identities allowed to sign the charts with Sigstore.
+	// Charts from HTTP repositories are verified against the bundle published
+	// next to them, and charts from registries against the signatures
+	// attached to the digest they are pulled by.
+	Sigstore []SigstoreIdentity `json:"sigstore,omitempty"`
+	// RequireDigest requires the digest of the charts to match the digest the
+	// repository index lists for them.
+	RequireDigest bool `json:"requireDigest,omitempty"`
+}
+
+// SigstoreIdentity is the identity of a Sigstore keyless signer: the subject
+// of its certificate and the OIDC issuer that authenticated it.
+type SigstoreIdentity struct {
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer"`
+}
+
+// SigstoreVerifier verifies Sigstore signatures.
+type SigstoreVerifier interface {
+	// VerifyBlob verifies the file against the bundle, which must have been
+	// signed by one of the identities.
+	VerifyBlob(file, bundle string, identities []SigstoreIdentity) error
+	// VerifyImage verifies the signatures attached to the OCI reference, one
+	// of which must have been made by one of the identities.
+	VerifyImage(ref string, identities []SigstoreIdentity) error
+}
+
+// CosignVerifier verifies Sigstore signatures with the cosign command.
+type CosignVerifier struct {
+	// Command is the cosign command. It defaults to cosign.
+	Command string
+}
+
+// VerifyBlob implements SigstoreVerifier.
+func (v CosignVerifier) VerifyBlob(file, bundle string, identities []SigstoreIdentity) error {
+	return v.verify(identities, "verify-blob", file, "--bundle", bundle)
+}
+
+// VerifyImage implements SigstoreVerifier.
+func (v CosignVerifier) VerifyImage(ref string, identities []SigstoreIdentity) error {
+	return v.verify(identities, "verify", ref)
+}
+
+func (v CosignVerifier) verify(identities []SigstoreIdentity, args ...string) error {
+	command := v.Command
+	if command == "" {
+		command = "cosign"
+	}
+	var errs []string
+	for _, id := range identities {
+		cmd := exec.Command(command, append(args,
+			"--certificate-identity", id.Subject,
+			"--certificate-oidc-issuer", id.Issuer)...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			errs = append(errs, errors.Wrapf(err, "%s: %s", id.Subject, strings.TrimSpace(stderr.String())).Error())
+			continue
+		}
+		return nil
+	}
+	return errors.Errorf("no valid Sigstore signature by an allowed identity: %s", strings.Join(errs, "; "))
+}
+
+// LoadTrustPolicy loads the trust policy file. A missing file means there is
+// no policy, and nil is returned.
+func LoadTrustPolicy(path string) (*TrustPolicy, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &TrustPolicy{}
+	if err := yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse trust policy %s", path)
+	}
+	for n, r := range p.Rules {
+		if r == nil || r.Repository == "" {
+			return nil, errors.Errorf("trust policy %s: rule %d has no repository", path, n)
+		}
+		for _, id := range r.Sigstore {
+			if id.Subject == "" || id.Issuer == "" {
+				return nil, errors.Errorf("trust policy %s: Sigstore identities of %s need a subject and an issuer", path, r.Repository)
+			}
+		}
+		if r.Keyring != "" && !filepath.IsAbs(r.Keyring) {
+			r.Keyring = filepath.Join(filepath.Dir(path), r.Keyring)
+		}
+	}
+	return p, nil
+}
+
+// Match returns the rule applying to the chart at chartURL, downloaded from
+// the named repository if any, or nil if no rule applies.
+//
+// A rule naming the repository wins over rules matching the URL, and the
+// longest matching URL wins over shorter ones.
+func (p *TrustPolicy) Match(repoName, chartURL string) *TrustRule {
+	if p == nil {
+		return nil
+	}
+	var match, fallback *TrustRule
+	for _, r := range p.Rules {
+		switch {
+		case r.Repository == "*":
+			fallback = r
+		case repoName != "" && r.Repository == repoName:
+			return r
+		case matchesURL(r.Repository, chartURL):
+			if match == nil || len(r.Repository) > len(match.Repository) {
+				match = r
+			}
+		}
+	}
+	if match != nil {
+		return match
+	}
+	return fallback
+}
+
+// matchesURL returns whether the chart URL is below the prefix URL.
+func matchesURL(prefix, chartURL string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.Contains(prefix, "://") || !strings.HasPrefix(chartURL, prefix) {
+		return false
+	}
+	rest := chartURL[len(prefix):]
+	return rest == "" || rest[0] == '/' || rest[0] == ':' || rest[0] == '@'
+}
+
+// enforce verifies the chart downloaded to destfile from u against the rule,
+// fetching the verification data it needs. It returns the verification of the
+// provenance file, if the rule requires one.
+func (r *TrustRule) enforce(c *ChartDownloader, g getter.Getter, u string, destfile string) (*provenance.Verification, error) {
+	if r.RequireDigest && !registry.IsOCI(u) {
+		if c.resolvedDigest == "" {
+			return nil, errors.New("the repository index lists no digest for the chart")
+		}
+		digest, err := provenance.DigestFile(destfile)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimPrefix(c.resolvedDigest, "sha256:") != digest {
+			return nil, errors.Errorf("the digest of the chart sha256:%s does not match the digest sha256:%s listed in the repository index", digest, strings.TrimPrefix(c.resolvedDigest, "sha256:"))
+		}
+	}
+
+	var ver *provenance.Verification
+	if r.Keyring != "" || len(r.Signers) > 0 {
+		provfile := destfile + ".prov"
+		if _, err := os.Stat(provfile); err != nil {
+			body, err := g.Get(u + ".prov")
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to fetch provenance %q", u+".prov")
+			}
+			if err := fileutil.AtomicWriteFile(provfile, body, 0644); err != nil {
+				return nil, err
+			}
+		}
+		keyring := r.Keyring
+		if keyring == "" {
+			keyring = c.Keyring
+		}
+		var err error
+		if ver, err = VerifyChart(destfile, keyring); err != nil {
+			return nil, err
+		}
+		if len(r.Signers) > 0 && !r.signedByAllowedSigner(ver) {
+			return nil, errors.Errorf("the chart is signed by %s, which is not an allowed signer", signerName(ver))
+		}
+	}
+
+	if len(r.Sigstore) > 0 {
+		sigstore := c.SigstoreVerifier
+		if sigstore == nil {
+			sigstore = CosignVerifier{}
+		}
+		if registry.IsOCI(u) {
+			if err := sigstore.VerifyImage(strings.TrimPrefix(u, registry.OCIScheme+"://"), r.Sigstore); err != nil {
+				return nil, err
+			}
+		} else {
+			bundle := destfile + SigstoreBundleExt
+			body, err := g.Get(u + SigstoreBundleExt)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to fetch Sigstore bundle %q", u+SigstoreBundleExt)
+			}
+			if err := fileutil.AtomicWriteFile(bundle, body, 0644); err != nil {
+				return nil, err
+			}
+			if err := sigstore.VerifyBlob(destfile, bundle, r.Sigstore); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ver, nil
+}
+
+// signedByAllowedSigner returns whether the key the chart was signed with is
+// one of the signers of the rule.
+func (r *TrustRule) signedByAllowedSigner(ver *provenance.Verification) bool {
+	if ver.SignedBy == nil {
+		return false
+	}
+	fingerprint := hex.EncodeToString(ver.SignedBy.PrimaryKey.Fingerprint[:])
+	for _, signer := range r.Signers {
+		if strings.EqualFold(strings.TrimPrefix(strings.ReplaceAll(signer, " ", ""), "0x"), fingerprint) {
+			return true
+		}
+		for name, id := range ver.SignedBy.Identities {
+			if signer == name || (id.UserId != nil && strings.EqualFold(signer, id.UserId.Email)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func signerName(ver *provenance.Verification) string {
+	if ver.SignedBy == nil {
+		return "an unknown key"
+	}
+	for name := range ver.SignedBy.Identities {
+		return name
+	}
+	return strings.ToUpper(hex.EncodeToString(ver.SignedBy.PrimaryKey.Fingerprint[:]))
+}
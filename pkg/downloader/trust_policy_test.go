@@ -0,0 +1,258 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo/repotest"
+)
+
+func TestLoadTrustPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trust-policy.yaml")
+
+	p, err := LoadTrustPolicy(path)
+	if err != nil || p != nil {
+		t.Fatalf("expected no policy when the file is missing, got %v, %v", p, err)
+	}
+
+	policy := `apiVersion: v1
+rules:
+- repository: stable
+  keyring: keys/stable.gpg
+  signers:
+  - release@example.com
+- repository: oci://registry.example.com/charts
+  sigstore:
+  - subject: https://github.com/example/charts/.github/workflows/release.yaml@refs/heads/main
+    issuer: https://token.actions.githubusercontent.com
+`
+	if err := os.WriteFile(path, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err = LoadTrustPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(p.Rules))
+	}
+	if expect := filepath.Join(dir, "keys/stable.gpg"); p.Rules[0].Keyring != expect {
+		t.Errorf("expected keyring %s, got %s", expect, p.Rules[0].Keyring)
+	}
+
+	for _, policy := range []string{
+		"rules:\n- keyring: stable.gpg\n",
+		"rules:\n- repository: stable\n  sigstore:\n  - subject: someone\n",
+		"rules:\n- repository: stable\n  unknown: true\n",
+	} {
+		if err := os.WriteFile(path, []byte(policy), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadTrustPolicy(path); err == nil {
+			t.Errorf("expected an error loading %q", policy)
+		}
+	}
+}
+
+func TestTrustPolicyMatch(t *testing.T) {
+	p := &TrustPolicy{Rules: []*TrustRule{
+		{Repository: "*"},
+		{Repository: "stable"},
+		{Repository: "https://example.com/charts/"},
+		{Repository: "https://example.com/charts/internal"},
+		{Repository: "oci://registry.example.com/charts/nginx"},
+	}}
+
+	tests := []struct {
+		repo, url, expect string
+	}{
+		{"stable", "https://example.com/charts/nginx-1.0.0.tgz", "stable"},
+		{"", "https://example.com/charts/nginx-1.0.0.tgz", "https://example.com/charts/"},
+		{"", "https://example.com/charts/internal/nginx-1.0.0.tgz", "https://example.com/charts/internal"},
+		{"", "https://example.com/chartsmuseum/nginx-1.0.0.tgz", "*"},
+		{"", "oci://registry.example.com/charts/nginx:1.0.0", "oci://registry.example.com/charts/nginx"},
+		{"", "oci://registry.example.com/charts/nginx-ingress:1.0.0", "*"},
+	}
+	for _, tt := range tests {
+		if r := p.Match(tt.repo, tt.url); r == nil || r.Repository != tt.expect {
+			t.Errorf("expected %s %s to match %s, got %v", tt.repo, tt.url, tt.expect, r)
+		}
+	}
+
+	if r := (&TrustPolicy{Rules: []*TrustRule{{Repository: "stable"}}}).Match("", "https://example.com/a.tgz"); r != nil {
+		t.Errorf("expected no rule to match, got %v", r)
+	}
+	if r := (*TrustPolicy)(nil).Match("stable", "https://example.com/a.tgz"); r != nil {
+		t.Errorf("expected no rule to match without a policy, got %v", r)
+	}
+}
+
+type fakeSigstoreVerifier struct {
+	files []string
+	err   error
+}
+
+func (v *fakeSigstoreVerifier) VerifyBlob(file, bundle string, _ []SigstoreIdentity) error {
+	v.files = append(v.files, file, bundle)
+	return v.err
+}
+
+func (v *fakeSigstoreVerifier) VerifyImage(ref string, _ []SigstoreIdentity) error {
+	v.files = append(v.files, ref)
+	return v.err
+}
+
+func TestDownloadToTrustPolicy(t *testing.T) {
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/*.tgz*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	if err := srv.CreateIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srv.Root(), "signtest-0.1.0.tgz"+SigstoreBundleExt), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repoConfig := filepath.Join(srv.Root(), "repositories.yaml")
+	identity := []SigstoreIdentity{{Subject: "release@example.com", Issuer: "https://accounts.example.com"}}
+
+	tests := []struct {
+		name     string
+		rule     TrustRule
+		sigstore *fakeSigstoreVerifier
+		err      string
+	}{
+		{
+			name: "allowed signer",
+			rule: TrustRule{Repository: "test", Keyring: "testdata/helm-test-key.pub", Signers: []string{"helm-testing@helm.sh"}},
+		},
+		{
+			name: "other signer",
+			rule: TrustRule{Repository: "test", Keyring: "testdata/helm-test-key.pub", Signers: []string{"release@example.com"}},
+			err:  "not an allowed signer",
+		},
+		{
+			name: "digest",
+			rule: TrustRule{Repository: srv.URL(), RequireDigest: true},
+		},
+		{
+			name:     "sigstore",
+			rule:     TrustRule{Repository: "*", Sigstore: identity},
+			sigstore: &fakeSigstoreVerifier{},
+		},
+		{
+			name:     "sigstore failure",
+			rule:     TrustRule{Repository: "*", Sigstore: identity},
+			sigstore: &fakeSigstoreVerifier{err: os.ErrPermission},
+			err:      "does not satisfy the trust policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			rule := tt.rule
+			c := ChartDownloader{
+				Out:              os.Stderr,
+				Verify:           VerifyNever,
+				RepositoryConfig: repoConfig,
+				RepositoryCache:  srv.Root(),
+				Getters: getter.All(&cli.EnvSettings{
+					RepositoryConfig: repoConfig,
+					RepositoryCache:  srv.Root(),
+				}),
+				TrustPolicy: &TrustPolicy{Rules: []*TrustRule{&rule}},
+			}
+			if tt.sigstore != nil {
+				c.SigstoreVerifier = tt.sigstore
+			}
+
+			where, v, err := c.DownloadTo("test/signtest", "", dest)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				if _, err := os.Stat(where); !os.IsNotExist(err) {
+					t.Errorf("expected the untrusted chart to be removed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rule.Signers) > 0 && (v == nil || v.FileHash == "") {
+				t.Error("expected the provenance to be verified")
+			}
+			if tt.sigstore != nil && (len(tt.sigstore.files) != 2 || tt.sigstore.files[1] != where+SigstoreBundleExt) {
+				t.Errorf("expected the chart to be verified against its Sigstore bundle, got %v", tt.sigstore.files)
+			}
+		})
+	}
+}
+
+func TestDownloadToTrustPolicyOCI(t *testing.T) {
+	srv, err := repotest.NewTempServerWithCleanup(t, "../../cmd/helm/testdata/testcharts/oci-dependent-chart-0.1.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	ociSrv, err := repotest.NewOCIServer(t, srv.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ociSrv.Run(t)
+
+	ref := ociSrv.RegistryURL + "/u/ocitestuser/oci-dependent-chart"
+	digest, err := ociSrv.Client.Resolve(ref + ":0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sigstore := &fakeSigstoreVerifier{}
+	c := ChartDownloader{
+		Out:            os.Stderr,
+		Verify:         VerifyNever,
+		RegistryClient: ociSrv.Client,
+		Getters:        getter.All(&cli.EnvSettings{}),
+		Options:        []getter.Option{getter.WithRegistryClient(ociSrv.Client)},
+		TrustPolicy: &TrustPolicy{Rules: []*TrustRule{{
+			Repository: "*",
+			Sigstore:   []SigstoreIdentity{{Subject: "release@example.com", Issuer: "https://accounts.example.com"}},
+		}}},
+		SigstoreVerifier: sigstore,
+	}
+	where, _, err := c.DownloadTo("oci://"+ref, "0.1.0", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(where) != "oci-dependent-chart-0.1.0.tgz" {
+		t.Errorf("expected the chart to be saved as oci-dependent-chart-0.1.0.tgz, got %s", where)
+	}
+	// The digest verified is the digest pulled, not the mutable tag.
+	if want := ref + "@" + digest; len(sigstore.files) != 1 || sigstore.files[0] != want {
+		t.Errorf("expected %s to be verified, got %v", want, sigstore.files)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// checksumPlaceholder matches the placeholders checksumOf renders, holding
+// the hex encoded reference. They are replaced by the checksums once all
+// templates are rendered.
+var checksumPlaceholder = regexp.MustCompile(`_helmChecksumOf_([0-9a-f]+)_`)
+
+// checksumOf returns a placeholder for the checksum of the rendered document
+// ref refers to: the name of a resource, optionally prefixed by its kind, as
+// in "ConfigMap/app-config". Any template of the chart or its subcharts may
+// render the document.
+func checksumOf(ref string) (string, error) {
+	if strings.TrimSpace(ref) == "" {
+		return "", errors.New(warnWrap("checksumOf: a document name is required"))
+	}
+	return "_helmChecksumOf_" + hex.EncodeToString([]byte(ref)) + "_", nil
+}
+
+// renderedDoc is a document of a rendered template.
+type renderedDoc struct {
+	file, kind, name, text string
+}
+
+// checksumResolver computes the checksums of rendered documents, resolving
+// the checksums they contain themselves first.
+type checksumResolver struct {
+	docs      []renderedDoc
+	checksums map[int]string
+	resolving map[int]bool
+}
+
+// resolveChecksums replaces the checksumOf placeholders in the rendered files
+// by the SHA-256 checksums of the documents they refer to, which are looked
+// up in docs, the rendered files of all templates.
+func resolveChecksums(rendered, docs map[string]string) error {
+	r := &checksumResolver{checksums: map[int]string{}, resolving: map[int]bool{}}
+	files := make([]string, 0, len(docs))
+	for file := range docs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		manifests := releaseutil.SplitManifests(docs[file])
+		keys := make([]string, 0, len(manifests))
+		for k := range manifests {
+			keys = append(keys, k)
+		}
+		sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+		for _, k := range keys {
+			var head releaseutil.SimpleHead
+			if err := yaml.Unmarshal([]byte(manifests[k]), &head); err != nil || head.Metadata == nil {
+				continue
+			}
+			r.docs = append(r.docs, renderedDoc{file: file, kind: head.Kind, name: head.Metadata.Name, text: manifests[k]})
+		}
+	}
+
+	for file, text := range rendered {
+		resolved, err := r.replace(text)
+		if err != nil {
+			return errors.Wrapf(err, "%s", file)
+		}
+		rendered[file] = resolved
+	}
+	return nil
+}
+
+// replace replaces the placeholders in text by the checksums they refer to.
+func (r *checksumResolver) replace(text string) (string, error) {
+	var err error
+	resolved := checksumPlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if err != nil {
+			return placeholder
+		}
+		ref, _ := hex.DecodeString(checksumPlaceholder.FindStringSubmatch(placeholder)[1])
+		var i int
+		if i, err = r.lookup(string(ref)); err != nil {
+			return placeholder
+		}
+		var sum string
+		if sum, err = r.checksum(i); err != nil {
+			err = errors.Wrapf(err, "checksumOf %q", ref)
+		}
+		return sum
+	})
+	return resolved, err
+}
+
+// lookup returns the index of the document ref refers to.
+func (r *checksumResolver) lookup(ref string) (int, error) {
+	kind, name, qualified := strings.Cut(ref, "/")
+	if !qualified {
+		kind, name = "", ref
+	}
+	var matches []int
+	for i, d := range r.docs {
+		if d.name == name && (kind == "" || strings.EqualFold(d.kind, kind)) {
+			matches = append(matches, i)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return 0, errors.Errorf("checksumOf %q: no rendered document has this name", ref)
+	case len(matches) > 1:
+		var found []string
+		for _, i := range matches {
+			found = append(found, fmt.Sprintf("%s/%s in %s", r.docs[i].kind, name, r.docs[i].file))
+		}
+		return 0, errors.Errorf("checksumOf %q: several rendered documents have this name, qualify it with the kind: %s", ref, strings.Join(found, ", "))
+	}
+	return matches[0], nil
+}
+
+// checksum returns the checksum of the i-th document, with the checksums it
+// refers to resolved.
+func (r *checksumResolver) checksum(i int) (string, error) {
+	if sum, ok := r.checksums[i]; ok {
+		return sum, nil
+	}
+	d := r.docs[i]
+	if r.resolving[i] {
+		return "", errors.Errorf("%s/%s in %s refers to its own checksum", d.kind, d.name, d.file)
+	}
+	r.resolving[i] = true
+	defer delete(r.resolving, i)
+	text, err := r.replace(d.text)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(text))
+	r.checksums[i] = hex.EncodeToString(sum[:])
+	return r.checksums[i], nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestRenderChecksumOf(t *testing.T) {
+	config := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\ndata:\n  level: {{ .Values.level }}"
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    metadata:
+      annotations:
+        checksum/config: {{ checksumOf "app-config" }}
+        checksum/db: {{ checksumOf "Secret/db" }}`
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app"},
+		Templates: []*chart.File{
+			{Name: "templates/configmap.yaml", Data: []byte(config)},
+			{Name: "templates/deployment.yaml", Data: []byte(deployment)},
+		},
+	}
+	// The secret of the subchart holds the checksum of the configuration of
+	// its parent, so it changes along with it.
+	ch.AddDependency(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "db"},
+		Templates: []*chart.File{
+			{Name: "templates/secret.yaml", Data: []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n  annotations:\n    checksum/config: {{ checksumOf \"app-config\" }}\n")},
+		},
+	})
+
+	sum := func(s string) string {
+		b := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(b[:])
+	}
+	render := func(level string) map[string]string {
+		t.Helper()
+		out, err := Render(ch, map[string]interface{}{"Values": map[string]interface{}{"level": level}})
+		if err != nil {
+			t.Fatalf("failed to render chart: %s", err)
+		}
+		return out
+	}
+
+	out := render("info")
+	configSum := sum(strings.ReplaceAll(config, "{{ .Values.level }}", "info"))
+	secretSum := sum("apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n  annotations:\n    checksum/config: " + configSum)
+	if !strings.Contains(out["app/templates/deployment.yaml"], "checksum/config: "+configSum) {
+		t.Errorf("expected the checksum of the configuration, got:\n%s", out["app/templates/deployment.yaml"])
+	}
+	if !strings.Contains(out["app/templates/deployment.yaml"], "checksum/db: "+secretSum) {
+		t.Errorf("expected the checksum of the secret, got:\n%s", out["app/templates/deployment.yaml"])
+	}
+	if !strings.Contains(out["app/charts/db/templates/secret.yaml"], "checksum/config: "+configSum) {
+		t.Errorf("expected the checksum of the configuration in the subchart, got:\n%s", out["app/charts/db/templates/secret.yaml"])
+	}
+	if out["app/templates/deployment.yaml"] == render("debug")["app/templates/deployment.yaml"] {
+		t.Error("expected the checksums to change with the configuration")
+	}
+
+	// Rendering only some files still resolves the checksums over all.
+	only, err := new(Engine).RenderFiles(ch, map[string]interface{}{"Values": map[string]interface{}{"level": "info"}}, "app/templates/deployment.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(only) != 1 || only["app/templates/deployment.yaml"] != out["app/templates/deployment.yaml"] {
+		t.Errorf("expected only the deployment with resolved checksums, got %v", only)
+	}
+}
+
+func TestRenderChecksumOfErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, tpl, expect string
+	}{
+		{"missing", `{{ checksumOf "nothing" }}`, `checksumOf "nothing": no rendered document has this name`},
+		{"ambiguous", "kind: ConfigMap\nmetadata:\n  name: app\n---\nkind: Service\nmetadata:\n  name: app\n  annotations:\n    sum: {{ checksumOf \"app\" }}",
+			`checksumOf "app": several rendered documents have this name, qualify it with the kind: ConfigMap/app in c/templates/t.yaml, Service/app in c/templates/t.yaml`},
+		{"cycle", "kind: ConfigMap\nmetadata:\n  name: a\n  annotations:\n    sum: {{ checksumOf \"b\" }}\n---\nkind: ConfigMap\nmetadata:\n  name: b\n  annotations:\n    sum: {{ checksumOf \"a\" }}",
+			`refers to its own checksum`},
+		{"empty", `{{ checksumOf "" }}`, `checksumOf: a document name is required`},
+	} {
+		ch := &chart.Chart{
+			Metadata:  &chart.Metadata{Name: "c"},
+			Templates: []*chart.File{{Name: "templates/t.yaml", Data: []byte(tt.tpl)}},
+		}
+		_, err := Render(ch, map[string]interface{}{})
+		if err == nil || !strings.Contains(err.Error(), tt.expect) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.expect, err)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package engine
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"path"
 	"path/filepath"
@@ -25,6 +26,7 @@ import (
 	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/client-go/rest"
@@ -44,6 +46,18 @@ type Engine struct {
 	clientProvider *ClientProvider
 	// EnableDNS tells the engine to allow DNS lookups when rendering templates
 	EnableDNS bool
+	// MaxIncludeDepth limits how deeply include and tpl calls nest. Zero
+	// uses the default of 1000.
+	MaxIncludeDepth int
+	// TemplateTimeout limits how long rendering a single template file may
+	// take. Zero means no limit. The limit is best-effort: a rendering that
+	// timed out stops at its next write, include, tpl, until or untilStep
+	// call, and a loop over large values or a slow function call without
+	// any of those only stops once it completes.
+	TemplateTimeout time.Duration
+	// SourceComments adds a comment identifying the template, its lines and
+	// its chart to each rendered YAML document, see ParseSourceComment.
+	SourceComments bool
 }
 
 // New creates a new instance of Engine using the passed in rest config.
@@ -78,6 +92,46 @@ func (e Engine) Render(chrt *chart.Chart, values chartutil.Values) (map[string]s
 	return e.render(tmap)
 }
 
+// RenderFiles renders only the given templates of a chart, such as
+// "mychart/templates/deployment.yaml", with the named templates of the chart
+// and its dependencies available to them.
+func (e Engine) RenderFiles(chrt *chart.Chart, values chartutil.Values, names ...string) (map[string]string, error) {
+	tmap := allTemplates(chrt, values)
+	only := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := tmap[name]; !ok {
+			return nil, errors.Errorf("template %s not found in chart %s", name, chrt.Name())
+		}
+		only[name] = true
+	}
+	return e.renderTemplates(tmap, only)
+}
+
+// Eval renders a template text, such as a single expression, in the render
+// context of the chart. The text has access to the values, capabilities and
+// files of the chart as well as to its named templates and those of its
+// dependencies.
+func (e Engine) Eval(chrt *chart.Chart, values chartutil.Values, text string) (string, error) {
+	tmap := make(map[string]renderable)
+	vals := recAllTpls(chrt, tmap, values)
+	basePath := path.Join(chrt.ChartFullPath(), "templates")
+	name := path.Join(basePath, evalTemplateName)
+	tmap[name] = renderable{
+		tpl:        text,
+		vals:       vals,
+		basePath:   basePath,
+		extensions: chrt.Metadata.HasFeature(chart.FeatureTemplateExtensions),
+	}
+	rendered, err := e.renderTemplates(tmap, map[string]bool{name: true})
+	if err != nil {
+		return "", err
+	}
+	return rendered[name], nil
+}
+
+// evalTemplateName is the name of the template Eval renders a text as.
+const evalTemplateName = "<eval>"
+
 // Render takes a chart, optional values, and value overrides, and attempts to
 // render the Go templates using the default options.
 func Render(chrt *chart.Chart, values chartutil.Values) (map[string]string, error) {
@@ -112,6 +166,8 @@ type renderable struct {
 	vals chartutil.Values
 	// namespace prefix to the templates of the current chart
 	basePath string
+	// extensions is set when the chart opts into the extension functions.
+	extensions bool
 }
 
 const warnStartDelim = "HELM_ERR_START"
@@ -124,29 +180,148 @@ func warnWrap(warn string) string {
 	return warnStartDelim + warn + warnEndDelim
 }
 
+// renderState tracks the execution of the template file being rendered.
+type renderState struct {
+	// stack holds the names of the nested include and tpl calls.
+	stack    []string
+	maxDepth int
+
+	// template is the file being rendered, and deadline the time its
+	// rendering times out at, if any.
+	template string
+	timeout  time.Duration
+	deadline time.Time
+}
+
+func newRenderState(maxDepth int) *renderState {
+	if maxDepth <= 0 {
+		maxDepth = recursionMaxNums
+	}
+	return &renderState{maxDepth: maxDepth}
+}
+
+// begin resets the state to render the given file.
+func (s *renderState) begin(filename string, timeout time.Duration) {
+	s.stack = s.stack[:0]
+	s.template = filename
+	s.timeout = timeout
+	s.deadline = time.Time{}
+	if timeout > 0 {
+		s.deadline = time.Now().Add(timeout)
+	}
+}
+
+// expired returns an error once the rendering of the file timed out.
+func (s *renderState) expired() error {
+	if s.deadline.IsZero() || time.Now().Before(s.deadline) {
+		return nil
+	}
+	return errors.Errorf("rendering %s exceeded the timeout of %s", s.template, s.timeout)
+}
+
+// enter records a nested include or tpl call, failing once the calls nest
+// deeper than allowed. The error names the cycle the call belongs to.
+func (s *renderState) enter(name string) error {
+	if err := s.expired(); err != nil {
+		return errors.New(warnWrap(err.Error()))
+	}
+	if len(s.stack) >= s.maxDepth {
+		return errors.New(warnWrap(fmt.Sprintf("include cycle %s exceeds the maximum depth of %d; rendering template has a nested reference name: %s: unable to execute template",
+			strings.Join(s.cycle(name), " -> "), s.maxDepth, name)))
+	}
+	s.stack = append(s.stack, name)
+	return nil
+}
+
+func (s *renderState) leave() {
+	s.stack = s.stack[:len(s.stack)-1]
+}
+
+// cycle returns the calls from the latest call to name to the stack top,
+// followed by name, or the last calls if name was not called before.
+func (s *renderState) cycle(name string) []string {
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		if s.stack[i] == name {
+			return append(append([]string{}, s.stack[i:]...), name)
+		}
+	}
+	start := len(s.stack) - 5
+	if start < 0 {
+		start = 0
+	}
+	return append(append([]string{}, s.stack[start:]...), name)
+}
+
+// deadlineWriter fails the writes of a template once its rendering timed
+// out, which aborts the execution.
+type deadlineWriter struct {
+	w     io.Writer
+	state *renderState
+}
+
+func (d deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.state.expired(); err != nil {
+		return 0, err
+	}
+	return d.w.Write(p)
+}
+
+// untilFun replaces sprig's until, see untilStepFun.
+func untilFun(state *renderState) func(int) ([]int, error) {
+	untilStep := untilStepFun(state)
+	return func(count int) ([]int, error) {
+		step := 1
+		if count < 0 {
+			step = -1
+		}
+		return untilStep(0, count, step)
+	}
+}
+
+// untilStepFun replaces sprig's untilStep so that ranging over long
+// sequences stops once the rendering timed out, both while the sequence is
+// built and at the next sequence a nested range builds.
+func untilStepFun(state *renderState) func(int, int, int) ([]int, error) {
+	return func(start, stop, step int) ([]int, error) {
+		v := []int{}
+		if (stop < start && step >= 0) || (stop >= start && step <= 0) {
+			return v, nil
+		}
+		for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+			if len(v)%4096 == 0 {
+				if err := state.expired(); err != nil {
+					return nil, err
+				}
+			}
+			v = append(v, i)
+		}
+		return v, nil
+	}
+}
+
 // 'include' needs to be defined in the scope of a 'tpl' template as
 // well as regular file-loaded templates.
-func includeFun(t *template.Template, includedNames map[string]int) func(string, interface{}) (string, error) {
+func includeFun(t *template.Template, state *renderState) func(string, interface{}) (string, error) {
 	return func(name string, data interface{}) (string, error) {
 		var buf strings.Builder
-		if v, ok := includedNames[name]; ok {
-			if v > recursionMaxNums {
-				return "", errors.Wrapf(fmt.Errorf("unable to execute template"), "rendering template has a nested reference name: %s", name)
-			}
-			includedNames[name]++
-		} else {
-			includedNames[name] = 1
+		if err := state.enter(name); err != nil {
+			return "", err
 		}
-		err := t.ExecuteTemplate(&buf, name, data)
-		includedNames[name]--
+		err := t.ExecuteTemplate(deadlineWriter{&buf, state}, name, data)
+		state.leave()
 		return buf.String(), err
 	}
 }
 
 // As does 'tpl', so that nested calls to 'tpl' see the templates
 // defined by their enclosing contexts.
-func tplFun(parent *template.Template, includedNames map[string]int, strict bool) func(string, interface{}) (string, error) {
+func tplFun(parent *template.Template, state *renderState, strict bool, extensions map[string]bool) func(string, interface{}) (string, error) {
 	return func(tpl string, vals interface{}) (string, error) {
+		if err := state.enter("tpl"); err != nil {
+			return "", err
+		}
+		defer state.leave()
+
 		t, err := parent.Clone()
 		if err != nil {
 			return "", errors.Wrapf(err, "cannot clone template")
@@ -164,8 +339,8 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 		// Re-inject 'include' so that it can close over our clone of t;
 		// this lets any 'define's inside tpl be 'include'd.
 		t.Funcs(template.FuncMap{
-			"include": includeFun(t, includedNames),
-			"tpl":     tplFun(t, includedNames, strict),
+			"include": includeFun(t, state),
+			"tpl":     tplFun(t, state, strict, extensions),
 		})
 
 		// We need a .New template, as template text which is just blanks
@@ -179,8 +354,17 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 			return "", errors.Wrapf(err, "cannot parse template %q", tpl)
 		}
 
+		// The text follows the functions of the chart whose template calls tpl.
+		if extensions[calledFrom(vals)] {
+			for _, tt := range t.Templates() {
+				if tt.Tree != nil && tt.Tree.ParseName == parent.Name() {
+					useExtensions(tt.Tree.Root)
+				}
+			}
+		}
+
 		var buf strings.Builder
-		if err := t.Execute(&buf, vals); err != nil {
+		if err := t.Execute(deadlineWriter{&buf, state}, vals); err != nil {
 			return "", errors.Wrapf(err, "error during tpl function execution for %q", tpl)
 		}
 
@@ -189,14 +373,39 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 	}
 }
 
+// calledFrom returns the name of the template file rendering with the given
+// values, if known.
+func calledFrom(vals interface{}) string {
+	var v chartutil.Values
+	switch m := vals.(type) {
+	case chartutil.Values:
+		v = m
+	case map[string]interface{}:
+		v = m
+	default:
+		return ""
+	}
+	name, err := v.PathValue("Template.Name")
+	if err != nil {
+		return ""
+	}
+	s, _ := name.(string)
+	return s
+}
+
 // initFunMap creates the Engine's FuncMap and adds context-specific functions.
-func (e Engine) initFunMap(t *template.Template) {
+//
+// extensions holds the template files whose chart opts into the extension
+// functions.
+func (e Engine) initFunMap(t *template.Template, state *renderState, extensions map[string]bool) {
 	funcMap := funcMap()
-	includedNames := make(map[string]int)
+	addExtensionFuncs(funcMap)
 
 	// Add the template-rendering functions here so we can close over t.
-	funcMap["include"] = includeFun(t, includedNames)
-	funcMap["tpl"] = tplFun(t, includedNames, e.Strict)
+	funcMap["include"] = includeFun(t, state)
+	funcMap["tpl"] = tplFun(t, state, e.Strict, extensions)
+	funcMap["until"] = untilFun(state)
+	funcMap["untilStep"] = untilStepFun(state)
 
 	// Add the `required` function here so we can use lintMode
 	funcMap["required"] = func(warn string, val interface{}) (interface{}, error) {
@@ -248,7 +457,13 @@ func (e Engine) initFunMap(t *template.Template) {
 }
 
 // render takes a map of templates/values and renders them.
-func (e Engine) render(tpls map[string]renderable) (rendered map[string]string, err error) {
+func (e Engine) render(tpls map[string]renderable) (map[string]string, error) {
+	return e.renderTemplates(tpls, nil)
+}
+
+// renderTemplates parses all of the templates and renders those in only, or
+// all but the partials when only is nil.
+func (e Engine) renderTemplates(tpls map[string]renderable, only map[string]bool) (rendered map[string]string, err error) {
 	// Basically, what we do here is start with an empty parent template and then
 	// build up a list of templates -- one for each file. Once all of the templates
 	// have been parsed, we loop through again and execute every template.
@@ -270,7 +485,23 @@ func (e Engine) render(tpls map[string]renderable) (rendered map[string]string,
 		t.Option("missingkey=zero")
 	}
 
-	e.initFunMap(t)
+	extensions := make(map[string]bool)
+	for filename, r := range tpls {
+		if r.extensions {
+			extensions[filename] = true
+		}
+	}
+	state := newRenderState(e.MaxIncludeDepth)
+	e.initFunMap(t, state, extensions)
+
+	// Let the files of the charts using the extensions render themselves.
+	render := tplFun(t, state, e.Strict, extensions)
+	for filename := range extensions {
+		if f, ok := tpls[filename].vals["Files"].(files); ok {
+			fileRenderers.Store(f.key(), render)
+			defer fileRenderers.Delete(f.key())
+		}
+	}
 
 	// We want to parse the templates in a predictable order. The order favors
 	// higher-level (in file system) templates over deeply nested templates.
@@ -283,30 +514,97 @@ func (e Engine) render(tpls map[string]renderable) (rendered map[string]string,
 		}
 	}
 
+	// Named templates keep the functions of the chart defining them, whichever
+	// chart includes them.
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil && extensions[tt.Tree.ParseName] {
+			useExtensions(tt.Tree.Root)
+		}
+	}
+
 	rendered = make(map[string]string, len(keys))
+	checksums := false
 	for _, filename := range keys {
 		// Don't render partials. We don't care out the direct output of partials.
 		// They are only included from other templates.
 		if strings.HasPrefix(path.Base(filename), "_") {
 			continue
 		}
-		// At render time, add information about the template that is being rendered.
-		vals := tpls[filename].vals
-		vals["Template"] = chartutil.Values{"Name": filename, "BasePath": tpls[filename].basePath}
-		var buf strings.Builder
-		if err := t.ExecuteTemplate(&buf, filename, vals); err != nil {
-			return map[string]string{}, cleanupExecError(filename, err)
+		if only != nil && !only[filename] {
+			continue
 		}
+		if rendered[filename], err = e.renderFile(t, tpls, filename, state); err != nil {
+			return map[string]string{}, err
+		}
+		checksums = checksums || checksumPlaceholder.MatchString(rendered[filename])
+	}
 
-		// Work around the issue where Go will emit "<no value>" even if Options(missing=zero)
-		// is set. Since missing=error will never get here, we do not need to handle
-		// the Strict case.
-		rendered[filename] = strings.ReplaceAll(buf.String(), "<no value>", "")
+	// The checksums of checksumOf are resolved over the documents of all
+	// templates, including those not asked for.
+	if checksums {
+		docs := rendered
+		if only != nil {
+			docs = make(map[string]string, len(keys))
+			for _, filename := range keys {
+				if strings.HasPrefix(path.Base(filename), "_") {
+					continue
+				}
+				if docs[filename], err = e.renderFile(t, tpls, filename, state); err != nil {
+					return map[string]string{}, err
+				}
+			}
+		}
+		if err := resolveChecksums(rendered, docs); err != nil {
+			return map[string]string{}, err
+		}
 	}
 
+	if e.SourceComments {
+		for filename := range rendered {
+			rendered[filename] = addSourceComments(filename, tpls[filename].basePath, tpls[filename].tpl, rendered[filename])
+		}
+	}
 	return rendered, nil
 }
 
+// renderFile executes the template of the given file.
+func (e Engine) renderFile(t *template.Template, tpls map[string]renderable, filename string, state *renderState) (string, error) {
+	// At render time, add information about the template that is being rendered.
+	vals := tpls[filename].vals
+	vals["Template"] = chartutil.Values{"Name": filename, "BasePath": tpls[filename].basePath}
+	var buf strings.Builder
+	state.begin(filename, e.TemplateTimeout)
+	if err := executeTemplate(t, &buf, filename, vals, state); err != nil {
+		return "", cleanupExecError(filename, err)
+	}
+
+	// Work around the issue where Go will emit "<no value>" even if Options(missing=zero)
+	// is set. Since missing=error will never get here, we do not need to handle
+	// the Strict case.
+	out := strings.ReplaceAll(buf.String(), "<no value>", "")
+
+	// Subcharts are rendered first, so the notes of a subchart are
+	// available to its parent as .Subcharts.<name>.Notes.
+	if filename == path.Join(tpls[filename].basePath, notesFileSuffix) {
+		vals["Notes"] = out
+	}
+	return out, nil
+}
+
+// executeTemplate renders the named template. Once the rendering timed out,
+// includes, tpl calls, until and untilStep calls and writes fail, so that the
+// execution stops at the next of them, and a rendering that completes late
+// fails too.
+func executeTemplate(t *template.Template, buf *strings.Builder, name string, vals chartutil.Values, state *renderState) error {
+	if state.deadline.IsZero() {
+		return t.ExecuteTemplate(buf, name, vals)
+	}
+	if err := t.ExecuteTemplate(deadlineWriter{buf, state}, name, vals); err != nil {
+		return err
+	}
+	return state.expired()
+}
+
 func cleanupParseError(filename string, err error) error {
 	tokens := strings.Split(err.Error(), ": ")
 	if len(tokens) == 1 {
@@ -418,9 +716,10 @@ func recAllTpls(c *chart.Chart, templates map[string]renderable, vals chartutil.
 			continue
 		}
 		templates[path.Join(newParentID, t.Name)] = renderable{
-			tpl:      string(t.Data),
-			vals:     next,
-			basePath: path.Join(newParentID, "templates"),
+			tpl:        string(t.Data),
+			vals:       next,
+			basePath:   path.Join(newParentID, "templates"),
+			extensions: c.Metadata.HasFeature(chart.FeatureTemplateExtensions),
 		}
 	}
 
@@ -19,10 +19,12 @@ package engine
 import (
 	"fmt"
 	"path"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
 	"text/template"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -136,6 +138,42 @@ func TestRender(t *testing.T) {
 	}
 }
 
+func TestEvalAndRenderFiles(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "moby.name" }}{{ .Chart.Name }}-{{ .Values.name }}{{ end }}`)},
+			{Name: "templates/good", Data: []byte(`{{ include "moby.name" . }}`)},
+			{Name: "templates/bad", Data: []byte(`{{ fail "bad" }}`)},
+		},
+	}
+	vals := chartutil.Values{
+		"Values":       chartutil.Values{"name": "dick"},
+		"Capabilities": chartutil.DefaultCapabilities,
+	}
+
+	var e Engine
+	out, err := e.Eval(c, vals, `{{ .Values.name | b64enc }} {{ include "moby.name" . }} {{ .Capabilities.KubeVersion.Major }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "ZGljaw== moby-dick 1"; out != expect {
+		t.Errorf("Expected %q, got %q", expect, out)
+	}
+
+	files, err := e.RenderFiles(c, vals, "moby/templates/good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files["moby/templates/good"] != "moby-dick" {
+		t.Errorf("Expected only the good template to be rendered, got %v", files)
+	}
+
+	if _, err := e.RenderFiles(c, vals, "moby/templates/missing"); err == nil {
+		t.Error("Expected an error for a missing template")
+	}
+}
+
 func TestRenderRefsOrdering(t *testing.T) {
 	parentChart := &chart.Chart{
 		Metadata: &chart.Metadata{
@@ -615,6 +653,30 @@ func TestChartValuesContainsIsRoot(t *testing.T) {
 	}
 }
 
+func TestRenderSubchartNotes(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "parent"},
+		Templates: []*chart.File{
+			{Name: "templates/NOTES.txt", Data: []byte(`Installed {{ .Chart.Name }}. {{ .Subcharts.db.Notes | trim }}`)},
+		},
+	}
+	ch.AddDependency(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "db"},
+		Templates: []*chart.File{
+			{Name: "templates/NOTES.txt", Data: []byte("The password of {{ .Chart.Name }} is in a secret.\n")},
+		},
+	})
+
+	out, err := Render(ch, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("failed to render chart: %s", err)
+	}
+	expect := "Installed parent. The password of db is in a secret."
+	if got := out["parent/templates/NOTES.txt"]; got != expect {
+		t.Errorf("Expected %q, got %q", expect, got)
+	}
+}
+
 func TestRenderDependency(t *testing.T) {
 	deptpl := `{{define "myblock"}}World{{end}}`
 	toptpl := `Hello {{template "myblock"}}`
@@ -1049,6 +1111,82 @@ func TestRenderRecursionLimit(t *testing.T) {
 
 }
 
+func TestRenderIncludeDepth(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "cycle"},
+		Templates: []*chart.File{
+			{Name: "templates/base", Data: []byte(`{{include "a" . }}`)},
+			{Name: "templates/_helpers", Data: []byte(`{{define "a"}}{{include "b" . }}{{end}}{{define "b"}}{{include "a" . }}{{end}}`)},
+		},
+	}
+	v := chartutil.Values{"Values": "", "Chart": c.Metadata}
+
+	e := Engine{MaxIncludeDepth: 10}
+	_, err := e.Render(c, v)
+	expectErr := "execution error at (cycle/templates/base:1:2): include cycle a -> b -> a exceeds the maximum depth of 10"
+	if err == nil || !strings.Contains(err.Error(), expectErr) {
+		t.Errorf("Expected error containing %q, got %v", expectErr, err)
+	}
+}
+
+func TestRenderTemplateTimeout(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "slow"},
+		Templates: []*chart.File{
+			{Name: "templates/output", Data: []byte(`{{ range until 10000 }}{{ range until 2000 }}{{ . }}{{ end }}{{ end }}`)},
+			{Name: "templates/silent", Data: []byte(`{{ range until 10000 }}{{ range until 2000 }}{{ end }}{{ end }}`)},
+		},
+	}
+	v := chartutil.Values{"Values": "", "Chart": c.Metadata}
+
+	for _, name := range []string{"output", "silent"} {
+		ch := *c
+		for _, f := range c.Templates {
+			if f.Name == "templates/"+name {
+				ch.Templates = []*chart.File{f}
+			}
+		}
+		e := Engine{TemplateTimeout: 50 * time.Millisecond}
+		_, err := e.Render(&ch, v)
+		expectErr := fmt.Sprintf("rendering slow/templates/%s exceeded the timeout of 50ms", name)
+		if err == nil || !strings.Contains(err.Error(), expectErr) {
+			t.Errorf("Expected error containing %q, got %v", expectErr, err)
+		}
+	}
+
+	e := Engine{TemplateTimeout: time.Minute}
+	out, err := e.Render(&chart.Chart{
+		Metadata:  c.Metadata,
+		Templates: []*chart.File{{Name: "templates/fast", Data: []byte(`{{ .Chart.Name }}`)}},
+	}, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out["slow/templates/fast"]; got != "slow" {
+		t.Errorf("Expected %q, got %q", "slow", got)
+	}
+}
+
+func TestUntilStopsOnTimeout(t *testing.T) {
+	state := newRenderState(0)
+	state.begin("slow/templates/tight", time.Minute)
+	if got, err := untilFun(state)(3); err != nil || !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("Expected [0 1 2], got %v, %v", got, err)
+	}
+	if got, err := untilStepFun(state)(5, 0, -2); err != nil || !reflect.DeepEqual(got, []int{5, 3, 1}) {
+		t.Errorf("Expected [5 3 1], got %v, %v", got, err)
+	}
+	if got, err := untilStepFun(state)(0, 5, -1); err != nil || len(got) != 0 {
+		t.Errorf("Expected an empty sequence, got %v, %v", got, err)
+	}
+
+	state.deadline = time.Now().Add(-time.Second)
+	expectErr := "rendering slow/templates/tight exceeded the timeout of 1m0s"
+	if _, err := untilFun(state)(100000000); err == nil || err.Error() != expectErr {
+		t.Errorf("Expected error %q, got %v", expectErr, err)
+	}
+}
+
 func TestRenderLoadTemplateForTplFromFile(t *testing.T) {
 	c := &chart.Chart{
 		Metadata: &chart.Metadata{Name: "TplLoadFromFile"},
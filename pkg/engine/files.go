@@ -17,11 +17,19 @@ limitations under the License.
 package engine
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"path"
+	"reflect"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
 
 	"helm.sh/helm/v3/pkg/chart"
 )
@@ -63,6 +71,71 @@ func (f files) Get(name string) string {
 	return string(f.GetBytes(name))
 }
 
+// GetTpl renders the given file as a template with the given context, usually
+// the current one. The file can include the named templates of the chart.
+//
+// It is available to charts listing the templateExtensions feature in
+// Chart.yaml.
+//
+//	{{ .Files.GetTpl "config/app.yaml" . }}
+func (f files) GetTpl(name string, context interface{}) (string, error) {
+	render, ok := fileRenderers.Load(f.key())
+	if !ok {
+		return "", errors.New(warnWrap(fmt.Sprintf("Files.GetTpl requires the %q feature in Chart.yaml", chart.FeatureTemplateExtensions)))
+	}
+	data, ok := f[name]
+	if !ok {
+		return "", errors.New(warnWrap(fmt.Sprintf("Files.GetTpl: file %s not found", name)))
+	}
+	out, err := render.(func(string, interface{}) (string, error))(string(data), context)
+	return out, errors.Wrapf(err, "rendering file %s", name)
+}
+
+// SizeOf returns the size in bytes of the given file, or 0 if it does not
+// exist.
+//
+//	{{ if gt (.Files.SizeOf "big.bin") 1048576 }}{{ fail "big.bin exceeds 1MiB" }}{{ end }}
+func (f files) SizeOf(name string) int {
+	return len(f[name])
+}
+
+// IsBinary reports whether the given file holds binary data, that is data
+// that is not valid UTF-8 text or contains NUL bytes. Binary files should be
+// embedded with Base64.
+func (f files) IsBinary(name string) bool {
+	data := f[name]
+	return !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0
+}
+
+// Base64 returns the base64 encoding of the given file. The file is encoded
+// from its raw bytes, so binary files round-trip unchanged.
+//
+//	binaryData:
+//	  logo.png: {{ .Files.Base64 "logo.png" }}
+func (f files) Base64(name string) string {
+	data := f[name]
+	var b strings.Builder
+	b.Grow(base64.StdEncoding.EncodedLen(len(data)))
+	enc := base64.NewEncoder(base64.StdEncoding, &b)
+	enc.Write(data)
+	enc.Close()
+	return b.String()
+}
+
+// Sha256Sum returns the hex encoded SHA-256 digest of the given file.
+//
+//	checksum/config: {{ .Files.Sha256Sum "config.yaml" }}
+func (f files) Sha256Sum(name string) string {
+	sum := sha256.Sum256(f[name])
+	return hex.EncodeToString(sum[:])
+}
+
+// Digest returns the digest of the given file in the "sha256:<hex>" form used
+// by OCI and container images.
+func (f files) Digest(name string) string {
+	return "sha256:" + f.Sha256Sum(name)
+}
+
 // Glob takes a glob pattern and returns another files object only containing
 // matched  files.
 //
@@ -163,3 +236,12 @@ func (f files) Lines(path string) []string {
 	}
 	return strings.Split(s, "\n")
 }
+
+// fileRenderers holds, while a chart is rendered, the function rendering text
+// as a template for the files of each chart allowed to use GetTpl. They are
+// keyed by the files so .Files remains a plain map in templates.
+var fileRenderers sync.Map
+
+func (f files) key() uintptr {
+	return reflect.ValueOf(f).Pointer()
+}
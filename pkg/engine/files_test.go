@@ -19,6 +19,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
 )
 
 var cases = []struct {
@@ -109,3 +112,48 @@ func TestBlankLines(t *testing.T) {
 	as.Equal("bar", out[0])
 	as.Equal("", out[3])
 }
+
+func TestFileHelpers(t *testing.T) {
+	as := assert.New(t)
+
+	f := getTestFiles()
+	f["logo.png"] = []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+
+	as.Equal(11, f.SizeOf("ship/captain.txt"))
+	as.Equal(0, f.SizeOf("missing.txt"))
+
+	as.False(f.IsBinary("ship/captain.txt"))
+	as.True(f.IsBinary("logo.png"))
+
+	as.Equal("VGhlIENhcHRhaW4=", f.Base64("ship/captain.txt"))
+	as.Equal("iVBORwD/", f.Base64("logo.png"))
+
+	as.Equal("b77c5f972cc53c789e869038e0eb56bbbb3b27def54287a753472bf1c98853ef", f.Sha256Sum("story/author.txt"))
+	as.Equal("sha256:"+f.Sha256Sum("story/author.txt"), f.Digest("story/author.txt"))
+}
+
+func TestFilesGetTpl(t *testing.T) {
+	helpers := &chart.File{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "app.name" }}{{ .Chart.Name }}{{ end }}`)}
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app", Features: []string{chart.FeatureTemplateExtensions}},
+		Templates: []*chart.File{
+			helpers,
+			{Name: "templates/config", Data: []byte(`{{ .Files.GetTpl "config/app.yaml" . }}`)},
+		},
+		Files: []*chart.File{
+			{Name: "config/app.yaml", Data: []byte(`name: {{ include "app.name" . }}
+replicas: {{ .Values.replicas }}`)},
+		},
+	}
+	vals := chartutil.Values{"Values": map[string]interface{}{"replicas": 3}}
+
+	out, err := Render(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "name: app\nreplicas: 3", out["app/templates/config"])
+
+	c.Metadata.Features = nil
+	_, err = Render(c, vals)
+	assert.ErrorContains(t, err, `Files.GetTpl requires the "templateExtensions" feature in Chart.yaml`)
+}
@@ -40,6 +40,9 @@ import (
 //
 // These are late-bound in Engine.Render().  The
 // version included in the FuncMap is a placeholder.
+//
+// "checksumOf" renders a placeholder, which Engine.Render() replaces by the
+// checksum once all templates are rendered.
 func funcMap() template.FuncMap {
 	f := sprig.TxtFuncMap()
 	delete(f, "env")
@@ -54,6 +57,11 @@ func funcMap() template.FuncMap {
 		"toJson":        toJSON,
 		"fromJson":      fromJSON,
 		"fromJsonArray": fromJSONArray,
+		"k8sName":       k8sName,
+		"dns1123":       dns1123,
+		"labelValue":    labelValue,
+		"truncHash":     truncHash,
+		"checksumOf":    checksumOf,
 
 		// This is a placeholder for the "include" function, which is
 		// late-bound to a template. By declaring it here, we preserve the
@@ -0,0 +1,408 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+	yamlv3 "sigs.k8s.io/yaml/goyaml.v3"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// extensionPrefix is prepended to the name under which an extension function
+// is registered. Calls from charts that opt into the extensions are rewritten
+// to use these names, so the same template set can hold charts that do and
+// charts that do not.
+const extensionPrefix = "_ext_"
+
+// extensionFuncMap returns the functions available to the templates of charts
+// listing the templateExtensions feature in Chart.yaml.
+//
+// Functions sharing a name with a standard function replace it for those
+// charts only:
+//
+//   - "fromYaml", "fromYamlArray", "fromJson" and "fromJsonArray" fail the
+//     rendering on invalid input instead of returning the error as data.
+//   - "deepEqual" compares numbers by value, so 1 from a template equals 1
+//     from the values.
+func extensionFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"toYamlPretty":        toYAMLPretty,
+		"fromYaml":            fromYAMLStrict,
+		"fromYamlArray":       fromYAMLArrayStrict,
+		"fromJson":            fromJSONStrict,
+		"fromJsonArray":       fromJSONArrayStrict,
+		"deepEqual":           deepEqual,
+		"semverInRange":       semverInRange,
+		"semverFilter":        semverFilter,
+		"semverMaxSatisfying": semverMaxSatisfying,
+		"cidrHost":            cidrHost,
+		"cidrSubnet":          cidrSubnet,
+		"cidrContains":        cidrContains,
+	}
+}
+
+// addExtensionFuncs registers the extension functions in f. Functions without
+// a standard counterpart are also registered under their own name, failing
+// with an explanation, so templates calling them still parse.
+func addExtensionFuncs(f template.FuncMap) {
+	for name, fn := range extensionFuncMap() {
+		f[extensionPrefix+name] = fn
+		if _, ok := f[name]; !ok {
+			f[name] = extensionRequired(name)
+		}
+	}
+}
+
+func extensionRequired(name string) func(...interface{}) (interface{}, error) {
+	return func(...interface{}) (interface{}, error) {
+		return nil, errors.New(warnWrap(fmt.Sprintf("function %q requires the %q feature in Chart.yaml", name, chart.FeatureTemplateExtensions)))
+	}
+}
+
+// useExtensions rewrites the calls to extension functions in the given node
+// and its children to their extension implementation.
+func useExtensions(node parse.Node) {
+	extensions := extensionFuncMap()
+	var walk func(parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c)
+			}
+		case *parse.CommandNode:
+			for _, a := range n.Args {
+				walk(a)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IdentifierNode:
+			if _, ok := extensions[n.Ident]; ok {
+				n.Ident = extensionPrefix + n.Ident
+			}
+		case *parse.IfNode:
+			walkBranch(&n.BranchNode, walk)
+		case *parse.RangeNode:
+			walkBranch(&n.BranchNode, walk)
+		case *parse.WithNode:
+			walkBranch(&n.BranchNode, walk)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	walk(node)
+}
+
+func walkBranch(b *parse.BranchNode, walk func(parse.Node)) {
+	walk(b.Pipe)
+	walk(b.List)
+	walk(b.ElseList)
+}
+
+// toYAMLPretty marshals v to YAML indenting nested blocks, sequences included,
+// by the given number of spaces. Multi-line strings are written as literal
+// blocks.
+func toYAMLPretty(indent interface{}, v interface{}) (string, error) {
+	n, err := toInt64(indent)
+	if err != nil || n < 2 || n > 9 {
+		return "", errors.New(warnWrap(fmt.Sprintf("toYamlPretty: indent must be between 2 and 9, got %v", indent)))
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.New(warnWrap("toYamlPretty: " + err.Error()))
+	}
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return "", errors.New(warnWrap("toYamlPretty: " + err.Error()))
+	}
+	clearStyle(&doc)
+
+	var buf bytes.Buffer
+	enc := yamlv3.NewEncoder(&buf)
+	enc.SetIndent(int(n))
+	if err := enc.Encode(&doc); err != nil {
+		return "", errors.New(warnWrap("toYamlPretty: " + err.Error()))
+	}
+	if err := enc.Close(); err != nil {
+		return "", errors.New(warnWrap("toYamlPretty: " + err.Error()))
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// clearStyle drops the flow and quoting styles the JSON input left on the
+// nodes, letting the encoder pick the block style.
+func clearStyle(n *yamlv3.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+// fromYAMLStrict converts a YAML document into a map[string]interface{},
+// failing on invalid input.
+func fromYAMLStrict(str string) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(str), &m); err != nil {
+		return nil, errors.New(warnWrap("fromYaml: " + err.Error()))
+	}
+	return m, nil
+}
+
+// fromYAMLArrayStrict converts a YAML array into a []interface{}, failing on
+// invalid input.
+func fromYAMLArrayStrict(str string) ([]interface{}, error) {
+	a := []interface{}{}
+	if err := yaml.Unmarshal([]byte(str), &a); err != nil {
+		return nil, errors.New(warnWrap("fromYamlArray: " + err.Error()))
+	}
+	return a, nil
+}
+
+// fromJSONStrict converts a JSON document into a map[string]interface{},
+// failing on invalid input.
+func fromJSONStrict(str string) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(str), &m); err != nil {
+		return nil, errors.New(warnWrap("fromJson: " + err.Error()))
+	}
+	return m, nil
+}
+
+// fromJSONArrayStrict converts a JSON array into a []interface{}, failing on
+// invalid input.
+func fromJSONArrayStrict(str string) ([]interface{}, error) {
+	a := []interface{}{}
+	if err := json.Unmarshal([]byte(str), &a); err != nil {
+		return nil, errors.New(warnWrap("fromJsonArray: " + err.Error()))
+	}
+	return a, nil
+}
+
+// deepEqual reports whether a and b are equal once converted to JSON, which
+// makes the numbers parsed from values equal to the integers of templates.
+func deepEqual(a, b interface{}) bool {
+	na, errA := normalize(a)
+	nb, errB := normalize(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var n interface{}
+	err = json.Unmarshal(data, &n)
+	return n, err
+}
+
+// semverInRange reports whether version satisfies the constraint.
+func semverInRange(constraint string, version string) (bool, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, errors.New(warnWrap(fmt.Sprintf("semverInRange: invalid constraint %q: %s", constraint, err)))
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, errors.New(warnWrap(fmt.Sprintf("semverInRange: invalid version %q: %s", version, err)))
+	}
+	return c.Check(v), nil
+}
+
+// semverFilter returns the versions satisfying the constraint, lowest first.
+// Entries that are not versions are skipped.
+func semverFilter(constraint string, versions interface{}) ([]string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, errors.New(warnWrap(fmt.Sprintf("semverFilter: invalid constraint %q: %s", constraint, err)))
+	}
+	list, err := toStrings(versions)
+	if err != nil {
+		return nil, errors.New(warnWrap("semverFilter: " + err.Error()))
+	}
+	var matched semver.Collection
+	for _, s := range list {
+		v, err := semver.NewVersion(s)
+		if err != nil {
+			continue
+		}
+		if c.Check(v) {
+			matched = append(matched, v)
+		}
+	}
+	sort.Sort(matched)
+	out := make([]string, len(matched))
+	for i, v := range matched {
+		out[i] = v.Original()
+	}
+	return out, nil
+}
+
+// semverMaxSatisfying returns the highest of the versions satisfying the
+// constraint, or an empty string if none does.
+func semverMaxSatisfying(constraint string, versions interface{}) (string, error) {
+	matched, err := semverFilter(constraint, versions)
+	if err != nil || len(matched) == 0 {
+		return "", err
+	}
+	return matched[len(matched)-1], nil
+}
+
+// cidrHost returns the address of the given host number in the prefix.
+// Negative numbers count back from the end of the prefix.
+func cidrHost(prefix string, hostnum interface{}) (string, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return "", errors.New(warnWrap("cidrHost: " + err.Error()))
+	}
+	num, err := toInt64(hostnum)
+	if err != nil {
+		return "", errors.New(warnWrap("cidrHost: " + err.Error()))
+	}
+	p = p.Masked()
+	hostBits := p.Addr().BitLen() - p.Bits()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(hostBits))
+	n := big.NewInt(num)
+	if num < 0 {
+		n.Add(n, size)
+	}
+	if n.Sign() < 0 || n.Cmp(size) >= 0 {
+		return "", errors.New(warnWrap(fmt.Sprintf("cidrHost: prefix %s has no host number %d", prefix, num)))
+	}
+	return addrAdd(p.Addr(), n).String(), nil
+}
+
+// cidrSubnet returns the netnum-th subnet of the prefix extended by newbits.
+func cidrSubnet(prefix string, newbits interface{}, netnum interface{}) (string, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return "", errors.New(warnWrap("cidrSubnet: " + err.Error()))
+	}
+	bits, err := toInt64(newbits)
+	if err != nil {
+		return "", errors.New(warnWrap("cidrSubnet: " + err.Error()))
+	}
+	num, err := toInt64(netnum)
+	if err != nil {
+		return "", errors.New(warnWrap("cidrSubnet: " + err.Error()))
+	}
+	p = p.Masked()
+	length := int64(p.Bits()) + bits
+	if bits < 0 || length > int64(p.Addr().BitLen()) {
+		return "", errors.New(warnWrap(fmt.Sprintf("cidrSubnet: cannot extend prefix %s by %d bits", prefix, bits)))
+	}
+	if num < 0 || big.NewInt(num).Cmp(new(big.Int).Lsh(big.NewInt(1), uint(bits))) >= 0 {
+		return "", errors.New(warnWrap(fmt.Sprintf("cidrSubnet: prefix %s has no subnet number %d of %d bits", prefix, num, bits)))
+	}
+	offset := new(big.Int).Lsh(big.NewInt(num), uint(int64(p.Addr().BitLen())-length))
+	return netip.PrefixFrom(addrAdd(p.Addr(), offset), int(length)).String(), nil
+}
+
+// cidrContains reports whether the prefix contains the address.
+func cidrContains(prefix string, address string) (bool, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return false, errors.New(warnWrap("cidrContains: " + err.Error()))
+	}
+	a, err := netip.ParseAddr(address)
+	if err != nil {
+		return false, errors.New(warnWrap("cidrContains: " + err.Error()))
+	}
+	return p.Contains(a), nil
+}
+
+// addrAdd returns the address n after a. n must fit in the address family.
+func addrAdd(a netip.Addr, n *big.Int) netip.Addr {
+	sum := new(big.Int).SetBytes(a.AsSlice())
+	sum.Add(sum, n)
+	buf := make([]byte, a.BitLen()/8)
+	sum.FillBytes(buf)
+	out, _ := netip.AddrFromSlice(buf)
+	return out
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case float64:
+		if n == float64(int64(n)) {
+			return int64(n), nil
+		}
+	case json.Number:
+		return n.Int64()
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	return 0, errors.Errorf("%v is not an integer", v)
+}
+
+func toStrings(v interface{}) ([]string, error) {
+	switch l := v.(type) {
+	case []string:
+		return l, nil
+	case []interface{}:
+		out := make([]string, 0, len(l))
+		for _, e := range l {
+			s, ok := e.(string)
+			if !ok {
+				return nil, errors.Errorf("%v is not a string", e)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	}
+	return nil, errors.Errorf("%v is not a list of strings", v)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestExtensionFuncs(t *testing.T) {
+	tests := []struct {
+		tpl, expect string
+		vars        interface{}
+	}{{
+		tpl:    `{{ toYamlPretty 4 . }}`,
+		expect: "a:\n    b:\n        - one\n        - \"2\"\n    c: |-\n        line 1\n        line 2",
+		vars:   map[string]interface{}{"a": map[string]interface{}{"b": []string{"one", "2"}, "c": "line 1\nline 2"}},
+	}, {
+		tpl:    `{{ toYamlPretty 2 . }}`,
+		expect: "list:\n  - x: true",
+		vars:   map[string]interface{}{"list": []interface{}{map[string]interface{}{"x": true}}},
+	}, {
+		tpl:    `{{ fromYaml . }}`,
+		expect: "map[hello:world]",
+		vars:   `hello: world`,
+	}, {
+		tpl:    `{{ fromJsonArray . }}`,
+		expect: "[one 2]",
+		vars:   `["one", 2]`,
+	}, {
+		tpl:    `{{ deepEqual .a (dict "n" 1) }}`,
+		expect: "true",
+		vars:   map[string]interface{}{"a": map[string]interface{}{"n": float64(1)}},
+	}, {
+		tpl:    `{{ semverInRange ">=1.2.0 <2.0.0" "1.4.2" }} {{ semverInRange "^2" "1.4.2" }}`,
+		expect: "true false",
+	}, {
+		tpl:    `{{ semverFilter "~1.2" . }} {{ semverMaxSatisfying "~1.2" . }} {{ semverMaxSatisfying ">3" . | quote }}`,
+		expect: `[1.2.0 1.2.7 v1.2.10] v1.2.10 ""`,
+		vars:   []interface{}{"1.2.7", "1.3.0", "v1.2.10", "latest", "1.2.0"},
+	}, {
+		tpl:    `{{ cidrHost "10.0.0.0/24" 5 }} {{ cidrHost "10.0.0.0/24" -2 }} {{ cidrHost "fd00::/64" 1 }}`,
+		expect: "10.0.0.5 10.0.0.254 fd00::1",
+	}, {
+		tpl:    `{{ cidrSubnet "10.0.0.0/16" 8 3 }} {{ cidrSubnet "fd00::/48" 16 256 }}`,
+		expect: "10.0.3.0/24 fd00:0:0:100::/64",
+	}, {
+		tpl:    `{{ cidrContains "10.0.0.0/16" "10.0.42.1" }} {{ cidrContains "10.0.0.0/16" "10.1.0.1" }}`,
+		expect: "true false",
+	}}
+
+	funcs := funcMap()
+	for k, v := range extensionFuncMap() {
+		funcs[k] = v
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		err := template.Must(template.New("test").Funcs(funcs).Parse(tt.tpl)).Execute(&b, tt.vars)
+		assert.NoError(t, err, tt.tpl)
+		assert.Equal(t, tt.expect, b.String(), tt.tpl)
+	}
+
+	failures := []struct {
+		tpl, expect string
+		vars        interface{}
+	}{
+		{`{{ fromYaml . }}`, "fromYaml: error unmarshaling JSON", "- one\n- two\n"},
+		{`{{ fromJson . }}`, "fromJson: json: cannot unmarshal array", `["one"]`},
+		{`{{ toYamlPretty 1 . }}`, "indent must be between 2 and 9", "x"},
+		{`{{ semverInRange "nope" "1.0.0" }}`, `invalid constraint "nope"`, nil},
+		{`{{ cidrHost "10.0.0.0/30" 4 }}`, "has no host number 4", nil},
+		{`{{ cidrSubnet "10.0.0.0/24" 2 4 }}`, "has no subnet number 4", nil},
+		{`{{ cidrSubnet "10.0.0.0/24" 9 0 }}`, "cannot extend prefix", nil},
+	}
+	for _, tt := range failures {
+		var b strings.Builder
+		err := template.Must(template.New("test").Funcs(funcs).Parse(tt.tpl)).Execute(&b, tt.vars)
+		assert.ErrorContains(t, err, tt.expect, tt.tpl)
+	}
+}
+
+func TestRenderExtensions(t *testing.T) {
+	lib := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "lib", Type: "library", Features: []string{chart.FeatureTemplateExtensions}},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "lib.config" }}{{ toYamlPretty 4 . }}{{ end }}`)},
+		},
+	}
+	app := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app", Features: []string{chart.FeatureTemplateExtensions}},
+		Templates: []*chart.File{
+			{Name: "templates/strict", Data: []byte(`{{ tpl "{{ fromYaml .Values.doc }}" . }}`)},
+		},
+	}
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "parent"},
+		Templates: []*chart.File{
+			{Name: "templates/config", Data: []byte(`{{ include "lib.config" .Values.config }}`)},
+			{Name: "templates/tolerant", Data: []byte(`{{ (fromYaml .Values.doc).Error | empty | not }}`)},
+		},
+	}
+	parent.AddDependency(lib)
+	parent.AddDependency(app)
+
+	vals := chartutil.Values{"Values": map[string]interface{}{
+		"config": map[string]interface{}{"a": []interface{}{"b"}},
+		"doc":    "- not a map",
+		"app":    map[string]interface{}{"doc": "hello: world"},
+	}}
+	out, err := Render(parent, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{
+		"parent/templates/config":            "a:\n    - b",
+		"parent/templates/tolerant":          "true",
+		"parent/charts/app/templates/strict": "map[hello:world]",
+	}
+	for name, e := range expect {
+		assert.Equal(t, e, out[name], name)
+	}
+
+	values := vals["Values"].(map[string]interface{})
+	values["app"] = map[string]interface{}{"doc": "- not a map"}
+	_, err = Render(parent, vals)
+	assert.ErrorContains(t, err, "fromYaml: error unmarshaling JSON")
+	values["app"] = map[string]interface{}{"doc": "hello: world"}
+
+	parent.Templates = append(parent.Templates, &chart.File{Name: "templates/pretty", Data: []byte(`{{ toYamlPretty 2 .Values.config }}`)})
+	_, err = Render(parent, vals)
+	assert.ErrorContains(t, err, `function "toYamlPretty" requires the "templateExtensions" feature in Chart.yaml`)
+}
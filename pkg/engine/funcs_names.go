@@ -0,0 +1,56 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// k8sName turns s into a DNS-1123 label, see chartutil.K8sName.
+func k8sName(s string) (string, error) {
+	name, err := chartutil.K8sName(s)
+	if err != nil {
+		return "", errors.New(warnWrap("k8sName: " + err.Error()))
+	}
+	return name, nil
+}
+
+// dns1123 turns s into a DNS-1123 subdomain, see chartutil.DNS1123Subdomain.
+func dns1123(s string) (string, error) {
+	name, err := chartutil.DNS1123Subdomain(s)
+	if err != nil {
+		return "", errors.New(warnWrap("dns1123: " + err.Error()))
+	}
+	return name, nil
+}
+
+// labelValue turns s into a valid label value, see chartutil.LabelValue. It
+// is the same normalization Helm applies to the labels it sets itself.
+func labelValue(s string) string {
+	return chartutil.LabelValue(s)
+}
+
+// truncHash truncates s to at most max characters, see chartutil.TruncHash.
+func truncHash(max int, s string) (string, error) {
+	v, err := chartutil.TruncHash(max, s)
+	if err != nil {
+		return "", errors.New(warnWrap("truncHash: " + err.Error()))
+	}
+	return v, nil
+}
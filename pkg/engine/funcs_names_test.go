@@ -0,0 +1,99 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameFuncs(t *testing.T) {
+	long := strings.Repeat("a", 60) + "-release-name"
+	longer := strings.Repeat("a", 60) + "-release-other"
+
+	for _, tt := range []struct {
+		name   string
+		fn     func(string) (string, error)
+		in     string
+		expect string
+		err    string
+	}{
+		{"k8sName", k8sName, "My_App.v2", "my-app-v2", ""},
+		{"k8sName", k8sName, "--web--", "web", ""},
+		{"k8sName", k8sName, "ünïcode", "n-code", ""},
+		{"k8sName", k8sName, "!!!", "", `k8sName: "!!!" cannot be made a valid name`},
+		{"dns1123", dns1123, "API.Example.com", "api.example.com", ""},
+		{"dns1123", dns1123, ".svc_name.", "svc-name", ""},
+		{"labelValue", withNoError(labelValue), "1.2.3+build/7", "1.2.3-build-7", ""},
+		{"labelValue", withNoError(labelValue), "Chart_Name", "Chart_Name", ""},
+		{"labelValue", withNoError(labelValue), "", "", ""},
+	} {
+		got, err := tt.fn(tt.in)
+		if tt.err != "" {
+			assert.ErrorContains(t, err, tt.err, tt.name)
+			continue
+		}
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expect, got, "%s(%q)", tt.name, tt.in)
+	}
+
+	// Truncated names keep a hash of the whole name, so they stay different
+	// and valid.
+	a, err := k8sName(long)
+	assert.NoError(t, err)
+	b, err := k8sName(longer)
+	assert.NoError(t, err)
+	assert.Len(t, a, 63)
+	assert.NotEqual(t, a, b)
+	assert.True(t, strings.HasPrefix(a, strings.Repeat("a", 54)+"-"), a)
+
+	v := labelValue(strings.Repeat("x", 54) + "..." + strings.Repeat("y", 10))
+	assert.Len(t, v, 63)
+	assert.NotContains(t, v, ".-")
+}
+
+func withNoError(fn func(string) string) func(string) (string, error) {
+	return func(s string) (string, error) { return fn(s), nil }
+}
+
+func TestTruncHash(t *testing.T) {
+	got, err := truncHash(20, "short")
+	assert.NoError(t, err)
+	assert.Equal(t, "short", got)
+
+	got, err = truncHash(20, "a-very-long-identifier-indeed")
+	assert.NoError(t, err)
+	assert.Len(t, got, 20)
+	assert.Equal(t, "a-very-long-", got[:12])
+
+	_, err = truncHash(9, "a-very-long-identifier")
+	assert.ErrorContains(t, err, "truncHash: length must be greater than 9")
+}
+
+func TestRenderNameFuncs(t *testing.T) {
+	tpl := `{{ printf "%s-%s" .Release.Name .Chart.Name | k8sName }} {{ .Chart.Version | labelValue }} {{ .Release.Name | truncHash 63 }}`
+	vals := map[string]interface{}{
+		"Release": map[string]interface{}{"Name": "Prod_DB"},
+		"Chart":   map[string]interface{}{"Name": "postgres", "Version": "1.0.0+meta"},
+	}
+	var out strings.Builder
+	assert.NoError(t, template.Must(template.New("t").Funcs(funcMap()).Parse(tpl)).Execute(&out, vals))
+	assert.Equal(t, "prod-db-postgres 1.0.0-meta Prod_DB", out.String())
+}
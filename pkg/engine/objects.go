@@ -0,0 +1,118 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/yamlstream"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// notesFileSuffix is the suffix of the templates rendering the release notes,
+// which are text rather than objects.
+const notesFileSuffix = "NOTES.txt"
+
+// RenderedObject is a Kubernetes object rendered from a template of a chart.
+type RenderedObject struct {
+	// Object is the parsed object.
+	Object *unstructured.Unstructured
+	// Template is the name of the template the object was rendered from,
+	// e.g. "mychart/templates/deployment.yaml".
+	Template string
+	// Index is the position of the object among the objects rendered by the
+	// template, starting at 0.
+	Index int
+	// Line is the line of the rendered output of the template at which the
+	// document of the object starts, starting at 1.
+	Line int
+}
+
+// RenderToObjects renders a chart like Render and parses the output of each
+// template into objects, so that callers post-processing the output do not
+// need to parse the YAML themselves.
+//
+// The objects are returned by template name, in the order they were rendered.
+// The release notes and templates rendering no objects are left out.
+func (e Engine) RenderToObjects(chrt *chart.Chart, values chartutil.Values) (map[string][]RenderedObject, error) {
+	rendered, err := e.Render(chrt, values)
+	if err != nil {
+		return nil, err
+	}
+	return ParseObjects(rendered)
+}
+
+// ParseObjects parses the output of Render into objects by template name, as
+// returned by RenderToObjects.
+func ParseObjects(rendered map[string]string) (map[string][]RenderedObject, error) {
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	objects := make(map[string][]RenderedObject)
+	for _, name := range names {
+		if strings.HasSuffix(name, notesFileSuffix) {
+			continue
+		}
+		objs, err := parseTemplateObjects(name, rendered[name])
+		if err != nil {
+			return nil, err
+		}
+		if len(objs) > 0 {
+			objects[name] = objs
+		}
+	}
+	return objects, nil
+}
+
+// parseTemplateObjects splits the output of a template into YAML documents and
+// parses each of them. Empty documents are skipped.
+func parseTemplateObjects(name, content string) ([]RenderedObject, error) {
+	var objs []RenderedObject
+	r := yamlstream.NewReader(strings.NewReader(content))
+	for {
+		doc, err := r.Next()
+		if err == io.EOF {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal(doc.Data, &obj); err != nil {
+			return nil, errors.Wrapf(err, "%s: unable to parse document at line %d", name, doc.Line)
+		}
+		if obj == nil {
+			// The document only contains comments.
+			continue
+		}
+		u := &unstructured.Unstructured{Object: obj}
+		if u.GetAPIVersion() == "" || u.GetKind() == "" {
+			return nil, errors.Errorf("%s: document at line %d is not a Kubernetes object: apiVersion and kind are required", name, doc.Line)
+		}
+		objs = append(objs, RenderedObject{Object: u, Template: name, Index: len(objs), Line: doc.Line})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestRenderToObjects(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/configmaps.yaml", Data: []byte("# leading comment\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name }}\n---\n# only a comment\n--- # second\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: second\n")},
+			{Name: "templates/empty.yaml", Data: []byte("{{- if false }}\nkind: Secret\n{{- end }}")},
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "x" }}kind: Secret{{ end }}`)},
+			{Name: "templates/NOTES.txt", Data: []byte("Thanks for installing")},
+		},
+	}
+	vals := map[string]interface{}{"Values": map[string]interface{}{"name": "first"}}
+
+	objects, err := Engine{}.RenderToObjects(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("expected objects of a single template, got %v", objects)
+	}
+	objs := objects["moby/templates/configmaps.yaml"]
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objs))
+	}
+	for i, expect := range []struct {
+		name string
+		line int
+	}{{"first", 1}, {"second", 9}} {
+		if objs[i].Object.GetName() != expect.name || objs[i].Index != i || objs[i].Line != expect.line {
+			t.Errorf("expected object %d to be %q at line %d, got %q at index %d line %d", i, expect.name, expect.line, objs[i].Object.GetName(), objs[i].Index, objs[i].Line)
+		}
+		if objs[i].Object.GetKind() != "ConfigMap" || objs[i].Template != "moby/templates/configmaps.yaml" {
+			t.Errorf("unexpected object %d: %v from %s", i, objs[i].Object, objs[i].Template)
+		}
+	}
+}
+
+func TestParseObjectsErrors(t *testing.T) {
+	for content, expect := range map[string]string{
+		"apiVersion: v1\nkind: ConfigMap\n---\nfoo: [": "t.yaml: unable to parse document at line 4",
+		"apiVersion: v1\n---\nfoo: bar":                "t.yaml: document at line 1 is not a Kubernetes object",
+	} {
+		_, err := ParseObjects(map[string]string{"t.yaml": content})
+		if err == nil || !strings.HasPrefix(err.Error(), expect) {
+			t.Errorf("expected error %q, got %v", expect, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/internal/yamlstream"
+)
+
+// SourceLocation identifies the template a rendered document comes from.
+type SourceLocation struct {
+	// Template is the path of the template, e.g. "parent/charts/sub/templates/service.yaml".
+	Template string `json:"template"`
+	// Chart is the name of the chart of the template.
+	Chart string `json:"chart"`
+	// StartLine and EndLine are the lines of the template the document
+	// comes from.
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// String returns the source comment of the location.
+func (l SourceLocation) String() string {
+	return fmt.Sprintf("# Template: %s:%d-%d (chart: %s)", l.Template, l.StartLine, l.EndLine, l.Chart)
+}
+
+var sourceCommentRegex = regexp.MustCompile(`(?m)^# Template: (\S+):(\d+)-(\d+) \(chart: ([^)]+)\)\s*$`)
+
+// ParseSourceComment returns the location of the source comment of a rendered
+// document, if it has one.
+func ParseSourceComment(doc string) (SourceLocation, bool) {
+	m := sourceCommentRegex.FindStringSubmatch(doc)
+	if m == nil {
+		return SourceLocation{}, false
+	}
+	start, _ := strconv.Atoi(m[2])
+	end, _ := strconv.Atoi(m[3])
+	return SourceLocation{Template: m[1], Chart: m[4], StartLine: start, EndLine: end}, true
+}
+
+// documentRanges returns the first and last line of each document of a
+// template, counting from one.
+func documentRanges(tpl string) [][2]int {
+	lines := strings.Split(strings.TrimSuffix(tpl, "\n"), "\n")
+	var ranges [][2]int
+	start := 1
+	for i, line := range lines {
+		if yamlstream.IsSeparator(line) {
+			ranges = append(ranges, [2]int{start, i})
+			start = i + 2
+		}
+	}
+	return append(ranges, [2]int{start, len(lines)})
+}
+
+// addSourceComments adds a source comment to each document of a rendered
+// YAML template. The lines of a document are those of the corresponding
+// document of the template when the template renders as many documents as
+// it has, e.g. when it does not render documents in a loop, and those of the
+// whole template otherwise.
+func addSourceComments(filename, basePath, tpl, rendered string) string {
+	if ext := path.Ext(filename); ext != ".yaml" && ext != ".yml" {
+		return rendered
+	}
+
+	ranges := documentRanges(tpl)
+	lines := strings.Split(rendered, "\n")
+	documents := 1
+	for _, line := range lines {
+		if yamlstream.IsSeparator(line) {
+			documents++
+		}
+	}
+	if documents != len(ranges) {
+		ranges = [][2]int{{1, ranges[len(ranges)-1][1]}}
+	}
+
+	location := SourceLocation{Template: filename, Chart: path.Base(path.Dir(basePath))}
+	var b strings.Builder
+	doc, commented := 0, false
+	for i, line := range lines {
+		if yamlstream.IsSeparator(line) {
+			doc++
+			commented = false
+		} else if !commented && strings.TrimSpace(line) != "" {
+			r := ranges[0]
+			if len(ranges) > 1 {
+				r = ranges[doc]
+			}
+			location.StartLine, location.EndLine = r[0], r[1]
+			b.WriteString(location.String())
+			b.WriteString("\n")
+			commented = true
+		}
+		b.WriteString(line)
+		if i < len(lines)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
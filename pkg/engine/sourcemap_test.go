@@ -0,0 +1,69 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestSourceComments(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "parent", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/docs.yaml", Data: []byte("a: 1\n---\n{{- if .Values.b }}\nb: 2\n{{- end }}\n---\nc: 3\n")},
+			{Name: "templates/loop.yaml", Data: []byte("{{- range .Values.items }}\n---\nitem: {{ . }}\n{{- end }}\n")},
+			{Name: "templates/NOTES.txt", Data: []byte("notes")},
+		},
+	}
+	sub := &chart.Chart{
+		Metadata:  &chart.Metadata{Name: "sub", Version: "1.2.3"},
+		Templates: []*chart.File{{Name: "templates/sub.yaml", Data: []byte("sub: true\n")}},
+	}
+	c.AddDependency(sub)
+
+	vals := chartutil.Values{"Values": chartutil.Values{"b": false, "items": []interface{}{1, 2}}}
+	out, err := Engine{SourceComments: true}.Render(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"parent/templates/docs.yaml":           "# Template: parent/templates/docs.yaml:1-1 (chart: parent)\na: 1\n---\n---\n# Template: parent/templates/docs.yaml:7-7 (chart: parent)\nc: 3\n",
+		"parent/templates/loop.yaml":           "\n---\n# Template: parent/templates/loop.yaml:1-4 (chart: parent)\nitem: 1\n---\n# Template: parent/templates/loop.yaml:1-4 (chart: parent)\nitem: 2\n",
+		"parent/templates/NOTES.txt":           "notes",
+		"parent/charts/sub/templates/sub.yaml": "# Template: parent/charts/sub/templates/sub.yaml:1-1 (chart: sub)\nsub: true\n",
+	}
+	for name, data := range expect {
+		if out[name] != data {
+			t.Errorf("Expected %q for %s, got %q", data, name, out[name])
+		}
+	}
+
+	location, ok := ParseSourceComment(out["parent/charts/sub/templates/sub.yaml"])
+	if !ok {
+		t.Fatal("Expected a source comment")
+	}
+	if expect := (SourceLocation{Template: "parent/charts/sub/templates/sub.yaml", Chart: "sub", StartLine: 1, EndLine: 1}); location != expect {
+		t.Errorf("Expected %+v, got %+v", expect, location)
+	}
+	if _, ok := ParseSourceComment("a: 1"); ok {
+		t.Error("Expected no source comment")
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package errcode defines a catalog of codes of the errors and warnings Helm
+reports to users.
+
+Errors carrying a code can be matched by automation with Of instead of
+parsing their messages, and their messages can be translated with
+Translations. Codes are stable: a code is never reused for another condition.
+
+The catalog does not cover every error yet: errors of the release actions and
+of the charts that users commonly handle carry a code, while other errors, e.g.
+those of the Kubernetes API, of the storage or of the repositories, have none
+and Of returns an empty code for them. Codes are added to the catalog as
+errors get one.
+*/
+package errcode // import "helm.sh/helm/v3/pkg/errcode"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Code identifies an error or a warning of the catalog, e.g. "HELM-1001".
+type Code string
+
+// Severity is the severity of an entry of the catalog.
+type Severity string
+
+// Severities of the entries of the catalog.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Codes of the catalog. Codes HELM-1xxx are about releases, HELM-2xxx about
+// charts and values, and HELM-3xxx are warnings.
+const (
+	ReleaseNotFound       Code = "HELM-1001"
+	ReleaseExists         Code = "HELM-1002"
+	NoDeployedReleases    Code = "HELM-1003"
+	OperationInProgress   Code = "HELM-1004"
+	InvalidRevision       Code = "HELM-1005"
+	ReleaseNameInUse      Code = "HELM-1006"
+	InvalidReleaseName    Code = "HELM-1007"
+	NewerReleaseSchema    Code = "HELM-1008"
+	FreezeWindowActive    Code = "HELM-1009"
+	MissingRelease        Code = "HELM-1010"
+	ReleaseNameNotAllowed Code = "HELM-1011"
+	ResourceConflict      Code = "HELM-1012"
+	InvalidManifest       Code = "HELM-1013"
+	HookFailed            Code = "HELM-1014"
+	ReleaseUnchanged      Code = "HELM-1015"
+
+	MissingChart            Code = "HELM-2001"
+	IncompatibleKubeVersion Code = "HELM-2002"
+	DeprecatedChart         Code = "HELM-2003"
+	InvalidValues           Code = "HELM-2004"
+
+	NewerReleaseSchemaShown Code = "HELM-3001"
+)
+
+// Entry describes an error or a warning of the catalog.
+type Entry struct {
+	Code     Code     `json:"code"`
+	Severity Severity `json:"severity"`
+	// Message is the English message, a format string taking the arguments
+	// of the error.
+	Message string `json:"message"`
+	// Description explains the condition to users.
+	Description string `json:"description"`
+}
+
+var catalog = map[Code]Entry{
+	ReleaseNotFound: {
+		Severity:    SeverityError,
+		Message:     "release: not found",
+		Description: "The release does not exist in the namespace, or the revision does not exist.",
+	},
+	ReleaseExists: {
+		Severity:    SeverityError,
+		Message:     "release: already exists",
+		Description: "A record of the release revision already exists in the storage.",
+	},
+	NoDeployedReleases: {
+		Severity:    SeverityError,
+		Message:     "has no deployed releases",
+		Description: "The release has no revision with the deployed status.",
+	},
+	OperationInProgress: {
+		Severity:    SeverityError,
+		Message:     "another operation (install/upgrade/rollback) is in progress",
+		Description: "The latest revision of the release has a pending status. Wait for the operation to complete, or roll back the release if it was interrupted.",
+	},
+	InvalidRevision: {
+		Severity:    SeverityError,
+		Message:     "invalid release revision",
+		Description: "Revisions are positive numbers.",
+	},
+	ReleaseNameInUse: {
+		Severity:    SeverityError,
+		Message:     "cannot re-use a name that is still in use",
+		Description: "A release of the name is installed in the namespace. Upgrade it, or uninstall it first.",
+	},
+	InvalidReleaseName: {
+		Severity:    SeverityError,
+		Message:     "invalid release name, must match regex %s and the length must not be longer than 53",
+		Description: "Release names are lowercase DNS names of at most 53 characters.",
+	},
+	NewerReleaseSchema: {
+		Severity:    SeverityError,
+		Message:     "release %s revision %d was written by Helm %s with release schema version %d, but this version of Helm only supports up to %d: upgrade Helm to modify the release",
+		Description: "A newer version of Helm wrote the release with information this version does not understand, and would lose.",
+	},
+	FreezeWindowActive: {
+		Severity:    SeverityError,
+		Message:     "%s of release %s is not allowed during freeze window %q%s (use --override-freeze to proceed anyway)",
+		Description: "A freeze window of the freeze policy forbids changing the release now.",
+	},
+	MissingRelease: {
+		Severity:    SeverityError,
+		Message:     "no release provided",
+		Description: "The operation requires the name of a release.",
+	},
+	ReleaseNameNotAllowed: {
+		Severity:    SeverityError,
+		Message:     "release name %q violates the name policy: %s",
+		Description: "The name policy enforces naming conventions for new releases, e.g. a prefix or a maximum length. Choose a name following them.",
+	},
+	ResourceConflict: {
+		Severity:    SeverityError,
+		Message:     "%s exists and cannot be imported into the current release: %s",
+		Description: "A resource of the release already exists in the cluster, but is not owned by the release. Delete it, or annotate and label it as owned by the release to adopt it.",
+	},
+	InvalidManifest: {
+		Severity:    SeverityError,
+		Message:     "unable to build kubernetes objects",
+		Description: "The manifest of the release does not describe valid Kubernetes objects, e.g. it uses a kind the cluster does not know.",
+	},
+	HookFailed: {
+		Severity:    SeverityError,
+		Message:     "warning: Hook %s %s failed",
+		Description: "A hook of the release could not be created or did not complete successfully. The logs of the hook tell why.",
+	},
+	ReleaseUnchanged: {
+		Severity:    SeverityError,
+		Message:     "release is unchanged",
+		Description: "The upgrade was skipped because it would not change the release, as asked with --skip-if-unchanged.",
+	},
+	MissingChart: {
+		Severity:    SeverityError,
+		Message:     "no chart provided",
+		Description: "The operation requires a chart.",
+	},
+	IncompatibleKubeVersion: {
+		Severity:    SeverityError,
+		Message:     "chart requires kubeVersion: %s which is incompatible with Kubernetes %s",
+		Description: "The kubeVersion constraint of the chart does not allow the version of the cluster.",
+	},
+	DeprecatedChart: {
+		Severity:    SeverityError,
+		Message:     "chart %s-%s: %s",
+		Description: "The chart is deprecated or past its end of life, and deprecated charts are refused.",
+	},
+	InvalidValues: {
+		Severity:    SeverityError,
+		Message:     "values don't meet the specifications of the schema(s) in the following chart(s):\n%s",
+		Description: "The values do not validate against the values.schema.json of the chart or of its dependencies.",
+	},
+	NewerReleaseSchemaShown: {
+		Severity:    SeverityWarning,
+		Message:     "release %s revision %d was written by Helm %s with a newer release schema: some of its information may not be shown",
+		Description: "A newer version of Helm wrote the release with information this version does not understand.",
+	},
+}
+
+// Catalog returns the entries of the catalog, ordered by code.
+func Catalog() []Entry {
+	entries := make([]Entry, 0, len(catalog))
+	for code := range catalog {
+		entry, _ := Lookup(code)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	return entries
+}
+
+// Lookup returns the entry of a code.
+func Lookup(code Code) (Entry, bool) {
+	entry, ok := catalog[code]
+	entry.Code = code
+	return entry, ok
+}
+
+// Error is an error of the catalog.
+type Error struct {
+	Code Code
+	// Args are the arguments of the message of the entry.
+	Args []interface{}
+}
+
+// New creates an error of a code of the catalog, with the arguments of its
+// message.
+func New(code Code, args ...interface{}) *Error {
+	return &Error{Code: code, Args: args}
+}
+
+// Error returns the English message of the error.
+func (e *Error) Error() string {
+	return Format(e.Code, e.Args...)
+}
+
+// Format returns the English message of a code of the catalog with the given
+// arguments, e.g. for warnings.
+func Format(code Code, args ...interface{}) string {
+	entry, ok := Lookup(code)
+	if !ok {
+		return fmt.Sprint(append([]interface{}{code}, args...)...)
+	}
+	if len(args) == 0 {
+		return entry.Message
+	}
+	return fmt.Sprintf(entry.Message, args...)
+}
+
+// Of returns the code of the first error of the catalog in the chain of err,
+// or an empty code if there is none.
+func Of(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// Translations are the messages of the catalog in another language, by code.
+// The messages are format strings taking the same arguments as the English
+// messages; explicit argument indexes such as %[2]s allow reordering them.
+type Translations map[Code]string
+
+// LoadTranslations reads translations from a YAML or JSON file mapping codes
+// to messages.
+func LoadTranslations(filename string) (Translations, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	t := Translations{}
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse translations %s: %w", filename, err)
+	}
+	return t, nil
+}
+
+// Message returns the message of an error in the language of the
+// translations. Errors without a code or a translation keep their message.
+// The translation replaces the message of the error of the catalog only: the
+// context other errors of the chain add to it is kept.
+func (t Translations) Message(err error) string {
+	var e *Error
+	if !errors.As(err, &e) {
+		return err.Error()
+	}
+	translation, ok := t[e.Code]
+	if !ok {
+		return err.Error()
+	}
+	translated := translation
+	if len(e.Args) > 0 {
+		translated = fmt.Sprintf(translation, e.Args...)
+	}
+	msg := err.Error()
+	original := e.Error()
+	if i := len(msg) - len(original); i >= 0 && msg[i:] == original {
+		return msg[:i] + translated
+	}
+	return translated
+}
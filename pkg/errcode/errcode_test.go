@@ -0,0 +1,92 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errcode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCatalog(t *testing.T) {
+	entries := Catalog()
+	if len(entries) != len(catalog) {
+		t.Fatalf("expected %d entries, got %d", len(catalog), len(entries))
+	}
+	for i, e := range entries {
+		if !strings.HasPrefix(string(e.Code), "HELM-") {
+			t.Errorf("code %q does not start with HELM-", e.Code)
+		}
+		if i > 0 && entries[i-1].Code >= e.Code {
+			t.Errorf("entries are not ordered by code: %s before %s", entries[i-1].Code, e.Code)
+		}
+		if e.Severity != SeverityError && e.Severity != SeverityWarning {
+			t.Errorf("%s: invalid severity %q", e.Code, e.Severity)
+		}
+		if e.Message == "" || e.Description == "" {
+			t.Errorf("%s: missing message or description", e.Code)
+		}
+	}
+	if _, ok := Lookup("HELM-0000"); ok {
+		t.Error("expected an unknown code not to be found")
+	}
+}
+
+func TestError(t *testing.T) {
+	err := errors.Wrap(New(IncompatibleKubeVersion, ">=1.30", "v1.29.0"), "install failed")
+	if got, want := err.Error(), "install failed: chart requires kubeVersion: >=1.30 which is incompatible with Kubernetes v1.29.0"; got != want {
+		t.Errorf("expected message %q, got %q", want, got)
+	}
+	if code := Of(err); code != IncompatibleKubeVersion {
+		t.Errorf("expected code %s, got %q", IncompatibleKubeVersion, code)
+	}
+	if code := Of(errors.New("boom")); code != "" {
+		t.Errorf("expected no code for an error outside of the catalog, got %q", code)
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "de.yaml")
+	data := `HELM-1001: "Release nicht gefunden"
+HELM-2002: "Das Chart verlangt kubeVersion %[1]s, der Cluster hat Kubernetes %[2]s"
+`
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	translations, err := LoadTranslations(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{New(ReleaseNotFound), "Release nicht gefunden"},
+		{errors.Wrap(New(ReleaseNotFound), "upgrade failed"), "upgrade failed: Release nicht gefunden"},
+		{New(IncompatibleKubeVersion, ">=1.30", "v1.29.0"), "Das Chart verlangt kubeVersion >=1.30, der Cluster hat Kubernetes v1.29.0"},
+		{New(ReleaseNameInUse), "cannot re-use a name that is still in use"},
+		{errors.New("boom"), "boom"},
+	} {
+		if got := translations.Message(tt.err); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -41,11 +41,10 @@ func collectPlugins(settings *cli.EnvSettings) (Providers, error) {
 		for _, downloader := range plugin.Metadata.Downloaders {
 			result = append(result, Provider{
 				Schemes: downloader.Protocols,
-				New: NewPluginGetter(
+				New: newSandboxedPluginGetter(
 					downloader.Command,
 					settings,
-					plugin.Metadata.Name,
-					plugin.Dir,
+					plugin,
 				),
 			})
 		}
@@ -61,6 +60,8 @@ type pluginGetter struct {
 	name     string
 	base     string
 	opts     options
+	// plugin, if set, is the plugin run, in its sandbox if it has one.
+	plugin *plugin.Plugin
 }
 
 func (p *pluginGetter) setupOptionsEnv(env []string) []string {
@@ -83,6 +84,13 @@ func (p *pluginGetter) Get(href string, options ...Option) (*bytes.Buffer, error
 	buf := bytes.NewBuffer(nil)
 	prog.Stdout = buf
 	prog.Stderr = os.Stderr
+	if p.plugin != nil {
+		cleanup, err := p.plugin.SandboxCommand(prog, p.settings.PluginSandbox)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+	}
 	if err := prog.Run(); err != nil {
 		if eerr, ok := err.(*exec.ExitError); ok {
 			os.Stderr.Write(eerr.Stderr)
@@ -95,12 +103,23 @@ func (p *pluginGetter) Get(href string, options ...Option) (*bytes.Buffer, error
 
 // NewPluginGetter constructs a valid plugin getter
 func NewPluginGetter(command string, settings *cli.EnvSettings, name, base string) Constructor {
+	return newPluginGetter(command, settings, name, base, nil)
+}
+
+// newSandboxedPluginGetter constructs a plugin getter running the plugin in
+// its sandbox, if it has one.
+func newSandboxedPluginGetter(command string, settings *cli.EnvSettings, plug *plugin.Plugin) Constructor {
+	return newPluginGetter(command, settings, plug.Metadata.Name, plug.Dir, plug)
+}
+
+func newPluginGetter(command string, settings *cli.EnvSettings, name, base string, plug *plugin.Plugin) Constructor {
 	return func(options ...Option) (Getter, error) {
 		result := &pluginGetter{
 			command:  command,
 			settings: settings,
 			name:     name,
 			base:     base,
+			plugin:   plug,
 		}
 		for _, opt := range options {
 			opt(&result.opts)
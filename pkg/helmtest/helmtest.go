@@ -0,0 +1,425 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package helmtest runs Helm actions against a real Kubernetes API server in Go
+tests, for integration tests of charts and of projects embedding Helm.
+
+A Harness connects either to an API server started by the test, e.g. with
+envtest from controller-runtime, or to a cluster of a kubeconfig file, e.g. one
+created with kind. It installs, upgrades and uninstalls charts in a namespace
+of its own, fails the test when an action fails, and asserts on the resulting
+objects and hooks:
+
+	func TestChart(t *testing.T) {
+		h := helmtest.New(t, helmtest.Options{RESTConfig: cfg}) // cfg from envtest
+		rel := h.Install("testdata/mychart", map[string]interface{}{"replicas": 2})
+		h.AssertExists("apps/v1", "Deployment", rel.Name+"-web")
+		h.AssertHookSucceeded(rel, "mychart-migrate")
+		h.Uninstall(rel.Name)
+		h.AssertNotExists("apps/v1", "Deployment", rel.Name+"-web")
+	}
+
+Without a RESTConfig, the kubeconfig file defaults to $KUBECONFIG, and the
+tests are skipped when there is none or its cluster cannot be reached, so
+that they only run where a cluster is provided. Note that API servers without
+controllers, such as envtest, never make workloads and hook jobs ready: use
+hooks that do not need to run, or a cluster such as kind, to test waiting and
+hooks.
+*/
+package helmtest // import "helm.sh/helm/v3/pkg/helmtest"
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// DefaultTimeout is the timeout of the actions of a Harness without one.
+const DefaultTimeout = 2 * time.Minute
+
+// Options configure a Harness.
+type Options struct {
+	// RESTConfig connects to the API server, e.g. one started with envtest.
+	RESTConfig *rest.Config
+	// KubeConfig is the kubeconfig file of the cluster when RESTConfig is
+	// nil, e.g. of a kind cluster. It defaults to $KUBECONFIG; the kubeconfig
+	// of the home directory is never used, so that tests do not run against
+	// the cluster of the user by accident.
+	KubeConfig string
+	// KubeContext is the context of the kubeconfig file to use.
+	KubeContext string
+	// Namespace is the namespace the charts are installed in. By default, a
+	// namespace is created for the test and deleted after it.
+	Namespace string
+	// Driver is the storage driver of the releases; "secret" by default.
+	Driver string
+	// Timeout is the timeout of the actions; DefaultTimeout by default.
+	Timeout time.Duration
+}
+
+// Harness runs Helm actions against a Kubernetes API server for a test.
+type Harness struct {
+	t       testing.TB
+	timeout time.Duration
+
+	// Namespace is the namespace the charts are installed in.
+	Namespace string
+
+	getter    *genericclioptions.ConfigFlags
+	cfg       *action.Configuration
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
+	installed map[string]bool
+}
+
+// New connects a Harness to the API server of the options. The releases it
+// installs are uninstalled when the test completes.
+func New(t testing.TB, opts Options) *Harness {
+	t.Helper()
+
+	if opts.RESTConfig == nil && opts.KubeConfig == "" {
+		opts.KubeConfig = os.Getenv(clientcmd.RecommendedConfigPathEnvVar)
+		if opts.KubeConfig == "" {
+			t.Skipf("helmtest: no cluster configured: set $%s or the options of the harness", clientcmd.RecommendedConfigPathEnvVar)
+		}
+	}
+
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = "helmtest-" + rand.String(8)
+	}
+	getter, err := restClientGetter(t, opts, namespace)
+	if err != nil {
+		t.Fatalf("helmtest: %s", err)
+	}
+	restConfig, err := getter.ToRESTConfig()
+	if err != nil {
+		if opts.RESTConfig == nil {
+			t.Skipf("helmtest: no cluster configured: %s", err)
+		}
+		t.Fatalf("helmtest: %s", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		t.Fatalf("helmtest: %s", err)
+	}
+	if _, err := clientset.Discovery().ServerVersion(); err != nil {
+		if opts.RESTConfig == nil {
+			t.Skipf("helmtest: cluster unreachable: %s", err)
+		}
+		t.Fatalf("helmtest: API server unreachable: %s", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		t.Fatalf("helmtest: %s", err)
+	}
+	mapper, err := getter.ToRESTMapper()
+	if err != nil {
+		t.Fatalf("helmtest: %s", err)
+	}
+
+	h := &Harness{
+		t:         t,
+		timeout:   opts.Timeout,
+		Namespace: namespace,
+		getter:    getter,
+		clientset: clientset,
+		dynamic:   dynamicClient,
+		mapper:    mapper,
+		installed: map[string]bool{},
+	}
+	if h.timeout == 0 {
+		h.timeout = DefaultTimeout
+	}
+	if opts.Namespace == "" {
+		h.createNamespace()
+	}
+
+	h.cfg = new(action.Configuration)
+	if err := h.cfg.Init(getter, h.Namespace, opts.Driver, t.Logf); err != nil {
+		t.Fatalf("helmtest: %s", err)
+	}
+	t.Cleanup(h.uninstallAll)
+	return h
+}
+
+// restClientGetter returns the getter of the Kubernetes clients of the
+// options, defaulting to the given namespace as the getter of the command line
+// of Helm defaults to the namespace of the command. A REST config is written to a kubeconfig file, so that the clients
+// are created the same way as by the Helm CLI.
+func restClientGetter(t testing.TB, opts Options, namespace string) (*genericclioptions.ConfigFlags, error) {
+	kubeConfig, kubeContext := opts.KubeConfig, opts.KubeContext
+	if opts.RESTConfig != nil {
+		kubeConfig = filepath.Join(t.TempDir(), "kubeconfig")
+		if err := writeKubeConfig(kubeConfig, opts.RESTConfig); err != nil {
+			return nil, err
+		}
+		kubeContext = ""
+	}
+	flags := genericclioptions.NewConfigFlags(true)
+	flags.KubeConfig = &kubeConfig
+	flags.Context = &kubeContext
+	flags.Namespace = &namespace
+	if opts.RESTConfig != nil {
+		// The discovery of API servers started by the test is not worth
+		// caching beyond the test.
+		cacheDir := filepath.Join(t.TempDir(), "cache")
+		flags.CacheDir = &cacheDir
+	}
+	return flags, nil
+}
+
+// writeKubeConfig writes a kubeconfig file connecting with a REST config.
+func writeKubeConfig(filename string, c *rest.Config) error {
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = c.Host + c.APIPath
+	cluster.CertificateAuthority = c.CAFile
+	cluster.CertificateAuthorityData = c.CAData
+	cluster.InsecureSkipTLSVerify = c.Insecure
+	cluster.TLSServerName = c.ServerName
+
+	user := clientcmdapi.NewAuthInfo()
+	user.ClientCertificate = c.CertFile
+	user.ClientCertificateData = c.CertData
+	user.ClientKey = c.KeyFile
+	user.ClientKeyData = c.KeyData
+	user.Token = c.BearerToken
+	user.TokenFile = c.BearerTokenFile
+	user.Username = c.Username
+	user.Password = c.Password
+
+	kubeContext := clientcmdapi.NewContext()
+	kubeContext.Cluster = "helmtest"
+	kubeContext.AuthInfo = "helmtest"
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["helmtest"] = cluster
+	config.AuthInfos["helmtest"] = user
+	config.Contexts["helmtest"] = kubeContext
+	config.CurrentContext = "helmtest"
+	return clientcmd.WriteToFile(*config, filename)
+}
+
+// createNamespace creates the namespace of the harness, deleted after the
+// test.
+func (h *Harness) createNamespace() {
+	h.t.Helper()
+	name := h.Namespace
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if _, err := h.clientset.CoreV1().Namespaces().Create(context.Background(), ns, metav1.CreateOptions{}); err != nil {
+		h.t.Fatalf("helmtest: failed to create namespace %s: %s", name, err)
+	}
+	h.t.Cleanup(func() {
+		err := h.clientset.CoreV1().Namespaces().Delete(context.Background(), name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			h.t.Errorf("helmtest: failed to delete namespace %s: %s", name, err)
+		}
+	})
+}
+
+// Configuration returns the action configuration of the harness, for running
+// actions the harness has no method for.
+func (h *Harness) Configuration() *action.Configuration {
+	return h.cfg
+}
+
+// Install installs a chart from a path with the given values, and fails the
+// test if the installation fails. The install action can be configured by the
+// given functions; by default the release name is generated.
+func (h *Harness) Install(chartPath string, vals map[string]interface{}, configure ...func(*action.Install)) *release.Release {
+	h.t.Helper()
+	client := action.NewInstall(h.cfg)
+	client.Namespace = h.Namespace
+	client.GenerateName = true
+	client.Timeout = h.timeout
+	for _, fn := range configure {
+		fn(client)
+	}
+	chrt := h.loadChart(chartPath)
+	if client.GenerateName && client.ReleaseName == "" {
+		name, _, err := client.NameAndChart([]string{chartPath})
+		if err != nil {
+			h.t.Fatalf("helmtest: %s", err)
+		}
+		client.ReleaseName = name
+	}
+	rel, err := client.Run(chrt, vals)
+	if rel != nil {
+		h.installed[rel.Name] = true
+	}
+	if err != nil {
+		h.t.Fatalf("helmtest: install of %s failed: %s", chartPath, err)
+	}
+	return rel
+}
+
+// Upgrade upgrades a release to a chart from a path with the given values,
+// and fails the test if the upgrade fails.
+func (h *Harness) Upgrade(name, chartPath string, vals map[string]interface{}, configure ...func(*action.Upgrade)) *release.Release {
+	h.t.Helper()
+	client := action.NewUpgrade(h.cfg)
+	client.Namespace = h.Namespace
+	client.Timeout = h.timeout
+	for _, fn := range configure {
+		fn(client)
+	}
+	rel, err := client.Run(name, h.loadChart(chartPath), vals)
+	if err != nil {
+		h.t.Fatalf("helmtest: upgrade of %s failed: %s", name, err)
+	}
+	return rel
+}
+
+// Uninstall uninstalls a release, and fails the test if the uninstallation
+// fails.
+func (h *Harness) Uninstall(name string, configure ...func(*action.Uninstall)) *release.UninstallReleaseResponse {
+	h.t.Helper()
+	client := action.NewUninstall(h.cfg)
+	client.Timeout = h.timeout
+	for _, fn := range configure {
+		fn(client)
+	}
+	res, err := client.Run(name)
+	if err != nil {
+		h.t.Fatalf("helmtest: uninstall of %s failed: %s", name, err)
+	}
+	delete(h.installed, name)
+	return res
+}
+
+// Get returns an object of the namespace of the harness, or of the cluster
+// for cluster-scoped kinds. It returns nil if the object does not exist.
+func (h *Harness) Get(apiVersion, kind, name string) *unstructured.Unstructured {
+	h.t.Helper()
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		h.t.Fatalf("helmtest: %s", err)
+	}
+	mapping, err := h.mapper.RESTMapping(gv.WithKind(kind).GroupKind(), gv.Version)
+	if err != nil {
+		h.t.Fatalf("helmtest: %s", err)
+	}
+	var client dynamic.ResourceInterface = h.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		client = h.dynamic.Resource(mapping.Resource).Namespace(h.Namespace)
+	}
+	obj, err := client.Get(context.Background(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		h.t.Fatalf("helmtest: failed to get %s %s: %s", kind, name, err)
+	}
+	return obj
+}
+
+// AssertExists fails the test if an object does not exist, and returns it
+// otherwise.
+func (h *Harness) AssertExists(apiVersion, kind, name string) *unstructured.Unstructured {
+	h.t.Helper()
+	obj := h.Get(apiVersion, kind, name)
+	if obj == nil {
+		h.t.Errorf("helmtest: expected %s %s to exist", kind, name)
+	}
+	return obj
+}
+
+// AssertNotExists fails the test if an object exists and is not being
+// deleted.
+func (h *Harness) AssertNotExists(apiVersion, kind, name string) {
+	h.t.Helper()
+	if obj := h.Get(apiVersion, kind, name); obj != nil && obj.GetDeletionTimestamp() == nil {
+		h.t.Errorf("helmtest: expected %s %s not to exist", kind, name)
+	}
+}
+
+// AssertHookSucceeded fails the test if a hook of a release did not run
+// successfully in the last operation running it.
+func (h *Harness) AssertHookSucceeded(rel *release.Release, hookName string) {
+	h.t.Helper()
+	h.assertHookPhase(rel, hookName, release.HookPhaseSucceeded)
+}
+
+// AssertHookNotRun fails the test if a hook of a release has run.
+func (h *Harness) AssertHookNotRun(rel *release.Release, hookName string) {
+	h.t.Helper()
+	h.assertHookPhase(rel, hookName, "")
+}
+
+func (h *Harness) assertHookPhase(rel *release.Release, hookName string, phase release.HookPhase) {
+	h.t.Helper()
+	for _, hook := range rel.Hooks {
+		if hook.Name != hookName {
+			continue
+		}
+		if hook.LastRun.Phase != phase {
+			h.t.Errorf("helmtest: expected hook %s of release %s to be %s, got %s", hookName, rel.Name, phaseString(phase), phaseString(hook.LastRun.Phase))
+		}
+		return
+	}
+	h.t.Errorf("helmtest: release %s has no hook %s", rel.Name, hookName)
+}
+
+func phaseString(phase release.HookPhase) string {
+	if phase == "" {
+		return "not run"
+	}
+	return phase.String()
+}
+
+func (h *Harness) loadChart(chartPath string) *chart.Chart {
+	h.t.Helper()
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		h.t.Fatalf("helmtest: failed to load chart %s: %s", chartPath, err)
+	}
+	return chrt
+}
+
+// uninstallAll uninstalls the releases the harness installed and the test
+// did not uninstall.
+func (h *Harness) uninstallAll() {
+	for name := range h.installed {
+		client := action.NewUninstall(h.cfg)
+		client.Timeout = h.timeout
+		if _, err := client.Run(name); err != nil {
+			h.t.Logf("helmtest: failed to uninstall %s: %s", name, err)
+		}
+	}
+}
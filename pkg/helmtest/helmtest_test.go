@@ -0,0 +1,160 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmtest
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestWriteKubeConfig(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "kubeconfig")
+	err := writeKubeConfig(filename, &rest.Config{
+		Host:            "https://127.0.0.1:6443",
+		BearerToken:     "token",
+		TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := clientcmd.BuildConfigFromFlags("", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Host != "https://127.0.0.1:6443" {
+		t.Errorf("expected host https://127.0.0.1:6443, got %s", c.Host)
+	}
+	if c.BearerToken != "token" {
+		t.Errorf("expected bearer token to be kept, got %q", c.BearerToken)
+	}
+	if string(c.CAData) != "ca" {
+		t.Errorf("expected CA data to be kept, got %q", c.CAData)
+	}
+}
+
+func TestNewSkipsWithoutCluster(t *testing.T) {
+	t.Setenv("KUBECONFIG", "")
+
+	var skipped bool
+	t.Run("unconfigured", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		New(t, Options{})
+		t.Error("expected the test to be skipped")
+	})
+	if !skipped {
+		t.Error("expected a test without a cluster to be skipped")
+	}
+}
+
+func TestNewSkipsUnreachableCluster(t *testing.T) {
+	server := httptest.NewServer(nil)
+	server.Close()
+
+	kubeConfig := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := writeKubeConfig(kubeConfig, &rest.Config{Host: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	var skipped bool
+	t.Run("unreachable", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		New(t, Options{KubeConfig: kubeConfig})
+		t.Error("expected the test to be skipped")
+	})
+	if !skipped {
+		t.Error("expected a test with an unreachable cluster to be skipped")
+	}
+}
+
+// TestHarness and TestHarnessAssertionsFail run against the cluster of
+// $KUBECONFIG, e.g. one created with kind, and are skipped without one.
+func TestHarness(t *testing.T) {
+	h := New(t, Options{})
+
+	rel := h.Install("testdata/mychart", map[string]interface{}{"replicas": 2})
+	if rel.Info.Status != release.StatusDeployed {
+		t.Errorf("expected release to be deployed, got %s", rel.Info.Status)
+	}
+	deployment := h.AssertExists("apps/v1", "Deployment", rel.Name+"-web")
+	if deployment.GetNamespace() != h.Namespace {
+		t.Errorf("expected deployment in namespace %s, got %s", h.Namespace, deployment.GetNamespace())
+	}
+	h.AssertExists("v1", "ConfigMap", rel.Name+"-config")
+	h.AssertHookSucceeded(rel, "mychart-installed")
+	h.AssertHookNotRun(rel, "mychart-upgraded")
+
+	rel = h.Upgrade(rel.Name, "testdata/mychart", map[string]interface{}{"greeting": "bonjour"})
+	if rel.Version != 2 {
+		t.Errorf("expected revision 2, got %d", rel.Version)
+	}
+	config := h.AssertExists("v1", "ConfigMap", rel.Name+"-config")
+	if greeting := config.Object["data"].(map[string]interface{})["greeting"]; greeting != "bonjour" {
+		t.Errorf("expected the upgrade to patch the config map, got greeting %v", greeting)
+	}
+	h.AssertHookSucceeded(rel, "mychart-upgraded")
+
+	h.Uninstall(rel.Name)
+	h.AssertNotExists("apps/v1", "Deployment", rel.Name+"-web")
+	h.AssertNotExists("v1", "ConfigMap", rel.Name+"-config")
+	if _, err := action.NewGet(h.Configuration()).Run(rel.Name); err == nil {
+		t.Error("expected the release to be uninstalled")
+	}
+}
+
+// recorder records the failures of a test instead of failing it.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestHarnessAssertionsFail(t *testing.T) {
+	h := New(t, Options{})
+	rel := h.Install("testdata/mychart", nil, func(client *action.Install) { client.ReleaseName = "failing" })
+
+	r := &recorder{TB: t}
+	h.t = r
+	h.AssertExists("apps/v1", "Deployment", "missing")
+	h.AssertNotExists("apps/v1", "Deployment", "failing-web")
+	h.AssertHookNotRun(rel, "mychart-installed")
+	h.AssertHookSucceeded(rel, "mychart-upgraded")
+	h.AssertHookSucceeded(rel, "missing")
+	h.t = t
+
+	want := []string{
+		"helmtest: expected Deployment missing to exist",
+		"helmtest: expected Deployment failing-web not to exist",
+		"helmtest: expected hook mychart-installed of release failing to be not run, got Succeeded",
+		"helmtest: expected hook mychart-upgraded of release failing to be Succeeded, got not run",
+		"helmtest: release failing has no hook missing",
+	}
+	if fmt.Sprint(r.failures) != fmt.Sprint(want) {
+		t.Errorf("expected failures %q, got %q", want, r.failures)
+	}
+}
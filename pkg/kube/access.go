@@ -0,0 +1,113 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AccessDenial is a verb the client is not allowed to use on a kind of
+// resources in a namespace.
+type AccessDenial struct {
+	Verb string
+	// Group and Resource identify the kind of resources, e.g. "apps" and
+	// "deployments".
+	Group    string
+	Resource string
+	// Namespace is the namespace of the resources, empty for cluster-scoped
+	// resources.
+	Namespace string
+	// Reason is the reason given by the authorizer, if any.
+	Reason string
+}
+
+func (d AccessDenial) String() string {
+	resource := d.Resource
+	if d.Group != "" {
+		resource += "." + d.Group
+	}
+	s := fmt.Sprintf("cannot %s %s", d.Verb, resource)
+	if d.Namespace != "" {
+		s += fmt.Sprintf(" in namespace %q", d.Namespace)
+	}
+	if d.Reason != "" {
+		s += ": " + d.Reason
+	}
+	return s
+}
+
+// ReviewAccess asks the API server with SelfSubjectAccessReviews whether the
+// client may use the verbs on the kinds of the given resources, in each of
+// their namespaces. It returns the denied accesses, sorted by namespace.
+func (c *Client) ReviewAccess(resources ResourceList, verbs ...string) ([]AccessDenial, error) {
+	client, err := c.getKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	return reviewAccess(context.Background(), client, resources, verbs)
+}
+
+func reviewAccess(ctx context.Context, client kubernetes.Interface, resources ResourceList, verbs []string) ([]AccessDenial, error) {
+	reviewed := make(map[authorizationv1.ResourceAttributes]bool)
+	var denials []AccessDenial
+	for _, info := range resources {
+		if info.Mapping == nil {
+			continue
+		}
+		namespace := info.Namespace
+		if !info.Namespaced() {
+			namespace = ""
+		}
+		for _, verb := range verbs {
+			attrs := authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     info.Mapping.Resource.Group,
+				Resource:  info.Mapping.Resource.Resource,
+			}
+			if reviewed[attrs] {
+				continue
+			}
+			reviewed[attrs] = true
+
+			review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attrs},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return nil, errors.Wrap(err, "unable to review the access of the client")
+			}
+			if !review.Status.Allowed {
+				denials = append(denials, AccessDenial{
+					Verb:      verb,
+					Group:     attrs.Group,
+					Resource:  attrs.Resource,
+					Namespace: namespace,
+					Reason:    review.Status.Reason,
+				})
+			}
+		}
+	}
+	sort.SliceStable(denials, func(i, j int) bool { return denials[i].Namespace < denials[j].Namespace })
+	return denials, nil
+}
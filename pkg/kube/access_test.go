@@ -0,0 +1,87 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestReviewAccess(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	reviews := 0
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		// Deny everything in the namespace "other" and the deletion of
+		// namespaces.
+		if attrs.Namespace == "other" || attrs.Resource == "namespaces" && attrs.Verb == "delete" {
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Reason: "no RBAC policy matched"}
+		} else {
+			review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true}
+		}
+		return true, review, nil
+	})
+
+	info := func(group, resourceName, namespace, name string, scope meta.RESTScope) *resource.Info {
+		return &resource.Info{
+			Name:      name,
+			Namespace: namespace,
+			Mapping: &meta.RESTMapping{
+				Resource: schema.GroupVersionResource{Group: group, Version: "v1", Resource: resourceName},
+				Scope:    scope,
+			},
+		}
+	}
+	resources := ResourceList{
+		info("apps", "deployments", "default", "web", meta.RESTScopeNamespace),
+		info("apps", "deployments", "default", "worker", meta.RESTScopeNamespace),
+		info("", "configmaps", "other", "config", meta.RESTScopeNamespace),
+		info("", "namespaces", "", "other", meta.RESTScopeRoot),
+	}
+
+	denials, err := reviewAccess(context.Background(), client, resources, []string{"create", "delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The deployments of the same namespace are reviewed once per verb.
+	if reviews != 6 {
+		t.Errorf("expected 6 reviews, got %d", reviews)
+	}
+	want := []string{
+		`cannot delete namespaces: no RBAC policy matched`,
+		`cannot create configmaps in namespace "other": no RBAC policy matched`,
+		`cannot delete configmaps in namespace "other": no RBAC policy matched`,
+	}
+	if len(denials) != len(want) {
+		t.Fatalf("expected %d denials, got %v", len(want), denials)
+	}
+	for i, d := range denials {
+		if d.String() != want[i] {
+			t.Errorf("expected denial %q, got %q", want[i], d.String())
+		}
+	}
+}
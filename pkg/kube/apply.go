@@ -0,0 +1,136 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/util/csaupgrade"
+)
+
+// UpdateServerSide works like Update, but creates and changes resources with
+// server-side apply instead of client-side patches. Conflicts with other field
+// managers are forced, as the release is the source of truth of its resources.
+func (c *Client) UpdateServerSide(original, target ResourceList, force bool) (*Result, error) {
+	return c.update(original, target, force, true)
+}
+
+// MigrateToServerSideApply transfers the ownership of the fields that Helm set
+// with client-side updates of the given resources to server-side apply, by
+// rewriting their managedFields. The resources are not changed otherwise, so
+// later server-side applies neither conflict with nor remove those fields.
+func (c *Client) MigrateToServerSideApply(resources ResourceList) error {
+	manager := getManagedFieldsManager()
+	return perform(resources, func(info *resource.Info) error {
+		helper := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(manager)
+		obj, err := helper.Get(info.Namespace, info.Name)
+		if err != nil {
+			return errors.Wrapf(err, "could not get %s %q", info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+		patch, err := csaupgrade.UpgradeManagedFieldsPatch(obj, sets.New(manager), manager)
+		if err != nil {
+			return errors.Wrapf(err, "could not migrate the managed fields of %s %q", info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+		if patch == nil {
+			c.Log("Fields of %s %q are already managed by server-side apply", info.Mapping.GroupVersionKind.Kind, info.Name)
+			return nil
+		}
+		c.Log("Migrating fields of %s %q to server-side apply", info.Mapping.GroupVersionKind.Kind, info.Name)
+		obj, err = helper.Patch(info.Namespace, info.Name, types.JSONPatchType, patch, nil)
+		if err != nil {
+			return errors.Wrapf(err, "could not migrate the managed fields of %s %q", info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+		return info.Refresh(obj, true)
+	})
+}
+
+// applyResource creates or updates the resource with server-side apply.
+func applyResource(info *resource.Info) error {
+	data, err := json.Marshal(info.Object)
+	if err != nil {
+		return errors.Wrap(err, "serializing target configuration")
+	}
+	force := true
+	obj, err := resource.NewHelper(info.Client, info.Mapping).
+		WithFieldManager(getManagedFieldsManager()).
+		Patch(info.Namespace, info.Name, types.ApplyPatchType, data, &metav1.PatchOptions{Force: &force})
+	if err != nil {
+		return err
+	}
+	return info.Refresh(obj, true)
+}
+
+// Unchanged reports in the same order whether applying each of the given
+// resources server-side would leave it as it is. The resources are applied as
+// server-side dry runs and the results compared with the live objects, so
+// defaults and mutating admission are accounted for. Resources that do not
+// exist are changed.
+func (c *Client) Unchanged(resources ResourceList) ([]bool, error) {
+	unchanged := make([]bool, len(resources))
+	for i, info := range resources {
+		kind := info.Mapping.GroupVersionKind.Kind
+		helper := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager())
+		live, err := helper.Get(info.Namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get %s %q", kind, info.Name)
+		}
+		data, err := json.Marshal(info.Object)
+		if err != nil {
+			return nil, errors.Wrapf(err, "serializing %s %q", kind, info.Name)
+		}
+		force := true
+		applied, err := helper.DryRun(true).Patch(info.Namespace, info.Name, types.ApplyPatchType, data, &metav1.PatchOptions{Force: &force})
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to dry-run apply %s %q", kind, info.Name)
+		}
+		unchanged[i], err = sameObject(live, applied)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to compare %s %q", kind, info.Name)
+		}
+	}
+	return unchanged, nil
+}
+
+// sameObject returns whether two revisions of an object are the same, apart
+// from the bookkeeping the API server does on every write.
+func sameObject(a, b runtime.Object) (bool, error) {
+	left, err := runtime.DefaultUnstructuredConverter.ToUnstructured(a)
+	if err != nil {
+		return false, err
+	}
+	right, err := runtime.DefaultUnstructuredConverter.ToUnstructured(b)
+	if err != nil {
+		return false, err
+	}
+	for _, obj := range []map[string]interface{}{left, right} {
+		unstructured.RemoveNestedField(obj, "metadata", "managedFields")
+		unstructured.RemoveNestedField(obj, "metadata", "resourceVersion")
+	}
+	return equality.Semantic.DeepEqual(left, right), nil
+}
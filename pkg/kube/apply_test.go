@@ -0,0 +1,190 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestUpdateServerSide(t *testing.T) {
+	original := newPodList("starfish")
+	target := newPodList("starfish")
+	target.Items[0].Spec.Containers[0].Image = "abc/app:v5"
+
+	var applied bool
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch req.Method {
+			case "GET":
+				return newResponse(200, &original.Items[0])
+			case "PATCH":
+				if ct := req.Header.Get("Content-Type"); ct != string(types.ApplyPatchType) {
+					t.Errorf("expected an apply patch, got %s", ct)
+				}
+				if req.URL.Query().Get("force") != "true" {
+					t.Error("expected the apply to force conflicts")
+				}
+				applied = true
+				return newResponse(200, &target.Items[0])
+			}
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+	first, err := c.Build(objBody(&original), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Build(objBody(&target), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.UpdateServerSide(first, second, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applied {
+		t.Error("expected the pod to be applied")
+	}
+	if len(result.Updated) != 1 {
+		t.Errorf("expected 1 resource updated, got %d", len(result.Updated))
+	}
+}
+
+func TestMigrateToServerSideApply(t *testing.T) {
+	managedPod := func(name string, operation metav1.ManagedFieldsOperationType) v1.Pod {
+		pod := newPod(name)
+		pod.ResourceVersion = "7"
+		pod.ManagedFields = []metav1.ManagedFieldsEntry{{
+			Manager:    getManagedFieldsManager(),
+			Operation:  operation,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:containers":{}}}`)},
+		}}
+		return pod
+	}
+	pods := map[string]v1.Pod{
+		"starfish": managedPod("starfish", metav1.ManagedFieldsOperationUpdate),
+		"otter":    managedPod("otter", metav1.ManagedFieldsOperationApply),
+	}
+	list := newPodList("starfish", "otter")
+
+	var patched []string
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+			pod := pods[name]
+			switch req.Method {
+			case "GET":
+				return newResponse(200, &pod)
+			case "PATCH":
+				if ct := req.Header.Get("Content-Type"); ct != string(types.JSONPatchType) {
+					t.Errorf("expected a JSON patch, got %s", ct)
+				}
+				body, err := io.ReadAll(req.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(body), `"operation":"Apply"`) {
+					t.Errorf("expected the managed fields to be applied, got %s", body)
+				}
+				patched = append(patched, name)
+				return newResponse(200, &pod)
+			}
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+	resources, err := c.Build(objBody(&list), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.MigrateToServerSideApply(resources); err != nil {
+		t.Fatal(err)
+	}
+	// Fields already owned by server-side apply are left alone.
+	if len(patched) != 1 || patched[0] != "starfish" {
+		t.Errorf("expected only starfish to be migrated, got %v", patched)
+	}
+}
+
+func TestUnchanged(t *testing.T) {
+	live := map[string]v1.Pod{}
+	for _, name := range []string{"starfish", "otter"} {
+		pod := newPod(name)
+		pod.ResourceVersion = "7"
+		live[name] = pod
+	}
+	list := newPodList("starfish", "otter", "squid")
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+			pod, ok := live[name]
+			switch {
+			case req.Method == "GET" && !ok:
+				return newResponse(404, notFoundBody())
+			case req.Method == "GET":
+				return newResponse(200, &pod)
+			case req.Method == "PATCH":
+				if req.URL.Query().Get("dryRun") != "All" {
+					t.Fatalf("expected a dry run, got %s", req.URL.RawQuery)
+				}
+				// The server bumps the bookkeeping of every write.
+				pod.ResourceVersion = "8"
+				pod.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: getManagedFieldsManager(), Operation: metav1.ManagedFieldsOperationApply}}
+				if name == "otter" {
+					pod.Spec.Containers[0].Image = "abc/app:v5"
+				}
+				return newResponse(200, &pod)
+			}
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+	resources, err := c.Build(objBody(&list), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unchanged, err := c.Unchanged(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []bool{true, false, false}; !reflect.DeepEqual(expected, unchanged) {
+		t.Errorf("expected %v, got %v", expected, unchanged)
+	}
+}
@@ -29,7 +29,6 @@ import (
 	"sync"
 	"time"
 
-	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/pkg/errors"
 	batch "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
@@ -42,20 +41,20 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
-	cachetools "k8s.io/client-go/tools/cache"
 	watchtools "k8s.io/client-go/tools/watch"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"helm.sh/helm/v3/pkg/diff"
 )
 
 // ErrNoObjectsVisited indicates that during a visit operation, no matching objects were found.
@@ -68,6 +67,8 @@ var metadataAccessor = meta.NewAccessor()
 var ManagedFieldsManager string
 
 // Client represents a client capable of communicating with the Kubernetes API.
+//
+// A Client is safe for concurrent use once its exported fields are set.
 type Client struct {
 	// Factory provides a minimal version of the kubectl Factory interface. If
 	// you need the full Factory you can type switch to the full interface.
@@ -81,7 +82,17 @@ type Client struct {
 	Log     func(string, ...interface{})
 	// Namespace allows to bypass the kubeconfig file for the choice of the namespace
 	Namespace string
-
+	// StatusMappings determine the readiness of custom resources when waiting.
+	StatusMappings *StatusMappings
+	// StatusMappingsSource is a file, or a ConfigMap given as
+	// "configmap:<namespace>/<name>", to load StatusMappings from the first
+	// time they are needed.
+	StatusMappingsSource string
+
+	// The mutexes guard the lazy initialization of kubeClient and
+	// StatusMappings, so that a Client can be shared by concurrent actions.
+	clientMu   sync.Mutex
+	mappingsMu sync.Mutex
 	kubeClient *kubernetes.Clientset
 }
 
@@ -112,6 +123,9 @@ var nopLogger = func(_ string, _ ...interface{}) {}
 
 // getKubeClient get or create a new KubernetesClientSet
 func (c *Client) getKubeClient() (*kubernetes.Clientset, error) {
+	c.clientMu.Lock()
+	defer c.clientMu.Unlock()
+
 	var err error
 	if c.kubeClient == nil {
 		c.kubeClient, err = c.Factory.KubernetesClientSet()
@@ -139,11 +153,25 @@ func (c *Client) IsReachable() error {
 
 // Create creates Kubernetes resources specified in the resource list.
 func (c *Client) Create(resources ResourceList) (*Result, error) {
+	return c.CreateWithContext(context.Background(), resources)
+}
+
+// CreateWithContext works like Create, but stops creating resources once ctx
+// is done.
+func (c *Client) CreateWithContext(ctx context.Context, resources ResourceList) (*Result, error) {
 	c.Log("creating %d resource(s)", len(resources))
-	if err := perform(resources, createResource); err != nil {
+	warnings := &warningRecorder{}
+	create := func(info *resource.Info) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return createResource(info)
+	}
+	if err := perform(resources, warnings.perform(create)); err != nil {
 		return nil, err
 	}
-	return &Result{Created: resources}, nil
+	c.invalidateDiscoveryForCRDs(resources)
+	return &Result{Created: resources, Warnings: warnings.list()}, nil
 }
 
 func transformRequests(req *rest.Request) {
@@ -287,7 +315,11 @@ func (c *Client) Wait(resources ResourceList, timeout time.Duration) error {
 	if err != nil {
 		return err
 	}
-	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true))
+	mappings, err := c.statusMappings()
+	if err != nil {
+		return err
+	}
+	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true), WithStatusMappings(mappings))
 	w := waiter{
 		c:       checker,
 		log:     c.Log,
@@ -302,7 +334,11 @@ func (c *Client) WaitWithJobs(resources ResourceList, timeout time.Duration) err
 	if err != nil {
 		return err
 	}
-	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true), CheckJobs(true))
+	mappings, err := c.statusMappings()
+	if err != nil {
+		return err
+	}
+	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true), CheckJobs(true), WithStatusMappings(mappings))
 	w := waiter{
 		c:       checker,
 		log:     c.Log,
@@ -313,11 +349,17 @@ func (c *Client) WaitWithJobs(resources ResourceList, timeout time.Duration) err
 
 // WaitForDelete wait up to the given timeout for the specified resources to be deleted.
 func (c *Client) WaitForDelete(resources ResourceList, timeout time.Duration) error {
+	return c.WaitForDeleteWithContext(context.Background(), resources, timeout)
+}
+
+// WaitForDeleteWithContext works like WaitForDelete, but stops waiting once
+// ctx is done.
+func (c *Client) WaitForDeleteWithContext(ctx context.Context, resources ResourceList, timeout time.Duration) error {
 	w := waiter{
 		log:     c.Log,
 		timeout: timeout,
 	}
-	return w.waitForDeletedResources(resources)
+	return w.waitForDeletedResources(ctx, resources)
 }
 
 func (c *Client) namespace() string {
@@ -340,27 +382,31 @@ func (c *Client) newBuilder() *resource.Builder {
 }
 
 // Build validates for Kubernetes objects and returns unstructured infos.
+//
+// Every document of the stream is decoded on its own, so errors name the
+// position (document number and line) of the document they originate from.
 func (c *Client) Build(reader io.Reader, validate bool) (ResourceList, error) {
-	validationDirective := metav1.FieldValidationIgnore
-	if validate {
-		validationDirective = metav1.FieldValidationStrict
-	}
+	return c.buildDocuments(readDocuments(reader), validate, false)
+}
 
-	schema, err := c.Factory.Validator(validationDirective)
-	if err != nil {
-		return nil, err
-	}
-	result, err := c.newBuilder().
-		Unstructured().
-		Schema(schema).
-		Stream(reader, "").
-		Do().Infos()
-	return result, scrubValidationError(err)
+// BuildFromObjects validates the given Kubernetes objects and returns
+// unstructured infos. It is meant for callers that already hold parsed
+// objects and would otherwise need to serialize them to YAML for Build.
+func (c *Client) BuildFromObjects(objs []unstructured.Unstructured, validate bool) (ResourceList, error) {
+	return c.buildDocuments(objectDocuments(objs), validate, false)
 }
 
 // BuildTable validates for Kubernetes objects and returns unstructured infos.
 // The returned kind is a Table.
 func (c *Client) BuildTable(reader io.Reader, validate bool) (ResourceList, error) {
+	return c.buildDocuments(readDocuments(reader), validate, true)
+}
+
+// buildDocuments builds the documents one at a time, so that only the
+// document being built is held in memory next to the infos. Like the
+// builder, it goes on after the documents that fail to build and returns
+// their errors together.
+func (c *Client) buildDocuments(next documents, validate, table bool) (ResourceList, error) {
 	validationDirective := metav1.FieldValidationIgnore
 	if validate {
 		validationDirective = metav1.FieldValidationStrict
@@ -370,13 +416,31 @@ func (c *Client) BuildTable(reader io.Reader, validate bool) (ResourceList, erro
 	if err != nil {
 		return nil, err
 	}
-	result, err := c.newBuilder().
-		Unstructured().
-		Schema(schema).
-		Stream(reader, "").
-		TransformRequests(transformRequests).
-		Do().Infos()
-	return result, scrubValidationError(err)
+
+	result := ResourceList{}
+	var errs []error
+	for {
+		doc, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		builder := c.newBuilder().
+			Unstructured().
+			Schema(schema).
+			Stream(bytes.NewReader(doc.data), doc.source)
+		if table {
+			builder = builder.TransformRequests(transformRequests)
+		}
+		infos, err := builder.Do().Infos()
+		result = append(result, infos...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return result, scrubValidationError(utilerrors.Flatten(utilerrors.NewAggregate(errs)))
 }
 
 // Update takes the current list of objects and target list of objects and
@@ -387,14 +451,21 @@ func (c *Client) BuildTable(reader io.Reader, validate bool) (ResourceList, erro
 // resource updates, creations, and deletions that were attempted. These can be
 // used for cleanup or other logging purposes.
 func (c *Client) Update(original, target ResourceList, force bool) (*Result, error) {
+	return c.update(original, target, force, false)
+}
+
+func (c *Client) update(original, target ResourceList, force, serverSide bool) (*Result, error) {
 	updateErrors := []string{}
 	res := &Result{}
+	warnings := &warningRecorder{}
+	defer func() { res.Warnings = warnings.list() }()
 
 	c.Log("checking %d resources for changes", len(target))
 	err := target.Visit(func(info *resource.Info, err error) error {
 		if err != nil {
 			return err
 		}
+		defer warnings.watch(info)()
 
 		helper := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager())
 		if _, err := helper.Get(info.Namespace, info.Name); err != nil {
@@ -406,7 +477,11 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 			res.Created = append(res.Created, info)
 
 			// Since the resource does not exist, create it.
-			if err := createResource(info); err != nil {
+			create := createResource
+			if serverSide {
+				create = applyResource
+			}
+			if err := create(info); err != nil {
 				return errors.Wrap(err, "failed to create resource")
 			}
 
@@ -421,7 +496,7 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 			return errors.Errorf("no %s with the name %q found", kind, info.Name)
 		}
 
-		if err := updateResource(c, info, originalInfo.Object, force); err != nil {
+		if err := updateResource(c, info, originalInfo.Object, force, serverSide); err != nil {
 			c.Log("error updating the resource %q:\n\t %v", info.Name, err)
 			updateErrors = append(updateErrors, err.Error())
 		}
@@ -431,6 +506,8 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 		return nil
 	})
 
+	c.invalidateDiscoveryForCRDs(res.Created, res.Updated)
+
 	switch {
 	case err != nil:
 		return res, err
@@ -453,7 +530,9 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 			c.Log("Skipping delete of %q due to annotation [%s=%s]", info.Name, ResourcePolicyAnno, KeepPolicy)
 			continue
 		}
-		if err := deleteResource(info, metav1.DeletePropagationBackground); err != nil {
+		if err := warnings.perform(func(info *resource.Info) error {
+			return deleteResource(info, metav1.DeletePropagationBackground)
+		})(info); err != nil {
 			c.Log("Failed to delete %q, err: %s", info.ObjectName(), err)
 			continue
 		}
@@ -482,7 +561,8 @@ func rdelete(c *Client, resources ResourceList, propagation metav1.DeletionPropa
 	var errs []error
 	res := &Result{}
 	mtx := sync.Mutex{}
-	err := perform(resources, func(info *resource.Info) error {
+	warnings := &warningRecorder{}
+	err := perform(resources, warnings.perform(func(info *resource.Info) error {
 		c.Log("Starting delete for %q %s", info.Name, info.Mapping.GroupVersionKind.Kind)
 		err := deleteResource(info, propagation)
 		if err == nil || apierrors.IsNotFound(err) {
@@ -499,7 +579,7 @@ func rdelete(c *Client, resources ResourceList, propagation metav1.DeletionPropa
 		// Collect the error and continue on
 		errs = append(errs, err)
 		return nil
-	})
+	}))
 	if err != nil {
 		if errors.Is(err, ErrNoObjectsVisited) {
 			err = fmt.Errorf("object not found, skipping delete: %w", err)
@@ -509,15 +589,10 @@ func rdelete(c *Client, resources ResourceList, propagation metav1.DeletionPropa
 	if errs != nil {
 		return nil, errs
 	}
+	res.Warnings = warnings.list()
 	return res, nil
 }
 
-func (c *Client) watchTimeout(t time.Duration) func(*resource.Info) error {
-	return func(info *resource.Info) error {
-		return c.watchUntilReady(t, info)
-	}
-}
-
 // WatchUntilReady watches the resources given and waits until it is ready.
 //
 // This method is mainly for hook implementations. It watches for a resource to
@@ -532,10 +607,35 @@ func (c *Client) watchTimeout(t time.Duration) func(*resource.Info) error {
 //     ascertained by watching the status.phase field in a pod's output.
 //
 // Handling for other kinds will be added as necessary.
+//
+// Resources of the same kind and namespace are watched over a single
+// connection, and all of them are watched in parallel.
 func (c *Client) WatchUntilReady(resources ResourceList, timeout time.Duration) error {
-	// For jobs, there's also the option to do poll c.Jobs(namespace).Get():
-	// https://github.com/adamreese/kubernetes/blob/master/test/e2e/job.go#L291-L300
-	return perform(resources, c.watchTimeout(timeout))
+	return c.WatchUntilReadyWithContext(context.Background(), resources, timeout)
+}
+
+// WatchUntilReadyWithContext works like WatchUntilReady, but stops watching
+// once ctx is done.
+func (c *Client) WatchUntilReadyWithContext(ctx context.Context, resources ResourceList, timeout time.Duration) error {
+	if len(resources) == 0 {
+		return ErrNoObjectsVisited
+	}
+
+	sets := watchSets(resources)
+	errs := make(chan error, len(sets))
+	for _, set := range sets {
+		go func(set *watchSet) {
+			errs <- c.watchUntilReady(ctx, timeout, set)
+		}(set)
+	}
+
+	var result error
+	for range sets {
+		if err := <-errs; err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
 }
 
 func perform(infos ResourceList, fn func(*resource.Info) error) error {
@@ -632,43 +732,24 @@ func createPatch(target *resource.Info, current runtime.Object) ([]byte, types.P
 		return nil, types.StrategicMergePatchType, errors.Wrap(err, "serializing live configuration")
 	}
 
-	// Get a versioned object
-	versionedObject := AsVersioned(target)
-
-	// Unstructured objects, such as CRDs, may not have an not registered error
-	// returned from ConvertToVersion. Anything that's unstructured should
-	// use the jsonpatch.CreateMergePatch. Strategic Merge Patch is not supported
-	// on objects like CRDs.
-	_, isUnstructured := versionedObject.(runtime.Unstructured)
-
-	// On newer K8s versions, CRDs aren't unstructured but has this dedicated type
-	_, isCRD := versionedObject.(*apiextv1beta1.CustomResourceDefinition)
-
-	if isUnstructured || isCRD {
-		// fall back to generic JSON merge patch
-		patch, err := jsonpatch.CreateMergePatch(oldData, newData)
-		return patch, types.MergePatchType, err
-	}
-
-	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(versionedObject)
-	if err != nil {
-		return nil, types.StrategicMergePatchType, errors.Wrap(err, "unable to create patch metadata from object")
-	}
-
-	patch, err := strategicpatch.CreateThreeWayMergePatch(oldData, newData, currentData, patchMeta, true)
-	return patch, types.StrategicMergePatchType, err
+	return diff.MergePatch(oldData, newData, currentData, AsVersioned(target))
 }
 
-func updateResource(c *Client, target *resource.Info, currentObj runtime.Object, force bool) error {
+func updateResource(c *Client, target *resource.Info, currentObj runtime.Object, force, serverSide bool) error {
 	var (
 		obj    runtime.Object
 		helper = resource.NewHelper(target.Client, target.Mapping).WithFieldManager(getManagedFieldsManager())
 		kind   = target.Mapping.GroupVersionKind.Kind
 	)
 
-	// if --force is applied, attempt to replace the existing resource with the new object.
-	if force {
-		var err error
+	strategy, err := upgradeStrategy(target, force)
+	if err != nil {
+		return err
+	}
+
+	// if --force is applied, or the resource asks for it, attempt to replace
+	// the existing resource with the new object.
+	if strategy == UpgradeStrategyReplace {
 		obj, err = helper.Replace(target.Namespace, target.Name, true, target.Object)
 		if err != nil {
 			return errors.Wrap(err, "failed to replace object")
@@ -689,6 +770,33 @@ func updateResource(c *Client, target *resource.Info, currentObj runtime.Object,
 			}
 			return nil
 		}
+		switch strategy {
+		case UpgradeStrategyRecreate:
+			return c.recreateResource(target, metav1.DeletePropagationBackground)
+		case UpgradeStrategyRecreateOrphan:
+			if kind == "StatefulSet" {
+				if err := c.resizeStatefulSetClaims(target, currentObj); err != nil {
+					return err
+				}
+			}
+			return c.recreateResource(target, metav1.DeletePropagationOrphan)
+		}
+		if kind == "StatefulSet" {
+			changed, err := immutableStatefulSetChanges(currentObj, target.Object)
+			if err != nil {
+				return errors.Wrap(err, "failed to compare StatefulSet specs")
+			}
+			if len(changed) > 0 {
+				return immutableStatefulSetError(target.Name, changed)
+			}
+		}
+		if serverSide {
+			c.Log("Apply %s %q in namespace %s", kind, target.Name, target.Namespace)
+			if err := applyResource(target); err != nil {
+				return errors.Wrapf(err, "cannot apply %q with kind %s", target.Name, kind)
+			}
+			return nil
+		}
 		// send patch to server
 		c.Log("Patch %s %q in namespace %s", kind, target.Name, target.Namespace)
 		obj, err = helper.Patch(target.Namespace, target.Name, patchType, patch, nil)
@@ -701,23 +809,16 @@ func updateResource(c *Client, target *resource.Info, currentObj runtime.Object,
 	return nil
 }
 
-func (c *Client) watchUntilReady(timeout time.Duration, info *resource.Info) error {
-	kind := info.Mapping.GroupVersionKind.Kind
+func (c *Client) watchUntilReady(ctx context.Context, timeout time.Duration, set *watchSet) error {
+	mapping := set.resources[0].Mapping
+	kind := mapping.GroupVersionKind.Kind
 	switch kind {
 	case "Job", "Pod":
 	default:
 		return nil
 	}
 
-	c.Log("Watching for changes to %s %s with timeout of %v", kind, info.Name, timeout)
-
-	// Use a selector on the name of the resource. This should be unique for the
-	// given version and kind
-	selector, err := fields.ParseSelector(fmt.Sprintf("metadata.name=%s", info.Name))
-	if err != nil {
-		return err
-	}
-	lw := cachetools.NewListWatchFromClient(info.Client, info.Mapping.Resource.Resource, info.Namespace, selector)
+	c.Log("Watching for changes to %s with timeout of %v", set, timeout)
 
 	// What we watch for depends on the Kind.
 	// - For a Job, we watch for completion.
@@ -725,36 +826,50 @@ func (c *Client) watchUntilReady(timeout time.Duration, info *resource.Info) err
 	// In the future, we might want to add some special logic for types
 	// like Ingress, Volume, etc.
 
-	ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), timeout)
+	// The watch may see other resources of the kind, which are ignored.
+	pending := set.names()
+
+	ctx, cancel := watchtools.ContextWithOptionalTimeout(ctx, timeout)
 	defer cancel()
-	_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, nil, func(e watch.Event) (bool, error) {
-		// Make sure the incoming object is versioned as we use unstructured
-		// objects when we build manifests
-		obj := convertWithMapper(e.Object, info.Mapping)
+	_, err := watchtools.UntilWithSync(ctx, set.listWatch(), &unstructured.Unstructured{}, nil, func(e watch.Event) (bool, error) {
+		if e.Type == watch.Error {
+			// Handle error and return with an error.
+			c.Log("Error event for %s", set)
+			return true, errors.Errorf("failed to deploy %s", set)
+		}
+		name, err := metadataAccessor.Name(e.Object)
+		if err != nil || !pending[name] {
+			return false, nil
+		}
+
 		switch e.Type {
 		case watch.Added, watch.Modified:
+			// Make sure the incoming object is versioned as we use unstructured
+			// objects when we build manifests
+			obj := convertWithMapper(e.Object, mapping)
 			// For things like a secret or a config map, this is the best indicator
 			// we get. We care mostly about jobs, where what we want to see is
 			// the status go into a good state. For other types, like ReplicaSet
 			// we don't really do anything to support these as hooks.
-			c.Log("Add/Modify event for %s: %v", info.Name, e.Type)
+			c.Log("Add/Modify event for %s: %v", name, e.Type)
+			var done bool
 			switch kind {
 			case "Job":
-				return c.waitForJob(obj, info.Name)
+				done, err = c.waitForJob(obj, name)
 			case "Pod":
-				return c.waitForPodSuccess(obj, info.Name)
+				done, err = c.waitForPodSuccess(obj, name)
+			}
+			if err != nil {
+				return true, err
+			}
+			if done {
+				delete(pending, name)
 			}
-			return true, nil
 		case watch.Deleted:
-			c.Log("Deleted event for %s", info.Name)
-			return true, nil
-		case watch.Error:
-			// Handle error and return with an error.
-			c.Log("Error event for %s", info.Name)
-			return true, errors.Errorf("failed to deploy %s", info.Name)
-		default:
-			return false, nil
+			c.Log("Deleted event for %s", name)
+			delete(pending, name)
 		}
+		return len(pending) == 0, nil
 	})
 	return err
 }
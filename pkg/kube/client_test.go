@@ -18,6 +18,8 @@ package kube
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -25,6 +27,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -213,6 +216,85 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateStrategyAnnotation(t *testing.T) {
+	listA := newPodList("starfish", "otter")
+	listB := newPodList("starfish", "otter")
+	listB.Items[0].Annotations = map[string]string{UpgradeStrategyAnno: UpgradeStrategyRecreate}
+	listB.Items[0].Spec.Containers[0].Ports = []v1.ContainerPort{{Name: "https", ContainerPort: 443}}
+	listB.Items[1].Annotations = map[string]string{UpgradeStrategyAnno: UpgradeStrategyReplace}
+
+	var actions []string
+	deleted := false
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			p, m := req.URL.Path, req.Method
+			actions = append(actions, p+":"+m)
+			switch {
+			case p == "/namespaces/default/pods/starfish" && m == "GET":
+				if deleted {
+					return newResponse(404, notFoundBody())
+				}
+				return newResponse(200, &listA.Items[0])
+			case p == "/namespaces/default/pods/starfish" && m == "DELETE":
+				deleted = true
+				return newResponse(200, &listA.Items[0])
+			case p == "/namespaces/default/pods" && m == "POST":
+				return newResponse(201, &listB.Items[0])
+			case p == "/namespaces/default/pods/otter" && m == "GET":
+				return newResponse(200, &listA.Items[1])
+			case p == "/namespaces/default/pods/otter" && m == "PUT":
+				return newResponse(200, &listB.Items[1])
+			default:
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return nil, nil
+			}
+		}),
+	}
+	first, err := c.Build(objBody(&listA), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Build(objBody(&listB), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Update(first, second, false); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedActions := []string{
+		"/namespaces/default/pods/starfish:GET",
+		"/namespaces/default/pods/starfish:GET",
+		"/namespaces/default/pods/starfish:DELETE",
+		"/namespaces/default/pods/starfish:GET",
+		"/namespaces/default/pods:POST",
+		"/namespaces/default/pods/otter:GET",
+		"/namespaces/default/pods/otter:GET",
+		"/namespaces/default/pods/otter:PUT",
+	}
+	if len(expectedActions) != len(actions) {
+		t.Fatalf("unexpected requests, expected %v, got %v", expectedActions, actions)
+	}
+	for k, v := range expectedActions {
+		if actions[k] != v {
+			t.Errorf("expected %s request got %s", v, actions[k])
+		}
+	}
+
+	listB.Items[1].Annotations[UpgradeStrategyAnno] = "sideways"
+	invalid, err := c.Build(objBody(&listB.Items[1]), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := upgradeStrategy(invalid[0], false); err == nil {
+		t.Error("expected an error for an invalid upgrade strategy")
+	}
+}
+
 func TestBuild(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -252,6 +334,78 @@ func TestBuild(t *testing.T) {
 	}
 }
 
+func TestBuildErrorPosition(t *testing.T) {
+	manifest := testServiceManifest + "\n---\n" + `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: broken
+data: [
+`
+	c := newTestClient(t)
+	_, err := c.Build(strings.NewReader(manifest), false)
+	if err == nil {
+		t.Fatal("expected an error for the malformed document")
+	}
+	if !strings.Contains(err.Error(), "document 2 (line 15)") {
+		t.Errorf("expected the error to name the malformed document, got %q", err)
+	}
+}
+
+func TestBuildFromObjects(t *testing.T) {
+	svc := unstructured.Unstructured{}
+	svc.SetAPIVersion("v1")
+	svc.SetKind("Service")
+	svc.SetName("my-service")
+	cm := unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetName("my-config")
+
+	c := newTestClient(t)
+	infos, err := c.BuildFromObjects([]unstructured.Unstructured{svc, cm}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 result objects, got %d", len(infos))
+	}
+	if infos[0].Name != "my-service" || infos[1].Name != "my-config" {
+		t.Errorf("expected objects in the given order, got %q and %q", infos[0].Name, infos[1].Name)
+	}
+
+	infos, err = c.BuildFromObjects(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		t.Errorf("expected no result objects, got %d", len(infos))
+	}
+}
+
+func TestReadDocuments(t *testing.T) {
+	stream := "---\n# leading comment\n---\na: 1\n--- # separator comment\n\nb: 2\n---\n---\nc: 3"
+	next := readDocuments(strings.NewReader(stream))
+	expect := []document{
+		{data: []byte("# leading comment\n"), source: "document 1 (line 2)"},
+		{data: []byte("a: 1\n"), source: "document 2 (line 4)"},
+		{data: []byte("\nb: 2\n"), source: "document 3 (line 6)"},
+		{data: []byte("c: 3"), source: "document 4 (line 10)"},
+	}
+	for i := range expect {
+		doc, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(doc.data) != string(expect[i].data) || doc.source != expect[i].source {
+			t.Errorf("expected document %d to be %q at %q, got %q at %q", i, expect[i].data, expect[i].source, doc.data, doc.source)
+		}
+	}
+	if _, err := next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last document, got %v", err)
+	}
+}
+
 func TestBuildTable(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -341,6 +495,28 @@ func TestPerform(t *testing.T) {
 	}
 }
 
+func TestCreateWithContext(t *testing.T) {
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+	list := newPodList("starfish", "otter")
+	resources, err := c.Build(objBody(&list), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CreateWithContext(ctx, resources); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the creation to be cancelled, got %v", err)
+	}
+}
+
 func TestReal(t *testing.T) {
 	t.Skip("This is a live test, comment this line to run")
 	c := New(nil)
@@ -0,0 +1,151 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+)
+
+// discoveryGetter is implemented by factories and getters that are able to
+// hand out the discovery client and REST mapper used to build resources.
+type discoveryGetter interface {
+	ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error)
+	ToRESTMapper() (meta.RESTMapper, error)
+}
+
+// memCacheClientGetter wraps a RESTClientGetter so that every caller shares a
+// single in-memory discovery cache and REST mapper.
+type memCacheClientGetter struct {
+	genericclioptions.RESTClientGetter
+
+	mu              sync.Mutex
+	discoveryClient discovery.CachedDiscoveryInterface
+	restMapper      meta.RESTMapper
+}
+
+// NewMemCacheClientGetter returns a RESTClientGetter that memoizes discovery
+// information in memory on top of the given getter.
+//
+// The discovery client and REST mapper are created once and handed to every
+// caller, so an action run (and the kube client it uses) only performs
+// discovery once. Invalidating the returned discovery client, or resetting the
+// returned REST mapper, also invalidates any cache kept by the wrapped getter.
+func NewMemCacheClientGetter(getter genericclioptions.RESTClientGetter) genericclioptions.RESTClientGetter {
+	if _, ok := getter.(*memCacheClientGetter); ok {
+		return getter
+	}
+	return &memCacheClientGetter{RESTClientGetter: getter}
+}
+
+// ToDiscoveryClient returns the shared in-memory cached discovery client.
+func (g *memCacheClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.toDiscoveryClient()
+}
+
+func (g *memCacheClientGetter) toDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	if g.discoveryClient == nil {
+		dc, err := g.RESTClientGetter.ToDiscoveryClient()
+		if err != nil {
+			return nil, err
+		}
+		g.discoveryClient = memory.NewMemCacheClient(dc)
+	}
+	return g.discoveryClient, nil
+}
+
+// ToRESTMapper returns the shared REST mapper backed by the shared discovery
+// client.
+func (g *memCacheClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.restMapper == nil {
+		dc, err := g.toDiscoveryClient()
+		if err != nil {
+			return nil, err
+		}
+		mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+		g.restMapper = restmapper.NewShortcutExpander(mapper, dc, nil)
+	}
+	return g.restMapper, nil
+}
+
+// InvalidateDiscovery drops any cached discovery information and resets the
+// REST mapper so that newly registered types, such as the ones added when a
+// CustomResourceDefinition is created, can be mapped by the next request.
+func (c *Client) InvalidateDiscovery() error {
+	getter, ok := c.Factory.(discoveryGetter)
+	if !ok {
+		return nil
+	}
+
+	dc, err := getter.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	c.Log("Clearing discovery cache")
+	dc.Invalidate()
+	// Refill the cache right away, so that the new groups are known before
+	// the REST mapper below is reset and queried again.
+	_, _ = dc.ServerGroups()
+
+	restMapper, err := getter.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	c.Log("Clearing REST mapper cache")
+	meta.MaybeResetRESTMapper(restMapper)
+	return nil
+}
+
+// invalidateDiscoveryForCRDs invalidates the discovery cache if any of the
+// given resources is a CustomResourceDefinition. Failures are only logged, as
+// the resources themselves were applied successfully.
+func (c *Client) invalidateDiscoveryForCRDs(lists ...ResourceList) {
+	for _, resources := range lists {
+		if !containsCRDs(resources) {
+			continue
+		}
+		if err := c.InvalidateDiscovery(); err != nil {
+			c.Log("Warning: unable to invalidate discovery cache: %s", err)
+		}
+		return
+	}
+}
+
+// containsCRDs reports whether any of the given resources is a
+// CustomResourceDefinition.
+func containsCRDs(resources ResourceList) bool {
+	for _, info := range resources {
+		if info.Mapping == nil {
+			continue
+		}
+		gvk := info.Mapping.GroupVersionKind
+		if gvk.Group == "apiextensions.k8s.io" && gvk.Kind == "CustomResourceDefinition" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func TestMemCacheClientGetter(t *testing.T) {
+	fakeDiscovery := cmdtesting.NewFakeCachedDiscoveryClient()
+	getter := NewMemCacheClientGetter(genericclioptions.NewTestConfigFlags().WithDiscoveryClient(fakeDiscovery))
+
+	if NewMemCacheClientGetter(getter) != getter {
+		t.Error("expected an already wrapped getter to be returned as is")
+	}
+
+	dc1, err := getter.ToDiscoveryClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc2, err := getter.ToDiscoveryClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dc1 != dc2 {
+		t.Error("expected the discovery client to be shared between callers")
+	}
+
+	if _, err := getter.ToRESTMapper(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{Factory: cmdutil.NewFactory(getter), Log: nopLogger}
+	if err := c.InvalidateDiscovery(); err != nil {
+		t.Fatal(err)
+	}
+	// Invalidating the memory cache invalidates the wrapped cache as well.
+	// Resetting the REST mapper invalidates the discovery client once more.
+	if fakeDiscovery.Invalidations == 0 {
+		t.Error("expected the wrapped discovery client to be invalidated")
+	}
+}
+
+func TestInvalidateDiscoveryForCRDs(t *testing.T) {
+	fakeDiscovery := cmdtesting.NewFakeCachedDiscoveryClient()
+	getter := NewMemCacheClientGetter(genericclioptions.NewTestConfigFlags().WithDiscoveryClient(fakeDiscovery))
+	c := &Client{Factory: cmdutil.NewFactory(getter), Log: nopLogger}
+
+	info := func(group, kind string) *resource.Info {
+		return &resource.Info{
+			Name:    "foo",
+			Mapping: &meta.RESTMapping{GroupVersionKind: schema.GroupVersionKind{Group: group, Version: "v1", Kind: kind}},
+		}
+	}
+
+	c.invalidateDiscoveryForCRDs(ResourceList{info("", "ConfigMap")}, ResourceList{info("apps", "Deployment")})
+	if fakeDiscovery.Invalidations != 0 {
+		t.Errorf("expected no invalidation without CRDs, got %d", fakeDiscovery.Invalidations)
+	}
+
+	c.invalidateDiscoveryForCRDs(nil, ResourceList{info("", "ConfigMap"), info("apiextensions.k8s.io", "CustomResourceDefinition")})
+	if fakeDiscovery.Invalidations == 0 {
+		t.Error("expected the discovery cache to be invalidated after applying a CRD")
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"helm.sh/helm/v3/internal/yamlstream"
+)
+
+// document is a single document of a YAML stream, or a single object handed
+// over by the caller, together with its position for use in error messages.
+type document struct {
+	data   []byte
+	source string
+}
+
+// documents returns the next document to build, or io.EOF after the last one.
+type documents func() (document, error)
+
+// readDocuments reads the documents of a YAML stream one at a time.
+//
+// Each document is labeled with its position in the stream (e.g. "document 2
+// (line 14)") so that decoding and validation errors can be traced back to
+// the document that caused them. Documents that are empty or only contain
+// whitespace are skipped but still count towards the line numbers.
+//
+// The stream is read as the documents are built, so building a release
+// manifest of tens of megabytes does not hold the whole manifest in memory
+// next to the objects decoded from it.
+func readDocuments(reader io.Reader) documents {
+	r := yamlstream.NewReader(reader)
+	n := 0
+	return func() (document, error) {
+		doc, err := r.Next()
+		if err == io.EOF {
+			return document{}, err
+		}
+		if err != nil {
+			return document{}, errors.Wrap(err, "unable to read YAML stream")
+		}
+		n++
+		return document{data: doc.Data, source: fmt.Sprintf("document %d (line %d)", n, doc.Line)}, nil
+	}
+}
+
+// objectDocuments serializes the given objects into documents labeled with
+// their index, kind and name, one at a time.
+func objectDocuments(objs []unstructured.Unstructured) documents {
+	i := 0
+	return func() (document, error) {
+		if i == len(objs) {
+			return document{}, io.EOF
+		}
+		obj := &objs[i]
+		i++
+		source := fmt.Sprintf("object %d (%s %q)", i, obj.GetKind(), obj.GetName())
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return document{}, errors.Wrapf(err, "unable to serialize %s", source)
+		}
+		return document{data: data, source: source}, nil
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/cli-runtime/pkg/resource"
+	restfake "k8s.io/client-go/rest/fake"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// Verb is an operation of a KubeClient on the cluster.
+type Verb string
+
+// Verbs of the operations recorded by FailingKubeClient.
+const (
+	VerbCreate          Verb = "create"
+	VerbGet             Verb = "get"
+	VerbStatus          Verb = "status"
+	VerbUpdate          Verb = "update"
+	VerbDelete          Verb = "delete"
+	VerbWait            Verb = "wait"
+	VerbWaitForDelete   Verb = "wait-for-delete"
+	VerbWatchUntilReady Verb = "watch-until-ready"
+	VerbMigrate         Verb = "migrate"
+	VerbScale           Verb = "scale"
+)
+
+// Failure injects an error into the operations of a FailingKubeClient.
+type Failure struct {
+	// Verb is the operation to fail. All operations match if empty.
+	Verb Verb
+	// GVK is the kind of the resources to fail the operation for. Empty
+	// fields match any value; an operation matches if one of its resources
+	// does.
+	GVK schema.GroupVersionKind
+	// Name is the name of the resource to fail the operation for. All
+	// resources match if empty.
+	Name string
+	// Times is the number of matching operations to fail, after which they
+	// succeed again. All matching operations fail if zero.
+	Times int
+	// Err is the error returned by the operation.
+	Err error
+}
+
+// Operation is an operation of a FailingKubeClient, recorded for assertions.
+type Operation struct {
+	Verb      Verb
+	Resources kube.ResourceList
+	// Err is the error the operation returned, if any.
+	Err error
+}
+
+// Operations returns the operations the client performed, in order.
+func (f *FailingKubeClient) Operations() []Operation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Operation{}, f.operations...)
+}
+
+// OperationsOf returns the operations of a verb the client performed, in order.
+func (f *FailingKubeClient) OperationsOf(verb Verb) []Operation {
+	var ops []Operation
+	for _, op := range f.Operations() {
+		if op.Verb == verb {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// check waits for the configured latency and records an operation. It returns
+// the configured error of the operation if not nil, or else the error of the
+// first failure matching the operation.
+func (f *FailingKubeClient) check(verb Verb, resources kube.ResourceList, err error) error {
+	time.Sleep(f.Latency)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		err = f.inject(verb, resources)
+	}
+	f.operations = append(f.operations, Operation{Verb: verb, Resources: resources, Err: err})
+	return err
+}
+
+func (f *FailingKubeClient) inject(verb Verb, resources kube.ResourceList) error {
+	if f.failed == nil {
+		f.failed = map[int]int{}
+	}
+	for i, failure := range f.Failures {
+		if !failure.matches(verb, resources) {
+			continue
+		}
+		if failure.Times > 0 && f.failed[i] >= failure.Times {
+			continue
+		}
+		f.failed[i]++
+		return failure.Err
+	}
+	return nil
+}
+
+func (failure *Failure) matches(verb Verb, resources kube.ResourceList) bool {
+	if failure.Verb != "" && failure.Verb != verb {
+		return false
+	}
+	if failure.GVK.Empty() && failure.Name == "" {
+		return true
+	}
+	for _, info := range resources {
+		gvk := infoGVK(info)
+		if (failure.GVK.Group == "" || failure.GVK.Group == gvk.Group) &&
+			(failure.GVK.Version == "" || failure.GVK.Version == gvk.Version) &&
+			(failure.GVK.Kind == "" || failure.GVK.Kind == gvk.Kind) &&
+			(failure.Name == "" || failure.Name == info.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func infoGVK(info *resource.Info) schema.GroupVersionKind {
+	if info.Mapping != nil {
+		return info.Mapping.GroupVersionKind
+	}
+	if info.Object != nil {
+		return info.Object.GetObjectKind().GroupVersionKind()
+	}
+	return schema.GroupVersionKind{}
+}
+
+// parseManifests parses the objects of YAML manifests into resources, without
+// a cluster to map their kinds. Objects without a namespace are in the
+// default namespace. The objects do not exist in the cluster: their clients
+// answer all requests with NotFound.
+func parseManifests(r io.Reader) (kube.ResourceList, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var resources kube.ResourceList
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return resources, nil
+			}
+			return nil, errors.Wrap(err, "unable to parse manifest")
+		}
+		// Empty documents are skipped, as by a real client.
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		// Numbers are decoded as integers where possible, as by the API
+		// machinery of a real client.
+		obj := &unstructured.Unstructured{}
+		if err := utiljson.Unmarshal(raw, &obj.Object); err != nil {
+			return nil, errors.Wrap(err, "unable to parse manifest")
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(metav1.NamespaceDefault)
+		}
+		gvk := obj.GroupVersionKind()
+		resources = append(resources, &resource.Info{
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+			Object:    obj,
+			Mapping: &meta.RESTMapping{
+				Resource:         gvk.GroupVersion().WithResource(strings.ToLower(gvk.Kind) + "s"),
+				GroupVersionKind: gvk,
+				Scope:            meta.RESTScopeNamespace,
+			},
+			Client: notFoundClient,
+		})
+	}
+}
+
+const notFoundStatus = `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`
+
+var notFoundClient = &restfake.RESTClient{
+	NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+	Client: restfake.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Content-Type", runtime.ContentTypeJSON)
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader(notFoundStatus)),
+		}, nil
+	}),
+}
@@ -18,11 +18,15 @@ limitations under the License.
 package fake
 
 import (
+	"context"
 	"io"
+	"slices"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/resource"
 
@@ -31,7 +35,11 @@ import (
 
 // FailingKubeClient implements KubeClient for testing purposes. It also has
 // additional errors you can set to fail different functions, otherwise it
-// delegates all its calls to `PrintingKubeClient`
+// delegates all its calls to `PrintingKubeClient`.
+//
+// Besides the errors of the functions, Failures can fail operations of given
+// resources, and Latency slows down all operations. The operations on the
+// cluster are recorded and returned by Operations.
 type FailingKubeClient struct {
 	PrintingKubeClient
 	CreateError                      error
@@ -41,77 +49,230 @@ type FailingKubeClient struct {
 	DeleteWithPropagationError       error
 	WatchUntilReadyError             error
 	UpdateError                      error
+	MigrateError                     error
+	ScaleError                       error
 	BuildError                       error
 	BuildTableError                  error
 	BuildDummy                       bool
 	BuildUnstructuredError           error
 	WaitAndGetCompletedPodPhaseError error
 	WaitDuration                     time.Duration
+	// Warnings are returned as the warnings of the API server by Create and
+	// Update.
+	Warnings []string
+	// Failures are the errors injected into the operations of matching verbs
+	// and resources, checked in order after the errors of the functions.
+	Failures []Failure
+	// Latency is the time every operation on the cluster takes.
+	Latency time.Duration
+	// ParseManifests makes Build parse the manifests into resources, so that
+	// failures can be injected and operations recorded for their objects.
+	ParseManifests bool
+	// AccessDenials are returned by ReviewAccess for the verbs they deny.
+	AccessDenials []kube.AccessDenial
+	// Changed names the resources Unchanged reports as changed, all others
+	// are unchanged.
+	Changed []string
+	// WatchDuration is the time WatchUntilReadyWithContext watches before it
+	// returns, unless its context is done earlier.
+	WatchDuration time.Duration
+
+	mu         sync.Mutex
+	operations []Operation
+	failed     map[int]int
+	replicas   map[string]int32
 }
 
 // Create returns the configured error if set or prints
 func (f *FailingKubeClient) Create(resources kube.ResourceList) (*kube.Result, error) {
-	if f.CreateError != nil {
-		return nil, f.CreateError
+	if err := f.check(VerbCreate, resources, f.CreateError); err != nil {
+		return nil, err
+	}
+	result, err := f.PrintingKubeClient.Create(resources)
+	if result != nil {
+		result.Warnings = f.Warnings
 	}
-	return f.PrintingKubeClient.Create(resources)
+	return result, err
 }
 
 // Get returns the configured error if set or prints
 func (f *FailingKubeClient) Get(resources kube.ResourceList, related bool) (map[string][]runtime.Object, error) {
-	if f.GetError != nil {
-		return nil, f.GetError
+	if err := f.check(VerbGet, resources, f.GetError); err != nil {
+		return nil, err
 	}
 	return f.PrintingKubeClient.Get(resources, related)
 }
 
+// Status returns the configured error if set or prints
+func (f *FailingKubeClient) Status(resources kube.ResourceList) ([]kube.ResourceStatus, error) {
+	if err := f.check(VerbStatus, resources, f.GetError); err != nil {
+		return nil, err
+	}
+	return f.PrintingKubeClient.Status(resources)
+}
+
 // Waits the amount of time defined on f.WaitDuration, then returns the configured error if set or prints.
 func (f *FailingKubeClient) Wait(resources kube.ResourceList, d time.Duration) error {
 	time.Sleep(f.WaitDuration)
-	if f.WaitError != nil {
-		return f.WaitError
+	if err := f.check(VerbWait, resources, f.WaitError); err != nil {
+		return err
 	}
 	return f.PrintingKubeClient.Wait(resources, d)
 }
 
 // WaitWithJobs returns the configured error if set or prints
 func (f *FailingKubeClient) WaitWithJobs(resources kube.ResourceList, d time.Duration) error {
-	if f.WaitError != nil {
-		return f.WaitError
+	if err := f.check(VerbWait, resources, f.WaitError); err != nil {
+		return err
 	}
 	return f.PrintingKubeClient.WaitWithJobs(resources, d)
 }
 
 // WaitForDelete returns the configured error if set or prints
 func (f *FailingKubeClient) WaitForDelete(resources kube.ResourceList, d time.Duration) error {
-	if f.WaitError != nil {
-		return f.WaitError
+	if err := f.check(VerbWaitForDelete, resources, f.WaitError); err != nil {
+		return err
 	}
 	return f.PrintingKubeClient.WaitForDelete(resources, d)
 }
 
 // Delete returns the configured error if set or prints
 func (f *FailingKubeClient) Delete(resources kube.ResourceList) (*kube.Result, []error) {
-	if f.DeleteError != nil {
-		return nil, []error{f.DeleteError}
+	if err := f.check(VerbDelete, resources, f.DeleteError); err != nil {
+		return nil, []error{err}
 	}
 	return f.PrintingKubeClient.Delete(resources)
 }
 
 // WatchUntilReady returns the configured error if set or prints
 func (f *FailingKubeClient) WatchUntilReady(resources kube.ResourceList, d time.Duration) error {
-	if f.WatchUntilReadyError != nil {
-		return f.WatchUntilReadyError
+	if err := f.check(VerbWatchUntilReady, resources, f.WatchUntilReadyError); err != nil {
+		return err
 	}
 	return f.PrintingKubeClient.WatchUntilReady(resources, d)
 }
 
+// CreateWithContext returns the error of ctx if it is done, or else works like
+// Create.
+func (f *FailingKubeClient) CreateWithContext(ctx context.Context, resources kube.ResourceList) (*kube.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, f.check(VerbCreate, resources, err)
+	}
+	return f.Create(resources)
+}
+
+// WatchUntilReadyWithContext watches for WatchDuration, returning the error of
+// ctx if it is done meanwhile, and else works like WatchUntilReady.
+func (f *FailingKubeClient) WatchUntilReadyWithContext(ctx context.Context, resources kube.ResourceList, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+	case <-time.After(f.WatchDuration):
+	}
+	if err := ctx.Err(); err != nil {
+		return f.check(VerbWatchUntilReady, resources, err)
+	}
+	return f.WatchUntilReady(resources, d)
+}
+
+// WaitForDeleteWithContext returns the error of ctx if it is done, or else
+// works like WaitForDelete.
+func (f *FailingKubeClient) WaitForDeleteWithContext(ctx context.Context, resources kube.ResourceList, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return f.check(VerbWaitForDelete, resources, err)
+	}
+	return f.WaitForDelete(resources, d)
+}
+
 // Update returns the configured error if set or prints
 func (f *FailingKubeClient) Update(r, modified kube.ResourceList, ignoreMe bool) (*kube.Result, error) {
-	if f.UpdateError != nil {
-		return &kube.Result{}, f.UpdateError
+	if err := f.check(VerbUpdate, modified, f.UpdateError); err != nil {
+		return &kube.Result{Warnings: f.Warnings}, err
+	}
+	result, err := f.PrintingKubeClient.Update(r, modified, ignoreMe)
+	if result != nil {
+		result.Warnings = f.Warnings
+	}
+	return result, err
+}
+
+// UpdateServerSide returns the configured update error if set or prints
+func (f *FailingKubeClient) UpdateServerSide(r, modified kube.ResourceList, force bool) (*kube.Result, error) {
+	return f.Update(r, modified, force)
+}
+
+// MigrateToServerSideApply returns the configured error if set or prints
+func (f *FailingKubeClient) MigrateToServerSideApply(resources kube.ResourceList) error {
+	if err := f.check(VerbMigrate, resources, f.MigrateError); err != nil {
+		return err
 	}
-	return f.PrintingKubeClient.Update(r, modified, ignoreMe)
+	return f.PrintingKubeClient.MigrateToServerSideApply(resources)
+}
+
+// Replicas returns the replicas the resources were last scaled to, or else
+// those of their objects.
+func (f *FailingKubeClient) Replicas(resources kube.ResourceList) ([]int32, error) {
+	if err := f.check(VerbGet, resources, f.GetError); err != nil {
+		return nil, err
+	}
+	replicas, err := f.PrintingKubeClient.Replicas(resources)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, info := range resources {
+		if r, ok := f.replicas[scaleKey(info)]; ok {
+			replicas[i] = r
+		}
+	}
+	return replicas, nil
+}
+
+// Scale returns the configured error if set or records the replicas of the
+// resources.
+func (f *FailingKubeClient) Scale(resources kube.ResourceList, replicas []int32) error {
+	if err := f.check(VerbScale, resources, f.ScaleError); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.replicas == nil {
+		f.replicas = map[string]int32{}
+	}
+	for i, info := range resources {
+		f.replicas[scaleKey(info)] = replicas[i]
+	}
+	return nil
+}
+
+func scaleKey(info *resource.Info) string {
+	return infoGVK(info).Kind + "/" + info.Namespace + "/" + info.Name
+}
+
+// ReviewAccess returns the AccessDenials of the given verbs.
+func (f *FailingKubeClient) ReviewAccess(_ kube.ResourceList, verbs ...string) ([]kube.AccessDenial, error) {
+	var denials []kube.AccessDenial
+	for _, d := range f.AccessDenials {
+		for _, verb := range verbs {
+			if d.Verb == verb {
+				denials = append(denials, d)
+			}
+		}
+	}
+	return denials, nil
+}
+
+// Unchanged returns the configured get error if set, or reports the resources
+// not named in Changed as unchanged.
+func (f *FailingKubeClient) Unchanged(resources kube.ResourceList) ([]bool, error) {
+	if err := f.check(VerbGet, resources, f.GetError); err != nil {
+		return nil, err
+	}
+	unchanged := make([]bool, len(resources))
+	for i, info := range resources {
+		unchanged[i] = !slices.Contains(f.Changed, info.Name)
+	}
+	return unchanged, nil
 }
 
 // Build returns the configured error if set or prints
@@ -122,9 +283,23 @@ func (f *FailingKubeClient) Build(r io.Reader, _ bool) (kube.ResourceList, error
 	if f.BuildDummy {
 		return createDummyResourceList(), nil
 	}
+	if f.ParseManifests {
+		return parseManifests(r)
+	}
 	return f.PrintingKubeClient.Build(r, false)
 }
 
+// BuildFromObjects returns the configured error if set or prints
+func (f *FailingKubeClient) BuildFromObjects(objs []unstructured.Unstructured, _ bool) (kube.ResourceList, error) {
+	if f.BuildUnstructuredError != nil {
+		return []*resource.Info{}, f.BuildUnstructuredError
+	}
+	if f.BuildDummy {
+		return createDummyResourceList(), nil
+	}
+	return f.PrintingKubeClient.BuildFromObjects(objs, false)
+}
+
 // BuildTable returns the configured error if set or prints
 func (f *FailingKubeClient) BuildTable(r io.Reader, _ bool) (kube.ResourceList, error) {
 	if f.BuildTableError != nil {
@@ -143,8 +318,8 @@ func (f *FailingKubeClient) WaitAndGetCompletedPodPhase(s string, d time.Duratio
 
 // DeleteWithPropagationPolicy returns the configured error if set or prints
 func (f *FailingKubeClient) DeleteWithPropagationPolicy(resources kube.ResourceList, policy metav1.DeletionPropagation) (*kube.Result, []error) {
-	if f.DeleteWithPropagationError != nil {
-		return nil, []error{f.DeleteWithPropagationError}
+	if err := f.check(VerbDelete, resources, f.DeleteWithPropagationError); err != nil {
+		return nil, []error{err}
 	}
 	return f.PrintingKubeClient.DeleteWithPropagationPolicy(resources, policy)
 }
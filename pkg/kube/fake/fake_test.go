@@ -0,0 +1,99 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const manifests = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: apps
+`
+
+func TestFailingKubeClientFailures(t *testing.T) {
+	errTimeout := errors.New("timed out")
+	f := &FailingKubeClient{
+		PrintingKubeClient: PrintingKubeClient{Out: io.Discard},
+		ParseManifests:     true,
+		Failures: []Failure{
+			{Verb: VerbWait, GVK: schema.GroupVersionKind{Group: "batch", Kind: "Job"}, Times: 1, Err: errTimeout},
+		},
+	}
+
+	resources, err := f.Build(strings.NewReader(manifests), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(resources))
+	}
+	if ns := resources[0].Namespace; ns != "default" {
+		t.Errorf("expected the job in the default namespace, got %q", ns)
+	}
+
+	if _, err := f.Create(resources); err != nil {
+		t.Errorf("expected create to succeed, got %v", err)
+	}
+	if err := f.Wait(resources[1:], time.Second); err != nil {
+		t.Errorf("expected waiting for the config map to succeed, got %v", err)
+	}
+	if err := f.Wait(resources, time.Second); err != errTimeout {
+		t.Errorf("expected waiting for the job to time out, got %v", err)
+	}
+	if err := f.Wait(resources, time.Second); err != nil {
+		t.Errorf("expected the failure to be injected once, got %v", err)
+	}
+
+	ops := f.Operations()
+	if len(ops) != 4 {
+		t.Fatalf("expected 4 operations, got %d", len(ops))
+	}
+	if ops[0].Verb != VerbCreate || len(ops[0].Resources) != 2 {
+		t.Errorf("unexpected first operation %+v", ops[0])
+	}
+	if waits := f.OperationsOf(VerbWait); len(waits) != 3 || waits[1].Err != errTimeout {
+		t.Errorf("unexpected wait operations %+v", waits)
+	}
+}
+
+func TestFailingKubeClientLatency(t *testing.T) {
+	f := &FailingKubeClient{
+		PrintingKubeClient: PrintingKubeClient{Out: io.Discard},
+		Latency:            10 * time.Millisecond,
+	}
+	start := time.Now()
+	if _, err := f.Get(nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < f.Latency {
+		t.Errorf("expected the operation to take at least %s, took %s", f.Latency, elapsed)
+	}
+}
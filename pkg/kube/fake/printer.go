@@ -23,6 +23,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/resource"
 
@@ -33,6 +34,8 @@ import (
 // the given output.
 type PrintingKubeClient struct {
 	Out io.Writer
+	// User is the name returned by CurrentUser.
+	User string
 }
 
 // IsReachable checks if the cluster is reachable
@@ -57,6 +60,23 @@ func (p *PrintingKubeClient) Get(resources kube.ResourceList, _ bool) (map[strin
 	return make(map[string][]runtime.Object), nil
 }
 
+// Status prints the resources and reports all of them as ready.
+func (p *PrintingKubeClient) Status(resources kube.ResourceList) ([]kube.ResourceStatus, error) {
+	if _, err := io.Copy(p.Out, bufferize(resources)); err != nil {
+		return nil, err
+	}
+	statuses := make([]kube.ResourceStatus, len(resources))
+	for i, info := range resources {
+		statuses[i] = kube.ResourceStatus{Info: info, Health: kube.HealthReady}
+	}
+	return statuses, nil
+}
+
+// CurrentUser returns the configured user.
+func (p *PrintingKubeClient) CurrentUser() (string, error) {
+	return p.User, nil
+}
+
 func (p *PrintingKubeClient) Wait(resources kube.ResourceList, _ time.Duration) error {
 	_, err := io.Copy(p.Out, bufferize(resources))
 	return err
@@ -101,11 +121,52 @@ func (p *PrintingKubeClient) Update(_, modified kube.ResourceList, _ bool) (*kub
 	return &kube.Result{Updated: modified}, nil
 }
 
+// UpdateServerSide implements KubeClient UpdateServerSide.
+func (p *PrintingKubeClient) UpdateServerSide(original, modified kube.ResourceList, force bool) (*kube.Result, error) {
+	return p.Update(original, modified, force)
+}
+
+// MigrateToServerSideApply implements KubeClient MigrateToServerSideApply.
+//
+// It only prints out the resources to be migrated.
+func (p *PrintingKubeClient) MigrateToServerSideApply(resources kube.ResourceList) error {
+	_, err := io.Copy(p.Out, bufferize(resources))
+	return err
+}
+
+// Replicas returns spec.replicas of the objects of the resources, one if
+// unset, without printing them.
+func (p *PrintingKubeClient) Replicas(resources kube.ResourceList) ([]int32, error) {
+	replicas := make([]int32, len(resources))
+	for i, info := range resources {
+		replicas[i] = 1
+		if u, ok := info.Object.(*unstructured.Unstructured); ok {
+			if r, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas"); found {
+				replicas[i] = int32(r)
+			}
+		}
+	}
+	return replicas, nil
+}
+
+// Scale implements KubeClient Scale.
+//
+// It only prints out the resources to be scaled.
+func (p *PrintingKubeClient) Scale(resources kube.ResourceList, _ []int32) error {
+	_, err := io.Copy(p.Out, bufferize(resources))
+	return err
+}
+
 // Build implements KubeClient Build.
 func (p *PrintingKubeClient) Build(_ io.Reader, _ bool) (kube.ResourceList, error) {
 	return []*resource.Info{}, nil
 }
 
+// BuildFromObjects implements KubeClient BuildFromObjects.
+func (p *PrintingKubeClient) BuildFromObjects(_ []unstructured.Unstructured, _ bool) (kube.ResourceList, error) {
+	return []*resource.Info{}, nil
+}
+
 // BuildTable implements KubeClient BuildTable.
 func (p *PrintingKubeClient) BuildTable(_ io.Reader, _ bool) (kube.ResourceList, error) {
 	return []*resource.Info{}, nil
@@ -0,0 +1,110 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// FinalizerRemovableKinds lists the kinds whose finalizers are known to be
+// safe to remove once the resource is marked for deletion. Finalizers of
+// other kinds usually guard cleanup done by a controller (e.g. of an
+// operator) and must not be removed by Helm.
+var FinalizerRemovableKinds = []string{
+	"ConfigMap",
+	"Job",
+	"PersistentVolumeClaim",
+	"Pod",
+	"Secret",
+	"Service",
+}
+
+// IsFinalizerRemovable reports whether the finalizers of the given kind may be
+// removed by Helm.
+func IsFinalizerRemovable(kind string) bool {
+	for _, k := range FinalizerRemovableKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// StuckResource is a resource that was marked for deletion but still exists
+// because of pending finalizers.
+type StuckResource struct {
+	Info       *resource.Info
+	Finalizers []string
+}
+
+func (s StuckResource) String() string {
+	kind := s.Info.Mapping.GroupVersionKind.Kind
+	if s.Info.Namespace == "" {
+		return fmt.Sprintf("%s %s is waiting on finalizers %v", kind, s.Info.Name, s.Finalizers)
+	}
+	return fmt.Sprintf("%s %s/%s is waiting on finalizers %v", kind, s.Info.Namespace, s.Info.Name, s.Finalizers)
+}
+
+// StuckOnFinalizers returns the resources that are marked for deletion but
+// still exist because of pending finalizers. Resources that are gone or not
+// marked for deletion are skipped.
+func (c *Client) StuckOnFinalizers(resources ResourceList) ([]StuckResource, error) {
+	var stuck []StuckResource
+	for _, info := range resources {
+		if err := info.Get(); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return stuck, err
+		}
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			return stuck, err
+		}
+		if accessor.GetDeletionTimestamp() == nil {
+			continue
+		}
+		if finalizers := accessor.GetFinalizers(); len(finalizers) > 0 {
+			stuck = append(stuck, StuckResource{Info: info, Finalizers: finalizers})
+		}
+	}
+	return stuck, nil
+}
+
+// RemoveFinalizers removes all finalizers of the given resources, which lets
+// the API server complete their deletion. Resources that are already gone
+// are skipped.
+func (c *Client) RemoveFinalizers(resources ResourceList) error {
+	patch := []byte(`{"metadata":{"finalizers":null}}`)
+	for _, info := range resources {
+		c.Log("Removing finalizers of %s %q in namespace %s", info.Mapping.GroupVersionKind.Kind, info.Name, info.Namespace)
+		helper := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager())
+		if _, err := helper.Patch(info.Namespace, info.Name, types.MergePatchType, patch, nil); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "unable to remove finalizers of %s %q", info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+	}
+	return nil
+}
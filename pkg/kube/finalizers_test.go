@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestStuckOnFinalizers(t *testing.T) {
+	newClaim := func(name string, deleting bool, finalizers ...string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("PersistentVolumeClaim")
+		obj.SetNamespace(defaultNamespace)
+		obj.SetName(name)
+		obj.SetFinalizers(finalizers)
+		if deleting {
+			now := metav1.Now()
+			obj.SetDeletionTimestamp(&now)
+		}
+		return obj
+	}
+
+	resources := ResourceList{
+		newUnstructuredInfo(t, newClaim("stuck", true, "kubernetes.io/pvc-protection")),
+		newUnstructuredInfo(t, newClaim("deleting", true)),
+		newUnstructuredInfo(t, newClaim("alive", false, "kubernetes.io/pvc-protection")),
+	}
+
+	c := newTestClient(t)
+	stuck, err := c.StuckOnFinalizers(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stuck) != 1 {
+		t.Fatalf("expected 1 stuck resource, got %d", len(stuck))
+	}
+	expected := "PersistentVolumeClaim default/stuck is waiting on finalizers [kubernetes.io/pvc-protection]"
+	if stuck[0].String() != expected {
+		t.Errorf("expected %q, got %q", expected, stuck[0].String())
+	}
+
+	if !IsFinalizerRemovable("PersistentVolumeClaim") {
+		t.Error("expected finalizers of PersistentVolumeClaims to be removable")
+	}
+	if IsFinalizerRemovable("Certificate") {
+		t.Error("expected finalizers of Certificates not to be removable")
+	}
+}
@@ -17,11 +17,13 @@ limitations under the License.
 package kube
 
 import (
+	"context"
 	"io"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -110,7 +112,128 @@ type InterfaceResources interface {
 	BuildTable(reader io.Reader, validate bool) (ResourceList, error)
 }
 
+// InterfaceDiscovery is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceDiscovery and integrate its method(s) into the Interface.
+type InterfaceDiscovery interface {
+	// InvalidateDiscovery drops cached discovery information and REST mappings
+	// so that types registered after the cache was filled (e.g. by CRDs) are
+	// recognized by subsequent calls.
+	InvalidateDiscovery() error
+}
+
+// InterfaceObjects is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceObjects and integrate its method(s) into the Interface.
+type InterfaceObjects interface {
+	// BuildFromObjects creates a resource list from already decoded objects.
+	//
+	// Validates against OpenAPI schema if validate is true.
+	BuildFromObjects(objs []unstructured.Unstructured, validate bool) (ResourceList, error)
+}
+
+// InterfaceFinalizers is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceFinalizers and integrate its method(s) into the Interface.
+type InterfaceFinalizers interface {
+	// StuckOnFinalizers returns the resources that are marked for deletion
+	// but still exist because of pending finalizers.
+	StuckOnFinalizers(resources ResourceList) ([]StuckResource, error)
+
+	// RemoveFinalizers removes all finalizers of the given resources.
+	RemoveFinalizers(resources ResourceList) error
+}
+
+// InterfaceStatus is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceStatus and integrate its method(s) into the Interface.
+type InterfaceStatus interface {
+	// Status returns the live existence, health and age of the given
+	// resources in the same order.
+	Status(resources ResourceList) ([]ResourceStatus, error)
+}
+
+// InterfaceIdentity is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceIdentity and integrate its method(s) into the Interface.
+type InterfaceIdentity interface {
+	// CurrentUser returns the name of the user the API server authenticates
+	// the client as.
+	CurrentUser() (string, error)
+}
+
+// InterfaceServerSideApply is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceServerSideApply and integrate its method(s) into the Interface.
+type InterfaceServerSideApply interface {
+	// UpdateServerSide updates one or more resources, or creates them if they
+	// don't exist, with server-side apply.
+	UpdateServerSide(original, target ResourceList, force bool) (*Result, error)
+
+	// MigrateToServerSideApply transfers the ownership of the fields set by
+	// client-side updates of the given resources to server-side apply.
+	MigrateToServerSideApply(resources ResourceList) error
+}
+
+// InterfaceScale is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceScale and integrate its method(s) into the Interface.
+type InterfaceScale interface {
+	// Replicas returns the live number of replicas of the given workloads in
+	// the same order.
+	Replicas(resources ResourceList) ([]int32, error)
+
+	// Scale sets the replicas of the given workloads to the numbers in the
+	// same order.
+	Scale(resources ResourceList, replicas []int32) error
+}
+
+// InterfaceAccess is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceAccess and integrate its method(s) into the Interface.
+type InterfaceAccess interface {
+	// ReviewAccess returns the verbs the client is not allowed to use on the
+	// kinds of the given resources in their namespaces.
+	ReviewAccess(resources ResourceList, verbs ...string) ([]AccessDenial, error)
+}
+
+// InterfaceUnchanged is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceUnchanged and integrate its method(s) into the Interface.
+type InterfaceUnchanged interface {
+	// Unchanged reports in the same order whether applying each of the given
+	// resources server-side would leave it as it is in the cluster.
+	Unchanged(resources ResourceList) ([]bool, error)
+}
+
+// InterfaceContext is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceContext and pass a context to the methods of the Interface.
+type InterfaceContext interface {
+	// CreateWithContext works like Create, but stops creating resources once
+	// ctx is done.
+	CreateWithContext(ctx context.Context, resources ResourceList) (*Result, error)
+
+	// WatchUntilReadyWithContext works like WatchUntilReady, but stops
+	// watching once ctx is done.
+	WatchUntilReadyWithContext(ctx context.Context, resources ResourceList, timeout time.Duration) error
+
+	// WaitForDeleteWithContext works like WaitForDelete, but stops waiting
+	// once ctx is done.
+	WaitForDeleteWithContext(ctx context.Context, resources ResourceList, timeout time.Duration) error
+}
+
 var _ Interface = (*Client)(nil)
 var _ InterfaceExt = (*Client)(nil)
 var _ InterfaceDeletionPropagation = (*Client)(nil)
 var _ InterfaceResources = (*Client)(nil)
+var _ InterfaceDiscovery = (*Client)(nil)
+var _ InterfaceObjects = (*Client)(nil)
+var _ InterfaceFinalizers = (*Client)(nil)
+var _ InterfaceStatus = (*Client)(nil)
+var _ InterfaceIdentity = (*Client)(nil)
+var _ InterfaceServerSideApply = (*Client)(nil)
+var _ InterfaceScale = (*Client)(nil)
+var _ InterfaceAccess = (*Client)(nil)
+var _ InterfaceUnchanged = (*Client)(nil)
+var _ InterfaceContext = (*Client)(nil)
@@ -30,6 +30,7 @@ import (
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes"
@@ -58,6 +59,15 @@ func CheckJobs(checkJobs bool) ReadyCheckerOption {
 	}
 }
 
+// WithStatusMappings returns a ReadyCheckerOption that configures a
+// ReadyChecker to determine the readiness of the mapped kinds from their
+// status conditions.
+func WithStatusMappings(m *StatusMappings) ReadyCheckerOption {
+	return func(c *ReadyChecker) {
+		c.statusMappings = m
+	}
+}
+
 // NewReadyChecker creates a new checker. Passed ReadyCheckerOptions can
 // be used to override defaults.
 func NewReadyChecker(cl kubernetes.Interface, log func(string, ...interface{}), opts ...ReadyCheckerOption) ReadyChecker {
@@ -76,29 +86,41 @@ func NewReadyChecker(cl kubernetes.Interface, log func(string, ...interface{}),
 
 // ReadyChecker is a type that can check core Kubernetes types for readiness.
 type ReadyChecker struct {
-	client        kubernetes.Interface
-	log           func(string, ...interface{})
-	checkJobs     bool
-	pausedAsReady bool
+	client         kubernetes.Interface
+	log            func(string, ...interface{})
+	checkJobs      bool
+	pausedAsReady  bool
+	statusMappings *StatusMappings
+	// watches hold the live state of the resources being checked. Resources
+	// missing from it are fetched from the API server.
+	watches *watchCache
 }
 
 // IsReady checks if v is ready. It supports checking readiness for pods,
 // deployments, persistent volume claims, services, daemon sets, custom
 // resource definitions, stateful sets, replication controllers, jobs (optional),
-// and replica sets. All other resource kinds are always considered ready.
+// and replica sets. Kinds with a status mapping (see WithStatusMappings) are
+// checked against their mapped conditions. All other resource kinds are always
+// considered ready.
 //
 // IsReady will fetch the latest state of the object from the server prior to
 // performing readiness checks, and it will return any error encountered.
 func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, error) {
+	if v.Mapping != nil {
+		if mapping := c.statusMappings.lookup(v.Mapping.GroupVersionKind.GroupKind()); mapping != nil {
+			return c.customResourceReady(v, mapping)
+		}
+	}
+
 	switch value := AsVersioned(v).(type) {
 	case *corev1.Pod:
-		pod, err := c.client.CoreV1().Pods(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+		pod, err := c.getPod(ctx, v)
 		if err != nil || !c.isPodReady(pod) {
 			return false, err
 		}
 	case *batchv1.Job:
 		if c.checkJobs {
-			job, err := c.client.BatchV1().Jobs(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+			job, err := c.getJob(ctx, v)
 			if err != nil {
 				return false, err
 			}
@@ -106,7 +128,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return ready, err
 		}
 	case *appsv1.Deployment, *appsv1beta1.Deployment, *appsv1beta2.Deployment, *extensionsv1beta1.Deployment:
-		currentDeployment, err := c.client.AppsV1().Deployments(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+		currentDeployment, err := c.getDeployment(ctx, v)
 		if err != nil {
 			return false, err
 		}
@@ -123,7 +145,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return false, nil
 		}
 	case *corev1.PersistentVolumeClaim:
-		claim, err := c.client.CoreV1().PersistentVolumeClaims(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+		claim, err := c.getPersistentVolumeClaim(ctx, v)
 		if err != nil {
 			return false, err
 		}
@@ -131,7 +153,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return false, nil
 		}
 	case *corev1.Service:
-		svc, err := c.client.CoreV1().Services(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+		svc, err := c.getService(ctx, v)
 		if err != nil {
 			return false, err
 		}
@@ -139,7 +161,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return false, nil
 		}
 	case *extensionsv1beta1.DaemonSet, *appsv1.DaemonSet, *appsv1beta2.DaemonSet:
-		ds, err := c.client.AppsV1().DaemonSets(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+		ds, err := c.getDaemonSet(ctx, v)
 		if err != nil {
 			return false, err
 		}
@@ -169,7 +191,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return false, nil
 		}
 	case *appsv1.StatefulSet, *appsv1beta1.StatefulSet, *appsv1beta2.StatefulSet:
-		sts, err := c.client.AppsV1().StatefulSets(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+		sts, err := c.getStatefulSet(ctx, v)
 		if err != nil {
 			return false, err
 		}
@@ -177,7 +199,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return false, nil
 		}
 	case *corev1.ReplicationController:
-		rc, err := c.client.CoreV1().ReplicationControllers(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+		rc, err := c.getReplicationController(ctx, v)
 		if err != nil {
 			return false, err
 		}
@@ -189,7 +211,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return false, err
 		}
 	case *extensionsv1beta1.ReplicaSet, *appsv1beta2.ReplicaSet, *appsv1.ReplicaSet:
-		rs, err := c.client.AppsV1().ReplicaSets(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+		rs, err := c.getReplicaSet(ctx, v)
 		if err != nil {
 			return false, err
 		}
@@ -461,3 +483,138 @@ func getPods(ctx context.Context, client kubernetes.Interface, namespace, select
 	})
 	return list.Items, err
 }
+
+// watch starts watching the resources whose live state IsReady reads, so
+// that polling their readiness does not fetch every resource on its own. It
+// returns a function to stop the watches.
+func (c *ReadyChecker) watch(resources ResourceList) func() {
+	var watched ResourceList
+	for _, v := range resources {
+		if c.watchable(v) {
+			watched = append(watched, v)
+		}
+	}
+	c.watches = newWatchCache(watched)
+	return c.watches.close
+}
+
+// watchable returns whether IsReady reads the live state of v from the
+// watches of the checker.
+func (c *ReadyChecker) watchable(v *resource.Info) bool {
+	if v.Mapping == nil || v.Client == nil {
+		return false
+	}
+	gvk := v.Mapping.GroupVersionKind
+	if c.statusMappings.lookup(gvk.GroupKind()) != nil {
+		return true
+	}
+	switch gvk {
+	case corev1.SchemeGroupVersion.WithKind("Pod"),
+		corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"),
+		corev1.SchemeGroupVersion.WithKind("Service"),
+		corev1.SchemeGroupVersion.WithKind("ReplicationController"),
+		appsv1.SchemeGroupVersion.WithKind("Deployment"),
+		appsv1.SchemeGroupVersion.WithKind("DaemonSet"),
+		appsv1.SchemeGroupVersion.WithKind("StatefulSet"),
+		appsv1.SchemeGroupVersion.WithKind("ReplicaSet"):
+		return true
+	case batchv1.SchemeGroupVersion.WithKind("Job"):
+		return c.checkJobs
+	}
+	return false
+}
+
+// cached fills obj with the live state of v held by the watches of the
+// checker. It returns false if v is not of the given kind or its state is not
+// held, in which case it has to be fetched from the API server.
+func (c *ReadyChecker) cached(v *resource.Info, gvk schema.GroupVersionKind, obj runtime.Object) bool {
+	if v.Mapping == nil || v.Mapping.GroupVersionKind != gvk {
+		return false
+	}
+	u, ok := c.watches.get(v)
+	if !ok {
+		return false
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj) == nil
+}
+
+// getPod returns the live state of a Pod.
+func (c *ReadyChecker) getPod(ctx context.Context, v *resource.Info) (*corev1.Pod, error) {
+	pod := &corev1.Pod{}
+	if c.cached(v, corev1.SchemeGroupVersion.WithKind("Pod"), pod) {
+		return pod, nil
+	}
+	return c.client.CoreV1().Pods(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
+
+// getJob returns the live state of a Job.
+func (c *ReadyChecker) getJob(ctx context.Context, v *resource.Info) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+	if c.cached(v, batchv1.SchemeGroupVersion.WithKind("Job"), job) {
+		return job, nil
+	}
+	return c.client.BatchV1().Jobs(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
+
+// getDeployment returns the live state of a Deployment.
+func (c *ReadyChecker) getDeployment(ctx context.Context, v *resource.Info) (*appsv1.Deployment, error) {
+	deployment := &appsv1.Deployment{}
+	if c.cached(v, appsv1.SchemeGroupVersion.WithKind("Deployment"), deployment) {
+		return deployment, nil
+	}
+	return c.client.AppsV1().Deployments(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
+
+// getPersistentVolumeClaim returns the live state of a PersistentVolumeClaim.
+func (c *ReadyChecker) getPersistentVolumeClaim(ctx context.Context, v *resource.Info) (*corev1.PersistentVolumeClaim, error) {
+	claim := &corev1.PersistentVolumeClaim{}
+	if c.cached(v, corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"), claim) {
+		return claim, nil
+	}
+	return c.client.CoreV1().PersistentVolumeClaims(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
+
+// getService returns the live state of a Service.
+func (c *ReadyChecker) getService(ctx context.Context, v *resource.Info) (*corev1.Service, error) {
+	svc := &corev1.Service{}
+	if c.cached(v, corev1.SchemeGroupVersion.WithKind("Service"), svc) {
+		return svc, nil
+	}
+	return c.client.CoreV1().Services(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
+
+// getDaemonSet returns the live state of a DaemonSet.
+func (c *ReadyChecker) getDaemonSet(ctx context.Context, v *resource.Info) (*appsv1.DaemonSet, error) {
+	ds := &appsv1.DaemonSet{}
+	if c.cached(v, appsv1.SchemeGroupVersion.WithKind("DaemonSet"), ds) {
+		return ds, nil
+	}
+	return c.client.AppsV1().DaemonSets(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
+
+// getStatefulSet returns the live state of a StatefulSet.
+func (c *ReadyChecker) getStatefulSet(ctx context.Context, v *resource.Info) (*appsv1.StatefulSet, error) {
+	sts := &appsv1.StatefulSet{}
+	if c.cached(v, appsv1.SchemeGroupVersion.WithKind("StatefulSet"), sts) {
+		return sts, nil
+	}
+	return c.client.AppsV1().StatefulSets(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
+
+// getReplicationController returns the live state of a ReplicationController.
+func (c *ReadyChecker) getReplicationController(ctx context.Context, v *resource.Info) (*corev1.ReplicationController, error) {
+	rc := &corev1.ReplicationController{}
+	if c.cached(v, corev1.SchemeGroupVersion.WithKind("ReplicationController"), rc) {
+		return rc, nil
+	}
+	return c.client.CoreV1().ReplicationControllers(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
+
+// getReplicaSet returns the live state of a ReplicaSet.
+func (c *ReadyChecker) getReplicaSet(ctx context.Context, v *resource.Info) (*appsv1.ReplicaSet, error) {
+	rs := &appsv1.ReplicaSet{}
+	if c.cached(v, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), rs) {
+		return rs, nil
+	}
+	return c.client.AppsV1().ReplicaSets(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
+}
@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// Health values of a ResourceStatus.
+const (
+	// HealthReady means the resource exists and is ready, as it would be
+	// considered when waiting for it.
+	HealthReady = "Ready"
+	// HealthNotReady means the resource exists but is not ready yet.
+	HealthNotReady = "NotReady"
+	// HealthFailed means the resource failed, e.g. a Job that exceeded its
+	// backoff limit.
+	HealthFailed = "Failed"
+	// HealthMissing means the resource does not exist.
+	HealthMissing = "Missing"
+	// HealthUnknown means the resource could not be retrieved.
+	HealthUnknown = "Unknown"
+)
+
+// statusConcurrency is the number of resources whose status is fetched at
+// the same time.
+const statusConcurrency = 8
+
+// ResourceStatus is the live status of a resource.
+type ResourceStatus struct {
+	Info *resource.Info
+	// Health is one of the Health* values.
+	Health string
+	// Message explains the health of a failed or unknown resource.
+	Message string
+	// CreationTimestamp is when the resource was created. It is zero for
+	// missing resources.
+	CreationTimestamp time.Time
+}
+
+// Status returns the live status of the given resources in the same order.
+// The health of a resource is evaluated the same way as when waiting for it,
+// including Jobs and custom resources with a status mapping.
+func (c *Client) Status(resources ResourceList) ([]ResourceStatus, error) {
+	cs, err := c.getKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := c.statusMappings()
+	if err != nil {
+		return nil, err
+	}
+	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true), CheckJobs(true), WithStatusMappings(mappings))
+	return resourceStatuses(context.Background(), checker, resources), nil
+}
+
+func resourceStatuses(ctx context.Context, checker ReadyChecker, resources ResourceList) []ResourceStatus {
+	statuses := make([]ResourceStatus, len(resources))
+	sem := make(chan struct{}, statusConcurrency)
+	var wg sync.WaitGroup
+	for i, info := range resources {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, info *resource.Info) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			statuses[i] = resourceStatus(ctx, checker, info)
+		}(i, info)
+	}
+	wg.Wait()
+	return statuses
+}
+
+func resourceStatus(ctx context.Context, checker ReadyChecker, info *resource.Info) ResourceStatus {
+	status := ResourceStatus{Info: info}
+
+	// Fetch the live object into a copy to leave the resource list intact.
+	live := *info
+	if err := live.Get(); err != nil {
+		if apierrors.IsNotFound(err) {
+			status.Health = HealthMissing
+			return status
+		}
+		status.Health = HealthUnknown
+		status.Message = err.Error()
+		return status
+	}
+	if accessor, err := meta.Accessor(live.Object); err == nil {
+		status.CreationTimestamp = accessor.GetCreationTimestamp().Time
+	}
+
+	ready, err := checker.IsReady(ctx, &live)
+	switch {
+	case err != nil:
+		status.Health = HealthFailed
+		status.Message = err.Error()
+	case ready:
+		status.Health = HealthReady
+	default:
+		status.Health = HealthNotReady
+	}
+	return status
+}
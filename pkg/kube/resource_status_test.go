@@ -0,0 +1,90 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+	restfake "k8s.io/client-go/rest/fake"
+)
+
+func TestResourceStatuses(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	configMap := &unstructured.Unstructured{}
+	configMap.SetAPIVersion("v1")
+	configMap.SetKind("ConfigMap")
+	configMap.SetNamespace(defaultNamespace)
+	configMap.SetName("config")
+	configMap.SetCreationTimestamp(created)
+
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace(defaultNamespace)
+	pod.SetName("starting")
+
+	missing := newUnstructuredInfo(t, configMap)
+	missing.Name = "missing"
+	missing.Client = &restfake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: restfake.CreateHTTPClient(func(_ *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusNotFound, notFoundBody())
+		}),
+	}
+
+	client := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "starting", Namespace: defaultNamespace},
+		Status:     v1.PodStatus{Phase: v1.PodPending},
+	})
+	checker := NewReadyChecker(client, nil, PausedAsReady(true), CheckJobs(true))
+
+	statuses := resourceStatuses(context.Background(), checker, ResourceList{
+		newUnstructuredInfo(t, configMap),
+		newUnstructuredInfo(t, pod),
+		missing,
+	})
+
+	expected := []struct {
+		name    string
+		health  string
+		created bool
+	}{
+		{"config", HealthReady, true},
+		{"starting", HealthNotReady, false},
+		{"missing", HealthMissing, false},
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %d statuses, got %d", len(expected), len(statuses))
+	}
+	for i, want := range expected {
+		got := statuses[i]
+		if got.Info.Name != want.name || got.Health != want.health {
+			t.Errorf("expected %s to be %s, got %s %s", want.name, want.health, got.Info.Name, got.Health)
+		}
+		if want.created && !got.CreationTimestamp.Equal(created.Time) {
+			t.Errorf("expected %s to be created at %s, got %s", want.name, created, got.CreationTimestamp)
+		}
+	}
+}
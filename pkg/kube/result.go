@@ -23,6 +23,9 @@ type Result struct {
 	Created ResourceList
 	Updated ResourceList
 	Deleted ResourceList
+	// Warnings are the warnings the API server returned for the requests,
+	// such as deprecation notices and the warnings of admission webhooks.
+	Warnings []string
 }
 
 // If needed, we can add methods to the Result type for things like diffing
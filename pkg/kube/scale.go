@@ -0,0 +1,81 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// Replicas returns the live number of replicas of the given workloads in the
+// same order. Workloads without spec.replicas run the default of one replica.
+func (c *Client) Replicas(resources ResourceList) ([]int32, error) {
+	replicas := make([]int32, len(resources))
+	for i, info := range resources {
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get %s %q", info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+		replicas[i], err = specReplicas(obj)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read the replicas of %s %q", info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+	}
+	return replicas, nil
+}
+
+// Scale sets the replicas of the given workloads to the numbers in the same
+// order.
+func (c *Client) Scale(resources ResourceList, replicas []int32) error {
+	if len(resources) != len(replicas) {
+		return errors.Errorf("got %d replica counts for %d resources", len(replicas), len(resources))
+	}
+	for i, info := range resources {
+		c.Log("Scaling %s %q in namespace %s to %d replicas", info.Mapping.GroupVersionKind.Kind, info.Name, info.Namespace, replicas[i])
+		patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas[i]))
+		helper := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager())
+		if _, err := helper.Patch(info.Namespace, info.Name, types.MergePatchType, patch, nil); err != nil {
+			return errors.Wrapf(err, "unable to scale %s %q", info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+	}
+	return nil
+}
+
+// specReplicas returns spec.replicas of a workload, one if unset.
+func specReplicas(obj runtime.Object) (int32, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return 0, err
+		}
+		u = &unstructured.Unstructured{Object: content}
+	}
+	replicas, found, err := unstructured.NestedInt64(u.Object, "spec", "replicas")
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 1, nil
+	}
+	return int32(replicas), nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestReplicas(t *testing.T) {
+	newDeployment := func(name string, replicas ...int64) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("apps/v1")
+		obj.SetKind("Deployment")
+		obj.SetNamespace(defaultNamespace)
+		obj.SetName(name)
+		for _, r := range replicas {
+			if err := unstructured.SetNestedField(obj.Object, r, "spec", "replicas"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return obj
+	}
+
+	resources := ResourceList{
+		newUnstructuredInfo(t, newDeployment("scaled", 3)),
+		newUnstructuredInfo(t, newDeployment("zero", 0)),
+		newUnstructuredInfo(t, newDeployment("default")),
+	}
+
+	c := newTestClient(t)
+	replicas, err := c.Replicas(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []int32{3, 0, 1}
+	for i := range expected {
+		if replicas[i] != expected[i] {
+			t.Errorf("expected %d replicas of %s, got %d", expected[i], resources[i].Name, replicas[i])
+		}
+	}
+
+	if err := c.Scale(resources, []int32{1}); err == nil {
+		t.Error("expected an error for mismatched replica counts")
+	}
+}
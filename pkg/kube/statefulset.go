@@ -0,0 +1,236 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+)
+
+// statefulSetMutableFields are the fields of a StatefulSet spec that can be
+// updated in place. Changes to all other fields are rejected by the API server.
+var statefulSetMutableFields = map[string]bool{
+	"replicas":                             true,
+	"ordinals":                             true,
+	"template":                             true,
+	"updateStrategy":                       true,
+	"persistentVolumeClaimRetentionPolicy": true,
+	"minReadySeconds":                      true,
+}
+
+// immutableStatefulSetChanges returns the immutable spec fields that differ
+// between the current and the target StatefulSet.
+//
+// Both objects are compared as rendered by the chart, so that fields defaulted
+// by the API server do not show up as changes.
+func immutableStatefulSetChanges(current, target runtime.Object) ([]string, error) {
+	currentSpec, err := unstructuredSpec(current)
+	if err != nil {
+		return nil, err
+	}
+	targetSpec, err := unstructuredSpec(target)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]struct{})
+	for k := range currentSpec {
+		keys[k] = struct{}{}
+	}
+	for k := range targetSpec {
+		keys[k] = struct{}{}
+	}
+
+	var changed []string
+	for k := range keys {
+		if statefulSetMutableFields[k] {
+			continue
+		}
+		if !reflect.DeepEqual(currentSpec[k], targetSpec[k]) {
+			changed = append(changed, "spec."+k)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// expandedClaimTemplates returns the volumeClaimTemplates of a StatefulSet
+// whose storage request grew, mapped to the new request.
+func expandedClaimTemplates(current, target runtime.Object) (map[string]resource.Quantity, error) {
+	currentSizes, err := claimTemplateSizes(current)
+	if err != nil {
+		return nil, err
+	}
+	targetSizes, err := claimTemplateSizes(target)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := make(map[string]resource.Quantity)
+	for name, size := range targetSizes {
+		if old, ok := currentSizes[name]; ok && size.Cmp(old) > 0 {
+			expanded[name] = size
+		}
+	}
+	return expanded, nil
+}
+
+func claimTemplateSizes(obj runtime.Object) (map[string]resource.Quantity, error) {
+	spec, err := unstructuredSpec(obj)
+	if err != nil {
+		return nil, err
+	}
+	templates, _, err := unstructured.NestedSlice(spec, "volumeClaimTemplates")
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]resource.Quantity)
+	for _, t := range templates {
+		template, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(template, "metadata", "name")
+		storage, found, _ := unstructured.NestedString(template, "spec", "resources", "requests", "storage")
+		if name == "" || !found {
+			continue
+		}
+		size, err := resource.ParseQuantity(storage)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid storage request of volumeClaimTemplate %q", name)
+		}
+		sizes[name] = size
+	}
+	return sizes, nil
+}
+
+func unstructuredSpec(obj runtime.Object) (map[string]interface{}, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	spec, _, err := unstructured.NestedMap(u, "spec")
+	return spec, err
+}
+
+// immutableStatefulSetError explains how to upgrade a StatefulSet whose
+// immutable fields changed.
+func immutableStatefulSetError(name string, fields []string) error {
+	return errors.Errorf("StatefulSet %q cannot be upgraded in place as the fields %s are immutable. "+
+		"Annotate it with %s: %s to delete the StatefulSet while keeping its pods and create it again. "+
+		"Increased storage requests of volumeClaimTemplates are then applied to the existing PersistentVolumeClaims where their StorageClass allows volume expansion",
+		name, strings.Join(fields, ", "), UpgradeStrategyAnno, UpgradeStrategyRecreateOrphan)
+}
+
+// resizeStatefulSetClaims applies increased storage requests of the
+// volumeClaimTemplates of target to the PersistentVolumeClaims of the live
+// StatefulSet.
+func (c *Client) resizeStatefulSetClaims(target *cliresource.Info, currentObj runtime.Object) error {
+	sizes, err := expandedClaimTemplates(currentObj, target.Object)
+	if err != nil {
+		return errors.Wrap(err, "failed to compare volumeClaimTemplates")
+	}
+	if len(sizes) == 0 {
+		return nil
+	}
+
+	live, err := cliresource.NewHelper(target.Client, target.Mapping).Get(target.Namespace, target.Name)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get StatefulSet %q", target.Name)
+	}
+	spec, err := unstructuredSpec(live)
+	if err != nil {
+		return err
+	}
+	replicas, found, err := unstructured.NestedInt64(spec, "replicas")
+	if err != nil {
+		return err
+	}
+	if !found {
+		replicas = 1
+	}
+	start, _, err := unstructured.NestedInt64(spec, "ordinals", "start")
+	if err != nil {
+		return err
+	}
+
+	client, err := c.getKubeClient()
+	if err != nil {
+		return err
+	}
+	return expandStatefulSetClaims(context.Background(), client, c.Log, target.Namespace, target.Name, int(start), int(replicas), sizes)
+}
+
+// expandStatefulSetClaims resizes the PersistentVolumeClaims created from the
+// given volumeClaimTemplates of a StatefulSet. Claims whose StorageClass does
+// not allow volume expansion are skipped with a warning.
+func expandStatefulSetClaims(ctx context.Context, client kubernetes.Interface, log func(string, ...interface{}), namespace, name string, start, replicas int, sizes map[string]resource.Quantity) error {
+	expandable := make(map[string]bool)
+	for template, size := range sizes {
+		for i := start; i < start+replicas; i++ {
+			claimName := fmt.Sprintf("%s-%s-%d", template, name, i)
+			claim, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, claimName, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if class := claim.Spec.StorageClassName; class != nil && *class != "" {
+				allowed, ok := expandable[*class]
+				if !ok {
+					sc, err := client.StorageV1().StorageClasses().Get(ctx, *class, metav1.GetOptions{})
+					if err != nil {
+						return errors.Wrapf(err, "unable to get StorageClass %q of PersistentVolumeClaim %q", *class, claimName)
+					}
+					allowed = sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion
+					expandable[*class] = allowed
+				}
+				if !allowed {
+					log("Warning: PersistentVolumeClaim %q cannot be resized to %s as StorageClass %q does not allow volume expansion", claimName, size.String(), *class)
+					continue
+				}
+			}
+
+			current := claim.Spec.Resources.Requests.Storage()
+			if current != nil && current.Cmp(size) >= 0 {
+				continue
+			}
+			log("Resizing PersistentVolumeClaim %q in namespace %s to %s", claimName, namespace, size.String())
+			patch := fmt.Sprintf(`{"spec":{"resources":{"requests":{"storage":%q}}}}`, size.String())
+			if _, err := client.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, claimName, types.MergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: getManagedFieldsManager()}); err != nil {
+				return errors.Wrapf(err, "unable to resize PersistentVolumeClaim %q", claimName)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newClaimStatefulSet(serviceName, storage string, replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "StatefulSet",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": defaultNamespace},
+		"spec": map[string]interface{}{
+			"replicas":    replicas,
+			"serviceName": serviceName,
+			"volumeClaimTemplates": []interface{}{
+				map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "data"},
+					"spec": map[string]interface{}{
+						"resources": map[string]interface{}{
+							"requests": map[string]interface{}{"storage": storage},
+						},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func TestImmutableStatefulSetChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		target *unstructured.Unstructured
+		want   []string
+	}{
+		{
+			name:   "mutable fields only",
+			target: newClaimStatefulSet("db", "1Gi", 3),
+		},
+		{
+			name:   "service name and volume claim templates",
+			target: newClaimStatefulSet("database", "2Gi", 1),
+			want:   []string{"spec.serviceName", "spec.volumeClaimTemplates"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := immutableStatefulSetChanges(newClaimStatefulSet("db", "1Gi", 1), tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected changes %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	err := immutableStatefulSetError("db", []string{"spec.serviceName"})
+	if !strings.Contains(err.Error(), UpgradeStrategyAnno+": "+UpgradeStrategyRecreateOrphan) {
+		t.Errorf("expected error to suggest the %s strategy, got %q", UpgradeStrategyRecreateOrphan, err)
+	}
+}
+
+func TestExpandedClaimTemplates(t *testing.T) {
+	sizes, err := expandedClaimTemplates(newClaimStatefulSet("db", "1Gi", 1), newClaimStatefulSet("db", "2Gi", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, ok := sizes["data"]; !ok || size.String() != "2Gi" {
+		t.Errorf("expected data to be expanded to 2Gi, got %v", sizes)
+	}
+
+	sizes, err = expandedClaimTemplates(newClaimStatefulSet("db", "2Gi", 1), newClaimStatefulSet("db", "1Gi", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 0 {
+		t.Errorf("expected shrunk templates to be ignored, got %v", sizes)
+	}
+}
+
+func TestExpandStatefulSetClaims(t *testing.T) {
+	claim := func(name, class string) *v1.PersistentVolumeClaim {
+		return &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultNamespace},
+			Spec: v1.PersistentVolumeClaimSpec{
+				StorageClassName: &class,
+				Resources: v1.VolumeResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		}
+	}
+	allowed, denied := true, false
+	client := fake.NewSimpleClientset(
+		claim("data-db-0", "expandable"),
+		claim("data-db-1", "fixed"),
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "expandable"}, AllowVolumeExpansion: &allowed},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fixed"}, AllowVolumeExpansion: &denied},
+	)
+
+	var warnings []string
+	log := func(format string, _ ...interface{}) {
+		if strings.HasPrefix(format, "Warning") {
+			warnings = append(warnings, format)
+		}
+	}
+	sizes := map[string]resource.Quantity{"data": resource.MustParse("2Gi")}
+	// The third replica has no claim yet and is skipped.
+	if err := expandStatefulSetClaims(context.Background(), client, log, defaultNamespace, "db", 0, 3, sizes); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"data-db-0": "2Gi", "data-db-1": "1Gi"} {
+		pvc, err := client.CoreV1().PersistentVolumeClaims(defaultNamespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pvc.Spec.Resources.Requests.Storage().String(); got != want {
+			t.Errorf("expected %s to request %s, got %s", name, want, got)
+		}
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected one warning for the non-expandable claim, got %v", warnings)
+	}
+}
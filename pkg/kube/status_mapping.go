@@ -0,0 +1,224 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// StatusMappingsConfigMapKey is the key of a ConfigMap that holds status
+// mappings.
+const StatusMappingsConfigMapKey = "mappings.yaml"
+
+// statusMappingsConfigMapPrefix marks a status mappings source as a ConfigMap
+// in the form "configmap:<namespace>/<name>".
+const statusMappingsConfigMapPrefix = "configmap:"
+
+// StatusCondition is a condition found in the status.conditions of a resource.
+type StatusCondition struct {
+	// Type is the type of the condition, e.g. "Ready".
+	Type string `json:"type"`
+	// Status is the expected status of the condition. Defaults to "True".
+	Status string `json:"status,omitempty"`
+}
+
+// StatusMapping describes how the readiness of a kind is determined from its
+// status.
+type StatusMapping struct {
+	// Group is the API group of the kind, e.g. "cert-manager.io".
+	Group string `json:"group"`
+	// Kind is the kind the mapping applies to, e.g. "Certificate".
+	Kind string `json:"kind"`
+	// ReadyConditions must all be present for a resource to be ready.
+	ReadyConditions []StatusCondition `json:"readyConditions"`
+	// FailedConditions mark a resource as failed if any of them is present.
+	FailedConditions []StatusCondition `json:"failedConditions,omitempty"`
+	// ObservedGeneration requires status.observedGeneration to be up to date
+	// with metadata.generation before the conditions are considered.
+	ObservedGeneration bool `json:"observedGeneration,omitempty"`
+}
+
+// StatusMappings is a set of readiness mappings for custom resources.
+//
+// Without a mapping, resources of a kind Helm does not know about are
+// considered ready as soon as they exist.
+type StatusMappings struct {
+	Mappings []StatusMapping `json:"mappings"`
+}
+
+// ParseStatusMappings parses status mappings from YAML or JSON.
+func ParseStatusMappings(data []byte) (*StatusMappings, error) {
+	m := &StatusMappings{}
+	if err := yaml.UnmarshalStrict(data, m); err != nil {
+		return nil, errors.Wrap(err, "unable to parse status mappings")
+	}
+	for i, mapping := range m.Mappings {
+		if mapping.Kind == "" {
+			return nil, errors.Errorf("status mapping %d: kind is required", i)
+		}
+		if len(mapping.ReadyConditions) == 0 {
+			return nil, errors.Errorf("status mapping for %s: at least one ready condition is required", mapping.groupKind())
+		}
+	}
+	return m, nil
+}
+
+// LoadStatusMappingsFile loads status mappings from a file.
+func LoadStatusMappingsFile(path string) (*StatusMappings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStatusMappings(data)
+}
+
+// LoadStatusMappings loads status mappings from the given source. The
+// source is either a path to a file or a ConfigMap in the cluster, given as
+// "configmap:<namespace>/<name>". The mappings are read from the
+// StatusMappingsConfigMapKey of the ConfigMap.
+func (c *Client) LoadStatusMappings(source string) (*StatusMappings, error) {
+	if !strings.HasPrefix(source, statusMappingsConfigMapPrefix) {
+		return LoadStatusMappingsFile(source)
+	}
+
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(source, statusMappingsConfigMapPrefix), "/")
+	if !ok {
+		name, namespace = namespace, c.namespace()
+	}
+	client, err := c.getKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get status mappings from ConfigMap %s/%s", namespace, name)
+	}
+	data, ok := cm.Data[StatusMappingsConfigMapKey]
+	if !ok {
+		return nil, errors.Errorf("ConfigMap %s/%s has no %q key", namespace, name, StatusMappingsConfigMapKey)
+	}
+	return ParseStatusMappings([]byte(data))
+}
+
+// statusMappings returns the status mappings of the client, loading them from
+// StatusMappingsSource on first use.
+func (c *Client) statusMappings() (*StatusMappings, error) {
+	c.mappingsMu.Lock()
+	defer c.mappingsMu.Unlock()
+
+	if c.StatusMappings == nil && c.StatusMappingsSource != "" {
+		m, err := c.LoadStatusMappings(c.StatusMappingsSource)
+		if err != nil {
+			return nil, err
+		}
+		c.StatusMappings = m
+	}
+	return c.StatusMappings, nil
+}
+
+func (m *StatusMappings) lookup(gk schema.GroupKind) *StatusMapping {
+	if m == nil {
+		return nil
+	}
+	for i := range m.Mappings {
+		if m.Mappings[i].groupKind() == gk {
+			return &m.Mappings[i]
+		}
+	}
+	return nil
+}
+
+func (m StatusMapping) groupKind() schema.GroupKind {
+	return schema.GroupKind{Group: m.Group, Kind: m.Kind}
+}
+
+// customResourceReady fetches the latest state of v and checks it against the
+// given mapping.
+func (c *ReadyChecker) customResourceReady(v *resource.Info, mapping *StatusMapping) (bool, error) {
+	u, ok := c.watches.get(v)
+	if !ok {
+		if err := v.Get(); err != nil {
+			return false, err
+		}
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(v.Object)
+		if err != nil {
+			return false, errors.Wrapf(err, "unable to convert %s to unstructured", v.ObjectName())
+		}
+		u = &unstructured.Unstructured{Object: obj}
+	}
+
+	if mapping.ObservedGeneration {
+		observed, found, err := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+		if err != nil {
+			return false, err
+		}
+		if !found || observed < u.GetGeneration() {
+			c.log("%s is not ready: %s/%s, observed generation is not up to date", mapping.Kind, v.Namespace, v.Name)
+			return false, nil
+		}
+	}
+
+	conditions, _, err := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+	for _, fc := range mapping.FailedConditions {
+		if cond, ok := findCondition(conditions, fc); ok {
+			message, _ := cond["message"].(string)
+			return false, errors.Errorf("%s %s/%s failed: condition %s is %s: %s", mapping.Kind, v.Namespace, v.Name, fc.Type, fc.status(), message)
+		}
+	}
+	for _, rc := range mapping.ReadyConditions {
+		if _, ok := findCondition(conditions, rc); !ok {
+			c.log("%s is not ready: %s/%s, waiting for condition %s to be %s", mapping.Kind, v.Namespace, v.Name, rc.Type, rc.status())
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (s StatusCondition) status() string {
+	if s.Status == "" {
+		return string(metav1.ConditionTrue)
+	}
+	return s.Status
+}
+
+// findCondition returns the condition matching the type and status of want.
+func findCondition(conditions []interface{}, want StatusCondition) (map[string]interface{}, bool) {
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		status, _ := cond["status"].(string)
+		if cond["type"] == want.Type && strings.EqualFold(status, want.status()) {
+			return cond, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,194 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest/fake"
+)
+
+const testStatusMappings = `
+mappings:
+  - group: cert-manager.io
+    kind: Certificate
+    observedGeneration: true
+    readyConditions:
+      - type: Ready
+    failedConditions:
+      - type: Issuing
+        status: "False"
+`
+
+func TestParseStatusMappings(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		errMsg string
+	}{
+		{
+			name: "valid mappings",
+			data: testStatusMappings,
+		},
+		{
+			name:   "missing kind",
+			data:   "mappings:\n  - group: example.com\n    readyConditions:\n      - type: Ready\n",
+			errMsg: "kind is required",
+		},
+		{
+			name:   "missing ready conditions",
+			data:   "mappings:\n  - group: example.com\n    kind: Widget\n",
+			errMsg: "at least one ready condition is required",
+		},
+		{
+			name:   "unknown field",
+			data:   "mappings:\n  - kind: Widget\n    readyConditions:\n      - type: Ready\n    unknown: true\n",
+			errMsg: "unable to parse status mappings",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseStatusMappings([]byte(tt.data))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.lookup(schema.GroupKind{Group: "cert-manager.io", Kind: "Certificate"}) == nil {
+				t.Error("expected a mapping for cert-manager.io/Certificate")
+			}
+			if m.lookup(schema.GroupKind{Group: "example.com", Kind: "Certificate"}) != nil {
+				t.Error("expected no mapping for example.com/Certificate")
+			}
+		})
+	}
+}
+
+func Test_ReadyChecker_customResourceReady(t *testing.T) {
+	mappings, err := ParseStatusMappings([]byte(testStatusMappings))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		generation int64
+		status     map[string]interface{}
+		want       bool
+		wantErr    bool
+	}{
+		{
+			name:       "ready",
+			generation: 2,
+			status: map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions":         []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+			},
+			want: true,
+		},
+		{
+			name:       "outdated observed generation",
+			generation: 3,
+			status: map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions":         []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+			},
+			want: false,
+		},
+		{
+			name:       "condition not yet true",
+			generation: 1,
+			status: map[string]interface{}{
+				"observedGeneration": int64(1),
+				"conditions":         []interface{}{map[string]interface{}{"type": "Ready", "status": "False"}},
+			},
+			want: false,
+		},
+		{
+			name:       "failed",
+			generation: 1,
+			status: map[string]interface{}{
+				"observedGeneration": int64(1),
+				"conditions":         []interface{}{map[string]interface{}{"type": "Issuing", "status": "False", "message": "rate limited"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion("cert-manager.io/v1")
+			obj.SetKind("Certificate")
+			obj.SetNamespace(defaultNamespace)
+			obj.SetName("example")
+			obj.SetGeneration(tt.generation)
+			obj.Object["status"] = tt.status
+
+			c := NewReadyChecker(nil, nil, WithStatusMappings(mappings))
+			got, err := c.IsReady(context.Background(), newUnstructuredInfo(t, obj))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsReady() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newUnstructuredInfo returns an info for obj whose client serves obj.
+func newUnstructuredInfo(t *testing.T, obj *unstructured.Unstructured) *resource.Info {
+	t.Helper()
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gvk := obj.GroupVersionKind()
+	return &resource.Info{
+		Client: &fake.RESTClient{
+			NegotiatedSerializer: unstructuredSerializer,
+			Client: fake.CreateHTTPClient(func(_ *http.Request) (*http.Response, error) {
+				header := http.Header{}
+				header.Set("Content-Type", runtime.ContentTypeJSON)
+				return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(bytes.NewReader(data))}, nil
+			}),
+		},
+		Mapping: &meta.RESTMapping{
+			Resource:         gvk.GroupVersion().WithResource(strings.ToLower(gvk.Kind) + "s"),
+			GroupVersionKind: gvk,
+			Scope:            meta.RESTScopeNamespace,
+		},
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Object:    obj,
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// UpgradeStrategyAnno is the annotation name for the upgrade strategy of a
+// resource. It overrides the strategy chosen for the whole release (e.g.
+// with --force) for the annotated resource only.
+const UpgradeStrategyAnno = "helm.sh/upgrade-strategy"
+
+const (
+	// UpgradeStrategyPatch updates the resource with a three-way merge patch.
+	UpgradeStrategyPatch = "patch"
+	// UpgradeStrategyReplace replaces the resource with the new object.
+	UpgradeStrategyReplace = "replace"
+	// UpgradeStrategyRecreate deletes the resource and creates it again when
+	// it changed. This allows changing immutable fields, e.g. of Jobs or the
+	// clusterIP of a Service.
+	UpgradeStrategyRecreate = "recreate"
+	// UpgradeStrategyRecreateOrphan deletes the resource while keeping its
+	// dependents, e.g. the pods of a StatefulSet, and creates it again when it
+	// changed. The new resource adopts the orphaned dependents. For
+	// StatefulSets, increased storage requests of volumeClaimTemplates are
+	// applied to the existing PersistentVolumeClaims.
+	UpgradeStrategyRecreateOrphan = "recreate-orphan"
+)
+
+// recreateTimeout is how long to wait for a resource to be gone before it is
+// created again.
+var recreateTimeout = 2 * time.Minute
+
+// upgradeStrategy returns the upgrade strategy of the given resource. Without
+// annotation, the resource is replaced if force is set and patched otherwise.
+func upgradeStrategy(info *resource.Info, force bool) (string, error) {
+	strategy := UpgradeStrategyPatch
+	if force {
+		strategy = UpgradeStrategyReplace
+	}
+
+	annotations, err := metadataAccessor.Annotations(info.Object)
+	if err != nil {
+		return "", err
+	}
+	value, ok := annotations[UpgradeStrategyAnno]
+	if !ok {
+		return strategy, nil
+	}
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case UpgradeStrategyPatch, UpgradeStrategyReplace, UpgradeStrategyRecreate, UpgradeStrategyRecreateOrphan:
+		return value, nil
+	default:
+		return "", errors.Errorf("invalid %s annotation %q on %s %q: must be one of %s, %s, %s or %s",
+			UpgradeStrategyAnno, value, info.Mapping.GroupVersionKind.Kind, info.Name,
+			UpgradeStrategyPatch, UpgradeStrategyReplace, UpgradeStrategyRecreate, UpgradeStrategyRecreateOrphan)
+	}
+}
+
+// recreateResource deletes the live version of target with the given
+// propagation policy, waits for it to be gone and creates target again.
+func (c *Client) recreateResource(target *resource.Info, policy metav1.DeletionPropagation) error {
+	kind := target.Mapping.GroupVersionKind.Kind
+	c.Log("Recreating %s %q in namespace %s", kind, target.Name, target.Namespace)
+
+	if err := deleteResource(target, policy); err != nil {
+		return errors.Wrapf(err, "cannot delete %q with kind %s for recreation", target.Name, kind)
+	}
+
+	// Waiting refreshes the object of the info it waits for, so wait on a copy
+	// to keep the target object intact for the creation.
+	live := *target
+	w := waiter{
+		log:     c.Log,
+		timeout: recreateTimeout,
+	}
+	if err := w.waitForDeletedResources(context.Background(), ResourceList{&live}); err != nil {
+		return errors.Wrapf(err, "timed out waiting for %q with kind %s to be deleted for recreation", target.Name, kind)
+	}
+
+	if err := createResource(target); err != nil {
+		return errors.Wrapf(err, "cannot recreate %q with kind %s", target.Name, kind)
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -45,13 +46,55 @@ type waiter struct {
 }
 
 // waitForResources polls to get the current status of all pods, PVCs, Services and
-// Jobs(optional) until all are ready or a timeout is reached
+// Jobs(optional) until all are ready or a timeout is reached. Resources with
+// a timeout of their own are waited for concurrently, up to that timeout.
 func (w *waiter) waitForResources(created ResourceList) error {
-	w.log("beginning wait for %d resources with timeout of %v", len(created), w.timeout)
+	groups, err := waitGroups(created, w.timeout)
+	if err != nil {
+		return err
+	}
+	skipped := len(created)
+	for _, g := range groups {
+		skipped -= len(g.resources)
+	}
+	if skipped > 0 {
+		w.log("not waiting for %d resources annotated with %s", skipped, NoWaitAnno)
+	}
+
+	errs := make([]error, len(groups))
+	var wg sync.WaitGroup
+	for i, g := range groups {
+		wg.Add(1)
+		go func(i int, g waitGroup) {
+			defer wg.Done()
+			errs[i] = w.waitForResourcesWithin(g.resources, g.timeout)
+		}(i, g)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		switch {
+		case err == nil:
+		case groups[i].timeout != w.timeout:
+			return errors.Wrapf(err, "resources with %s %s", WaitTimeoutAnno, groups[i].timeout)
+		default:
+			return err
+		}
+	}
+	return nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+// waitForResourcesWithin polls the status of the given resources until all
+// are ready or the timeout is reached.
+func (w *waiter) waitForResourcesWithin(created ResourceList, timeout time.Duration) error {
+	w.log("beginning wait for %d resources with timeout of %v", len(created), timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	checker := w.c
+	defer checker.watch(created)()
+
 	numberOfErrors := make([]int, len(created))
 	for i := range numberOfErrors {
 		numberOfErrors[i] = 0
@@ -60,7 +103,7 @@ func (w *waiter) waitForResources(created ResourceList) error {
 	return wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
 		waitRetries := 30
 		for i, v := range created {
-			ready, err := w.c.IsReady(ctx, v)
+			ready, err := checker.IsReady(ctx, v)
 
 			if waitRetries > 0 && w.isRetryableError(err, v) {
 				numberOfErrors[i]++
@@ -100,10 +143,10 @@ func (w *waiter) isRetryableHTTPStatusCode(httpStatusCode int32) bool {
 }
 
 // waitForDeletedResources polls to check if all the resources are deleted or a timeout is reached
-func (w *waiter) waitForDeletedResources(deleted ResourceList) error {
+func (w *waiter) waitForDeletedResources(ctx context.Context, deleted ResourceList) error {
 	w.log("beginning wait for %d resources to be deleted with timeout of %v", len(deleted), w.timeout)
 
-	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
 	defer cancel()
 
 	return wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(_ context.Context) (bool, error) {
@@ -0,0 +1,114 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// NoWaitAnno is the annotation name to leave a resource out when waiting for
+// the resources of a release to be ready, if set to "true".
+const NoWaitAnno = "helm.sh/no-wait"
+
+// WaitTimeoutAnno is the annotation name for how long to wait for a resource
+// to be ready, e.g. "10m". The annotated resource is waited for on its own,
+// so that it neither uses up nor is bound by the timeout of the release.
+const WaitTimeoutAnno = "helm.sh/wait-timeout"
+
+// waitGroup are resources waited for with the same timeout.
+type waitGroup struct {
+	timeout   time.Duration
+	resources ResourceList
+}
+
+// waitGroups partitions the given resources by how long to wait for them,
+// leaving out the resources that opt out of waiting. Resources without
+// timeout annotation are waited for up to the given timeout, in the first
+// group.
+func waitGroups(resources ResourceList, timeout time.Duration) ([]waitGroup, error) {
+	groups := []waitGroup{{timeout: timeout}}
+	index := map[time.Duration]int{}
+	for _, info := range resources {
+		skip, err := noWait(info)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+		t, ok, err := waitTimeout(info)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			groups[0].resources = append(groups[0].resources, info)
+			continue
+		}
+		i, ok := index[t]
+		if !ok {
+			i = len(groups)
+			index[t] = i
+			groups = append(groups, waitGroup{timeout: t})
+		}
+		groups[i].resources = append(groups[i].resources, info)
+	}
+	if len(groups[0].resources) == 0 {
+		groups = groups[1:]
+	}
+	return groups, nil
+}
+
+// noWait returns whether the resource opts out of waiting.
+func noWait(info *resource.Info) (bool, error) {
+	annotations, err := metadataAccessor.Annotations(info.Object)
+	if err != nil {
+		return false, err
+	}
+	value, ok := annotations[NoWaitAnno]
+	if !ok {
+		return false, nil
+	}
+	skip, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, errors.Errorf("invalid %s annotation %q on %s %q: must be true or false",
+			NoWaitAnno, value, info.Mapping.GroupVersionKind.Kind, info.Name)
+	}
+	return skip, nil
+}
+
+// waitTimeout returns the wait timeout annotated on the resource, if any.
+func waitTimeout(info *resource.Info) (time.Duration, bool, error) {
+	annotations, err := metadataAccessor.Annotations(info.Object)
+	if err != nil {
+		return 0, false, err
+	}
+	value, ok := annotations[WaitTimeoutAnno]
+	if !ok {
+		return 0, false, nil
+	}
+	timeout, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || timeout <= 0 {
+		return 0, false, errors.Errorf("invalid %s annotation %q on %s %q: must be a positive duration, e.g. 10m",
+			WaitTimeoutAnno, value, info.Mapping.GroupVersionKind.Kind, info.Name)
+	}
+	return timeout, true, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitGroups(t *testing.T) {
+	list := newPodList("starfish", "otter", "squid", "dolphin", "whale")
+	list.Items[1].Annotations = map[string]string{NoWaitAnno: "true"}
+	list.Items[2].Annotations = map[string]string{WaitTimeoutAnno: "10m"}
+	list.Items[3].Annotations = map[string]string{WaitTimeoutAnno: "10m", NoWaitAnno: "false"}
+	list.Items[4].Annotations = map[string]string{WaitTimeoutAnno: "30s"}
+
+	c := newTestClient(t)
+	resources, err := c.Build(objBody(&list), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := waitGroups(resources, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		timeout time.Duration
+		names   []string
+	}{
+		{5 * time.Minute, []string{"starfish"}},
+		{10 * time.Minute, []string{"squid", "dolphin"}},
+		{30 * time.Second, []string{"whale"}},
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("expected %d groups, got %d", len(expected), len(groups))
+	}
+	for i, e := range expected {
+		if groups[i].timeout != e.timeout {
+			t.Errorf("expected group %d to have timeout %s, got %s", i, e.timeout, groups[i].timeout)
+		}
+		if len(groups[i].resources) != len(e.names) {
+			t.Fatalf("expected group %d to have %d resources, got %d", i, len(e.names), len(groups[i].resources))
+		}
+		for j, name := range e.names {
+			if groups[i].resources[j].Name != name {
+				t.Errorf("expected %s in group %d, got %s", name, i, groups[i].resources[j].Name)
+			}
+		}
+	}
+
+	// Without resources to wait for with the default timeout, there is no
+	// group for it.
+	groups, err = waitGroups(resources[1:3], 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].timeout != 10*time.Minute {
+		t.Errorf("expected a single group with the annotated timeout, got %v", groups)
+	}
+
+	for _, annotations := range []map[string]string{
+		{NoWaitAnno: "maybe"},
+		{WaitTimeoutAnno: "soon"},
+		{WaitTimeoutAnno: "-1m"},
+	} {
+		pod := newPod("invalid")
+		pod.Annotations = annotations
+		invalid, err := c.Build(objBody(&pod), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := waitGroups(invalid, time.Minute); err == nil {
+			t.Errorf("expected an error for annotations %v", annotations)
+		}
+	}
+}
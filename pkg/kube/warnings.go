@@ -0,0 +1,113 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest"
+)
+
+// warningRecorder collects the warnings the API server returns, such as
+// deprecation notices and the warnings of admission webhooks, while Helm
+// operates on resources. By default, client-go only logs them.
+type warningRecorder struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+// perform wraps a function operating on a resource so that the warnings of
+// its requests are recorded.
+func (w *warningRecorder) perform(fn func(*resource.Info) error) func(*resource.Info) error {
+	return func(info *resource.Info) error {
+		defer w.watch(info)()
+		return fn(info)
+	}
+}
+
+// watch records the warnings of the requests for a resource until the
+// returned function is called.
+func (w *warningRecorder) watch(info *resource.Info) func() {
+	client := info.Client
+	if client == nil {
+		return func() {}
+	}
+	info.Client = &warningClient{RESTClient: client, handler: &warningHandler{recorder: w, info: info}}
+	return func() { info.Client = client }
+}
+
+// list returns the recorded warnings, in the order they were received.
+func (w *warningRecorder) list() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.warnings...)
+}
+
+func (w *warningRecorder) record(warning string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, existing := range w.warnings {
+		if existing == warning {
+			return
+		}
+	}
+	w.warnings = append(w.warnings, warning)
+}
+
+// warningHandler records the warnings of the requests for a resource,
+// prefixed with the resource.
+type warningHandler struct {
+	recorder *warningRecorder
+	info     *resource.Info
+}
+
+func (h *warningHandler) HandleWarningHeader(code int, _ string, message string) {
+	// Only 299 warnings are defined by Kubernetes.
+	if code != 299 || message == "" {
+		return
+	}
+	h.recorder.record(fmt.Sprintf("%s %q: %s", h.info.Mapping.GroupVersionKind.Kind, h.info.Name, message))
+}
+
+// warningClient sets a warning handler on all requests of a REST client.
+type warningClient struct {
+	resource.RESTClient
+	handler rest.WarningHandler
+}
+
+func (c *warningClient) Get() *rest.Request {
+	return c.RESTClient.Get().WarningHandler(c.handler)
+}
+
+func (c *warningClient) Post() *rest.Request {
+	return c.RESTClient.Post().WarningHandler(c.handler)
+}
+
+func (c *warningClient) Patch(pt types.PatchType) *rest.Request {
+	return c.RESTClient.Patch(pt).WarningHandler(c.handler)
+}
+
+func (c *warningClient) Delete() *rest.Request {
+	return c.RESTClient.Delete().WarningHandler(c.handler)
+}
+
+func (c *warningClient) Put() *rest.Request {
+	return c.RESTClient.Put().WarningHandler(c.handler)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"net/http"
+	"testing"
+
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestCreateWarnings(t *testing.T) {
+	list := newPodList("starfish", "otter")
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path != "/namespaces/default/pods" || req.Method != "POST" {
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			}
+			resp, err := newResponse(201, &list.Items[0])
+			resp.Header.Add("Warning", `299 - "would violate PodSecurity \"restricted:latest\""`)
+			// Warnings other than 299 are not defined by Kubernetes.
+			resp.Header.Add("Warning", `199 - "miscellaneous"`)
+			return resp, err
+		}),
+	}
+	resources, err := c.Build(objBody(&list), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.Create(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`Pod "otter": would violate PodSecurity "restricted:latest"`,
+		`Pod "starfish": would violate PodSecurity "restricted:latest"`,
+	}
+	if len(result.Warnings) != len(expected) {
+		t.Fatalf("expected warnings %q, got %q", expected, result.Warnings)
+	}
+	// Resources of the same kind are created concurrently.
+	for _, e := range expected {
+		found := false
+		for _, w := range result.Warnings {
+			found = found || w == e
+		}
+		if !found {
+			t.Errorf("expected warning %q, got %q", e, result.Warnings)
+		}
+	}
+	for _, info := range resources {
+		if _, ok := info.Client.(*warningClient); ok {
+			t.Errorf("expected the client of %s to be restored", info.Name)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	cachetools "k8s.io/client-go/tools/cache"
+)
+
+// watchKey identifies the resources that can share a watch: those of the same
+// type in the same namespace.
+type watchKey struct {
+	resource  schema.GroupVersionResource
+	namespace string
+}
+
+// watchSet are resources watched over a single connection.
+type watchSet struct {
+	key       watchKey
+	resources ResourceList
+}
+
+// watchSets groups the given resources by type and namespace, in the order
+// they first appear.
+func watchSets(resources ResourceList) []*watchSet {
+	var sets []*watchSet
+	index := map[watchKey]*watchSet{}
+	for _, info := range resources {
+		if info.Mapping == nil {
+			continue
+		}
+		key := watchKey{resource: info.Mapping.Resource, namespace: info.Namespace}
+		set, ok := index[key]
+		if !ok {
+			set = &watchSet{key: key}
+			index[key] = set
+			sets = append(sets, set)
+		}
+		set.resources = append(set.resources, info)
+	}
+	return sets
+}
+
+// listWatch lists and watches the resources of the set. A single resource is
+// selected by name. Several resources are selected by the labels they have in
+// common, if any, and otherwise all resources of their type in the namespace
+// are watched.
+func (s *watchSet) listWatch() *cachetools.ListWatch {
+	first := s.resources[0]
+	var fieldSelector, labelSelector string
+	if len(s.resources) == 1 {
+		fieldSelector = fields.OneTermEqualSelector("metadata.name", first.Name).String()
+	} else {
+		labelSelector = commonLabels(s.resources).String()
+	}
+	return cachetools.NewFilteredListWatchFromClient(first.Client, s.key.resource.Resource, s.key.namespace, func(options *metav1.ListOptions) {
+		options.FieldSelector = fieldSelector
+		options.LabelSelector = labelSelector
+	})
+}
+
+// names returns the names of the resources of the set.
+func (s *watchSet) names() map[string]bool {
+	names := make(map[string]bool, len(s.resources))
+	for _, info := range s.resources {
+		names[info.Name] = true
+	}
+	return names
+}
+
+func (s *watchSet) String() string {
+	names := make([]string, 0, len(s.resources))
+	for _, info := range s.resources {
+		names = append(names, info.Name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%s %s", s.resources[0].Mapping.GroupVersionKind.Kind, strings.Join(names, ", "))
+}
+
+// commonLabels returns the labels all given resources have.
+func commonLabels(resources ResourceList) labels.Set {
+	var common labels.Set
+	for _, info := range resources {
+		l, err := metadataAccessor.Labels(info.Object)
+		if err != nil {
+			return nil
+		}
+		if common == nil {
+			common = labels.Set{}
+			for k, v := range l {
+				common[k] = v
+			}
+			continue
+		}
+		for k, v := range common {
+			if l[k] != v {
+				delete(common, k)
+			}
+		}
+	}
+	return common
+}
+
+// watchCache holds the live state of resources, watching each type and
+// namespace over a single connection rather than fetching every resource on
+// its own.
+type watchCache struct {
+	watches map[watchKey]cachedWatch
+	stop    chan struct{}
+}
+
+// cachedWatch is the store of a watch and the controller filling it.
+type cachedWatch struct {
+	store      cachetools.Store
+	controller cachetools.Controller
+}
+
+// newWatchCache starts watching the given resources. The watches end when the
+// cache is closed.
+func newWatchCache(resources ResourceList) *watchCache {
+	w := &watchCache{
+		watches: map[watchKey]cachedWatch{},
+		stop:    make(chan struct{}),
+	}
+	for _, set := range watchSets(resources) {
+		store, controller := cachetools.NewInformer(set.listWatch(), &unstructured.Unstructured{}, 0, cachetools.ResourceEventHandlerFuncs{})
+		w.watches[set.key] = cachedWatch{store: store, controller: controller}
+		go controller.Run(w.stop)
+	}
+	return w
+}
+
+// get returns the live state of the resource. It returns false if the
+// resource is not watched, is not synced yet or was not found, in which case
+// it needs to be fetched from the API server.
+func (w *watchCache) get(info *resource.Info) (*unstructured.Unstructured, bool) {
+	if w == nil || info.Mapping == nil {
+		return nil, false
+	}
+	watch, ok := w.watches[watchKey{resource: info.Mapping.Resource, namespace: info.Namespace}]
+	if !ok || !watch.controller.HasSynced() {
+		return nil, false
+	}
+	key := info.Name
+	if info.Namespace != "" {
+		key = info.Namespace + "/" + info.Name
+	}
+	obj, exists, err := watch.store.GetByKey(key)
+	if err != nil || !exists {
+		return nil, false
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	return u, ok
+}
+
+// close stops all watches of the cache.
+func (w *watchCache) close() {
+	close(w.stop)
+}
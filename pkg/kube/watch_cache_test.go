@@ -0,0 +1,160 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func newHookJob(name string, labels map[string]string, complete bool) batchv1.Job {
+	job := batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: v1.NamespaceDefault, Labels: labels},
+	}
+	if complete {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+	}
+	return job
+}
+
+func TestWatchSets(t *testing.T) {
+	c := newTestClient(t)
+	list := newPodList("starfish", "otter")
+	list.Items[0].Labels = map[string]string{"app": "sea", "tier": "front"}
+	list.Items[1].Labels = map[string]string{"app": "sea", "tier": "back"}
+	pods, err := c.Build(objBody(&list), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newPodWithStatus("squid", v1.PodStatus{}, "other")
+	squid, err := c.Build(objBody(&other), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sets := watchSets(append(pods, squid...))
+	if len(sets) != 2 {
+		t.Fatalf("expected 2 watch sets, got %d", len(sets))
+	}
+	if len(sets[0].resources) != 2 || sets[0].key.namespace != "default" {
+		t.Errorf("expected the pods of the default namespace to share a watch, got %s", sets[0])
+	}
+	if got := commonLabels(sets[0].resources).String(); got != "app=sea" {
+		t.Errorf("expected the common labels to be app=sea, got %q", got)
+	}
+	if len(sets[1].resources) != 1 || sets[1].key.namespace != "other" {
+		t.Errorf("expected the pod of another namespace to have its own watch, got %s", sets[1])
+	}
+}
+
+func TestWatchUntilReady(t *testing.T) {
+	labels := map[string]string{"app": "sea"}
+	jobs := &batchv1.JobList{
+		TypeMeta: metav1.TypeMeta{APIVersion: "batch/v1", Kind: "JobList"},
+		Items: []batchv1.Job{
+			newHookJob("starfish", labels, true),
+			newHookJob("otter", labels, true),
+			// Not a hook, its state is ignored.
+			newHookJob("squid", labels, false),
+		},
+	}
+
+	var mu sync.Mutex
+	var lists []string
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if req.Method != "GET" || req.URL.Path != "/namespaces/default/jobs" {
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			}
+			if req.URL.Query().Get("watch") == "true" {
+				// The watch stays open without events.
+				return &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": []string{"application/json"}}, Body: io.NopCloser(blockingReader{})}, nil
+			}
+			mu.Lock()
+			lists = append(lists, req.URL.Query().Get("labelSelector"))
+			mu.Unlock()
+			return newResponse(200, jobs)
+		}),
+	}
+	hooks := jobs.DeepCopy()
+	hooks.Items = hooks.Items[:2]
+	resources, err := c.Build(objBody(hooks), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.WatchUntilReady(resources, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lists) != 1 || lists[0] != "app=sea" {
+		t.Errorf("expected a single list of the jobs with label app=sea, got %q", lists)
+	}
+}
+
+func TestWatchUntilReadyFailedJob(t *testing.T) {
+	job := newHookJob("starfish", nil, false)
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+	jobs := &batchv1.JobList{
+		TypeMeta: metav1.TypeMeta{APIVersion: "batch/v1", Kind: "JobList"},
+		Items:    []batchv1.Job{job},
+	}
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("watch") == "true" {
+				return &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": []string{"application/json"}}, Body: io.NopCloser(blockingReader{})}, nil
+			}
+			if got := req.URL.Query().Get("fieldSelector"); got != "metadata.name=starfish" {
+				t.Errorf("expected a single job to be selected by name, got %q", got)
+			}
+			return newResponse(200, jobs)
+		}),
+	}
+	resources, err := c.Build(objBody(&job), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.WatchUntilReady(resources, 10*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "job starfish failed: BackoffLimitExceeded") {
+		t.Errorf("expected the job to fail, got %v", err)
+	}
+}
+
+// blockingReader is the body of a watch without events.
+type blockingReader struct{}
+
+func (blockingReader) Read(_ []byte) (int, error) {
+	select {}
+}
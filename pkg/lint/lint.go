@@ -19,6 +19,7 @@ package lint // import "helm.sh/helm/v3/pkg/lint"
 import (
 	"path/filepath"
 
+	"helm.sh/helm/v3/pkg/admission"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/lint/rules"
 	"helm.sh/helm/v3/pkg/lint/support"
@@ -31,13 +32,20 @@ func All(basedir string, values map[string]interface{}, namespace string, _ bool
 
 // AllWithKubeVersion runs all the available linters on the given base directory, allowing to specify the kubernetes version.
 func AllWithKubeVersion(basedir string, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) support.Linter {
+	return AllWithAdmissionPolicies(basedir, values, namespace, kubeVersion, nil)
+}
+
+// AllWithAdmissionPolicies runs all the available linters on the given base
+// directory, and validates the rendered templates against the given admission
+// policies.
+func AllWithAdmissionPolicies(basedir string, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion, policies *admission.Policies) support.Linter {
 	// Using abs path to get directory context
 	chartDir, _ := filepath.Abs(basedir)
 
 	linter := support.Linter{ChartDir: chartDir}
 	rules.Chartfile(&linter)
 	rules.ValuesWithOverrides(&linter, values)
-	rules.TemplatesWithKubeVersion(&linter, values, namespace, kubeVersion)
+	rules.TemplatesWithAdmissionPolicies(&linter, values, namespace, kubeVersion, policies)
 	rules.Dependencies(&linter)
 	return linter
 }
@@ -92,6 +92,57 @@ func validateNoDeprecations(resource *K8sYamlStruct, kubeVersion *chartutil.Kube
 	}
 }
 
+// apiLifecycle is implemented by the Kubernetes API types that declare when
+// they were introduced and removed.
+type apiLifecycle interface {
+	APILifecycleIntroduced() (major, minor int)
+	APILifecycleRemoved() (major, minor int)
+}
+
+// validateAPIAvailability checks that the API of a resource is served by the
+// given Kubernetes version. Without a version there is nothing to check, as
+// charts may target older clusters than the one Helm was built against.
+func validateAPIAvailability(resource *K8sYamlStruct, kubeVersion *chartutil.KubeVersion) error {
+	if kubeVersion == nil || resource.APIVersion == "" || resource.Kind == "" {
+		return nil
+	}
+
+	runtimeObject, err := resourceToRuntimeObject(resource)
+	if err != nil {
+		// do not error for non-kubernetes resources
+		if runtime.IsNotRegisteredError(err) {
+			return nil
+		}
+		return err
+	}
+	lifecycle, ok := runtimeObject.(apiLifecycle)
+	if !ok {
+		return nil
+	}
+
+	maj, err := strconv.Atoi(kubeVersion.Major)
+	if err != nil {
+		return err
+	}
+	min, err := strconv.Atoi(kubeVersion.Minor)
+	if err != nil {
+		return err
+	}
+
+	gvk := fmt.Sprintf("%s %s", resource.APIVersion, resource.Kind)
+	if introducedMaj, introducedMin := lifecycle.APILifecycleIntroduced(); introducedMaj > maj || (introducedMaj == maj && introducedMin > min) {
+		return fmt.Errorf("%s is not available in the targeted Kubernetes version, it was introduced in v%d.%d", gvk, introducedMaj, introducedMin)
+	}
+	removedMaj, removedMin := lifecycle.APILifecycleRemoved()
+	if removedMaj == 0 && removedMin == 0 {
+		return nil
+	}
+	if removedMaj < maj || (removedMaj == maj && removedMin <= min) {
+		return fmt.Errorf("%s is not available in the targeted Kubernetes version, it was removed in v%d.%d", gvk, removedMaj, removedMin)
+	}
+	return nil
+}
+
 func resourceToRuntimeObject(resource *K8sYamlStruct) (runtime.Object, error) {
 	scheme := runtime.NewScheme()
 	kscheme.AddToScheme(scheme)
@@ -16,7 +16,11 @@ limitations under the License.
 
 package rules // import "helm.sh/helm/v3/pkg/lint/rules"
 
-import "testing"
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
 
 func TestValidateNoDeprecations(t *testing.T) {
 	deprecated := &K8sYamlStruct{
@@ -39,3 +43,24 @@ func TestValidateNoDeprecations(t *testing.T) {
 		t.Errorf("Expected a v1 Pod to not be deprecated")
 	}
 }
+
+func TestValidateAPIAvailability(t *testing.T) {
+	hpa := &K8sYamlStruct{
+		APIVersion: "autoscaling/v2beta1",
+		Kind:       "HorizontalPodAutoscaler",
+	}
+	if err := validateAPIAvailability(hpa, nil); err != nil {
+		t.Errorf("Expected no error without a Kubernetes version, got %s", err)
+	}
+	if err := validateAPIAvailability(hpa, &chartutil.KubeVersion{Version: "v1.24.0", Major: "1", Minor: "24"}); err != nil {
+		t.Errorf("Expected autoscaling/v2beta1 to be available in v1.24, got %s", err)
+	}
+	if err := validateAPIAvailability(hpa, &chartutil.KubeVersion{Version: "v1.25.0", Major: "1", Minor: "25"}); err == nil {
+		t.Error("Expected autoscaling/v2beta1 to be unavailable in v1.25")
+	}
+
+	hpa.APIVersion = "autoscaling/v2"
+	if err := validateAPIAvailability(hpa, &chartutil.KubeVersion{Version: "v1.31.0", Major: "1", Minor: "31"}); err != nil {
+		t.Errorf("Expected autoscaling/v2 to be available, got %s", err)
+	}
+}
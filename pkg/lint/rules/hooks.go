@@ -0,0 +1,205 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// hookEvents are the values of the hook annotation Helm runs hooks on.
+var hookEvents = map[string]bool{
+	release.HookPreInstall.String():   true,
+	release.HookPostInstall.String():  true,
+	release.HookPreDelete.String():    true,
+	release.HookPostDelete.String():   true,
+	release.HookPreUpgrade.String():   true,
+	release.HookPostUpgrade.String():  true,
+	release.HookPreRollback.String():  true,
+	release.HookPostRollback.String(): true,
+	release.HookTest.String():         true,
+	"test-success":                    true,
+}
+
+var hookDeletePolicies = map[string]bool{
+	release.HookSucceeded.String():          true,
+	release.HookFailed.String():             true,
+	release.HookBeforeHookCreation.String(): true,
+}
+
+// renderedHook is a hook rendered from the template at path.
+type renderedHook struct {
+	path      string
+	kind      string
+	name      string
+	namespace string
+	events    []string
+	weight    int
+}
+
+// annotationValues splits an annotation holding a list of values.
+func annotationValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		values = append(values, strings.ToLower(strings.TrimSpace(v)))
+	}
+	return values
+}
+
+// parseHook returns the hook obj is, or nil if it is none.
+func parseHook(fpath string, obj *K8sYamlStruct) *renderedHook {
+	value, ok := obj.Metadata.Annotations[release.HookAnnotation]
+	if !ok {
+		return nil
+	}
+	// Invalid weights are reported by validateHookAnnotations and count as 0,
+	// as they do when installing.
+	weight, _ := strconv.Atoi(obj.Metadata.Annotations[release.HookWeightAnnotation])
+	return &renderedHook{
+		path:      fpath,
+		kind:      obj.Kind,
+		name:      obj.Metadata.Name,
+		namespace: obj.Metadata.Namespace,
+		events:    annotationValues(value),
+		weight:    weight,
+	}
+}
+
+// validateHookAnnotations checks that the hook annotations of obj have values
+// Helm knows. A hook with an unknown event is never run.
+func validateHookAnnotations(obj *K8sYamlStruct) error {
+	annotations := obj.Metadata.Annotations
+	if value, ok := annotations[release.HookAnnotation]; ok {
+		for _, e := range annotationValues(value) {
+			// crd-install hooks are reported by validateNoCRDHooks.
+			if !hookEvents[e] && e != "crd-install" {
+				return errors.Errorf("hook %s %q has the unknown event %q, so it is never run", obj.Kind, obj.Metadata.Name, e)
+			}
+		}
+	}
+	if value, ok := annotations[release.HookDeleteAnnotation]; ok {
+		for _, p := range annotationValues(value) {
+			if !hookDeletePolicies[p] {
+				return errors.Errorf("hook %s %q has the unknown delete policy %q", obj.Kind, obj.Metadata.Name, p)
+			}
+		}
+	}
+	if value, ok := annotations[release.HookWeightAnnotation]; ok {
+		if _, err := strconv.Atoi(value); err != nil {
+			return errors.Errorf("hook %s %q has the weight %q, which is not an integer and counts as 0", obj.Kind, obj.Metadata.Name, value)
+		}
+	}
+	if value, ok := annotations[release.HookRetriesAnnotation]; ok {
+		if retries, err := strconv.Atoi(value); err != nil || retries < 0 {
+			return errors.Errorf("hook %s %q has the retries %q, which is not a non-negative integer, so it is not retried", obj.Kind, obj.Metadata.Name, value)
+		}
+	}
+	if value, ok := annotations[release.HookRetryBackoffAnnotation]; ok {
+		if backoff, err := time.ParseDuration(value); err != nil || backoff < 0 {
+			return errors.Errorf("hook %s %q has the retry backoff %q, which is not a duration, so the default applies", obj.Kind, obj.Metadata.Name, value)
+		}
+	}
+	if value, ok := annotations[release.HookTimeoutAnnotation]; ok {
+		if timeout, err := time.ParseDuration(value); err != nil || timeout < 0 {
+			return errors.Errorf("hook %s %q has the timeout %q, which is not a duration, so the timeout of the action applies", obj.Kind, obj.Metadata.Name, value)
+		}
+	}
+	return nil
+}
+
+// validateHookLogs checks that the pods of a test hook are not deleted before
+// their logs can be read, e.g. by helm test --logs.
+func validateHookLogs(obj *K8sYamlStruct) error {
+	switch obj.Kind {
+	case "Pod", "Job", "CronJob", "Deployment", "StatefulSet", "DaemonSet":
+	default:
+		return nil
+	}
+	annotations := obj.Metadata.Annotations
+	isTest := false
+	for _, e := range annotationValues(annotations[release.HookAnnotation]) {
+		isTest = isTest || e == release.HookTest.String() || e == "test-success"
+	}
+	if !isTest {
+		return nil
+	}
+	value, ok := annotations[release.HookDeleteAnnotation]
+	if !ok {
+		return nil
+	}
+	for _, p := range annotationValues(value) {
+		if p == release.HookSucceeded.String() || p == release.HookFailed.String() {
+			return errors.Errorf("test %s %q is deleted by the delete policy %q before its logs can be read", obj.Kind, obj.Metadata.Name, p)
+		}
+	}
+	return nil
+}
+
+// validateHooks checks the hooks rendered from all templates together:
+// hooks of an event with the same name and weight run in an order that only
+// depends on their kinds, and hooks in namespaces other than that of the
+// release need the templates to create them.
+func validateHooks(linter *support.Linter, hooks []*renderedHook, namespaces map[string]bool, namespace string) {
+	type group struct {
+		event  string
+		name   string
+		weight int
+	}
+	byGroup := map[group][]*renderedHook{}
+	var groups []group
+	for _, h := range hooks {
+		for _, e := range h.events {
+			g := group{e, h.name, h.weight}
+			if _, ok := byGroup[g]; !ok {
+				groups = append(groups, g)
+			}
+			byGroup[g] = append(byGroup[g], h)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return byGroup[groups[i]][0].path < byGroup[groups[j]][0].path
+	})
+	for _, g := range groups {
+		same := byGroup[g]
+		if len(same) < 2 {
+			continue
+		}
+		var others []string
+		for _, h := range same[1:] {
+			others = append(others, fmt.Sprintf("%s in %s", h.kind, h.path))
+		}
+		linter.RunLinterRule(support.WarningSev, same[0].path, errors.Errorf(
+			"%s hook %s %q has the same name and weight %d as %s, so they run in an order that only depends on their kinds; give them different weights",
+			g.event, same[0].kind, g.name, g.weight, strings.Join(others, ", ")))
+	}
+
+	for _, h := range hooks {
+		if h.namespace == "" || h.namespace == namespace || namespaces[h.namespace] {
+			continue
+		}
+		linter.RunLinterRule(support.WarningSev, h.path, errors.Errorf(
+			"hook %s %q is in the namespace %q, which the templates do not create", h.kind, h.name, h.namespace))
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func hookStruct(kind, name string, annotations map[string]string) *K8sYamlStruct {
+	return &K8sYamlStruct{Kind: kind, Metadata: k8sYamlMetadata{Name: name, Annotations: annotations}}
+}
+
+func TestValidateHookAnnotations(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		errText     string
+	}{
+		{map[string]string{"helm.sh/hook": "pre-install, Post-Upgrade", "helm.sh/hook-weight": "-5", "helm.sh/hook-delete-policy": "before-hook-creation,hook-succeeded"}, ""},
+		{map[string]string{"helm.sh/hook": "test-success"}, ""},
+		{map[string]string{"helm.sh/hook": "crd-install"}, ""},
+		{map[string]string{"helm.sh/hook": "pre-install,post-instal"}, `hook Job "migrate" has the unknown event "post-instal", so it is never run`},
+		{map[string]string{"helm.sh/hook": "pre-install", "helm.sh/hook-delete-policy": "hook-success"}, `hook Job "migrate" has the unknown delete policy "hook-success"`},
+		{map[string]string{"helm.sh/hook": "pre-install", "helm.sh/hook-weight": "first"}, `hook Job "migrate" has the weight "first", which is not an integer and counts as 0`},
+		{map[string]string{"helm.sh/hook": "pre-install", "helm.sh/hook-retries": "3", "helm.sh/hook-retry-backoff": "30s"}, ""},
+		{map[string]string{"helm.sh/hook": "pre-install", "helm.sh/hook-retries": "-1"}, `hook Job "migrate" has the retries "-1", which is not a non-negative integer, so it is not retried`},
+		{map[string]string{"helm.sh/hook": "pre-install", "helm.sh/hook-retries": "3", "helm.sh/hook-retry-backoff": "30"}, `hook Job "migrate" has the retry backoff "30", which is not a duration, so the default applies`},
+		{map[string]string{"helm.sh/hook": "pre-install", "helm.sh/hook-timeout": "30m"}, ""},
+		{map[string]string{"helm.sh/hook": "pre-install", "helm.sh/hook-timeout": "1800"}, `hook Job "migrate" has the timeout "1800", which is not a duration, so the timeout of the action applies`},
+	}
+	for _, tt := range tests {
+		err := validateHookAnnotations(hookStruct("Job", "migrate", tt.annotations))
+		if tt.errText == "" {
+			if err != nil {
+				t.Errorf("expected no error for %v, got %q", tt.annotations, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.errText {
+			t.Errorf("expected error %q for %v, got %v", tt.errText, tt.annotations, err)
+		}
+	}
+}
+
+func TestValidateHookLogs(t *testing.T) {
+	if err := validateHookLogs(hookStruct("Pod", "check", map[string]string{"helm.sh/hook": "test", "helm.sh/hook-delete-policy": "hook-succeeded"})); err == nil {
+		t.Error("expected an error for a test pod deleted when it succeeds")
+	}
+	if err := validateHookLogs(hookStruct("Pod", "check", map[string]string{"helm.sh/hook": "test", "helm.sh/hook-delete-policy": "before-hook-creation"})); err != nil {
+		t.Errorf("expected no error for a test pod deleted before it is created again, got %q", err)
+	}
+	if err := validateHookLogs(hookStruct("Job", "migrate", map[string]string{"helm.sh/hook": "pre-install", "helm.sh/hook-delete-policy": "hook-succeeded"})); err != nil {
+		t.Errorf("expected no error for a hook that is not a test, got %q", err)
+	}
+	if err := validateHookLogs(hookStruct("Deployment", "check", map[string]string{"helm.sh/hook": "test", "helm.sh/hook-delete-policy": "hook-failed"})); err == nil {
+		t.Error("expected an error for a test deployment deleted when it fails")
+	}
+	if err := validateHookLogs(hookStruct("ConfigMap", "check", map[string]string{"helm.sh/hook": "test", "helm.sh/hook-delete-policy": "hook-succeeded"})); err != nil {
+		t.Errorf("expected no error for a test without pods, got %q", err)
+	}
+}
+
+func TestTemplateHooks(t *testing.T) {
+	hook := func(kind, name, namespace, events, weight string) string {
+		return `apiVersion: v1
+kind: ` + kind + `
+metadata:
+  name: ` + name + `
+  namespace: ` + namespace + `
+  annotations:
+    helm.sh/hook: ` + events + `
+    helm.sh/hook-weight: "` + weight + `"
+`
+	}
+	ch := chart.Chart{
+		Metadata: &chart.Metadata{Name: "hooks", APIVersion: "v2", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/migrate.yaml", Data: []byte(hook("Job", "migrate", namespace, "pre-install,pre-upgrade", "1"))},
+			{Name: "templates/migrate-config.yaml", Data: []byte(hook("ConfigMap", "migrate", namespace, "pre-upgrade", "1"))},
+			{Name: "templates/seed.yaml", Data: []byte(hook("Job", "seed", namespace, "pre-install", "1"))},
+			{Name: "templates/tools.yaml", Data: []byte(hook("Job", "tools", "tools", "post-install", "0"))},
+			{Name: "templates/monitoring.yaml", Data: []byte(hook("Job", "monitoring", "monitoring", "post-install", "0"))},
+			{Name: "templates/namespace.yaml", Data: []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: monitoring\n")},
+		},
+	}
+	dir := t.TempDir()
+	if err := chartutil.SaveDir(&ch, dir); err != nil {
+		t.Fatal(err)
+	}
+	linter := &support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+	Templates(linter, nil, namespace, strict)
+
+	var messages []string
+	for _, msg := range linter.Messages {
+		messages = append(messages, msg.Error())
+	}
+	expected := []string{
+		`[WARNING] templates/migrate-config.yaml: pre-upgrade hook ConfigMap "migrate" has the same name and weight 1 as Job in templates/migrate.yaml, so they run in an order that only depends on their kinds; give them different weights`,
+		`[WARNING] templates/tools.yaml: hook Job "tools" is in the namespace "tools", which the templates do not create`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected messages\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apimachinery/pkg/util/yaml"
 
+	"helm.sh/helm/v3/pkg/admission"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/engine"
@@ -51,6 +52,12 @@ func Templates(linter *support.Linter, values map[string]interface{}, namespace
 
 // TemplatesWithKubeVersion lints the templates in the Linter, allowing to specify the kubernetes version.
 func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) {
+	TemplatesWithAdmissionPolicies(linter, values, namespace, kubeVersion, nil)
+}
+
+// TemplatesWithAdmissionPolicies lints the templates in the Linter, and
+// validates the rendered objects against the given admission policies.
+func TemplatesWithAdmissionPolicies(linter *support.Linter, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion, policies *admission.Policies) {
 	fpath := "templates/"
 	templatesPath := filepath.Join(linter.ChartDir, fpath)
 
@@ -82,7 +89,7 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 
 	// lint ignores import-values
 	// See https://github.com/helm/helm/issues/9658
-	if err := chartutil.ProcessDependenciesWithMerge(chart, values); err != nil {
+	if err := chartutil.ProcessDependenciesWithCapabilities(chart, values, caps); err != nil {
 		return
 	}
 
@@ -113,6 +120,8 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 	- Generated content is a valid Yaml file
 	- Metadata.Namespace is not set
 	*/
+	var hooks []*renderedHook
+	namespaces := map[string]bool{}
 	for _, template := range chart.Templates {
 		fileName, data := template.Name, template.Data
 		fpath = fileName
@@ -138,6 +147,9 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 		renderedContent := renderedContentMap[path.Join(chart.Name(), fileName)]
 		if strings.TrimSpace(renderedContent) != "" {
 			linter.RunLinterRule(support.WarningSev, fpath, validateTopIndentLevel(renderedContent))
+			if policies != nil {
+				validateAdmissionPolicies(linter, fpath, renderedContent, namespace, policies)
+			}
 
 			decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(renderedContent), 4096)
 
@@ -161,14 +173,28 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 					// NOTE: set to warnings to allow users to support out-of-date kubernetes
 					// Refs https://github.com/helm/helm/issues/8596
 					linter.RunLinterRule(support.WarningSev, fpath, validateMetadataName(yamlStruct))
-					linter.RunLinterRule(support.WarningSev, fpath, validateNoDeprecations(yamlStruct, kubeVersion))
+					// An API that is not available in the targeted version
+					// fails to install rather than being merely deprecated.
+					if linter.RunLinterRule(support.ErrorSev, fpath, validateAPIAvailability(yamlStruct, kubeVersion)) {
+						linter.RunLinterRule(support.WarningSev, fpath, validateNoDeprecations(yamlStruct, kubeVersion))
+					}
 
 					linter.RunLinterRule(support.ErrorSev, fpath, validateMatchSelector(yamlStruct, renderedContent))
 					linter.RunLinterRule(support.ErrorSev, fpath, validateListAnnotations(yamlStruct, renderedContent))
+
+					if yamlStruct.Kind == "Namespace" {
+						namespaces[yamlStruct.Metadata.Name] = true
+					}
+					if h := parseHook(fpath, yamlStruct); h != nil {
+						linter.RunLinterRule(support.ErrorSev, fpath, validateHookAnnotations(yamlStruct))
+						linter.RunLinterRule(support.WarningSev, fpath, validateHookLogs(yamlStruct))
+						hooks = append(hooks, h)
+					}
 				}
 			}
 		}
 	}
+	validateHooks(linter, hooks, namespaces, namespace)
 }
 
 // validateTopIndentLevel checks that the content does not start with an indent level > 0.
@@ -197,6 +223,23 @@ func validateTopIndentLevel(content string) error {
 	return scanner.Err()
 }
 
+// validateAdmissionPolicies reports the objects the admission policies would
+// deny as errors, and the other failed validations as warnings.
+func validateAdmissionPolicies(linter *support.Linter, fpath, content, namespace string, policies *admission.Policies) {
+	violations, err := policies.ValidateManifest(content, namespace)
+	if err != nil {
+		// Invalid YAML is reported by the other rules.
+		return
+	}
+	for _, v := range violations {
+		severity := support.WarningSev
+		if v.Denied() {
+			severity = support.ErrorSev
+		}
+		linter.RunLinterRule(severity, fpath, errors.New(v.String()))
+	}
+}
+
 // Validation functions
 func validateTemplatesDir(templatesPath string) error {
 	if fi, err := os.Stat(templatesPath); err == nil {
@@ -346,6 +389,7 @@ type K8sYamlStruct struct {
 }
 
 type k8sYamlMetadata struct {
-	Namespace string
-	Name      string
+	Namespace   string
+	Name        string
+	Annotations map[string]string
 }
@@ -23,6 +23,9 @@ import (
 	"strings"
 	"testing"
 
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+
+	"helm.sh/helm/v3/pkg/admission"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/lint/support"
@@ -85,6 +88,51 @@ func TestTemplateIntegrationHappyPath(t *testing.T) {
 	}
 }
 
+func TestTemplateAdmissionPolicies(t *testing.T) {
+	os.Rename(wrongTemplatePath, ignoredTemplatePath)
+	defer os.Rename(ignoredTemplatePath, wrongTemplatePath)
+
+	policy := func(name, expression string) admissionv1.ValidatingAdmissionPolicy {
+		p := admissionv1.ValidatingAdmissionPolicy{}
+		p.Name = name
+		p.Spec.Validations = []admissionv1.Validation{{Expression: expression, Message: name + " failed"}}
+		return p
+	}
+	binding := func(name string, action admissionv1.ValidationAction) admissionv1.ValidatingAdmissionPolicyBinding {
+		b := admissionv1.ValidatingAdmissionPolicyBinding{}
+		b.Name = name
+		b.Spec.PolicyName = name
+		b.Spec.ValidationActions = []admissionv1.ValidationAction{action}
+		return b
+	}
+	policies := &admission.Policies{
+		Policies: []admissionv1.ValidatingAdmissionPolicy{
+			policy("named", "object.metadata.name != ''"),
+			policy("typed", "has(object.spec.type)"),
+			policy("labeled", "'team' in object.metadata.labels"),
+		},
+		Bindings: []admissionv1.ValidatingAdmissionPolicyBinding{
+			binding("named", admissionv1.Deny),
+			binding("typed", admissionv1.Warn),
+			binding("labeled", admissionv1.Deny),
+		},
+	}
+
+	linter := support.Linter{ChartDir: templateTestBasedir}
+	TemplatesWithAdmissionPolicies(&linter, values, namespace, nil, policies)
+	res := linter.Messages
+
+	if len(res) != 2 {
+		t.Fatalf("Expected 2 messages, got %d, %v", len(res), res)
+	}
+	if res[0].Severity != support.WarningSev || !strings.Contains(res[0].Err.Error(), "typed failed") {
+		t.Errorf("Unexpected message: %s", res[0])
+	}
+	if res[1].Severity != support.ErrorSev || !strings.Contains(res[1].Err.Error(), "labeled failed") {
+		t.Errorf("Unexpected message: %s", res[1])
+	}
+}
+
 func TestV3Fail(t *testing.T) {
 	linter := support.Linter{ChartDir: "./testdata/v3-fail"}
 	Templates(&linter, values, namespace, strict)
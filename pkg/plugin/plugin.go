@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -40,6 +41,10 @@ type Downloaders struct {
 	// Command is the executable path with which the plugin performs
 	// the actual download for the corresponding Protocols
 	Command string `json:"command"`
+	// Upload is the executable path with which the plugin uploads a file to
+	// the corresponding Protocols for 'helm repo publish'. It is called with
+	// the path of the file and the URL to upload it to.
+	Upload string `json:"upload,omitempty"`
 }
 
 // PlatformCommand represents a command for a particular operating system and architecture
@@ -93,10 +98,20 @@ type Metadata struct {
 	// Hooks are commands that will run on events.
 	Hooks Hooks
 
+	// Flags are the flags the plugin command accepts.
+	//
+	// Declared flags are listed by `helm <plugin> --help` and offered by shell
+	// completion. They are not parsed by Helm: every argument is still passed
+	// through to the plugin as-is.
+	Flags []Flag `json:"flags,omitempty"`
+
 	// Downloaders field is used if the plugin supply downloader mechanism
 	// for special protocols.
 	Downloaders []Downloaders `json:"downloaders"`
 
+	// Sandbox, if set, runs the plugin in a sandbox. See Sandbox.
+	Sandbox *Sandbox `json:"sandbox,omitempty"`
+
 	// UseTunnelDeprecated indicates that this command needs a tunnel.
 	// Setting this will cause a number of side effects, such as the
 	// automatic setting of HELM_HOST.
@@ -104,6 +119,35 @@ type Metadata struct {
 	UseTunnelDeprecated bool `json:"useTunnel,omitempty"`
 }
 
+// Flag types supported in a plugin's flag declarations.
+const (
+	FlagTypeString      = "string"
+	FlagTypeBool        = "bool"
+	FlagTypeInt         = "int"
+	FlagTypeStringArray = "stringArray"
+)
+
+// Flag declares a flag accepted by a plugin command.
+type Flag struct {
+	// Name is the long name of the flag, without the leading dashes.
+	Name string `json:"name"`
+
+	// Shorthand is an optional one-letter abbreviation of the flag.
+	Shorthand string `json:"shorthand,omitempty"`
+
+	// Usage is the help text shown for the flag.
+	Usage string `json:"usage,omitempty"`
+
+	// Type is one of "string", "bool", "int" or "stringArray". Defaults to "string".
+	Type string `json:"type,omitempty"`
+
+	// Default is the default value shown in help.
+	Default string `json:"default,omitempty"`
+
+	// Completions are the values offered when completing the value of the flag.
+	Completions []string `json:"completions,omitempty"`
+}
+
 // Plugin represents a plugin.
 type Plugin struct {
 	// Metadata is a parsed representation of a plugin.yaml
@@ -173,6 +217,9 @@ func (p *Plugin) PrepareCommand(extraArgs []string) (string, []string, error) {
 // Plugin names can only contain the ASCII characters a-z, A-Z, 0-9, ​_​ and ​-.
 var validPluginName = regexp.MustCompile("^[A-Za-z0-9_-]+$")
 
+// validFlagName is a regular expression that validates declared flag names.
+var validFlagName = regexp.MustCompile("^[A-Za-z0-9][A-Za-z0-9_-]*$")
+
 // validatePluginData validates a plugin's YAML data.
 func validatePluginData(plug *Plugin, filepath string) error {
 	// When metadata section missing, initialize with no data
@@ -184,10 +231,69 @@ func validatePluginData(plug *Plugin, filepath string) error {
 	}
 	plug.Metadata.Usage = sanitizeString(plug.Metadata.Usage)
 
+	if err := validateFlags(plug.Metadata.Flags); err != nil {
+		return fmt.Errorf("invalid flags at %q: %s", filepath, err)
+	}
+	if sb := plug.Metadata.Sandbox; sb != nil {
+		for _, name := range sb.Env {
+			if !validEnvName.MatchString(name) {
+				return fmt.Errorf("invalid sandbox environment variable %q at %q", name, filepath)
+			}
+		}
+	}
+
 	// We could also validate SemVer, executable, and other fields should we so choose.
 	return nil
 }
 
+// validateFlags checks that declared flags have valid, unique names and
+// a supported type with a default of that type.
+func validateFlags(flags []Flag) error {
+	names := map[string]bool{}
+	shorthands := map[string]bool{}
+	for i := range flags {
+		f := &flags[i]
+		if !validFlagName.MatchString(f.Name) {
+			return fmt.Errorf("invalid flag name %q", f.Name)
+		}
+		if names[f.Name] {
+			return fmt.Errorf("flag %q is declared twice", f.Name)
+		}
+		names[f.Name] = true
+		if f.Shorthand != "" {
+			if len(f.Shorthand) != 1 || !validFlagName.MatchString(f.Shorthand) {
+				return fmt.Errorf("invalid shorthand %q for flag %q", f.Shorthand, f.Name)
+			}
+			if shorthands[f.Shorthand] {
+				return fmt.Errorf("shorthand %q is declared twice", f.Shorthand)
+			}
+			shorthands[f.Shorthand] = true
+		}
+		f.Usage = sanitizeString(f.Usage)
+
+		var err error
+		switch f.Type {
+		case "":
+			f.Type = FlagTypeString
+		case FlagTypeString, FlagTypeStringArray:
+		case FlagTypeBool:
+			if f.Default != "" {
+				_, err = strconv.ParseBool(f.Default)
+			}
+		case FlagTypeInt:
+			if f.Default != "" {
+				_, err = strconv.Atoi(f.Default)
+			}
+		default:
+			return fmt.Errorf("flag %q has unknown type %q", f.Name, f.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("flag %q has invalid %s default %q", f.Name, f.Type, f.Default)
+		}
+	}
+	return nil
+}
+
 // sanitizeString normalize spaces and removes non-printable characters.
 func sanitizeString(str string) string {
 	return strings.Map(func(r rune) rune {
@@ -369,6 +369,8 @@ func TestValidatePluginData(t *testing.T) {
 		{false, mockPlugin("foo -bar ")}, // Test trailing chars
 		{false, mockPlugin("foo\nbar")},  // Test newline
 		{false, mockMissingMeta},         // Test if the metadata section missing
+		{true, &Plugin{Metadata: &Metadata{Name: "sandboxed", Sandbox: &Sandbox{Env: []string{"KUBECONFIG", "AWS_*"}}}}},
+		{false, &Plugin{Metadata: &Metadata{Name: "sandboxed", Sandbox: &Sandbox{Env: []string{"AWS_*_KEY"}}}}},
 	} {
 		err := validatePluginData(item.plug, fmt.Sprintf("test-%d", i))
 		if item.pass && err != nil {
@@ -379,6 +381,40 @@ func TestValidatePluginData(t *testing.T) {
 	}
 }
 
+func TestValidatePluginFlags(t *testing.T) {
+	for i, item := range []struct {
+		pass  bool
+		flags []Flag
+	}{
+		{true, []Flag{{Name: "output", Shorthand: "o"}, {Name: "dry-run", Type: FlagTypeBool, Default: "true"}}},
+		{true, []Flag{{Name: "max", Type: FlagTypeInt, Default: "10"}, {Name: "set", Type: FlagTypeStringArray}}},
+		{false, []Flag{{Name: "--output"}}},
+		{false, []Flag{{Name: "output"}, {Name: "output"}}},
+		{false, []Flag{{Name: "output", Shorthand: "out"}}},
+		{false, []Flag{{Name: "output", Shorthand: "o"}, {Name: "offline", Shorthand: "o"}}},
+		{false, []Flag{{Name: "output", Type: "float"}}},
+		{false, []Flag{{Name: "max", Type: FlagTypeInt, Default: "ten"}}},
+	} {
+		plug := mockPlugin("flags")
+		plug.Metadata.Flags = item.flags
+		err := validatePluginData(plug, fmt.Sprintf("test-%d", i))
+		if item.pass && err != nil {
+			t.Errorf("failed to validate case %d: %s", i, err)
+		} else if !item.pass && err == nil {
+			t.Errorf("expected case %d to fail", i)
+		}
+	}
+
+	plug := mockPlugin("flags")
+	plug.Metadata.Flags = []Flag{{Name: "output"}}
+	if err := validatePluginData(plug, "defaults"); err != nil {
+		t.Fatal(err)
+	}
+	if plug.Metadata.Flags[0].Type != FlagTypeString {
+		t.Errorf("expected the flag type to default to %q, got %q", FlagTypeString, plug.Metadata.Flags[0].Type)
+	}
+}
+
 func TestDetectDuplicates(t *testing.T) {
 	plugs := []*Plugin{
 		mockPlugin("foo"),
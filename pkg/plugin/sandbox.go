@@ -0,0 +1,199 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Sandbox restricts what a plugin is given when it runs.
+//
+// A sandboxed plugin only gets the variables of the environment of Helm that
+// are listed in SandboxEnv, or in RestrictedSandboxEnv if it is restricted,
+// and those it asks for in Env. In particular, credentials such as
+// HELM_KUBETOKEN or KUBECONFIG are not passed unless asked for. HOME and
+// TMPDIR point to a scratch directory that is removed once the plugin exited.
+//
+// Unless it is restricted, a sandboxed plugin can still read and write
+// whatever the user running Helm can, including kubeconfig files and the
+// credentials of registries and repositories at their usual locations.
+type Sandbox struct {
+	// Env lists the further variables of the environment passed to the
+	// plugin. A name ending in "*" passes all variables with that prefix.
+	Env []string `json:"env,omitempty"`
+
+	// Restricted runs the plugin without network access and without access
+	// to the files of the user, for plugins that only transform their input.
+	// It is only supported on Linux, where the plugin runs in new user, mount
+	// and network namespaces. The plugin only sees the system directories
+	// such as /usr and /etc, its own directory and the Helm binary, all
+	// read-only, and its scratch directory, which is its working directory.
+	// The hooks of the plugin are not restricted, so that they can still
+	// install it.
+	Restricted bool `json:"restricted,omitempty"`
+}
+
+// RestrictedSandboxEnv are the variables of the environment always passed to
+// restricted plugins.
+var RestrictedSandboxEnv = []string{
+	"PATH",
+	"SYSTEMROOT",
+	"HELM_BIN",
+	"HELM_DEBUG",
+	"HELM_NAMESPACE",
+	"HELM_PLUGIN_NAME",
+	"HELM_PLUGIN_DIR",
+}
+
+// SandboxEnv are the variables of the environment always passed to sandboxed
+// plugins that are not restricted: those passed to restricted plugins, the
+// locations of the files of Helm and the credentials of downloader plugins.
+var SandboxEnv = append(append([]string{}, RestrictedSandboxEnv...),
+	"HELM_KUBECONTEXT",
+	"HELM_PLUGINS",
+	"HELM_CACHE_HOME",
+	"HELM_CONFIG_HOME",
+	"HELM_DATA_HOME",
+	"HELM_REGISTRY_CONFIG",
+	"HELM_REPOSITORY_CACHE",
+	"HELM_REPOSITORY_CONFIG",
+	"HELM_PLUGIN_USERNAME",
+	"HELM_PLUGIN_PASSWORD",
+	"HELM_PLUGIN_PASS_CREDENTIALS_ALL",
+)
+
+// validEnvName matches the names of variables a sandbox may pass, with an
+// optional trailing "*".
+var validEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\*?$`)
+
+// Sandboxed returns whether the plugin runs in a sandbox: if it declares one,
+// or if force is set to sandbox all plugins.
+func (p *Plugin) Sandboxed(force bool) bool {
+	return force || p.Metadata.Sandbox != nil
+}
+
+// Environ returns the variables of env passed to the plugin. All of them are
+// passed if it does not run in a sandbox.
+func (p *Plugin) Environ(env []string, force bool) []string {
+	if !p.Sandboxed(force) {
+		return env
+	}
+	var sb Sandbox
+	if p.Metadata.Sandbox != nil {
+		sb = *p.Metadata.Sandbox
+	}
+	allowed := SandboxEnv
+	if sb.Restricted {
+		allowed = RestrictedSandboxEnv
+	}
+	return FilterEnv(env, append(append([]string{}, allowed...), sb.Env...))
+}
+
+// FilterEnv returns the variables of env whose names are allowed. A name
+// ending in "*" allows all variables with that prefix.
+func FilterEnv(env, allowed []string) []string {
+	var result []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if envAllowed(name, allowed) {
+			result = append(result, kv)
+		}
+	}
+	return result
+}
+
+// lookupEnv returns the value of a variable of env.
+func lookupEnv(env []string, name string) (string, bool) {
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == name {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func envAllowed(name string, allowed []string) bool {
+	for _, a := range allowed {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok && strings.HasPrefix(name, prefix) {
+			return true
+		}
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SandboxCommand prepares a command running the plugin to run in its sandbox,
+// if it runs in one. The environment of the command, or that of Helm if it
+// has none, is filtered by Environ. The returned function removes the scratch
+// directory of the plugin and must be called once the command exited.
+func (p *Plugin) SandboxCommand(cmd *exec.Cmd, force bool) (cleanup func(), err error) {
+	return p.sandbox(cmd, force, true)
+}
+
+// SandboxHook prepares a command running a hook of the plugin like
+// SandboxCommand, except that hooks are never restricted.
+func (p *Plugin) SandboxHook(cmd *exec.Cmd, force bool) (cleanup func(), err error) {
+	return p.sandbox(cmd, force, false)
+}
+
+func (p *Plugin) sandbox(cmd *exec.Cmd, force, restricted bool) (func(), error) {
+	if !p.Sandboxed(force) {
+		return func() {}, nil
+	}
+	restricted = restricted && p.Metadata.Sandbox != nil && p.Metadata.Sandbox.Restricted
+
+	dir, err := os.MkdirTemp("", "helm-plugin-"+p.Metadata.Name+"-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating the scratch directory of plugin %s", p.Metadata.Name)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(p.Environ(env, force), "HOME="+dir, "TMPDIR="+dir, "TMP="+dir, "TEMP="+dir)
+	if restricted {
+		root, err := os.MkdirTemp("", "helm-plugin-root-")
+		if err != nil {
+			cleanup()
+			return nil, errors.Wrapf(err, "creating the root directory of plugin %s", p.Metadata.Name)
+		}
+		cleanup = func() {
+			os.RemoveAll(dir)
+			os.Remove(root)
+		}
+		paths := []string{p.Dir}
+		if bin, ok := lookupEnv(env, "HELM_BIN"); ok && filepath.IsAbs(bin) {
+			paths = append(paths, bin)
+		}
+		cmd.Dir = dir
+		if err := restrict(cmd, root, dir, paths); err != nil {
+			cleanup()
+			return nil, errors.Wrapf(err, "unable to run plugin %s", p.Metadata.Name)
+		}
+	}
+	return cleanup, nil
+}
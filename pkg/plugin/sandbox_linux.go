@@ -0,0 +1,215 @@
+//go:build linux
+
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// sandboxInit is the name a restricted plugin is started under: Helm runs
+// itself under that name in the namespaces of the plugin to set up its file
+// system, and then runs the plugin.
+const sandboxInit = "helm-plugin-sandbox"
+
+// systemPaths are the directories restricted plugins can read, so that they
+// find their interpreters and libraries.
+var systemPaths = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/libx32", "/etc", "/nix/store"}
+
+// devices are the devices restricted plugins can use.
+var devices = []string{"/dev/null", "/dev/zero", "/dev/full", "/dev/random", "/dev/urandom"}
+
+func init() {
+	if len(os.Args) > 0 && os.Args[0] == sandboxInit {
+		if err := runSandboxInit(os.Args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: unable to set up the sandbox of the plugin: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// restrict makes a command run in new user, mount and network namespaces, in
+// which the user is mapped to itself and there is no network but loopback.
+// The command only sees the system directories and the given paths, read-only,
+// and its scratch directory, on a file system built in root.
+func restrict(cmd *exec.Cmd, root, scratch string, paths []string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	path := cmd.Path
+	if !filepath.IsAbs(path) {
+		if path, err = filepath.Abs(path); err != nil {
+			return err
+		}
+	}
+
+	// The plugin itself is exposed along with the paths.
+	args := []string{sandboxInit, root, scratch, strconv.Itoa(len(paths) + 1), path}
+	args = append(args, paths...)
+	args = append(args, path)
+	cmd.Args = append(args, cmd.Args...)
+	cmd.Path = self
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	// Setting up the file system takes CAP_SYS_ADMIN in the namespaces,
+	// which the init step drops before it runs the plugin.
+	cmd.SysProcAttr.AmbientCaps = []uintptr{capSysAdmin}
+	return nil
+}
+
+// The capability and prctl options used to restrict plugins, which the
+// syscall package does not define.
+const (
+	capSysAdmin          = 21
+	prSetNoNewPrivs      = 38
+	prCapAmbient         = 47
+	prCapAmbientClearAll = 4
+)
+
+// lockedMountFlags are the flags of mounts that remounting them in a user
+// namespace must keep.
+const lockedMountFlags = syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_NOATIME | syscall.MS_NODIRATIME | syscall.MS_RELATIME
+
+// runSandboxInit sets up the file system of a restricted plugin and runs it.
+// Its arguments are the root and scratch directories, the number of paths to
+// expose read-only followed by the paths, and the path of the plugin followed
+// by its arguments, starting with its name.
+func runSandboxInit(args []string) error {
+	runtime.LockOSThread()
+	if len(args) < 3 {
+		return fmt.Errorf("missing arguments")
+	}
+	root, scratch := args[0], args[1]
+	n, err := strconv.Atoi(args[2])
+	if err != nil || len(args) < 5+n {
+		return fmt.Errorf("invalid arguments")
+	}
+	paths, command := args[3:3+n], args[3+n:]
+
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("making the mounts private: %w", err)
+	}
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("mounting the root: %w", err)
+	}
+	for _, p := range systemPaths {
+		if err := bindMount(root, p, true, true); err != nil {
+			return err
+		}
+	}
+	for _, p := range devices {
+		if err := bindMount(root, p, false, true); err != nil {
+			return err
+		}
+	}
+	for _, p := range paths {
+		if err := bindMount(root, p, true, false); err != nil {
+			return err
+		}
+	}
+	if err := bindMount(root, scratch, false, false); err != nil {
+		return err
+	}
+
+	old := filepath.Join(root, ".old")
+	if err := os.Mkdir(old, 0700); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(root, old); err != nil {
+		return fmt.Errorf("changing the root: %w", err)
+	}
+	if err := syscall.Chdir("/"); err != nil {
+		return err
+	}
+	if err := syscall.Unmount("/.old", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detaching the file system of Helm: %w", err)
+	}
+	if err := os.Remove("/.old"); err != nil {
+		return err
+	}
+	if err := syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("making the root read-only: %w", err)
+	}
+	if err := syscall.Chdir(scratch); err != nil {
+		return err
+	}
+
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prCapAmbient, prCapAmbientClearAll, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("dropping the capabilities: %w", errno)
+	}
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("dropping the privileges: %w", errno)
+	}
+	return syscall.Exec(command[0], command[1:], os.Environ())
+}
+
+// bindMount makes path available at the same location under root, read-only
+// if asked to. Optional paths are skipped if they do not exist.
+func bindMount(root, path string, readOnly, optional bool) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if optional && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	// Paths below those already exposed, which may be read-only, have their
+	// mount points already.
+	target := filepath.Join(root, path)
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		if fi.IsDir() {
+			err = os.MkdirAll(target, 0755)
+		} else if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+			var f *os.File
+			if f, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+				err = f.Close()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := syscall.Mount(path, target, "", syscall.MS_BIND, ""); err != nil {
+		return fmt.Errorf("exposing %s: %w", path, err)
+	}
+	if !readOnly {
+		return nil
+	}
+	// The flags locking the mount in the namespace must be kept.
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(target, &st); err != nil {
+		return err
+	}
+	flags := syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | uintptr(st.Flags)&lockedMountFlags
+	if err := syscall.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("making %s read-only: %w", path, err)
+	}
+	return nil
+}
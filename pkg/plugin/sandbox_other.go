@@ -0,0 +1,31 @@
+//go:build !linux
+
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// restrict fails, as the network and files can only be denied to commands on
+// Linux.
+func restrict(_ *exec.Cmd, _, _ string, _ []string) error {
+	return errors.New("restricted plugins can only be run on Linux")
+}
@@ -0,0 +1,141 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEnviron(t *testing.T) {
+	env := []string{"PATH=/bin", "HOME=/home/user", "KUBECONFIG=/home/user/.kube/config", "HELM_KUBETOKEN=secret",
+		"HELM_DEBUG=true", "HELM_CONFIG_HOME=/home/user/.config/helm", "AWS_REGION=eu-west-1", "AWS_PROFILE=prod"}
+	plug := func(sb *Sandbox) *Plugin {
+		return &Plugin{Metadata: &Metadata{Name: "sandboxed", Sandbox: sb}}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		plug     *Plugin
+		force    bool
+		expected []string
+	}{
+		{"without a sandbox", plug(nil), false, env},
+		{"forced into a sandbox", plug(nil), true, []string{"PATH=/bin", "HELM_DEBUG=true", "HELM_CONFIG_HOME=/home/user/.config/helm"}},
+		{"with a sandbox", plug(&Sandbox{Env: []string{"KUBECONFIG", "AWS_*"}}), false,
+			[]string{"PATH=/bin", "KUBECONFIG=/home/user/.kube/config", "HELM_DEBUG=true", "HELM_CONFIG_HOME=/home/user/.config/helm", "AWS_REGION=eu-west-1", "AWS_PROFILE=prod"}},
+		{"restricted", plug(&Sandbox{Env: []string{"AWS_REGION"}, Restricted: true}), false,
+			[]string{"PATH=/bin", "HELM_DEBUG=true", "AWS_REGION=eu-west-1"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.plug.Environ(env, tt.force); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSandboxCommand(t *testing.T) {
+	plug := &Plugin{Metadata: &Metadata{Name: "sandboxed", Sandbox: &Sandbox{}}}
+	cmd := exec.Command("true")
+	cmd.Env = []string{"PATH=/bin", "HOME=/home/user"}
+	cleanup, err := plug.SandboxCommand(cmd, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmd.Env) != 5 || cmd.Env[0] != "PATH=/bin" {
+		t.Fatalf("expected PATH and the scratch directory in the environment, got %v", cmd.Env)
+	}
+	home := cmd.Env[1][len("HOME="):]
+	if _, err := os.Stat(home); err != nil {
+		t.Fatalf("expected the scratch directory to exist: %s", err)
+	}
+	if cmd.Dir != "" || cmd.SysProcAttr != nil {
+		t.Error("expected a plugin that is not restricted to run as usual")
+	}
+	cleanup()
+	if _, err := os.Stat(home); !os.IsNotExist(err) {
+		t.Error("expected the scratch directory to be removed")
+	}
+
+	// Hooks are not restricted.
+	plug.Metadata.Sandbox.Restricted = true
+	cmd = exec.Command("true")
+	cleanup, err = plug.SandboxHook(cmd, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if cmd.Dir != "" || cmd.SysProcAttr != nil {
+		t.Error("expected the hook of a restricted plugin not to be restricted")
+	}
+
+	cmd = exec.Command("true")
+	cleanup, err = plug.SandboxCommand(cmd, false)
+	if runtime.GOOS != "linux" {
+		if err == nil {
+			cleanup()
+			t.Error("expected restricted plugins to be refused")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if cmd.Dir == "" || cmd.SysProcAttr == nil {
+		t.Error("expected a restricted plugin to run in its scratch directory and own namespaces")
+	}
+}
+
+func TestRestrictedPluginFiles(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("restricted plugins only run on Linux")
+	}
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	pluginDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(pluginDir, "input"), []byte("input"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	plug := &Plugin{Dir: pluginDir, Metadata: &Metadata{Name: "restricted", Sandbox: &Sandbox{Restricted: true}}}
+
+	cmd := exec.Command("sh", "-c", `cat "$0" && echo out > "$HOME/output" && cat "$HOME/output" && ! cat "$1" 2>/dev/null && ! touch "$0.new" 2>/dev/null`,
+		filepath.Join(pluginDir, "input"), secret)
+	cleanup, err := plug.SandboxCommand(cmd, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	out, err := cmd.CombinedOutput()
+	if err != nil && strings.Contains(string(out), "unable to set up the sandbox") {
+		t.Skipf("namespaces are not available: %s", out)
+	}
+	if err != nil {
+		t.Fatalf("expected the plugin to only read its own files and write its scratch directory: %s: %s", err, out)
+	}
+	if string(out) != "inputout\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
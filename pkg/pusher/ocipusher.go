@@ -92,6 +92,12 @@ func (pusher *OCIPusher) push(chartRef, href string) error {
 
 	chartCreationTime := ctime.Created(stat)
 	pushOpts = append(pushOpts, registry.PushOptCreationTime(chartCreationTime.Format(time.RFC3339)))
+	if len(pusher.opts.annotations) > 0 {
+		pushOpts = append(pushOpts, registry.PushOptAnnotations(pusher.opts.annotations))
+	}
+	if len(pusher.opts.referrers) > 0 {
+		pushOpts = append(pushOpts, registry.PushOptReferrers(pusher.opts.referrers...))
+	}
 
 	_, err = client.Push(chartBytes, ref, pushOpts...)
 	return err
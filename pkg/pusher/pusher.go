@@ -33,6 +33,8 @@ type options struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	annotations           map[string]string
+	referrers             []registry.Referrer
 }
 
 // Option allows specifying various settings configurable by the user for overriding the defaults
@@ -68,6 +70,22 @@ func WithPlainHTTP(plainHTTP bool) Option {
 	}
 }
 
+// WithAnnotations sets annotations on the pushed chart, e.g. its source
+// revision or the URL of the build that produced it.
+func WithAnnotations(annotations map[string]string) Option {
+	return func(opts *options) {
+		opts.annotations = annotations
+	}
+}
+
+// WithReferrers attaches artifacts to the pushed chart, such as an SBOM or
+// test results.
+func WithReferrers(referrers ...registry.Referrer) Option {
+	return func(opts *options) {
+		opts.referrers = referrers
+	}
+}
+
 // Pusher is an interface to support upload to the specified URL.
 type Pusher interface {
 	// Push file content by url string
@@ -0,0 +1,367 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package redact masks secrets in what Helm prints and records.
+
+A Redactor masks the data of Kubernetes Secrets in manifests and diffs, and
+the values of keys matching its patterns, e.g. "password", in chart values and
+in free text such as log messages. A nil Redactor masks nothing, which is how
+secrets are shown on request.
+*/
+package redact // import "helm.sh/helm/v3/pkg/redact"
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/diff"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Mask replaces the secrets that are redacted.
+const Mask = "[REDACTED]"
+
+// DefaultPatterns match the keys whose values are secrets, unless other
+// patterns are given.
+var DefaultPatterns = []string{
+	`(?i)passw(or)?d`,
+	`(?i)secret`,
+	`(?i)token`,
+	`(?i)(api|access|private)[-_]?key`,
+	`(?i)credential`,
+}
+
+// Redactor masks secrets.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// New returns a Redactor masking the values of the keys matching one of the
+// regular expressions, or one of DefaultPatterns if there are none.
+func New(patterns ...string) (*Redactor, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+	r := &Redactor{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid redaction pattern %q", p)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// secretKey reports whether the value of the key is a secret.
+func (r *Redactor) secretKey(key string) bool {
+	for _, re := range r.patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns a copy of the values with the values of secret keys masked,
+// at any depth. Empty values are left as they are as they reveal nothing.
+func (r *Redactor) Values(vals map[string]interface{}) map[string]interface{} {
+	if r == nil || vals == nil {
+		return vals
+	}
+	out := make(map[string]interface{}, len(vals))
+	for k, v := range vals {
+		if r.secretKey(k) && !empty(v) {
+			out[k] = Mask
+			continue
+		}
+		out[k] = r.value(v)
+	}
+	return out
+}
+
+// Paths returns a copy of the values with the values at the paths, as
+// dot-separated keys, masked. Unlike Values, it masks the values a chart marks
+// as sensitive regardless of patterns, see chartutil.SensitiveValues.
+func Paths(vals map[string]interface{}, paths []string) map[string]interface{} {
+	if len(paths) == 0 || vals == nil {
+		return vals
+	}
+	out := copyMap(vals)
+	for _, p := range paths {
+		maskPath(out, strings.Split(p, "."))
+	}
+	return out
+}
+
+// maskPath masks the value at the path in vals, copying the maps on the way
+// as they may be shared with the original values.
+func maskPath(vals map[string]interface{}, path []string) {
+	v, ok := vals[path[0]]
+	if !ok || empty(v) {
+		return
+	}
+	if len(path) == 1 {
+		vals[path[0]] = Mask
+		return
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		m = copyMap(m)
+		vals[path[0]] = m
+		maskPath(m, path[1:])
+	}
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func (r *Redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return r.Values(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = r.value(v[i])
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func empty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// textSecret matches "key: value", "key=value" and "key": "value" in text.
+var textSecret = regexp.MustCompile(`([A-Za-z0-9_.-]+)("?[ \t]*[:=][ \t]*)("?)([^\s",}]+)`)
+
+// Text masks the values of secret keys written as "key: value", "key=value"
+// or "key": "value" in text, e.g. a log message.
+func (r *Redactor) Text(s string) string {
+	if r == nil {
+		return s
+	}
+	return textSecret.ReplaceAllStringFunc(s, func(m string) string {
+		parts := textSecret.FindStringSubmatch(m)
+		if !r.secretKey(parts[1]) {
+			return m
+		}
+		return parts[1] + parts[2] + parts[3] + Mask
+	})
+}
+
+// documentSeparator matches the lines separating the documents of manifests.
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// Manifest masks the data of the Secrets in a manifest of YAML documents.
+// Other documents, and the comments preceding Secrets such as their source
+// template, are kept as they are.
+func (r *Redactor) Manifest(manifest string) string {
+	if r == nil {
+		return manifest
+	}
+	var b strings.Builder
+	prev := 0
+	for _, loc := range documentSeparator.FindAllStringIndex(manifest, -1) {
+		b.WriteString(redactDocument(manifest[prev:loc[0]]))
+		b.WriteString(manifest[loc[0]:loc[1]])
+		prev = loc[1]
+	}
+	b.WriteString(redactDocument(manifest[prev:]))
+	return b.String()
+}
+
+// redactDocument masks the data of the document if it is a Secret.
+func redactDocument(doc string) string {
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal([]byte(doc), &obj); err != nil || !isSecret(obj) {
+		return doc
+	}
+	maskData(obj)
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return doc
+	}
+
+	// Keep the leading comments and the trailing new lines of the document.
+	var head strings.Builder
+	for _, l := range strings.SplitAfter(doc, "\n") {
+		if t := strings.TrimSpace(l); t != "" && !strings.HasPrefix(t, "#") {
+			break
+		}
+		head.WriteString(l)
+	}
+	body := strings.TrimRight(doc, "\n")
+	return head.String() + strings.TrimSuffix(string(out), "\n") + doc[len(body):]
+}
+
+func isSecret(obj map[string]interface{}) bool {
+	return obj["apiVersion"] == "v1" && obj["kind"] == "Secret"
+}
+
+// maskData masks the values of the data and stringData of a Secret.
+func maskData(obj map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		if data, ok := obj[field].(map[string]interface{}); ok {
+			obj[field] = maskAll(data)
+		}
+	}
+}
+
+func maskAll(data map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for k := range data {
+		out[k] = Mask
+	}
+	return out
+}
+
+// Changes returns a copy of the changes with the data of Secrets masked in
+// the resources and the patches.
+func (r *Redactor) Changes(changes []diff.Change) []diff.Change {
+	if r == nil {
+		return changes
+	}
+	out := make([]diff.Change, len(changes))
+	for i, c := range changes {
+		if c.Key.Kind == "Secret" {
+			c.Original = redactObject(c.Original)
+			c.Target = redactObject(c.Target)
+			c.Patch = redactPatch(c.Patch)
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func redactObject(obj map[string]interface{}) map[string]interface{} {
+	if !isSecret(obj) {
+		return obj
+	}
+	out := copyMap(obj)
+	maskData(out)
+	return out
+}
+
+func redactPatch(patch diff.Patch) diff.Patch {
+	if patch == nil {
+		return nil
+	}
+	out := make(diff.Patch, len(patch))
+	for i, op := range patch {
+		switch {
+		case op.Path == "/data" || op.Path == "/stringData":
+			if data, ok := op.Value.(map[string]interface{}); ok {
+				op.Value = maskAll(data)
+			} else if op.Value != nil {
+				op.Value = Mask
+			}
+		case strings.HasPrefix(op.Path, "/data/") || strings.HasPrefix(op.Path, "/stringData/"):
+			if op.Value != nil {
+				op.Value = Mask
+			}
+		}
+		out[i] = op
+	}
+	return out
+}
+
+// Release returns a copy of the release with the data of Secrets masked in
+// its manifest and hooks, and the secret keys masked in its values and in the
+// default values of its chart and subcharts, along with the values the chart
+// marks as sensitive, see Sensitive. The release is left untouched.
+func (r *Redactor) Release(rel *release.Release) *release.Release {
+	if r == nil || rel == nil {
+		return rel
+	}
+	out := Sensitive(rel)
+	out.Manifest = r.Manifest(out.Manifest)
+	out.Config = r.Values(out.Config)
+	if out.Hooks != nil {
+		hooks := make([]*release.Hook, len(out.Hooks))
+		for i, h := range out.Hooks {
+			hook := *h
+			hook.Manifest = r.Manifest(h.Manifest)
+			hooks[i] = &hook
+		}
+		out.Hooks = hooks
+	}
+	if out.Chart != nil {
+		out.Chart = mapValues(out.Chart, func(c *chart.Chart) map[string]interface{} {
+			return r.Values(c.Values)
+		})
+	}
+	return out
+}
+
+// Sensitive returns a copy of the release with the values its chart marks as
+// sensitive masked, in its values and in the default values of its chart and
+// subcharts, see chartutil.SensitiveValues. The release is left untouched.
+func Sensitive(rel *release.Release) *release.Release {
+	if rel == nil {
+		return nil
+	}
+	out := *rel
+	if paths, err := chartutil.SensitiveValues(rel.Chart); err == nil {
+		out.Config = Paths(rel.Config, paths)
+	}
+	if rel.Chart != nil {
+		out.Chart = mapValues(rel.Chart, func(c *chart.Chart) map[string]interface{} {
+			paths, err := chartutil.SensitiveValues(c)
+			if err != nil {
+				return c.Values
+			}
+			return Paths(c.Values, paths)
+		})
+	}
+	return &out
+}
+
+// mapValues returns a copy of the chart and its subcharts with their values
+// replaced by those f returns.
+func mapValues(c *chart.Chart, f func(*chart.Chart) map[string]interface{}) *chart.Chart {
+	out := *c
+	out.Values = f(c)
+	deps := make([]*chart.Chart, 0, len(c.Dependencies()))
+	for _, d := range c.Dependencies() {
+		deps = append(deps, mapValues(d, f))
+	}
+	out.SetDependencies(deps...)
+	return &out
+}
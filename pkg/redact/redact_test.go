@@ -0,0 +1,223 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/diff"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New("(")
+	assert.ErrorContains(t, err, `invalid redaction pattern "("`)
+
+	r, err := New("^pin$")
+	require.NoError(t, err)
+	assert.True(t, r.secretKey("pin"))
+	assert.False(t, r.secretKey("password"), "custom patterns replace the defaults")
+}
+
+func TestValues(t *testing.T) {
+	r, err := New()
+	require.NoError(t, err)
+
+	vals := map[string]interface{}{
+		"image": "nginx",
+		"db": map[string]interface{}{
+			"user":     "admin",
+			"password": "hunter2",
+		},
+		"existingSecret": "",
+		"clients": []interface{}{
+			map[string]interface{}{"name": "a", "apiKey": "abc"},
+		},
+		"auth": map[string]interface{}{"tokens": []interface{}{"x", "y"}},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"image": "nginx",
+		"db": map[string]interface{}{
+			"user":     "admin",
+			"password": Mask,
+		},
+		"existingSecret": "",
+		"clients": []interface{}{
+			map[string]interface{}{"name": "a", "apiKey": Mask},
+		},
+		"auth": map[string]interface{}{"tokens": Mask},
+	}, r.Values(vals))
+	assert.Equal(t, "hunter2", vals["db"].(map[string]interface{})["password"], "the values are left untouched")
+
+	var none *Redactor
+	assert.Equal(t, vals, none.Values(vals))
+}
+
+func TestText(t *testing.T) {
+	r, err := New()
+	require.NoError(t, err)
+
+	for in, want := range map[string]string{
+		"connecting with password=hunter2 to db":    "connecting with password=[REDACTED] to db",
+		`{"user":"admin","dbPassword":"hunter2"}`:   `{"user":"admin","dbPassword":"[REDACTED]"}`,
+		"auth.token: abc, name: foo":                "auth.token: [REDACTED], name: foo",
+		`Patch Secret "creds" in namespace default`: `Patch Secret "creds" in namespace default`,
+	} {
+		assert.Equal(t, want, r.Text(in))
+	}
+}
+
+func TestManifest(t *testing.T) {
+	r, err := New()
+	require.NoError(t, err)
+
+	manifest := `---
+# Source: chart/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+type: Opaque
+data:
+  password: aHVudGVyMg==
+stringData:
+  user: admin
+---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  password: not-a-secret-here
+`
+	want := `---
+# Source: chart/templates/secret.yaml
+apiVersion: v1
+data:
+  password: '[REDACTED]'
+kind: Secret
+metadata:
+  name: creds
+stringData:
+  user: '[REDACTED]'
+type: Opaque
+---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  password: not-a-secret-here
+`
+	assert.Equal(t, want, r.Manifest(manifest))
+
+	var none *Redactor
+	assert.Equal(t, manifest, none.Manifest(manifest))
+}
+
+func TestChanges(t *testing.T) {
+	r, err := New()
+	require.NoError(t, err)
+
+	original := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\ndata:\n  password: b2xk\n"
+	target := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\ndata:\n  password: bmV3\n  user: YWRtaW4=\n"
+	changes, err := diff.Manifests(original, target, "default")
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+
+	redacted := r.Changes(changes)
+	assert.Equal(t, map[string]interface{}{"password": Mask}, redacted[0].Original["data"])
+	assert.Equal(t, map[string]interface{}{"password": Mask, "user": Mask}, redacted[0].Target["data"])
+	for _, op := range redacted[0].Patch {
+		assert.Equal(t, Mask, op.Value, op.Path)
+	}
+	assert.Equal(t, "b2xk", changes[0].Original["data"].(map[string]interface{})["password"], "the changes are left untouched")
+}
+
+func TestRelease(t *testing.T) {
+	r, err := New()
+	require.NoError(t, err)
+
+	sub := &chart.Chart{Metadata: &chart.Metadata{Name: "sub"}, Values: map[string]interface{}{"token": "abc"}}
+	ch := &chart.Chart{Metadata: &chart.Metadata{Name: "app"}, Values: map[string]interface{}{"password": "default"}}
+	ch.AddDependency(sub)
+	rel := &release.Release{
+		Name:     "foo",
+		Chart:    ch,
+		Config:   map[string]interface{}{"password": "hunter2"},
+		Manifest: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\nstringData:\n  password: hunter2\n",
+		Hooks: []*release.Hook{{
+			Name:     "pre",
+			Manifest: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: hook\nstringData:\n  key: value\n",
+		}},
+	}
+
+	out := r.Release(rel)
+	assert.Equal(t, Mask, out.Config["password"])
+	assert.NotContains(t, out.Manifest, "hunter2")
+	assert.NotContains(t, out.Hooks[0].Manifest, "value")
+	assert.Equal(t, Mask, out.Chart.Values["password"])
+	require.Len(t, out.Chart.Dependencies(), 1)
+	assert.Equal(t, Mask, out.Chart.Dependencies()[0].Values["token"])
+	assert.Same(t, out.Chart, out.Chart.Dependencies()[0].Parent())
+
+	assert.Equal(t, "hunter2", rel.Config["password"])
+	assert.Contains(t, rel.Manifest, "hunter2")
+	assert.Contains(t, rel.Hooks[0].Manifest, "key: value")
+	assert.Equal(t, "abc", sub.Values["token"])
+	assert.Same(t, ch, sub.Parent())
+}
+
+func TestPaths(t *testing.T) {
+	vals := map[string]interface{}{
+		"db":    map[string]interface{}{"user": "admin", "password": "hunter2"},
+		"tls":   map[string]interface{}{"key": "abc"},
+		"empty": "",
+	}
+	assert.Equal(t, map[string]interface{}{
+		"db":    map[string]interface{}{"user": "admin", "password": Mask},
+		"tls":   Mask,
+		"empty": "",
+	}, Paths(vals, []string{"db.password", "tls", "empty", "missing.key"}))
+	assert.Equal(t, "hunter2", vals["db"].(map[string]interface{})["password"], "the values are left untouched")
+}
+
+func TestSensitive(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app"},
+		Schema:   []byte(`{"properties": {"pin": {"type": "string", "x-helm-sensitive": true}}}`),
+		Values:   map[string]interface{}{"pin": "0000"},
+	}
+	rel := &release.Release{Name: "foo", Chart: ch, Config: map[string]interface{}{"pin": "1234"}}
+
+	out := Sensitive(rel)
+	assert.Equal(t, Mask, out.Config["pin"])
+	assert.Equal(t, Mask, out.Chart.Values["pin"])
+	assert.Equal(t, "1234", rel.Config["pin"])
+	assert.Equal(t, "0000", ch.Values["pin"])
+
+	// Sensitive values are masked even if no pattern matches their key.
+	r, err := New()
+	require.NoError(t, err)
+	assert.Equal(t, Mask, r.Release(rel).Config["pin"])
+}
@@ -482,6 +482,24 @@ func (c *Client) Pull(ref string, options ...PullOption) (*PullResult, error) {
 	return result, nil
 }
 
+// Resolve returns the digest of the manifest the reference points to. Unlike
+// Pull, it resolves the references of any artifact, such as container images.
+func (c *Client) Resolve(ref string) (string, error) {
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	remotesResolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return "", err
+	}
+	_, desc, err := remotesResolver.Resolve(ctx(c.out, c.debug), parsedRef.String())
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
 // PullOptWithChart returns a function that sets the withChart setting on pull
 func PullOptWithChart(withChart bool) PullOption {
 	return func(operation *pullOperation) {
@@ -513,7 +531,9 @@ type (
 		Config   *descriptorPushSummary         `json:"config"`
 		Chart    *descriptorPushSummaryWithMeta `json:"chart"`
 		Prov     *descriptorPushSummary         `json:"prov"`
-		Ref      string                         `json:"ref"`
+		// Referrers are the manifests of the artifacts attached to the chart.
+		Referrers []*descriptorPushSummary `json:"referrers,omitempty"`
+		Ref       string                   `json:"ref"`
 	}
 
 	descriptorPushSummary struct {
@@ -530,6 +550,8 @@ type (
 		provData     []byte
 		strictMode   bool
 		creationTime string
+		annotations  map[string]string
+		referrers    []Referrer
 	}
 )
 
@@ -556,6 +578,11 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 				"strict mode enabled, ref basename and tag must match the chart name and version")
 		}
 	}
+	for _, r := range operation.referrers {
+		if err := validateReferrer(r); err != nil {
+			return nil, err
+		}
+	}
 	memoryStore := content.NewMemory()
 	chartDescriptor, err := memoryStore.Add("", ChartLayerMediaType, data)
 	if err != nil {
@@ -584,6 +611,15 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 	}
 
 	ociAnnotations := generateOCIAnnotations(meta, operation.creationTime)
+annotations:
+	for k, v := range operation.annotations {
+		for _, immutableOciKey := range immutableOciAnnotations {
+			if immutableOciKey == k {
+				continue annotations
+			}
+		}
+		ociAnnotations[k] = v
+	}
 
 	manifestData, manifest, err := content.GenerateManifest(&configDescriptor, ociAnnotations, descriptors...)
 	if err != nil {
@@ -599,9 +635,24 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 		return nil, err
 	}
 	registryStore := content.Registry{Resolver: remotesResolver}
+
+	// Referrers are pushed before the chart, so that the tag never points to
+	// a chart missing some of them.
+	var referrers []*descriptorPushSummary
+	pushed := &manifestPush{client: c, ref: parsedRef, store: memoryStore, target: registryStore}
+	for _, r := range operation.referrers {
+		desc, err := pushed.pushReferrer(ctx(c.out, c.debug), manifest, r, ociAnnotations[ocispec.AnnotationCreated])
+		if err != nil {
+			pushed.rollback(ctx(c.out, c.debug))
+			return nil, errors.Wrap(err, "failed to push the artifacts attached to the chart")
+		}
+		referrers = append(referrers, &descriptorPushSummary{Digest: desc.Digest.String(), Size: desc.Size})
+	}
+
 	_, err = oras.Copy(ctx(c.out, c.debug), memoryStore, parsedRef.String(), registryStore, "",
 		oras.WithNameValidation(nil))
 	if err != nil {
+		pushed.rollback(ctx(c.out, c.debug))
 		return nil, err
 	}
 	chartSummary := &descriptorPushSummaryWithMeta{
@@ -618,9 +669,10 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 			Digest: configDescriptor.Digest.String(),
 			Size:   configDescriptor.Size,
 		},
-		Chart: chartSummary,
-		Prov:  &descriptorPushSummary{}, // prevent nil references
-		Ref:   parsedRef.String(),
+		Chart:     chartSummary,
+		Prov:      &descriptorPushSummary{}, // prevent nil references
+		Referrers: referrers,
+		Ref:       parsedRef.String(),
 	}
 	if operation.provData != nil {
 		result.Prov = &descriptorPushSummary{
@@ -630,6 +682,9 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 	}
 	fmt.Fprintf(c.out, "Pushed: %s\n", result.Ref)
 	fmt.Fprintf(c.out, "Digest: %s\n", result.Manifest.Digest)
+	for _, r := range result.Referrers {
+		fmt.Fprintf(c.out, "Attached: %s\n", r.Digest)
+	}
 	if strings.Contains(parsedRef.Reference, "_") {
 		fmt.Fprintf(c.out, "%s contains an underscore.\n", result.Ref)
 		fmt.Fprint(c.out, registryUnderscoreMessage+"\n")
@@ -54,7 +54,15 @@ func (suite *HTTPRegistryClientTestSuite) Test_3_Tags() {
 	testTags(&suite.TestSuite)
 }
 
-func (suite *HTTPRegistryClientTestSuite) Test_4_ManInTheMiddle() {
+func (suite *HTTPRegistryClientTestSuite) Test_3_Resolve() {
+	testResolve(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_4_PushReferrers() {
+	testPushReferrers(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_5_ManInTheMiddle() {
 	ref := fmt.Sprintf("%s/testrepo/supposedlysafechart:9.9.9", suite.CompromisedRegistryHost)
 
 	// returns content that does not match the expected digest
@@ -0,0 +1,173 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+	"oras.land/oras-go/pkg/registry"
+	registryauth "oras.land/oras-go/pkg/registry/remote/auth"
+)
+
+// Referrer is an artifact attached to a chart when it is pushed, such as an
+// SBOM or test results. It is pushed as a manifest whose subject is the chart
+// manifest, so that registries implementing the OCI referrers API list it
+// along with the chart.
+type Referrer struct {
+	// ArtifactType is the media type of the artifact, e.g.
+	// application/spdx+json.
+	ArtifactType string
+	// Data is the content of the artifact.
+	Data []byte
+	// Annotations are set on the manifest of the artifact.
+	Annotations map[string]string
+}
+
+// PushOptReferrers returns a function that attaches referrers to the chart on
+// push. The chart is only pushed once all of them are, and they are deleted if
+// the push fails.
+func PushOptReferrers(referrers ...Referrer) PushOption {
+	return func(operation *pushOperation) {
+		operation.referrers = append(operation.referrers, referrers...)
+	}
+}
+
+// PushOptAnnotations returns a function that adds annotations to the chart
+// manifest on push, e.g. the source revision or the URL of the build. They
+// cannot override the annotations generated from the chart metadata.
+func PushOptAnnotations(annotations map[string]string) PushOption {
+	return func(operation *pushOperation) {
+		if operation.annotations == nil {
+			operation.annotations = map[string]string{}
+		}
+		for k, v := range annotations {
+			operation.annotations[k] = v
+		}
+	}
+}
+
+// validateReferrer checks a referrer before anything is pushed.
+func validateReferrer(r Referrer) error {
+	if _, _, err := mime.ParseMediaType(r.ArtifactType); err != nil {
+		return errors.Wrapf(err, "invalid artifact type %q", r.ArtifactType)
+	}
+	if len(r.Data) == 0 {
+		return errors.Errorf("artifact of type %s is empty", r.ArtifactType)
+	}
+	return nil
+}
+
+// manifestPush pushes referrer manifests by digest, remembering those it
+// created so that they can be deleted should the push fail.
+type manifestPush struct {
+	client  *Client
+	ref     registry.Reference
+	store   *content.Memory
+	target  content.Registry
+	created []string
+}
+
+// push pushes the manifest and its content by digest, unless the registry
+// already has it.
+func (p *manifestPush) push(ctx context.Context, desc ocispec.Descriptor, data []byte) error {
+	digestRef := fmt.Sprintf("%s/%s@%s", p.ref.Registry, p.ref.Repository, desc.Digest)
+	if _, _, err := p.target.Resolve(ctx, digestRef); err == nil {
+		return nil
+	}
+	if err := p.store.StoreManifest(digestRef, desc, data); err != nil {
+		return err
+	}
+	if _, err := oras.Copy(ctx, p.store, digestRef, p.target, "", oras.WithNameValidation(nil)); err != nil {
+		return err
+	}
+	p.created = append(p.created, desc.Digest.String())
+	return nil
+}
+
+// pushReferrer pushes the referrer with the given subject, and returns the
+// descriptor of its manifest. The subject does not need to be pushed yet.
+func (p *manifestPush) pushReferrer(ctx context.Context, subject ocispec.Descriptor, r Referrer, creationTime string) (ocispec.Descriptor, error) {
+	p.store.Set(ocispec.DescriptorEmptyJSON, ocispec.DescriptorEmptyJSON.Data)
+	layer, err := p.store.Add("", r.ArtifactType, r.Data)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	annotations := map[string]string{ocispec.AnnotationCreated: creationTime}
+	for k, v := range r.Annotations {
+		annotations[k] = v
+	}
+	manifest := ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: r.ArtifactType,
+		Config:       ocispec.DescriptorEmptyJSON,
+		Layers:       []ocispec.Descriptor{layer},
+		Subject:      &subject,
+		Annotations:  annotations,
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc, err := p.store.Add("", ocispec.MediaTypeImageManifest, data)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, p.push(ctx, desc, data)
+}
+
+// rollback deletes the manifests created. It is best effort:
+// registries may not allow deletions, in which case the manifests are left
+// untagged.
+func (p *manifestPush) rollback(ctx context.Context) {
+	scheme := "https"
+	if p.client.plainHTTP {
+		scheme = "http"
+	}
+	ctx = registryauth.AppendScopes(ctx, registryauth.ScopeRepository(p.ref.Repository, "delete"))
+	for i := len(p.created) - 1; i >= 0; i-- {
+		err := func() error {
+			u := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, p.ref.Host(), p.ref.Repository, p.created[i])
+			req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := p.client.registryAuthorizer.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNotFound {
+				return errors.Errorf("unexpected status %s", resp.Status)
+			}
+			return nil
+		}()
+		if err != nil {
+			fmt.Fprintf(p.client.out, "WARNING: failed to delete %s@%s after the push failed: %s\n", p.ref.Repository, p.created[i], err)
+		}
+	}
+	p.created = nil
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -31,12 +32,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/containerd/containerd/remotes"
 	"github.com/distribution/distribution/v3/configuration"
 	"github.com/distribution/distribution/v3/registry"
 	_ "github.com/distribution/distribution/v3/registry/auth/htpasswd"
 	_ "github.com/distribution/distribution/v3/registry/storage/driver/inmemory"
 	"github.com/foxcpp/go-mockdns"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/phayes/freeport"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/crypto/bcrypt"
 
@@ -137,7 +141,10 @@ func setup(suite *TestSuite, tlsEnabled, insecure bool) *registry.Registry {
 
 	config.HTTP.Addr = fmt.Sprintf(":%d", port)
 	config.HTTP.DrainTimeout = time.Duration(10) * time.Second
-	config.Storage = map[string]configuration.Parameters{"inmemory": map[string]interface{}{}}
+	config.Storage = map[string]configuration.Parameters{
+		"inmemory": map[string]interface{}{},
+		"delete":   map[string]interface{}{"enabled": true},
+	}
 
 	// Basic auth is not possible if we are serving HTTP.
 	if tlsEnabled {
@@ -394,3 +401,104 @@ func testTags(suite *TestSuite) {
 	suite.Nil(err, "no error retrieving tags")
 	suite.Equal(1, len(tags))
 }
+
+func testResolve(suite *TestSuite) {
+	// bad/missing ref
+	ref := fmt.Sprintf("%s/testrepo/no-existy:1.2.3", suite.DockerRegistryHost)
+	_, err := suite.RegistryClient.Resolve(ref)
+	suite.NotNil(err, "error on bad/missing ref")
+
+	// The digest is the digest of the manifest pulled
+	ref = fmt.Sprintf("%s/testrepo/signtest:0.1.0", suite.DockerRegistryHost)
+	digest, err := suite.RegistryClient.Resolve(ref)
+	suite.Nil(err, "no error resolving a chart")
+	suite.Equal("sha256:fbbade96da6050f68f94f122881e3b80051a18f13ab5f4081868dd494538f5c2", digest)
+}
+
+// failingTagResolver fails pushes to the tag, and records the other
+// references pushed.
+type failingTagResolver struct {
+	remotes.Resolver
+	tag    string
+	pushed []string
+}
+
+func (r *failingTagResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	if strings.Contains(ref, ":"+r.tag) {
+		return nil, errors.New("failed to push the tag")
+	}
+	// The digest of the content pushed is appended to the reference.
+	r.pushed = append(r.pushed, ref[:strings.LastIndex(ref, "@")])
+	return r.Resolver.Pusher(ctx, ref)
+}
+
+func testPushReferrers(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/signtest-0.1.0.tgz")
+	suite.Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Nil(err, "no error extracting chart meta")
+	ref := fmt.Sprintf("%s/testreferrers/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
+	sbom := Referrer{
+		ArtifactType: "application/spdx+json",
+		Data:         []byte(`{"spdxVersion":"SPDX-2.3"}`),
+	}
+
+	// invalid artifact type
+	_, err = suite.RegistryClient.Push(chartData, ref, PushOptReferrers(Referrer{ArtifactType: "not a media type", Data: []byte("x")}))
+	suite.NotNil(err, "error pushing a referrer with an invalid artifact type")
+
+	// the tag fails to be pushed, so the chart and referrers are removed
+	parsedRef, err := parseReference(ref)
+	suite.Nil(err)
+	resolver, err := suite.RegistryClient.resolver(parsedRef)
+	suite.Nil(err)
+	failing := &failingTagResolver{Resolver: resolver, tag: meta.Version}
+	client, err := NewClient(ClientOptWriter(suite.Out), ClientOptPlainHTTP(), ClientOptResolver(failing))
+	suite.Nil(err)
+	_, err = client.Push(chartData, ref, PushOptReferrers(sbom))
+	suite.NotNil(err, "error pushing when the tag cannot be pushed")
+	suite.Len(failing.pushed, 1, "the referrer is pushed by digest")
+	for _, pushed := range failing.pushed {
+		_, _, err := resolver.Resolve(context.Background(), pushed)
+		suite.NotNil(err, "manifest %s is deleted", pushed)
+	}
+	_, err = suite.RegistryClient.Pull(ref)
+	suite.NotNil(err, "the chart is not tagged")
+
+	// push with referrers and annotations
+	result, err := suite.RegistryClient.Push(chartData, ref,
+		PushOptReferrers(sbom, Referrer{ArtifactType: "application/vnd.junit+xml", Data: []byte("<testsuites/>")}),
+		PushOptAnnotations(map[string]string{
+			ocispec.AnnotationRevision: "0123456789abcdef",
+			ocispec.AnnotationTitle:    "overridden",
+		}))
+	suite.Nil(err, "no error pushing with referrers")
+	suite.Len(result.Referrers, 2)
+
+	_, err = suite.RegistryClient.Pull(ref)
+	suite.Nil(err, "no error pulling the chart pushed with referrers")
+
+	_, desc, err := resolver.Resolve(context.Background(), ref)
+	suite.Nil(err)
+	fetcher, err := resolver.Fetcher(context.Background(), ref)
+	suite.Nil(err)
+	rc, err := fetcher.Fetch(context.Background(), desc)
+	suite.Nil(err)
+	defer rc.Close()
+	var manifest ocispec.Manifest
+	suite.Nil(json.NewDecoder(rc).Decode(&manifest))
+	suite.Equal("0123456789abcdef", manifest.Annotations[ocispec.AnnotationRevision])
+	suite.Equal(meta.Name, manifest.Annotations[ocispec.AnnotationTitle])
+
+	for _, r := range result.Referrers {
+		digestRef := fmt.Sprintf("%s/testreferrers/%s@%s", suite.DockerRegistryHost, meta.Name, r.Digest)
+		_, desc, err := resolver.Resolve(context.Background(), digestRef)
+		suite.Nil(err, "referrer %s is pushed", digestRef)
+		rc, err := fetcher.Fetch(context.Background(), desc)
+		suite.Nil(err)
+		var referrer ocispec.Manifest
+		suite.Nil(json.NewDecoder(rc).Decode(&referrer))
+		rc.Close()
+		suite.Equal(result.Manifest.Digest, referrer.Subject.Digest.String())
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// ChartDigest returns the SHA-256 digest of the content of a chart and its
+// dependencies, so that a chart can be told apart from the chart of a
+// revision without rendering it. It is computed before the dependencies
+// disabled by conditions or tags are removed from the chart.
+func ChartDigest(ch *chart.Chart) string {
+	if ch == nil {
+		return ""
+	}
+	h := sha256.New()
+	if err := writeChart(h, ch); err != nil {
+		return ""
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+func writeChart(h hash.Hash, ch *chart.Chart) error {
+	// encoding/json sorts map keys, so equal charts have the same digest.
+	data, err := json.Marshal(ch)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(h, "%d:", len(data))
+	h.Write(data)
+	for _, dep := range ch.Dependencies() {
+		if err := writeChart(h, dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValuesDigest returns the SHA-256 digest of values, so that values can be
+// told apart from the values of a revision without comparing them.
+func ValuesDigest(values map[string]interface{}) string {
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	// encoding/json sorts map keys, so equal values have the same digest.
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestChartDigest(t *testing.T) {
+	mk := func(values map[string]interface{}) *chart.Chart {
+		c := &chart.Chart{
+			Metadata:  &chart.Metadata{Name: "parent", Version: "1.0.0"},
+			Templates: []*chart.File{{Name: "templates/a.yaml", Data: []byte("a: b")}},
+		}
+		c.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: "child", Version: "1.0.0"}, Values: values})
+		return c
+	}
+
+	digest := ChartDigest(mk(map[string]interface{}{"a": 1, "b": 2}))
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", digest)
+	assert.Equal(t, digest, ChartDigest(mk(map[string]interface{}{"b": 2, "a": 1})))
+	assert.NotEqual(t, digest, ChartDigest(mk(map[string]interface{}{"a": 1})))
+	assert.Empty(t, ChartDigest(nil))
+}
+
+func TestValuesDigest(t *testing.T) {
+	a := ValuesDigest(map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": "d"}})
+	b := ValuesDigest(map[string]interface{}{"b": map[string]interface{}{"c": "d"}, "a": 1})
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, ValuesDigest(map[string]interface{}{"a": 2}))
+	assert.Equal(t, ValuesDigest(nil), ValuesDigest(map[string]interface{}{}))
+}
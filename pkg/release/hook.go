@@ -17,6 +17,8 @@ limitations under the License.
 package release
 
 import (
+	stdtime "time"
+
 	"helm.sh/helm/v3/pkg/time"
 )
 
@@ -59,6 +61,18 @@ const HookWeightAnnotation = "helm.sh/hook-weight"
 // HookDeleteAnnotation is the label name for the delete policy for a hook
 const HookDeleteAnnotation = "helm.sh/hook-delete-policy"
 
+// HookRetriesAnnotation is the label name for the number of times a failed
+// hook is re-created
+const HookRetriesAnnotation = "helm.sh/hook-retries"
+
+// HookRetryBackoffAnnotation is the label name for the wait before the first
+// retry of a failed hook
+const HookRetryBackoffAnnotation = "helm.sh/hook-retry-backoff"
+
+// HookTimeoutAnnotation is the label name for the time to wait for a hook,
+// overriding the timeout of the action
+const HookTimeoutAnnotation = "helm.sh/hook-timeout"
+
 // Hook defines a hook object.
 type Hook struct {
 	Name string `json:"name,omitempty"`
@@ -76,6 +90,14 @@ type Hook struct {
 	Weight int `json:"weight,omitempty"`
 	// DeletePolicies are the policies that indicate when to delete the hook
 	DeletePolicies []HookDeletePolicy `json:"delete_policies,omitempty"`
+	// Retries is the number of times the hook is re-created after it failed
+	Retries int `json:"retries,omitempty"`
+	// RetryBackoff is the wait before the first retry, doubling with every
+	// further retry. A default applies if zero.
+	RetryBackoff stdtime.Duration `json:"retry_backoff,omitempty"`
+	// Timeout is the time to wait for the hook to complete. The timeout of
+	// the action applies if zero.
+	Timeout stdtime.Duration `json:"timeout,omitempty"`
 }
 
 // A HookExecution records the result for the last execution of a hook for a given release.
@@ -86,6 +108,8 @@ type HookExecution struct {
 	CompletedAt time.Time `json:"completed_at,omitempty"`
 	// Phase indicates whether the hook completed successfully
 	Phase HookPhase `json:"phase"`
+	// Attempts is the number of times the hook was created
+	Attempts int `json:"attempts,omitempty"`
 }
 
 // A HookPhase indicates the state of a hook execution
@@ -0,0 +1,47 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "helm.sh/helm/v3/pkg/time"
+
+// HookStatus describes the last execution of a hook of a release.
+type HookStatus struct {
+	// Name is the name of the hook.
+	Name string `json:"name"`
+	// Kind is the kind of the hook.
+	Kind string `json:"kind"`
+	// Events are the events the hook runs on.
+	Events []HookEvent `json:"events,omitempty"`
+	// Phase is the phase of the last execution, empty if the hook never ran.
+	Phase HookPhase `json:"phase,omitempty"`
+	// StartedAt and CompletedAt are when the last execution started and
+	// completed.
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	// ExitCodes are the exit codes of the terminated containers of the pods
+	// of the hook, if they still exist.
+	ExitCodes []ContainerExitCode `json:"exit_codes,omitempty"`
+}
+
+// ContainerExitCode is the exit code of a terminated container of a pod.
+type ContainerExitCode struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	ExitCode  int32  `json:"exit_code"`
+	// Reason is the reason the container terminated, e.g. Error or
+	// OOMKilled.
+	Reason string `json:"reason,omitempty"`
+}
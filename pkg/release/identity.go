@@ -0,0 +1,44 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+// Identity describes who performed an operation on a release.
+type Identity struct {
+	// User is the Kubernetes user, as authenticated by the API server.
+	User string `json:"user,omitempty"`
+	// DeployedBy is a free-form description of who performed the operation,
+	// e.g. a person or a pipeline.
+	DeployedBy string `json:"deployed_by,omitempty"`
+	// CI holds the metadata of the CI job that performed the operation, e.g.
+	// the repository and run ID, as found in the environment.
+	CI map[string]string `json:"ci,omitempty"`
+}
+
+// String returns the description of who performed the operation, followed by
+// the Kubernetes user if both are known.
+func (i *Identity) String() string {
+	if i == nil {
+		return ""
+	}
+	switch {
+	case i.DeployedBy != "" && i.User != "":
+		return i.DeployedBy + " (" + i.User + ")"
+	case i.DeployedBy != "":
+		return i.DeployedBy
+	default:
+		return i.User
+	}
+}
@@ -18,6 +18,7 @@ package release
 import (
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/time"
 )
 
@@ -37,4 +38,20 @@ type Info struct {
 	Notes string `json:"notes,omitempty"`
 	// Contains the deployed resources information
 	Resources map[string][]runtime.Object `json:"resources,omitempty"`
+	// Contains the live existence, health and age of the deployed resources
+	ResourceStatuses []ResourceStatus `json:"resource_statuses,omitempty"`
+	// Contains the last execution of the hooks, including the exit codes of
+	// their containers
+	HookStatuses []HookStatus `json:"hook_statuses,omitempty"`
+	// Health is the traffic light health of the deployed resources, see
+	// SummarizeHealth. It is only set when the health was evaluated.
+	Health string `json:"health,omitempty"`
+	// DeployedBy identifies who performed the operation that created this
+	// revision.
+	DeployedBy *Identity `json:"deployed_by,omitempty"`
+	// Warnings are the warnings the API server returned while applying the
+	// resources of this revision, such as deprecation notices.
+	Warnings []string `json:"warnings,omitempty"`
+	// Deprecation describes the deprecation of the chart of this revision.
+	Deprecation *chart.Deprecation `json:"deprecation,omitempty"`
 }
@@ -0,0 +1,56 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "helm.sh/helm/v3/pkg/time"
+
+// JournalPhase is a step of the operation creating a revision of a release.
+type JournalPhase string
+
+const (
+	// JournalCreated is recorded with the pending revision when it is first
+	// stored, before the operation changes the cluster.
+	JournalCreated JournalPhase = "created"
+	// JournalApplying is written before the operation first changes the
+	// cluster, i.e. before the pre hooks run and the resources are applied.
+	JournalApplying JournalPhase = "applying"
+	// JournalApplied is written once the resources are applied, before
+	// waiting for them and running the post hooks.
+	JournalApplied JournalPhase = "applied"
+	// JournalCompleted is written once the resources are ready and the post
+	// hooks have run, before the revision is marked deployed.
+	JournalCompleted JournalPhase = "completed"
+)
+
+// JournalEntry records that the operation creating a revision reached a
+// phase. As entries are written before and after the operation changes the
+// cluster, the journal of a revision left pending by a crashed operation
+// tells how far the operation got.
+type JournalEntry struct {
+	Phase JournalPhase `json:"phase"`
+	Time  time.Time    `json:"time"`
+}
+
+// Journaled reports whether the journal of the release has the phase.
+func (r *Release) Journaled(phase JournalPhase) bool {
+	for _, e := range r.Journal {
+		if e.Phase == phase {
+			return true
+		}
+	}
+	return false
+}
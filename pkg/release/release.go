@@ -15,7 +15,35 @@ limitations under the License.
 
 package release
 
-import "helm.sh/helm/v3/pkg/chart"
+import (
+	"encoding/json"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// The methods the resources of a release can be applied with.
+const (
+	// ApplyMethodClientSide updates resources with patches computed by Helm.
+	// It is the method of releases that have none recorded.
+	ApplyMethodClientSide = "csa"
+	// ApplyMethodServerSide updates resources with server-side apply.
+	ApplyMethodServerSide = "ssa"
+)
+
+// The parts of a release that can be stored apart from its record.
+const (
+	OverflowNotes    = "notes"
+	OverflowManifest = "manifest"
+	// OverflowValues are the values the chart marks as sensitive.
+	OverflowValues = "values"
+	// OverflowSensitiveManifest, OverflowSensitiveHooks and
+	// OverflowSensitiveNotes are the manifest, the manifests of the hooks and
+	// the notes of a release with sensitive values, stored with them as they
+	// may hold them rendered.
+	OverflowSensitiveManifest = "sensitive-manifest"
+	OverflowSensitiveHooks    = "sensitive-hooks"
+	OverflowSensitiveNotes    = "sensitive-notes"
+)
 
 // Release describes a deployment of a chart, together with the chart
 // and the variables used to deploy that chart.
@@ -37,9 +65,50 @@ type Release struct {
 	Version int `json:"version,omitempty"`
 	// Namespace is the kubernetes namespace of the release.
 	Namespace string `json:"namespace,omitempty"`
+	// Namespaces are the namespaces the resources of the release are
+	// deployed into, when some of them are deployed outside of Namespace.
+	// Resources without a namespace are deployed into Namespace.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ResourceHashes are the hashes of the rendered resources of the release,
+	// by resource. They let an upgrade skip resources that did not change.
+	ResourceHashes map[string]string `json:"resource_hashes,omitempty"`
+	// ChartDigest and ValuesDigest are the digests of the chart and the
+	// values of the revision, see ChartDigest and ValuesDigest. They tell
+	// whether a chart and values differ from those of the revision without
+	// rendering them.
+	ChartDigest  string `json:"chart_digest,omitempty"`
+	ValuesDigest string `json:"values_digest,omitempty"`
+	// ResourceLabels are the labels stamped on the resources of the release
+	// beyond those of its manifest, e.g. its revision. They are removed from
+	// the resources by an upgrade or rollback that does not stamp them.
+	ResourceLabels map[string]string `json:"resource_labels,omitempty"`
+	// ApplyMethod is the method the resources of the release are updated
+	// with, ApplyMethodClientSide if empty.
+	ApplyMethod string `json:"apply_method,omitempty"`
+	// Suspension records the workloads scaled to zero while the release is
+	// suspended. It is nil if the release is not suspended.
+	Suspension *Suspension `json:"suspension,omitempty"`
+	// Journal records the phases the operation creating the revision
+	// reached, see JournalEntry.
+	Journal []JournalEntry `json:"journal,omitempty"`
+	// HelmVersion is the version of Helm that wrote the revision.
+	HelmVersion string `json:"helm_version,omitempty"`
+	// SchemaVersion is the version of the schema of the record of the
+	// revision, see CurrentSchemaVersion.
+	SchemaVersion int `json:"schema_version,omitempty"`
+	// Overflow lists the parts of the revision that are stored apart from its
+	// record: OverflowNotes or OverflowManifest if they were too large to be
+	// kept in it, OverflowValues and the sensitive manifests and notes if
+	// the chart marks values as sensitive. It is only set on the records
+	// themselves, which then have schema SchemaVersionOverflow.
+	Overflow []string `json:"overflow,omitempty"`
 	// Labels of the release.
 	// Disabled encoding into Json cause labels are stored in storage driver metadata field.
 	Labels map[string]string `json:"-"`
+
+	// unknownFields are the encoded fields of the release this version of
+	// Helm does not know about.
+	unknownFields map[string]json.RawMessage
 }
 
 // SetStatus is a helper for setting the status on a release.
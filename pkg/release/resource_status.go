@@ -0,0 +1,65 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "helm.sh/helm/v3/pkg/time"
+
+// ResourceStatus describes the live status of a resource of a release.
+type ResourceStatus struct {
+	// APIVersion is the API version of the resource.
+	APIVersion string `json:"api_version"`
+	// Kind is the kind of the resource.
+	Kind string `json:"kind"`
+	// Name is the name of the resource.
+	Name string `json:"name"`
+	// Namespace is the namespace of the resource, if namespaced.
+	Namespace string `json:"namespace,omitempty"`
+	// Health is one of Ready, NotReady, Failed, Missing or Unknown.
+	Health string `json:"health"`
+	// Message explains the health of a failed or unknown resource.
+	Message string `json:"message,omitempty"`
+	// Created is when the resource was created.
+	Created time.Time `json:"created,omitempty"`
+}
+
+// Traffic light health of a release, see SummarizeHealth.
+const (
+	// HealthGreen means all resources of the release are ready.
+	HealthGreen = "green"
+	// HealthYellow means some resources are not ready yet or their health
+	// could not be determined.
+	HealthYellow = "yellow"
+	// HealthRed means some resources failed or are missing.
+	HealthRed = "red"
+	// HealthUnknown means the health of the release could not be evaluated.
+	HealthUnknown = "unknown"
+)
+
+// SummarizeHealth condenses the live status of the resources of a release
+// into a traffic light: red if any resource failed or is missing, yellow if
+// any is not ready or unknown, and green otherwise.
+func SummarizeHealth(statuses []ResourceStatus) string {
+	health := HealthGreen
+	for _, s := range statuses {
+		switch s.Health {
+		case "Failed", "Missing":
+			return HealthRed
+		case "NotReady", "Unknown":
+			health = HealthYellow
+		}
+	}
+	return health
+}
@@ -0,0 +1,41 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "testing"
+
+func TestSummarizeHealth(t *testing.T) {
+	for _, tt := range []struct {
+		health []string
+		expect string
+	}{
+		{nil, HealthGreen},
+		{[]string{"Ready", "Ready"}, HealthGreen},
+		{[]string{"Ready", "NotReady"}, HealthYellow},
+		{[]string{"Unknown", "Ready"}, HealthYellow},
+		{[]string{"NotReady", "Missing"}, HealthRed},
+		{[]string{"Failed", "Ready"}, HealthRed},
+	} {
+		statuses := make([]ResourceStatus, len(tt.health))
+		for i, h := range tt.health {
+			statuses[i].Health = h
+		}
+		if got := SummarizeHealth(statuses); got != tt.expect {
+			t.Errorf("expected %s for %v, got %s", tt.expect, tt.health, got)
+		}
+	}
+}
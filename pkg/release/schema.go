@@ -0,0 +1,128 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// CurrentSchemaVersion is the newest version of the schema of the release
+// records this version of Helm reads and writes. It is increased whenever
+// records gain information older versions of Helm would lose or misinterpret
+// when they read or modify the record.
+//
+// Records are written with the oldest schema version able to hold them, so
+// that only the records actually using newer information are flagged to older
+// versions of Helm. Records without a schema version were written before it
+// was recorded.
+const CurrentSchemaVersion = SchemaVersionOverflow
+
+// Versions of the schema of release records.
+const (
+	// SchemaVersionBase is the schema of records holding all of their
+	// release.
+	SchemaVersionBase = 1
+	// SchemaVersionOverflow is the schema of records with parts of their
+	// release stored apart, see Release.Overflow. Older versions of Helm
+	// read them as having no manifest, notes or sensitive values.
+	SchemaVersionOverflow = 2
+)
+
+// NewerSchema reports whether the release was written with a schema newer
+// than the one this version of Helm knows about.
+func (r *Release) NewerSchema() bool {
+	return r.SchemaVersion > CurrentSchemaVersion
+}
+
+// Stamp records the Helm version and schema version the release is written
+// with. The Helm version of a revision is kept once recorded, and the schema
+// version is never lowered, so that a record read by an older version of Helm
+// keeps telling that it holds newer information.
+func (r *Release) Stamp(helmVersion string) {
+	if r.HelmVersion == "" {
+		r.HelmVersion = helmVersion
+	}
+	r.RequireSchema(SchemaVersionBase)
+}
+
+// RequireSchema raises the schema version of the release to the given
+// version if it is lower.
+func (r *Release) RequireSchema(version int) {
+	if r.SchemaVersion < version {
+		r.SchemaVersion = version
+	}
+}
+
+// releaseFields are the JSON names of the fields of Release.
+var releaseFields = func() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(Release{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// release has the fields of Release without its JSON methods.
+type release Release
+
+// UnmarshalJSON decodes a release, keeping the fields this version of Helm
+// does not know about so that they survive when the release is written back.
+func (r *Release) UnmarshalJSON(data []byte) error {
+	var rel release
+	if err := json.Unmarshal(data, &rel); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name := range fields {
+		if releaseFields[name] {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+	rel.unknownFields = fields
+	*r = Release(rel)
+	return nil
+}
+
+// MarshalJSON encodes a release along with the unknown fields it was decoded
+// with.
+func (r Release) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(release(r))
+	if err != nil || len(r.unknownFields) == 0 {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range r.unknownFields {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
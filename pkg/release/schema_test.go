@@ -0,0 +1,74 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStamp(t *testing.T) {
+	rel := &Release{}
+	rel.Stamp("v3.15")
+	if rel.HelmVersion != "v3.15" || rel.SchemaVersion != SchemaVersionBase {
+		t.Errorf("expected v3.15 and schema %d, got %s and %d", SchemaVersionBase, rel.HelmVersion, rel.SchemaVersion)
+	}
+	if rel.NewerSchema() {
+		t.Error("expected the release not to have a newer schema")
+	}
+
+	// An older version of Helm updating a newer record keeps what it says.
+	rel = &Release{HelmVersion: "v3.99", SchemaVersion: CurrentSchemaVersion + 1}
+	rel.Stamp("v3.15")
+	if rel.HelmVersion != "v3.99" || rel.SchemaVersion != CurrentSchemaVersion+1 {
+		t.Errorf("expected v3.99 and schema %d, got %s and %d", CurrentSchemaVersion+1, rel.HelmVersion, rel.SchemaVersion)
+	}
+	if !rel.NewerSchema() {
+		t.Error("expected the release to have a newer schema")
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	data := []byte(`{"name":"test","version":2,"schema_version":99,"future":{"a":1},"other":"b"}`)
+
+	var rel Release
+	if err := json.Unmarshal(data, &rel); err != nil {
+		t.Fatal(err)
+	}
+	if rel.Name != "test" || rel.Version != 2 || rel.SchemaVersion != 99 {
+		t.Errorf("unexpected release %+v", rel)
+	}
+
+	rel.Version = 3
+	out, err := json.Marshal(&rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"future":{"a":1},"name":"test","other":"b","schema_version":99,"version":3}`
+	if string(out) != expect {
+		t.Errorf("expected %s, got %s", expect, out)
+	}
+
+	// Releases without unknown fields encode as they always have.
+	out, err = json.Marshal(&Release{Name: "test", Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = `{"name":"test","version":1}`
+	if string(out) != expect {
+		t.Errorf("expected %s, got %s", expect, out)
+	}
+}
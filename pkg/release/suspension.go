@@ -0,0 +1,37 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "helm.sh/helm/v3/pkg/time"
+
+// Suspension records the workloads of a release that were scaled to zero, so
+// that they can be scaled back when the release is resumed.
+type Suspension struct {
+	// Suspended is when the release was suspended.
+	Suspended time.Time `json:"suspended,omitempty"`
+	// Workloads are the workloads that were scaled to zero.
+	Workloads []SuspendedWorkload `json:"workloads,omitempty"`
+}
+
+// SuspendedWorkload is a workload scaled to zero by a suspension.
+type SuspendedWorkload struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Replicas is the number of replicas the workload ran before it was
+	// suspended.
+	Replicas int32 `json:"replicas"`
+}
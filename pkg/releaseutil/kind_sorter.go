@@ -19,6 +19,8 @@ package releaseutil
 import (
 	"sort"
 
+	"github.com/pkg/errors"
+
 	"helm.sh/helm/v3/pkg/release"
 )
 
@@ -109,6 +111,62 @@ var UninstallOrder KindSortOrder = []string{
 	"PriorityClass",
 }
 
+// KindPlacement places a kind right before or right after another kind of an
+// ordering, e.g. the Issuers of cert-manager before its Certificates.
+type KindPlacement struct {
+	Kind   string `json:"kind"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Place returns a copy of the ordering with the kinds placed in turn, so that
+// a kind may be placed relative to a kind placed before. A kind already in the
+// ordering is moved.
+func (o KindSortOrder) Place(placements ...KindPlacement) (KindSortOrder, error) {
+	order := append(KindSortOrder{}, o...)
+	for _, p := range placements {
+		if p.Kind == "" {
+			return nil, errors.New("kind placement without a kind")
+		}
+		if (p.Before == "") == (p.After == "") {
+			return nil, errors.Errorf("kind %q must be placed either before or after another kind", p.Kind)
+		}
+		other := p.Before + p.After
+		if other == p.Kind {
+			return nil, errors.Errorf("kind %q cannot be placed relative to itself", p.Kind)
+		}
+		order = order.without(p.Kind)
+		i := order.index(other)
+		if i < 0 {
+			return nil, errors.Errorf("kind %q cannot be placed relative to %q, which is not in the order", p.Kind, other)
+		}
+		if p.After != "" {
+			i++
+		}
+		order = append(order[:i], append(KindSortOrder{p.Kind}, order[i:]...)...)
+	}
+	return order, nil
+}
+
+func (o KindSortOrder) index(kind string) int {
+	for i, k := range o {
+		if k == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+func (o KindSortOrder) without(kind string) KindSortOrder {
+	order := make(KindSortOrder, 0, len(o))
+	for _, k := range o {
+		if k != kind {
+			order = append(order, k)
+		}
+	}
+	return order
+}
+
 // sort manifests by kind.
 //
 // Results are sorted by 'ordering', keeping order of items with equal kind/priority
@@ -18,6 +18,7 @@ package releaseutil
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v3/pkg/release"
@@ -337,3 +338,37 @@ func TestKindSorterForHooks(t *testing.T) {
 		})
 	}
 }
+
+func TestKindSortOrderPlace(t *testing.T) {
+	base := KindSortOrder{"Namespace", "Secret", "Deployment"}
+	order, err := base.Place(
+		KindPlacement{Kind: "Issuer", After: "Secret"},
+		KindPlacement{Kind: "Certificate", After: "Issuer"},
+		KindPlacement{Kind: "Secret", Before: "Namespace"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "Secret Namespace Issuer Certificate Deployment"
+	if got := strings.Join(order, " "); got != expect {
+		t.Errorf("expected %q, got %q", expect, got)
+	}
+	if got := strings.Join(base, " "); got != "Namespace Secret Deployment" {
+		t.Errorf("expected the base order to be unchanged, got %q", got)
+	}
+
+	for _, tt := range []struct {
+		placement KindPlacement
+		err       string
+	}{
+		{KindPlacement{Before: "Secret"}, "kind placement without a kind"},
+		{KindPlacement{Kind: "Issuer"}, `kind "Issuer" must be placed either before or after another kind`},
+		{KindPlacement{Kind: "Issuer", Before: "Secret", After: "Secret"}, `kind "Issuer" must be placed either before or after another kind`},
+		{KindPlacement{Kind: "Secret", After: "Secret"}, `kind "Secret" cannot be placed relative to itself`},
+		{KindPlacement{Kind: "Issuer", Before: "Certificate"}, `kind "Issuer" cannot be placed relative to "Certificate", which is not in the order`},
+	} {
+		if _, err := base.Place(tt.placement); err == nil || err.Error() != tt.err {
+			t.Errorf("expected error %q for %+v, got %v", tt.err, tt.placement, err)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"sigs.k8s.io/yaml"
@@ -166,6 +167,8 @@ func (file *manifestFile) sort(result *result) error {
 		}
 
 		hw := calculateHookWeight(entry)
+		retries, backoff := calculateHookRetries(entry)
+		timeout := calculateHookTimeout(entry)
 
 		h := &release.Hook{
 			Name:           entry.Metadata.Name,
@@ -175,6 +178,9 @@ func (file *manifestFile) sort(result *result) error {
 			Events:         []release.HookEvent{},
 			Weight:         hw,
 			DeletePolicies: []release.HookDeletePolicy{},
+			Retries:        retries,
+			RetryBackoff:   backoff,
+			Timeout:        timeout,
 		}
 
 		isUnknownHook := false
@@ -222,6 +228,34 @@ func calculateHookWeight(entry SimpleHead) int {
 	return hw
 }
 
+// calculateHookRetries finds the retries and backoff in the hook retry
+// annotations.
+//
+// Hooks without valid retries are not retried, and invalid backoffs leave the
+// default backoff
+func calculateHookRetries(entry SimpleHead) (int, time.Duration) {
+	retries, err := strconv.Atoi(entry.Metadata.Annotations[release.HookRetriesAnnotation])
+	if err != nil || retries < 0 {
+		return 0, 0
+	}
+	backoff, err := time.ParseDuration(entry.Metadata.Annotations[release.HookRetryBackoffAnnotation])
+	if err != nil || backoff < 0 {
+		backoff = 0
+	}
+	return retries, backoff
+}
+
+// calculateHookTimeout finds the timeout in the hook timeout annotation.
+//
+// Hooks without a valid timeout wait for the timeout of the action.
+func calculateHookTimeout(entry SimpleHead) time.Duration {
+	timeout, err := time.ParseDuration(entry.Metadata.Annotations[release.HookTimeoutAnnotation])
+	if err != nil || timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
 // operateAnnotationValues finds the given annotation and runs the operate function with the value of that annotation
 func operateAnnotationValues(entry SimpleHead, annotation string, operate func(p string)) {
 	if dps, ok := entry.Metadata.Annotations[annotation]; ok {
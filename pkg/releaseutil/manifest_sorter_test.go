@@ -19,6 +19,7 @@ package releaseutil
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"sigs.k8s.io/yaml"
 
@@ -226,3 +227,47 @@ metadata:
 		}
 	}
 }
+
+func TestCalculateHookRetries(t *testing.T) {
+	for _, tt := range []struct {
+		annotations map[string]string
+		retries     int
+		backoff     time.Duration
+	}{
+		{map[string]string{}, 0, 0},
+		{map[string]string{release.HookRetriesAnnotation: "3"}, 3, 0},
+		{map[string]string{release.HookRetriesAnnotation: "3", release.HookRetryBackoffAnnotation: "30s"}, 3, 30 * time.Second},
+		{map[string]string{release.HookRetriesAnnotation: "3", release.HookRetryBackoffAnnotation: "soon"}, 3, 0},
+		{map[string]string{release.HookRetriesAnnotation: "-1", release.HookRetryBackoffAnnotation: "30s"}, 0, 0},
+	} {
+		entry := SimpleHead{Metadata: &struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		}{Annotations: tt.annotations}}
+		retries, backoff := calculateHookRetries(entry)
+		if retries != tt.retries || backoff != tt.backoff {
+			t.Errorf("%v: expected %d retries after %s, got %d after %s", tt.annotations, tt.retries, tt.backoff, retries, backoff)
+		}
+	}
+}
+
+func TestCalculateHookTimeout(t *testing.T) {
+	for annotation, expected := range map[string]time.Duration{
+		"":     0,
+		"30m":  30 * time.Minute,
+		"30":   0,
+		"-10s": 0,
+	} {
+		annotations := map[string]string{}
+		if annotation != "" {
+			annotations[release.HookTimeoutAnnotation] = annotation
+		}
+		entry := SimpleHead{Metadata: &struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		}{Annotations: annotations}}
+		if timeout := calculateHookTimeout(entry); timeout != expected {
+			t.Errorf("%q: expected a timeout of %s, got %s", annotation, expected, timeout)
+		}
+	}
+}
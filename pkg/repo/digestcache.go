@@ -0,0 +1,145 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"helm.sh/helm/v3/internal/fileutil"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// DigestCache remembers the digests of the chart archives of a directory, so
+// that indexing it again only digests the archives that changed.
+//
+// An archive is considered unchanged when its size and modification time are.
+type DigestCache struct {
+	mu      sync.Mutex
+	entries map[string]digestCacheEntry
+}
+
+type digestCacheEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Digest  string    `json:"digest"`
+}
+
+// NewDigestCache returns an empty digest cache.
+func NewDigestCache() *DigestCache {
+	return &DigestCache{entries: map[string]digestCacheEntry{}}
+}
+
+// LoadDigestCache loads the digest cache written to path. A missing file is
+// an empty cache.
+func LoadDigestCache(path string) (*DigestCache, error) {
+	c := NewDigestCache()
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.entries); err != nil {
+		return nil, err
+	}
+	if c.entries == nil {
+		c.entries = map[string]digestCacheEntry{}
+	}
+	return c, nil
+}
+
+// WriteFile writes the digest cache to path.
+func (c *DigestCache) WriteFile(path string, mode os.FileMode) error {
+	c.mu.Lock()
+	b, err := json.Marshal(c.entries)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return fileutil.AtomicWriteFile(path, bytes.NewReader(b), mode)
+}
+
+// lookup returns the cached digest of the archive arch of dir, if it did not
+// change since.
+func (c *DigestCache) lookup(dir, arch string) (string, bool) {
+	key, fi, err := c.stat(dir, arch)
+	if err != nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.Size != fi.Size() || !e.ModTime.Equal(fi.ModTime()) {
+		return "", false
+	}
+	return e.Digest, true
+}
+
+// digest returns the digest of the archive arch of dir, from the cache if it
+// did not change since, and caches it otherwise.
+func (c *DigestCache) digest(dir, arch string) (string, error) {
+	if digest, ok := c.lookup(dir, arch); ok {
+		return digest, nil
+	}
+	key, fi, err := c.stat(dir, arch)
+	if err != nil {
+		return "", err
+	}
+	digest, err := provenance.DigestFile(arch)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.entries[key] = digestCacheEntry{Size: fi.Size(), ModTime: fi.ModTime(), Digest: digest}
+	c.mu.Unlock()
+	return digest, nil
+}
+
+// prune drops the archives of dir other than archives from the cache.
+func (c *DigestCache) prune(dir string, archives []string) {
+	keep := make(map[string]bool, len(archives))
+	for _, arch := range archives {
+		if key, err := filepath.Rel(dir, arch); err == nil {
+			keep[filepath.ToSlash(key)] = true
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if !keep[key] {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *DigestCache) stat(dir, arch string) (string, os.FileInfo, error) {
+	key, err := filepath.Rel(dir, arch)
+	if err != nil {
+		return "", nil, err
+	}
+	fi, err := os.Stat(arch)
+	if err != nil {
+		return "", nil, err
+	}
+	return filepath.ToSlash(key), fi, nil
+}
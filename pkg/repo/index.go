@@ -19,12 +19,15 @@ package repo
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
@@ -129,17 +132,8 @@ func (i IndexFile) MustAdd(md *chart.Metadata, filename, baseURL, digest string)
 		return errors.Wrapf(err, "validate failed for %s", filename)
 	}
 
-	u := filename
-	if baseURL != "" {
-		_, file := filepath.Split(filename)
-		var err error
-		u, err = urlutil.URLJoin(baseURL, file)
-		if err != nil {
-			u = path.Join(baseURL, file)
-		}
-	}
 	cr := &ChartVersion{
-		URLs:     []string{u},
+		URLs:     []string{chartURL(filename, baseURL)},
 		Metadata: md,
 		Digest:   digest,
 		Created:  time.Now(),
@@ -149,6 +143,19 @@ func (i IndexFile) MustAdd(md *chart.Metadata, filename, baseURL, digest string)
 	return nil
 }
 
+// chartURL returns the URL of the chart archive filename relative to baseURL.
+func chartURL(filename, baseURL string) string {
+	if baseURL == "" {
+		return filename
+	}
+	_, file := filepath.Split(filename)
+	u, err := urlutil.URLJoin(baseURL, file)
+	if err != nil {
+		u = path.Join(baseURL, file)
+	}
+	return u
+}
+
 // Add adds a file to the index and logs an error.
 //
 // Deprecated: Use index.MustAdd instead.
@@ -226,11 +233,74 @@ func (i IndexFile) Get(name, version string) (*ChartVersion, error) {
 //
 // The mode on the file is set to 'mode'.
 func (i IndexFile) WriteFile(dest string, mode os.FileMode) error {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(i.encode(w))
+	}()
+	err := fileutil.AtomicWriteFile(dest, r, mode)
+	r.CloseWithError(err)
+	return err
+}
+
+// emptyEntries is how the entries of an index without charts are encoded.
+var emptyEntries = []byte("\nentries: {}\n")
+
+// encode writes the index in YAML format to w, one chart version at a time so
+// that large indexes are not held in memory twice. The output is the same as
+// that of yaml.Marshal.
+func (i IndexFile) encode(w io.Writer) error {
+	entries := i.Entries
+	i.Entries = map[string]ChartVersions{}
 	b, err := yaml.Marshal(i)
 	if err != nil {
 		return err
 	}
-	return fileutil.AtomicWriteFile(dest, bytes.NewReader(b), mode)
+	n := bytes.Index(b, emptyEntries)
+	if len(entries) == 0 || n < 0 {
+		i.Entries = entries
+		b, err := yaml.Marshal(i)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	if _, err := w.Write(b[:n+1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "entries:\n"); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for k, cv := range entries[name] {
+			// Versions are encoded in their place in the document so that they
+			// are indented, and long strings wrapped, the same way.
+			b, err := yaml.Marshal(map[string]map[string]ChartVersions{"entries": {name: {cv}}})
+			if err != nil {
+				return err
+			}
+			// Skip the "entries:" line, and the name of the chart for all but
+			// its first version.
+			skip := 1
+			if k > 0 {
+				skip = 2
+			}
+			for ; skip > 0; skip-- {
+				b = b[bytes.IndexByte(b, '\n')+1:]
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = w.Write(b[n+len(emptyEntries):])
+	return err
 }
 
 // WriteJSONFile writes an index file in JSON format to the given destination
@@ -296,6 +366,44 @@ type ChartVersion struct {
 //
 // The index returned will be in an unsorted state
 func IndexDirectory(dir, baseURL string) (*IndexFile, error) {
+	return IndexDirectoryWithOptions(dir, IndexOptions{BaseURL: baseURL})
+}
+
+// IndexOptions configures how IndexDirectoryWithOptions indexes a directory.
+type IndexOptions struct {
+	// BaseURL is the URL the charts are relative to.
+	BaseURL string
+	// Workers is the number of archives read in parallel. It defaults to the
+	// number of CPUs.
+	Workers int
+	// Cache holds the digests of the archives as of a previous indexing. The
+	// archives that did not change since are not digested again, and the cache
+	// is updated with the others.
+	Cache *DigestCache
+	// Previous is the index the directory was previously indexed into. The
+	// archives that did not change since, according to Cache, and are in it
+	// are not read at all, their entries are reused instead.
+	Previous *IndexFile
+}
+
+// indexedArchive is the result of indexing an archive.
+type indexedArchive struct {
+	fname     string
+	parentURL string
+	md        *chart.Metadata
+	digest    string
+	// reused is the entry of the archive in the previous index.
+	reused *ChartVersion
+	err    error
+}
+
+// IndexDirectoryWithOptions reads a (flat) directory and generates an index,
+// reading the archives in parallel and skipping unchanged ones.
+//
+// It indexes only charts that have been packaged (*.tgz).
+//
+// The index returned will be in an unsorted state
+func IndexDirectoryWithOptions(dir string, opts IndexOptions) (*IndexFile, error) {
 	archives, err := filepath.Glob(filepath.Join(dir, "*.tgz"))
 	if err != nil {
 		return nil, err
@@ -306,38 +414,103 @@ func IndexDirectory(dir, baseURL string) (*IndexFile, error) {
 	}
 	archives = append(archives, moreArchives...)
 
-	index := NewIndexFile()
-	for _, arch := range archives {
-		fname, err := filepath.Rel(dir, arch)
-		if err != nil {
-			return index, err
+	previous := map[string]*ChartVersion{}
+	if opts.Previous != nil {
+		for _, cvs := range opts.Previous.Entries {
+			for _, cv := range cvs {
+				if cv.Digest != "" && len(cv.URLs) > 0 {
+					previous[cv.Digest] = cv
+				}
+			}
 		}
+	}
+	if opts.Cache != nil {
+		opts.Cache.prune(dir, archives)
+	}
 
-		var parentDir string
-		parentDir, fname = filepath.Split(fname)
-		// filepath.Split appends an extra slash to the end of parentDir. We want to strip that out.
-		parentDir = strings.TrimSuffix(parentDir, string(os.PathSeparator))
-		parentURL, err := urlutil.URLJoin(baseURL, parentDir)
-		if err != nil {
-			parentURL = path.Join(baseURL, parentDir)
-		}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	results := make([]indexedArchive, len(archives))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range next {
+				results[n] = indexArchive(dir, archives[n], opts, previous)
+			}
+		}()
+	}
+	for n := range archives {
+		next <- n
+	}
+	close(next)
+	wg.Wait()
 
-		c, err := loader.Load(arch)
-		if err != nil {
-			// Assume this is not a chart.
+	index := NewIndexFile()
+	for _, r := range results {
+		if r.err != nil {
+			return index, r.err
+		}
+		if r.reused != nil {
+			index.Entries[r.reused.Name] = append(index.Entries[r.reused.Name], r.reused)
 			continue
 		}
-		hash, err := provenance.DigestFile(arch)
-		if err != nil {
-			return index, err
+		if r.md == nil {
+			// Assume this is not a chart.
+			continue
 		}
-		if err := index.MustAdd(c.Metadata, fname, parentURL, hash); err != nil {
-			return index, errors.Wrapf(err, "failed adding to %s to index", fname)
+		if err := index.MustAdd(r.md, r.fname, r.parentURL, r.digest); err != nil {
+			return index, errors.Wrapf(err, "failed adding to %s to index", r.fname)
 		}
 	}
 	return index, nil
 }
 
+// indexArchive reads the metadata and digest of an archive of dir, unless its
+// entry in the previous index can be reused.
+func indexArchive(dir, arch string, opts IndexOptions, previous map[string]*ChartVersion) indexedArchive {
+	fname, err := filepath.Rel(dir, arch)
+	if err != nil {
+		return indexedArchive{err: err}
+	}
+	r := indexedArchive{}
+
+	var parentDir string
+	parentDir, r.fname = filepath.Split(fname)
+	// filepath.Split appends an extra slash to the end of parentDir. We want to strip that out.
+	parentDir = strings.TrimSuffix(parentDir, string(os.PathSeparator))
+	r.parentURL, err = urlutil.URLJoin(opts.BaseURL, parentDir)
+	if err != nil {
+		r.parentURL = path.Join(opts.BaseURL, parentDir)
+	}
+
+	if opts.Cache != nil {
+		if digest, ok := opts.Cache.lookup(dir, arch); ok {
+			if cv, ok := previous[digest]; ok && cv.URLs[0] == chartURL(r.fname, r.parentURL) {
+				r.reused = cv
+				return r
+			}
+		}
+	}
+
+	c, err := loader.Open(arch)
+	if err != nil || c.Metadata == nil {
+		// Assume this is not a chart.
+		return r
+	}
+	if opts.Cache != nil {
+		r.digest, r.err = opts.Cache.digest(dir, arch)
+	} else {
+		r.digest, r.err = provenance.DigestFile(arch)
+	}
+	r.md = c.Metadata
+	return r
+}
+
 // loadIndex loads an index file and does minimal validity checking.
 //
 // The source parameter is only used for logging.
@@ -28,6 +28,8 @@ import (
 	"strings"
 	"testing"
 
+	"sigs.k8s.io/yaml"
+
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
@@ -506,6 +508,82 @@ func TestIndexDirectory(t *testing.T) {
 	}
 }
 
+func TestIndexDirectoryWithOptions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"frobnitz-1.2.3.tgz", "sprocket-1.1.0.tgz", "sprocket-1.2.0.tgz"} {
+		b, err := os.ReadFile(filepath.Join("testdata/repository", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache := NewDigestCache()
+	opts := IndexOptions{BaseURL: "http://localhost:8080", Workers: 2, Cache: cache}
+	previous, err := IndexDirectoryWithOptions(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(previous.Entries["sprocket"]); l != 2 {
+		t.Fatalf("Expected 2 versions of sprocket, got %d", l)
+	}
+	if l := len(cache.entries); l != 3 {
+		t.Fatalf("Expected 3 cached digests, got %d", l)
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "digests.json")
+	if err := cache.WriteFile(cachePath, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache, err = LoadDigestCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged archives are not read again, so their entries are kept even
+	// if the archives are not charts anymore as long as they look the same.
+	frobnitz := filepath.Join(dir, "frobnitz-1.2.3.tgz")
+	fi, err := os.Stat(frobnitz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(frobnitz, make([]byte, fi.Size()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(frobnitz, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "sprocket-1.1.0.tgz")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts = IndexOptions{BaseURL: "http://localhost:8080", Cache: cache, Previous: previous}
+	index, err := IndexDirectoryWithOptions(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Entries["frobnitz"][0] != previous.Entries["frobnitz"][0] {
+		t.Error("Expected the entry of the unchanged frobnitz archive to be reused")
+	}
+	if l := len(index.Entries["sprocket"]); l != 1 {
+		t.Errorf("Expected 1 version of sprocket, got %d", l)
+	}
+	if _, ok := cache.entries["sprocket-1.1.0.tgz"]; ok {
+		t.Error("Expected the removed archive to be pruned from the cache")
+	}
+
+	// Without the previous index, the archive is read again.
+	index, err = IndexDirectoryWithOptions(dir, IndexOptions{BaseURL: "http://localhost:8080", Cache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := index.Entries["frobnitz"]; ok {
+		t.Error("Expected the corrupted frobnitz archive to be skipped")
+	}
+}
+
 func TestIndexAdd(t *testing.T) {
 	i := NewIndexFile()
 
@@ -559,6 +637,42 @@ func TestIndexWrite(t *testing.T) {
 	}
 }
 
+func TestIndexWriteMatchesMarshal(t *testing.T) {
+	for _, f := range []string{testfile, annotationstestfile, chartmuseumtestfile, unorderedTestfile} {
+		t.Run(f, func(t *testing.T) {
+			i, err := LoadIndexFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Long strings are wrapped depending on where they are in the document.
+			md := &chart.Metadata{
+				APIVersion:  "v2",
+				Name:        "clipper",
+				Version:     "0.1.0",
+				Description: strings.Repeat("a long description ", 10) + "\n\nwith paragraphs",
+			}
+			if err := i.MustAdd(md, "clipper-0.1.0.tgz", "http://example.com/charts", "sha256:1234567890"); err != nil {
+				t.Fatal(err)
+			}
+			expected, err := yaml.Marshal(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testpath := filepath.Join(t.TempDir(), "index.yaml")
+			if err := i.WriteFile(testpath, 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(testpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(expected) {
+				t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+			}
+		})
+	}
+}
+
 func TestIndexJSONWrite(t *testing.T) {
 	i := NewIndexFile()
 	if err := i.MustAdd(&chart.Metadata{APIVersion: "v2", Name: "clipper", Version: "0.1.0"}, "clipper-0.1.0.tgz", "http://example.com/charts", "sha256:1234567890"); err != nil {
@@ -0,0 +1,412 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/fileutil"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// maxPublishAttempts is how many times the index of a repository is updated
+// before giving up when other publishers keep modifying it concurrently.
+const maxPublishAttempts = 5
+
+// Publisher publishes packaged charts to a chart repository.
+type Publisher interface {
+	// Publish uploads the chart archive at chartPath, along with its
+	// provenance file if there is one next to it, and adds it to the index of
+	// the repository.
+	Publish(chartPath string) error
+}
+
+var (
+	_ Publisher = (*ChartMuseumPublisher)(nil)
+	_ Publisher = (*IndexPublisher)(nil)
+	_ Publisher = (*GitPublisher)(nil)
+)
+
+// ChartMuseumPublisher publishes charts with the upload API of ChartMuseum and
+// compatible servers, which maintain the index of the repository themselves.
+type ChartMuseumPublisher struct {
+	// URL is the URL of the repository, e.g. https://charts.example.com or
+	// https://charts.example.com/org/repo for a multitenant server.
+	URL      string
+	Username string
+	Password string
+	// Force replaces a version of a chart that was already published.
+	Force  bool
+	Client *http.Client
+}
+
+// Publish uploads the chart to the API of the server.
+func (p *ChartMuseumPublisher) Publish(chartPath string) error {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid repository URL %q", p.URL)
+	}
+	// ChartMuseum serves the API of the repository at /a/b under /api/a/b.
+	u.Path = path.Join("/api", u.Path, "charts")
+	if p.Force {
+		u.RawQuery = "force=true"
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	if err := addFormFile(w, "chart", chartPath); err != nil {
+		return err
+	}
+	if _, err := os.Stat(chartPath + ".prov"); err == nil {
+		if err := addFormFile(w, "prov", chartPath+".prov"); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if p.Username != "" || p.Password != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return errors.Errorf("%s is already published to %s, use --force to replace it", filepath.Base(chartPath), p.URL)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("failed to publish %s to %s: %s: %s", filepath.Base(chartPath), p.URL, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func addFormFile(w *multipart.Writer, field, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	part, err := w.CreateFormFile(field, filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
+
+// PublishBackend stores the files of a chart repository whose index is
+// maintained by Helm.
+type PublishBackend interface {
+	// Read returns the content of the named file. The error satisfies
+	// os.IsNotExist if there is no such file.
+	Read(name string) ([]byte, error)
+	// Write stores data as the named file.
+	Write(name string, data []byte) error
+}
+
+// IndexPublisher publishes charts to a PublishBackend, merging them into the
+// index.yaml of the repository.
+//
+// The index is read again after it is written, and the merge is retried if
+// another publisher replaced it in the meantime, so that concurrent
+// publications do not lose each other's charts.
+type IndexPublisher struct {
+	Backend PublishBackend
+	// URL is the URL of the repository, to which the charts are relative in
+	// the index.
+	URL string
+	// Force replaces a version of a chart that was already published.
+	Force bool
+}
+
+// Publish uploads the chart and adds it to the index of the repository.
+func (p *IndexPublisher) Publish(chartPath string) error {
+	md, digest, err := chartDigest(chartPath)
+	if err != nil {
+		return err
+	}
+	index, err := p.readIndex()
+	if err != nil {
+		return err
+	}
+	if index.Has(md.Name, md.Version) && !p.Force {
+		return errors.Errorf("%s %s is already published to %s, use --force to replace it", md.Name, md.Version, p.URL)
+	}
+
+	filename := filepath.Base(chartPath)
+	for _, name := range []string{filename, filename + ".prov"} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(chartPath), name))
+		if os.IsNotExist(err) && name != filename {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.Backend.Write(name, data); err != nil {
+			return errors.Wrapf(err, "failed to upload %s", name)
+		}
+	}
+
+	for attempt := 0; attempt < maxPublishAttempts; attempt++ {
+		if attempt > 0 {
+			if index, err = p.readIndex(); err != nil {
+				return err
+			}
+		}
+		removeChartVersion(index, md.Name, md.Version)
+		if err := index.MustAdd(md, filename, p.URL, digest); err != nil {
+			return err
+		}
+		index.SortEntries()
+		index.Generated = time.Now()
+		data, err := yaml.Marshal(index)
+		if err != nil {
+			return err
+		}
+		if err := p.Backend.Write(indexPath, data); err != nil {
+			return errors.Wrapf(err, "failed to upload %s", indexPath)
+		}
+
+		// Make sure that the index was not concurrently replaced by one
+		// missing the chart.
+		if index, err = p.readIndex(); err != nil {
+			return err
+		}
+		if cv, err := index.Get(md.Name, md.Version); err == nil && cv.Digest == digest {
+			return nil
+		}
+	}
+	return errors.Errorf("failed to add %s %s to the index of %s: it keeps being modified concurrently", md.Name, md.Version, p.URL)
+}
+
+// readIndex returns the index of the repository, or an empty one if there is
+// none yet.
+func (p *IndexPublisher) readIndex() (*IndexFile, error) {
+	data, err := p.Backend.Read(indexPath)
+	if os.IsNotExist(errors.Cause(err)) {
+		return NewIndexFile(), nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the index of %s", p.URL)
+	}
+	return loadIndex(data, p.URL)
+}
+
+// chartDigest returns the metadata and digest of a chart archive.
+func chartDigest(chartPath string) (*chart.Metadata, string, error) {
+	c, err := loader.Open(chartPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if c.Metadata == nil {
+		return nil, "", errors.Errorf("%s is not a chart archive", chartPath)
+	}
+	digest, err := provenance.DigestFile(chartPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return c.Metadata, digest, nil
+}
+
+// removeChartVersion removes a version of a chart from the index.
+func removeChartVersion(i *IndexFile, name, version string) {
+	cvs := i.Entries[name]
+	kept := cvs[:0]
+	for _, cv := range cvs {
+		if cv.Version != version {
+			kept = append(kept, cv)
+		}
+	}
+	if len(kept) > 0 {
+		i.Entries[name] = kept
+	} else {
+		delete(i.Entries, name)
+	}
+}
+
+// DirBackend is a PublishBackend storing files in a local directory.
+type DirBackend string
+
+// Read returns the content of the named file of the directory.
+func (d DirBackend) Read(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(d), name))
+}
+
+// Write atomically replaces the named file of the directory.
+func (d DirBackend) Write(name string, data []byte) error {
+	return fileutil.AtomicWriteFile(filepath.Join(string(d), name), bytes.NewReader(data), 0644)
+}
+
+// CommandBackend is a PublishBackend reading the files of a repository with a
+// Getter, such as the one of a downloader plugin, and writing them with a
+// command, such as the upload command of that plugin.
+type CommandBackend struct {
+	// URL is the URL of the repository.
+	URL    string
+	Getter getter.Getter
+	// Options are the options of the Getter, e.g. its credentials.
+	Options []getter.Option
+	// Upload is the command and arguments run with the path of a local file
+	// and the URL to upload it to appended.
+	Upload []string
+	// Env is the environment of the upload command.
+	Env []string
+}
+
+// Read downloads the named file of the repository.
+func (b *CommandBackend) Read(name string) ([]byte, error) {
+	buf, err := b.Getter.Get(strings.TrimSuffix(b.URL, "/")+"/"+name, b.Options...)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write uploads data as the named file of the repository.
+func (b *CommandBackend) Write(name string, data []byte) error {
+	dir, err := os.MkdirTemp("", "helm-publish-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+
+	argv := append(append([]string{}, b.Upload[1:]...), file, strings.TrimSuffix(b.URL, "/")+"/"+name)
+	cmd := exec.Command(b.Upload[0], argv...)
+	cmd.Env = b.Env
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "upload command %q failed", b.Upload[0])
+	}
+	return nil
+}
+
+// GitPublisher publishes charts to a chart repository served from a git
+// repository, such as GitHub Pages, by committing them to a local clone of it
+// with their index, and pushing the commit.
+//
+// If the push is rejected because another publisher pushed first, the commit
+// is made again on top of theirs.
+type GitPublisher struct {
+	// Dir is the working tree of the clone. It must not have uncommitted
+	// changes.
+	Dir string
+	// URL is the URL the repository is served from, to which the charts are
+	// relative in the index.
+	URL string
+	// Force replaces a version of a chart that was already published.
+	Force bool
+	// Push pulls the upstream branch before committing and pushes the commit.
+	Push bool
+}
+
+// Publish commits the chart and its index to the clone, and pushes them.
+func (p *GitPublisher) Publish(chartPath string) error {
+	md, _, err := chartDigest(chartPath)
+	if err != nil {
+		return err
+	}
+	status, err := p.git("status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		return errors.Errorf("%s has uncommitted changes", p.Dir)
+	}
+
+	msg := fmt.Sprintf("Publish %s %s", md.Name, md.Version)
+	for attempt := 0; attempt < maxPublishAttempts; attempt++ {
+		if p.Push {
+			if _, err := p.git("pull", "--ff-only"); err != nil {
+				return err
+			}
+		}
+		publisher := &IndexPublisher{Backend: DirBackend(p.Dir), URL: p.URL, Force: p.Force}
+		if err := publisher.Publish(chartPath); err != nil {
+			return err
+		}
+		if _, err := p.git("add", "--all"); err != nil {
+			return err
+		}
+		if _, err := p.git("commit", "--quiet", "--message", msg); err != nil {
+			return err
+		}
+		if !p.Push {
+			return nil
+		}
+		_, err := p.git("push", "--quiet")
+		if err == nil {
+			return nil
+		}
+		if _, err := p.git("reset", "--quiet", "--hard", "HEAD~1"); err != nil {
+			return err
+		}
+		// Unless someone else pushed first, in which case the commit is made
+		// again on top of theirs, give up.
+		if !strings.Contains(err.Error(), "rejected") {
+			return err
+		}
+	}
+	return errors.Errorf("failed to push %s %s: the repository keeps being modified concurrently", md.Name, md.Version)
+}
+
+func (p *GitPublisher) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = p.Dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Errorf("git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
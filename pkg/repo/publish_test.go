@@ -0,0 +1,192 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const publishTestChart = "testdata/repository/frobnitz-1.2.3.tgz"
+
+func TestChartMuseumPublisher(t *testing.T) {
+	var published []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/org/charts" {
+			http.NotFound(w, r)
+			return
+		}
+		if user, pass, _ := r.BasicAuth(); user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if published != nil && r.URL.Query().Get("force") != "true" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f, _, err := r.FormFile("chart")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		published, _ = io.ReadAll(f)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	p := &ChartMuseumPublisher{URL: srv.URL + "/org", Username: "user", Password: "pass"}
+	if err := p.Publish(publishTestChart); err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := os.ReadFile(publishTestChart)
+	if string(published) != string(expected) {
+		t.Error("the uploaded chart differs from the archive")
+	}
+
+	if err := p.Publish(publishTestChart); err == nil {
+		t.Error("expected an error publishing the same chart again")
+	}
+	p.Force = true
+	if err := p.Publish(publishTestChart); err != nil {
+		t.Errorf("expected the chart to be replaced, got %s", err)
+	}
+}
+
+func TestIndexPublisher(t *testing.T) {
+	dir := t.TempDir()
+	p := &IndexPublisher{Backend: DirBackend(dir), URL: "https://charts.example.com"}
+
+	if err := p.Publish(publishTestChart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "frobnitz-1.2.3.tgz")); err != nil {
+		t.Error(err)
+	}
+	index, err := LoadIndexFile(filepath.Join(dir, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv, err := index.Get("frobnitz", "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cv.URLs[0] != "https://charts.example.com/frobnitz-1.2.3.tgz" {
+		t.Errorf("unexpected chart URL %q", cv.URLs[0])
+	}
+
+	if err := p.Publish(publishTestChart); err == nil {
+		t.Error("expected an error publishing the same chart again")
+	}
+	p.Force = true
+	if err := p.Publish(publishTestChart); err != nil {
+		t.Fatal(err)
+	}
+	if index, err = LoadIndexFile(filepath.Join(dir, "index.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(index.Entries["frobnitz"]); n != 1 {
+		t.Errorf("expected the chart to be replaced, got %d versions", n)
+	}
+}
+
+// racingBackend replaces the index once, right after it is written, as a
+// concurrent publisher that read it before would.
+type racingBackend struct {
+	DirBackend
+	raced bool
+}
+
+func (b *racingBackend) Write(name string, data []byte) error {
+	if err := b.DirBackend.Write(name, data); err != nil {
+		return err
+	}
+	if name != indexPath || b.raced {
+		return nil
+	}
+	b.raced = true
+	other := NewIndexFile()
+	if err := other.MustAdd(&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "other", Version: "0.1.0"}, "other-0.1.0.tgz", "https://charts.example.com", "sha256:0"); err != nil {
+		return err
+	}
+	return other.WriteFile(filepath.Join(string(b.DirBackend), indexPath), 0644)
+}
+
+func TestIndexPublisherConcurrentUpdate(t *testing.T) {
+	dir := t.TempDir()
+	p := &IndexPublisher{Backend: &racingBackend{DirBackend: DirBackend(dir)}, URL: "https://charts.example.com"}
+
+	if err := p.Publish(publishTestChart); err != nil {
+		t.Fatal(err)
+	}
+	index, err := LoadIndexFile(filepath.Join(dir, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !index.Has("frobnitz", "1.2.3") {
+		t.Error("expected the published chart to be in the index")
+	}
+	if !index.Has("other", "0.1.0") {
+		t.Error("expected the concurrently published chart to be kept in the index")
+	}
+}
+
+func TestGitPublisher(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=helm", "-c", "user.email=helm@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", args, err, out)
+		}
+	}
+
+	remote := t.TempDir()
+	git(remote, "init", "--quiet", "--bare")
+	clone := t.TempDir()
+	git(clone, "clone", "--quiet", remote, ".")
+	git(clone, "commit", "--quiet", "--allow-empty", "--message", "init")
+	git(clone, "push", "--quiet", "origin", "HEAD")
+	t.Setenv("GIT_AUTHOR_NAME", "helm")
+	t.Setenv("GIT_AUTHOR_EMAIL", "helm@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "helm")
+	t.Setenv("GIT_COMMITTER_EMAIL", "helm@example.com")
+
+	p := &GitPublisher{Dir: clone, URL: "https://example.github.io/charts", Push: true}
+	if err := p.Publish(publishTestChart); err != nil {
+		t.Fatal(err)
+	}
+
+	check := t.TempDir()
+	git(check, "clone", "--quiet", remote, ".")
+	index, err := LoadIndexFile(filepath.Join(check, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !index.Has("frobnitz", "1.2.3") {
+		t.Error("expected the published chart to be pushed")
+	}
+}
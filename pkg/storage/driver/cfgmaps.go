@@ -34,6 +34,7 @@ import (
 )
 
 var _ Driver = (*ConfigMaps)(nil)
+var _ Overflower = (*ConfigMaps)(nil)
 
 // ConfigMapsDriverName is the string name of the driver.
 const ConfigMapsDriverName = "ConfigMap"
@@ -218,6 +219,46 @@ func (cfgmaps *ConfigMaps) Delete(key string) (rls *rspb.Release, err error) {
 	return rls, nil
 }
 
+// PutOverflow stores data in a ConfigMap named key. Like the Secrets of
+// overflowSecretType, it is not labeled as owned by Helm.
+func (cfgmaps *ConfigMaps) PutOverflow(key string, data []byte) error {
+	b, err := compressOverflow(data)
+	if err != nil {
+		return errors.Wrapf(err, "put overflow: failed to encode %q", key)
+	}
+	obj := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: key},
+		BinaryData: map[string][]byte{"data": b},
+	}
+	_, err = cfgmaps.impl.Create(context.Background(), obj, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = cfgmaps.impl.Update(context.Background(), obj, metav1.UpdateOptions{})
+	}
+	return errors.Wrapf(err, "put overflow: failed to store %q", key)
+}
+
+// GetOverflow returns the data stored in the ConfigMap named key.
+func (cfgmaps *ConfigMaps) GetOverflow(key string) ([]byte, error) {
+	obj, err := cfgmaps.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "get overflow: failed to get %q", key)
+	}
+	data, err := decompressOverflow(obj.BinaryData["data"])
+	return data, errors.Wrapf(err, "get overflow: failed to decode %q", key)
+}
+
+// DeleteOverflow deletes the ConfigMap named key.
+func (cfgmaps *ConfigMaps) DeleteOverflow(key string) error {
+	err := cfgmaps.impl.Delete(context.Background(), key, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return errors.Wrapf(err, "delete overflow: failed to delete %q", key)
+}
+
 // newConfigMapsObject constructs a kubernetes ConfigMap object
 // to store a release. Each configmap data entry is the base64
 // encoded gzipped string of a release.
@@ -21,18 +21,19 @@ import (
 
 	"github.com/pkg/errors"
 
+	"helm.sh/helm/v3/pkg/errcode"
 	rspb "helm.sh/helm/v3/pkg/release"
 )
 
 var (
 	// ErrReleaseNotFound indicates that a release is not found.
-	ErrReleaseNotFound = errors.New("release: not found")
+	ErrReleaseNotFound error = errcode.New(errcode.ReleaseNotFound)
 	// ErrReleaseExists indicates that a release already exists.
-	ErrReleaseExists = errors.New("release: already exists")
+	ErrReleaseExists error = errcode.New(errcode.ReleaseExists)
 	// ErrInvalidKey indicates that a release key could not be parsed.
 	ErrInvalidKey = errors.New("release: invalid key")
 	// ErrNoDeployedReleases indicates that there are no releases with the given key in the deployed state
-	ErrNoDeployedReleases = errors.New("has no deployed releases")
+	ErrNoDeployedReleases error = errcode.New(errcode.NoDeployedReleases)
 )
 
 // StorageDriverError records an error and the release name that caused it
@@ -92,6 +93,22 @@ type Queryor interface {
 	Query(labels map[string]string) ([]*rspb.Release, error)
 }
 
+// Overflower is the interface of drivers that can store data too large to be
+// kept in the record of a release as separate objects, see
+// storage.Storage.MaxNotesSize.
+//
+// PutOverflow stores data under key, replacing the data stored before.
+//
+// GetOverflow returns the data stored under key or ErrReleaseNotFound.
+//
+// DeleteOverflow deletes the data stored under key. Deleting data that does
+// not exist is not an error.
+type Overflower interface {
+	PutOverflow(key string, data []byte) error
+	GetOverflow(key string) ([]byte, error)
+	DeleteOverflow(key string) error
+}
+
 // Driver is the interface composed of Creator, Updator, Deletor, and Queryor
 // interfaces. It defines the behavior for storing, updating, deleted,
 // and retrieving Helm releases from some underlying storage mechanism,
@@ -0,0 +1,398 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v3/pkg/storage/driver"
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kblabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+var _ Driver = (*HelmReleases)(nil)
+
+// HelmReleasesDriverName is the string name of the driver.
+const HelmReleasesDriverName = "HelmRelease"
+
+// HelmReleaseGroupVersionResource identifies the HelmRelease custom resource
+// defined by HelmReleaseCRD.
+var HelmReleaseGroupVersionResource = schema.GroupVersionResource{
+	Group:    "helm.sh",
+	Version:  "v1",
+	Resource: "helmreleases",
+}
+
+// HelmReleaseCRD is the CustomResourceDefinition of the HelmRelease custom
+// resource used by the HelmReleases driver. It must be installed in the
+// cluster before the driver is used.
+const HelmReleaseCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: helmreleases.helm.sh
+spec:
+  group: helm.sh
+  names:
+    kind: HelmRelease
+    listKind: HelmReleaseList
+    plural: helmreleases
+    singular: helmrelease
+    shortNames:
+    - hr
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+    additionalPrinterColumns:
+    - name: Release
+      type: string
+      jsonPath: .status.name
+    - name: Revision
+      type: integer
+      jsonPath: .status.revision
+    - name: Status
+      type: string
+      jsonPath: .status.status
+    - name: Chart
+      type: string
+      jsonPath: .status.chart
+    - name: App Version
+      type: string
+      jsonPath: .status.appVersion
+    - name: Age
+      type: date
+      jsonPath: .metadata.creationTimestamp
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required:
+            - release
+            properties:
+              release:
+                description: The release record, gzipped and base64 encoded.
+                type: string
+          status:
+            type: object
+            properties:
+              name:
+                type: string
+              revision:
+                type: integer
+              status:
+                type: string
+              chart:
+                type: string
+              appVersion:
+                type: string
+              description:
+                type: string
+              firstDeployed:
+                type: string
+                format: date-time
+              lastDeployed:
+                type: string
+                format: date-time
+`
+
+// HelmReleases is a wrapper around an implementation of a kubernetes dynamic
+// ResourceInterface for HelmRelease custom resources.
+//
+// Each release record is stored in the spec of a HelmRelease, and a summary of
+// it in the status, so that the history of the releases can be inspected,
+// watched and access controlled like any other resource.
+type HelmReleases struct {
+	impl dynamic.ResourceInterface
+	Log  func(string, ...interface{})
+}
+
+// NewHelmReleases initializes a new HelmReleases wrapping an implementation of
+// the kubernetes dynamic ResourceInterface.
+func NewHelmReleases(impl dynamic.ResourceInterface) *HelmReleases {
+	return &HelmReleases{
+		impl: impl,
+		Log:  func(_ string, _ ...interface{}) {},
+	}
+}
+
+// Name returns the name of the driver.
+func (hrs *HelmReleases) Name() string {
+	return HelmReleasesDriverName
+}
+
+// Get fetches the release named by key. The corresponding release is returned
+// or error if not found.
+func (hrs *HelmReleases) Get(key string) (*rspb.Release, error) {
+	obj, err := hrs.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) && !isCRDMissing(err) {
+			return nil, ErrReleaseNotFound
+		}
+
+		hrs.Log("get: failed to get %q: %s", key, err)
+		return nil, wrapCRDMissing(err)
+	}
+	r, err := decodeHelmRelease(obj)
+	if err != nil {
+		hrs.Log("get: failed to decode data %q: %s", key, err)
+		return nil, err
+	}
+	r.Labels = filterSystemLabels(obj.GetLabels())
+	return r, nil
+}
+
+// List fetches all releases and returns the list releases such
+// that filter(release) == true. An error is returned if the
+// HelmReleases fail to be retrieved.
+func (hrs *HelmReleases) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	lsel := kblabels.Set{"owner": "helm"}.AsSelector()
+	opts := metav1.ListOptions{LabelSelector: lsel.String()}
+
+	list, err := hrs.impl.List(context.Background(), opts)
+	if err != nil {
+		hrs.Log("list: failed to list: %s", err)
+		return nil, wrapCRDMissing(err)
+	}
+
+	var results []*rspb.Release
+	for i := range list.Items {
+		item := &list.Items[i]
+		rls, err := decodeHelmRelease(item)
+		if err != nil {
+			hrs.Log("list: failed to decode release %q: %s", item.GetName(), err)
+			continue
+		}
+
+		rls.Labels = item.GetLabels()
+
+		if filter(rls) {
+			results = append(results, rls)
+		}
+	}
+	return results, nil
+}
+
+// Query fetches all releases that match the provided map of labels.
+// An error is returned if the HelmReleases fail to be retrieved.
+func (hrs *HelmReleases) Query(labels map[string]string) ([]*rspb.Release, error) {
+	ls := kblabels.Set{}
+	for k, v := range labels {
+		if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+			return nil, errors.Errorf("invalid label value: %q: %s", v, strings.Join(errs, "; "))
+		}
+		ls[k] = v
+	}
+
+	opts := metav1.ListOptions{LabelSelector: ls.AsSelector().String()}
+
+	list, err := hrs.impl.List(context.Background(), opts)
+	if err != nil {
+		hrs.Log("query: failed to query with labels: %s", err)
+		return nil, wrapCRDMissing(err)
+	}
+
+	if len(list.Items) == 0 {
+		return nil, ErrReleaseNotFound
+	}
+
+	var results []*rspb.Release
+	for i := range list.Items {
+		item := &list.Items[i]
+		rls, err := decodeHelmRelease(item)
+		if err != nil {
+			hrs.Log("query: failed to decode release: %s", err)
+			continue
+		}
+		rls.Labels = item.GetLabels()
+		results = append(results, rls)
+	}
+	return results, nil
+}
+
+// Create creates a new HelmRelease holding the release. If the
+// HelmRelease already exists, ErrReleaseExists is returned.
+func (hrs *HelmReleases) Create(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("createdAt", strconv.Itoa(int(time.Now().Unix())))
+
+	obj, err := newHelmReleaseObject(key, rls, lbs)
+	if err != nil {
+		hrs.Log("create: failed to encode release %q: %s", rls.Name, err)
+		return err
+	}
+	created, err := hrs.impl.Create(context.Background(), obj, metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return ErrReleaseExists
+		}
+
+		hrs.Log("create: failed to create: %s", err)
+		return wrapCRDMissing(err)
+	}
+	return hrs.updateStatus(created, obj)
+}
+
+// Update updates the HelmRelease holding the release.
+func (hrs *HelmReleases) Update(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("modifiedAt", strconv.Itoa(int(time.Now().Unix())))
+
+	obj, err := newHelmReleaseObject(key, rls, lbs)
+	if err != nil {
+		hrs.Log("update: failed to encode release %q: %s", rls.Name, err)
+		return err
+	}
+	current, err := hrs.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		hrs.Log("update: failed to get %q: %s", key, err)
+		return wrapCRDMissing(err)
+	}
+	obj.SetResourceVersion(current.GetResourceVersion())
+	updated, err := hrs.impl.Update(context.Background(), obj, metav1.UpdateOptions{})
+	if err != nil {
+		hrs.Log("update: failed to update: %s", err)
+		return err
+	}
+	return hrs.updateStatus(updated, obj)
+}
+
+// updateStatus sets the status of the stored HelmRelease to the one of obj,
+// as the status subresource is ignored when creating or updating the object.
+func (hrs *HelmReleases) updateStatus(stored, obj *unstructured.Unstructured) error {
+	status, _, _ := unstructured.NestedMap(obj.Object, "status")
+	if err := unstructured.SetNestedMap(stored.Object, status, "status"); err != nil {
+		return err
+	}
+	if _, err := hrs.impl.UpdateStatus(context.Background(), stored, metav1.UpdateOptions{}); err != nil {
+		hrs.Log("failed to update status of %q: %s", stored.GetName(), err)
+		return err
+	}
+	return nil
+}
+
+// Delete deletes the HelmRelease holding the release named by key.
+func (hrs *HelmReleases) Delete(key string) (rls *rspb.Release, err error) {
+	if rls, err = hrs.Get(key); err != nil {
+		return nil, err
+	}
+	if err = hrs.impl.Delete(context.Background(), key, metav1.DeleteOptions{}); err != nil {
+		return rls, err
+	}
+	return rls, nil
+}
+
+// newHelmReleaseObject constructs a HelmRelease object to store a release. The
+// spec holds the base64 encoded gzipped string of the release, and the status
+// a summary of it.
+//
+// The HelmRelease has the same labels as the ConfigMap of the ConfigMaps
+// driver.
+func newHelmReleaseObject(key string, rls *rspb.Release, lbs labels) (*unstructured.Unstructured, error) {
+	const owner = "helm"
+
+	s, err := encodeRelease(rls)
+	if err != nil {
+		return nil, err
+	}
+
+	if lbs == nil {
+		lbs.init()
+	}
+
+	lbs.fromMap(rls.Labels)
+
+	lbs.set("name", rls.Name)
+	lbs.set("owner", owner)
+	lbs.set("status", rls.Info.Status.String())
+	lbs.set("version", strconv.Itoa(rls.Version))
+
+	status := map[string]interface{}{
+		"name":        rls.Name,
+		"revision":    int64(rls.Version),
+		"status":      rls.Info.Status.String(),
+		"description": rls.Info.Description,
+	}
+	if rls.Chart != nil && rls.Chart.Metadata != nil {
+		status["chart"] = rls.Chart.Metadata.Name + "-" + rls.Chart.Metadata.Version
+		status["appVersion"] = rls.Chart.Metadata.AppVersion
+	}
+	if !rls.Info.FirstDeployed.IsZero() {
+		status["firstDeployed"] = rls.Info.FirstDeployed.UTC().Format(time.RFC3339)
+	}
+	if !rls.Info.LastDeployed.IsZero() {
+		status["lastDeployed"] = rls.Info.LastDeployed.UTC().Format(time.RFC3339)
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"release": s},
+		"status": status,
+	}}
+	obj.SetAPIVersion(HelmReleaseGroupVersionResource.GroupVersion().String())
+	obj.SetKind("HelmRelease")
+	obj.SetName(key)
+	obj.SetLabels(lbs.toMap())
+	return obj, nil
+}
+
+// decodeHelmRelease decodes the release stored in the spec of obj.
+func decodeHelmRelease(obj *unstructured.Unstructured) (*rspb.Release, error) {
+	data, found, err := unstructured.NestedString(obj.Object, "spec", "release")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Errorf("HelmRelease %q has no release", obj.GetName())
+	}
+	return decodeRelease(data)
+}
+
+// isCRDMissing tells whether err is the error returned when the HelmRelease
+// CRD is not installed in the cluster. The API server then answers requests
+// with a plain 404 page rather than a NotFound status for a missing object.
+func isCRDMissing(err error) bool {
+	return meta.IsNoMatchError(err) ||
+		(apierrors.IsNotFound(err) && apierrors.HasStatusCause(err, metav1.CauseTypeUnexpectedServerResponse))
+}
+
+func wrapCRDMissing(err error) error {
+	if isCRDMissing(err) {
+		return errors.Wrap(err, "the HelmRelease CustomResourceDefinition (helmreleases.helm.sh) is not installed in the cluster")
+	}
+	return err
+}
@@ -0,0 +1,245 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+func TestHelmReleaseName(t *testing.T) {
+	hrs, _ := newTestFixtureHelmReleases(t)
+	if hrs.Name() != HelmReleasesDriverName {
+		t.Errorf("Expected name to be %q, got %q", HelmReleasesDriverName, hrs.Name())
+	}
+}
+
+func TestHelmReleaseGet(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	hrs, _ := newTestFixtureHelmReleases(t, []*rspb.Release{rel}...)
+
+	// get release with key
+	got, err := hrs.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release: %s", err)
+	}
+	// compare fetched release with original
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	if _, err := hrs.Get("nonexistent"); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestHelmReleaseList(t *testing.T) {
+	hrs, _ := newTestFixtureHelmReleases(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-2", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-4", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-5", 1, "default", rspb.StatusSuperseded),
+		releaseStub("key-6", 1, "default", rspb.StatusSuperseded),
+	}...)
+
+	// list all deployed releases
+	dpl, err := hrs.List(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusDeployed
+	})
+	if err != nil {
+		t.Errorf("Failed to list deployed: %s", err)
+	}
+	if len(dpl) != 2 {
+		t.Errorf("Expected 2 deployed, got %d", len(dpl))
+	}
+	// Check if release having both system and custom labels, this is needed to ensure that selector filtering would work.
+	if _, ok := dpl[0].Labels["name"]; !ok {
+		t.Fatalf("Expected 'name' label in results, actual %v", dpl[0].Labels)
+	}
+	if _, ok := dpl[0].Labels["key1"]; !ok {
+		t.Fatalf("Expected 'key1' label in results, actual %v", dpl[0].Labels)
+	}
+}
+
+func TestHelmReleaseQuery(t *testing.T) {
+	hrs, _ := newTestFixtureHelmReleases(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-2", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+	}...)
+
+	rls, err := hrs.Query(map[string]string{"status": "deployed"})
+	if err != nil {
+		t.Errorf("Failed to query: %s", err)
+	}
+	if len(rls) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(rls))
+	}
+
+	_, err = hrs.Query(map[string]string{"name": "notExist"})
+	if err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestHelmReleaseCreate(t *testing.T) {
+	hrs, client := newTestFixtureHelmReleases(t)
+
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+	rel.Chart = &chart.Chart{Metadata: &chart.Metadata{Name: "pigeon", Version: "0.1.0", AppVersion: "1.0"}}
+
+	// store the release in a HelmRelease
+	if err := hrs.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+	if err := hrs.Create(key, rel); err != ErrReleaseExists {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseExists, err)
+	}
+
+	// get the release back
+	got, err := hrs.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	// the status subresource is updated with a summary of the release
+	var updatedStatus bool
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "update" && action.GetSubresource() == "status" {
+			updatedStatus = true
+		}
+	}
+	if !updatedStatus {
+		t.Error("Expected the status of the HelmRelease to be updated")
+	}
+	obj, err := client.Resource(HelmReleaseGroupVersionResource).Namespace("default").Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, _, _ := unstructured.NestedMap(obj.Object, "status")
+	expected := map[string]interface{}{
+		"name":        name,
+		"revision":    int64(vers),
+		"status":      "deployed",
+		"description": "",
+		"chart":       "pigeon-0.1.0",
+		"appVersion":  "1.0",
+	}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("Expected status %v, got %v", expected, status)
+	}
+}
+
+func TestHelmReleaseUpdate(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	hrs, client := newTestFixtureHelmReleases(t, []*rspb.Release{rel}...)
+
+	// modify release status code
+	rel.Info.Status = rspb.StatusSuperseded
+
+	// perform the update
+	if err := hrs.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+
+	// fetch the updated release
+	got, err := hrs.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if rel.Info.Status != got.Info.Status {
+		t.Errorf("Expected status %s, got status %s", rel.Info.Status.String(), got.Info.Status.String())
+	}
+
+	obj, err := client.Resource(HelmReleaseGroupVersionResource).Namespace("default").Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _, _ := unstructured.NestedString(obj.Object, "status", "status"); s != "superseded" {
+		t.Errorf("Expected summary status superseded, got %q", s)
+	}
+}
+
+func TestHelmReleaseDelete(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	hrs, _ := newTestFixtureHelmReleases(t, []*rspb.Release{rel}...)
+
+	// perform the delete on a non-existent release
+	_, err := hrs.Delete("nonexistent")
+	if err != ErrReleaseNotFound {
+		t.Fatalf("Expected ErrReleaseNotFound: got {%v}", err)
+	}
+
+	// perform the delete
+	rls, err := hrs.Delete(key)
+	if err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, rls) {
+		t.Errorf("Expected {%v}, got {%v}", rel, rls)
+	}
+
+	// fetch the deleted release
+	_, err = hrs.Get(key)
+	if !reflect.DeepEqual(ErrReleaseNotFound, err) {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestHelmReleaseCRDMissing(t *testing.T) {
+	hrs, client := newTestFixtureHelmReleases(t)
+	client.PrependReactor("*", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewGenericServerResponse(http.StatusNotFound, action.GetVerb(), HelmReleaseGroupVersionResource.GroupResource(), "", "404 page not found", 0, true)
+	})
+
+	_, err := hrs.Get(testKey("smug-pigeon", 1))
+	if err == nil || err == ErrReleaseNotFound || !strings.Contains(err.Error(), "CustomResourceDefinition") {
+		t.Errorf("Expected an error about the missing CRD, got {%v}", err)
+	}
+}
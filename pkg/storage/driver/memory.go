@@ -25,6 +25,7 @@ import (
 )
 
 var _ Driver = (*Memory)(nil)
+var _ Overflower = (*Memory)(nil)
 
 const (
 	// MemoryDriverName is the string name of this driver.
@@ -42,6 +43,8 @@ type Memory struct {
 	namespace string
 	// A map of namespaces to releases
 	cache map[string]memReleases
+	// The data stored apart from releases, by namespace and key
+	overflow map[string][]byte
 }
 
 // NewMemory initializes a new memory driver.
@@ -222,6 +225,33 @@ func (mem *Memory) Delete(key string) (*rspb.Release, error) {
 	return nil, ErrReleaseNotFound
 }
 
+// PutOverflow stores data under key in the current namespace.
+func (mem *Memory) PutOverflow(key string, data []byte) error {
+	defer unlock(mem.wlock())
+	if mem.overflow == nil {
+		mem.overflow = map[string][]byte{}
+	}
+	mem.overflow[mem.namespace+"/"+key] = append([]byte(nil), data...)
+	return nil
+}
+
+// GetOverflow returns the data stored under key in the current namespace.
+func (mem *Memory) GetOverflow(key string) ([]byte, error) {
+	defer unlock(mem.rlock())
+	data, ok := mem.overflow[mem.namespace+"/"+key]
+	if !ok {
+		return nil, ErrReleaseNotFound
+	}
+	return data, nil
+}
+
+// DeleteOverflow deletes the data stored under key in the current namespace.
+func (mem *Memory) DeleteOverflow(key string) error {
+	defer unlock(mem.wlock())
+	delete(mem.overflow, mem.namespace+"/"+key)
+	return nil
+}
+
 // wlock locks mem for writing
 func (mem *Memory) wlock() func() {
 	mem.Lock()
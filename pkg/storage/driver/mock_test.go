@@ -29,6 +29,9 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kblabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	rspb "helm.sh/helm/v3/pkg/release"
@@ -80,6 +83,22 @@ func tsFixtureMemory(t *testing.T) *Memory {
 	return mem
 }
 
+// newTestFixtureHelmReleases initializes a fake dynamic client.
+// HelmReleases are created for each release provided.
+func newTestFixtureHelmReleases(t *testing.T, releases ...*rspb.Release) (*HelmReleases, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		HelmReleaseGroupVersionResource: "HelmReleaseList",
+	})
+	hrs := NewHelmReleases(client.Resource(HelmReleaseGroupVersionResource).Namespace("default"))
+	for _, rls := range releases {
+		if err := hrs.Create(testKey(rls.Name, rls.Version), rls); err != nil {
+			t.Fatalf("Failed to create release: %s", err)
+		}
+	}
+	return hrs, client
+}
+
 // newTestFixture initializes a MockConfigMapsInterface.
 // ConfigMaps are created for each release provided.
 func newTestFixtureCfgMaps(t *testing.T, releases ...*rspb.Release) *ConfigMaps {
@@ -34,6 +34,7 @@ import (
 )
 
 var _ Driver = (*Secrets)(nil)
+var _ Overflower = (*Secrets)(nil)
 
 // SecretsDriverName is the string name of the driver.
 const SecretsDriverName = "Secret"
@@ -200,6 +201,51 @@ func (secrets *Secrets) Delete(key string) (rls *rspb.Release, err error) {
 	return rls, err
 }
 
+// overflowSecretType is the type of the Secrets holding the data of releases
+// stored apart from their records. They are not labeled as owned by Helm so
+// they are not taken for releases.
+const overflowSecretType = "helm.sh/release-overflow.v1"
+
+// PutOverflow stores data in a Secret named key.
+func (secrets *Secrets) PutOverflow(key string, data []byte) error {
+	b, err := compressOverflow(data)
+	if err != nil {
+		return errors.Wrapf(err, "put overflow: failed to encode %q", key)
+	}
+	obj := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: key},
+		Type:       overflowSecretType,
+		Data:       map[string][]byte{"data": b},
+	}
+	_, err = secrets.impl.Create(context.Background(), obj, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.impl.Update(context.Background(), obj, metav1.UpdateOptions{})
+	}
+	return errors.Wrapf(err, "put overflow: failed to store %q", key)
+}
+
+// GetOverflow returns the data stored in the Secret named key.
+func (secrets *Secrets) GetOverflow(key string) ([]byte, error) {
+	obj, err := secrets.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "get overflow: failed to get %q", key)
+	}
+	data, err := decompressOverflow(obj.Data["data"])
+	return data, errors.Wrapf(err, "get overflow: failed to decode %q", key)
+}
+
+// DeleteOverflow deletes the Secret named key.
+func (secrets *Secrets) DeleteOverflow(key string) error {
+	err := secrets.impl.Delete(context.Background(), key, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return errors.Wrapf(err, "delete overflow: failed to delete %q", key)
+}
+
 // newSecretsObject constructs a kubernetes Secret object
 // to store a release. Each secret data entry is the base64
 // encoded gzipped string of a release.
@@ -277,7 +277,14 @@ type SQLReleaseCustomLabelWrapper struct {
 
 // NewSQL initializes a new sql driver.
 func NewSQL(connectionString string, logger func(string, ...interface{}), namespace string) (*SQL, error) {
-	db, err := sqlx.Connect(postgreSQLDialect, connectionString)
+	return NewSQLWithPassword(connectionString, nil, logger, namespace)
+}
+
+// NewSQLWithPassword initializes a new sql driver which authenticates each new
+// connection to the database with the password returned by password, e.g. a
+// cloud IAM token. A nil password uses the one of the connection string.
+func NewSQLWithPassword(connectionString string, password SQLPasswordFunc, logger func(string, ...interface{}), namespace string) (*SQL, error) {
+	db, err := ConnectSQL(connectionString, password)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,367 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v3/pkg/storage/driver"
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SQLAuthEnvVar selects how the SQL driver authenticates to the database
+	// when the password is not part of the connection string.
+	SQLAuthEnvVar = "HELM_DRIVER_SQL_AUTH"
+	// SQLAuthRefreshEnvVar overrides how long credentials obtained by
+	// SQLPasswordFromEnv are reused before they are obtained again.
+	SQLAuthRefreshEnvVar = "HELM_DRIVER_SQL_AUTH_REFRESH"
+
+	// defaultSQLAuthRefresh is shorter than the 15 minutes an RDS IAM token is
+	// valid for, so that a token is never used right before it expires.
+	defaultSQLAuthRefresh = 10 * time.Minute
+)
+
+// SQLPasswordFunc returns the password to authenticate a new connection to the
+// database with. It is called each time the connection pool opens a
+// connection, which allows the use of short-lived credentials such as cloud
+// IAM tokens.
+type SQLPasswordFunc func(ctx context.Context) (string, error)
+
+// ConnectSQL connects to the PostgreSQL database described by
+// connectionString. If password is not nil, it provides the password of every
+// connection, overriding any password of the connection string.
+func ConnectSQL(connectionString string, password SQLPasswordFunc) (*sqlx.DB, error) {
+	if password == nil {
+		return sqlx.Connect(postgreSQLDialect, connectionString)
+	}
+	dsn, err := normalizeDSN(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	db := sqlx.NewDb(sql.OpenDB(&sqlConnector{dsn: dsn, password: password}), postgreSQLDialect)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// sqlConnector opens connections with the password returned by password at
+// the time of the connection.
+type sqlConnector struct {
+	dsn      string
+	password SQLPasswordFunc
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	password, err := c.password(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to obtain the password of the SQL database")
+	}
+	// Later keywords of a connection string override earlier ones.
+	connector, err := pq.NewConnector(c.dsn + " password=" + quoteDSNValue(password))
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *sqlConnector) Driver() sqldriver.Driver {
+	return &pq.Driver{}
+}
+
+// SQLPasswordFromEnv returns the SQLPasswordFunc selected by the
+// HELM_DRIVER_SQL_AUTH environment variable, or nil if it is not set:
+//
+//   - "aws-rds-iam" generates RDS IAM authentication tokens for the host, port
+//     and user of the connection string, signed with the AWS credentials and
+//     region of the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+//     AWS_SESSION_TOKEN and AWS_REGION environment variables.
+//   - "exec:<command>" runs the command and uses its output, e.g.
+//     "exec:gcloud sql generate-login-token" for Cloud SQL IAM authentication.
+//   - "file:<path>" reads the file at path, e.g. a token kept up to date by a
+//     sidecar. The file is read for every new connection.
+//
+// Tokens generated or returned by a command are reused for 10 minutes, or for
+// the duration of the HELM_DRIVER_SQL_AUTH_REFRESH environment variable.
+func SQLPasswordFromEnv(connectionString string) (SQLPasswordFunc, error) {
+	auth := os.Getenv(SQLAuthEnvVar)
+	if auth == "" {
+		return nil, nil
+	}
+	refresh := defaultSQLAuthRefresh
+	if v := os.Getenv(SQLAuthRefreshEnvVar); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", SQLAuthRefreshEnvVar)
+		}
+		refresh = d
+	}
+
+	switch {
+	case auth == "aws-rds-iam":
+		dsn, err := normalizeDSN(connectionString)
+		if err != nil {
+			return nil, err
+		}
+		opts := parseDSN(dsn)
+		if opts["host"] == "" || opts["user"] == "" {
+			return nil, errors.New("the connection string must set the host and user for RDS IAM authentication")
+		}
+		port := opts["port"]
+		if port == "" {
+			port = "5432"
+		}
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+		if region == "" {
+			return nil, errors.New("AWS_REGION must be set for RDS IAM authentication")
+		}
+		return CachedSQLPassword(RDSIAMPassword(net.JoinHostPort(opts["host"], port), region, opts["user"]), refresh), nil
+	case strings.HasPrefix(auth, "exec:"):
+		command := strings.Fields(strings.TrimPrefix(auth, "exec:"))
+		if len(command) == 0 {
+			return nil, errors.Errorf("%s command is empty", SQLAuthEnvVar)
+		}
+		return CachedSQLPassword(ExecSQLPassword(command[0], command[1:]...), refresh), nil
+	case strings.HasPrefix(auth, "file:"):
+		path := strings.TrimPrefix(auth, "file:")
+		if path == "" {
+			return nil, errors.Errorf("%s file path is empty", SQLAuthEnvVar)
+		}
+		return FileSQLPassword(path), nil
+	default:
+		return nil, errors.Errorf("unknown %s %q: must be aws-rds-iam, exec:<command> or file:<path>", SQLAuthEnvVar, auth)
+	}
+}
+
+// CachedSQLPassword reuses the password returned by password for ttl.
+func CachedSQLPassword(password SQLPasswordFunc, ttl time.Duration) SQLPasswordFunc {
+	var (
+		mu      sync.Mutex
+		cached  string
+		expires time.Time
+	)
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Now().Before(expires) {
+			return cached, nil
+		}
+		p, err := password(ctx)
+		if err != nil {
+			return "", err
+		}
+		cached, expires = p, time.Now().Add(ttl)
+		return cached, nil
+	}
+}
+
+// ExecSQLPassword returns the output of the command, without surrounding
+// whitespace.
+func ExecSQLPassword(name string, args ...string) SQLPasswordFunc {
+	return func(ctx context.Context) (string, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to run %s", name)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+}
+
+// FileSQLPassword returns the content of the file at path, without
+// surrounding whitespace.
+func FileSQLPassword(path string) SQLPasswordFunc {
+	return func(context.Context) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+}
+
+// RDSIAMPassword generates an authentication token for the user of the RDS
+// database at endpoint (host:port), signed with the credentials of the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+// variables. Tokens are valid for 15 minutes.
+//
+// Applications with other sources of AWS credentials can generate tokens with
+// the AWS SDK instead and pass them as an SQLPasswordFunc.
+func RDSIAMPassword(endpoint, region, user string) SQLPasswordFunc {
+	return func(context.Context) (string, error) {
+		creds := awsCredentials{
+			accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if creds.accessKeyID == "" || creds.secretAccessKey == "" {
+			return "", errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for RDS IAM authentication")
+		}
+		return rdsAuthToken(endpoint, region, user, creds, time.Now()), nil
+	}
+}
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// rdsAuthToken presigns a connect request to endpoint with AWS Signature
+// Version 4, which is what an RDS IAM authentication token is.
+func rdsAuthToken(endpoint, region, user string, creds awsCredentials, now time.Time) string {
+	const (
+		service   = "rds-db"
+		algorithm = "AWS4-HMAC-SHA256"
+	)
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+
+	query := map[string]string{
+		"Action":              "connect",
+		"DBUser":              user,
+		"X-Amz-Algorithm":     algorithm,
+		"X-Amz-Credential":    creds.accessKeyID + "/" + scope,
+		"X-Amz-Date":          now.Format("20060102T150405Z"),
+		"X-Amz-Expires":       "900",
+		"X-Amz-SignedHeaders": "host",
+	}
+	if creds.sessionToken != "" {
+		query["X-Amz-Security-Token"] = creds.sessionToken
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, awsURIEncode(k)+"="+awsURIEncode(query[k]))
+	}
+	canonicalQuery := strings.Join(params, "&")
+
+	emptyPayload := sha256.Sum256(nil)
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		canonicalQuery,
+		"host:" + endpoint + "\n",
+		"host",
+		hex.EncodeToString(emptyPayload[:]),
+	}, "\n")
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		algorithm,
+		query["X-Amz-Date"],
+		scope,
+		hex.EncodeToString(hashedRequest[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return endpoint + "/?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEncode escapes s as required by AWS Signature Version 4, which only
+// leaves the unreserved characters of RFC 3986 as is.
+func awsURIEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// normalizeDSN converts a postgres:// URL to a keyword/value connection
+// string, to which keywords can then be appended.
+func normalizeDSN(connectionString string) (string, error) {
+	if strings.HasPrefix(connectionString, "postgres://") || strings.HasPrefix(connectionString, "postgresql://") {
+		dsn, err := pq.ParseURL(connectionString)
+		return dsn, errors.Wrap(err, "invalid SQL connection string")
+	}
+	return connectionString, nil
+}
+
+// parseDSN returns the keywords and values of a keyword/value connection
+// string.
+func parseDSN(dsn string) map[string]string {
+	opts := map[string]string{}
+	s := strings.TrimSpace(dsn)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " ")
+		var value strings.Builder
+		if strings.HasPrefix(s, "'") {
+			i := 1
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ' ')
+			if end < 0 {
+				end = len(s)
+			}
+			value.WriteString(s[:end])
+			s = s[end:]
+		}
+		opts[key] = value.String()
+		s = strings.TrimLeft(s, " ")
+	}
+	return opts
+}
+
+// quoteDSNValue quotes a value of a keyword/value connection string.
+func quoteDSNValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
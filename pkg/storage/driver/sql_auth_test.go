@@ -0,0 +1,127 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRDSAuthToken(t *testing.T) {
+	creds := awsCredentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "secret", sessionToken: "session/token"}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	token := rdsAuthToken("db.example.com:5432", "us-east-1", "helm user", creds, now)
+
+	prefix := "db.example.com:5432/?Action=connect&DBUser=helm%20user&X-Amz-Algorithm=AWS4-HMAC-SHA256" +
+		"&X-Amz-Credential=AKIDEXAMPLE%2F20240102%2Fus-east-1%2Frds-db%2Faws4_request" +
+		"&X-Amz-Date=20240102T030405Z&X-Amz-Expires=900" +
+		"&X-Amz-Security-Token=session%2Ftoken&X-Amz-SignedHeaders=host&X-Amz-Signature="
+	if !strings.HasPrefix(token, prefix) {
+		t.Fatalf("unexpected token %q", token)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{64}$`).MatchString(strings.TrimPrefix(token, prefix)) {
+		t.Errorf("invalid signature in token %q", token)
+	}
+	if token != rdsAuthToken("db.example.com:5432", "us-east-1", "helm user", creds, now) {
+		t.Error("expected the same token for the same request")
+	}
+	if token == rdsAuthToken("db.example.com:5432", "us-east-1", "helm user", creds, now.Add(time.Second)) {
+		t.Error("expected a different token at a different time")
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	dsn, err := normalizeDSN("postgres://helm@db.example.com:6543/releases?sslmode=require")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := parseDSN(dsn + " password=" + quoteDSNValue(`it's a \secret`))
+	expected := map[string]string{
+		"host":     "db.example.com",
+		"port":     "6543",
+		"user":     "helm",
+		"dbname":   "releases",
+		"sslmode":  "require",
+		"password": `it's a \secret`,
+	}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("expected %v, got %v", expected, opts)
+	}
+}
+
+func TestCachedSQLPassword(t *testing.T) {
+	calls := 0
+	password := CachedSQLPassword(func(context.Context) (string, error) {
+		calls++
+		return "token", nil
+	}, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		p, err := password(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != "token" {
+			t.Errorf("expected token, got %q", p)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the password to be obtained once, got %d", calls)
+	}
+}
+
+func TestSQLPasswordFromEnv(t *testing.T) {
+	t.Setenv(SQLAuthEnvVar, "")
+	if password, err := SQLPasswordFromEnv("host=db user=helm"); err != nil || password != nil {
+		t.Errorf("expected no password, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(SQLAuthEnvVar, "file:"+path)
+	password, err := SQLPasswordFromEnv("host=db user=helm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := password(context.Background()); err != nil || p != "token" {
+		t.Errorf("expected token, got %q (%v)", p, err)
+	}
+
+	t.Setenv(SQLAuthEnvVar, "aws-rds-iam")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	if _, err := SQLPasswordFromEnv("host=db user=helm"); err == nil {
+		t.Error("expected an error without a region")
+	}
+	if _, err := SQLPasswordFromEnv("host=db"); err == nil {
+		t.Error("expected an error without a user")
+	}
+
+	t.Setenv(SQLAuthEnvVar, "kerberos")
+	if _, err := SQLPasswordFromEnv("host=db user=helm"); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
@@ -34,6 +34,10 @@ var systemLabels = []string{"name", "owner", "status", "version", "createdAt", "
 
 // encodeRelease encodes a release returning a base64 encoded
 // gzipped string representation, or error.
+//
+// The release is encoded as JSON, which records the schema version of the
+// release and keeps the fields written by newer versions of Helm that this
+// one does not know about, see rspb.CurrentSchemaVersion.
 func encodeRelease(rls *rspb.Release) (string, error) {
 	b, err := json.Marshal(rls)
 	if err != nil {
@@ -86,6 +90,32 @@ func decodeRelease(data string) (*rspb.Release, error) {
 	return &rls, nil
 }
 
+// compressOverflow gzips the data of a release stored apart from its record.
+func compressOverflow(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressOverflow reverses compressOverflow.
+func decompressOverflow(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
 // Checks if label is system
 func isSystemLabel(key string) bool {
 	for _, v := range GetSystemLabels() {
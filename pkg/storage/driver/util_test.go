@@ -14,8 +14,11 @@ limitations under the License.
 package driver
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
+
+	rspb "helm.sh/helm/v3/pkg/release"
 )
 
 func TestGetSystemLabel(t *testing.T) {
@@ -106,3 +109,84 @@ func TestContainsSystemLabels(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeReleaseUnknownFields(t *testing.T) {
+	var rls rspb.Release
+	if err := json.Unmarshal([]byte(`{"name":"test","version":1,"schema_version":99,"future":"value"}`), &rls); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := encodeRelease(&rls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeRelease(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SchemaVersion != 99 || !decoded.NewerSchema() {
+		t.Errorf("expected schema version 99, got %d", decoded.SchemaVersion)
+	}
+
+	out, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"future":"value","name":"test","schema_version":99,"version":1}`
+	if string(out) != expect {
+		t.Errorf("expected %s, got %s", expect, out)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	drivers := map[string]interface {
+		Driver
+		Overflower
+	}{
+		"secrets":    newTestFixtureSecrets(t, rel),
+		"configmaps": newTestFixtureCfgMaps(t, rel),
+		"memory":     tsFixtureMemory(t),
+	}
+	for name, d := range drivers {
+		t.Run(name, func(t *testing.T) {
+			key := testKey("smug-pigeon", 1) + ".notes"
+			if _, err := d.GetOverflow(key); err != ErrReleaseNotFound {
+				t.Fatalf("Expected ErrReleaseNotFound, got {%v}", err)
+			}
+			for _, data := range []string{"first notes", "second notes"} {
+				if err := d.PutOverflow(key, []byte(data)); err != nil {
+					t.Fatalf("Failed to put overflow: %s", err)
+				}
+				got, err := d.GetOverflow(key)
+				if err != nil {
+					t.Fatalf("Failed to get overflow: %s", err)
+				}
+				if string(got) != data {
+					t.Errorf("Expected %q, got %q", data, got)
+				}
+			}
+
+			// The overflow is not taken for a release.
+			rels, err := d.List(func(_ *rspb.Release) bool { return true })
+			if err != nil {
+				t.Fatalf("Failed to list releases: %s", err)
+			}
+			for _, r := range rels {
+				if r.Name == "" {
+					t.Errorf("Expected only releases, got {%v}", r)
+				}
+			}
+
+			if err := d.DeleteOverflow(key); err != nil {
+				t.Fatalf("Failed to delete overflow: %s", err)
+			}
+			if err := d.DeleteOverflow(key); err != nil {
+				t.Errorf("Expected deleting missing overflow to succeed, got {%v}", err)
+			}
+			if _, err := d.GetOverflow(key); err != ErrReleaseNotFound {
+				t.Errorf("Expected ErrReleaseNotFound, got {%v}", err)
+			}
+		})
+	}
+}
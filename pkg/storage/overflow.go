@@ -0,0 +1,346 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	rspb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// truncatedNotes marks notes truncated to MaxNotesSize.
+const truncatedNotes = "\n[notes truncated]"
+
+// spill returns the release to store under key. If its notes or manifest
+// exceed their maximum size, or its chart marks values as sensitive, it is a
+// copy without them, which are stored apart. The rendered manifests and notes
+// of a release with sensitive values are stored with them, unless the records
+// are stored in the same confidential store. Records with parts stored apart
+// are stamped with rspb.SchemaVersionOverflow. rls itself is never changed.
+//
+// The parts of a release read without them, see restoreAll, stay stored
+// apart.
+func (s *Storage) spill(key string, rls *rspb.Release) (*rspb.Release, error) {
+	notes := s.MaxNotesSize > 0 && rls.Info != nil && len(rls.Info.Notes) > s.MaxNotesSize
+	manifest := s.MaxManifestSize > 0 && len(rls.Manifest) > s.MaxManifestSize
+	var sensitive []string
+	if s.Sensitive != nil && len(rls.Config) > 0 {
+		paths, err := chartutil.SensitiveValues(rls.Chart)
+		if err != nil {
+			return nil, err
+		}
+		sensitive = paths
+	}
+	if !notes && !manifest && len(sensitive) == 0 && len(rls.Overflow) == 0 {
+		return rls, nil
+	}
+
+	stored := *rls
+	stored.Overflow = append([]string(nil), rls.Overflow...)
+	if len(sensitive) > 0 {
+		rest, values := splitValues(rls.Config, sensitive)
+		if len(values) > 0 {
+			data, err := json.Marshal(values)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to encode the sensitive values of release %q", key)
+			}
+			if err := s.Sensitive.PutOverflow(overflowKey(key, rspb.OverflowValues), data); err != nil {
+				return nil, errors.Wrapf(err, "unable to store the sensitive values of release %q", key)
+			}
+			stored.Config = rest
+			stored.Overflow = append(stored.Overflow, rspb.OverflowValues)
+			if !s.confidentialRecords() {
+				if err := s.spillRendered(key, rls, &stored); err != nil {
+					return nil, err
+				}
+				notes, manifest = false, false
+			}
+		}
+	}
+	o, ok := s.overflower()
+	if notes {
+		info := *rls.Info
+		stored.Info = &info
+		if ok {
+			if err := o.PutOverflow(overflowKey(key, rspb.OverflowNotes), []byte(info.Notes)); err != nil {
+				return nil, errors.Wrapf(err, "unable to store the notes of release %q", key)
+			}
+			info.Notes = ""
+			stored.Overflow = append(stored.Overflow, rspb.OverflowNotes)
+		} else {
+			s.Log("truncating the notes of release %q to %d bytes", key, s.MaxNotesSize)
+			info.Notes = truncate(info.Notes, s.MaxNotesSize) + truncatedNotes
+		}
+	}
+	if manifest {
+		if ok {
+			if err := o.PutOverflow(overflowKey(key, rspb.OverflowManifest), []byte(rls.Manifest)); err != nil {
+				return nil, errors.Wrapf(err, "unable to store the manifest of release %q", key)
+			}
+			stored.Manifest = ""
+			stored.Overflow = append(stored.Overflow, rspb.OverflowManifest)
+		} else {
+			// Unlike notes, the manifest is needed to upgrade and uninstall
+			// the release, so it is kept whole.
+			s.Log("the manifest of release %q exceeds %d bytes, but the %s driver cannot store it apart", key, s.MaxManifestSize, s.Driver.Name())
+		}
+	}
+	if len(stored.Overflow) > 0 {
+		stored.RequireSchema(rspb.SchemaVersionOverflow)
+	}
+	return &stored, nil
+}
+
+// spillRendered stores the manifest, the manifests of the hooks and the notes
+// of the release in the sensitive store, and removes them from stored.
+func (s *Storage) spillRendered(key string, rls, stored *rspb.Release) error {
+	if err := s.Sensitive.PutOverflow(overflowKey(key, rspb.OverflowSensitiveManifest), []byte(rls.Manifest)); err != nil {
+		return errors.Wrapf(err, "unable to store the manifest of release %q", key)
+	}
+	stored.Manifest = ""
+	stored.Overflow = append(stored.Overflow, rspb.OverflowSensitiveManifest)
+
+	if len(rls.Hooks) > 0 {
+		manifests := make([]string, len(rls.Hooks))
+		stored.Hooks = make([]*rspb.Hook, len(rls.Hooks))
+		for i, h := range rls.Hooks {
+			manifests[i] = h.Manifest
+			hook := *h
+			hook.Manifest = ""
+			stored.Hooks[i] = &hook
+		}
+		data, err := json.Marshal(manifests)
+		if err != nil {
+			return errors.Wrapf(err, "unable to encode the hooks of release %q", key)
+		}
+		if err := s.Sensitive.PutOverflow(overflowKey(key, rspb.OverflowSensitiveHooks), data); err != nil {
+			return errors.Wrapf(err, "unable to store the hooks of release %q", key)
+		}
+		stored.Overflow = append(stored.Overflow, rspb.OverflowSensitiveHooks)
+	}
+
+	if rls.Info != nil && rls.Info.Notes != "" {
+		if err := s.Sensitive.PutOverflow(overflowKey(key, rspb.OverflowSensitiveNotes), []byte(rls.Info.Notes)); err != nil {
+			return errors.Wrapf(err, "unable to store the notes of release %q", key)
+		}
+		info := *rls.Info
+		info.Notes = ""
+		stored.Info = &info
+		stored.Overflow = append(stored.Overflow, rspb.OverflowSensitiveNotes)
+	}
+	return nil
+}
+
+// restore returns the release stored under key with the parts stored apart
+// from its record. If there are any, it is a copy of rls.
+func (s *Storage) restore(key string, rls *rspb.Release) (*rspb.Release, error) {
+	if len(rls.Overflow) == 0 {
+		return rls, nil
+	}
+
+	restored := *rls
+	restored.Overflow = nil
+	// The parts stored apart are what the record needed the schema for.
+	if restored.SchemaVersion == rspb.SchemaVersionOverflow {
+		restored.SchemaVersion = rspb.SchemaVersionBase
+	}
+	for _, part := range rls.Overflow {
+		o, ok := s.partStore(part)
+		if !ok {
+			return nil, errors.Errorf("release %q has its %s stored apart, which the %s driver cannot read", key, part, s.Driver.Name())
+		}
+		data, err := o.GetOverflow(overflowKey(key, part))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read the %s of release %q", part, key)
+		}
+		switch part {
+		case rspb.OverflowNotes, rspb.OverflowSensitiveNotes:
+			info := *rls.Info
+			info.Notes = string(data)
+			restored.Info = &info
+		case rspb.OverflowManifest, rspb.OverflowSensitiveManifest:
+			restored.Manifest = string(data)
+		case rspb.OverflowValues:
+			var values map[string]interface{}
+			if err := json.Unmarshal(data, &values); err != nil {
+				return nil, errors.Wrapf(err, "unable to decode the sensitive values of release %q", key)
+			}
+			restored.Config = mergeValues(rls.Config, values)
+		case rspb.OverflowSensitiveHooks:
+			var manifests []string
+			if err := json.Unmarshal(data, &manifests); err != nil {
+				return nil, errors.Wrapf(err, "unable to decode the hooks of release %q", key)
+			}
+			if len(manifests) != len(rls.Hooks) {
+				return nil, errors.Errorf("release %q has %d hooks, but %d hook manifests are stored apart", key, len(rls.Hooks), len(manifests))
+			}
+			restored.Hooks = make([]*rspb.Hook, len(rls.Hooks))
+			for i, h := range rls.Hooks {
+				hook := *h
+				hook.Manifest = manifests[i]
+				restored.Hooks[i] = &hook
+			}
+		}
+	}
+	return &restored, nil
+}
+
+// restoreAll restores the parts of the releases stored apart from their
+// records. The latest revision of every release must be restored, but older
+// revisions whose parts cannot be read, e.g. because they were lost, are kept
+// without them, so that listing the history and pruning it still work.
+func (s *Storage) restoreAll(ls []*rspb.Release) ([]*rspb.Release, error) {
+	latest := map[string]int{}
+	for _, rls := range ls {
+		if id := rls.Namespace + "/" + rls.Name; rls.Version > latest[id] {
+			latest[id] = rls.Version
+		}
+	}
+	for i, rls := range ls {
+		key := makeKey(rls.Name, rls.Version)
+		restored, err := s.restore(key, rls)
+		if err != nil {
+			if rls.Version == latest[rls.Namespace+"/"+rls.Name] {
+				return nil, err
+			}
+			s.Log("reading release %q without the parts stored apart from its record: %s", key, err)
+			continue
+		}
+		ls[i] = restored
+	}
+	return ls, nil
+}
+
+// partStore returns where the part of releases is stored apart.
+func (s *Storage) partStore(part string) (driver.Overflower, bool) {
+	switch part {
+	case rspb.OverflowValues, rspb.OverflowSensitiveManifest, rspb.OverflowSensitiveHooks, rspb.OverflowSensitiveNotes:
+		return s.Sensitive, s.Sensitive != nil
+	}
+	return s.overflower()
+}
+
+// confidentialRecords returns whether the records of releases are stored in
+// the store keeping sensitive values confidential.
+func (s *Storage) confidentialRecords() bool {
+	o, ok := s.overflower()
+	return ok && o == s.Sensitive
+}
+
+// overflower returns the driver storing the parts of releases apart from
+// their records, looking through drivers wrapping others.
+func (s *Storage) overflower() (driver.Overflower, bool) {
+	d := s.Driver
+	for {
+		if o, ok := d.(driver.Overflower); ok {
+			return o, true
+		}
+		w, ok := d.(interface{ Unwrap() driver.Driver })
+		if !ok {
+			return nil, false
+		}
+		d = w.Unwrap()
+	}
+}
+
+// overflowKey is the key a part of the release stored under key is stored
+// apart under.
+func overflowKey(key, part string) string {
+	return key + "." + part
+}
+
+// truncate cuts s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// splitValues splits the values into the values at the paths, as
+// dot-separated keys, and the rest. vals itself is never changed.
+func splitValues(vals map[string]interface{}, paths []string) (rest, sensitive map[string]interface{}) {
+	rest = copyValues(vals)
+	sensitive = map[string]interface{}{}
+	for _, p := range paths {
+		moveValue(rest, sensitive, strings.Split(p, "."))
+	}
+	return rest, sensitive
+}
+
+// moveValue moves the value at the path from src to dst, copying the maps of
+// src on the way as they may be shared with the original values.
+func moveValue(src, dst map[string]interface{}, path []string) {
+	v, ok := src[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		dst[path[0]] = v
+		delete(src, path[0])
+		return
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	m = copyValues(m)
+	src[path[0]] = m
+	d, ok := dst[path[0]].(map[string]interface{})
+	if !ok {
+		d = map[string]interface{}{}
+	}
+	moveValue(m, d, path[1:])
+	if len(d) > 0 {
+		dst[path[0]] = d
+	}
+}
+
+// mergeValues returns a copy of the values with the sensitive values split
+// from them put back.
+func mergeValues(vals, sensitive map[string]interface{}) map[string]interface{} {
+	out := copyValues(vals)
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	for k, v := range sensitive {
+		sub, ok := v.(map[string]interface{})
+		if cur, isMap := out[k].(map[string]interface{}); ok && isMap {
+			out[k] = mergeValues(cur, sub)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func copyValues(vals map[string]interface{}) map[string]interface{} {
+	if vals == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(vals))
+	for k, v := range vals {
+		out[k] = v
+	}
+	return out
+}
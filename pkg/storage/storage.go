@@ -22,6 +22,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"helm.sh/helm/v3/internal/version"
 	rspb "helm.sh/helm/v3/pkg/release"
 	relutil "helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage/driver"
@@ -43,6 +44,30 @@ type Storage struct {
 	// ignored (meaning no limits are imposed).
 	MaxHistory int
 
+	// MaxNotesSize and MaxManifestSize are the sizes in bytes above which the
+	// notes and the manifest of a release are stored apart from its record,
+	// if the driver supports it, see driver.Overflower. Otherwise notes are
+	// truncated to MaxNotesSize. Values of 0 or less impose no limit.
+	MaxNotesSize    int
+	MaxManifestSize int
+
+	// Sensitive stores the values the charts of releases mark as sensitive
+	// apart from the records of the releases, see
+	// chartutil.SensitiveValues. It must keep them confidential, e.g. in
+	// Secrets. Sensitive values are kept in the records if nil.
+	//
+	// If Sensitive is not the driver storing the records, e.g. Secrets for
+	// records in ConfigMaps, the manifest, the manifests of the hooks and the
+	// notes of a release with sensitive values are stored there too, as they
+	// may hold them rendered. Helm only stores them apart from such records
+	// when asked to, see action.Configuration.SensitiveSecrets.
+	Sensitive driver.Overflower
+
+	// HelmVersion is the Helm version releases are stamped with when they
+	// are written, see rspb.Release.Stamp. Init sets it to the version of
+	// this Helm.
+	HelmVersion string
+
 	Log func(string, ...interface{})
 }
 
@@ -50,53 +75,118 @@ type Storage struct {
 // if the storage driver failed to fetch the release, or the
 // release identified by the key, version pair does not exist.
 func (s *Storage) Get(name string, version int) (*rspb.Release, error) {
-	s.Log("getting release %q", makeKey(name, version))
-	return s.Driver.Get(makeKey(name, version))
+	key := makeKey(name, version)
+	s.Log("getting release %q", key)
+	rls, err := s.Driver.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.restore(key, rls)
 }
 
 // Create creates a new storage entry holding the release. An
 // error is returned if the storage driver fails to store the
-// release, or a release with an identical key already exists.
+// release, or a release with an identical key already exists. The release is
+// stamped with the Helm version and schema version it is written with.
 func (s *Storage) Create(rls *rspb.Release) error {
+	return s.CreateWithMaxHistory(rls, s.MaxHistory)
+}
+
+// CreateWithMaxHistory is like Create, but keeps at most maxHistory revisions
+// of the release instead of MaxHistory. A maxHistory of zero keeps all
+// revisions. Unlike setting MaxHistory, this is safe for concurrent use.
+func (s *Storage) CreateWithMaxHistory(rls *rspb.Release, maxHistory int) error {
 	s.Log("creating release %q", makeKey(rls.Name, rls.Version))
-	if s.MaxHistory > 0 {
+	if maxHistory > 0 {
 		// Want to make space for one more release.
-		if err := s.removeLeastRecent(rls.Name, s.MaxHistory-1); err != nil &&
+		if err := s.removeLeastRecent(rls.Name, maxHistory-1); err != nil &&
 			!errors.Is(err, driver.ErrReleaseNotFound) {
 			return err
 		}
 	}
-	return s.Driver.Create(makeKey(rls.Name, rls.Version), rls)
+	rls.Stamp(s.HelmVersion)
+	key := makeKey(rls.Name, rls.Version)
+	stored, err := s.spill(key, rls)
+	if err != nil {
+		return err
+	}
+	return s.Driver.Create(key, stored)
 }
 
 // Update updates the release in storage. An error is returned if the
 // storage backend fails to update the release or if the release
 // does not exist.
 func (s *Storage) Update(rls *rspb.Release) error {
-	s.Log("updating release %q", makeKey(rls.Name, rls.Version))
-	return s.Driver.Update(makeKey(rls.Name, rls.Version), rls)
+	key := makeKey(rls.Name, rls.Version)
+	s.Log("updating release %q", key)
+	rls.Stamp(s.HelmVersion)
+	stored, err := s.spill(key, rls)
+	if err != nil {
+		return err
+	}
+	return s.Driver.Update(key, stored)
 }
 
 // Delete deletes the release from storage. An error is returned if
 // the storage backend fails to delete the release or if the release
 // does not exist.
 func (s *Storage) Delete(name string, version int) (*rspb.Release, error) {
-	s.Log("deleting release %q", makeKey(name, version))
-	return s.Driver.Delete(makeKey(name, version))
+	key := makeKey(name, version)
+	s.Log("deleting release %q", key)
+	rls, err := s.Driver.Delete(key)
+	if err != nil || len(rls.Overflow) == 0 {
+		return rls, err
+	}
+	restored, err := s.restore(key, rls)
+	if err != nil {
+		s.Log("unable to read the parts of release %q stored apart: %s", key, err)
+		restored = rls
+	}
+	for _, part := range rls.Overflow {
+		o, ok := s.partStore(part)
+		if !ok {
+			continue
+		}
+		if err := o.DeleteOverflow(overflowKey(key, part)); err != nil {
+			s.Log("unable to delete the %s of release %q: %s", part, key, err)
+		}
+	}
+	return restored, nil
+}
+
+// List returns the releases for which filter returns true, with the parts
+// stored apart from their records. An error is returned if the storage
+// backend fails to retrieve the releases.
+func (s *Storage) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	ls, err := s.Driver.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.restoreAll(ls)
+}
+
+// Query returns the releases matching the labels, with the parts stored
+// apart from their records.
+func (s *Storage) Query(labels map[string]string) ([]*rspb.Release, error) {
+	ls, err := s.Driver.Query(labels)
+	if err != nil {
+		return nil, err
+	}
+	return s.restoreAll(ls)
 }
 
 // ListReleases returns all releases from storage. An error is returned if the
 // storage backend fails to retrieve the releases.
 func (s *Storage) ListReleases() ([]*rspb.Release, error) {
 	s.Log("listing all releases in storage")
-	return s.Driver.List(func(_ *rspb.Release) bool { return true })
+	return s.List(func(_ *rspb.Release) bool { return true })
 }
 
 // ListUninstalled returns all releases with Status == UNINSTALLED. An error is returned
 // if the storage backend fails to retrieve the releases.
 func (s *Storage) ListUninstalled() ([]*rspb.Release, error) {
 	s.Log("listing uninstalled releases in storage")
-	return s.Driver.List(func(rls *rspb.Release) bool {
+	return s.List(func(rls *rspb.Release) bool {
 		return relutil.StatusFilter(rspb.StatusUninstalled).Check(rls)
 	})
 }
@@ -105,7 +195,7 @@ func (s *Storage) ListUninstalled() ([]*rspb.Release, error) {
 // if the storage backend fails to retrieve the releases.
 func (s *Storage) ListDeployed() ([]*rspb.Release, error) {
 	s.Log("listing all deployed releases in storage")
-	return s.Driver.List(func(rls *rspb.Release) bool {
+	return s.List(func(rls *rspb.Release) bool {
 		return relutil.StatusFilter(rspb.StatusDeployed).Check(rls)
 	})
 }
@@ -134,7 +224,7 @@ func (s *Storage) Deployed(name string) (*rspb.Release, error) {
 func (s *Storage) DeployedAll(name string) ([]*rspb.Release, error) {
 	s.Log("getting deployed releases from %q history", name)
 
-	ls, err := s.Driver.Query(map[string]string{
+	ls, err := s.Query(map[string]string{
 		"name":   name,
 		"owner":  "helm",
 		"status": "deployed",
@@ -153,7 +243,7 @@ func (s *Storage) DeployedAll(name string) ([]*rspb.Release, error) {
 func (s *Storage) History(name string) ([]*rspb.Release, error) {
 	s.Log("getting release history for %q", name)
 
-	return s.Driver.Query(map[string]string{"name": name, "owner": "helm"})
+	return s.Query(map[string]string{"name": name, "owner": "helm"})
 }
 
 // removeLeastRecent removes items from history until the length number of releases
@@ -260,7 +350,8 @@ func Init(d driver.Driver) *Storage {
 		d = driver.NewMemory()
 	}
 	return &Storage{
-		Driver: d,
-		Log:    func(_ string, _ ...interface{}) {},
+		Driver:      d,
+		HelmVersion: version.GetVersion(),
+		Log:         func(_ string, _ ...interface{}) {},
 	}
 }
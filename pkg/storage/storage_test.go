@@ -23,6 +23,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"helm.sh/helm/v3/pkg/chart"
 	rspb "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
 )
@@ -49,6 +50,30 @@ func TestStorageCreate(t *testing.T) {
 	}
 }
 
+func TestStorageStampsHelmVersion(t *testing.T) {
+	storage := Init(driver.NewMemory())
+	storage.HelmVersion = "v3.0.0-test"
+
+	rls := ReleaseTestData{Name: "angry-beaver", Version: 1}.ToRelease()
+	assertErrNil(t.Fatal, storage.Create(rls), "StoreRelease")
+
+	res, err := storage.Get(rls.Name, rls.Version)
+	assertErrNil(t.Fatal, err, "QueryRelease")
+	if res.HelmVersion != "v3.0.0-test" || res.SchemaVersion != rspb.SchemaVersionBase {
+		t.Fatalf("Expected the release to be stamped with v3.0.0-test and schema %d, got %q and %d",
+			rspb.SchemaVersionBase, res.HelmVersion, res.SchemaVersion)
+	}
+
+	// The version a revision was first written with is kept.
+	storage.HelmVersion = "v3.1.0-test"
+	assertErrNil(t.Fatal, storage.Update(res), "UpdateRelease")
+	res, err = storage.Get(rls.Name, rls.Version)
+	assertErrNil(t.Fatal, err, "QueryRelease")
+	if res.HelmVersion != "v3.0.0-test" {
+		t.Fatalf("Expected the release to keep v3.0.0-test, got %q", res.HelmVersion)
+	}
+}
+
 func TestStorageUpdate(t *testing.T) {
 	// initialize storage
 	storage := Init(driver.NewMemory())
@@ -535,6 +560,203 @@ func TestUpgradeInitiallyFailedReleaseWithHistoryLimit(t *testing.T) {
 	}
 }
 
+// withoutOverflower hides that the driver it wraps can store the parts of
+// releases apart.
+type withoutOverflower struct {
+	driver.Driver
+}
+
+func TestStorageOverflow(t *testing.T) {
+	mem := driver.NewMemory()
+	storage := Init(mem)
+	storage.MaxNotesSize = 10
+	storage.MaxManifestSize = 20
+
+	rls := ReleaseTestData{
+		Name:     "angry-beaver",
+		Version:  1,
+		Manifest: "kind: ConfigMap\nmetadata:\n  name: big\n",
+	}.ToRelease()
+	rls.Info.Notes = "these notes are too long"
+	assertErrNil(t.Fatal, storage.Create(rls), "StoreRelease")
+	if rls.Overflow != nil || rls.Info.Notes == "" || rls.Manifest == "" {
+		t.Fatalf("Expected the created release to be left as is, got %v", rls)
+	}
+
+	key := makeKey(rls.Name, rls.Version)
+	stored, err := mem.Get(key)
+	assertErrNil(t.Fatal, err, "GetRecord")
+	if stored.Info.Notes != "" || stored.Manifest != "" {
+		t.Errorf("Expected the notes and manifest to be stored apart, got %v", stored)
+	}
+	if want := []string{rspb.OverflowNotes, rspb.OverflowManifest}; !reflect.DeepEqual(stored.Overflow, want) {
+		t.Errorf("Expected overflow %v, got %v", want, stored.Overflow)
+	}
+	if stored.SchemaVersion != rspb.SchemaVersionOverflow {
+		t.Errorf("Expected the record to have schema %d, got %d", rspb.SchemaVersionOverflow, stored.SchemaVersion)
+	}
+
+	res, err := storage.Get(rls.Name, rls.Version)
+	assertErrNil(t.Fatal, err, "QueryRelease")
+	if !reflect.DeepEqual(rls, res) {
+		t.Fatalf("Expected %v, got %v", rls, res)
+	}
+	hist, err := storage.History(rls.Name)
+	assertErrNil(t.Fatal, err, "History")
+	if len(hist) != 1 || !reflect.DeepEqual(rls, hist[0]) {
+		t.Fatalf("Expected %v, got %v", rls, hist)
+	}
+
+	_, err = storage.Delete(rls.Name, rls.Version)
+	assertErrNil(t.Fatal, err, "DeleteRelease")
+	if _, err := mem.GetOverflow(overflowKey(key, rspb.OverflowNotes)); !errors.Is(err, driver.ErrReleaseNotFound) {
+		t.Errorf("Expected the notes stored apart to be deleted, got %v", err)
+	}
+}
+
+func TestStorageOverflowMissingParts(t *testing.T) {
+	mem := driver.NewMemory()
+	storage := Init(mem)
+	storage.MaxManifestSize = 20
+
+	for v := 1; v <= 3; v++ {
+		rls := ReleaseTestData{
+			Name:     "angry-beaver",
+			Version:  v,
+			Manifest: "kind: ConfigMap\nmetadata:\n  name: big\n",
+		}.ToRelease()
+		assertErrNil(t.Fatal, storage.Create(rls), "StoreRelease")
+	}
+
+	// An older revision that lost its manifest is listed without it.
+	assertErrNil(t.Fatal, mem.DeleteOverflow(overflowKey(makeKey("angry-beaver", 1), rspb.OverflowManifest)), "DeleteOverflow")
+	hist, err := storage.History("angry-beaver")
+	assertErrNil(t.Fatal, err, "History")
+	if len(hist) != 3 {
+		t.Fatalf("Expected 3 revisions, got %d", len(hist))
+	}
+	for _, rls := range hist {
+		if lost := rls.Version == 1; (rls.Manifest == "") != lost {
+			t.Errorf("Expected revision %d to have its manifest restored: %t, got %q", rls.Version, !lost, rls.Manifest)
+		}
+	}
+
+	// Writing it back keeps the parts it was read without stored apart.
+	for _, rls := range hist {
+		if rls.Version == 1 {
+			assertErrNil(t.Fatal, storage.Update(rls), "UpdateRelease")
+		}
+	}
+	stored, err := mem.Get(makeKey("angry-beaver", 1))
+	assertErrNil(t.Fatal, err, "GetRecord")
+	if want := []string{rspb.OverflowManifest}; !reflect.DeepEqual(stored.Overflow, want) {
+		t.Errorf("Expected overflow %v, got %v", want, stored.Overflow)
+	}
+
+	// The latest revision must be read whole.
+	assertErrNil(t.Fatal, mem.DeleteOverflow(overflowKey(makeKey("angry-beaver", 3), rspb.OverflowManifest)), "DeleteOverflow")
+	if _, err := storage.History("angry-beaver"); err == nil {
+		t.Error("Expected an error reading the history without the manifest of the latest revision")
+	}
+}
+
+func TestStorageSensitiveValues(t *testing.T) {
+	mem := driver.NewMemory()
+	secrets := driver.NewMemory()
+	storage := Init(mem)
+	storage.Sensitive = secrets
+
+	rls := ReleaseTestData{
+		Name:     "angry-beaver",
+		Version:  1,
+		Manifest: "kind: Secret\nstringData:\n  password: hunter2\n",
+	}.ToRelease()
+	rls.Info.Notes = "Log in with hunter2"
+	rls.Hooks = []*rspb.Hook{{Name: "migrate", Kind: "Job", Path: "templates/migrate.yaml", Manifest: "kind: Job\nenv:\n  PASSWORD: hunter2\n"}}
+	rls.Chart = &chart.Chart{
+		Metadata: &chart.Metadata{Name: "db"},
+		Schema:   []byte(`{"properties": {"auth": {"properties": {"password": {"type": "string", "x-helm-sensitive": true}}}}}`),
+	}
+	rls.Config = map[string]interface{}{
+		"auth":     map[string]interface{}{"user": "admin", "password": "hunter2"},
+		"replicas": "3",
+	}
+	assertErrNil(t.Fatal, storage.Create(rls), "StoreRelease")
+	if rls.Overflow != nil || rls.Config["auth"].(map[string]interface{})["password"] != "hunter2" {
+		t.Fatalf("Expected the created release to be left as is, got %v", rls)
+	}
+
+	key := makeKey(rls.Name, rls.Version)
+	stored, err := mem.Get(key)
+	assertErrNil(t.Fatal, err, "GetRecord")
+	want := map[string]interface{}{
+		"auth":     map[string]interface{}{"user": "admin"},
+		"replicas": "3",
+	}
+	if !reflect.DeepEqual(stored.Config, want) {
+		t.Errorf("Expected the sensitive values to be stored apart, got %v", stored.Config)
+	}
+	if want := []string{rspb.OverflowValues, rspb.OverflowSensitiveManifest, rspb.OverflowSensitiveHooks, rspb.OverflowSensitiveNotes}; !reflect.DeepEqual(stored.Overflow, want) {
+		t.Errorf("Expected overflow %v, got %v", want, stored.Overflow)
+	}
+	if stored.Manifest != "" || stored.Hooks[0].Manifest != "" || stored.Info.Notes != "" {
+		t.Errorf("Expected the manifests and notes to be stored apart, got %q, %q and %q", stored.Manifest, stored.Hooks[0].Manifest, stored.Info.Notes)
+	}
+	if stored.Hooks[0].Name != "migrate" {
+		t.Errorf("Expected the hooks to be kept in the record, got %v", stored.Hooks[0])
+	}
+	if rls.Hooks[0].Manifest == "" || rls.Info.Notes == "" {
+		t.Fatalf("Expected the created release to be left as is, got %v", rls)
+	}
+	for _, part := range stored.Overflow {
+		if _, err := secrets.GetOverflow(overflowKey(key, part)); err != nil {
+			t.Errorf("Expected the %s to be stored in the sensitive store, got %v", part, err)
+		}
+	}
+
+	res, err := storage.Get(rls.Name, rls.Version)
+	assertErrNil(t.Fatal, err, "QueryRelease")
+	if !reflect.DeepEqual(rls, res) {
+		t.Fatalf("Expected %v, got %v", rls, res)
+	}
+
+	_, err = storage.Delete(rls.Name, rls.Version)
+	assertErrNil(t.Fatal, err, "DeleteRelease")
+	for _, part := range stored.Overflow {
+		if _, err := secrets.GetOverflow(overflowKey(key, part)); !errors.Is(err, driver.ErrReleaseNotFound) {
+			t.Errorf("Expected the %s to be deleted, got %v", part, err)
+		}
+	}
+
+	// Records kept in the sensitive store keep their manifest.
+	storage.Sensitive = mem
+	assertErrNil(t.Fatal, storage.Create(rls), "StoreRelease")
+	stored, err = mem.Get(key)
+	assertErrNil(t.Fatal, err, "GetRecord")
+	if want := []string{rspb.OverflowValues}; !reflect.DeepEqual(stored.Overflow, want) {
+		t.Errorf("Expected overflow %v, got %v", want, stored.Overflow)
+	}
+	if stored.Manifest != rls.Manifest || stored.Hooks[0].Manifest != rls.Hooks[0].Manifest || stored.Info.Notes != rls.Info.Notes {
+		t.Errorf("Expected the manifests and notes to be kept in the record, got %v", stored)
+	}
+}
+
+func TestStorageOverflowTruncatesNotes(t *testing.T) {
+	storage := Init(withoutOverflower{driver.NewMemory()})
+	storage.MaxNotesSize = 8
+
+	rls := ReleaseTestData{Name: "angry-beaver", Version: 1}.ToRelease()
+	rls.Info.Notes = "noteséé"
+	assertErrNil(t.Fatal, storage.Create(rls), "StoreRelease")
+
+	res, err := storage.Get(rls.Name, rls.Version)
+	assertErrNil(t.Fatal, err, "QueryRelease")
+	// The truncation does not split the second character.
+	if want := "notesé" + truncatedNotes; res.Info.Notes != want {
+		t.Errorf("Expected notes %q, got %q", want, res.Info.Notes)
+	}
+}
+
 type ReleaseTestData struct {
 	Name      string
 	Version   int